@@ -171,6 +171,39 @@ func (i *InitPermissionData) Run() error {
 		{Code: "table_permission:create", Name: "分配数据权限", Resource: "table_permission", Action: "create", ParentID: 0, Description: "分配数据权限"},
 		{Code: "table_permission:update", Name: "更新数据权限", Resource: "table_permission", Action: "update", ParentID: 0, Description: "更新数据权限"},
 		{Code: "table_permission:delete", Name: "撤销数据权限", Resource: "table_permission", Action: "delete", ParentID: 0, Description: "撤销数据权限"},
+
+		// 层级权限
+		{Code: "hierarchy", Name: "层级", Resource: "hierarchy", Action: "", ParentID: 0, Description: "层级模块"},
+		{Code: "hierarchy:list", Name: "查看层级", Resource: "hierarchy", Action: "list", ParentID: 0, Description: "查看层级列表"},
+		{Code: "hierarchy:create", Name: "创建层级", Resource: "hierarchy", Action: "create", ParentID: 0, Description: "创建层级"},
+		{Code: "hierarchy:update", Name: "更新层级", Resource: "hierarchy", Action: "update", ParentID: 0, Description: "更新层级"},
+		{Code: "hierarchy:delete", Name: "删除层级", Resource: "hierarchy", Action: "delete", ParentID: 0, Description: "删除层级"},
+
+		// 匹配规则权限
+		{Code: "match_rule", Name: "匹配规则", Resource: "match_rule", Action: "", ParentID: 0, Description: "查重匹配规则模块"},
+		{Code: "match_rule:list", Name: "查看匹配规则", Resource: "match_rule", Action: "list", ParentID: 0, Description: "查看匹配规则列表"},
+		{Code: "match_rule:create", Name: "创建匹配规则", Resource: "match_rule", Action: "create", ParentID: 0, Description: "创建匹配规则"},
+		{Code: "match_rule:update", Name: "更新匹配规则", Resource: "match_rule", Action: "update", ParentID: 0, Description: "更新匹配规则"},
+		{Code: "match_rule:delete", Name: "删除匹配规则", Resource: "match_rule", Action: "delete", ParentID: 0, Description: "删除匹配规则"},
+
+		// 字段组权限
+		{Code: "table_field_group", Name: "字段组", Resource: "table_field_group", Action: "", ParentID: 0, Description: "表单字段组模块"},
+		{Code: "table_field_group:list", Name: "查看字段组", Resource: "table_field_group", Action: "list", ParentID: 0, Description: "查看字段组列表"},
+		{Code: "table_field_group:create", Name: "创建字段组", Resource: "table_field_group", Action: "create", ParentID: 0, Description: "创建字段组"},
+		{Code: "table_field_group:update", Name: "更新字段组", Resource: "table_field_group", Action: "update", ParentID: 0, Description: "更新字段组"},
+		{Code: "table_field_group:delete", Name: "删除字段组", Resource: "table_field_group", Action: "delete", ParentID: 0, Description: "删除字段组"},
+
+		// 关联视图权限
+		{Code: "table_relation", Name: "关联视图", Resource: "table_relation", Action: "", ParentID: 0, Description: "记录关联视图模块"},
+		{Code: "table_relation:list", Name: "查看关联视图", Resource: "table_relation", Action: "list", ParentID: 0, Description: "查看关联视图列表"},
+		{Code: "table_relation:create", Name: "创建关联视图", Resource: "table_relation", Action: "create", ParentID: 0, Description: "创建关联视图"},
+		{Code: "table_relation:update", Name: "更新关联视图", Resource: "table_relation", Action: "update", ParentID: 0, Description: "更新关联视图"},
+		{Code: "table_relation:delete", Name: "删除关联视图", Resource: "table_relation", Action: "delete", ParentID: 0, Description: "删除关联视图"},
+
+		// 模型迁移权限
+		{Code: "model_package", Name: "模型迁移", Resource: "model_package", Action: "", ParentID: 0, Description: "模型定义导出导入模块"},
+		{Code: "model_package:list", Name: "导出模型定义", Resource: "model_package", Action: "list", ParentID: 0, Description: "导出模型定义和预览导入差异"},
+		{Code: "model_package:update", Name: "导入模型定义", Resource: "model_package", Action: "update", ParentID: 0, Description: "导入模型定义并发布表结构"},
 	}
 	// 创建权限
 	createdCount := 0
@@ -232,6 +265,10 @@ func (i *InitPermissionData) buildPermissionTree(ctx context.Context) error {
 		"table_ext":             {"table_ext:list", "table_ext:create", "table_ext:update", "table_ext:delete"},
 		"table_approval_def":    {"table_approval_def:list", "table_approval_def:create", "table_approval_def:update", "table_approval_def:delete"},
 		"table_permission":      {"table_permission:list", "table_permission:create", "table_permission:update", "table_permission:delete"},
+		"hierarchy":             {"hierarchy:list", "hierarchy:create", "hierarchy:update", "hierarchy:delete"},
+		"match_rule":            {"match_rule:list", "match_rule:create", "match_rule:update", "match_rule:delete"},
+		"table_field_group":     {"table_field_group:list", "table_field_group:create", "table_field_group:update", "table_field_group:delete"},
+		"table_relation":        {"table_relation:list", "table_relation:create", "table_relation:update", "table_relation:delete"},
 	}
 
 	for parentCode, childCodes := range parentChildMap {
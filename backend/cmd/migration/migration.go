@@ -58,6 +58,15 @@ func (m *Migrate) Run() error {
 		&model.TableApprovalDefinition{},
 		&model.ApplicationApiLog{},
 		&model.GlobalId{},
+		&model.EntityJob{},
+		&model.Hierarchy{},
+		&model.HierarchyVersion{},
+		&model.HierarchyNode{},
+		&model.MatchRule{},
+		&model.DuplicateCluster{},
+		&model.DuplicateClusterMember{},
+		&model.EntityMerge{},
+		&model.SchemaVersion{},
 	)
 	if err != nil {
 		return errors.Wrap(err, "Failed to auto migrate approval tables")
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"piemdm/internal/model"
+	"piemdm/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Command struct {
+	modelPackageService service.ModelPackageService
+	out                 io.Writer
+}
+
+func NewCommand(modelPackageService service.ModelPackageService) *Command {
+	return &Command{
+		modelPackageService: modelPackageService,
+		out:                 os.Stdout,
+	}
+}
+
+const usage = `用法:
+  model export [-tables product,customer] [-o model.json]  导出模型定义包, 不指定表时导出全部表
+  model diff -f model.json                                  预览导入差异, 不修改数据库
+  model import -f model.json [-checksum 摘要] [-allow-data-loss]  导入模型定义包并发布表结构`
+
+// Run 执行子命令
+func (cmd *Command) Run(args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "export":
+		return cmd.export(args[1:])
+	case "diff":
+		return cmd.diff(args[1:])
+	case "import":
+		return cmd.importPackage(args[1:])
+	}
+	return fmt.Errorf("未知命令 %s\n%s", args[0], usage)
+}
+
+// cliContext 命令行没有请求上下文, 操作人记录在 user_name 中
+func cliContext(user string) *gin.Context {
+	c := &gin.Context{}
+	c.Set("user_name", user)
+	return c
+}
+
+func (cmd *Command) export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	tables := fs.String("tables", "", "表编码, 逗号分隔")
+	output := fs.String("o", "", "输出文件, 默认输出到标准输出")
+	user := fs.String("user", "cli", "操作人")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var tableCodes []string
+	for _, code := range strings.Split(*tables, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			tableCodes = append(tableCodes, code)
+		}
+	}
+	pkg, err := cmd.modelPackageService.Export(cliContext(*user), tableCodes)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(pkg, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if *output == "" {
+		_, err = cmd.out.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.out, "已导出 %d 张表到 %s\n", len(pkg.Tables), *output)
+	return nil
+}
+
+func readPackage(path string) (*model.ModelPackage, error) {
+	if path == "" {
+		return nil, errors.New("请使用 -f 指定模型定义包文件")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pkg model.ModelPackage
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("解析模型定义包失败: %w", err)
+	}
+	return &pkg, nil
+}
+
+func (cmd *Command) diff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	file := fs.String("f", "", "模型定义包文件")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	pkg, err := readPackage(*file)
+	if err != nil {
+		return err
+	}
+	diff, err := cmd.modelPackageService.Diff(pkg)
+	if err != nil {
+		return err
+	}
+	cmd.printDiff(diff)
+	return nil
+}
+
+func (cmd *Command) importPackage(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := fs.String("f", "", "模型定义包文件")
+	checksum := fs.String("checksum", "", "diff 输出的差异摘要, 校验差异未变化")
+	allowDataLoss := fs.Bool("allow-data-loss", false, "确认发布会丢失数据的表结构变更")
+	user := fs.String("user", "cli", "操作人")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	pkg, err := readPackage(*file)
+	if err != nil {
+		return err
+	}
+	result, err := cmd.modelPackageService.Import(cliContext(*user), pkg, &service.ModelImportOptions{
+		Checksum:      *checksum,
+		AllowDataLoss: *allowDataLoss,
+	})
+	var packageErr *service.ModelPackageError
+	if errors.As(err, &packageErr) {
+		cmd.printDiff(packageErr.Diff)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.out, "已导入 %d 项变更\n", len(result.Diff.Changes))
+	for _, version := range result.Versions {
+		fmt.Fprintf(cmd.out, "已发布 %s 表结构版本 %d\n", version.TableCode, version.Version)
+	}
+	if len(result.Failed) > 0 {
+		for table, reason := range result.Failed {
+			fmt.Fprintf(cmd.out, "发布 %s 失败: %s\n", table, reason)
+		}
+		return errors.New("部分表发布失败, 定义已导入, 请修正后在模型管理中重新发布")
+	}
+	return nil
+}
+
+func (cmd *Command) printDiff(diff *model.ModelPackageDiff) {
+	if len(diff.Changes) == 0 {
+		fmt.Fprintln(cmd.out, "没有变更")
+	}
+	for _, change := range diff.Changes {
+		line := fmt.Sprintf("%-7s %-18s %s", change.Action, change.Kind, change.Key)
+		if len(change.Fields) > 0 {
+			line += " (" + strings.Join(change.Fields, ", ") + ")"
+		}
+		fmt.Fprintln(cmd.out, line)
+	}
+	for _, plan := range diff.Schemas {
+		for _, table := range plan.Tables {
+			for _, change := range table.Changes {
+				fmt.Fprintf(cmd.out, "表结构  %s %s %s%s 影响 %d 行, 丢失 %d 行, 冲突 %d 行\n",
+					table.Table, change.Kind, change.Column, change.Index, change.Affected, change.Lost, change.Conflicts)
+			}
+		}
+	}
+	for _, message := range diff.Errors {
+		fmt.Fprintln(cmd.out, "错误: "+message)
+	}
+	if diff.DataLoss {
+		fmt.Fprintln(cmd.out, "警告: 发布表结构会丢失数据, 导入时需要 -allow-data-loss")
+	}
+	fmt.Fprintln(cmd.out, "checksum: "+diff.Checksum)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"piemdm/pkg/configloader"
+	"piemdm/pkg/log"
+)
+
+// 模型定义导出导入命令, 用于在环境间迁移数据模型:
+//
+//	model [-conf config/prod.yml] export [-tables product,customer] [-o model.json]
+//	model [-conf config/prod.yml] diff -f model.json
+//	model [-conf config/prod.yml] import -f model.json [-checksum xxx] [-allow-data-loss]
+func main() {
+	v, err := configloader.Load()
+	if err != nil {
+		panic(err)
+	}
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	logger := log.NewLog(v)
+
+	app, cleanup, err := newApp(v, logger)
+	if err != nil {
+		panic(err)
+	}
+	err = app.Run(flag.Args())
+	cleanup()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,44 @@
+//go:build wireinject
+// +build wireinject
+
+package main
+
+import (
+	"piemdm/internal/repository"
+	"piemdm/internal/service"
+	"piemdm/pkg/helper/sid"
+	"piemdm/pkg/jwt"
+	"piemdm/pkg/log"
+
+	"github.com/google/wire"
+	"github.com/spf13/viper"
+)
+
+var ServiceSet = wire.NewSet(
+	service.NewService,
+	service.NewTableFieldService,
+	service.NewModelPackageService,
+)
+
+var RepositorySet = wire.NewSet(
+	repository.NewDB,
+	repository.NewRedis,
+	repository.NewRepository,
+	repository.NewBaseRepository,
+	repository.NewTableRepository,
+	repository.NewTableFieldRepository,
+	repository.NewSchemaVersionRepository,
+	repository.NewTableFieldGroupRepository,
+	repository.NewUserRoleRepository,
+	repository.NewModelPackageRepository,
+)
+
+func newApp(*viper.Viper, *log.Logger) (*Command, func(), error) {
+	panic(wire.Build(
+		RepositorySet,
+		ServiceSet,
+		NewCommand,
+		sid.NewSid,
+		jwt.NewJwt,
+	))
+}
@@ -61,6 +61,11 @@ var HandlerSet = wire.NewSet(
 	handler.NewTableApprovalDefinitionHandler,
 	handler.NewUploadHandler,
 	handler.NewTablePermissionHandler,
+	handler.NewHierarchyHandler,
+	handler.NewMatchRuleHandler,
+	handler.NewTableFieldGroupHandler,
+	handler.NewTableRelationHandler,
+	handler.NewModelPackageHandler,
 
 	// OpenAPI
 	handler.NewOpenApiHandler,
@@ -84,6 +89,7 @@ var ServiceSet = wire.NewSet(
 	service.NewCronParamService,
 	service.NewEntityService,
 	service.NewEntityLogService,
+	service.NewEntityJobService,
 	service.NewGlobalIdService,
 	service.NewRoleService,
 	service.NewPermissionService,
@@ -94,6 +100,11 @@ var ServiceSet = wire.NewSet(
 	service.NewAutocodeService,
 	service.NewUploadService,
 	service.NewTablePermissionService,
+	service.NewHierarchyService,
+	service.NewMatchRuleService,
+	service.NewTableFieldGroupService,
+	service.NewTableRelationService,
+	service.NewModelPackageService,
 
 	// OpenAPI
 	service.NewOpenApiAuthService,
@@ -125,6 +136,7 @@ var RepositorySet = wire.NewSet(
 	repository.NewCronLogRepository,
 	repository.NewEntityRepository,
 	repository.NewEntityLogRepository,
+	repository.NewEntityJobRepository,
 	repository.NewGlobalIdRepository,
 	repository.NewRoleRepository,
 	repository.NewPermissionRepository,
@@ -133,6 +145,13 @@ var RepositorySet = wire.NewSet(
 	repository.NewTableApprovalDefinitionRepository,
 	repository.NewTablePermissionRepository,
 	repository.NewUserRoleRepository,
+	repository.NewHierarchyRepository,
+	repository.NewMatchRuleRepository,
+	repository.NewTableFieldGroupRepository,
+	repository.NewTableRelationRepository,
+	repository.NewModelPackageRepository,
+	repository.NewEntityMergeRepository,
+	repository.NewSchemaVersionRepository,
 
 	// OpenAPI
 	repository.NewApplicationApiLogRepository,
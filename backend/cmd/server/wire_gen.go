@@ -41,7 +41,10 @@ func newApp(viperViper *viper.Viper, logger *log.Logger) (*router.Server, func()
 	tableFieldRepository := repository.NewTableFieldRepository(repositoryRepository, base)
 	entityRepository := repository.NewEntityRepository(repositoryRepository, base, tableFieldRepository)
 	tableRepository := repository.NewTableRepository(repositoryRepository, base)
-	tableFieldService := service.NewTableFieldService(serviceService, tableFieldRepository, tableRepository)
+	schemaVersionRepository := repository.NewSchemaVersionRepository(repositoryRepository, base)
+	tableFieldGroupRepository := repository.NewTableFieldGroupRepository(repositoryRepository, base)
+	userRoleRepository := repository.NewUserRoleRepository(db)
+	tableFieldService := service.NewTableFieldService(serviceService, tableFieldRepository, tableRepository, schemaVersionRepository, tableFieldGroupRepository, userRoleRepository)
 	tableApprovalDefinitionRepository := repository.NewTableApprovalDefinitionRepository(repositoryRepository, base)
 	approvalRepository := repository.NewApprovalRepository(repositoryRepository, base)
 	approvalTaskRepository := repository.NewApprovalTaskRepository(repositoryRepository, base)
@@ -61,11 +64,16 @@ func newApp(viperViper *viper.Viper, logger *log.Logger) (*router.Server, func()
 	userRepository := repository.NewUserRepository(repositoryRepository, base)
 	notificationService := notification.NewNotificationServiceProvider(viperViper, logger)
 	autocodeService := service.NewAutocodeService(serviceService, globalIdService)
-	approvalService := service.NewApprovalService(serviceService, approvalRepository, approvalTaskService, approvalDefinitionService, approvalNodeService, entityRepository, tableFieldService, entityLogService, webhookService, tableFieldRepository, approvalDefinitionRepository, tableApprovalDefinitionRepository, approvalNodeRepository, globalIdService, approvalTaskRepository, userRepository, notificationService, feishuService, autocodeService)
+	hierarchyRepository := repository.NewHierarchyRepository(repositoryRepository, base)
+	matchRuleRepository := repository.NewMatchRuleRepository(repositoryRepository, base)
+	entityMergeRepository := repository.NewEntityMergeRepository(repositoryRepository, base)
+	approvalService := service.NewApprovalService(serviceService, approvalRepository, approvalTaskService, approvalDefinitionService, approvalNodeService, entityRepository, tableFieldService, entityLogService, webhookService, tableFieldRepository, approvalDefinitionRepository, tableApprovalDefinitionRepository, approvalNodeRepository, globalIdService, approvalTaskRepository, userRepository, notificationService, feishuService, autocodeService, tableRepository, hierarchyRepository, entityMergeRepository)
 	tablePermissionRepository := repository.NewTablePermissionRepository(db)
-	userRoleRepository := repository.NewUserRoleRepository(db)
 	tablePermissionService := service.NewTablePermissionService(tablePermissionRepository, tableRepository, userRoleRepository)
-	entityService := service.NewEntityService(serviceService, entityRepository, tableFieldService, tableFieldRepository, tableApprovalDefinitionRepository, approvalService, globalIdService, entityLogService, autocodeService, tablePermissionService, tableRepository, viperViper)
+	entityJobRepository := repository.NewEntityJobRepository(repositoryRepository, base)
+	entityJobService := service.NewEntityJobService(serviceService, entityJobRepository, viperViper)
+	tableRelationRepository := repository.NewTableRelationRepository(repositoryRepository, base)
+	entityService := service.NewEntityService(serviceService, entityRepository, tableFieldService, tableFieldRepository, tableApprovalDefinitionRepository, approvalService, globalIdService, entityLogService, autocodeService, tablePermissionService, tableRepository, entityJobService, matchRuleRepository, entityMergeRepository, tableRelationRepository, viperViper)
 	tableApprovalDefinitionService := service.NewTableApprovalDefinitionService(serviceService, tableApprovalDefinitionRepository)
 	entityHandler := handler.NewEntityHandler(handlerHandler, entityService, tableFieldService, tableApprovalDefinitionService, tablePermissionService, viperViper)
 	approvalHandler := handler.NewApprovalHandler(handlerHandler, approvalService)
@@ -74,7 +82,7 @@ func newApp(viperViper *viper.Viper, logger *log.Logger) (*router.Server, func()
 	approvalTaskHandler := handler.NewApprovalTaskHandler(handlerHandler, approvalTaskService, approvalService)
 	tableService := service.NewTableService(serviceService, tableRepository, tableFieldRepository)
 	tableHandler := handler.NewTableHandler(handlerHandler, tableService, tablePermissionService)
-	tableFieldHandler := handler.NewTableFieldHandler(handlerHandler, tableFieldService)
+	tableFieldHandler := handler.NewTableFieldHandler(handlerHandler, tableFieldService, entityService)
 	applicationRepository := repository.NewApplicationRepository(repositoryRepository, base)
 	applicationService := service.NewApplicationService(serviceService, applicationRepository)
 	applicationHandler := handler.NewApplicationHandler(handlerHandler, applicationService)
@@ -113,10 +121,21 @@ func newApp(viperViper *viper.Viper, logger *log.Logger) (*router.Server, func()
 	uploadService := service.NewUploadService()
 	uploadHandler := handler.NewUploadHandler(handlerHandler, uploadService)
 	tablePermissionHandler := handler.NewTablePermissionHandler(handlerHandler, tablePermissionService)
+	hierarchyService := service.NewHierarchyService(serviceService, hierarchyRepository, tableRepository, entityRepository, tableApprovalDefinitionRepository, approvalService, tablePermissionService)
+	hierarchyHandler := handler.NewHierarchyHandler(handlerHandler, hierarchyService)
+	matchRuleService := service.NewMatchRuleService(serviceService, matchRuleRepository, tableRepository, tableFieldService)
+	matchRuleHandler := handler.NewMatchRuleHandler(handlerHandler, matchRuleService)
+	tableFieldGroupService := service.NewTableFieldGroupService(serviceService, tableFieldGroupRepository, tableFieldRepository, tableRepository, roleRepository)
+	tableFieldGroupHandler := handler.NewTableFieldGroupHandler(handlerHandler, tableFieldGroupService)
+	tableRelationService := service.NewTableRelationService(serviceService, tableRelationRepository, tableFieldRepository, tableRepository)
+	tableRelationHandler := handler.NewTableRelationHandler(handlerHandler, tableRelationService)
+	modelPackageRepository := repository.NewModelPackageRepository(repositoryRepository, base)
+	modelPackageService := service.NewModelPackageService(serviceService, modelPackageRepository, tableFieldService)
+	modelPackageHandler := handler.NewModelPackageHandler(handlerHandler, modelPackageService, entityService)
 	openApiHandler := handler.NewOpenApiHandler(logger, entityService, entityRepository)
 	applicationEntityRepository := repository.NewApplicationEntityRepository(repositoryRepository, base)
 	applicationApiLogRepository := repository.NewApplicationApiLogRepository(repositoryRepository, base)
-	engine := router.NewServerHTTP(logger, jwtJWT, entityHandler, approvalHandler, approvalService, approvalDefinitionHandler, approvalNodeHandler, approvalTaskHandler, tableHandler, tableFieldHandler, applicationHandler, webhookHandler, webhookDeliveryHandler, cronHandler, cronLogHandler, roleHandler, permissionHandler, userHandler, notificationHandler, notificationTemplateHandler, notificationLogHandler, tableApprovalDefinitionHandler, uploadHandler, tablePermissionHandler, openApiHandler, applicationRepository, applicationEntityRepository, applicationApiLogRepository, client, viperViper, enforcer)
+	engine := router.NewServerHTTP(logger, jwtJWT, entityHandler, approvalHandler, approvalService, approvalDefinitionHandler, approvalNodeHandler, approvalTaskHandler, tableHandler, tableFieldHandler, applicationHandler, webhookHandler, webhookDeliveryHandler, cronHandler, cronLogHandler, roleHandler, permissionHandler, userHandler, notificationHandler, notificationTemplateHandler, notificationLogHandler, tableApprovalDefinitionHandler, uploadHandler, tablePermissionHandler, hierarchyHandler, matchRuleHandler, tableFieldGroupHandler, tableRelationHandler, modelPackageHandler, openApiHandler, applicationRepository, applicationEntityRepository, applicationApiLogRepository, client, viperViper, enforcer)
 	server := router.NewServer(engine, notificationHandler, feishuService, approvalService)
 	return server, func() {
 	}, nil
@@ -141,7 +160,9 @@ func newCronApp(viperViper *viper.Viper, logger *log.Logger) (*cron.Cron, func()
 	tableFieldRepository := repository.NewTableFieldRepository(repositoryRepository, base)
 	entityRepository := repository.NewEntityRepository(repositoryRepository, base, tableFieldRepository)
 	tableRepository := repository.NewTableRepository(repositoryRepository, base)
-	tableFieldService := service.NewTableFieldService(serviceService, tableFieldRepository, tableRepository)
+	schemaVersionRepository := repository.NewSchemaVersionRepository(repositoryRepository, base)
+	tableFieldGroupRepository := repository.NewTableFieldGroupRepository(repositoryRepository, base)
+	tableFieldService := service.NewTableFieldService(serviceService, tableFieldRepository, tableRepository, schemaVersionRepository, tableFieldGroupRepository, userRoleRepository)
 	tableApprovalDefinitionRepository := repository.NewTableApprovalDefinitionRepository(repositoryRepository, base)
 	approvalRepository := repository.NewApprovalRepository(repositoryRepository, base)
 	approvalTaskRepository := repository.NewApprovalTaskRepository(repositoryRepository, base)
@@ -160,10 +181,16 @@ func newCronApp(viperViper *viper.Viper, logger *log.Logger) (*cron.Cron, func()
 	globalIdService := service.NewGlobalIdService(serviceService, globalIdRepository)
 	notificationService := notification.NewNotificationServiceProvider(viperViper, logger)
 	autocodeService := service.NewAutocodeService(serviceService, globalIdService)
-	approvalService := service.NewApprovalService(serviceService, approvalRepository, approvalTaskService, approvalDefinitionService, approvalNodeService, entityRepository, tableFieldService, entityLogService, webhookService, tableFieldRepository, approvalDefinitionRepository, tableApprovalDefinitionRepository, approvalNodeRepository, globalIdService, approvalTaskRepository, userRepository, notificationService, feishuService, autocodeService)
+	hierarchyRepository := repository.NewHierarchyRepository(repositoryRepository, base)
+	matchRuleRepository := repository.NewMatchRuleRepository(repositoryRepository, base)
+	entityMergeRepository := repository.NewEntityMergeRepository(repositoryRepository, base)
+	approvalService := service.NewApprovalService(serviceService, approvalRepository, approvalTaskService, approvalDefinitionService, approvalNodeService, entityRepository, tableFieldService, entityLogService, webhookService, tableFieldRepository, approvalDefinitionRepository, tableApprovalDefinitionRepository, approvalNodeRepository, globalIdService, approvalTaskRepository, userRepository, notificationService, feishuService, autocodeService, tableRepository, hierarchyRepository, entityMergeRepository)
 	tablePermissionRepository := repository.NewTablePermissionRepository(db)
 	tablePermissionService := service.NewTablePermissionService(tablePermissionRepository, tableRepository, userRoleRepository)
-	entityService := service.NewEntityService(serviceService, entityRepository, tableFieldService, tableFieldRepository, tableApprovalDefinitionRepository, approvalService, globalIdService, entityLogService, autocodeService, tablePermissionService, tableRepository, viperViper)
+	entityJobRepository := repository.NewEntityJobRepository(repositoryRepository, base)
+	entityJobService := service.NewEntityJobService(serviceService, entityJobRepository, viperViper)
+	tableRelationRepository := repository.NewTableRelationRepository(repositoryRepository, base)
+	entityService := service.NewEntityService(serviceService, entityRepository, tableFieldService, tableFieldRepository, tableApprovalDefinitionRepository, approvalService, globalIdService, entityLogService, autocodeService, tablePermissionService, tableRepository, entityJobService, matchRuleRepository, entityMergeRepository, tableRelationRepository, viperViper)
 	cronCron := cron.NewCron(scanner, cronService, cronParamService, entityService)
 	return cronCron, func() {
 	}, nil
@@ -183,7 +210,10 @@ func newWebhookApp(viperViper *viper.Viper, logger *log.Logger) (*webhook.Webhoo
 	tableFieldRepository := repository.NewTableFieldRepository(repositoryRepository, base)
 	entityRepository := repository.NewEntityRepository(repositoryRepository, base, tableFieldRepository)
 	tableRepository := repository.NewTableRepository(repositoryRepository, base)
-	tableFieldService := service.NewTableFieldService(serviceService, tableFieldRepository, tableRepository)
+	schemaVersionRepository := repository.NewSchemaVersionRepository(repositoryRepository, base)
+	tableFieldGroupRepository := repository.NewTableFieldGroupRepository(repositoryRepository, base)
+	userRoleRepository := repository.NewUserRoleRepository(db)
+	tableFieldService := service.NewTableFieldService(serviceService, tableFieldRepository, tableRepository, schemaVersionRepository, tableFieldGroupRepository, userRoleRepository)
 	tableApprovalDefinitionRepository := repository.NewTableApprovalDefinitionRepository(repositoryRepository, base)
 	approvalRepository := repository.NewApprovalRepository(repositoryRepository, base)
 	approvalTaskRepository := repository.NewApprovalTaskRepository(repositoryRepository, base)
@@ -201,11 +231,16 @@ func newWebhookApp(viperViper *viper.Viper, logger *log.Logger) (*webhook.Webhoo
 	userRepository := repository.NewUserRepository(repositoryRepository, base)
 	notificationService := notification.NewNotificationServiceProvider(viperViper, logger)
 	autocodeService := service.NewAutocodeService(serviceService, globalIdService)
-	approvalService := service.NewApprovalService(serviceService, approvalRepository, approvalTaskService, approvalDefinitionService, approvalNodeService, entityRepository, tableFieldService, entityLogService, webhookService, tableFieldRepository, approvalDefinitionRepository, tableApprovalDefinitionRepository, approvalNodeRepository, globalIdService, approvalTaskRepository, userRepository, notificationService, feishuService, autocodeService)
+	hierarchyRepository := repository.NewHierarchyRepository(repositoryRepository, base)
+	matchRuleRepository := repository.NewMatchRuleRepository(repositoryRepository, base)
+	entityMergeRepository := repository.NewEntityMergeRepository(repositoryRepository, base)
+	approvalService := service.NewApprovalService(serviceService, approvalRepository, approvalTaskService, approvalDefinitionService, approvalNodeService, entityRepository, tableFieldService, entityLogService, webhookService, tableFieldRepository, approvalDefinitionRepository, tableApprovalDefinitionRepository, approvalNodeRepository, globalIdService, approvalTaskRepository, userRepository, notificationService, feishuService, autocodeService, tableRepository, hierarchyRepository, entityMergeRepository)
 	tablePermissionRepository := repository.NewTablePermissionRepository(db)
-	userRoleRepository := repository.NewUserRoleRepository(db)
 	tablePermissionService := service.NewTablePermissionService(tablePermissionRepository, tableRepository, userRoleRepository)
-	entityService := service.NewEntityService(serviceService, entityRepository, tableFieldService, tableFieldRepository, tableApprovalDefinitionRepository, approvalService, globalIdService, entityLogService, autocodeService, tablePermissionService, tableRepository, viperViper)
+	entityJobRepository := repository.NewEntityJobRepository(repositoryRepository, base)
+	entityJobService := service.NewEntityJobService(serviceService, entityJobRepository, viperViper)
+	tableRelationRepository := repository.NewTableRelationRepository(repositoryRepository, base)
+	entityService := service.NewEntityService(serviceService, entityRepository, tableFieldService, tableFieldRepository, tableApprovalDefinitionRepository, approvalService, globalIdService, entityLogService, autocodeService, tablePermissionService, tableRepository, entityJobService, matchRuleRepository, entityMergeRepository, tableRelationRepository, viperViper)
 	taskEntityService := provideTaskEntityService(entityService)
 	webhookDeliveryRepository := repository.NewWebhookDeliveryRepository(repositoryRepository, base)
 	webhookDeliveryService := service.NewWebhookDeliveryService(serviceService, webhookDeliveryRepository)
@@ -228,11 +263,11 @@ func provideFeishuConfig(v *viper.Viper) configs.FeishuConfig {
 	return cfg.Integrations.Feishu
 }
 
-var HandlerSet = wire.NewSet(handler.NewHandler, handler.NewUserHandler, handler.NewApprovalHandler, handler.NewApprovalDefinitionHandler, handler.NewApprovalNodeHandler, handler.NewApprovalTaskHandler, handler.NewTableHandler, handler.NewTableFieldHandler, handler.NewApplicationHandler, handler.NewWebhookHandler, handler.NewWebhookDeliveryHandler, handler.NewCronHandler, handler.NewCronLogHandler, handler.NewEntityHandler, handler.NewRoleHandler, handler.NewPermissionHandler, handler.NewNotificationHandler, handler.NewNotificationTemplateHandler, handler.NewNotificationLogHandler, handler.NewTableApprovalDefinitionHandler, handler.NewUploadHandler, handler.NewTablePermissionHandler, handler.NewOpenApiHandler)
+var HandlerSet = wire.NewSet(handler.NewHandler, handler.NewUserHandler, handler.NewApprovalHandler, handler.NewApprovalDefinitionHandler, handler.NewApprovalNodeHandler, handler.NewApprovalTaskHandler, handler.NewTableHandler, handler.NewTableFieldHandler, handler.NewApplicationHandler, handler.NewWebhookHandler, handler.NewWebhookDeliveryHandler, handler.NewCronHandler, handler.NewCronLogHandler, handler.NewEntityHandler, handler.NewRoleHandler, handler.NewPermissionHandler, handler.NewNotificationHandler, handler.NewNotificationTemplateHandler, handler.NewNotificationLogHandler, handler.NewTableApprovalDefinitionHandler, handler.NewUploadHandler, handler.NewTablePermissionHandler, handler.NewHierarchyHandler, handler.NewMatchRuleHandler, handler.NewTableFieldGroupHandler, handler.NewModelPackageHandler, handler.NewOpenApiHandler)
 
-var ServiceSet = wire.NewSet(service.NewService, service.NewUserService, service.NewApprovalService, service.NewApprovalDefinitionService, service.NewApprovalNodeService, service.NewApprovalTaskService, service.NewTableService, service.NewTableFieldService, service.NewApplicationService, service.NewWebhookService, service.NewWebhookDeliveryService, service.NewCronService, service.NewCronLogService, service.NewCronParamService, service.NewEntityService, service.NewEntityLogService, service.NewGlobalIdService, service.NewRoleService, service.NewPermissionService, service.NewNotificationService, service.NewNotificationTemplateService, service.NewNotificationLogService, service.NewTableApprovalDefinitionService, service.NewAutocodeService, service.NewUploadService, service.NewTablePermissionService, service.NewOpenApiAuthService, service.NewApplicationApiLogService, provideFeishuConfig, feishu.NewService)
+var ServiceSet = wire.NewSet(service.NewService, service.NewUserService, service.NewApprovalService, service.NewApprovalDefinitionService, service.NewApprovalNodeService, service.NewApprovalTaskService, service.NewTableService, service.NewTableFieldService, service.NewApplicationService, service.NewWebhookService, service.NewWebhookDeliveryService, service.NewCronService, service.NewCronLogService, service.NewCronParamService, service.NewEntityService, service.NewEntityLogService, service.NewEntityJobService, service.NewGlobalIdService, service.NewRoleService, service.NewPermissionService, service.NewNotificationService, service.NewNotificationTemplateService, service.NewNotificationLogService, service.NewTableApprovalDefinitionService, service.NewAutocodeService, service.NewUploadService, service.NewTablePermissionService, service.NewHierarchyService, service.NewMatchRuleService, service.NewTableFieldGroupService, service.NewTableRelationService, service.NewModelPackageService, service.NewOpenApiAuthService, service.NewApplicationApiLogService, provideFeishuConfig, feishu.NewService)
 
-var RepositorySet = wire.NewSet(repository.NewDB, repository.NewRedis, repository.NewRepository, repository.NewBaseRepository, repository.NewUserRepository, repository.NewApprovalRepository, repository.NewApprovalDefinitionRepository, repository.NewApprovalNodeRepository, repository.NewApprovalTaskRepository, repository.NewTableRepository, repository.NewTableFieldRepository, repository.NewApplicationRepository, repository.NewWebhookRepository, repository.NewWebhookDeliveryRepository, repository.NewCronRepository, repository.NewCronParamRepository, repository.NewCronLogRepository, repository.NewEntityRepository, repository.NewEntityLogRepository, repository.NewGlobalIdRepository, repository.NewRoleRepository, repository.NewPermissionRepository, repository.NewNotificationTemplateRepository, repository.NewNotificationLogRepository, repository.NewTableApprovalDefinitionRepository, repository.NewTablePermissionRepository, repository.NewUserRoleRepository, repository.NewApplicationApiLogRepository, repository.NewApplicationEntityRepository)
+var RepositorySet = wire.NewSet(repository.NewDB, repository.NewRedis, repository.NewRepository, repository.NewBaseRepository, repository.NewUserRepository, repository.NewApprovalRepository, repository.NewApprovalDefinitionRepository, repository.NewApprovalNodeRepository, repository.NewApprovalTaskRepository, repository.NewTableRepository, repository.NewTableFieldRepository, repository.NewApplicationRepository, repository.NewWebhookRepository, repository.NewWebhookDeliveryRepository, repository.NewCronRepository, repository.NewCronParamRepository, repository.NewCronLogRepository, repository.NewEntityRepository, repository.NewEntityLogRepository, repository.NewEntityJobRepository, repository.NewGlobalIdRepository, repository.NewRoleRepository, repository.NewPermissionRepository, repository.NewNotificationTemplateRepository, repository.NewNotificationLogRepository, repository.NewTableApprovalDefinitionRepository, repository.NewTablePermissionRepository, repository.NewUserRoleRepository, repository.NewHierarchyRepository, repository.NewMatchRuleRepository, repository.NewTableFieldGroupRepository, repository.NewTableRelationRepository, repository.NewModelPackageRepository, repository.NewEntityMergeRepository, repository.NewSchemaVersionRepository, repository.NewApplicationApiLogRepository, repository.NewApplicationEntityRepository)
 
 var CasbinSet = wire.NewSet(casbin.InitEnforcer)
 
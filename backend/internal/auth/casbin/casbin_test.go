@@ -0,0 +1,37 @@
+package casbin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gorm_sqlite "gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestInitEnforcer_SQLite(t *testing.T) {
+	db, err := gorm.Open(gorm_sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	for _, sql := range []string{
+		"CREATE TABLE roles (id integer PRIMARY KEY, code text)",
+		"CREATE TABLE user_roles (user_id integer, role_id integer)",
+		"CREATE TABLE permissions (id integer PRIMARY KEY, resource text, action text, status text)",
+		"CREATE TABLE role_permissions (role_id integer, permission_id integer)",
+		"INSERT INTO roles VALUES (1, 'editor')",
+		"INSERT INTO user_roles VALUES (7, 1)",
+		"INSERT INTO permissions VALUES (1, 'entity:customer', 'read', 'Normal'), (2, 'entity:customer', 'delete', 'Frozen')",
+		"INSERT INTO role_permissions VALUES (1, 1), (1, 2)",
+	} {
+		require.NoError(t, db.Exec(sql).Error)
+	}
+
+	e, err := InitEnforcer(db)
+	require.NoError(t, err)
+	ok, err := e.Enforce("7", "entity:customer", "read")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	// 冻结的权限不生效
+	ok, err = e.Enforce("7", "entity:customer", "delete")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
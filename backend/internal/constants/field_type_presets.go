@@ -11,7 +11,7 @@ type FieldPreset struct {
 	Label string `json:"label"`
 	// 分组
 	Group string `json:"group"`
-	// 数据类型: Text, Number, Date, Boolean
+	// 数据类型: Text, Number, Date, DateTime, JSON
 	DataType string `json:"dataType"`
 	// 字段长度（仅 string 类型）
 	Length int `json:"length,omitempty"`
@@ -317,30 +317,31 @@ var fieldTypePresets = map[string]*FieldPreset{
 			},
 		},
 	},
-	// "formula": {
-	// 	Label:    "公式",
-	// 	Group:    "advanced",
-	// 	DataType: "Text",
-	// 	Length:   255,
-	// 	UI: &model.FieldUI{
-	// 		Widget: "Input",
-	// 		WidgetProps: map[string]any{
-	// 			"disabled": true,
-	// 		},
-	// 	},
-	// },
-	// "json": {  // 暂时注释掉,以后实现
-	// 	Label:    "JSON",
-	// 	Group:    "advanced",
-	// 	DataType: "Text",
-	// 	Length:   2000,
-	// 	UI: &model.FieldUI{
-	// 		Widget: "Textarea",
-	// 		WidgetProps: map[string]any{
-	// 			"rows": 10,
-	// 		},
-	// 	},
-	// },
+	// 公式字段的数据类型由 options.formula.resultType 决定, 这里是默认的 Text
+	"formula": {
+		Label:    "公式",
+		Group:    "advanced",
+		DataType: "Text",
+		Length:   255,
+		UI: &model.FieldUI{
+			Widget: "Input",
+			WidgetProps: map[string]any{
+				"disabled": true,
+			},
+		},
+	},
+	// JSON 字段使用数据库原生 JSON 列, 可在 options.json.schema 中配置 JSON Schema
+	"json": {
+		Label:    "JSON",
+		Group:    "advanced",
+		DataType: "JSON",
+		UI: &model.FieldUI{
+			Widget: "Textarea",
+			WidgetProps: map[string]any{
+				"rows": 10,
+			},
+		},
+	},
 	"attachment": {
 		Label:    "附件",
 		Group:    "advanced",
@@ -408,6 +409,22 @@ func GetFieldPreset(fieldType string) (*FieldPreset, bool) {
 	return preset.Clone(), true
 }
 
+// IsRelationFieldType 判断是否为关系类型字段 (多对一、一对多、多对多), 需要校验引用完整性
+func IsRelationFieldType(fieldType string) bool {
+	preset, ok := fieldTypePresets[fieldType]
+	return ok && preset.Group == "relation"
+}
+
+// RelationFieldTypes 关系类型字段列表
+func RelationFieldTypes() []string {
+	for _, group := range fieldTypeGroups {
+		if group.Name == "relation" {
+			return append([]string(nil), group.Types...)
+		}
+	}
+	return nil
+}
+
 // GetAllFieldTypePresets 获取所有字段类型预设
 func GetAllFieldTypePresets() map[string]*FieldPreset {
 	result := make(map[string]*FieldPreset)
@@ -16,6 +16,7 @@ var SystemFieldCodes = []string{
 	"operation",
 	"action",
 	"send_status",
+	"version",
 
 	// Workflow Fields (draft table specific)
 	"entity_id",
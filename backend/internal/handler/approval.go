@@ -125,7 +125,8 @@ func (h *approvalHandler) ApproveTask(c *gin.Context) {
 	// h.logger.Debug("handler approval ApproveTask", "taskId", taskId)
 	// if err := h.approvalService.ApproveTask(c, taskId, params.Comment); err != nil {
 	if err := h.approvalService.ProcessTask(c, taskId, params.Action, params.Comment); err != nil {
-		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+		// 修改的数据已被其他人修改时返回 409
+		handleWriteError(c, http.StatusInternalServerError, err)
 		return
 	}
 	resp.HandleSuccess(c, gin.H{
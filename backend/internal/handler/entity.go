@@ -2,17 +2,23 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"piemdm/internal/model"
 	"piemdm/internal/service"
 	"piemdm/pkg/helper/resp"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/spf13/viper"
+	"gorm.io/gorm"
 )
 
 type EntityHandler interface {
@@ -28,12 +34,57 @@ type EntityHandler interface {
 	BatchUpdate(c *gin.Context)
 	BatchDelete(c *gin.Context)
 
+	// 引用完整性
+	WhereUsed(c *gin.Context) // 引用指定记录的数据
+
+	// 关联视图
+	ListRelations(c *gin.Context) // 记录的全部关联视图及第一页关联记录
+	ListRelated(c *gin.Context)   // 分页查询一个关联视图中的关联记录
+
+	// 历史版本
+	Rollback(c *gin.Context) // 回滚到指定时间点或变更记录之后的版本
+
+	// 回收站
+	ListTrash(c *gin.Context) // 已删除的记录
+	Restore(c *gin.Context)   // 恢复已删除的记录
+	Purge(c *gin.Context)     // 彻底删除超过保留时长的记录 (管理员)
+
+	// 生命周期操作: 冻结、解冻、锁定、解锁、作废、扩展
+	Lifecycle(operation string) gin.HandlerFunc
+
+	// 树形结构
+	Move(c *gin.Context)        // 移动节点及其子树
+	Ancestors(c *gin.Context)   // 祖先节点
+	Descendants(c *gin.Context) // 后代节点
+	Siblings(c *gin.Context)    // 兄弟节点
+	Tree(c *gin.Context)        // 树形数据
+
+	// 单据 (抬头和行项目)
+	GetDocument(c *gin.Context)    // 查询单据
+	CreateDocument(c *gin.Context) // 新增单据
+	UpdateDocument(c *gin.Context) // 修改单据
+
+	// 查重
+	CheckDuplicates(c *gin.Context)        // 保存前查找疑似重复的记录
+	ScanDuplicates(c *gin.Context)         // 按匹配规则扫描全表
+	ListDuplicateClusters(c *gin.Context)  // 疑似重复分组
+	GetDuplicateCluster(c *gin.Context)    // 疑似重复分组及成员记录
+	ReviewDuplicateCluster(c *gin.Context) // 确认或排除疑似重复分组
+
+	// 合并
+	PreviewMerge(c *gin.Context) // 预览合并结果
+	Merge(c *gin.Context)        // 合并记录
+	ListMerges(c *gin.Context)   // 合并记录列表
+	GetMerge(c *gin.Context)     // 合并详情
+	Unmerge(c *gin.Context)      // 取消合并
+
 	// 历史与日志
 	ListEntityHistories(c *gin.Context) // get entity from *draft table
 	ListEntityLogs(c *gin.Context)      // get entity from *log table
 
 	// 辅助功能
 	Import(ctx *gin.Context)
+	GetJob(c *gin.Context)
 	Export(c *gin.Context)
 	Template(c *gin.Context)
 	// 其他
@@ -63,49 +114,43 @@ func NewEntityHandler(handler *Handler, entityService service.EntityService, tab
 }
 
 func (h *entityHandler) List(c *gin.Context) {
-	// page=1&pageSize=15&...
-	// get from  table field from api /table_field/find
+	// page=1&pageSize=15&filter={...}&sort=-created_at&fields=code,name&as_of=2024-01-01 12:00:00&...
+	// 字段均按模型已发布的 TableField 白名单校验, as_of 按指定时间点的数据查询
 	page, pageSize := GetPage(c)
-	where := map[string]any{}
 	var total int64
 
-	entityID := c.Query("entity_id")
 	tableCode := c.Query("table_code")
-	dictionaryClass := c.Query("dict_code")
-	isDraft := c.Query("is_draft")
-	approvalCode := c.Query("approval_code")
-
-	if dictionaryClass != "" {
-		where["dict_code"] = dictionaryClass
+	if tableCode == "" {
+		tableCode = c.Param("table_code")
 	}
-	if entityID != "" {
-		where["entity_id"] = entityID
+	if c.Query("is_draft") != "" {
+		tableCode = fmt.Sprintf("%s_draft", tableCode)
 	}
-	if approvalCode != "" {
-		where["approval_code"] = approvalCode
+
+	// 控制参数不作为查询条件
+	query, err := model.ParseEntityQuery(c.Request.URL.Query(), "page", "pageSize", "table_code", "is_draft", "as_of")
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
 	}
-	if isDraft != "" {
-		tableCode = fmt.Sprintf("%s_draft", tableCode)
+	asOf, hasAsOf, err := parseAsOf(c.Query("as_of"))
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
 	}
 
-	for k, v := range c.Request.URL.Query() {
-		// v[0] not enpty then use condition
-		if v[0] != "" {
-			// replace fullwidth character
-			str := strings.ReplaceAll(v[0], "，", ",")
-			where[k] = strings.TrimSpace(str)
+	var entities []map[string]any
+	if hasAsOf {
+		if c.Query("is_draft") != "" {
+			resp.HandleError(c, http.StatusBadRequest, "草稿不支持按时间点查询", nil)
+			return
 		}
+		entities, err = h.entityService.ListAsOf(c, tableCode, page, pageSize, &total, query, asOf)
+	} else {
+		entities, err = h.entityService.List(c, tableCode, page, pageSize, &total, query)
 	}
-
-	// delete control parameter
-	delete(where, "page")
-	delete(where, "pageSize")
-	delete(where, "table_code")
-	delete(where, "is_draft")
-
-	entities, err := h.entityService.List(c, tableCode, page, pageSize, &total, where)
 	if err != nil {
-		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+		handleQueryError(c, err)
 		return
 	}
 
@@ -137,16 +182,69 @@ func (h *entityHandler) Get(c *gin.Context) {
 		return
 	}
 
-	entity, err := h.entityService.Get(c, tableCode, params.ID)
+	asOf, hasAsOf, err := parseAsOf(c.Query("as_of"))
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	var entity map[string]any
+	if hasAsOf {
+		// 指定时间点的数据, 不返回 ETag
+		entity, err = h.entityService.GetAsOf(c, tableCode, params.ID, asOf)
+	} else {
+		entity, err = h.entityService.Get(c, tableCode, params.ID)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			resp.HandleError(c, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	// 版本号作为 ETag 返回, 修改时通过 If-Match 提交
+	if version, ok := entity["version"]; ok && version != nil {
+		c.Header("ETag", fmt.Sprintf(`"%v"`, version))
+	}
+
+	// 按字段组排列字段, 当前用户不能查看的字段组中的字段和值不返回
+	layout, err := h.tableFieldService.GetTableFields(c, tableCode)
 	if err != nil {
 		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
 		return
 	}
+	order := make(map[string]int, len(layout))
+	for index, field := range layout {
+		order[field.Code] = index
+	}
+	visibleFields := make([]*model.TableField, 0, len(tableFields))
+	for _, field := range tableFields {
+		if _, ok := order[field.Code]; ok {
+			visibleFields = append(visibleFields, field)
+		} else {
+			delete(entity, field.Code)
+		}
+	}
+	sort.SliceStable(visibleFields, func(i, j int) bool {
+		return order[visibleFields[i].Code] < order[visibleFields[j].Code]
+	})
 
-	resp.HandleSuccess(c, gin.H{
+	data := gin.H{
 		"info":        entity,
-		"tableFields": tableFields,
-	})
+		"tableFields": visibleFields,
+		"fields":      layout,
+	}
+	// relations=true 时同时返回关联视图及每个视图第一页关联记录
+	if withRelations, _ := strconv.ParseBool(c.Query("relations")); withRelations {
+		_, pageSize := GetPage(c)
+		relations, err := h.entityService.ListRelations(c, tableCode, params.ID, pageSize)
+		if err != nil {
+			resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+			return
+		}
+		data["relations"] = relations
+	}
+	resp.HandleSuccess(c, data)
 }
 
 func (h *entityHandler) Create(c *gin.Context) {
@@ -171,6 +269,12 @@ func (h *entityHandler) Create(c *gin.Context) {
 	reason := req["reason"].(string)
 	delete(req, "reason")
 
+	// 确认忽略 Warn 匹配规则发现的疑似重复
+	if ignore, ok := req["ignore_duplicates"].(bool); ok {
+		c.Set("ignore_duplicates", ignore)
+	}
+	delete(req, "ignore_duplicates")
+
 	h.logger.Debug("handler-entity-CreateEntity", "req", req)
 
 	// 判断是否关联审批流程，如果有审批流程则提交审批, 没有审批流程, 则直接保存数据
@@ -183,12 +287,12 @@ func (h *entityHandler) Create(c *gin.Context) {
 	// 如果有审批流程则提交审批, 没有审批流程, 则直接保存数据
 	if len(tableApprovalDefs) > 0 {
 		if err := h.entityService.CreateDraft(c, tableCode, reason, req); err != nil {
-			resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+			handleWriteError(c, http.StatusBadRequest, err)
 			return
 		}
 	} else {
 		if err := h.entityService.Create(c, tableCode, req); err != nil {
-			resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+			handleWriteError(c, http.StatusBadRequest, err)
 			return
 		}
 	}
@@ -231,6 +335,26 @@ func (h *entityHandler) Update(c *gin.Context) {
 
 	formMap["id"] = uint(idInt64)
 	formMap["table_code"] = tableCode
+
+	// 乐观锁版本号: If-Match 请求头优先, 其次为请求体中的 version
+	version, ok, err := ifMatchVersion(c)
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if !ok && formMap["version"] != nil {
+		n, err := strconv.ParseUint(fmt.Sprint(formMap["version"]), 10, 64)
+		if err != nil {
+			resp.HandleError(c, http.StatusBadRequest, "version 不是合法的版本号", nil)
+			return
+		}
+		version, ok = uint(n), true
+	}
+	if ok {
+		formMap["version"] = version
+	} else {
+		delete(formMap, "version")
+	}
 	// delete(formMap, "table_code")
 	delete(formMap, "reason")
 
@@ -258,7 +382,7 @@ func (h *entityHandler) Update(c *gin.Context) {
 
 	if err := h.entityService.UpdateDraft(c, tableCode, reason, formMap); err != nil {
 		h.logger.Debug("error", "error", err.Error())
-		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		handleWriteError(c, http.StatusBadRequest, err)
 		return
 	}
 
@@ -276,6 +400,8 @@ func (h *entityHandler) BatchCreate(c *gin.Context) {
 func (h *entityHandler) BatchUpdate(c *gin.Context) {
 	var params struct {
 		IDs []uint `form:"ids" json:"ids"`
+		// id 对应的版本号, 提交时按版本修改
+		Versions map[uint]uint `form:"versions" json:"versions"`
 		// 未上传字段
 		Status    string `form:"status" json:"status"`
 		TableCode string `form:"table_code" json:"table_code"`
@@ -296,9 +422,9 @@ func (h *entityHandler) BatchUpdate(c *gin.Context) {
 	// params.Reason = "change status..."
 	h.logger.Info("service BatchUpdateEntities", "params", params)
 
-	err := h.entityService.BatchUpdate(c, params.TableCode, params.Reason, params.IDs, entityMap)
+	err := h.entityService.BatchUpdate(c, params.TableCode, params.Reason, params.IDs, params.Versions, entityMap)
 	if err != nil {
-		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+		handleWriteError(c, http.StatusInternalServerError, err)
 		return
 	}
 	resp.HandleSuccess(c, gin.H{
@@ -322,7 +448,7 @@ func (h *entityHandler) BatchDelete(c *gin.Context) {
 	reason := params.Reason
 	err := h.entityService.BatchDelete(c, tableCode, reason, params.IDs)
 	if err != nil {
-		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+		handleWriteError(c, http.StatusInternalServerError, err)
 		return
 	}
 	resp.HandleSuccess(c, gin.H{
@@ -330,205 +456,1034 @@ func (h *entityHandler) BatchDelete(c *gin.Context) {
 	})
 }
 
-func (h *entityHandler) ListEntityLogs(c *gin.Context) {
-	// page=1&pageSize=15&...
-	// get from  table field from api /table_field/find
-	page, pageSize := GetPage(c)
-	where := map[string]any{}
-	var total int64
+// Lifecycle 生成生命周期操作的处理函数
+// 单条操作的路径带 :id, 版本号取 If-Match 请求头或请求体中的 version; 批量操作的请求体为 {ids, versions, reason}
+func (h *entityHandler) Lifecycle(operation string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			IDs      []uint        `json:"ids"`
+			Versions map[uint]uint `json:"versions"`
+			Version  *uint         `json:"version"`
+			Reason   string        `json:"reason"`
+		}
+		// 单条操作可以不带请求体
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
 
-	entityID := c.Query("entity_id")
-	tableCode := c.Query("table_code")
-	if entityID != "" {
-		where["entity_id"] = entityID
-	}
-	logTableCode := fmt.Sprintf("%s_log", tableCode)
-	h.logger.Debug("handler-entity-ListEntities", "logTableCode", logTableCode)
+		ids, versions := req.IDs, req.Versions
+		if param := c.Param("id"); param != "" {
+			id, err := strconv.ParseUint(param, 10, 64)
+			if err != nil {
+				resp.HandleError(c, http.StatusBadRequest, "id 不是合法的记录ID", nil)
+				return
+			}
+			version, ok, err := ifMatchVersion(c)
+			if err != nil {
+				resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+				return
+			}
+			if !ok && req.Version != nil {
+				version, ok = *req.Version, true
+			}
+			ids, versions = []uint{uint(id)}, nil
+			if ok {
+				versions = map[uint]uint{uint(id): version}
+			}
+		}
+		if len(ids) == 0 {
+			resp.HandleError(c, http.StatusBadRequest, "ids is required", nil)
+			return
+		}
 
-	for k, v := range c.Request.URL.Query() {
-		if v[0] != "" {
-			str := strings.Replace(v[0], "，", ",", -1)
-			where[k] = strings.TrimSpace(str)
+		if err := h.entityService.ChangeStatus(c, c.Param("table_code"), operation, req.Reason, ids, versions); err != nil {
+			handleWriteError(c, http.StatusBadRequest, err)
+			return
 		}
+		resp.HandleSuccess(c, nil)
 	}
+}
 
-	// delete control parameter
-	delete(where, "page")
-	delete(where, "pageSize")
-	delete(where, "table_code")
-
-	// where["table_code"] = tableCode
-	entities, err := h.entityService.List(c, logTableCode, page, pageSize, &total, where)
+// Move 移动节点及其子树到新的父节点下, 请求体为 {parent_id, version, reason}, parent_id 为空或 0 时移动为根节点
+// 版本号取 If-Match 请求头或请求体中的 version
+func (h *entityHandler) Move(c *gin.Context) {
+	var params struct {
+		ID        uint   `uri:"id" binding:"required"`
+		TableCode string `uri:"table_code" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&params); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	var req struct {
+		ParentID uint   `json:"parent_id"`
+		Version  *uint  `json:"version"`
+		Reason   string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	version, ok, err := ifMatchVersion(c)
 	if err != nil {
-		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-
-	links := resp.GeneratePaginationLinks(c.Request, page, pageSize, int(total))
-	c.Header("Link", links.String())
-
-	resp.HandleSuccess(c, entities)
-}
-
-func (h *entityHandler) ListEntityHistories(c *gin.Context) {
-	// page=1&pageSize=15&...
-	// get from  table field from api /table_field/find
-	page, pageSize := GetPage(c)
-	where := map[string]any{}
-	var total int64
-
-	entityID := c.Query("entity_id")
-	code := c.Query("code")
-	tableCode := c.Query("table_code")
-	if entityID != "" {
-		where["entity_id"] = entityID
+	if !ok && req.Version != nil {
+		version, ok = *req.Version, true
 	}
-	if code != "" {
-		where["code"] = code
+	var versions map[uint]uint
+	if ok {
+		versions = map[uint]uint{params.ID: version}
 	}
-	tableCode = fmt.Sprintf("%s_draft", tableCode)
-
-	// Permission check for table_code
-	if tableCode != "" {
-		userIDStr := c.GetString("user_id")
-		if userIDStr != "" {
-			userID, err := strconv.ParseUint(userIDStr, 10, 32)
-			if err != nil {
-				h.logger.Error("Failed to parse user_id", "user_id", userIDStr, "error", err)
-				resp.HandleError(c, http.StatusInternalServerError, "Invalid user ID", nil)
-				return
-			}
 
-			hasPermission, err := h.tablePermissionService.CheckTablePermission(c, uint(userID), tableCode)
-			if err != nil {
-				h.logger.Error("Failed to check table permission", "error", err)
-				resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
-				return
-			}
+	if err := h.entityService.Move(c, params.TableCode, req.Reason, params.ID, req.ParentID, versions); err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
 
-			if !hasPermission {
-				h.logger.Warn("User has no permission to access table", "user_id", userID, "table_code", tableCode)
-				resp.HandleError(c, http.StatusForbidden, "No permission to access this table", nil)
-				return
-			}
+// treeParams 层级查询的路径参数和 depth 查询参数
+func treeParams(c *gin.Context) (tableCode string, id uint, depth int, err error) {
+	if param := c.Param("id"); param != "" {
+		n, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return "", 0, 0, fmt.Errorf("id 不是合法的记录ID")
 		}
+		id = uint(n)
 	}
+	if param := c.Query("depth"); param != "" {
+		if depth, err = strconv.Atoi(param); err != nil {
+			return "", 0, 0, fmt.Errorf("depth 必须是整数")
+		}
+	}
+	return c.Param("table_code"), id, depth, nil
+}
 
-	// delete control parameter
-	delete(where, "page")
-	delete(where, "pageSize")
-	delete(where, "table_code")
-
-	selectString := "id,code,name,entity_id,updated_at"
-	entities, err := h.entityService.Find(c, tableCode, selectString, where)
+// Ancestors 查询节点的祖先, 从根节点到父节点排列
+func (h *entityHandler) Ancestors(c *gin.Context) {
+	tableCode, id, _, err := treeParams(c)
 	if err != nil {
-		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-
-	links := resp.GeneratePaginationLinks(c.Request, page, pageSize, int(total))
-	c.Header("Link", links.String())
-
-	resp.HandleSuccess(c, entities)
+	rows, err := h.entityService.Ancestors(c, tableCode, id)
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+	resp.HandleSuccess(c, rows)
 }
 
-func (h *entityHandler) Import(c *gin.Context) {
-	file, err := c.FormFile("file") // 获取上传的文件
+// Descendants 查询节点的后代, depth 限制层数, 不传时返回全部后代
+func (h *entityHandler) Descendants(c *gin.Context) {
+	tableCode, id, depth, err := treeParams(c)
 	if err != nil {
 		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	reader, err := file.Open() // 打开文件，获取 io.Reader
+	rows, err := h.entityService.Descendants(c, tableCode, id, depth)
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+	resp.HandleSuccess(c, rows)
+}
 
-	// file, err := c.Request.MultipartForm.File["file"][0].Open()
-	tableCode := c.PostForm("table_code")
-	operation := c.PostForm("operation")
-	reason := c.PostForm("reason")
-	if err != nil || tableCode == "" || operation == "" {
+// Siblings 查询与节点同一父节点的其他节点
+func (h *entityHandler) Siblings(c *gin.Context) {
+	tableCode, id, _, err := treeParams(c)
+	if err != nil {
 		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
+	rows, err := h.entityService.Siblings(c, tableCode, id)
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+	resp.HandleSuccess(c, rows)
+}
 
-	h.logger.Debug("h Import: ",
-		"tableCode", tableCode,
-		"reason", reason,
-		"operation", operation)
-	if err = h.entityService.Import(c, tableCode, reason, operation, reader); err != nil {
-		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+// Tree 查询树形数据, 子节点放在 children 中; root 指定起始节点, 不传时返回全部根节点; depth 限制层数
+func (h *entityHandler) Tree(c *gin.Context) {
+	tableCode, _, depth, err := treeParams(c)
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	var root uint64
+	if param := c.Query("root"); param != "" {
+		if root, err = strconv.ParseUint(param, 10, 64); err != nil {
+			resp.HandleError(c, http.StatusBadRequest, "root 不是合法的记录ID", nil)
+			return
+		}
+	}
+	rows, err := h.entityService.Tree(c, tableCode, uint(root), depth)
+	if err != nil {
+		handleQueryError(c, err)
 		return
 	}
+	resp.HandleSuccess(c, rows)
+}
 
-	resp.HandleSuccess(c, nil)
+// documentRequest 单据请求体: 抬头、按行项目表分组的行项目和原因
+type documentRequest struct {
+	Header map[string]any              `json:"header"`
+	Items  map[string][]map[string]any `json:"items"`
+	Reason string                      `json:"reason"`
 }
 
-func (h *entityHandler) Export(c *gin.Context) {
-	// 可以使用 c.QueryMap("ids") 接收query array
-	// var params struct {
-	// 	TableCode  string                 `form:"table_code" json:"table_code"`
-	// 	Filter     string                 `form:"filter" json:"filter"`
-	// 	Ids        string                `form:"ids" json:"ids"`
-	// 	SearchData map[string]any `form:"searchData" json:"searchData"`
-	// }
-	// if err := c.ShouldBindQuery(&params); err != nil {
-	// 	resp.HandleError(c, http.StatusBadRequest,  err.Error(), nil)
-	// 	return
-	// }
-
-	// tableCode := params.TableCode
-	// filter := params.Filter
-	tableCode := c.Query("table_code")
-	filter := c.Query("filter")
-	ids := c.Query("ids")
-	where := make(map[string]any)
-	switch filter {
-	case "selected":
-		where["id in"] = ids
-	case "filtered":
-		// 根据查询条件下载的时候需要把参数收集起来
-		for k, v := range c.Request.URL.Query() {
-			if v[0] != "" {
-				where[k] = v[0]
+// bindDocument 读取单据请求体, 与修改接口一致, 数组类型的字段序列化为 JSON 字符串
+func bindDocument(c *gin.Context) (*service.EntityDocument, string, error) {
+	var req documentRequest
+	binding.EnableDecoderUseNumber = true
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return nil, "", err
+	}
+	if req.Reason == "" {
+		return nil, "", fmt.Errorf("reason is required")
+	}
+	if req.Header == nil {
+		return nil, "", fmt.Errorf("header is required")
+	}
+	records := []map[string]any{req.Header}
+	for _, lines := range req.Items {
+		records = append(records, lines...)
+	}
+	for _, record := range records {
+		for key, value := range record {
+			if arr, ok := value.([]any); ok {
+				data, err := json.Marshal(arr)
+				if err != nil {
+					return nil, "", fmt.Errorf("%s: %v", key, err)
+				}
+				record[key] = string(data)
 			}
 		}
-		delete(where, "ids")
-	default:
-		// all
 	}
+	return &service.EntityDocument{Header: req.Header, Items: req.Items}, req.Reason, nil
+}
 
-	delete(where, "table_code")
-	delete(where, "filter")
+// GetDocument 查询单据: 抬头及其全部行项目
+func (h *entityHandler) GetDocument(c *gin.Context) {
+	var params struct {
+		ID        uint   `uri:"id" binding:"required"`
+		TableCode string `uri:"table_code" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&params); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	doc, err := h.entityService.GetDocument(c, params.TableCode, params.ID)
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+	resp.HandleSuccess(c, doc)
+}
 
-	filename, err := h.entityService.Export(c, tableCode, filter, where)
+// CreateDocument 新增单据: 抬头和行项目在同一事务中写入, 配置了审批流程时提交一个审批
+func (h *entityHandler) CreateDocument(c *gin.Context) {
+	doc, reason, err := bindDocument(c)
 	if err != nil {
 		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	path := h.conf.GetString("app.export-save-path")
-	fullURL := h.conf.GetString("app.prefix-url") + "/" + path + filename
-	resp.HandleSuccess(c, gin.H{
-		"name":            filename,
-		"export_url":      fullURL,
-		"export_save_url": path,
-	})
+	delete(doc.Header, "id")
+	id, err := h.entityService.SaveDocument(c, c.Param("table_code"), reason, doc)
+	if err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, gin.H{"id": id})
 }
 
-func (h *entityHandler) Template(c *gin.Context) {
-	// 可以使用 c.QueryMap("ids") 接收query array
+// UpdateDocument 修改单据: 提交的行项目表整体替换, 未提交的行项目表不变
+// 抬头版本号: If-Match 请求头优先, 其次为抬头中的 version
+func (h *entityHandler) UpdateDocument(c *gin.Context) {
 	var params struct {
-		TableCode string `form:"table_code" json:"table_code"`
-		Operation string `form:"operation" json:"operation"`
+		ID        uint   `uri:"id" binding:"required"`
+		TableCode string `uri:"table_code" binding:"required"`
 	}
-	if err := c.ShouldBindQuery(&params); err != nil {
+	if err := c.ShouldBindUri(&params); err != nil {
 		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-
-	filename, err := h.entityService.Template(c, params.TableCode, params.Operation)
+	doc, reason, err := bindDocument(c)
 	if err != nil {
 		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	path := h.conf.GetString("app.export-save-path")
+	version, ok, err := ifMatchVersion(c)
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	doc.Header["id"] = params.ID
+	if ok {
+		doc.Header["version"] = version
+	}
+	if _, err := h.entityService.SaveDocument(c, params.TableCode, reason, doc); err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, gin.H{"id": params.ID})
+}
+
+// CheckDuplicates 按表的匹配规则查找与提交的数据疑似重复的记录, 不保存数据
+// 提交 id 时 (修改已有记录) 结果中不含该记录
+func (h *entityHandler) CheckDuplicates(c *gin.Context) {
+	req := map[string]any{}
+	binding.EnableDecoderUseNumber = true
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if id, ok := req["id"].(json.Number); ok {
+		n, err := id.Int64()
+		if err != nil || n < 0 {
+			resp.HandleError(c, http.StatusBadRequest, "id 不是合法的记录ID", nil)
+			return
+		}
+		req["id"] = uint(n)
+	}
+	candidates, err := h.entityService.CheckDuplicates(c, c.Param("table_code"), req)
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+	resp.HandleSuccess(c, candidates)
+}
+
+// ScanDuplicates 创建后台查重任务, 按 rule 指定的匹配规则扫描全表, 立即返回任务编号
+func (h *entityHandler) ScanDuplicates(c *gin.Context) {
+	var req struct {
+		Rule string `json:"rule" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	job, err := h.entityService.ScanDuplicates(c, c.Param("table_code"), req.Rule)
+	if err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, h.jobResponse(job))
+}
+
+// ListDuplicateClusters 分页查询疑似重复分组, 可按 status、rule 过滤, 按相似度从高到低排列
+func (h *entityHandler) ListDuplicateClusters(c *gin.Context) {
+	page, pageSize := GetPage(c)
+	var total int64
+
+	where := make(map[string]any)
+	if status := c.Query("status"); status != "" {
+		where["status"] = status
+	}
+	if rule := c.Query("rule"); rule != "" {
+		where["rule_code"] = rule
+	}
+	clusters, err := h.entityService.ListDuplicateClusters(c, c.Param("table_code"), page, pageSize, &total, where)
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+
+	links := resp.GeneratePaginationLinks(c.Request, page, pageSize, int(total))
+	c.Header("Link", links.String())
+
+	resp.HandleSuccess(c, clusters)
+}
+
+// GetDuplicateCluster 查询疑似重复分组及其成员记录
+func (h *entityHandler) GetDuplicateCluster(c *gin.Context) {
+	var params struct {
+		ID        uint   `uri:"id" binding:"required"`
+		TableCode string `uri:"table_code" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&params); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	cluster, err := h.entityService.GetDuplicateCluster(c, params.TableCode, params.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			resp.HandleError(c, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		handleQueryError(c, err)
+		return
+	}
+	resp.HandleSuccess(c, cluster)
+}
+
+// ReviewDuplicateCluster 确认 (Confirmed) 或排除 (Dismissed) 疑似重复分组
+// 排除的分组在重新扫描时不再生成
+func (h *entityHandler) ReviewDuplicateCluster(c *gin.Context) {
+	var params struct {
+		ID        uint   `uri:"id" binding:"required"`
+		TableCode string `uri:"table_code" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&params); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	var req struct {
+		Status string `json:"status" binding:"required,oneof=Confirmed Dismissed"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err := h.entityService.ReviewDuplicateCluster(c, params.TableCode, params.ID, req.Status); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			resp.HandleError(c, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
+
+// mergeRequest 合并和预览的请求参数
+type mergeRequest struct {
+	service.MergeRequest
+	Reason string `json:"reason"`
+}
+
+// PreviewMerge 按保留规则预览合并后的主记录和需要改为引用主记录的数据, 不修改数据
+func (h *entityHandler) PreviewMerge(c *gin.Context) {
+	var req mergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	preview, err := h.entityService.PreviewMerge(c, c.Param("table_code"), &req.MergeRequest)
+	if err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, preview)
+}
+
+// Merge 合并记录: 表配置了合并审批流程时提交审批, 返回的合并状态为 Pending; 否则直接合并
+func (h *entityHandler) Merge(c *gin.Context) {
+	var req mergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	merge, err := h.entityService.Merge(c, c.Param("table_code"), req.Reason, &req.MergeRequest)
+	if err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, merge)
+}
+
+// ListMerges 分页查询合并记录, 可按 status、survivor_id 过滤
+func (h *entityHandler) ListMerges(c *gin.Context) {
+	page, pageSize := GetPage(c)
+	var total int64
+
+	where := make(map[string]any)
+	if status := c.Query("status"); status != "" {
+		where["status"] = status
+	}
+	if survivorID, err := strconv.ParseUint(c.Query("survivor_id"), 10, 64); err == nil {
+		where["survivor_id"] = survivorID
+	}
+	merges, err := h.entityService.ListMerges(c, c.Param("table_code"), page, pageSize, &total, where)
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+
+	links := resp.GeneratePaginationLinks(c.Request, page, pageSize, int(total))
+	c.Header("Link", links.String())
+
+	resp.HandleSuccess(c, merges)
+}
+
+// GetMerge 查询合并记录
+func (h *entityHandler) GetMerge(c *gin.Context) {
+	var params struct {
+		ID        uint   `uri:"id" binding:"required"`
+		TableCode string `uri:"table_code" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&params); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	merge, err := h.entityService.GetMerge(c, params.TableCode, params.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			resp.HandleError(c, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		handleQueryError(c, err)
+		return
+	}
+	resp.HandleSuccess(c, merge)
+}
+
+// Unmerge 取消合并, 恢复主记录、被合并记录和引用; 合并后又被修改过的值保留当前值
+func (h *entityHandler) Unmerge(c *gin.Context) {
+	var params struct {
+		ID        uint   `uri:"id" binding:"required"`
+		TableCode string `uri:"table_code" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&params); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err := h.entityService.Unmerge(c, params.TableCode, req.Reason, params.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			resp.HandleError(c, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
+
+// WhereUsed 查询引用指定记录的数据, 按引用表和关系字段分组
+func (h *entityHandler) WhereUsed(c *gin.Context) {
+	var params struct {
+		ID        uint   `uri:"id" binding:"required"`
+		TableCode string `uri:"table_code" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&params); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	references, err := h.entityService.WhereUsed(c, params.TableCode, params.ID)
+	if err != nil {
+		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	if references == nil {
+		references = []*service.EntityReference{}
+	}
+	resp.HandleSuccess(c, references)
+}
+
+// ListRelations 查询记录的全部关联视图, 每个视图返回第一页关联记录
+// 当前用户没有权限访问的关联模型不返回
+func (h *entityHandler) ListRelations(c *gin.Context) {
+	var params struct {
+		ID        uint   `uri:"id" binding:"required"`
+		TableCode string `uri:"table_code" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&params); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	_, pageSize := GetPage(c)
+	relations, err := h.entityService.ListRelations(c, params.TableCode, params.ID, pageSize)
+	if err != nil {
+		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, relations)
+}
+
+// ListRelated 分页查询记录在一个关联视图中的关联记录
+// 过滤、排序和字段选择参数与列表相同, 作用于关联模型
+func (h *entityHandler) ListRelated(c *gin.Context) {
+	var params struct {
+		ID         uint   `uri:"id" binding:"required"`
+		TableCode  string `uri:"table_code" binding:"required"`
+		RelationID uint   `uri:"relation_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&params); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	page, pageSize := GetPage(c)
+	query, err := model.ParseEntityQuery(c.Request.URL.Query(), "page", "pageSize")
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	var total int64
+	records, err := h.entityService.ListRelated(c, params.TableCode, params.ID, params.RelationID, page, pageSize, &total, query)
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+
+	links := resp.GeneratePaginationLinks(c.Request, page, pageSize, int(total))
+	c.Header("Link", links.String())
+
+	resp.HandleSuccess(c, records)
+}
+
+// Rollback 将记录回滚到指定时间点 (as_of) 或指定变更记录 (log_id) 之后的版本
+// 按修改操作处理: 表配置了修改审批流程时提交审批, 否则直接修改
+func (h *entityHandler) Rollback(c *gin.Context) {
+	var params struct {
+		ID        uint   `uri:"id" binding:"required"`
+		TableCode string `uri:"table_code" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&params); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	var req struct {
+		AsOf   string `json:"as_of"`
+		LogID  uint   `json:"log_id"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	asOf, hasAsOf, err := parseAsOf(req.AsOf)
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if !hasAsOf && req.LogID == 0 {
+		resp.HandleError(c, http.StatusBadRequest, "as_of or log_id is required", nil)
+		return
+	}
+
+	target := service.RollbackTarget{AsOf: asOf, LogID: req.LogID}
+	if err := h.entityService.Rollback(c, params.TableCode, req.Reason, params.ID, target); err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
+
+// ListTrash 查询回收站, 查询参数同 List, 另可按 deleted_at、deleted_by、delete_reason 过滤和排序
+func (h *entityHandler) ListTrash(c *gin.Context) {
+	page, pageSize := GetPage(c)
+	var total int64
+
+	query, err := model.ParseEntityQuery(c.Request.URL.Query(), "page", "pageSize", "table_code")
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	entities, err := h.entityService.ListTrash(c, c.Param("table_code"), page, pageSize, &total, query)
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+
+	links := resp.GeneratePaginationLinks(c.Request, page, pageSize, int(total))
+	c.Header("Link", links.String())
+
+	resp.HandleSuccess(c, entities)
+}
+
+// trashRequest 恢复和彻底删除的请求参数
+type trashRequest struct {
+	IDs    []uint `json:"ids"`
+	Reason string `json:"reason"`
+}
+
+// Restore 从回收站恢复记录
+func (h *entityHandler) Restore(c *gin.Context) {
+	var req trashRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if len(req.IDs) == 0 {
+		resp.HandleError(c, http.StatusBadRequest, "ids is required", nil)
+		return
+	}
+	if err := h.entityService.Restore(c, c.Param("table_code"), req.Reason, req.IDs); err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
+
+// Purge 彻底删除回收站中超过保留时长的记录, 不指定 ids 时清理全部过期记录
+func (h *entityHandler) Purge(c *gin.Context) {
+	var req trashRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	purged, err := h.entityService.Purge(c, c.Param("table_code"), req.Reason, req.IDs)
+	if err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, gin.H{"purged": purged})
+}
+
+// parseAsOf 解析时间点参数, 支持 RFC3339、2006-01-02 15:04:05 和 2006-01-02 (当天结束时), 未指定时返回 false
+func parseAsOf(value string) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.Local(), true, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t.Add(24*time.Hour - time.Second), true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("as_of 时间格式不正确, 应为 2006-01-02 15:04:05、2006-01-02 或 RFC3339: %s", value)
+}
+
+func (h *entityHandler) ListEntityLogs(c *gin.Context) {
+	// page=1&pageSize=15&entity_id=1&...
+	page, pageSize := GetPage(c)
+	var total int64
+
+	tableCode := c.Query("table_code")
+	if tableCode == "" {
+		tableCode = c.Param("table_code")
+	}
+	logTableCode := fmt.Sprintf("%s_log", tableCode)
+	h.logger.Debug("handler-entity-ListEntities", "logTableCode", logTableCode)
+
+	// 控制参数和快捷过滤参数不作为查询条件
+	params := c.Request.URL.Query()
+	query, err := model.ParseEntityQuery(params, "page", "pageSize", "table_code", "field", "user", "from", "to")
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if filters := entityLogFilters(params); len(filters) > 0 {
+		if query.Filter != nil {
+			filters = append(filters, query.Filter)
+		}
+		query.Filter = &model.EntityFilter{And: filters}
+	}
+
+	entities, err := h.entityService.List(c, logTableCode, page, pageSize, &total, query)
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+	// 字段变化以 JSON 返回
+	for _, entity := range entities {
+		if changes, ok := entity["changes"].(string); ok && json.Valid([]byte(changes)) {
+			entity["changes"] = json.RawMessage(changes)
+		}
+	}
+
+	links := resp.GeneratePaginationLinks(c.Request, page, pageSize, int(total))
+	c.Header("Link", links.String())
+
+	resp.HandleSuccess(c, entities)
+}
+
+// entityLogFilters 变更历史的快捷过滤条件
+// field: 修改了指定字段 (含旧版按字段记录的日志); user: 修改人; from / to: 修改时间范围, 只有日期时 to 包含当天
+func entityLogFilters(params map[string][]string) []*model.EntityFilter {
+	get := func(key string) string {
+		if values := params[key]; len(values) > 0 {
+			return strings.TrimSpace(values[0])
+		}
+		return ""
+	}
+
+	var filters []*model.EntityFilter
+	if field := get("field"); field != "" {
+		filters = append(filters, &model.EntityFilter{Or: []*model.EntityFilter{
+			{Field: "changes." + field, Op: model.QueryOpNotNull},
+			{Field: "field_code", Op: model.QueryOpEq, Value: field},
+			{Field: "field_code", Op: model.QueryOpStartsWith, Value: field + "."},
+		}})
+	}
+	if user := get("user"); user != "" {
+		filters = append(filters, &model.EntityFilter{Field: "update_by", Op: model.QueryOpEq, Value: user})
+	}
+	if from := get("from"); from != "" {
+		filters = append(filters, &model.EntityFilter{Field: "updated_at", Op: model.QueryOpGte, Value: from})
+	}
+	if to := get("to"); to != "" {
+		if len(to) == len("2006-01-02") {
+			to += " 23:59:59"
+		}
+		filters = append(filters, &model.EntityFilter{Field: "updated_at", Op: model.QueryOpLte, Value: to})
+	}
+	return filters
+}
+
+func (h *entityHandler) ListEntityHistories(c *gin.Context) {
+	// page=1&pageSize=15&...
+	// get from  table field from api /table_field/find
+	page, pageSize := GetPage(c)
+	where := map[string]any{}
+	var total int64
+
+	entityID := c.Query("entity_id")
+	code := c.Query("code")
+	tableCode := c.Query("table_code")
+	if entityID != "" {
+		where["entity_id"] = entityID
+	}
+	if code != "" {
+		where["code"] = code
+	}
+	tableCode = fmt.Sprintf("%s_draft", tableCode)
+
+	// Permission check for table_code
+	if tableCode != "" {
+		userIDStr := c.GetString("user_id")
+		if userIDStr != "" {
+			userID, err := strconv.ParseUint(userIDStr, 10, 32)
+			if err != nil {
+				h.logger.Error("Failed to parse user_id", "user_id", userIDStr, "error", err)
+				resp.HandleError(c, http.StatusInternalServerError, "Invalid user ID", nil)
+				return
+			}
+
+			hasPermission, err := h.tablePermissionService.CheckTablePermission(c, uint(userID), tableCode)
+			if err != nil {
+				h.logger.Error("Failed to check table permission", "error", err)
+				resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+				return
+			}
+
+			if !hasPermission {
+				h.logger.Warn("User has no permission to access table", "user_id", userID, "table_code", tableCode)
+				resp.HandleError(c, http.StatusForbidden, "No permission to access this table", nil)
+				return
+			}
+		}
+	}
+
+	// delete control parameter
+	delete(where, "page")
+	delete(where, "pageSize")
+	delete(where, "table_code")
+
+	selectString := "id,code,name,entity_id,updated_at"
+	entities, err := h.entityService.Find(c, tableCode, selectString, where)
+	if err != nil {
+		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	links := resp.GeneratePaginationLinks(c.Request, page, pageSize, int(total))
+	c.Header("Link", links.String())
+
+	resp.HandleSuccess(c, entities)
+}
+
+// Import 上传文件创建后台导入任务, 立即返回任务编号
+// operation: BatchCreate BatchUpdate Upsert, Upsert 按 match_index 指定的唯一索引匹配已有记录
+// dry_run=true 时只校验不写入; 任务结束后可下载结果文件, 每行附带处理状态和错误信息
+// ignore_duplicates=true 时新增的行忽略 Warn 匹配规则发现的疑似重复
+// format: xlsx csv json ndjson, 为空时按文件扩展名识别; CSV 可指定 delimiter 和 encoding
+func (h *entityHandler) Import(c *gin.Context) {
+	file, err := c.FormFile("file") // 获取上传的文件
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	reader, err := file.Open() // 打开文件，获取 io.Reader
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	defer reader.Close()
+
+	format := c.PostForm("format")
+	if format == "" {
+		format = service.DetectFileFormat(file.Filename)
+	}
+	fileOpts, err := service.ParseFileOptions(format, c.PostForm("delimiter"), c.PostForm("encoding"))
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	tableCode := c.PostForm("table_code")
+	dryRun, _ := strconv.ParseBool(c.DefaultPostForm("dry_run", "false"))
+	ignoreDuplicates, _ := strconv.ParseBool(c.DefaultPostForm("ignore_duplicates", "false"))
+	opts := service.ImportOptions{
+		Operation:        c.PostForm("operation"),
+		Reason:           c.PostForm("reason"),
+		MatchIndex:       c.PostForm("match_index"),
+		DryRun:           dryRun,
+		IgnoreDuplicates: ignoreDuplicates,
+		File:             fileOpts,
+	}
+	if tableCode == "" || opts.Operation == "" {
+		resp.HandleError(c, http.StatusBadRequest, "table_code and operation are required", nil)
+		return
+	}
+
+	h.logger.Debug("h Import: ",
+		"tableCode", tableCode,
+		"options", opts)
+	job, err := h.entityService.Import(c, tableCode, opts, reader)
+	if err != nil {
+		handleWriteError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp.HandleSuccess(c, h.jobResponse(job))
+}
+
+// GetJob 查询后台任务进度, 完成后返回结果文件下载地址
+func (h *entityHandler) GetJob(c *gin.Context) {
+	tableCode := c.Param("table_code")
+	code := c.Param("code")
+
+	job, err := h.entityService.GetJob(c, tableCode, code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			resp.HandleError(c, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	resp.HandleSuccess(c, h.jobResponse(job))
+}
+
+// jobResponse 任务信息及结果文件下载地址
+func (h *entityHandler) jobResponse(job *model.EntityJob) gin.H {
+	resultURL := ""
+	if job.ResultFile != "" {
+		resultURL = h.conf.GetString("app.prefix-url") + "/" + h.conf.GetString("app.export-save-path") + job.ResultFile
+	}
+	return gin.H{
+		"job":        job,
+		"result_url": resultURL,
+	}
+}
+
+// Export 导出数据, 与列表使用相同的查询参数 (filter/sort/fields 及简单条件)
+// scope: filtered (默认) 按查询条件, selected 按 ids 指定的记录, all 忽略过滤条件; 兼容旧版 filter=selected|filtered|all
+// format: xlsx (默认) csv json ndjson; CSV 可指定 delimiter (默认逗号) 和 encoding (默认 utf-8)
+// labels=true 时选择、关联字段附加 <code>_label 显示名称列
+// relations=true 时附加 relations 列, 值为按关联模型分组的关联视图记录
+// async=true 时后台执行并立即返回任务, 通过任务查询接口获取进度和下载地址
+func (h *entityHandler) Export(c *gin.Context) {
+	fileOpts, err := service.ParseFileOptions(c.Query("format"), c.Query("delimiter"), c.Query("encoding"))
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	tableCode := c.Param("table_code")
+	if tableCode == "" {
+		tableCode = c.Query("table_code")
+	}
+
+	params := c.Request.URL.Query()
+	scope := params.Get("scope")
+	switch legacy := params.Get("filter"); legacy {
+	case "selected", "filtered", "all":
+		scope = legacy
+		params.Del("filter")
+	}
+
+	query, err := model.ParseEntityQuery(params,
+		"page", "pageSize", "table_code", "is_draft",
+		"scope", "ids", "format", "delimiter", "encoding", "labels", "items", "relations", "async")
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	switch scope {
+	case "selected":
+		var ids []any
+		for _, id := range strings.Split(params.Get("ids"), ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			resp.HandleError(c, http.StatusBadRequest, "ids is required when scope is selected", nil)
+			return
+		}
+		query.Filter = &model.EntityFilter{Field: "id", Op: model.QueryOpIn, Value: ids}
+	case "all":
+		query.Filter = nil
+	case "", "filtered":
+	default:
+		resp.HandleError(c, http.StatusBadRequest, "scope must be one of selected, filtered, all", nil)
+		return
+	}
+
+	labels, _ := strconv.ParseBool(c.DefaultQuery("labels", "false"))
+	items, _ := strconv.ParseBool(c.DefaultQuery("items", "false"))
+	relations, _ := strconv.ParseBool(c.DefaultQuery("relations", "false"))
+	async, _ := strconv.ParseBool(c.DefaultQuery("async", "false"))
+	job, err := h.entityService.Export(c, tableCode, query, service.ExportOptions{
+		Labels:    labels,
+		Items:     items,
+		Relations: relations,
+		Async:     async,
+		File:      fileOpts,
+	})
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+
+	data := h.jobResponse(job)
+	if job.ResultFile != "" {
+		// 兼容同步导出的返回格式
+		data["name"] = job.ResultFile
+		data["export_url"] = data["result_url"]
+		data["export_save_url"] = h.conf.GetString("app.export-save-path")
+	}
+	resp.HandleSuccess(c, data)
+}
+
+// Template 下载导入模板, 格式参数与 Export 相同
+func (h *entityHandler) Template(c *gin.Context) {
+	// 可以使用 c.QueryMap("ids") 接收query array
+	var params struct {
+		TableCode string `form:"table_code" json:"table_code"`
+		Operation string `form:"operation" json:"operation"`
+		Format    string `form:"format" json:"format"`
+		Delimiter string `form:"delimiter" json:"delimiter"`
+		Encoding  string `form:"encoding" json:"encoding"`
+	}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	fileOpts, err := service.ParseFileOptions(params.Format, params.Delimiter, params.Encoding)
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	filename, err := h.entityService.Template(c, params.TableCode, params.Operation, fileOpts)
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	path := h.conf.GetString("app.export-save-path")
 	fullURL := h.conf.GetString("app.prefix-url") + "/" + path + filename
 	resp.HandleSuccess(c, gin.H{
 		"name":            filename,
@@ -548,3 +1503,106 @@ func (h *entityHandler) GetStatistics(c *gin.Context) {
 
 	resp.HandleSuccess(c, statistics)
 }
+
+// handleWriteError 输出写入类接口的错误
+// 字段校验错误返回 422, 并在 errors 中给出逐字段的错误列表
+func handleWriteError(c *gin.Context, httpCode int, err error) {
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		resp.HandleError(c, http.StatusUnprocessableEntity, err.Error(), gin.H{
+			"errors": validationErr.Errors,
+		})
+		return
+	}
+	// 被其它数据引用不能删除时返回 409, 附带引用明细
+	var referenceErr *service.ReferenceError
+	if errors.As(err, &referenceErr) {
+		resp.HandleError(c, http.StatusConflict, err.Error(), gin.H{
+			"references": referenceErr.References,
+		})
+		return
+	}
+	// 记录当前状态不允许该操作时返回 409, 如修改已锁定的数据
+	var statusErr *service.StatusError
+	if errors.As(err, &statusErr) {
+		resp.HandleError(c, http.StatusConflict, err.Error(), gin.H{
+			"id":     statusErr.ID,
+			"status": statusErr.Status,
+		})
+		return
+	}
+	// 表结构变更计划不能发布时返回 409, 附带最新的计划
+	var planErr *service.SchemaPlanError
+	if errors.As(err, &planErr) {
+		resp.HandleError(c, http.StatusConflict, err.Error(), gin.H{
+			"plan": planErr.Plan,
+		})
+		return
+	}
+	// 模型定义包不能导入时返回 409, 附带最新的差异
+	var packageErr *service.ModelPackageError
+	if errors.As(err, &packageErr) {
+		resp.HandleError(c, http.StatusConflict, err.Error(), gin.H{
+			"diff": packageErr.Diff,
+		})
+		return
+	}
+	// 层级版本当前状态不允许该操作时返回 409, 如修改审批中的版本
+	var versionErr *service.HierarchyVersionError
+	if errors.As(err, &versionErr) {
+		resp.HandleError(c, http.StatusConflict, err.Error(), gin.H{
+			"version": versionErr.Version,
+			"status":  versionErr.Status,
+		})
+		return
+	}
+	// 移动节点后形成环时返回 422
+	var cycleErr *service.TreeCycleError
+	if errors.As(err, &cycleErr) {
+		resp.HandleError(c, http.StatusUnprocessableEntity, err.Error(), gin.H{
+			"id":        cycleErr.ID,
+			"parent_id": cycleErr.ParentID,
+		})
+		return
+	}
+	// 与已有记录疑似重复时返回 409, 附带疑似重复的记录和相似度
+	var duplicateErr *service.DuplicateError
+	if errors.As(err, &duplicateErr) {
+		resp.HandleError(c, http.StatusConflict, err.Error(), gin.H{
+			"duplicates": duplicateErr.Candidates,
+			"blocked":    duplicateErr.Blocked,
+		})
+		return
+	}
+	// 记录已被其他人修改时返回 409, 附带当前值和字段差异
+	var conflictErr *service.ConflictError
+	if errors.As(err, &conflictErr) {
+		resp.HandleError(c, http.StatusConflict, err.Error(), gin.H{
+			"conflicts": conflictErr.Conflicts,
+		})
+		return
+	}
+	resp.HandleError(c, httpCode, err.Error(), nil)
+}
+
+// ifMatchVersion 读取 If-Match 请求头中的版本号, 支持 "3" 与 W/"3" 两种格式
+func ifMatchVersion(c *gin.Context) (uint, bool, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return 0, false, nil
+	}
+	version, err := strconv.ParseUint(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("If-Match 不是合法的版本号: %s", header)
+	}
+	return uint(version), true, nil
+}
+
+// handleQueryError 输出查询类接口的错误, 查询条件不合法时返回 400
+func handleQueryError(c *gin.Context, err error) {
+	if errors.Is(err, model.ErrInvalidQuery) {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+}
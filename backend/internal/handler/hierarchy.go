@@ -0,0 +1,439 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"piemdm/internal/model"
+	"piemdm/internal/service"
+	"piemdm/pkg/helper/resp"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HierarchyHandler interface {
+	// 层级定义 (管理端)
+	List(c *gin.Context)
+	Get(c *gin.Context)
+	Create(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+
+	// 版本和节点
+	FindByTable(c *gin.Context)
+	Versions(c *gin.Context)
+	CreateVersion(c *gin.Context)
+	UpdateVersion(c *gin.Context)
+	DeleteVersion(c *gin.Context)
+	SaveNodes(c *gin.Context)
+	MoveNode(c *gin.Context)
+	Publish(c *gin.Context)
+	Tree(c *gin.Context)
+	Flatten(c *gin.Context)
+}
+
+type hierarchyHandler struct {
+	*Handler
+	hierarchyService service.HierarchyService
+}
+
+func NewHierarchyHandler(handler *Handler, hierarchyService service.HierarchyService) HierarchyHandler {
+	return &hierarchyHandler{
+		Handler:          handler,
+		hierarchyService: hierarchyService,
+	}
+}
+
+// List 获取层级列表
+// @Summary 获取层级列表
+// @Tags 层级管理
+// @Accept json
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(15)
+// @Param tableCode query string false "表代码"
+// @Success 200 {array} model.Hierarchy
+// @Router /admin/hierarchies [get]
+func (h *hierarchyHandler) List(c *gin.Context) {
+	var req struct {
+		Page      int    `form:"page,default=1"`
+		PageSize  int    `form:"pageSize,default=15"`
+		TableCode string `form:"tableCode"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	where := make(map[string]any)
+	var total int64
+	if req.TableCode != "" {
+		where["table_code"] = req.TableCode
+	}
+
+	hierarchies, err := h.hierarchyService.List(req.Page, req.PageSize, &total, where)
+	if err != nil {
+		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	links := resp.GeneratePaginationLinks(c.Request, req.Page, req.PageSize, int(total))
+	c.Header("Link", links.String())
+
+	resp.HandleSuccess(c, hierarchies)
+}
+
+// Get 获取层级详情
+// @Summary 获取层级详情
+// @Tags 层级管理
+// @Accept json
+// @Produce json
+// @Param id path int true "层级ID"
+// @Success 200 {object} model.Hierarchy
+// @Router /admin/hierarchies/{id} [get]
+func (h *hierarchyHandler) Get(c *gin.Context) {
+	var req struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	hierarchy, err := h.hierarchyService.Get(req.ID)
+	if err != nil {
+		resp.HandleError(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, hierarchy)
+}
+
+// Create 创建层级
+// @Summary 创建层级
+// @Tags 层级管理
+// @Accept json
+// @Produce json
+// @Param data body model.Hierarchy true "层级信息"
+// @Success 200 {object} model.Hierarchy
+// @Router /admin/hierarchies [post]
+func (h *hierarchyHandler) Create(c *gin.Context) {
+	var req struct {
+		Code        string `binding:"required,max=64"`  // 层级编码
+		Name        string `binding:"required,max=128"` // 层级名称
+		TableCode   string `binding:"required,max=64"`  // 节点引用的表
+		Description string `binding:"max=255"`          // 描述
+		Status      string `binding:"max=8"`            // 状态
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	hierarchy := model.Hierarchy{
+		Code:        req.Code,
+		Name:        req.Name,
+		TableCode:   req.TableCode,
+		Description: req.Description,
+		Status:      req.Status,
+	}
+	if err := h.hierarchyService.Create(c, &hierarchy); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, hierarchy)
+}
+
+// Update 更新层级, 编码和引用的表不能修改
+// @Summary 更新层级
+// @Tags 层级管理
+// @Accept json
+// @Produce json
+// @Param id path int true "层级ID"
+// @Param data body model.Hierarchy true "层级信息"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/hierarchies/{id} [put]
+func (h *hierarchyHandler) Update(c *gin.Context) {
+	var params struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&params); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	var req struct {
+		Code        string `binding:"max=64"`
+		Name        string `binding:"required,max=128"`
+		TableCode   string `binding:"max=64"`
+		Description string `binding:"max=255"`
+		Status      string `binding:"max=8"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	hierarchy := model.Hierarchy{
+		ID:          params.ID,
+		Code:        req.Code,
+		Name:        req.Name,
+		TableCode:   req.TableCode,
+		Description: req.Description,
+		Status:      req.Status,
+	}
+	if err := h.hierarchyService.Update(c, &hierarchy); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
+
+// Delete 删除层级及其全部版本
+// @Summary 删除层级
+// @Tags 层级管理
+// @Accept json
+// @Produce json
+// @Param id path int true "层级ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/hierarchies/{id} [delete]
+func (h *hierarchyHandler) Delete(c *gin.Context) {
+	var req struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if err := h.hierarchyService.Delete(c, req.ID); err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
+
+// FindByTable 查询表的全部层级, 参数 table_code
+func (h *hierarchyHandler) FindByTable(c *gin.Context) {
+	tableCode := c.Query("table_code")
+	if tableCode == "" {
+		resp.HandleError(c, http.StatusBadRequest, "table_code 不能为空", nil)
+		return
+	}
+	hierarchies, err := h.hierarchyService.FindByTable(c, tableCode)
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+	resp.HandleSuccess(c, hierarchies)
+}
+
+// Versions 查询层级的全部版本
+func (h *hierarchyHandler) Versions(c *gin.Context) {
+	versions, err := h.hierarchyService.Versions(c, c.Param("code"))
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+	resp.HandleSuccess(c, versions)
+}
+
+// hierarchyVersionRequest 新增或修改版本的请求体, 时间格式同 as_of
+type hierarchyVersionRequest struct {
+	Version     string `json:"version"`
+	ValidFrom   string `json:"valid_from"`
+	ValidTo     string `json:"valid_to"`
+	Description string `json:"description" binding:"max=255"`
+	CopyFrom    string `json:"copy_from"` // 复制节点的来源版本, 仅新增时使用
+}
+
+// toVersion 请求体转为层级版本, 时间为空时不限
+func (r *hierarchyVersionRequest) toVersion() (*model.HierarchyVersion, error) {
+	version := &model.HierarchyVersion{Version: r.Version, Description: r.Description}
+	for _, item := range []struct {
+		name  string
+		value string
+		field **time.Time
+	}{
+		{"valid_from", r.ValidFrom, &version.ValidFrom},
+		{"valid_to", r.ValidTo, &version.ValidTo},
+	} {
+		t, ok, err := parseHierarchyDate(item.name, item.value)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			*item.field = &t
+		}
+	}
+	return version, nil
+}
+
+// parseHierarchyDate 解析层级的时间参数, 格式为 2006-01-02 15:04:05、2006-01-02 或 RFC3339; 只有日期时取当天 0 点
+func parseHierarchyDate(name, value string) (time.Time, bool, error) {
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, ok, err := parseAsOf(value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%s 时间格式不正确, 应为 2006-01-02 15:04:05、2006-01-02 或 RFC3339: %s", name, value)
+	}
+	return t, ok, nil
+}
+
+// CreateVersion 新增编辑中的版本, 请求体为 {version, valid_from, valid_to, description, copy_from}
+func (h *hierarchyHandler) CreateVersion(c *gin.Context) {
+	var req hierarchyVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if req.Version == "" || len(req.Version) > 32 {
+		resp.HandleError(c, http.StatusBadRequest, "version 不能为空且不能超过 32 个字符", nil)
+		return
+	}
+	version, err := req.toVersion()
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err := h.hierarchyService.CreateVersion(c, c.Param("code"), version, req.CopyFrom); err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, version)
+}
+
+// UpdateVersion 修改版本的有效期和描述, 请求体为 {valid_from, valid_to, description}
+func (h *hierarchyHandler) UpdateVersion(c *gin.Context) {
+	var req hierarchyVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	changes, err := req.toVersion()
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err := h.hierarchyService.UpdateVersion(c, c.Param("code"), c.Param("version"), changes); err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
+
+// DeleteVersion 删除编辑中的版本
+func (h *hierarchyHandler) DeleteVersion(c *gin.Context) {
+	if err := h.hierarchyService.DeleteVersion(c, c.Param("code"), c.Param("version")); err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
+
+// SaveNodes 替换编辑中版本的全部节点, 请求体为 [{entity_id, parent_id, sort}]
+func (h *hierarchyHandler) SaveNodes(c *gin.Context) {
+	var nodes []*model.HierarchyNode
+	if err := c.ShouldBindJSON(&nodes); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err := h.hierarchyService.SaveNodes(c, c.Param("code"), c.Param("version"), nodes); err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
+
+// MoveNode 移动节点及其子树, 请求体为 {entity_id, parent_id}, parent_id 为空或 0 时移动为根节点
+func (h *hierarchyHandler) MoveNode(c *gin.Context) {
+	var req struct {
+		EntityID uint `json:"entity_id" binding:"required"`
+		ParentID uint `json:"parent_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err := h.hierarchyService.MoveNode(c, c.Param("code"), c.Param("version"), req.EntityID, req.ParentID); err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
+
+// Publish 发布编辑中的版本, 请求体为 {reason}; 配置了发布审批流程时提交审批
+func (h *hierarchyHandler) Publish(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err := h.hierarchyService.Publish(c, c.Param("code"), c.Param("version"), req.Reason); err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
+
+// hierarchyQuery 层级查询参数: version、date、root、depth
+func hierarchyQuery(c *gin.Context) (service.HierarchyQuery, error) {
+	query := service.HierarchyQuery{Version: c.Query("version")}
+	date, _, err := parseHierarchyDate("date", c.Query("date"))
+	if err != nil {
+		return query, err
+	}
+	query.Date = date
+	if param := c.Query("root"); param != "" {
+		n, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return query, fmt.Errorf("root 不是合法的记录ID")
+		}
+		query.Root = uint(n)
+	}
+	if param := c.Query("depth"); param != "" {
+		if query.Depth, err = strconv.Atoi(param); err != nil {
+			return query, fmt.Errorf("depth 必须是整数")
+		}
+	}
+	return query, nil
+}
+
+// Tree 查询层级的树形数据, 子节点放在 children 中
+// 参数 version 指定版本, 否则取 date (默认当前时间) 有效的已发布版本; root 为起始节点, depth 为返回的层数
+func (h *hierarchyHandler) Tree(c *gin.Context) {
+	query, err := hierarchyQuery(c)
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	view, err := h.hierarchyService.Tree(c, c.Param("code"), query)
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+	resp.HandleSuccess(c, view)
+}
+
+// Flatten 查询层级的扁平数据, 按深度优先排列, 每个节点带层级和路径; 参数同 Tree
+func (h *hierarchyHandler) Flatten(c *gin.Context) {
+	query, err := hierarchyQuery(c)
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	view, err := h.hierarchyService.Flatten(c, c.Param("code"), query)
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+	resp.HandleSuccess(c, view)
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"net/http"
+
+	"piemdm/internal/model"
+	"piemdm/internal/service"
+	"piemdm/pkg/helper/resp"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MatchRuleHandler interface {
+	List(c *gin.Context)
+	Get(c *gin.Context)
+	Create(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+}
+
+type matchRuleHandler struct {
+	*Handler
+	matchRuleService service.MatchRuleService
+}
+
+func NewMatchRuleHandler(handler *Handler, matchRuleService service.MatchRuleService) MatchRuleHandler {
+	return &matchRuleHandler{
+		Handler:          handler,
+		matchRuleService: matchRuleService,
+	}
+}
+
+// matchRuleRequest 新增、修改匹配规则的请求参数
+type matchRuleRequest struct {
+	Code        string            `binding:"max=64"`
+	Name        string            `binding:"required,max=128"`
+	TableCode   string            `binding:"max=64"`
+	Fields      model.MatchFields // 匹配字段
+	BlockFields string            `binding:"max=255"` // 分组字段, 逗号分隔
+	Threshold   float64           // 相似度阈值 (0~1]
+	Action      string            `binding:"omitempty,oneof=Warn Block"`
+	Sort        uint
+	Description string `binding:"max=255"`
+	Status      string `binding:"max=8"`
+}
+
+func (r *matchRuleRequest) toRule() model.MatchRule {
+	return model.MatchRule{
+		Code:        r.Code,
+		Name:        r.Name,
+		TableCode:   r.TableCode,
+		Fields:      r.Fields,
+		BlockFields: r.BlockFields,
+		Threshold:   r.Threshold,
+		Action:      r.Action,
+		Sort:        r.Sort,
+		Description: r.Description,
+		Status:      r.Status,
+	}
+}
+
+// List 获取匹配规则列表
+// @Summary 获取匹配规则列表
+// @Tags 匹配规则
+// @Accept json
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(15)
+// @Param tableCode query string false "表代码"
+// @Success 200 {array} model.MatchRule
+// @Router /admin/match_rules [get]
+func (h *matchRuleHandler) List(c *gin.Context) {
+	var req struct {
+		Page      int    `form:"page,default=1"`
+		PageSize  int    `form:"pageSize,default=15"`
+		TableCode string `form:"tableCode"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	where := make(map[string]any)
+	var total int64
+	if req.TableCode != "" {
+		where["table_code"] = req.TableCode
+	}
+
+	rules, err := h.matchRuleService.List(req.Page, req.PageSize, &total, where)
+	if err != nil {
+		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	links := resp.GeneratePaginationLinks(c.Request, req.Page, req.PageSize, int(total))
+	c.Header("Link", links.String())
+
+	resp.HandleSuccess(c, rules)
+}
+
+// Get 获取匹配规则详情
+// @Summary 获取匹配规则详情
+// @Tags 匹配规则
+// @Accept json
+// @Produce json
+// @Param id path int true "规则ID"
+// @Success 200 {object} model.MatchRule
+// @Router /admin/match_rules/{id} [get]
+func (h *matchRuleHandler) Get(c *gin.Context) {
+	var req struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	rule, err := h.matchRuleService.Get(req.ID)
+	if err != nil {
+		resp.HandleError(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, rule)
+}
+
+// Create 创建匹配规则
+// @Summary 创建匹配规则
+// @Tags 匹配规则
+// @Accept json
+// @Produce json
+// @Param data body model.MatchRule true "规则信息"
+// @Success 200 {object} model.MatchRule
+// @Router /admin/match_rules [post]
+func (h *matchRuleHandler) Create(c *gin.Context) {
+	var req matchRuleRequest
+	if err := c.ShouldBind(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if req.Code == "" || req.TableCode == "" {
+		resp.HandleError(c, http.StatusBadRequest, "Code 和 TableCode 不能为空", nil)
+		return
+	}
+
+	rule := req.toRule()
+	if err := h.matchRuleService.Create(c, &rule); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, rule)
+}
+
+// Update 更新匹配规则, 编码和表不能修改
+// @Summary 更新匹配规则
+// @Tags 匹配规则
+// @Accept json
+// @Produce json
+// @Param id path int true "规则ID"
+// @Param data body model.MatchRule true "规则信息"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/match_rules/{id} [put]
+func (h *matchRuleHandler) Update(c *gin.Context) {
+	var params struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&params); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	var req matchRuleRequest
+	if err := c.ShouldBind(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	rule := req.toRule()
+	rule.ID = params.ID
+	if err := h.matchRuleService.Update(c, &rule); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
+
+// Delete 删除匹配规则, 已生成的疑似重复分组保留
+// @Summary 删除匹配规则
+// @Tags 匹配规则
+// @Accept json
+// @Produce json
+// @Param id path int true "规则ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/match_rules/{id} [delete]
+func (h *matchRuleHandler) Delete(c *gin.Context) {
+	var req struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if err := h.matchRuleService.Delete(c, req.ID); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
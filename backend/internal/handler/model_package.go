@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"piemdm/internal/model"
+	"piemdm/internal/service"
+	"piemdm/pkg/helper/resp"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ModelPackageHandler interface {
+	Export(c *gin.Context)
+	Diff(c *gin.Context)
+	Import(c *gin.Context)
+}
+
+type modelPackageHandler struct {
+	*Handler
+	modelPackageService service.ModelPackageService
+	entityService       service.EntityService
+}
+
+func NewModelPackageHandler(handler *Handler, modelPackageService service.ModelPackageService, entityService service.EntityService) ModelPackageHandler {
+	return &modelPackageHandler{
+		Handler:             handler,
+		modelPackageService: modelPackageService,
+		entityService:       entityService,
+	}
+}
+
+// Export 导出模型定义包
+// @Summary 导出模型定义包
+// @Description 导出表、字段、字段组、审批绑定、数据权限及引用的审批定义, 用于在其他环境导入
+// @Tags 模型迁移
+// @Produce json
+// @Param table_codes query string false "表编码, 逗号分隔, 为空时导出全部表"
+// @Success 200 {object} model.ModelPackage
+// @Router /admin/model_packages/export [get]
+func (h *modelPackageHandler) Export(c *gin.Context) {
+	var tableCodes []string
+	for _, code := range strings.Split(c.Query("table_codes"), ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			tableCodes = append(tableCodes, code)
+		}
+	}
+
+	pkg, err := h.modelPackageService.Export(c, tableCodes)
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, pkg)
+}
+
+// Diff 预览导入模型定义包的差异
+// @Summary 预览导入模型定义包的差异
+// @Description 比较模型定义包与当前环境, 返回新增、修改、删除的对象和需要发布的表的表结构变更计划, 不修改数据库
+// @Tags 模型迁移
+// @Accept json
+// @Produce json
+// @Param data body model.ModelPackage true "模型定义包"
+// @Success 200 {object} model.ModelPackageDiff
+// @Router /admin/model_packages/diff [post]
+func (h *modelPackageHandler) Diff(c *gin.Context) {
+	var pkg model.ModelPackage
+	if err := c.ShouldBindJSON(&pkg); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	diff, err := h.modelPackageService.Diff(&pkg)
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, diff)
+}
+
+// Import 导入模型定义包
+// @Summary 导入模型定义包
+// @Description 按预览的差异在一个事务中保存定义, 然后发布字段有变化的表; 差异已变化、存在错误或会丢失数据但未确认时返回 409
+// @Tags 模型迁移
+// @Accept json
+// @Produce json
+// @Param data body object true "package: 模型定义包, checksum: 预览返回的差异摘要, allow_data_loss: 确认丢失数据"
+// @Success 200 {object} service.ModelImportResult
+// @Failure 409 {object} map[string]interface{}
+// @Router /admin/model_packages/import [post]
+func (h *modelPackageHandler) Import(c *gin.Context) {
+	var req struct {
+		Package *model.ModelPackage `json:"package" binding:"required"`
+		service.ModelImportOptions
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	result, err := h.modelPackageService.Import(c, req.Package, &req.ModelImportOptions)
+	if err != nil {
+		handleWriteError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// 发布后重算公式字段
+	for _, tableCode := range result.Diff.Tables {
+		if _, failed := result.Failed[tableCode]; failed {
+			continue
+		}
+		if _, err := h.entityService.RecomputeFormulas(c, tableCode); err != nil {
+			h.logger.Error("导入后重算公式字段失败", "table", tableCode, "err", err)
+		}
+	}
+	resp.HandleSuccess(c, result)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -60,6 +61,9 @@ func NewOpenApiHandler(
 // @Param table path string true "实体表名"
 // @Param page query int false "页码"
 // @Param pageSize query int false "每页数量"
+// @Param filter query string false "过滤条件 JSON, 如 {\"or\":[{\"field\":\"code\",\"op\":\"eq\",\"value\":\"A\"}]}"
+// @Param sort query string false "排序字段, 逗号分隔, - 前缀表示倒序, 如 -created_at,code"
+// @Param fields query string false "返回字段, 逗号分隔"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
@@ -102,25 +106,22 @@ func (h *openApiHandler) List(c *gin.Context) {
 		pageSize = 15
 	}
 
-	// 构建查询条件
-	where := make(map[string]any)
-
-	// 支持基础筛选参数 (id, status, created_at 等)
-	if id := c.Query("id"); id != "" {
-		where["id"] = id
-	}
-	if status := c.Query("status"); status != "" {
-		where["status"] = status
-	}
-	if createdAt := c.Query("created_at"); createdAt != "" {
-		where["created_at"] = createdAt
+	// 构建查询条件 (filter/sort/fields 及简单字段条件, 字段按白名单校验)
+	query, err := model.ParseEntityQuery(c.Request.URL.Query(), "page", "pageSize")
+	if err != nil {
+		resp.HandleError(c, http.StatusBadRequest, "PARAM_VALUE_INVALID: "+err.Error(), nil)
+		return
 	}
 
 	var total int64
 
-	// 调用 EntityRepository 直接获取数据 (OpenAPI 不需要用户权限检查)
-	entities, err := h.entityRepo.FindPage(tableCode, page, pageSize, &total, where)
+	// 结构化查询 (OpenAPI 不需要用户权限检查, 表权限已由中间件校验)
+	entities, err := h.entityService.Search(c, tableCode, page, pageSize, &total, query)
 	if err != nil {
+		if errors.Is(err, model.ErrInvalidQuery) {
+			resp.HandleError(c, http.StatusBadRequest, "PARAM_VALUE_INVALID: "+err.Error(), nil)
+			return
+		}
 		h.logger.Error("Failed to list entities", "error", err, "table", tableCode)
 		resp.HandleError(c, http.StatusInternalServerError, "SYSTEM_INTERNAL_ERROR: "+err.Error(), nil)
 		return
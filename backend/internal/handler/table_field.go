@@ -27,6 +27,8 @@ type TableFieldHandler interface {
 
 	// 特殊操作
 	Public(c *gin.Context)
+	PlanPublic(c *gin.Context)
+	ListSchemaVersions(c *gin.Context)
 	GetTableFields(c *gin.Context)
 	GetFieldTypePresets(c *gin.Context)
 	GetFieldTypeGroups(c *gin.Context)
@@ -36,12 +38,14 @@ type TableFieldHandler interface {
 type tableFieldHandler struct {
 	*Handler
 	tableFieldService service.TableFieldService
+	entityService     service.EntityService
 }
 
-func NewTableFieldHandler(handler *Handler, tableFieldService service.TableFieldService) TableFieldHandler {
+func NewTableFieldHandler(handler *Handler, tableFieldService service.TableFieldService, entityService service.EntityService) TableFieldHandler {
 	return &tableFieldHandler{
 		Handler:           handler,
 		tableFieldService: tableFieldService,
+		entityService:     entityService,
 	}
 }
 
@@ -122,14 +126,14 @@ func (h *tableFieldHandler) Get(c *gin.Context) {
 	resp.HandleSuccess(c, approval)
 }
 
-// GetTableFields 获取表的所有字段（包括系统字段）
 // GetTableFields 获取表的所有字段(包括系统字段)
 // @Summary 获取表的所有字段
+// @Description 字段按字段组排列并带字段组信息, 当前用户不能查看的字段组不返回, 不能修改的字段标记为只读
 // @Tags 表字段管理
 // @Accept json
 // @Produce json
 // @Param table_code query string true "表编码"
-// @Success 200 {array} model.TableField
+// @Success 200 {array} model.FieldMetadata
 // @Router /admin/table_fields/fields [get]
 func (h *tableFieldHandler) GetTableFields(c *gin.Context) {
 	tableCode := c.Query("table_code")
@@ -138,7 +142,7 @@ func (h *tableFieldHandler) GetTableFields(c *gin.Context) {
 		return
 	}
 
-	fields, err := h.tableFieldService.GetTableFields(tableCode)
+	fields, err := h.tableFieldService.GetTableFields(c, tableCode)
 	if err != nil {
 		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
 		return
@@ -309,11 +313,28 @@ func (h *tableFieldHandler) Update(c *gin.Context) {
 		tableField.Options = &options
 	}
 
-	err := h.tableFieldService.Update(c, &tableField)
+	before, err := h.tableFieldService.Get(req.Id)
+	if err != nil {
+		resp.HandleError(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	err = h.tableFieldService.Update(c, &tableField)
 	if err != nil {
 		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
 		return
 	}
+
+	// 公式定义变更后在后台重算已有数据, 返回重算任务
+	if service.FormulaChanged(before, &tableField) {
+		job, err := h.entityService.RecomputeFormulas(c, tableField.TableCode)
+		if err != nil {
+			resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+			return
+		}
+		resp.HandleSuccess(c, gin.H{"job": job})
+		return
+	}
 	resp.HandleSuccess(c, nil)
 }
 
@@ -382,6 +403,8 @@ func (h *tableFieldHandler) BatchDelete(c *gin.Context) {
 
 // Public 发布表结构
 // @Summary 发布表结构
+// @Description 按变更计划同步数据表和草稿表并记录表结构版本; checksum 为预览返回的计划摘要, 计划已变化时返回 409;
+// @Description 会丢失数据的计划需要 allow_data_loss 确认, 存在冲突时返回 409 并附带计划
 // @Tags 表字段管理
 // @Accept json
 // @Produce json
@@ -391,6 +414,7 @@ func (h *tableFieldHandler) BatchDelete(c *gin.Context) {
 func (h *tableFieldHandler) Public(c *gin.Context) {
 	var req struct {
 		TableCode string `form:"table_code" binding:"required,max=64" json:"table_code"`
+		service.PublicOptions
 	}
 
 	if err := c.ShouldBind(&req); err != nil {
@@ -398,7 +422,14 @@ func (h *tableFieldHandler) Public(c *gin.Context) {
 		return
 	}
 
-	err := h.tableFieldService.Public(c, req.TableCode)
+	version, err := h.tableFieldService.Public(c, req.TableCode, &req.PublicOptions)
+	if err != nil {
+		handleWriteError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// 发布后重算公式字段, 新增的公式字段为已有数据补全值
+	job, err := h.entityService.RecomputeFormulas(c, req.TableCode)
 	if err != nil {
 		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
 		return
@@ -412,9 +443,74 @@ func (h *tableFieldHandler) Public(c *gin.Context) {
 			"t_" + req.TableCode + "_draft",
 			"t_" + req.TableCode + "_log",
 		},
+		"version": version,
+		"job":     job,
 	})
 }
 
+// PlanPublic 预览发布表结构的变更计划
+// @Summary 预览发布表结构的变更计划
+// @Description 比较数据库中的表结构与字段定义, 返回新增、修改、重命名、删除的列和索引, 以及受影响和丢失数据的行数, 不修改数据库
+// @Tags 表字段管理
+// @Produce json
+// @Param table_code query string true "表编码"
+// @Success 200 {object} model.SchemaPlan
+// @Router /admin/table_fields/public/plan [get]
+func (h *tableFieldHandler) PlanPublic(c *gin.Context) {
+	var req struct {
+		TableCode string `form:"table_code" binding:"required,max=64"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	plan, err := h.tableFieldService.PlanPublic(req.TableCode)
+	if err != nil {
+		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, plan)
+}
+
+// ListSchemaVersions 获取表结构版本列表
+// @Summary 获取表结构版本列表
+// @Tags 表字段管理
+// @Produce json
+// @Param table_code query string true "表编码"
+// @Param status query string false "状态: Applied | Failed"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(15)
+// @Success 200 {array} model.SchemaVersion
+// @Router /admin/table_fields/schema_versions [get]
+func (h *tableFieldHandler) ListSchemaVersions(c *gin.Context) {
+	var req struct {
+		TableCode string `form:"table_code" binding:"required,max=64"`
+		Status    string `form:"status"`
+		Page      int    `form:"page,default=1"`
+		PageSize  int    `form:"pageSize,default=15"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	where := map[string]any{"table_code": req.TableCode}
+	if req.Status != "" {
+		where["status"] = req.Status
+	}
+	var total int64
+	versions, err := h.tableFieldService.ListSchemaVersions(req.Page, req.PageSize, &total, where)
+	if err != nil {
+		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	links := resp.GeneratePaginationLinks(c.Request, req.Page, req.PageSize, int(total))
+	c.Header("Link", links.String())
+	resp.HandleSuccess(c, versions)
+}
+
 // GetFieldTypePresets 获取所有字段类型预设
 // GET /api/admin/field-type-presets
 // GetFieldTypePresets 获取所有字段类型预设
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"net/http"
+
+	"piemdm/internal/model"
+	"piemdm/internal/service"
+	"piemdm/pkg/helper/resp"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TableFieldGroupHandler interface {
+	List(c *gin.Context)
+	Get(c *gin.Context)
+	Create(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+}
+
+type tableFieldGroupHandler struct {
+	*Handler
+	tableFieldGroupService service.TableFieldGroupService
+}
+
+func NewTableFieldGroupHandler(handler *Handler, tableFieldGroupService service.TableFieldGroupService) TableFieldGroupHandler {
+	return &tableFieldGroupHandler{
+		Handler:                handler,
+		tableFieldGroupService: tableFieldGroupService,
+	}
+}
+
+// tableFieldGroupRequest 新增、修改字段组的请求参数
+type tableFieldGroupRequest struct {
+	Code        string `binding:"max=64"`
+	Name        string `binding:"required,max=128"`
+	TableCode   string `binding:"max=64"`
+	View        string `binding:"max=32"`
+	Sort        uint
+	Collapsible string `binding:"omitempty,oneof=Yes No"`
+	Collapsed   string `binding:"omitempty,oneof=Yes No"`
+	ViewRoles   string `binding:"max=255"` // 可查看的角色编码, 逗号分隔
+	EditRoles   string `binding:"max=255"` // 可修改的角色编码, 逗号分隔
+	Description string `binding:"max=255"`
+	Status      string `binding:"max=8"`
+}
+
+func (r *tableFieldGroupRequest) toGroup() model.TableFieldGroup {
+	return model.TableFieldGroup{
+		Code:        r.Code,
+		Name:        r.Name,
+		TableCode:   r.TableCode,
+		View:        r.View,
+		Sort:        r.Sort,
+		Collapsible: r.Collapsible,
+		Collapsed:   r.Collapsed,
+		ViewRoles:   r.ViewRoles,
+		EditRoles:   r.EditRoles,
+		Description: r.Description,
+		Status:      r.Status,
+	}
+}
+
+// List 获取字段组列表
+// @Summary 获取字段组列表
+// @Tags 字段组
+// @Accept json
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(15)
+// @Param tableCode query string false "表代码"
+// @Success 200 {array} model.TableFieldGroup
+// @Router /admin/table_field_groups [get]
+func (h *tableFieldGroupHandler) List(c *gin.Context) {
+	var req struct {
+		Page      int    `form:"page,default=1"`
+		PageSize  int    `form:"pageSize,default=15"`
+		TableCode string `form:"tableCode"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	where := make(map[string]any)
+	var total int64
+	if req.TableCode != "" {
+		where["table_code"] = req.TableCode
+	}
+
+	groups, err := h.tableFieldGroupService.List(req.Page, req.PageSize, &total, where)
+	if err != nil {
+		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	links := resp.GeneratePaginationLinks(c.Request, req.Page, req.PageSize, int(total))
+	c.Header("Link", links.String())
+
+	resp.HandleSuccess(c, groups)
+}
+
+// Get 获取字段组详情
+// @Summary 获取字段组详情
+// @Tags 字段组
+// @Accept json
+// @Produce json
+// @Param id path int true "字段组ID"
+// @Success 200 {object} model.TableFieldGroup
+// @Router /admin/table_field_groups/{id} [get]
+func (h *tableFieldGroupHandler) Get(c *gin.Context) {
+	var req struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	group, err := h.tableFieldGroupService.Get(req.ID)
+	if err != nil {
+		resp.HandleError(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, group)
+}
+
+// Create 创建字段组
+// @Summary 创建字段组
+// @Tags 字段组
+// @Accept json
+// @Produce json
+// @Param data body model.TableFieldGroup true "字段组信息"
+// @Success 200 {object} model.TableFieldGroup
+// @Router /admin/table_field_groups [post]
+func (h *tableFieldGroupHandler) Create(c *gin.Context) {
+	var req tableFieldGroupRequest
+	if err := c.ShouldBind(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if req.Code == "" || req.TableCode == "" {
+		resp.HandleError(c, http.StatusBadRequest, "Code 和 TableCode 不能为空", nil)
+		return
+	}
+
+	group := req.toGroup()
+	if err := h.tableFieldGroupService.Create(c, &group); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, group)
+}
+
+// Update 更新字段组, 编码和表不能修改
+// @Summary 更新字段组
+// @Tags 字段组
+// @Accept json
+// @Produce json
+// @Param id path int true "字段组ID"
+// @Param data body model.TableFieldGroup true "字段组信息"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/table_field_groups/{id} [put]
+func (h *tableFieldGroupHandler) Update(c *gin.Context) {
+	var params struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&params); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	var req tableFieldGroupRequest
+	if err := c.ShouldBind(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	group := req.toGroup()
+	group.ID = params.ID
+	if err := h.tableFieldGroupService.Update(c, &group); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
+
+// Delete 删除字段组, 组中还有字段时不能删除
+// @Summary 删除字段组
+// @Tags 字段组
+// @Accept json
+// @Produce json
+// @Param id path int true "字段组ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/table_field_groups/{id} [delete]
+func (h *tableFieldGroupHandler) Delete(c *gin.Context) {
+	var req struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if err := h.tableFieldGroupService.Delete(c, req.ID); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
@@ -12,6 +12,7 @@ import (
 	"piemdm/internal/constants"
 	"piemdm/internal/handler"
 	"piemdm/internal/model"
+	"piemdm/internal/service"
 	mock_service "piemdm/test/mocks/service"
 
 	"github.com/gin-gonic/gin"
@@ -26,7 +27,7 @@ func TestTableFieldHandler_CreateTableField(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
-	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService)
+	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService, mock_service.NewMockEntityService(ctrl))
 
 	t.Run("Success_AllFieldTypes", func(t *testing.T) {
 		presets := constants.GetAllFieldTypePresets()
@@ -143,7 +144,7 @@ func TestTableFieldHandler_ListTableFields(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
-	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService)
+	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService, mock_service.NewMockEntityService(ctrl))
 
 	t.Run("Success", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/api/v1/admin/table_fields?table_code=test_table", nil)
@@ -183,7 +184,7 @@ func TestTableFieldHandler_GetTableField(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
-	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService)
+	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService, mock_service.NewMockEntityService(ctrl))
 
 	t.Run("Success", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/api/v1/admin/table_fields/1", nil)
@@ -219,7 +220,7 @@ func TestTableFieldHandler_UpdateTableField(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
-	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService)
+	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService, mock_service.NewMockEntityService(ctrl))
 
 	t.Run("Success", func(t *testing.T) {
 		reqBody := map[string]any{
@@ -239,6 +240,7 @@ func TestTableFieldHandler_UpdateTableField(t *testing.T) {
 		c, _ := gin.CreateTestContext(w)
 		c.Request = req
 
+		mockTableFieldService.EXPECT().Get(uint(1)).Return(&model.TableField{ID: 1, Code: "old_code", TableCode: "table1", FieldType: "text"}, nil)
 		mockTableFieldService.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx *gin.Context, field *model.TableField) error {
 			assert.Equal(t, uint(1), field.ID)
 			assert.Equal(t, "updated_code", field.Code)
@@ -256,7 +258,7 @@ func TestTableFieldHandler_BatchUpdateTableFields(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
-	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService)
+	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService, mock_service.NewMockEntityService(ctrl))
 
 	t.Run("Success", func(t *testing.T) {
 		reqBody := map[string]any{
@@ -287,7 +289,7 @@ func TestTableFieldHandler_BatchDeleteTableFields(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
-	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService)
+	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService, mock_service.NewMockEntityService(ctrl))
 
 	t.Run("Success", func(t *testing.T) {
 		reqBody := map[string]any{
@@ -313,7 +315,8 @@ func TestTableFieldHandler_PublicTableField(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
-	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService)
+	mockEntityService := mock_service.NewMockEntityService(ctrl)
+	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService, mockEntityService)
 
 	t.Run("Success", func(t *testing.T) {
 		reqBody := map[string]any{
@@ -326,7 +329,8 @@ func TestTableFieldHandler_PublicTableField(t *testing.T) {
 		c, _ := gin.CreateTestContext(w)
 		c.Request = req
 
-		mockTableFieldService.EXPECT().Public(gomock.Any(), "test_table").Return(nil)
+		mockTableFieldService.EXPECT().Public(gomock.Any(), "test_table", &service.PublicOptions{}).Return(nil, nil)
+		mockEntityService.EXPECT().RecomputeFormulas(gomock.Any(), "test_table").Return(nil, nil)
 
 		h.Public(c)
 		assert.Equal(t, http.StatusOK, w.Code)
@@ -335,6 +339,23 @@ func TestTableFieldHandler_PublicTableField(t *testing.T) {
 		json.Unmarshal(w.Body.Bytes(), &resp)
 		// Check that it returns the expected data structure if possible, though exact content depends on implementation
 	})
+
+	t.Run("DataLoss", func(t *testing.T) {
+		body, _ := json.Marshal(map[string]any{"table_code": "test_table", "checksum": "abc"})
+		req, _ := http.NewRequest("POST", "/api/v1/admin/table_fields/public", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		plan := &model.SchemaPlan{TableCode: "test_table", Checksum: "abc", DataLoss: true}
+		mockTableFieldService.EXPECT().Public(gomock.Any(), "test_table", &service.PublicOptions{Checksum: "abc"}).
+			Return(nil, &service.SchemaPlanError{Reason: "发布会丢失数据, 请确认后发布", Plan: plan})
+
+		h.Public(c)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), `"data_loss":true`)
+	})
 }
 
 func TestTableFieldHandler_GetTableFields(t *testing.T) {
@@ -343,7 +364,7 @@ func TestTableFieldHandler_GetTableFields(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
-	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService)
+	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService, mock_service.NewMockEntityService(ctrl))
 
 	t.Run("Success", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/api/v1/admin/table/fields?table_code=test_table", nil)
@@ -352,7 +373,7 @@ func TestTableFieldHandler_GetTableFields(t *testing.T) {
 		c.Request = req
 
 		mockFields := []*model.FieldMetadata{{Code: "f1"}}
-		mockTableFieldService.EXPECT().GetTableFields("test_table").Return(mockFields, nil)
+		mockTableFieldService.EXPECT().GetTableFields(c, "test_table").Return(mockFields, nil)
 
 		h.GetTableFields(c)
 		assert.Equal(t, http.StatusOK, w.Code)
@@ -375,7 +396,7 @@ func TestTableFieldHandler_GetTableOptions(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
-	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService)
+	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService, mock_service.NewMockEntityService(ctrl))
 
 	t.Run("Success", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/api/v1/admin/table/test_table/options", nil)
@@ -401,7 +422,7 @@ func TestTableFieldHandler_GetFieldTypePresets(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
-	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService)
+	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService, mock_service.NewMockEntityService(ctrl))
 
 	t.Run("Success", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/api/v1/admin/field-type-presets", nil)
@@ -420,7 +441,7 @@ func TestTableFieldHandler_GetFieldTypeGroups(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
-	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService)
+	h := handler.NewTableFieldHandler(createUserTestHandler(), mockTableFieldService, mock_service.NewMockEntityService(ctrl))
 
 	t.Run("Success", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/api/v1/admin/field-type-groups", nil)
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"net/http"
+
+	"piemdm/internal/model"
+	"piemdm/internal/service"
+	"piemdm/pkg/helper/resp"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TableRelationHandler interface {
+	List(c *gin.Context)
+	Get(c *gin.Context)
+	Create(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+}
+
+type tableRelationHandler struct {
+	*Handler
+	tableRelationService service.TableRelationService
+}
+
+func NewTableRelationHandler(handler *Handler, tableRelationService service.TableRelationService) TableRelationHandler {
+	return &tableRelationHandler{
+		Handler:              handler,
+		tableRelationService: tableRelationService,
+	}
+}
+
+// tableRelationRequest 新增、修改关联视图的请求参数
+type tableRelationRequest struct {
+	TableCode     string `binding:"max=64"`
+	RelationTable string `binding:"required,max=64"`
+	RelationName  string `binding:"required,max=128"`
+	RelationCode  string `binding:"required,max=64"` // 关联模型中引用该模型的字段
+	Sort          uint
+	Status        string `binding:"max=8"`
+}
+
+func (r *tableRelationRequest) toRelation() model.TableRelation {
+	return model.TableRelation{
+		TableCode:     r.TableCode,
+		RelationTable: r.RelationTable,
+		RelationName:  r.RelationName,
+		RelationCode:  r.RelationCode,
+		Sort:          r.Sort,
+		Status:        r.Status,
+	}
+}
+
+// List 获取关联视图列表
+// @Summary 获取关联视图列表
+// @Tags 关联视图
+// @Accept json
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(15)
+// @Param tableCode query string false "表代码"
+// @Success 200 {array} model.TableRelation
+// @Router /admin/table_relations [get]
+func (h *tableRelationHandler) List(c *gin.Context) {
+	var req struct {
+		Page      int    `form:"page,default=1"`
+		PageSize  int    `form:"pageSize,default=15"`
+		TableCode string `form:"tableCode"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	where := make(map[string]any)
+	var total int64
+	if req.TableCode != "" {
+		where["table_code"] = req.TableCode
+	}
+
+	relations, err := h.tableRelationService.List(req.Page, req.PageSize, &total, where)
+	if err != nil {
+		resp.HandleError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	links := resp.GeneratePaginationLinks(c.Request, req.Page, req.PageSize, int(total))
+	c.Header("Link", links.String())
+
+	resp.HandleSuccess(c, relations)
+}
+
+// Get 获取关联视图详情
+// @Summary 获取关联视图详情
+// @Tags 关联视图
+// @Accept json
+// @Produce json
+// @Param id path int true "关联视图ID"
+// @Success 200 {object} model.TableRelation
+// @Router /admin/table_relations/{id} [get]
+func (h *tableRelationHandler) Get(c *gin.Context) {
+	var req struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	relation, err := h.tableRelationService.Get(req.ID)
+	if err != nil {
+		resp.HandleError(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, relation)
+}
+
+// Create 创建关联视图
+// @Summary 创建关联视图
+// @Tags 关联视图
+// @Accept json
+// @Produce json
+// @Param data body model.TableRelation true "关联视图信息"
+// @Success 200 {object} model.TableRelation
+// @Router /admin/table_relations [post]
+func (h *tableRelationHandler) Create(c *gin.Context) {
+	var req tableRelationRequest
+	if err := c.ShouldBind(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if req.TableCode == "" {
+		resp.HandleError(c, http.StatusBadRequest, "TableCode 不能为空", nil)
+		return
+	}
+
+	relation := req.toRelation()
+	if err := h.tableRelationService.Create(c, &relation); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, relation)
+}
+
+// Update 更新关联视图, 所属的表不能修改
+// @Summary 更新关联视图
+// @Tags 关联视图
+// @Accept json
+// @Produce json
+// @Param id path int true "关联视图ID"
+// @Param data body model.TableRelation true "关联视图信息"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/table_relations/{id} [put]
+func (h *tableRelationHandler) Update(c *gin.Context) {
+	var params struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&params); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	var req tableRelationRequest
+	if err := c.ShouldBind(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	relation := req.toRelation()
+	relation.ID = params.ID
+	if err := h.tableRelationService.Update(c, &relation); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
+
+// Delete 删除关联视图
+// @Summary 删除关联视图
+// @Tags 关联视图
+// @Accept json
+// @Produce json
+// @Param id path int true "关联视图ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/table_relations/{id} [delete]
+func (h *tableRelationHandler) Delete(c *gin.Context) {
+	var req struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if err := h.tableRelationService.Delete(c, req.ID); err != nil {
+		resp.HandleError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	resp.HandleSuccess(c, nil)
+}
@@ -82,6 +82,7 @@ func CanonicalRequestMiddleware(
 		}
 
 		c.Set("application", app)
+		c.Set("source", model.EntityLogSourceOpenAPI) // 变更历史的来源
 		logger.Debug("Canonical request verified", "app_id", appId)
 		c.Next()
 	}
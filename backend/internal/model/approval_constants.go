@@ -62,6 +62,7 @@ const (
 	OperationBatchUnlock   = "BatchUnlock"   // 批量解锁
 	OperationBatchDelete   = "BatchDelete"   // 批量删除
 	OperationBatchExtend   = "BatchExtend"   // 批量扩展
+	OperationBatchVoid     = "BatchVoid"     // 批量作废
 )
 
 // Action 类型常量 (针对已有系统的操作类型)
@@ -211,7 +212,7 @@ func IsValidOperation(operation string) bool {
 		OperationVoid, OperationCancelBiz, OperationTerminate,
 		OperationBatchCreate, OperationBatchUpdate, OperationBatchFreeze,
 		OperationBatchUnfreeze, OperationBatchLock, OperationBatchUnlock,
-		OperationBatchDelete, OperationBatchExtend:
+		OperationBatchDelete, OperationBatchExtend, OperationBatchVoid:
 		return true
 	default:
 		return false
@@ -245,7 +246,7 @@ func GetActionByOperation(operation string) string {
 		return ActionInsert
 	case OperationUpdate, OperationLock, OperationUnlock, OperationVoid,
 		OperationCancelBiz, OperationTerminate, OperationBatchUpdate,
-		OperationBatchLock, OperationBatchUnlock:
+		OperationBatchLock, OperationBatchUnlock, OperationBatchVoid:
 		return ActionUpdate
 	case OperationFreeze, OperationBatchFreeze:
 		return ActionFreeze
@@ -263,7 +264,7 @@ func GetStatusByOperation(operation string) string {
 	switch operation {
 	case OperationCreate, OperationUpdate, OperationUnfreeze, OperationUnlock,
 		OperationCancelBiz, OperationBatchCreate, OperationBatchUpdate,
-		OperationBatchUnfreeze, OperationBatchUnlock, OperationBatchExtend:
+		OperationBatchUnfreeze, OperationBatchUnlock:
 		return StatusNormal
 	case OperationFreeze, OperationBatchFreeze:
 		return StatusFrozen
@@ -271,11 +272,11 @@ func GetStatusByOperation(operation string) string {
 		return StatusLocked
 	case OperationDelete, OperationBatchDelete:
 		return StatusDeleted
-	case OperationVoid:
+	case OperationVoid, OperationBatchVoid:
 		return StatusVoided
 	case OperationTerminate:
 		return StatusTerminated
-	case OperationExtend:
+	case OperationExtend, OperationBatchExtend:
 		return StatusExtended
 	default:
 		return StatusNormal // 默认返回 Normal
@@ -323,6 +324,12 @@ func GetOperationNameByOperation(operation string) string {
 		return "批量删除"
 	case OperationBatchExtend:
 		return "批量扩展"
+	case OperationBatchVoid:
+		return "批量作废"
+	case OperationMerge:
+		return "合并"
+	case OperationUnmerge:
+		return "取消合并"
 	default:
 		return "未知操作"
 	}
@@ -385,6 +392,46 @@ func CanTransitionApprovalStatus(from, to string) bool {
 	}
 }
 
+// IsLifecycleOperation 是否为数据生命周期操作 (冻结、解冻、锁定、解锁、作废、扩展及其批量操作)
+func IsLifecycleOperation(operation string) bool {
+	switch operation {
+	case OperationFreeze, OperationUnfreeze, OperationLock, OperationUnlock, OperationVoid, OperationExtend,
+		OperationBatchFreeze, OperationBatchUnfreeze, OperationBatchLock, OperationBatchUnlock,
+		OperationBatchVoid, OperationBatchExtend:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanTransitionEntityStatus 当前状态为 from 的数据能否执行 operation, 批量操作与单条操作规则相同
+// 已锁定、已作废的数据不能修改, 已锁定的数据不能删除; 空状态按 Normal 处理
+func CanTransitionEntityStatus(from, operation string) bool {
+	if from == "" {
+		from = StatusNormal
+	}
+	switch ConvertLegacyCodeToOperation(operation) {
+	case OperationUpdate, OperationBatchUpdate:
+		return from != StatusLocked && from != StatusVoided && from != StatusDeleted
+	case OperationFreeze, OperationBatchFreeze:
+		return from == StatusNormal || from == StatusExtended
+	case OperationUnfreeze, OperationBatchUnfreeze:
+		return from == StatusFrozen
+	case OperationLock, OperationBatchLock:
+		return from == StatusNormal || from == StatusExtended
+	case OperationUnlock, OperationBatchUnlock:
+		return from == StatusLocked
+	case OperationVoid, OperationBatchVoid:
+		return from == StatusNormal || from == StatusExtended || from == StatusFrozen
+	case OperationExtend, OperationBatchExtend:
+		return from == StatusNormal
+	case OperationDelete, OperationBatchDelete:
+		return from != StatusLocked
+	default:
+		return true
+	}
+}
+
 func CanTransitionTaskStatus(from, to string) bool {
 	switch from {
 	case TaskStatusPending:
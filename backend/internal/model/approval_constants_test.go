@@ -0,0 +1,37 @@
+package model_test
+
+import (
+	"testing"
+
+	"piemdm/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransitionEntityStatus(t *testing.T) {
+	cases := []struct {
+		from      string
+		operation string
+		want      bool
+	}{
+		{"", model.OperationFreeze, true}, // 空状态按 Normal 处理
+		{model.StatusFrozen, model.OperationBatchFreeze, false},
+		{model.StatusFrozen, model.OperationUnfreeze, true},
+		{model.StatusNormal, model.OperationBatchUnfreeze, false},
+		{model.StatusFrozen, model.OperationLock, false},
+		{model.StatusLocked, model.OperationUnlock, true},
+		{model.StatusFrozen, model.OperationBatchVoid, true},
+		{model.StatusVoided, model.OperationUnfreeze, false},
+		{model.StatusExtended, model.OperationExtend, false},
+		{model.StatusFrozen, model.OperationUpdate, true},
+		{model.StatusLocked, model.OperationBatchUpdate, false},
+		{model.StatusVoided, model.LegacyOperationCodeUpdate, false},
+		{model.StatusLocked, model.OperationDelete, false},
+		{model.StatusVoided, model.OperationBatchDelete, true},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, model.CanTransitionEntityStatus(tc.from, tc.operation), "%s -> %s", tc.from, tc.operation)
+	}
+	assert.True(t, model.IsLifecycleOperation(model.OperationBatchExtend))
+	assert.False(t, model.IsLifecycleOperation(model.OperationDelete))
+}
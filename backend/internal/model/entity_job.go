@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+)
+
+// 实体后台任务类型
+const (
+	EntityJobTypeImport    = "Import"    // 导入
+	EntityJobTypeExport    = "Export"    // 导出
+	EntityJobTypeRecompute = "Recompute" // 公式字段批量重算
+
+	EntityJobTypeDuplicateScan = "DuplicateScan" // 按匹配规则扫描全表查重
+)
+
+// 导入操作
+// Upsert 按唯一索引匹配: 未匹配的行按 BatchCreate 新增, 匹配到的行按 BatchUpdate 修改, 分别走各自的审批流程
+const (
+	ImportOperationCreate = OperationBatchCreate
+	ImportOperationUpdate = OperationBatchUpdate
+	ImportOperationUpsert = "Upsert"
+)
+
+// 实体后台任务状态
+const (
+	EntityJobStatusPending   = "Pending"   // 排队中
+	EntityJobStatusRunning   = "Running"   // 执行中
+	EntityJobStatusSucceeded = "Succeeded" // 已完成 (可能包含失败行, 见 Failed)
+	EntityJobStatusFailed    = "Failed"    // 执行失败 (文件无法解析等整体错误)
+)
+
+// EntityJob 实体导入/导出/公式重算后台任务
+type EntityJob struct {
+	ID           uint       `gorm:"primaryKey" json:"ID"`
+	Code         string     `gorm:"size:64;not null;uniqueIndex" json:"Code"` // 任务编号
+	Type         string     `gorm:"size:16;not null;index" json:"Type"`       // 任务类型: Import 导入 Export 导出 Recompute 公式重算 DuplicateScan 查重
+	TableCode    string     `gorm:"size:64;not null;index" json:"TableCode"`  // 表编码
+	Operation    string     `gorm:"size:32" json:"Operation"`                 // 操作: BatchCreate BatchUpdate Upsert, 查重任务为匹配规则编码
+	Format       string     `gorm:"size:16" json:"Format"`                    // 文件格式: xlsx csv json ndjson
+	MatchIndex   string     `gorm:"size:128" json:"MatchIndex"`               // Upsert 匹配使用的唯一索引名称
+	Reason       string     `gorm:"size:255" json:"Reason"`                   // 原因
+	DryRun       bool       `gorm:"default:false" json:"DryRun"`              // 仅校验, 不写入数据
+	Status       string     `gorm:"size:16;default:Pending;index" json:"Status"`
+	Total        int        `gorm:"default:0" json:"Total"`       // 总行数
+	Processed    int        `gorm:"default:0" json:"Processed"`   // 已处理行数
+	Succeeded    int        `gorm:"default:0" json:"Succeeded"`   // 成功行数
+	Failed       int        `gorm:"default:0" json:"Failed"`      // 失败行数
+	ResultFile   string     `gorm:"size:255" json:"ResultFile"`   // 结果文件名
+	ApprovalCode string     `gorm:"size:128" json:"ApprovalCode"` // 走审批流程时的审批编码, Upsert 时可能有新增、修改两个, 逗号分隔
+	Message      string     `gorm:"size:512" json:"Message"`      // 结果说明或错误信息
+	StartedAt    *time.Time `json:"StartedAt"`                    // 开始时间
+	FinishedAt   *time.Time `json:"FinishedAt"`                   // 完成时间
+	ExpiresAt    *time.Time `json:"ExpiresAt"`                    // 结果文件过期时间
+	CreatedBy    string     `gorm:"size:64;index" json:"CreatedBy"`
+	CreatedAt    *time.Time `json:"CreatedAt"`
+	UpdatedAt    *time.Time `json:"UpdatedAt"`
+}
+
+// IsFinished 任务是否已结束
+func (j *EntityJob) IsFinished() bool {
+	return j.Status == EntityJobStatusSucceeded || j.Status == EntityJobStatusFailed
+}
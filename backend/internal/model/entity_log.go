@@ -1,20 +1,36 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// 变更事件的来源
+const (
+	EntityLogSourceUI      = "UI"
+	EntityLogSourceOpenAPI = "OpenAPI"
+	EntityLogSourceImport  = "Import"
+	EntityLogSourceFeishu  = "Feishu"
+	EntityLogSourceSystem  = "System" // 系统自动处理: 公式重算、删除引用等
+)
+
+// EntityLog 变更历史, 每次修改一条记录生成一个事件
+// 字段变化以 JSON 记录在 Changes 中; FieldCode、BeforeUpdate、AfterUpdate 为旧版按字段记录的日志
 type EntityLog struct {
-	ID           uint   `gorm:"primaryKey"`
-	EntityID     uint   `gorm:"not null;index" binding:"required"`          // 关联的动态表记录ID
-	FieldCode    string `gorm:"size:63;not null" binding:"required,max=64"` // 修改的字段
-	FieldName    string `gorm:"size:127;" binding:"max=128"`                // 修改的字段
-	BeforeUpdate string `gorm:"size:255"`                                   // 修改前
-	AfterUpdate  string `gorm:"size:255"`                                   // 修改后
-	Reason       string `gorm:"size:255"`                                   // 原因
-	UpdateBy     string `gorm:"size:64"`                                    // 修改人
-	UpdatedAt    time.Time
-	DeletedAt    gorm.DeletedAt `gorm:"index"`
+	ID           uint            `gorm:"primaryKey"`
+	EntityID     uint            `gorm:"not null;index"`              // 关联的动态表记录ID
+	Operation    string          `gorm:"size:32;index"`               // 操作: Create, Update, BatchUpdate, Delete ...
+	Source       string          `gorm:"size:16"`                     // 来源: UI, OpenAPI, Import, Cron ...
+	ApprovalCode string          `gorm:"size:64;index"`               // 审批发布时的审批编码
+	Changes      json.RawMessage `gorm:"type:json"`                   // 字段变化 {"字段编码": {"name", "before", "after", "paths"}}
+	FieldCode    string          `gorm:"size:63" binding:"max=64"`    // 修改的字段 (旧版)
+	FieldName    string          `gorm:"size:127;" binding:"max=128"` // 修改的字段 (旧版)
+	BeforeUpdate string          `gorm:"size:255"`                    // 修改前 (旧版)
+	AfterUpdate  string          `gorm:"size:255"`                    // 修改后 (旧版)
+	Reason       string          `gorm:"size:255"`                    // 原因
+	UpdateBy     string          `gorm:"size:64;index"`               // 修改人
+	UpdatedAt    time.Time       `gorm:"index"`
+	DeletedAt    gorm.DeletedAt  `gorm:"index"`
 }
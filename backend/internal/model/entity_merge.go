@@ -0,0 +1,60 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// 合并操作, 用于配置合并的审批流程和记录变更历史
+const (
+	OperationMerge   = "Merge"   // 合并
+	OperationUnmerge = "Unmerge" // 取消合并
+)
+
+// mergeApprovalPrefix 合并审批的实体编码前缀, 与表编码区分
+const mergeApprovalPrefix = "merge:"
+
+// MergeApprovalEntity 表的合并在审批流程关联 (TableApprovalDefinition) 和审批实例中使用的实体编码, 如 merge:customer
+func MergeApprovalEntity(tableCode string) string {
+	return mergeApprovalPrefix + tableCode
+}
+
+// MergeTableOf 审批实例的实体编码是合并时返回表编码
+func MergeTableOf(entityCode string) (string, bool) {
+	code, ok := strings.CutPrefix(entityCode, mergeApprovalPrefix)
+	return code, ok && code != ""
+}
+
+// 合并状态
+const (
+	EntityMergePending  = "Pending"  // 审批中
+	EntityMergeMerged   = "Merged"   // 已合并
+	EntityMergeRejected = "Rejected" // 审批拒绝或撤回
+	EntityMergeReverted = "Reverted" // 已取消合并
+)
+
+// EntityMerge 一次记录合并: 被合并记录的值按保留规则写入主记录, 引用改为指向主记录, 被合并记录作废
+// 合并前的记录和引用保存在快照中, 用于取消合并
+type EntityMerge struct {
+	ID              uint            `gorm:"primaryKey" json:"ID"`
+	Code            string          `gorm:"size:64;not null;uniqueIndex" json:"Code"` // 合并编号
+	TableCode       string          `gorm:"size:64;not null;index" json:"TableCode"`  // 表编码
+	SurvivorID      uint            `gorm:"not null;index" json:"SurvivorID"`         // 主记录 id
+	SurvivorVersion uint            `json:"SurvivorVersion"`                          // 合并依据的主记录版本, 合并生效时主记录版本不一致则不合并
+	MergedIDs       json.RawMessage `gorm:"type:json" json:"MergedIDs"`               // 被合并记录 id 数组
+	Values          json.RawMessage `gorm:"type:json" json:"Values"`                  // 写入主记录的字段值 {"字段编码": 值}
+	Sources         json.RawMessage `gorm:"type:json" json:"Sources"`                 // 字段值来源 {"字段编码": 记录 id}
+	Snapshot        json.RawMessage `gorm:"type:json" json:"-"`                       // 合并前的主记录和被合并记录
+	References      json.RawMessage `gorm:"type:json" json:"References"`              // 改为指向主记录的引用
+	Status          string          `gorm:"size:16;default:Pending;index" json:"Status"`
+	ApprovalCode    string          `gorm:"size:128;index" json:"ApprovalCode"` // 合并审批的审批编码
+	Reason          string          `gorm:"size:255" json:"Reason"`
+	MergedAt        *time.Time      `json:"MergedAt"` // 合并生效时间
+	RevertedBy      string          `gorm:"size:64" json:"RevertedBy"`
+	RevertedAt      *time.Time      `json:"RevertedAt"` // 取消合并时间
+	CreatedBy       string          `gorm:"size:64" json:"CreatedBy"`
+	UpdatedBy       string          `gorm:"size:64" json:"UpdatedBy"`
+	CreatedAt       *time.Time      `json:"CreatedAt"`
+	UpdatedAt       *time.Time      `json:"UpdatedAt"`
+}
@@ -0,0 +1,186 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidQuery 查询条件不合法 (未知字段、不支持的操作符、值类型错误等)
+var ErrInvalidQuery = errors.New("invalid query")
+
+// 查询操作符
+const (
+	QueryOpEq         = "eq"         // 等于
+	QueryOpNe         = "ne"         // 不等于
+	QueryOpGt         = "gt"         // 大于
+	QueryOpGte        = "gte"        // 大于等于
+	QueryOpLt         = "lt"         // 小于
+	QueryOpLte        = "lte"        // 小于等于
+	QueryOpIn         = "in"         // 在列表中
+	QueryOpNin        = "nin"        // 不在列表中
+	QueryOpLike       = "like"       // 模糊匹配 (值中自带 % 通配符)
+	QueryOpContains   = "contains"   // 包含
+	QueryOpStartsWith = "startswith" // 以...开头
+	QueryOpEndsWith   = "endswith"   // 以...结尾
+	QueryOpBetween    = "between"    // 区间 [from, to]
+	QueryOpIsNull     = "isnull"     // 为空
+	QueryOpNotNull    = "notnull"    // 不为空
+	QueryOpHasAny     = "hasany"     // 多对多字段引用了任一值
+	QueryOpHasAll     = "hasall"     // 多对多字段引用了全部值
+)
+
+// EntityQuery 实体列表结构化查询
+//
+// 对应查询参数:
+//
+//	filter={"or":[{"field":"code","op":"like","value":"A%"},{"field":"name","op":"contains","value":"A"}]}
+//	sort=-created_at,code
+//	fields=code,name
+type EntityQuery struct {
+	Filter *EntityFilter `json:"filter,omitempty"` // 过滤条件
+	Sort   []EntitySort  `json:"sort,omitempty"`   // 排序, 按顺序生效
+	Fields []string      `json:"fields,omitempty"` // 返回字段, 为空时返回全部字段
+}
+
+// EntityFilter 过滤条件节点
+// 条件组: And / Or 非空; 单个条件: Field + Op + Value
+type EntityFilter struct {
+	And []*EntityFilter `json:"and,omitempty"`
+	Or  []*EntityFilter `json:"or,omitempty"`
+
+	Field string `json:"field,omitempty"`
+	Op    string `json:"op,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// EntitySort 排序字段
+type EntitySort struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// IsGroup 是否为条件组
+func (f *EntityFilter) IsGroup() bool {
+	return len(f.And) > 0 || len(f.Or) > 0
+}
+
+// legacyQueryOps 兼容旧版 "field op" 查询参数的操作符映射
+var legacyQueryOps = map[string]string{
+	"=":      QueryOpEq,
+	"!=":     QueryOpNe,
+	"<>":     QueryOpNe,
+	">":      QueryOpGt,
+	">=":     QueryOpGte,
+	"<":      QueryOpLt,
+	"<=":     QueryOpLte,
+	"in":     QueryOpIn,
+	"notin":  QueryOpNin,
+	"like":   QueryOpLike,
+	"hasany": QueryOpHasAny,
+	"hasall": QueryOpHasAll,
+}
+
+// ParseEntityQuery 从 URL 查询参数解析结构化查询
+// filter/sort/fields 为结构化参数; 其余参数按旧版简单条件处理:
+// "field=value" 为等于 (换行分隔时为 in), "field op=value" 为指定操作符
+// reserved 中的参数 (分页等控制参数) 不作为查询条件
+func ParseEntityQuery(params url.Values, reserved ...string) (*EntityQuery, error) {
+	query := &EntityQuery{}
+	var conditions []*EntityFilter
+
+	if raw := strings.TrimSpace(params.Get("filter")); raw != "" {
+		filter := &EntityFilter{}
+		decoder := json.NewDecoder(strings.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(filter); err != nil {
+			return nil, fmt.Errorf("%w: filter must be valid JSON: %v", ErrInvalidQuery, err)
+		}
+		conditions = append(conditions, filter)
+	}
+
+	if raw := strings.TrimSpace(params.Get("sort")); raw != "" {
+		for _, item := range strings.Split(raw, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			sort := EntitySort{Field: item}
+			if strings.HasPrefix(item, "-") {
+				sort = EntitySort{Field: item[1:], Desc: true}
+			}
+			query.Sort = append(query.Sort, sort)
+		}
+	}
+
+	if raw := strings.TrimSpace(params.Get("fields")); raw != "" {
+		for _, field := range strings.Split(raw, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				query.Fields = append(query.Fields, field)
+			}
+		}
+	}
+
+	skip := map[string]bool{"filter": true, "sort": true, "fields": true}
+	for _, key := range reserved {
+		skip[key] = true
+	}
+
+	for key, values := range params {
+		if skip[key] || len(values) == 0 || values[0] == "" {
+			continue
+		}
+		// 替换全角逗号
+		value := strings.TrimSpace(strings.ReplaceAll(values[0], "，", ","))
+
+		parts := strings.Fields(key)
+		switch len(parts) {
+		case 1:
+			condition := &EntityFilter{Field: parts[0], Op: QueryOpEq, Value: value}
+			if strings.Contains(value, "\n") {
+				condition.Op = QueryOpIn
+				condition.Value = splitQueryValues(value, "\n")
+			}
+			conditions = append(conditions, condition)
+		case 2:
+			op, ok := legacyQueryOps[strings.ToLower(parts[1])]
+			if !ok {
+				return nil, fmt.Errorf("%w: unsupported operator %q", ErrInvalidQuery, parts[1])
+			}
+			condition := &EntityFilter{Field: parts[0], Op: op, Value: value}
+			switch {
+			case op == QueryOpIn || op == QueryOpNin || op == QueryOpHasAny || op == QueryOpHasAll:
+				condition.Value = splitQueryValues(value, ",")
+			case op == QueryOpEq && strings.Contains(value, "\n"):
+				condition.Op = QueryOpIn
+				condition.Value = splitQueryValues(value, "\n")
+			}
+			conditions = append(conditions, condition)
+		default:
+			return nil, fmt.Errorf("%w: unsupported query parameter %q", ErrInvalidQuery, key)
+		}
+	}
+
+	switch len(conditions) {
+	case 0:
+	case 1:
+		query.Filter = conditions[0]
+	default:
+		query.Filter = &EntityFilter{And: conditions}
+	}
+
+	return query, nil
+}
+
+// splitQueryValues 按分隔符拆分查询值并去除空值
+func splitQueryValues(value, sep string) []any {
+	var result []any
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
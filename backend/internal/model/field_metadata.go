@@ -12,6 +12,22 @@ type FieldMetadata struct {
 	IsShow    bool   `json:"is_show"`           // 是否显示
 	IsSystem  bool   `json:"is_system"`         // 是否系统字段
 	IsFilter  bool   `json:"is_filter"`         // 是否可过滤
+	IsUnique  bool   `json:"is_unique"`         // 是否唯一索引字段
+	IndexName string `json:"index_name"`        // 唯一索引名称, 为空时以字段编码作为索引名
 	Sort      int    `json:"sort"`              // 排序
 	Options   any    `json:"options,omitempty"` // 字段配置选项(JSON)
+	// 字段组, 未分组的字段和系统字段为空
+	Group    *FieldGroupMetadata `json:"group,omitempty"`
+	Readonly bool                `json:"readonly"` // 当前用户按字段组权限不能修改
+}
+
+// FieldGroupMetadata 字段组元数据, 表单按字段组顺序分段显示
+// 未维护字段组、只填写了组名的旧字段, Code 为空
+type FieldGroupMetadata struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Sort        uint   `json:"sort"`
+	Collapsible bool   `json:"collapsible"`
+	Collapsed   bool   `json:"collapsed"`
+	Editable    bool   `json:"editable"`
 }
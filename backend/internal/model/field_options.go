@@ -14,13 +14,16 @@ type FieldOptions struct {
 	Behavior   *FieldBehavior   `json:"behavior,omitempty"`   // 行为配置
 
 	// 新增配置
-	Relation   *FieldRelation    `json:"relation,omitempty"`   // 关联配置
-	DateTime   *FieldDateTime    `json:"datetime,omitempty"`   // 日期时间行为
-	Sort       *FieldSort        `json:"sort,omitempty"`       // 排序字段
-	RichText   *FieldRichText    `json:"richtext,omitempty"`   // 富文本
-	Attachment *FieldAttachment  `json:"attachment,omitempty"` // 附件
-	Patterns   []SequencePattern `json:"patterns,omitempty"`   // 自动编码
-	Trim       bool              `json:"trim,omitempty"`       // 字符串去空格
+	Relation     *FieldRelation     `json:"relation,omitempty"`     // 关联配置
+	DateTime     *FieldDateTime     `json:"datetime,omitempty"`     // 日期时间行为
+	Sort         *FieldSort         `json:"sort,omitempty"`         // 排序字段
+	RichText     *FieldRichText     `json:"richtext,omitempty"`     // 富文本
+	Attachment   *FieldAttachment   `json:"attachment,omitempty"`   // 附件
+	Patterns     []SequencePattern  `json:"patterns,omitempty"`     // 自动编码
+	Formula      *FieldFormula      `json:"formula,omitempty"`      // 公式
+	JSON         *FieldJSON         `json:"json,omitempty"`         // JSON
+	Survivorship *FieldSurvivorship `json:"survivorship,omitempty"` // 合并时的保留规则
+	Trim         bool               `json:"trim,omitempty"`         // 字符串去空格
 }
 
 // FieldUI UI 配置
@@ -102,6 +105,75 @@ type FieldRelation struct {
 	ValueField string         `json:"valueField,omitempty"` // 存储字段 = 关联键（默认 code）
 	Multiple   bool           `json:"multiple,omitempty"`   // 是否多选
 	Filter     map[string]any `json:"filter,omitempty"`     // 过滤条件
+	OnDelete   string         `json:"onDelete,omitempty"`   // 被引用记录删除时的处理（默认 restrict）
+}
+
+// 被引用记录删除时的处理方式
+const (
+	RelationOnDeleteRestrict = "restrict" // 存在引用时禁止删除
+	RelationOnDeleteSetNull  = "setNull"  // 清空引用字段中被删除的值
+	RelationOnDeleteCascade  = "cascade"  // 级联删除引用记录, 引用表配置了删除审批时走审批
+)
+
+// IsValidRelationOnDelete 判断删除处理方式是否合法, 空值表示默认 restrict
+func IsValidRelationOnDelete(onDelete string) bool {
+	switch onDelete {
+	case "", RelationOnDeleteRestrict, RelationOnDeleteSetNull, RelationOnDeleteCascade:
+		return true
+	}
+	return false
+}
+
+// FieldFormula 公式字段配置, 值由表达式根据同一记录的其它字段计算, 保存时写入数据表以便索引和过滤
+type FieldFormula struct {
+	Expression string `json:"expression"`           // 表达式, 如 price * quantity
+	ResultType string `json:"resultType,omitempty"` // 结果类型: Text Number Date DateTime, 默认 Text
+}
+
+// 公式结果类型, 对应字段的数据类型
+const (
+	FormulaResultText     = "Text"
+	FormulaResultNumber   = "Number"
+	FormulaResultDate     = "Date"
+	FormulaResultDateTime = "DateTime"
+)
+
+// IsValidFormulaResultType 判断公式结果类型是否合法, 空值表示默认 Text
+func IsValidFormulaResultType(resultType string) bool {
+	switch resultType {
+	case "", FormulaResultText, FormulaResultNumber, FormulaResultDate, FormulaResultDateTime:
+		return true
+	}
+	return false
+}
+
+// FieldJSON JSON 字段配置
+type FieldJSON struct {
+	Schema map[string]any `json:"schema,omitempty"` // JSON Schema, 配置后写入的值必须符合该 schema
+}
+
+// FieldSurvivorship 合并记录时字段的保留规则
+type FieldSurvivorship struct {
+	Strategy    string   `json:"strategy,omitempty"`    // 保留规则, 空值为默认规则
+	SourceField string   `json:"sourceField,omitempty"` // source_priority: 记录来源字段, 如 source_system
+	Sources     []string `json:"sources,omitempty"`     // source_priority: 可信来源, 越靠前越可信
+}
+
+// 保留规则 (survivorship): 合并时字段取哪条记录的值
+const (
+	SurvivorshipMostRecent     = "most_recent"     // 最近修改的记录的非空值
+	SurvivorshipSourcePriority = "source_priority" // 来源字段在可信来源列表中最靠前的记录的非空值
+	SurvivorshipMostComplete   = "most_complete"   // 最完整 (最长) 的非空值
+	SurvivorshipManual         = "manual"          // 合并时人工选择, 未选择时保留主记录的值
+)
+
+// IsValidSurvivorship 判断保留规则是否合法, 空值为默认规则: 保留主记录的值, 为空时取最近修改的记录的非空值
+func IsValidSurvivorship(strategy string) bool {
+	switch strategy {
+	case "", SurvivorshipMostRecent, SurvivorshipSourcePriority, SurvivorshipMostComplete, SurvivorshipManual:
+		return true
+	}
+	return false
 }
 
 // FieldDateTime 日期时间行为配置
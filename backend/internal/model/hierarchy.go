@@ -0,0 +1,111 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 层级版本状态
+const (
+	HierarchyVersionDraft   = "Draft"   // 编辑中
+	HierarchyVersionPending = "Pending" // 审批中
+	HierarchyVersionActive  = "Active"  // 已发布
+)
+
+// OperationPublish 发布层级版本, 用于配置层级的审批流程
+const OperationPublish = "Publish"
+
+// hierarchyApprovalPrefix 层级审批的实体编码前缀, 与表编码区分
+const hierarchyApprovalPrefix = "hierarchy:"
+
+// HierarchyApprovalEntity 层级在审批流程关联 (TableApprovalDefinition) 和审批实例中使用的实体编码, 如 hierarchy:cost_center_reporting
+func HierarchyApprovalEntity(code string) string {
+	return hierarchyApprovalPrefix + code
+}
+
+// HierarchyCodeOf 审批实例的实体编码是层级时返回层级编码
+func HierarchyCodeOf(entityCode string) (string, bool) {
+	code, ok := strings.CutPrefix(entityCode, hierarchyApprovalPrefix)
+	return code, ok && code != ""
+}
+
+// Hierarchy 层级: 表的一个命名层级 (如汇报、法人、销售), 节点引用表中的记录, 与记录自身的 parent_id 无关
+type Hierarchy struct {
+	ID          uint   `gorm:"primaryKey" json:"ID"`
+	Code        string `gorm:"size:64;not null;uniqueIndex" json:"Code" binding:"required,max=64"` // 层级编码
+	Name        string `gorm:"size:128;not null" json:"Name" binding:"required,max=128"`           // 层级名称
+	TableCode   string `gorm:"size:64;not null;index" json:"TableCode" binding:"required,max=64"`  // 节点引用的表
+	Description string `gorm:"size:255" json:"Description" binding:"max=255"`
+	// 状态：Normal 正常 Frozen 已冻结 Deleted 已删除
+	Status    string         `gorm:"size:8;default:Normal" json:"Status"`
+	CreatedBy string         `gorm:"size:64" json:"CreatedBy"`
+	UpdatedBy string         `gorm:"size:64" json:"UpdatedBy"`
+	CreatedAt *time.Time     `json:"CreatedAt"`
+	UpdatedAt *time.Time     `json:"UpdatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (m *Hierarchy) BeforeDelete(tx *gorm.DB) (err error) {
+	if user, ok := tx.Statement.Context.Value("user_name").(string); ok {
+		m.UpdatedBy = user
+	}
+	tx.Model(m).Where("id = ?", m.ID).Updates(map[string]any{
+		"status":     "Deleted",
+		"updated_by": m.UpdatedBy,
+	})
+	return
+}
+
+func (m *Hierarchy) BeforeCreate(tx *gorm.DB) (err error) {
+	if user, ok := tx.Statement.Context.Value("user_name").(string); ok {
+		m.CreatedBy = user
+	}
+	return
+}
+
+func (m *Hierarchy) BeforeUpdate(tx *gorm.DB) (err error) {
+	if user, ok := tx.Statement.Context.Value("user_name").(string); ok {
+		m.UpdatedBy = user
+	}
+	return
+}
+
+// HierarchyVersion 层级版本: 每个版本有独立的节点和有效期
+// 编辑中的版本可以修改节点, 发布后不能修改; 同一层级已发布版本的有效期不能重叠
+type HierarchyVersion struct {
+	ID            uint       `gorm:"primaryKey" json:"ID"`
+	HierarchyCode string     `gorm:"size:64;not null;uniqueIndex:idx_hierarchy_version" json:"HierarchyCode"`                     // 层级编码
+	Version       string     `gorm:"size:32;not null;uniqueIndex:idx_hierarchy_version" json:"Version" binding:"required,max=32"` // 版本号
+	ValidFrom     *time.Time `json:"ValidFrom"`                                                                                   // 生效时间, 为空时不限
+	ValidTo       *time.Time `json:"ValidTo"`                                                                                     // 失效时间 (不含), 为空时不限
+	Status        string     `gorm:"size:16;default:Draft" json:"Status"`                                                         // 状态: Draft 编辑中 Pending 审批中 Active 已发布
+	ApprovalCode  string     `gorm:"size:128;index" json:"ApprovalCode"`                                                          // 发布审批的审批编码
+	Description   string     `gorm:"size:255" json:"Description" binding:"max=255"`
+	CreatedBy     string     `gorm:"size:64" json:"CreatedBy"`
+	UpdatedBy     string     `gorm:"size:64" json:"UpdatedBy"`
+	CreatedAt     *time.Time `json:"CreatedAt"`
+	UpdatedAt     *time.Time `json:"UpdatedAt"`
+}
+
+// ValidAt 版本在时间 t 是否有效
+func (m *HierarchyVersion) ValidAt(t time.Time) bool {
+	return (m.ValidFrom == nil || !t.Before(*m.ValidFrom)) && (m.ValidTo == nil || t.Before(*m.ValidTo))
+}
+
+// Overlaps 两个版本的有效期是否重叠
+func (m *HierarchyVersion) Overlaps(other *HierarchyVersion) bool {
+	return (m.ValidTo == nil || other.ValidFrom == nil || other.ValidFrom.Before(*m.ValidTo)) &&
+		(other.ValidTo == nil || m.ValidFrom == nil || m.ValidFrom.Before(*other.ValidTo))
+}
+
+// HierarchyNode 层级节点: 版本中的一条记录及其在该版本中的父节点
+type HierarchyNode struct {
+	ID        uint       `gorm:"primaryKey" json:"-"`
+	VersionID uint       `gorm:"not null;uniqueIndex:idx_hierarchy_node" json:"-"`         // 层级版本
+	EntityID  uint       `gorm:"not null;uniqueIndex:idx_hierarchy_node" json:"entity_id"` // 记录 id
+	ParentID  uint       `gorm:"not null;default:0;index" json:"parent_id"`                // 父节点的记录 id, 0 为根节点
+	Sort      int        `gorm:"default:0" json:"sort"`                                    // 同一父节点下的顺序
+	CreatedAt *time.Time `json:"-"`
+}
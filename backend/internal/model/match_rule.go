@@ -0,0 +1,144 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 匹配方法
+const (
+	MatchMethodExact        = "exact"         // 值完全相同
+	MatchMethodNormalized   = "normalized"    // 忽略大小写、全半角、空白和标点后相同
+	MatchMethodPhonetic     = "phonetic"      // 发音相同 (Soundex), 非拉丁字母按 normalized 比较
+	MatchMethodEditDistance = "edit_distance" // 编辑距离相似度
+	MatchMethodToken        = "token"         // 词 (中文按相邻两字) 集合的 Jaccard 相似度
+)
+
+// IsValidMatchMethod 判断匹配方法是否合法
+func IsValidMatchMethod(method string) bool {
+	switch method {
+	case MatchMethodExact, MatchMethodNormalized, MatchMethodPhonetic, MatchMethodEditDistance, MatchMethodToken:
+		return true
+	}
+	return false
+}
+
+// 匹配规则命中时的处理方式
+const (
+	MatchActionWarn  = "Warn"  // 提示疑似重复, 确认后可以继续保存
+	MatchActionBlock = "Block" // 禁止保存
+)
+
+// MatchField 匹配规则中的一个字段: 按 Method 计算相似度 (0~1), 按 Weight 加权
+type MatchField struct {
+	Field  string  `json:"field"`
+	Method string  `json:"method"`
+	Weight float64 `json:"weight"`
+}
+
+// MatchFields 匹配字段列表, 以 JSON 保存
+type MatchFields []MatchField
+
+// Value 实现 driver.Valuer 接口
+func (f MatchFields) Value() (driver.Value, error) {
+	return json.Marshal(f)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (f *MatchFields) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		if s, isString := value.(string); isString {
+			bytes = []byte(s)
+		} else {
+			return errors.New("type assertion to []byte failed")
+		}
+	}
+	return json.Unmarshal(bytes, f)
+}
+
+// MatchRule 查重匹配规则: 记录的加权相似度不低于 Threshold 时视为疑似重复
+// 新增、导入时按规则查找疑似重复的记录, 查重任务按规则扫描全表生成疑似重复分组
+type MatchRule struct {
+	ID        uint        `gorm:"primaryKey" json:"ID"`
+	Code      string      `gorm:"size:64;not null;uniqueIndex" json:"Code" binding:"required,max=64"` // 规则编码
+	Name      string      `gorm:"size:128;not null" json:"Name" binding:"required,max=128"`           // 规则名称
+	TableCode string      `gorm:"size:64;not null;index" json:"TableCode" binding:"required,max=64"`  // 表编码
+	Fields    MatchFields `gorm:"type:json" json:"Fields"`                                            // 匹配字段
+	// 分组字段, 逗号分隔: 只比较这些字段值相同的记录, 如国家、公司代码
+	BlockFields string  `gorm:"size:255" json:"BlockFields" binding:"max=255"`
+	Threshold   float64 `gorm:"default:0.8" json:"Threshold"`                  // 相似度阈值 (0~1]
+	Action      string  `gorm:"size:8;default:Warn" json:"Action"`             // 命中时的处理: Warn 提示 Block 禁止保存
+	Sort        uint    `gorm:"default:0" json:"Sort"`                         // 执行顺序
+	Description string  `gorm:"size:255" json:"Description" binding:"max=255"` // 描述
+	// 状态：Normal 正常 Frozen 已冻结 Deleted 已删除
+	Status    string         `gorm:"size:8;default:Normal" json:"Status"`
+	CreatedBy string         `gorm:"size:64" json:"CreatedBy"`
+	UpdatedBy string         `gorm:"size:64" json:"UpdatedBy"`
+	CreatedAt *time.Time     `json:"CreatedAt"`
+	UpdatedAt *time.Time     `json:"UpdatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (m *MatchRule) BeforeDelete(tx *gorm.DB) (err error) {
+	if user, ok := tx.Statement.Context.Value("user_name").(string); ok {
+		m.UpdatedBy = user
+	}
+	tx.Model(m).Where("id = ?", m.ID).Updates(map[string]any{
+		"status":     "Deleted",
+		"updated_by": m.UpdatedBy,
+	})
+	return
+}
+
+func (m *MatchRule) BeforeCreate(tx *gorm.DB) (err error) {
+	if user, ok := tx.Statement.Context.Value("user_name").(string); ok {
+		m.CreatedBy = user
+	}
+	return
+}
+
+func (m *MatchRule) BeforeUpdate(tx *gorm.DB) (err error) {
+	if user, ok := tx.Statement.Context.Value("user_name").(string); ok {
+		m.UpdatedBy = user
+	}
+	return
+}
+
+// 疑似重复分组状态
+const (
+	DuplicateClusterOpen      = "Open"      // 待处理
+	DuplicateClusterConfirmed = "Confirmed" // 已确认重复
+	DuplicateClusterDismissed = "Dismissed" // 已排除 (不是重复)
+)
+
+// DuplicateCluster 查重任务生成的疑似重复分组, 由数据管理员确认或排除
+type DuplicateCluster struct {
+	ID         uint                      `gorm:"primaryKey" json:"ID"`
+	TableCode  string                    `gorm:"size:64;not null;index" json:"TableCode"` // 表编码
+	RuleCode   string                    `gorm:"size:64;not null;index" json:"RuleCode"`  // 匹配规则编码
+	JobCode    string                    `gorm:"size:64;index" json:"JobCode"`            // 生成分组的查重任务
+	Score      float64                   `json:"Score"`                                   // 分组内记录两两相似度的最高值
+	Size       int                       `json:"Size"`                                    // 记录数
+	Status     string                    `gorm:"size:16;default:Open;index" json:"Status"`
+	ReviewedBy string                    `gorm:"size:64" json:"ReviewedBy"` // 确认或排除的用户
+	ReviewedAt *time.Time                `json:"ReviewedAt"`
+	Members    []*DuplicateClusterMember `gorm:"foreignKey:ClusterID" json:"Members"`
+	CreatedAt  *time.Time                `json:"CreatedAt"`
+	UpdatedAt  *time.Time                `json:"UpdatedAt"`
+}
+
+// DuplicateClusterMember 疑似重复分组中的记录
+type DuplicateClusterMember struct {
+	ID        uint    `gorm:"primaryKey" json:"-"`
+	ClusterID uint    `gorm:"not null;index" json:"-"`
+	EntityID  uint    `gorm:"not null;index" json:"EntityID"` // 记录 id
+	Score     float64 `json:"Score"`                          // 与分组内其他记录的最高相似度
+}
@@ -0,0 +1,155 @@
+package model
+
+import "time"
+
+// 模型定义包格式, 导入时校验
+const (
+	ModelPackageFormat  = "piemdm-model"
+	ModelPackageVersion = 1
+)
+
+// 模型定义包中的对象类型
+const (
+	PackageTableKind              = "Table"
+	PackageFieldKind              = "Field"
+	PackageFieldGroupKind         = "FieldGroup"
+	PackageTableApprovalKind      = "TableApproval"
+	PackageTablePermissionKind    = "TablePermission"
+	PackageApprovalDefinitionKind = "ApprovalDefinition"
+	PackageApprovalNodeKind       = "ApprovalNode"
+)
+
+// 导入模型定义包时对象的变更动作
+const (
+	PackageCreate = "Create"
+	PackageUpdate = "Update"
+	PackageDelete = "Delete"
+)
+
+// ModelPackage 模型定义包, 用于在环境间迁移数据模型
+// 只包含与环境无关的定义: 对象按编码关联, 不含 ID 和审计字段, 列表按编码排序便于比较
+type ModelPackage struct {
+	Format              string                       `json:"format"`  // 固定为 piemdm-model
+	Version             int                          `json:"version"` // 包格式版本
+	ExportedAt          *time.Time                   `json:"exported_at,omitempty"`
+	ExportedBy          string                       `json:"exported_by,omitempty"`
+	Tables              []*PackageTable              `json:"tables"`
+	ApprovalDefinitions []*PackageApprovalDefinition `json:"approval_definitions"` // 表审批绑定引用的审批定义
+}
+
+// PackageTable 表定义及其字段、字段组、审批绑定和数据权限
+type PackageTable struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	DisplayMode string `json:"display_mode"`
+	TableType   string `json:"table_type"`
+	ParentTable string `json:"parent_table,omitempty"`
+	ParentField string `json:"parent_field,omitempty"`
+	SelfField   string `json:"self_field,omitempty"`
+	TreeTable   string `json:"tree_table,omitempty"`
+	Sort        uint   `json:"sort"`
+	Description string `json:"description,omitempty"`
+	Status      string `json:"status"`
+
+	Fields      []*PackageField           `json:"fields"`
+	FieldGroups []*PackageFieldGroup      `json:"field_groups"`
+	Approvals   []*PackageTableApproval   `json:"approvals"`
+	Permissions []*PackageTablePermission `json:"permissions"`
+}
+
+// PackageField 字段定义
+type PackageField struct {
+	Code          string        `json:"code"`
+	Name          string        `json:"name"`
+	FieldType     string        `json:"field_type"`
+	Type          string        `json:"type"`
+	Length        int           `json:"length"`
+	Required      string        `json:"required,omitempty"`
+	IsIndex       string        `json:"is_index,omitempty"`
+	IsUnique      string        `json:"is_unique,omitempty"`
+	IndexName     string        `json:"index_name,omitempty"`
+	IndexPriority int           `json:"index_priority,omitempty"`
+	Description   string        `json:"description,omitempty"`
+	IsFilter      string        `json:"is_filter,omitempty"`
+	IsShow        string        `json:"is_show,omitempty"`
+	GroupName     string        `json:"group_name,omitempty"`
+	Sort          uint          `json:"sort"`
+	Options       *FieldOptions `json:"options,omitempty"`
+	Status        string        `json:"status"`
+}
+
+// PackageFieldGroup 字段组定义
+type PackageFieldGroup struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	View        string `json:"view,omitempty"`
+	Sort        uint   `json:"sort"`
+	Collapsible string `json:"collapsible,omitempty"`
+	Collapsed   string `json:"collapsed,omitempty"`
+	ViewRoles   string `json:"view_roles,omitempty"`
+	EditRoles   string `json:"edit_roles,omitempty"`
+	Description string `json:"description,omitempty"`
+	Status      string `json:"status"`
+}
+
+// PackageTableApproval 表操作绑定的审批定义, EntityCode 为表编码或合并审批的实体编码
+type PackageTableApproval struct {
+	EntityCode      string `json:"entity_code"`
+	Operation       string `json:"operation"`
+	ApprovalDefCode string `json:"approval_def_code"`
+	Description     string `json:"description,omitempty"`
+	Status          string `json:"status"`
+}
+
+// PackageTablePermission 数据权限, 按角色编码关联, 目标环境需已有该角色
+type PackageTablePermission struct {
+	RoleCode  string `json:"role_code"`
+	Operation string `json:"operation"`
+	Status    string `json:"status"`
+}
+
+// PackageApprovalDefinition 审批定义及其节点
+type PackageApprovalDefinition struct {
+	Code        string                 `json:"code"`
+	Name        string                 `json:"name"`
+	FormData    string                 `json:"form_data,omitempty"`
+	NodeList    string                 `json:"node_list,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Platform    string                 `json:"platform"`
+	Status      string                 `json:"status"`
+	Nodes       []*PackageApprovalNode `json:"nodes"`
+}
+
+// PackageApprovalNode 审批节点
+type PackageApprovalNode struct {
+	NodeCode        string `json:"node_code"`
+	NodeName        string `json:"node_name"`
+	NodeType        string `json:"node_type"`
+	Description     string `json:"description,omitempty"`
+	SortOrder       int    `json:"sort_order"`
+	ApproverType    string `json:"approver_type,omitempty"`
+	ApproverConfig  string `json:"approver_config,omitempty"`
+	ConditionConfig string `json:"condition_config,omitempty"`
+	Status          string `json:"status"`
+}
+
+// ModelPackageChange 导入时一个对象的变更
+type ModelPackageChange struct {
+	Kind    string   `json:"kind"`
+	Key     string   `json:"key"` // 对象编码, 子对象带上所属表或审批定义, 如 product.qty
+	Action  string   `json:"action"`
+	Fields  []string `json:"fields,omitempty"` // 修改的属性
+	Before  any      `json:"before,omitempty"`
+	After   any      `json:"after,omitempty"`
+	Warning string   `json:"warning,omitempty"`
+}
+
+// ModelPackageDiff 模型定义包与目标环境的差异, 预览和导入使用同一差异
+type ModelPackageDiff struct {
+	Checksum string                `json:"checksum"` // 变更内容摘要, 导入时校验差异未变化
+	Changes  []*ModelPackageChange `json:"changes"`
+	Tables   []string              `json:"tables"`  // 需要发布表结构的表
+	Schemas  []*SchemaPlan         `json:"schemas"` // 需要发布的表的表结构变更计划
+	Errors   []string              `json:"errors"`  // 阻止导入的问题, 如引用的角色不存在
+	DataLoss bool                  `json:"data_loss"`
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 表结构变更类型, 按此顺序执行
+const (
+	SchemaCreateTable   = "CreateTable"   // 新建表
+	SchemaDropIndex     = "DropIndex"     // 删除索引
+	SchemaRenameColumn  = "RenameColumn"  // 重命名列, 保留数据
+	SchemaConvertColumn = "ConvertColumn" // 修改列类型: 新建列, 复制并转换数据, 删除旧列
+	SchemaAlterColumn   = "AlterColumn"   // 修改长度、精度或允许为空
+	SchemaAddColumn     = "AddColumn"     // 新增列
+	SchemaDropColumn    = "DropColumn"    // 删除列
+	SchemaAddIndex      = "AddIndex"      // 新增索引
+)
+
+// 表结构版本状态
+const (
+	SchemaVersionApplied = "Applied"
+	SchemaVersionFailed  = "Failed"
+)
+
+// SchemaChange 一项表结构变更
+type SchemaChange struct {
+	Kind      string `json:"kind"`
+	Column    string `json:"column,omitempty"`
+	From      string `json:"from,omitempty"` // 重命名前的列名
+	Index     string `json:"index,omitempty"`
+	Before    string `json:"before,omitempty"`  // 变更前的列类型或索引列
+	After     string `json:"after,omitempty"`   // 变更后的列类型或索引列
+	Affected  int64  `json:"affected"`          // 需要改写的行数
+	Lost      int64  `json:"lost"`              // 丢失或截断数据的行数
+	Conflicts int64  `json:"conflicts"`         // 阻止变更的行数, 如新增唯一索引时的重复值
+	Warning   string `json:"warning,omitempty"` // 数据丢失或冲突说明
+}
+
+// SchemaTablePlan 一张表的变更计划
+type SchemaTablePlan struct {
+	Table   string          `json:"table"`
+	Exists  bool            `json:"exists"`
+	Rows    int64           `json:"rows"`
+	Changes []*SchemaChange `json:"changes"`
+}
+
+// SchemaPlan 发布表结构的变更计划, 预览和发布使用同一计划
+type SchemaPlan struct {
+	TableCode string             `json:"table_code"`
+	Version   int                `json:"version"`   // 发布后的表结构版本
+	Checksum  string             `json:"checksum"`  // 变更内容摘要, 发布时校验计划未变化
+	DataLoss  bool               `json:"data_loss"` // 发布会丢失数据, 需要确认
+	Conflicts bool               `json:"conflicts"` // 存在冲突, 不能发布
+	Tables    []*SchemaTablePlan `json:"tables"`
+}
+
+// HasChanges 判断计划是否包含变更
+func (p *SchemaPlan) HasChanges() bool {
+	for _, table := range p.Tables {
+		if len(table.Changes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaVersion 表结构版本, 每次发布变更记录一条
+type SchemaVersion struct {
+	ID        uint            `gorm:"primaryKey" json:"ID"`
+	TableCode string          `gorm:"size:64;not null;index" json:"TableCode"`
+	Version   int             `gorm:"not null" json:"Version"`
+	Checksum  string          `gorm:"size:64" json:"Checksum"`
+	Plan      json.RawMessage `gorm:"type:json" json:"Plan"` // 执行的变更计划
+	Status    string          `gorm:"size:16;index" json:"Status"`
+	Error     string          `gorm:"size:1024" json:"Error"` // 发布失败的原因
+	CreatedBy string          `gorm:"size:64" json:"CreatedBy"`
+	CreatedAt *time.Time      `json:"CreatedAt"`
+}
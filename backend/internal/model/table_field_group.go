@@ -1,12 +1,15 @@
 package model
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
-// 审批定义
+// TableFieldGroup 字段组, 决定表单、导入模板和审批表单中字段的分组和顺序
+// 字段的 GroupName 保存字段组编码, 兼容按字段组名称关联的旧数据
 type TableFieldGroup struct {
 	ID        uint   `gorm:"primarykey"`
 	Code      string `gorm:"size:64;unique;not null" binding:"required,max=64"` // 字段组code
@@ -14,6 +17,12 @@ type TableFieldGroup struct {
 	TableCode string `gorm:"size:64" binding:"required,max=64"`                 // 实体编码
 	View      string `gorm:"size:32" binding:"max=32"`                          // 视图编码
 	Sort      uint   `gorm:"size:10;default:0"`                                 // 显示顺序
+	// 是否可折叠、是否默认折叠: Yes/No
+	Collapsible string `gorm:"size:8;default:No" binding:"omitempty,oneof=Yes No"`
+	Collapsed   string `gorm:"size:8;default:No" binding:"omitempty,oneof=Yes No"`
+	// 可查看、可修改的角色编码, 逗号分隔, 为空时不限制; 可修改的角色还需要能查看
+	ViewRoles string `gorm:"size:255" binding:"max=255"`
+	EditRoles string `gorm:"size:255" binding:"max=255"`
 	// 状态：Normal 正常 Frozen 已冻结 Deleted 已删除
 	Status      string `gorm:"size:8;default:Normal"`
 	Description string `gorm:"size:255" binding:"max=255"` // 备注
@@ -24,6 +33,40 @@ type TableFieldGroup struct {
 	DeletedAt   gorm.DeletedAt `gorm:"index"`
 }
 
+// CanView 拥有指定角色的用户能否查看字段组
+func (m *TableFieldGroup) CanView(roles []string) bool {
+	return matchRoles(m.ViewRoles, roles)
+}
+
+// CanEdit 拥有指定角色的用户能否修改字段组中的字段
+func (m *TableFieldGroup) CanEdit(roles []string) bool {
+	return m.CanView(roles) && matchRoles(m.EditRoles, roles)
+}
+
+// GroupRoles 拆分逗号分隔的角色编码
+func GroupRoles(value string) []string {
+	var roles []string
+	for _, role := range strings.Split(value, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+func matchRoles(allowed string, roles []string) bool {
+	codes := GroupRoles(allowed)
+	if len(codes) == 0 {
+		return true
+	}
+	for _, role := range roles {
+		if slices.Contains(codes, role) {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *TableFieldGroup) BeforeDelete(tx *gorm.DB) (err error) {
 	if user, ok := tx.Statement.Context.Value("user_name").(string); ok {
 		m.UpdatedBy = user
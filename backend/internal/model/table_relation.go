@@ -6,6 +6,8 @@ import (
 	"gorm.io/gorm"
 )
 
+// TableRelation 关联视图: 查看记录时同时查看关联模型中引用该记录的数据, 如供应商的银行账户和联系人
+// RelationCode 为关联模型中引用该模型的字段; 关系字段按其配置的值字段关联, 其它字段按该模型的 code 关联
 type TableRelation struct {
 	ID            uint   `gorm:"primaryKey"`
 	TableCode     string `gorm:"size:64" binding:"max=64"`   // 模型
@@ -15,6 +17,8 @@ type TableRelation struct {
 	Sort          uint   `gorm:"size:10;default:0"`          // 显示顺序
 	// 状态：Normal 正常 Frozen 已冻结 Deleted 已删除
 	Status    string `gorm:"size:8;default:Normal"`
+	CreatedBy string `gorm:"size:64" json:",omitempty"`
+	UpdatedBy string `gorm:"size:64" json:",omitempty"`
 	CreatedAt *time.Time
 	UpdatedAt *time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
@@ -12,11 +12,11 @@ type WebhookDelivery struct {
 	Event           string     `gorm:"size:32;" binding:"max=32"`  // 事件名称
 	EntityID        uint       `gorm:"size:64" binding:"required"` // 实体编码
 	RequestHeaders  string     `gorm:"type:text"`                  // 请求头
-	RequestPayload  string     `gorm:"type:mediumtext"`            // 请求参数
+	RequestPayload  string     `gorm:"size:16777215"`              // 请求参数 (MySQL 为 mediumtext, 其它数据库为 text)
 	ResponseStatus  int        `gorm:"size:10" binding:"max=999"`  // 回应状态
-	ResponseMessage string     `gorm:"type:mediumtext"`            // 回应消息
+	ResponseMessage string     `gorm:"size:16777215"`              // 回应消息
 	ResponseHeaders string     `gorm:"type:text"`                  // 回应头
-	ResponseBody    string     `gorm:"type:mediumtext"`            // 回应信息
+	ResponseBody    string     `gorm:"size:16777215"`              // 回应信息
 	DeliveredAt     *time.Time // 投递时间
 	CompletedAt     *time.Time // 完成时间
 }
@@ -414,8 +414,8 @@ func (r *approvalTaskRepository) FindTasksNeedRemind() ([]*model.ApprovalTask, e
 	var approvalTasks []*model.ApprovalTask
 	// 查找需要催办的任务：状态为待处理，且距离上次催办时间超过催办间隔
 	if err := r.db.Where(`status = ? AND deleted_at IS NULL AND
-		(last_remind_at IS NULL OR last_remind_at < DATE_SUB(NOW(), INTERVAL 24 HOUR))`,
-		model.TaskStatusPending).Find(&approvalTasks).Error; err != nil {
+		(last_remind_at IS NULL OR last_remind_at < ?)`,
+		model.TaskStatusPending, time.Now().Add(-24*time.Hour)).Find(&approvalTasks).Error; err != nil {
 		return nil, err
 	}
 	return approvalTasks, nil
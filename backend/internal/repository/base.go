@@ -158,7 +158,7 @@ func (r *base) Find(model any, out any, sel string, where map[string]any, orders
 // GetPages 分页返回数据
 func (r *base) FindPage(model any, out any, pageIndex, pageSize int, totalCount *int64, where map[string]any, preloads []string, orders ...string) error {
 	// build Condition string
-	conditionString, values, _ := BuildCondition(where)
+	conditionString, values, _ := r.BuildCondition(where)
 
 	db := r.db.Model(model).Where(model)
 	// db = db.Where(map[string]any{"ID": 294})
@@ -193,7 +193,7 @@ func (r *base) FindPage(model any, out any, pageIndex, pageSize int, totalCount
 // FindPageWithScopes 分页返回数据，支持 GORM Scopes
 func (r *base) FindPageWithScopes(model any, out any, pageIndex, pageSize int, totalCount *int64, where map[string]any, preloads []string, scopes []func(*gorm.DB) *gorm.DB, orders ...string) error {
 	// build Condition string
-	conditionString, values, _ := BuildCondition(where)
+	conditionString, values, _ := r.BuildCondition(where)
 
 	db := r.db.Model(model).Where(model)
 	// Apply Scopes
@@ -5,7 +5,8 @@ import (
 	"strings"
 )
 
-func BuildCondition(where map[string]any) (whereSql string,
+// BuildCondition 按连接的数据库方言构建查询条件
+func (r *Repository) BuildCondition(where map[string]any) (whereSql string,
 	values []any, err error,
 ) {
 
@@ -146,8 +147,8 @@ func BuildCondition(where map[string]any) (whereSql string,
 					return "", nil, fmt.Errorf("unsupported type for 'notin' operator: %T", value)
 				}
 			case "like":
-				// "code like" : "%111%"
-				whereSql += fmt.Sprint(field, " like ?")
+				// "code like" : "%111%", 不区分大小写
+				whereSql += r.dialect.like(field)
 				values = append(values, value.(string))
 			}
 		default:
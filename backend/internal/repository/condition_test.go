@@ -66,14 +66,14 @@ func TestBuildCondition_MultiValue(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotWhereSql, gotValues, err := BuildCondition(tt.where)
+			gotWhereSql, gotValues, err := NewRepository(nil, nil, nil).BuildCondition(tt.where)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("BuildCondition() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 
 			if tt.name == "mixed conditions" {
-				assert.Contains(t, gotWhereSql, "name like ?")
+				assert.Contains(t, gotWhereSql, "name LIKE ?")
 				assert.Contains(t, gotWhereSql, "status in ?")
 				assert.Len(t, gotValues, tt.wantValuesLen)
 			} else {
@@ -0,0 +1,287 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"piemdm/pkg/helper/md5"
+
+	"gorm.io/gorm"
+)
+
+// dialect 数据库方言: 动态表的列类型、类型转换、JSON 路径查询和模糊匹配等各数据库写法不同的 SQL
+// 支持 MySQL (默认)、PostgreSQL 和 SQLite
+type dialect interface {
+	name() string
+	// dateTimeType 日期时间字段的列类型
+	dateTimeType() string
+	// jsonType JSON 字段的列类型
+	jsonType() string
+	// indexName 字段定义的索引在库中的名称
+	indexName(tableName, name string) string
+
+	// like 模糊匹配条件, 不区分大小写, 通配符以 \ 转义
+	like(column string) string
+	// nullsOrder 排序时空值的位置, 统一为升序在前、降序在后
+	nullsOrder(desc bool) string
+
+	// jsonPath JSON 路径参数, segments 为 .属性 或 [下标]
+	jsonPath(segments []string) string
+	// jsonText 路径上去掉引号的文本值, 带一个路径参数
+	jsonText(column string) string
+	// jsonValue 路径上的 JSON 值, 与 jsonNumber 参数比较时按数值比较, 带一个路径参数
+	jsonValue(column string) string
+	jsonNumber() string
+	// jsonTypeOf 路径上的值的类型, 带一个路径参数; 值为 null 时等于 jsonNull
+	jsonTypeOf(column string) string
+	jsonNull() string
+	// jsonValid 文本是否为合法的 JSON
+	jsonValid(column string) string
+
+	// cast 转换为指定列类型的表达式
+	cast(column string, to columnSpec) string
+	// textPattern 文本可以转换为指定列类型的条件
+	textPattern(column string, to columnSpec) (string, []any)
+	length(value string) string
+	truncate(value string, length int) string
+	// timeOfDay 日期时间中的时间部分, 格式为 00:00:00
+	timeOfDay(value string) string
+}
+
+// dialectOf 数据库连接对应的方言, 未知的数据库按 MySQL 处理
+func dialectOf(db *gorm.DB) dialect {
+	if db == nil || db.Dialector == nil {
+		return mysqlDialect{}
+	}
+	switch db.Dialector.Name() {
+	case "postgres":
+		return postgresDialect{}
+	case "sqlite":
+		return sqliteDialect{}
+	}
+	return mysqlDialect{}
+}
+
+// textPatterns 文本可以转换为各列类型的正则表达式 (MySQL REGEXP 与 PostgreSQL ~ 通用)
+var textPatterns = map[string]string{
+	"Integer":  `^-?[0-9]+$`,
+	"Decimal":  `^-?[0-9]+(\.[0-9]+)?$`,
+	"Date":     `^[0-9]{4}-[0-9]{2}-[0-9]{2}$`,
+	"DateTime": `^[0-9]{4}-[0-9]{2}-[0-9]{2}([ T][0-9]{2}:[0-9]{2}(:[0-9]{2}(\.[0-9]+)?)?)?$`,
+}
+
+// jsonPathExpression MySQL 和 SQLite 的 JSON 路径, 如 $."address"."city", $."items"[0]
+func jsonPathExpression(segments []string) string {
+	path := "$"
+	for _, segment := range segments {
+		if strings.HasPrefix(segment, "[") {
+			path += segment
+		} else {
+			path += `."` + segment[1:] + `"`
+		}
+	}
+	return path
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) name() string         { return "mysql" }
+func (mysqlDialect) dateTimeType() string { return "datetime(3)" }
+func (mysqlDialect) jsonType() string     { return "json" }
+
+// indexName MySQL 的索引名称在表内唯一, 直接使用字段定义的名称
+func (mysqlDialect) indexName(tableName, name string) string { return name }
+
+func (mysqlDialect) like(column string) string   { return column + " LIKE ?" }
+func (mysqlDialect) nullsOrder(desc bool) string { return "" }
+
+func (mysqlDialect) jsonPath(segments []string) string { return jsonPathExpression(segments) }
+func (mysqlDialect) jsonText(column string) string {
+	return "JSON_UNQUOTE(JSON_EXTRACT(" + column + ", ?))"
+}
+func (mysqlDialect) jsonValue(column string) string { return "JSON_EXTRACT(" + column + ", ?)" }
+func (mysqlDialect) jsonNumber() string             { return "?" }
+func (mysqlDialect) jsonTypeOf(column string) string {
+	return "JSON_TYPE(JSON_EXTRACT(" + column + ", ?))"
+}
+func (mysqlDialect) jsonNull() string               { return "NULL" }
+func (mysqlDialect) jsonValid(column string) string { return "JSON_VALID(" + column + ")" }
+
+func (mysqlDialect) cast(column string, to columnSpec) string {
+	switch to.kind {
+	case "Integer":
+		return "CAST(" + column + " AS SIGNED)"
+	case "Decimal":
+		return fmt.Sprintf("CAST(%s AS DECIMAL(%d,%d))", column, to.precision, to.scale)
+	case "Date":
+		return "CAST(" + column + " AS DATE)"
+	case "DateTime":
+		return "CAST(" + column + " AS DATETIME(3))"
+	case "JSON":
+		return column
+	}
+	return "CAST(" + column + " AS CHAR)"
+}
+
+func (mysqlDialect) textPattern(column string, to columnSpec) (string, []any) {
+	if to.kind == "JSON" {
+		return "JSON_VALID(" + column + ")", nil
+	}
+	return column + " REGEXP ?", []any{textPatterns[to.kind]}
+}
+
+func (mysqlDialect) length(value string) string { return "CHAR_LENGTH(" + value + ")" }
+func (mysqlDialect) truncate(value string, length int) string {
+	return fmt.Sprintf("LEFT(%s, %d)", value, length)
+}
+func (mysqlDialect) timeOfDay(value string) string { return "TIME(" + value + ")" }
+
+// postgresDialect PostgreSQL: JSON 字段使用 jsonb, 路径以逗号分隔的文本传入, 如 address,city
+// 文本转换为 JSON 时的校验 (IS JSON) 需要 PostgreSQL 16 及以上版本
+type postgresDialect struct{}
+
+func (postgresDialect) name() string         { return "postgres" }
+func (postgresDialect) dateTimeType() string { return "timestamptz(3)" }
+func (postgresDialect) jsonType() string     { return "jsonb" }
+
+// indexName PostgreSQL 的索引名称在库内唯一, 加上表名, 超过 63 字符时使用摘要
+func (postgresDialect) indexName(tableName, name string) string {
+	return tableIndexName(tableName, name)
+}
+
+// like PostgreSQL 的 LIKE 区分大小写, 使用 ILIKE 与 MySQL 保持一致
+func (postgresDialect) like(column string) string { return column + " ILIKE ?" }
+
+// nullsOrder PostgreSQL 默认升序时空值在后, 按 MySQL 的规则指定
+func (postgresDialect) nullsOrder(desc bool) string {
+	if desc {
+		return " NULLS LAST"
+	}
+	return " NULLS FIRST"
+}
+
+// jsonPath 属性名已由 jsonPathPattern 限制, 不含逗号
+func (postgresDialect) jsonPath(segments []string) string {
+	parts := make([]string, len(segments))
+	for i, segment := range segments {
+		parts[i] = strings.Trim(segment, ".[]")
+	}
+	return strings.Join(parts, ",")
+}
+func (postgresDialect) jsonText(column string) string {
+	return "(CAST(" + column + " AS jsonb) #>> string_to_array(?, ','))"
+}
+func (postgresDialect) jsonValue(column string) string {
+	return "(CAST(" + column + " AS jsonb) #> string_to_array(?, ','))"
+}
+func (postgresDialect) jsonNumber() string { return "to_jsonb(CAST(? AS double precision))" }
+func (d postgresDialect) jsonTypeOf(column string) string {
+	return "jsonb_typeof" + d.jsonValue(column)
+}
+func (postgresDialect) jsonNull() string               { return "null" }
+func (postgresDialect) jsonValid(column string) string { return column + " IS JSON" }
+
+func (postgresDialect) cast(column string, to columnSpec) string {
+	switch to.kind {
+	case "Integer":
+		return "CAST(" + column + " AS BIGINT)"
+	case "Decimal":
+		return fmt.Sprintf("CAST(%s AS NUMERIC(%d,%d))", column, to.precision, to.scale)
+	case "Date":
+		return "CAST(" + column + " AS DATE)"
+	case "DateTime":
+		return "CAST(" + column + " AS TIMESTAMPTZ(3))"
+	case "JSON":
+		return "CAST(" + column + " AS jsonb)"
+	}
+	return "CAST(" + column + " AS TEXT)"
+}
+
+func (d postgresDialect) textPattern(column string, to columnSpec) (string, []any) {
+	if to.kind == "JSON" {
+		return d.jsonValid(column), nil
+	}
+	return column + " ~ ?", []any{textPatterns[to.kind]}
+}
+
+func (postgresDialect) length(value string) string { return "CHAR_LENGTH(" + value + ")" }
+func (postgresDialect) truncate(value string, length int) string {
+	return fmt.Sprintf("LEFT(%s, %d)", value, length)
+}
+func (postgresDialect) timeOfDay(value string) string { return "CAST(" + value + " AS TIME)" }
+
+// sqliteDialect SQLite: 用于单机部署和试用, 日期时间和 JSON 以文本存储
+type sqliteDialect struct{}
+
+func (sqliteDialect) name() string         { return "sqlite" }
+func (sqliteDialect) dateTimeType() string { return "datetime" }
+func (sqliteDialect) jsonType() string     { return "json" }
+
+// indexName SQLite 的索引名称在库内唯一, 加上表名
+func (sqliteDialect) indexName(tableName, name string) string { return tableIndexName(tableName, name) }
+
+// like SQLite 的 LIKE 没有默认的转义字符
+func (sqliteDialect) like(column string) string   { return column + ` LIKE ? ESCAPE '\'` }
+func (sqliteDialect) nullsOrder(desc bool) string { return "" }
+
+func (sqliteDialect) jsonPath(segments []string) string { return jsonPathExpression(segments) }
+
+// jsonText json_extract 返回 SQL 值, 转换为文本后与参数比较
+func (sqliteDialect) jsonText(column string) string {
+	return "CAST(json_extract(" + column + ", ?) AS TEXT)"
+}
+func (sqliteDialect) jsonValue(column string) string  { return "json_extract(" + column + ", ?)" }
+func (sqliteDialect) jsonNumber() string              { return "?" }
+func (sqliteDialect) jsonTypeOf(column string) string { return "json_type(" + column + ", ?)" }
+func (sqliteDialect) jsonNull() string                { return "null" }
+func (sqliteDialect) jsonValid(column string) string  { return "json_valid(" + column + ")" }
+
+func (sqliteDialect) cast(column string, to columnSpec) string {
+	switch to.kind {
+	case "Integer":
+		return "CAST(" + column + " AS INTEGER)"
+	case "Decimal":
+		return "CAST(" + column + " AS REAL)"
+	case "Date":
+		return "DATE(" + column + ")"
+	case "DateTime":
+		return "DATETIME(" + column + ")"
+	case "JSON":
+		return column
+	}
+	return "CAST(" + column + " AS TEXT)"
+}
+
+// textPattern SQLite 没有正则表达式, 以 GLOB 匹配
+func (d sqliteDialect) textPattern(column string, to columnSpec) (string, []any) {
+	unsigned := "LTRIM(" + column + ", '-')"
+	switch to.kind {
+	case "JSON":
+		return d.jsonValid(column), nil
+	case "Integer":
+		return unsigned + " GLOB '[0-9]*' AND " + unsigned + " NOT GLOB '*[^0-9]*'", nil
+	case "Decimal":
+		return unsigned + " GLOB '[0-9]*' AND " + unsigned + " NOT GLOB '*[^0-9.]*' AND " + column + " NOT GLOB '*.*.*'", nil
+	case "Date":
+		return column + " GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]'", nil
+	}
+	return column + " GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]*'", nil
+}
+
+func (sqliteDialect) length(value string) string { return "LENGTH(" + value + ")" }
+func (sqliteDialect) truncate(value string, length int) string {
+	return fmt.Sprintf("SUBSTR(%s, 1, %d)", value, length)
+}
+func (sqliteDialect) timeOfDay(value string) string { return "TIME(" + value + ")" }
+
+// tableIndexName 索引名称在库内唯一的数据库中, 索引名称加上表名; 超过 63 字符 (PostgreSQL 的限制) 时使用摘要
+func tableIndexName(tableName, name string) string {
+	if strings.HasPrefix(name, tableName+"_") {
+		return name
+	}
+	full := tableName + "_" + name
+	if len(full) > 63 {
+		full = "idx_" + md5.Md5(full)
+	}
+	return full
+}
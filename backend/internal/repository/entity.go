@@ -1,12 +1,16 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"strings"
+	"time"
 
 	"piemdm/internal/model"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
@@ -63,22 +67,43 @@ import (
 type EntityRepository interface {
 	// 基础查询
 	FindOne(tableCode string, id uint) (map[string]any, error)
-	FindPage(tableCode string, page, pageSize int, total *int64, where map[string]any) ([]map[string]any, error)
+	FindPage(tableCode string, page, pageSize int, total *int64, query *CompiledEntityQuery) ([]map[string]any, error)
+	FindDeletedPage(tableCode string, page, pageSize int, total *int64, query *CompiledEntityQuery) ([]map[string]any, error)
+	FindChunk(tableCode string, query *CompiledEntityQuery, last map[string]any, limit int) ([]map[string]any, error)
+	Count(tableCode string, query *CompiledEntityQuery) (int64, error)
 	FindLogPage(tableCode string, page, pageSize int, total *int64, where map[string]any) ([]map[string]any, error)
 	Find(tableCode string, selectString string, where map[string]any) ([]map[string]any, error)
+	FindWithDeleted(tableCode string, selectString string, where map[string]any) ([]map[string]any, error)
+	FindLinked(tableCode, fieldCode string, values []string) ([]uint, error)
+	CompileQuery(tableCode string, query *model.EntityQuery, columns map[string]string, alias string) (*CompiledEntityQuery, error)
 
 	// Base CRUD
 	Create(c *gin.Context, tableCode string, entityMap any) error
 	Update(c *gin.Context, tableCode string, entity any, where map[string]any) error
-	Delete(c *gin.Context, tableCode string, id uint) error
+	Delete(c *gin.Context, tableCode string, id uint, reason string) error
 
 	// Batch operations
-	BatchUpdate(c *gin.Context, tableCode string, ids []uint, entityMap map[string]any) error
-	BatchDelete(c *gin.Context, tableCode string, ids []uint) error
+	BatchUpdate(c *gin.Context, tableCode string, ids []uint, versions map[uint]uint, entityMap map[string]any) error
+	BatchDelete(c *gin.Context, tableCode string, ids []uint, reason string) error
+
+	// 回收站
+	Restore(c *gin.Context, tableCode string, ids []uint) error
+	Purge(c *gin.Context, tableCode string, ids []uint) error
+
+	// 树形结构
+	FindChildren(tableCode string, parentIDs []uint, withDeleted bool) ([]map[string]any, error)
+	MoveSubtree(c *gin.Context, tableCode string, entity map[string]any, where map[string]any, descendants []*TreePath) error
+
 	// 统计操作
 	GetStatisticsByStatus(tableCode string) (map[string]int64, error)
+
+	// 事务
+	Transaction(c *gin.Context, fn func(repo EntityRepository) error) error
 }
 
+// ErrVersionConflict 按版本修改数据时版本不一致, 记录在读取后已被其他人修改
+var ErrVersionConflict = errors.New("数据已被其他人修改")
+
 type entityRepository struct {
 	*Repository
 	source Base
@@ -120,11 +145,24 @@ func (r *entityRepository) FindOne(tableCode string, id uint) (map[string]any, e
 	if err := r.db.Table(table).Where("id = ?", id).Take(&entity).Error; err != nil {
 		return nil, err
 	}
+	if err := r.attachLinks(tableCode, r.linkFields(tableCode), []map[string]any{entity}); err != nil {
+		return nil, err
+	}
 
 	return entity, nil
 }
 
-func (r *entityRepository) FindPage(tableCode string, page, pageSize int, total *int64, where map[string]any) ([]map[string]any, error) {
+// FindPage 分页查询, query 为已按字段白名单编译的结构化查询 (见 CompileQuery)
+func (r *entityRepository) FindPage(tableCode string, page, pageSize int, total *int64, query *CompiledEntityQuery) ([]map[string]any, error) {
+	return r.findPage(tableCode, "t.deleted_at is null", page, pageSize, total, query)
+}
+
+// FindDeletedPage 分页查询回收站中已删除的记录
+func (r *entityRepository) FindDeletedPage(tableCode string, page, pageSize int, total *int64, query *CompiledEntityQuery) ([]map[string]any, error) {
+	return r.findPage(tableCode, "t.deleted_at is not null", page, pageSize, total, query)
+}
+
+func (r *entityRepository) findPage(tableCode, deleted string, page, pageSize int, total *int64, query *CompiledEntityQuery) ([]map[string]any, error) {
 	tableName := tableCode
 	table := r.getTableName(tableName)
 
@@ -132,28 +170,36 @@ func (r *entityRepository) FindPage(tableCode string, page, pageSize int, total
 	// - 后端只返回原始 code 值(单选)或 code 数组(多选)
 	// - 前端负责查询字典并格式化显示为 "code name"
 	// - 优点: 简化后端,提高性能,统一处理方式
-
-	// 构建条件
-	conditionString, values, _ := BuildCondition(where)
+	if query == nil {
+		query = &CompiledEntityQuery{}
+	}
+	selectSql := query.Select
+	if selectSql == "" {
+		selectSql = "t.*"
+	}
+	order := query.Order
+	if order == "" {
+		order = "t.id desc"
+	}
 
 	// 执行查询 - 直接查询主表,不做 JOIN
 	var entities []map[string]any
-	query := r.db.Table(table + " t").
-		Select("t.*").
-		Where("t.deleted_at is null")
+	db := r.db.Table(table + " t").
+		Select(selectSql).
+		Where(deleted)
 
 	// 添加条件
-	if conditionString != "" {
-		query = query.Where(conditionString, values...)
+	if query.Where != "" {
+		db = db.Where(query.Where, query.Values...)
 	}
 
 	// 分页查询
-	if err := query.Order("t.id desc").
+	if err := db.Order(order).
 		Offset((page - 1) * pageSize).
 		Limit(pageSize).
 		Find(&entities).Error; err != nil {
-		// Check for MySQL Error 1146: Table doesn't exist
-		if strings.Contains(err.Error(), "Error 1146") {
+		// 表结构尚未发布时返回空列表
+		if isMissingTable(err) {
 			r.logger.Warn("Table does not exist, suppressing error", "table", table)
 			return []map[string]any{}, nil
 		}
@@ -163,22 +209,80 @@ func (r *entityRepository) FindPage(tableCode string, page, pageSize int, total
 
 	// 查询总数
 	countQuery := r.db.Table(table + " t").
-		Where("t.deleted_at is null")
+		Where(deleted)
 
-	if conditionString != "" {
-		countQuery = countQuery.Where(conditionString, values...)
+	if query.Where != "" {
+		countQuery = countQuery.Where(query.Where, query.Values...)
 	}
 
 	if err := countQuery.Count(total).Error; err != nil {
 		r.logger.Error("查询总数出错", "err", err)
 	}
 
+	if err := r.attachLinks(tableCode, query.Links, entities); err != nil {
+		r.logger.Error("查询关联表失败", "err", err)
+		return nil, err
+	}
+
 	// 前端统一查询方案: 不再需要处理 JSON_OBJECT
 	// 后端直接返回原始 code 值,由前端查询字典并格式化
 
 	return entities, nil
 }
 
+// FindChunk 按查询排序分批读取, 用于大数据量导出
+// last 为上一批的最后一条记录 (需包含排序字段), 为空时读取第一批; 按游标定位, 不使用 OFFSET
+func (r *entityRepository) FindChunk(tableCode string, query *CompiledEntityQuery, last map[string]any, limit int) ([]map[string]any, error) {
+	table := r.getTableName(tableCode)
+	if query == nil {
+		query = &CompiledEntityQuery{}
+	}
+	selectSql := query.Select
+	if selectSql == "" {
+		selectSql = "t.*"
+	}
+	order := query.Order
+	if order == "" {
+		order = "t.id desc"
+	}
+
+	db := r.db.Table(table + " t").
+		Select(selectSql).
+		Where("t.deleted_at is null")
+	if query.Where != "" {
+		db = db.Where(query.Where, query.Values...)
+	}
+	if keyset, values := query.KeysetCondition(last); keyset != "" {
+		db = db.Where(keyset, values...)
+	}
+
+	var entities []map[string]any
+	if err := db.Order(order).Limit(limit).Find(&entities).Error; err != nil {
+		r.logger.Error("分批查询失败", "table", table, "err", err)
+		return nil, err
+	}
+	if err := r.attachLinks(tableCode, query.Links, entities); err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// Count 统计符合查询条件的记录数
+func (r *entityRepository) Count(tableCode string, query *CompiledEntityQuery) (int64, error) {
+	table := r.getTableName(tableCode)
+	db := r.db.Table(table + " t").Where("t.deleted_at is null")
+	if query != nil && query.Where != "" {
+		db = db.Where(query.Where, query.Values...)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		r.logger.Error("查询总数出错", "table", table, "err", err)
+		return 0, err
+	}
+	return total, nil
+}
+
 func (r *entityRepository) FindLogPage(tableCode string, page, pageSize int, total *int64, where map[string]any) ([]map[string]any, error) {
 	tableName := tableCode
 	table := r.getTableName(tableName)
@@ -202,15 +306,36 @@ func (r *entityRepository) Find(tableCode, selectString string, where map[string
 	var entities []map[string]any
 
 	// 构建条件
-	conditionString, values, _ := BuildCondition(where)
+	conditionString, values, _ := r.BuildCondition(where)
 
+	// 多对多字段从关联表读取
+	selectString, links := r.selectLinks(tableCode, selectString)
 	if err := r.db.Table(table).Select(selectString).Where("deleted_at is null").Where(conditionString, values...).Find(&entities).Error; err != nil {
-		r.logger.Error("Can't find Entity", "err", err)
+		return nil, err
+	}
+	if err := r.attachLinks(tableCode, links, entities); err != nil {
+		return nil, err
 	}
 
 	return entities, nil
 }
 
+// FindWithDeleted 按条件查询, 包含已删除 (deleted_at 不为空) 的记录, 用于还原历史数据
+func (r *entityRepository) FindWithDeleted(tableCode, selectString string, where map[string]any) ([]map[string]any, error) {
+	table := r.getTableName(tableCode)
+	var entities []map[string]any
+
+	conditionString, values, _ := r.BuildCondition(where)
+	selectString, links := r.selectLinks(tableCode, selectString)
+	if err := r.db.Table(table).Select(selectString).Where(conditionString, values...).Find(&entities).Error; err != nil {
+		return nil, err
+	}
+	if err := r.attachLinks(tableCode, links, entities); err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
 // TODO 可以传入map，统一转为 struct
 func (r *entityRepository) Create(c *gin.Context, tableCode string, entity any) error {
 	table := r.getTableName(tableCode)
@@ -220,6 +345,9 @@ func (r *entityRepository) Create(c *gin.Context, tableCode string, entity any)
 		// 生成entity map
 		entityNew := r.stfr.BuildEntity(tableCode)
 
+		// 多对多字段的值写入关联表
+		entityMap, links := r.splitLinks(tableCode, entityMap)
+
 		// 过滤掉非数据库字段
 		excludeFields := map[string]bool{
 			"table_code": true,
@@ -232,6 +360,10 @@ func (r *entityRepository) Create(c *gin.Context, tableCode string, entity any)
 				entityNew[k] = v
 			}
 		}
+		// id 为零值时由数据库生成自增 id
+		if id, ok := linkOwnerID(entityNew["id"]); ok && id == 0 {
+			delete(entityNew, "id")
+		}
 
 		// 查询配置了unique index的字段
 		uniqueFields, err := r.stfr.Find("code,is_unique,index_name", map[string]any{
@@ -243,11 +375,14 @@ func (r *entityRepository) Create(c *gin.Context, tableCode string, entity any)
 		}
 
 		// 构建OnConflict的Columns
+		// PostgreSQL 和 SQLite 的冲突检测列必须与一个唯一索引一致: 使用第一个唯一索引 (同名索引的字段组成联合索引)
 		var conflictColumns []clause.Column
 		if len(uniqueFields) > 0 {
-			// 使用unique fields作为冲突检测列
+			first := uniqueFields[0]
 			for _, field := range uniqueFields {
-				conflictColumns = append(conflictColumns, clause.Column{Name: field.Code})
+				if field == first || (first.IndexName != "" && field.IndexName == first.IndexName) {
+					conflictColumns = append(conflictColumns, clause.Column{Name: field.Code})
+				}
 			}
 		} else {
 			// 如果没有配置unique index,使用id作为默认冲突检测列
@@ -263,18 +398,33 @@ func (r *entityRepository) Create(c *gin.Context, tableCode string, entity any)
 
 		// 添加所有非冲突列到更新列表
 		for k := range entityNew {
-			if !conflictColumnNames[k] && k != "id" && k != "created_at" && k != "deleted_at" {
+			if !conflictColumnNames[k] && k != "id" && k != "created_at" && k != "deleted_at" && k != "version" {
 				updateColumns = append(updateColumns, k)
 			}
 		}
+		doUpdates := clause.AssignmentColumns(updateColumns)
+		// 覆盖已有记录时版本号加 1
+		if !strings.HasSuffix(tableCode, "_draft") {
+			doUpdates = append(doUpdates, clause.Assignment{Column: clause.Column{Name: "version"}, Value: gorm.Expr("version + 1")})
+		}
 
 		// 配置OnConflict,指定冲突列和要更新的列
-		if err := r.db.WithContext(c).Table(table).Clauses(clause.OnConflict{
-			Columns:   conflictColumns,
-			DoUpdates: clause.AssignmentColumns(updateColumns),
-		}).Create(entityNew).Error; err != nil {
-			return err
-		}
+		return r.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Table(table).Clauses(clause.OnConflict{
+				Columns:   conflictColumns,
+				DoUpdates: doUpdates,
+			}).Create(entityNew).Error; err != nil {
+				return err
+			}
+			if len(links) == 0 {
+				return nil
+			}
+			id, err := r.createdID(tx, table, entityNew, conflictColumns)
+			if err != nil {
+				return err
+			}
+			return r.saveLinks(tx, c, tableCode, id, links)
+		})
 	default:
 		if err := r.db.WithContext(c).Table(table).Create(entity).Error; err != nil {
 			return err
@@ -284,40 +434,198 @@ func (r *entityRepository) Create(c *gin.Context, tableCode string, entity any)
 	return nil
 }
 
+// createdID 新增记录的 id: 按唯一索引覆盖已有记录时按唯一字段查询, 否则使用自增 id
+func (r *entityRepository) createdID(tx *gorm.DB, table string, entity map[string]any, conflictColumns []clause.Column) (uint, error) {
+	if id, ok := linkOwnerID(entity["id"]); ok && id > 0 {
+		return id, nil
+	}
+	if len(conflictColumns) > 0 && conflictColumns[0].Name != "id" {
+		db := tx.Table(table)
+		for _, column := range conflictColumns {
+			db = db.Where(column.Name+" = ?", entity[column.Name])
+		}
+		var ids []uint
+		if err := db.Limit(1).Pluck("id", &ids).Error; err != nil {
+			return 0, err
+		}
+		if len(ids) > 0 {
+			return ids[0], nil
+		}
+	}
+	if id, ok := linkOwnerID(entity["@id"]); ok && id > 0 {
+		return id, nil
+	}
+	return 0, fmt.Errorf("无法获取新增记录的 id")
+}
+
+// Update 修改数据, 数据表每次修改版本号加 1
+// where 中带 version 时按版本修改 (乐观锁), 版本不一致返回 ErrVersionConflict
 func (r *entityRepository) Update(c *gin.Context, tableCode string, entity any, where map[string]any) error {
 	table := r.getTableName(tableCode)
-	err := r.db.WithContext(c).Table(table).Where(where).Updates(entity).Error
-	if err != nil {
-		r.logger.Error("更新失败", "err", err)
-		return err
+	_, versioned := where["version"]
+
+	var links map[string][]string
+	if entityMap, ok := entity.(map[string]any); ok {
+		entityMap, links = r.splitLinks(tableCode, entityMap)
+		entity = bumpVersion(tableCode, entityMap)
+	}
+	if len(links) == 0 {
+		result := r.db.WithContext(c).Table(table).Where(where).Updates(entity)
+		if result.Error != nil {
+			r.logger.Error("更新失败", "err", result.Error)
+			return result.Error
+		}
+		if versioned && result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		return nil
 	}
 
-	return nil
+	// 同时修改多对多字段: 更新数据表后逐条同步关联表
+	err := r.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if len(entity.(map[string]any)) > 0 {
+			result := tx.Table(table).Where(where).Updates(entity)
+			if result.Error != nil {
+				return result.Error
+			}
+			if versioned && result.RowsAffected == 0 {
+				return ErrVersionConflict
+			}
+			// 已按版本修改, 版本号已变化
+			if versioned {
+				where = maps.Clone(where)
+				delete(where, "version")
+			}
+		}
+		var ids []uint
+		if err := tx.Table(table).Where(where).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := r.saveLinks(tx, c, tableCode, id, links); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrVersionConflict) {
+		r.logger.Error("更新失败", "err", err)
+	}
+	return err
 }
 
-func (r *entityRepository) BatchUpdate(c *gin.Context, tableCode string, ids []uint, entityMap map[string]any) error {
+// BatchUpdate 批量修改数据, versions 为 id 对应的版本号
+// 带版本的记录逐条按版本修改, 任一条版本不一致时整体回滚并返回 ErrVersionConflict
+func (r *entityRepository) BatchUpdate(c *gin.Context, tableCode string, ids []uint, versions map[uint]uint, entityMap map[string]any) error {
 	table := r.getTableName(tableCode)
-	if err := r.db.WithContext(c).Table(table).Where("id in ?", ids).Updates(entityMap).Error; err != nil {
-		return err
+	updates := bumpVersion(tableCode, entityMap)
+	if len(versions) == 0 {
+		return r.db.WithContext(c).Table(table).Where("id in ?", ids).Updates(updates).Error
 	}
-	return nil
+	return r.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			db := tx.Table(table).Where("id = ?", id)
+			version, versioned := versions[id]
+			if versioned {
+				db = db.Where("version = ?", version)
+			}
+			result := db.Updates(updates)
+			if result.Error != nil {
+				return result.Error
+			}
+			if versioned && result.RowsAffected == 0 {
+				return fmt.Errorf("%w: id %d", ErrVersionConflict, id)
+			}
+		}
+		return nil
+	})
 }
 
-func (r *entityRepository) Delete(c *gin.Context, tableCode string, id uint) error {
-	var entity model.Entity
-	if err := r.db.WithContext(c).Where("id = ?", id).First(&entity).Error; err != nil {
-		return err
+// bumpVersion 数据表的修改内容中版本号加 1, 提交的 version 只作为 where 条件不写入
+// 草稿表的 version 是草稿依据的数据版本, 原样写入
+func bumpVersion(tableCode string, entityMap map[string]any) map[string]any {
+	if strings.HasSuffix(tableCode, "_draft") {
+		return entityMap
 	}
-	return nil
+	updates := make(map[string]any, len(entityMap)+1)
+	for k, v := range entityMap {
+		if k != "version" {
+			updates[k] = v
+		}
+	}
+	updates["version"] = gorm.Expr("version + 1")
+	return updates
 }
 
-func (r *entityRepository) BatchDelete(c *gin.Context, tableCode string, ids []uint) error {
-	var entities []model.Entity
-	if err := r.db.WithContext(c).Where("id in ?", ids).Find(&entities).Delete(&entities).Error; err != nil {
-		return err
-	}
-	return nil
+// Transaction 在同一事务中执行 fn, fn 通过 repo 写入的数据同时提交或回滚
+// repo 方法内部的事务 (如多对多字段的写入) 作为保存点执行
+func (r *entityRepository) Transaction(c *gin.Context, fn func(repo EntityRepository) error) error {
+	return r.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		return fn(&entityRepository{
+			Repository: &Repository{db: tx, rdb: r.rdb, logger: r.logger, dialect: r.dialect},
+			source:     r.source,
+			stfr:       r.stfr,
+		})
+	})
+}
+
+func (r *entityRepository) Delete(c *gin.Context, tableCode string, id uint, reason string) error {
+	return r.BatchDelete(c, tableCode, []uint{id}, reason)
+}
+
+// BatchDelete 软删除: 记录删除时间、删除人和原因, 已删除的记录不重复删除
+func (r *entityRepository) BatchDelete(c *gin.Context, tableCode string, ids []uint, reason string) error {
+	table := r.getTableName(tableCode)
+	return r.db.WithContext(c).Table(table).
+		Where("id in ? AND deleted_at is null", ids).
+		Updates(bumpVersion(tableCode, map[string]any{
+			"deleted_at":    time.Now(),
+			"deleted_by":    c.GetString("user_name"),
+			"delete_reason": reason,
+		})).Error
+}
+
+// Restore 从回收站恢复记录, 批量修改中标记为 Deleted 的状态恢复为 Normal
+func (r *entityRepository) Restore(c *gin.Context, tableCode string, ids []uint) error {
+	table := r.getTableName(tableCode)
+	return r.db.WithContext(c).Table(table).
+		Where("id in ? AND deleted_at is not null", ids).
+		Updates(bumpVersion(tableCode, map[string]any{
+			"deleted_at":    nil,
+			"deleted_by":    nil,
+			"delete_reason": nil,
+			"status":        gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END", "Deleted", "Normal"),
+			"updated_by":    c.GetString("user_name"),
+			"updated_at":    time.Now(),
+		})).Error
 }
+
+// Purge 从回收站彻底删除记录及其多对多关联, 只删除已软删除的记录
+func (r *entityRepository) Purge(c *gin.Context, tableCode string, ids []uint) error {
+	table := r.getTableName(tableCode)
+	links := r.linkFields(tableCode)
+	return r.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var purged []uint
+		if err := tx.Table(table).Where("id in ? AND deleted_at is not null", ids).Pluck("id", &purged).Error; err != nil {
+			return err
+		}
+		if len(purged) == 0 {
+			return nil
+		}
+		for _, field := range links {
+			// 表结构尚未发布时没有关联表; 先检查表是否存在, PostgreSQL 事务中的语句出错后整个事务都不能再执行
+			linkTable := linkTableName(tableCode, field)
+			if !tx.Migrator().HasTable(linkTable) {
+				continue
+			}
+			if err := tx.Exec("DELETE FROM "+linkTable+" WHERE entity_id IN ?", purged).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec("DELETE FROM "+table+" WHERE id IN ?", purged).Error
+	})
+}
+
 func (r *entityRepository) GetStatisticsByStatus(tableCode string) (map[string]int64, error) {
 	if tableCode == "" {
 		return nil, fmt.Errorf("tableCode 不能为空")
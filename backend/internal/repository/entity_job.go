@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"time"
+
+	"piemdm/internal/model"
+)
+
+type EntityJobRepository interface {
+	// 基础查询
+	FindOne(id uint) (*model.EntityJob, error)
+	FirstByCode(code string) (*model.EntityJob, error)
+	FindPage(page, pageSize int, total *int64, where map[string]any) ([]*model.EntityJob, error)
+	FindExpired(before time.Time) ([]*model.EntityJob, error)
+
+	// Base CRUD
+	Create(job *model.EntityJob) error
+	Save(job *model.EntityJob) error
+}
+
+type entityJobRepository struct {
+	*Repository
+	source Base
+}
+
+func NewEntityJobRepository(repository *Repository, source Base) EntityJobRepository {
+	return &entityJobRepository{
+		Repository: repository,
+		source:     source,
+	}
+}
+
+func (r *entityJobRepository) FindOne(id uint) (*model.EntityJob, error) {
+	var job model.EntityJob
+	if err := r.source.FirstById(&job, id); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *entityJobRepository) FirstByCode(code string) (*model.EntityJob, error) {
+	var job model.EntityJob
+	if err := r.db.Where("code = ?", code).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *entityJobRepository) FindPage(page, pageSize int, total *int64, where map[string]any) ([]*model.EntityJob, error) {
+	var jobs []*model.EntityJob
+	var job model.EntityJob
+
+	preloads := []string{}
+	if err := r.source.FindPage(job, &jobs, page, pageSize, total, where, preloads, "ID desc"); err != nil {
+		r.logger.Error("获取实体任务列表失败", "err", err)
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// FindExpired 查询结果文件已过期且尚未清理的任务
+func (r *entityJobRepository) FindExpired(before time.Time) ([]*model.EntityJob, error) {
+	var jobs []*model.EntityJob
+	if err := r.db.Where("expires_at < ? AND result_file <> ''", before).Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *entityJobRepository) Create(job *model.EntityJob) error {
+	return r.source.Create(job)
+}
+
+// Save 保存任务全部字段 (含零值), 用于进度更新
+func (r *entityJobRepository) Save(job *model.EntityJob) error {
+	return r.source.Save(job)
+}
@@ -0,0 +1,427 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"piemdm/pkg/helper/md5"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 多对多关系字段的值不存储在数据表的列中, 每个字段使用独立的关联表:
+// - t_<表>_<字段>_link: 主表记录引用的目标编码, 每个引用一行
+// - t_<表>_<字段>_link_draft: 草稿记录引用的目标编码, entity_id 为草稿记录 id
+// - t_<表>_<字段>_link_log: 主表记录引用的增减记录
+
+// LinkDataType 多对多关系字段在查询字段白名单中的数据类型, 值存储在关联表中
+const LinkDataType = "Link"
+
+// linkFieldType 使用关联表存储的字段类型
+const linkFieldType = "manytomany"
+
+// 关联日志操作
+const (
+	LinkOperationAdd    = "Add"
+	LinkOperationRemove = "Remove"
+)
+
+// linkTableName 关联表名称, tableCode 为草稿表 (<code>_draft) 时返回草稿关联表
+func linkTableName(tableCode, fieldCode string) string {
+	if baseTable, ok := strings.CutSuffix(tableCode, "_draft"); ok {
+		return fmt.Sprintf("t_%s_%s_link_draft", baseTable, fieldCode)
+	}
+	return fmt.Sprintf("t_%s_%s_link", tableCode, fieldCode)
+}
+
+// linkLogTableName 关联日志表名称
+func linkLogTableName(tableCode, fieldCode string) string {
+	return fmt.Sprintf("t_%s_%s_link_log", tableCode, fieldCode)
+}
+
+// linkIndexName 索引名称包含表名以保证在库内唯一, 超过 63 字符 (MySQL 64、PostgreSQL 63) 时使用表名摘要
+func linkIndexName(prefix, tableName string) string {
+	name := prefix + "_" + tableName
+	if len(name) > 63 {
+		name = prefix + "_" + md5.Md5(tableName)
+	}
+	return name
+}
+
+// ParseLinkValues 解析多对多字段的值: 支持数组、JSON 数组字符串和逗号分隔字符串, 去除空值和重复值
+func ParseLinkValues(value any) []string {
+	var items []string
+	switch v := value.(type) {
+	case nil:
+	case []string:
+		items = v
+	case []any:
+		for _, item := range v {
+			if item != nil {
+				items = append(items, fmt.Sprintf("%v", item))
+			}
+		}
+	case []byte:
+		return ParseLinkValues(string(v))
+	case string:
+		str := strings.TrimSpace(v)
+		if strings.HasPrefix(str, "[") {
+			var array []any
+			if err := json.Unmarshal([]byte(str), &array); err == nil {
+				return ParseLinkValues(array)
+			}
+		}
+		items = strings.Split(str, ",")
+	default:
+		items = []string{fmt.Sprintf("%v", v)}
+	}
+
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" && !slices.Contains(values, item) {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// isMissingTable 判断是否为表不存在的错误 (表结构尚未发布)
+func isMissingTable(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "Error 1146") || // MySQL
+		strings.Contains(msg, "SQLSTATE 42P01") || // PostgreSQL
+		strings.Contains(msg, "no such table") // SQLite
+}
+
+// linkOwnerID 转换记录 id, 兼容各驱动返回的整数类型
+func linkOwnerID(value any) (uint, bool) {
+	switch v := value.(type) {
+	case uint:
+		return v, true
+	case uint32:
+		return uint(v), true
+	case uint64:
+		return uint(v), true
+	case int:
+		return uint(v), v >= 0
+	case int32:
+		return uint(v), v >= 0
+	case int64:
+		return uint(v), v >= 0
+	}
+	return 0, false
+}
+
+// buildLinkMigrationStruct 构建关联表的迁移结构体, 同一记录不能重复引用同一目标
+func buildLinkMigrationStruct(tableName string) any {
+	unique := linkIndexName("uk", tableName)
+	return reflect.New(reflect.StructOf([]reflect.StructField{
+		{Name: "ID", Type: reflect.TypeOf(uint(0)), Tag: `gorm:"primaryKey;column:id"`},
+		{Name: "EntityID", Type: reflect.TypeOf(uint(0)), Tag: reflect.StructTag(fmt.Sprintf(`gorm:"column:entity_id;not null;uniqueIndex:%s,priority:1"`, unique))},
+		{Name: "TargetCode", Type: reflect.TypeOf(""), Tag: reflect.StructTag(fmt.Sprintf(`gorm:"column:target_code;size:128;not null;uniqueIndex:%s,priority:2;index:%s"`, unique, linkIndexName("idx", tableName)))},
+		{Name: "Sort", Type: reflect.TypeOf(0), Tag: `gorm:"column:sort;default:0"`},
+		{Name: "CreatedBy", Type: reflect.TypeOf(""), Tag: `gorm:"column:created_by;size:64"`},
+		{Name: "CreatedAt", Type: reflect.TypeOf(time.Time{}), Tag: `gorm:"column:created_at"`},
+	})).Interface()
+}
+
+// buildLinkLogMigrationStruct 构建关联日志表的迁移结构体
+func buildLinkLogMigrationStruct(tableName string) any {
+	return reflect.New(reflect.StructOf([]reflect.StructField{
+		{Name: "ID", Type: reflect.TypeOf(uint(0)), Tag: `gorm:"primaryKey;column:id"`},
+		{Name: "EntityID", Type: reflect.TypeOf(uint(0)), Tag: reflect.StructTag(fmt.Sprintf(`gorm:"column:entity_id;not null;index:%s"`, linkIndexName("idx", tableName)))},
+		{Name: "TargetCode", Type: reflect.TypeOf(""), Tag: `gorm:"column:target_code;size:128;not null"`},
+		{Name: "Operation", Type: reflect.TypeOf(""), Tag: `gorm:"column:operation;size:16"`},
+		{Name: "UpdateBy", Type: reflect.TypeOf(""), Tag: `gorm:"column:update_by;size:64"`},
+		{Name: "UpdatedAt", Type: reflect.TypeOf(time.Time{}), Tag: `gorm:"column:updated_at"`},
+	})).Interface()
+}
+
+// PublicLinks 为多对多字段生成关联表、草稿关联表和关联日志表, 返回生成的表名
+// 字段此前以 JSON 数组存储在数据表列中时, 关联表为空则迁移已有的值; 原有列保留不删除
+func (r *tableFieldRepository) PublicLinks(tableCode string) ([]string, error) {
+	fields, err := r.Find("code", map[string]any{
+		"table_code": tableCode,
+		"status":     "Normal",
+		"field_type": linkFieldType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []string
+	for _, field := range fields {
+		for _, owner := range []string{tableCode, tableCode + "_draft"} {
+			linkTable := linkTableName(owner, field.Code)
+			if err := r.db.Table(linkTable).AutoMigrate(buildLinkMigrationStruct(linkTable)); err != nil {
+				return nil, fmt.Errorf("failed to migrate table %s: %v", linkTable, err)
+			}
+			if err := r.migrateLinkValues("t_"+owner, linkTable, field.Code); err != nil {
+				return nil, fmt.Errorf("failed to migrate values of %s: %v", linkTable, err)
+			}
+			tables = append(tables, linkTable)
+		}
+
+		logTable := linkLogTableName(tableCode, field.Code)
+		if err := r.db.Table(logTable).AutoMigrate(buildLinkLogMigrationStruct(logTable)); err != nil {
+			return nil, fmt.Errorf("failed to migrate table %s: %v", logTable, err)
+		}
+		tables = append(tables, logTable)
+	}
+
+	r.logger.Info("关联表同步完成", "table", tableCode, "tables", tables)
+	return tables, nil
+}
+
+// migrateLinkValues 将数据表列中以 JSON 数组存储的值迁移到空的关联表
+func (r *tableFieldRepository) migrateLinkValues(ownerTable, linkTable, column string) error {
+	if !r.db.Migrator().HasTable(ownerTable) || !r.db.Migrator().HasColumn(ownerTable, column) {
+		return nil
+	}
+	var count int64
+	if err := r.db.Table(linkTable).Count(&count).Error; err != nil || count > 0 {
+		return err
+	}
+
+	var rows []map[string]any
+	if err := r.db.Table(ownerTable).Select("id, " + column).
+		Where(column + " IS NOT NULL AND " + column + " <> ''").
+		Find(&rows).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var links []map[string]any
+	for _, row := range rows {
+		id, ok := linkOwnerID(row["id"])
+		if !ok {
+			continue
+		}
+		for i, code := range ParseLinkValues(row[column]) {
+			links = append(links, map[string]any{
+				"entity_id":   id,
+				"target_code": code,
+				"sort":        i,
+				"created_at":  now,
+			})
+		}
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	r.logger.Info("迁移多对多字段的值到关联表", "table", linkTable, "rows", len(rows), "links", len(links))
+	return r.db.Table(linkTable).CreateInBatches(links, 500).Error
+}
+
+// linkFields 表中使用关联表存储的字段编码, 草稿表使用主表的字段定义
+func (r *entityRepository) linkFields(tableCode string) []string {
+	fields, err := r.stfr.Find("code", map[string]any{
+		"table_code": strings.TrimSuffix(tableCode, "_draft"),
+		"status":     "Normal",
+		"field_type": linkFieldType,
+	})
+	if err != nil {
+		r.logger.Error("查询多对多字段失败", "table", tableCode, "err", err)
+		return nil
+	}
+	codes := make([]string, 0, len(fields))
+	for _, field := range fields {
+		codes = append(codes, field.Code)
+	}
+	return codes
+}
+
+// splitLinks 拆分出需要写入关联表的字段值, 返回不含这些字段的新 map; 没有关联字段时原样返回
+func (r *entityRepository) splitLinks(tableCode string, entityMap map[string]any) (map[string]any, map[string][]string) {
+	var links map[string][]string
+	for _, field := range r.linkFields(tableCode) {
+		if value, ok := entityMap[field]; ok {
+			if links == nil {
+				links = make(map[string][]string)
+			}
+			links[field] = ParseLinkValues(value)
+		}
+	}
+	if links == nil {
+		return entityMap, nil
+	}
+
+	rest := make(map[string]any, len(entityMap))
+	for k, v := range entityMap {
+		if _, ok := links[k]; !ok {
+			rest[k] = v
+		}
+	}
+	return rest, links
+}
+
+// selectLinks 拆分查询字段中的关联字段: 返回数据表查询字段和需要从关联表读取的字段
+func (r *entityRepository) selectLinks(tableCode, selectString string) (string, []string) {
+	fields := r.linkFields(tableCode)
+	if len(fields) == 0 || selectString == "" || selectString == "*" {
+		return selectString, fields
+	}
+
+	var columns, links []string
+	hasID := false
+	for _, column := range strings.Split(selectString, ",") {
+		column = strings.TrimSpace(column)
+		switch {
+		case slices.Contains(fields, column):
+			links = append(links, column)
+		default:
+			hasID = hasID || column == "id"
+			columns = append(columns, column)
+		}
+	}
+	// 读取关联值需要记录 id
+	if len(links) > 0 && !hasID {
+		columns = append(columns, "id")
+	}
+	return strings.Join(columns, ","), links
+}
+
+// attachLinks 从关联表读取 fields 的值, 以目标编码数组写入 rows; 没有引用时为空数组
+func (r *entityRepository) attachLinks(tableCode string, fields []string, rows []map[string]any) error {
+	if len(fields) == 0 || len(rows) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		if id, ok := linkOwnerID(row["id"]); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	for _, field := range fields {
+		table := linkTableName(tableCode, field)
+		var links []struct {
+			EntityID   uint
+			TargetCode string
+		}
+		if err := r.db.Table(table).Select("entity_id, target_code").
+			Where("entity_id in ?", ids).
+			Order("sort, id").
+			Find(&links).Error; err != nil {
+			if isMissingTable(err) {
+				r.logger.Warn("关联表不存在, 请先发布表结构", "table", table)
+				continue
+			}
+			return err
+		}
+
+		values := make(map[uint][]string, len(rows))
+		for _, link := range links {
+			values[link.EntityID] = append(values[link.EntityID], link.TargetCode)
+		}
+		for _, row := range rows {
+			id, _ := linkOwnerID(row["id"])
+			codes := values[id]
+			if codes == nil {
+				codes = []string{}
+			}
+			row[field] = codes
+		}
+	}
+	return nil
+}
+
+// saveLinks 将记录引用的目标编码同步到关联表: 删除不再引用的目标, 新增的目标按顺序追加
+// 主表的引用增减同时写入关联日志表
+func (r *entityRepository) saveLinks(tx *gorm.DB, c *gin.Context, tableCode string, ownerID uint, links map[string][]string) error {
+	userName := c.GetString("user_name")
+	now := time.Now()
+	for field, codes := range links {
+		table := linkTableName(tableCode, field)
+		var existing []struct {
+			TargetCode string
+			Sort       int
+		}
+		if err := tx.Table(table).Select("target_code, sort").Where("entity_id = ?", ownerID).Find(&existing).Error; err != nil {
+			return err
+		}
+		sorts := make(map[string]int, len(existing))
+		var removed []string
+		for _, link := range existing {
+			sorts[link.TargetCode] = link.Sort
+			if !slices.Contains(codes, link.TargetCode) {
+				removed = append(removed, link.TargetCode)
+			}
+		}
+
+		if len(removed) > 0 {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE entity_id = ? AND target_code IN ?", ownerID, removed).Error; err != nil {
+				return err
+			}
+		}
+		var added []string
+		var rows []map[string]any
+		for i, code := range codes {
+			sort, ok := sorts[code]
+			switch {
+			case !ok:
+				added = append(added, code)
+				rows = append(rows, map[string]any{
+					"entity_id":   ownerID,
+					"target_code": code,
+					"sort":        i,
+					"created_by":  userName,
+					"created_at":  now,
+				})
+			case sort != i:
+				if err := tx.Table(table).Where("entity_id = ? AND target_code = ?", ownerID, code).Update("sort", i).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if len(rows) > 0 {
+			if err := tx.Table(table).Create(rows).Error; err != nil {
+				return err
+			}
+		}
+
+		if strings.HasSuffix(tableCode, "_draft") || len(added)+len(removed) == 0 {
+			continue
+		}
+		logs := make([]map[string]any, 0, len(added)+len(removed))
+		for _, group := range []struct {
+			operation string
+			codes     []string
+		}{{LinkOperationAdd, added}, {LinkOperationRemove, removed}} {
+			for _, code := range group.codes {
+				logs = append(logs, map[string]any{
+					"entity_id":   ownerID,
+					"target_code": code,
+					"operation":   group.operation,
+					"update_by":   userName,
+					"updated_at":  now,
+				})
+			}
+		}
+		if err := tx.Table(linkLogTableName(tableCode, field)).Create(logs).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindLinked 查询多对多字段引用了 values 中任一目标的记录 id
+func (r *entityRepository) FindLinked(tableCode, fieldCode string, values []string) ([]uint, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	var ids []uint
+	table := linkTableName(tableCode, fieldCode)
+	if err := r.db.Table(table).Distinct("entity_id").Where("target_code in ?", values).Pluck("entity_id", &ids).Error; err != nil {
+		if isMissingTable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,62 @@
+package repository_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+	"piemdm/pkg/log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gorm_sqlite "gorm.io/driver/sqlite"
+	gorm "gorm.io/gorm"
+)
+
+func TestEntityLogRepository_ChangesFilter(t *testing.T) {
+	db, err := gorm.Open(gorm_sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	require.NoError(t, err)
+
+	logger := &log.Logger{Logger: slog.New(slog.NewTextHandler(os.Stdout, nil))}
+	base := repository.NewRepository(db, nil, logger)
+	tfr := repository.NewTableFieldRepository(base, repository.NewBaseRepository(base))
+	require.NoError(t, tfr.Public("t_product_log", model.EntityLog{}))
+	logRepo := repository.NewEntityLogRepository(base, repository.NewBaseRepository(base))
+	repo := repository.NewEntityRepository(base, repository.NewBaseRepository(base), tfr)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	logs := []*model.EntityLog{
+		{EntityID: 1, Operation: "Create", UpdateBy: "alice", Changes: json.RawMessage(`{"name":{"before":null,"after":"A"},"price":{"before":null,"after":1}}`)},
+		{EntityID: 1, Operation: "Update", UpdateBy: "bob", Changes: json.RawMessage(`{"name":{"before":"A","after":"B"}}`)},
+		{EntityID: 1, FieldCode: "price", BeforeUpdate: "1", AfterUpdate: "2", UpdateBy: "bob"}, // 旧版按字段记录的日志
+	}
+	for _, entityLog := range logs {
+		require.NoError(t, logRepo.Create(c, "product", entityLog))
+	}
+
+	columns := map[string]string{"id": "Number", "operation": "Text", "changes": "JSON", "field_code": "Text", "update_by": "Text"}
+	filter := &model.EntityFilter{And: []*model.EntityFilter{
+		{Or: []*model.EntityFilter{
+			{Field: "changes.price", Op: model.QueryOpNotNull},
+			{Field: "field_code", Op: model.QueryOpEq, Value: "price"},
+		}},
+		{Field: "update_by", Op: model.QueryOpIn, Value: []any{"alice", "bob"}},
+	}}
+	query, err := repo.CompileQuery("product_log", &model.EntityQuery{Filter: filter, Sort: []model.EntitySort{{Field: "id"}}}, columns, "t")
+	require.NoError(t, err)
+
+	var total int64
+	rows, err := repo.FindPage("product_log", 1, 10, &total, query)
+	require.NoError(t, err)
+	var got []string
+	for _, row := range rows {
+		got = append(got, fmt.Sprintf("%v %v", row["operation"], row["field_code"]))
+	}
+	assert.Equal(t, []string{"Create ", " price"}, got)
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"fmt"
+
+	"piemdm/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EntityMergeRepository interface {
+	FindOne(id uint) (*model.EntityMerge, error)
+	Find(where map[string]any) ([]*model.EntityMerge, error)
+	FindPage(page, pageSize int, total *int64, where map[string]any) ([]*model.EntityMerge, error)
+	Create(c *gin.Context, merge *model.EntityMerge) error
+	Update(c *gin.Context, merge *model.EntityMerge) error
+	// Transaction 在同一事务中修改数据和保存合并记录
+	Transaction(c *gin.Context, entities EntityRepository, fn func(repo EntityRepository, merges EntityMergeRepository) error) error
+}
+
+type entityMergeRepository struct {
+	*Repository
+	source Base
+}
+
+func NewEntityMergeRepository(repository *Repository, source Base) EntityMergeRepository {
+	return &entityMergeRepository{
+		Repository: repository,
+		source:     source,
+	}
+}
+
+func (r *entityMergeRepository) FindOne(id uint) (*model.EntityMerge, error) {
+	var merge model.EntityMerge
+	if err := r.source.FirstById(&merge, id); err != nil {
+		return nil, err
+	}
+	return &merge, nil
+}
+
+func (r *entityMergeRepository) Find(where map[string]any) ([]*model.EntityMerge, error) {
+	var merges []*model.EntityMerge
+	if err := r.db.Where(where).Order("id").Find(&merges).Error; err != nil {
+		return nil, err
+	}
+	return merges, nil
+}
+
+func (r *entityMergeRepository) FindPage(page, pageSize int, total *int64, where map[string]any) ([]*model.EntityMerge, error) {
+	var merges []*model.EntityMerge
+	var merge model.EntityMerge
+
+	err := r.source.FindPage(merge, &merges, page, pageSize, total, where, []string{}, "id desc")
+	if err != nil {
+		r.logger.Error("获取合并记录失败", "err", err)
+	}
+	return merges, nil
+}
+
+func (r *entityMergeRepository) Create(c *gin.Context, merge *model.EntityMerge) error {
+	return r.db.WithContext(c).Create(merge).Error
+}
+
+// Update 保存全部字段, 快照和引用在合并生效时写入
+func (r *entityMergeRepository) Update(c *gin.Context, merge *model.EntityMerge) error {
+	return r.db.WithContext(c).Save(merge).Error
+}
+
+func (r *entityMergeRepository) Transaction(c *gin.Context, entities EntityRepository, fn func(repo EntityRepository, merges EntityMergeRepository) error) error {
+	return entities.Transaction(c, func(repo EntityRepository) error {
+		tx, ok := repo.(*entityRepository)
+		if !ok {
+			return fmt.Errorf("不支持的数据仓库: %T", repo)
+		}
+		return fn(repo, &entityMergeRepository{Repository: tx.Repository, source: r.source})
+	})
+}
@@ -0,0 +1,695 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"piemdm/internal/model"
+)
+
+// 结构化查询限制
+const (
+	maxQueryDepth      = 5    // 条件组最大嵌套层数
+	maxQueryConditions = 50   // 单次查询最多条件数
+	maxQueryInSize     = 1000 // in / nin 最多值个数
+)
+
+// columnNamePattern 字段编码格式, 白名单之外的第二道防线
+var columnNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// jsonPathPattern JSON 字段的路径条件: 字段编码后接 .属性 或 [下标], 如 attrs.address.city, attrs.items[0].sku
+// 属性名不允许引号和逗号: PostgreSQL 的路径参数以逗号分隔 (见 postgresDialect.jsonPath)
+var jsonPathPattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)((?:\.[A-Za-z0-9_-]+|\[[0-9]+\])+)$`)
+
+// jsonPathSegment JSON 路径中的一段: .属性 或 [下标]
+var jsonPathSegment = regexp.MustCompile(`\.[A-Za-z0-9_-]+|\[[0-9]+\]`)
+
+// queryDateLayouts 日期/日期时间字段支持的输入格式
+var queryDateLayouts = []string{
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// CompiledEntityQuery 编译后的结构化查询
+type CompiledEntityQuery struct {
+	Where  string         // WHERE 条件 (不含 WHERE 关键字), 为空表示无条件
+	Values []any          // 条件参数
+	Order  string         // ORDER BY 子句 (不含 ORDER BY 关键字)
+	Select string         // SELECT 字段
+	Sort   []CompiledSort // 排序字段 (含 id 兜底), 用于按游标分批读取
+	Links  []string       // 需要从关联表读取的多对多字段
+}
+
+// CompiledSort 编译后的排序字段
+type CompiledSort struct {
+	Field  string // 字段编码, 即结果集中的键
+	Column string // 带别名的列名
+	Desc   bool
+}
+
+// CompileQuery 按字段白名单校验并按连接的数据库方言编译结构化查询
+// tableCode: 查询的表编码, 用于定位多对多字段的关联表
+// columns: 允许查询的字段编码 -> 数据类型 (Text, Number, Date, DateTime, JSON, 多对多字段为 LinkDataType)
+// JSON 字段可按路径过滤, 如 {"field":"attrs.address.city","op":"eq","value":"Paris"}
+// alias: 表别名, 如 "t"
+func (r *entityRepository) CompileQuery(tableCode string, query *model.EntityQuery, columns map[string]string, alias string) (*CompiledEntityQuery, error) {
+	return compileEntityQuery(r.dialect, tableCode, query, columns, alias)
+}
+
+func compileEntityQuery(d dialect, tableCode string, query *model.EntityQuery, columns map[string]string, alias string) (*CompiledEntityQuery, error) {
+	compiler := &entityQueryCompiler{table: tableCode, columns: columns, alias: alias, dialect: d}
+	compiled := &CompiledEntityQuery{}
+
+	if query == nil {
+		query = &model.EntityQuery{}
+	}
+
+	if query.Filter != nil {
+		where, values, err := compiler.compileFilter(query.Filter, 1)
+		if err != nil {
+			return nil, err
+		}
+		compiled.Where = where
+		compiled.Values = values
+	}
+
+	order, sorts, err := compiler.compileSort(query.Sort)
+	if err != nil {
+		return nil, err
+	}
+	compiled.Order = order
+	compiled.Sort = sorts
+
+	selectSql, links, err := compiler.compileFields(query.Fields)
+	if err != nil {
+		return nil, err
+	}
+	compiled.Select = selectSql
+	compiled.Links = links
+
+	return compiled, nil
+}
+
+type entityQueryCompiler struct {
+	table      string
+	columns    map[string]string
+	alias      string
+	dialect    dialect
+	conditions int
+}
+
+// column 校验字段并返回带别名的列名
+func (q *entityQueryCompiler) column(field string) (string, string, error) {
+	dataType, ok := q.columns[field]
+	if !ok || !columnNamePattern.MatchString(field) {
+		return "", "", fmt.Errorf("%w: unknown field %q", model.ErrInvalidQuery, field)
+	}
+	if q.alias == "" {
+		return field, dataType, nil
+	}
+	return q.alias + "." + field, dataType, nil
+}
+
+// idColumn 带别名的 id 列
+func (q *entityQueryCompiler) idColumn() string {
+	if q.alias == "" {
+		return "id"
+	}
+	return q.alias + ".id"
+}
+
+func (q *entityQueryCompiler) compileFilter(filter *model.EntityFilter, depth int) (string, []any, error) {
+	if depth > maxQueryDepth {
+		return "", nil, fmt.Errorf("%w: filter nested deeper than %d levels", model.ErrInvalidQuery, maxQueryDepth)
+	}
+
+	if filter.IsGroup() {
+		if filter.Field != "" {
+			return "", nil, fmt.Errorf("%w: a filter node is either a group or a condition", model.ErrInvalidQuery)
+		}
+		var parts []string
+		var values []any
+		for _, group := range []struct {
+			children []*model.EntityFilter
+			joiner   string
+		}{{filter.And, " AND "}, {filter.Or, " OR "}} {
+			if len(group.children) == 0 {
+				continue
+			}
+			var groupParts []string
+			for _, child := range group.children {
+				if child == nil {
+					continue
+				}
+				sql, childValues, err := q.compileFilter(child, depth+1)
+				if err != nil {
+					return "", nil, err
+				}
+				groupParts = append(groupParts, sql)
+				values = append(values, childValues...)
+			}
+			if len(groupParts) > 0 {
+				parts = append(parts, "("+strings.Join(groupParts, group.joiner)+")")
+			}
+		}
+		if len(parts) == 0 {
+			return "1 = 1", nil, nil
+		}
+		return strings.Join(parts, " AND "), values, nil
+	}
+
+	q.conditions++
+	if q.conditions > maxQueryConditions {
+		return "", nil, fmt.Errorf("%w: more than %d conditions", model.ErrInvalidQuery, maxQueryConditions)
+	}
+	return q.compileCondition(filter)
+}
+
+func (q *entityQueryCompiler) compileCondition(filter *model.EntityFilter) (string, []any, error) {
+	op := strings.ToLower(filter.Op)
+	if op == "" {
+		op = model.QueryOpEq
+	}
+	if column, path, ok := q.jsonPath(filter.Field); ok {
+		return q.compileJSONPathCondition(filter, op, column, path)
+	}
+
+	column, dataType, err := q.column(filter.Field)
+	if err != nil {
+		return "", nil, err
+	}
+	if dataType == LinkDataType {
+		return q.compileLinkCondition(filter, op)
+	}
+	if dataType == "JSON" {
+		switch op {
+		case model.QueryOpIsNull:
+			return column + " IS NULL", nil, nil
+		case model.QueryOpNotNull:
+			return column + " IS NOT NULL", nil, nil
+		}
+		return "", nil, fmt.Errorf("%w: filter JSON field %q by a path such as %s.key", model.ErrInvalidQuery, filter.Field, filter.Field)
+	}
+
+	switch op {
+	case model.QueryOpEq, model.QueryOpNe, model.QueryOpGt, model.QueryOpGte, model.QueryOpLt, model.QueryOpLte:
+		value, err := coerceQueryValue(filter.Field, dataType, filter.Value)
+		if err != nil {
+			return "", nil, err
+		}
+		operators := map[string]string{
+			model.QueryOpEq:  "=",
+			model.QueryOpNe:  "<>",
+			model.QueryOpGt:  ">",
+			model.QueryOpGte: ">=",
+			model.QueryOpLt:  "<",
+			model.QueryOpLte: "<=",
+		}
+		return fmt.Sprintf("%s %s ?", column, operators[op]), []any{value}, nil
+
+	case model.QueryOpIn, model.QueryOpNin:
+		items, err := queryValueList(filter.Field, filter.Value)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(items) > maxQueryInSize {
+			return "", nil, fmt.Errorf("%w: field %q has more than %d values", model.ErrInvalidQuery, filter.Field, maxQueryInSize)
+		}
+		if len(items) == 0 {
+			if op == model.QueryOpIn {
+				return "1 = 0", nil, nil
+			}
+			return "1 = 1", nil, nil
+		}
+		values := make([]any, 0, len(items))
+		for _, item := range items {
+			value, err := coerceQueryValue(filter.Field, dataType, item)
+			if err != nil {
+				return "", nil, err
+			}
+			values = append(values, value)
+		}
+		if op == model.QueryOpIn {
+			return column + " IN ?", []any{values}, nil
+		}
+		return column + " NOT IN ?", []any{values}, nil
+
+	case model.QueryOpLike, model.QueryOpContains, model.QueryOpStartsWith, model.QueryOpEndsWith:
+		if dataType != "Text" {
+			return "", nil, fmt.Errorf("%w: operator %q is only supported on text field %q", model.ErrInvalidQuery, op, filter.Field)
+		}
+		value, ok := queryString(filter.Value)
+		if !ok {
+			return "", nil, fmt.Errorf("%w: field %q expects a string value", model.ErrInvalidQuery, filter.Field)
+		}
+		switch op {
+		case model.QueryOpContains:
+			value = "%" + escapeLike(value) + "%"
+		case model.QueryOpStartsWith:
+			value = escapeLike(value) + "%"
+		case model.QueryOpEndsWith:
+			value = "%" + escapeLike(value)
+		}
+		return q.dialect.like(column), []any{value}, nil
+
+	case model.QueryOpBetween:
+		items, err := queryValueList(filter.Field, filter.Value)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(items) != 2 {
+			return "", nil, fmt.Errorf("%w: between on field %q expects [from, to]", model.ErrInvalidQuery, filter.Field)
+		}
+		from, err := coerceQueryValue(filter.Field, dataType, items[0])
+		if err != nil {
+			return "", nil, err
+		}
+		to, err := coerceQueryValue(filter.Field, dataType, items[1])
+		if err != nil {
+			return "", nil, err
+		}
+		return column + " BETWEEN ? AND ?", []any{from, to}, nil
+
+	case model.QueryOpIsNull:
+		return column + " IS NULL", nil, nil
+
+	case model.QueryOpNotNull:
+		return column + " IS NOT NULL", nil, nil
+	}
+
+	return "", nil, fmt.Errorf("%w: unsupported operator %q", model.ErrInvalidQuery, filter.Op)
+}
+
+// compileLinkCondition 编译多对多字段条件, 以子查询匹配关联表
+// hasany (eq / in 同义): 引用了任一值; hasall: 引用了全部值; isnull / notnull: 没有 / 有引用
+func (q *entityQueryCompiler) compileLinkCondition(filter *model.EntityFilter, op string) (string, []any, error) {
+	if !columnNamePattern.MatchString(strings.TrimSuffix(q.table, "_draft")) {
+		return "", nil, fmt.Errorf("%w: invalid table %q", model.ErrInvalidQuery, q.table)
+	}
+	link := linkTableName(q.table, filter.Field)
+	id := q.idColumn()
+
+	switch op {
+	case model.QueryOpIsNull:
+		return id + " NOT IN (SELECT entity_id FROM " + link + ")", nil, nil
+	case model.QueryOpNotNull:
+		return id + " IN (SELECT entity_id FROM " + link + ")", nil, nil
+	case model.QueryOpHasAny, model.QueryOpHasAll, model.QueryOpEq, model.QueryOpIn:
+	default:
+		return "", nil, fmt.Errorf("%w: operator %q is not supported on relation field %q, use hasany or hasall", model.ErrInvalidQuery, op, filter.Field)
+	}
+
+	var items []any
+	if str, ok := queryString(filter.Value); ok && op == model.QueryOpEq {
+		items = []any{str}
+	} else {
+		var err error
+		if items, err = queryValueList(filter.Field, filter.Value); err != nil {
+			return "", nil, err
+		}
+	}
+	if len(items) > maxQueryInSize {
+		return "", nil, fmt.Errorf("%w: field %q has more than %d values", model.ErrInvalidQuery, filter.Field, maxQueryInSize)
+	}
+	var values []string
+	for _, item := range items {
+		value, err := coerceQueryValue(filter.Field, "Text", item)
+		if err != nil {
+			return "", nil, err
+		}
+		if str := strings.TrimSpace(value.(string)); str != "" && !slices.Contains(values, str) {
+			values = append(values, str)
+		}
+	}
+	if len(values) == 0 {
+		return "", nil, fmt.Errorf("%w: field %q requires a value", model.ErrInvalidQuery, filter.Field)
+	}
+
+	if op == model.QueryOpHasAll {
+		return id + " IN (SELECT entity_id FROM " + link + " WHERE target_code IN ? GROUP BY entity_id HAVING COUNT(DISTINCT target_code) = ?)",
+			[]any{values, len(values)}, nil
+	}
+	return id + " IN (SELECT entity_id FROM " + link + " WHERE target_code IN ?)", []any{values}, nil
+}
+
+// jsonPath 解析 JSON 字段的路径条件, 返回带别名的列名和按方言格式化的路径参数 (如 MySQL 的 $."address"."city")
+// 字段不是 JSON 字段或不带路径时返回 false
+func (q *entityQueryCompiler) jsonPath(field string) (string, string, bool) {
+	match := jsonPathPattern.FindStringSubmatch(field)
+	if match == nil || q.columns[match[1]] != "JSON" {
+		return "", "", false
+	}
+	column := match[1]
+	if q.alias != "" {
+		column = q.alias + "." + column
+	}
+
+	return column, q.dialect.jsonPath(jsonPathSegment.FindAllString(match[2], -1)), true
+}
+
+// compileJSONPathCondition 编译 JSON 字段的路径条件, 路径以参数传入
+// 等值、in 与模糊匹配按去掉引号的文本比较; 大小比较的值为数字时按数值比较, 否则按文本比较
+// isnull: 路径不存在或值为 null; notnull: 路径存在且值不为 null
+func (q *entityQueryCompiler) compileJSONPathCondition(filter *model.EntityFilter, op, column, path string) (string, []any, error) {
+	extract := q.dialect.jsonValue(column)
+	text := q.dialect.jsonText(column)
+	number := q.dialect.jsonNumber()
+
+	switch op {
+	case model.QueryOpEq, model.QueryOpNe:
+		value, ok := queryString(filter.Value)
+		if !ok {
+			return "", nil, fmt.Errorf("%w: field %q expects a scalar value", model.ErrInvalidQuery, filter.Field)
+		}
+		if op == model.QueryOpEq {
+			return text + " = ?", []any{path, value}, nil
+		}
+		return text + " <> ?", []any{path, value}, nil
+
+	case model.QueryOpGt, model.QueryOpGte, model.QueryOpLt, model.QueryOpLte:
+		operators := map[string]string{
+			model.QueryOpGt:  ">",
+			model.QueryOpGte: ">=",
+			model.QueryOpLt:  "<",
+			model.QueryOpLte: "<=",
+		}
+		if n, ok := jsonQueryNumber(filter.Value); ok {
+			return fmt.Sprintf("%s %s %s", extract, operators[op], number), []any{path, n}, nil
+		}
+		value, ok := queryString(filter.Value)
+		if !ok {
+			return "", nil, fmt.Errorf("%w: field %q expects a scalar value", model.ErrInvalidQuery, filter.Field)
+		}
+		return fmt.Sprintf("%s %s ?", text, operators[op]), []any{path, value}, nil
+
+	case model.QueryOpBetween:
+		items, err := queryValueList(filter.Field, filter.Value)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(items) != 2 {
+			return "", nil, fmt.Errorf("%w: between on field %q expects [from, to]", model.ErrInvalidQuery, filter.Field)
+		}
+		from, fromNumber := jsonQueryNumber(items[0])
+		to, toNumber := jsonQueryNumber(items[1])
+		if fromNumber && toNumber {
+			return extract + " BETWEEN " + number + " AND " + number, []any{path, from, to}, nil
+		}
+		fromText, ok1 := queryString(items[0])
+		toText, ok2 := queryString(items[1])
+		if !ok1 || !ok2 {
+			return "", nil, fmt.Errorf("%w: field %q expects scalar values", model.ErrInvalidQuery, filter.Field)
+		}
+		return text + " BETWEEN ? AND ?", []any{path, fromText, toText}, nil
+
+	case model.QueryOpIn, model.QueryOpNin:
+		items, err := queryValueList(filter.Field, filter.Value)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(items) > maxQueryInSize {
+			return "", nil, fmt.Errorf("%w: field %q has more than %d values", model.ErrInvalidQuery, filter.Field, maxQueryInSize)
+		}
+		if len(items) == 0 {
+			if op == model.QueryOpIn {
+				return "1 = 0", nil, nil
+			}
+			return "1 = 1", nil, nil
+		}
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			value, ok := queryString(item)
+			if !ok {
+				return "", nil, fmt.Errorf("%w: field %q expects scalar values", model.ErrInvalidQuery, filter.Field)
+			}
+			values = append(values, value)
+		}
+		if op == model.QueryOpIn {
+			return text + " IN ?", []any{path, values}, nil
+		}
+		return text + " NOT IN ?", []any{path, values}, nil
+
+	case model.QueryOpLike, model.QueryOpContains, model.QueryOpStartsWith, model.QueryOpEndsWith:
+		value, ok := queryString(filter.Value)
+		if !ok {
+			return "", nil, fmt.Errorf("%w: field %q expects a string value", model.ErrInvalidQuery, filter.Field)
+		}
+		switch op {
+		case model.QueryOpContains:
+			value = "%" + escapeLike(value) + "%"
+		case model.QueryOpStartsWith:
+			value = escapeLike(value) + "%"
+		case model.QueryOpEndsWith:
+			value = "%" + escapeLike(value)
+		}
+		return q.dialect.like(text), []any{path, value}, nil
+
+	case model.QueryOpIsNull:
+		return "(" + extract + " IS NULL OR " + q.dialect.jsonTypeOf(column) + " = '" + q.dialect.jsonNull() + "')", []any{path, path}, nil
+
+	case model.QueryOpNotNull:
+		return q.dialect.jsonTypeOf(column) + " <> '" + q.dialect.jsonNull() + "'", []any{path}, nil
+	}
+
+	return "", nil, fmt.Errorf("%w: operator %q is not supported on JSON path %q", model.ErrInvalidQuery, filter.Op, filter.Field)
+}
+
+// jsonQueryNumber JSON 路径大小比较的值是否为数字, 字符串形式的数字 (旧版查询参数) 也按数字处理
+func jsonQueryNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func (q *entityQueryCompiler) compileSort(sorts []model.EntitySort) (string, []CompiledSort, error) {
+	var parts []string
+	var compiled []CompiledSort
+	for _, sort := range sorts {
+		column, dataType, err := q.column(sort.Field)
+		if err != nil {
+			return "", nil, err
+		}
+		if dataType == LinkDataType {
+			return "", nil, fmt.Errorf("%w: cannot sort by relation field %q", model.ErrInvalidQuery, sort.Field)
+		}
+		if dataType == "JSON" {
+			return "", nil, fmt.Errorf("%w: cannot sort by JSON field %q", model.ErrInvalidQuery, sort.Field)
+		}
+		if sort.Desc {
+			parts = append(parts, column+" DESC"+q.dialect.nullsOrder(true))
+		} else {
+			parts = append(parts, column+" ASC"+q.dialect.nullsOrder(false))
+		}
+		compiled = append(compiled, CompiledSort{Field: sort.Field, Column: column, Desc: sort.Desc})
+		// id 唯一, 其后的排序字段不再影响顺序
+		if sort.Field == "id" {
+			return strings.Join(parts, ", "), compiled, nil
+		}
+	}
+	// 以 id 兜底, 保证分页顺序稳定
+	idColumn := q.idColumn()
+	parts = append(parts, idColumn+" DESC")
+	compiled = append(compiled, CompiledSort{Field: "id", Column: idColumn, Desc: true})
+	return strings.Join(parts, ", "), compiled, nil
+}
+
+// KeysetCondition 生成"排在 last 之后"的游标条件, 用于按排序字段分批读取大量数据
+// 排序字段最后一项为 id, 保证游标唯一; NULL 按 MySQL 规则处理: 升序在前, 降序在后 (其它数据库排序时按此指定)
+func (c *CompiledEntityQuery) KeysetCondition(last map[string]any) (string, []any) {
+	if len(c.Sort) == 0 || last == nil {
+		return "", nil
+	}
+
+	// after(i) = 第 i 个字段严格在后 OR (第 i 个字段相等 AND after(i+1))
+	var build func(i int) (string, []any)
+	build = func(i int) (string, []any) {
+		sort := c.Sort[i]
+		value := last[sort.Field]
+
+		var after string
+		var afterValues []any
+		switch {
+		case value == nil && !sort.Desc:
+			after = sort.Column + " IS NOT NULL"
+		case value == nil && sort.Desc:
+			after = "1 = 0"
+		case sort.Desc:
+			after = "(" + sort.Column + " < ? OR " + sort.Column + " IS NULL)"
+			afterValues = []any{value}
+		default:
+			after = sort.Column + " > ?"
+			afterValues = []any{value}
+		}
+		if i == len(c.Sort)-1 {
+			return after, afterValues
+		}
+
+		equal := sort.Column + " IS NULL"
+		var equalValues []any
+		if value != nil {
+			equal = sort.Column + " = ?"
+			equalValues = []any{value}
+		}
+		rest, restValues := build(i + 1)
+		values := append(afterValues, equalValues...)
+		values = append(values, restValues...)
+		return "(" + after + " OR (" + equal + " AND " + rest + "))", values
+	}
+	return build(0)
+}
+
+// compileFields 编译返回字段, 多对多字段不在数据表中, 单独返回由关联表读取
+func (q *entityQueryCompiler) compileFields(fields []string) (string, []string, error) {
+	if len(fields) == 0 {
+		var links []string
+		for field, dataType := range q.columns {
+			if dataType == LinkDataType {
+				links = append(links, field)
+			}
+		}
+		slices.Sort(links)
+		if q.alias == "" {
+			return "*", links, nil
+		}
+		return q.alias + ".*", links, nil
+	}
+	// id 始终返回, 便于前端定位记录
+	selected := []string{"id"}
+	seen := map[string]bool{"id": true}
+	var links []string
+	for _, field := range fields {
+		if seen[field] {
+			continue
+		}
+		_, dataType, err := q.column(field)
+		if err != nil {
+			return "", nil, err
+		}
+		seen[field] = true
+		if dataType == LinkDataType {
+			links = append(links, field)
+			continue
+		}
+		selected = append(selected, field)
+	}
+	for i, field := range selected {
+		if q.alias != "" {
+			selected[i] = q.alias + "." + field
+		}
+	}
+	return strings.Join(selected, ", "), links, nil
+}
+
+// coerceQueryValue 按字段数据类型转换查询值
+func coerceQueryValue(field, dataType string, value any) (any, error) {
+	if value == nil {
+		return nil, fmt.Errorf("%w: field %q requires a value", model.ErrInvalidQuery, field)
+	}
+
+	switch dataType {
+	case "Number":
+		switch v := value.(type) {
+		case int, int64, uint, uint64, float64:
+			return v, nil
+		}
+		str, ok := queryString(value)
+		if !ok {
+			return nil, fmt.Errorf("%w: field %q expects a number", model.ErrInvalidQuery, field)
+		}
+		str = strings.TrimSpace(str)
+		if i, err := strconv.ParseInt(str, 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %q expects a number, got %q", model.ErrInvalidQuery, field, str)
+		}
+		return f, nil
+
+	case "Date", "DateTime":
+		str, ok := queryString(value)
+		if !ok {
+			return nil, fmt.Errorf("%w: field %q expects a date", model.ErrInvalidQuery, field)
+		}
+		str = strings.TrimSpace(str)
+		for _, layout := range queryDateLayouts {
+			if t, err := time.ParseInLocation(layout, str, time.Local); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: field %q expects a date like 2006-01-02 or 2006-01-02 15:04:05, got %q", model.ErrInvalidQuery, field, str)
+	}
+
+	str, ok := queryString(value)
+	if !ok {
+		return nil, fmt.Errorf("%w: field %q expects a scalar value", model.ErrInvalidQuery, field)
+	}
+	return str, nil
+}
+
+// queryString 将标量查询值转换为字符串
+func queryString(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int, int64, uint, uint64, float64:
+		return fmt.Sprintf("%v", v), true
+	}
+	return "", false
+}
+
+// queryValueList 将 in / between 的值统一为列表, 兼容逗号分隔字符串
+func queryValueList(field string, value any) ([]any, error) {
+	switch v := value.(type) {
+	case []any:
+		return v, nil
+	case []string:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return items, nil
+	case string:
+		var items []any
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("%w: field %q expects a list value", model.ErrInvalidQuery, field)
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"piemdm/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gorm_sqlite "gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCompileEntityQuery(t *testing.T) {
+	columns := map[string]string{
+		"id":         "Number",
+		"code":       "Text",
+		"name":       "Text",
+		"amount":     "Number",
+		"created_at": "DateTime",
+		"tags":       LinkDataType,
+		"attrs":      "JSON",
+	}
+
+	tests := []struct {
+		name       string
+		params     url.Values
+		wantWhere  string
+		wantValues int
+		wantOrder  string
+		wantSelect string
+		wantErr    bool
+	}{
+		{
+			name:       "empty query",
+			params:     url.Values{},
+			wantOrder:  "t.id DESC",
+			wantSelect: "t.*",
+		},
+		{
+			name:       "legacy equal and like",
+			params:     url.Values{"code like": {"A%"}},
+			wantWhere:  "t.code LIKE ?",
+			wantValues: 1,
+			wantOrder:  "t.id DESC",
+			wantSelect: "t.*",
+		},
+		{
+			name:       "legacy newline becomes in",
+			params:     url.Values{"code": {"A\nB"}},
+			wantWhere:  "t.code IN ?",
+			wantValues: 1,
+			wantOrder:  "t.id DESC",
+			wantSelect: "t.*",
+		},
+		{
+			name: "nested or group with typed operators",
+			params: url.Values{
+				"filter": {`{"and":[{"field":"amount","op":"gte","value":10},{"or":[{"field":"code","op":"startswith","value":"A"},{"field":"name","op":"contains","value":"B"}]}]}`},
+			},
+			wantWhere:  "(t.amount >= ? AND (t.code LIKE ? OR t.name LIKE ?))",
+			wantValues: 3,
+			wantOrder:  "t.id DESC",
+			wantSelect: "t.*",
+		},
+		{
+			name:       "multi column sort and projection",
+			params:     url.Values{"sort": {"-created_at,code"}, "fields": {"code,name"}},
+			wantOrder:  "t.created_at DESC, t.code ASC, t.id DESC",
+			wantSelect: "t.id, t.code, t.name",
+		},
+		{
+			name:       "between on date",
+			params:     url.Values{"filter": {`{"field":"created_at","op":"between","value":["2024-01-01","2024-12-31 23:59:59"]}`}},
+			wantWhere:  "t.created_at BETWEEN ? AND ?",
+			wantValues: 2,
+			wantOrder:  "t.id DESC",
+			wantSelect: "t.*",
+		},
+		{
+			name:       "legacy hasany on relation field",
+			params:     url.Values{"tags hasany": {"A,B"}},
+			wantWhere:  "t.id IN (SELECT entity_id FROM t_item_tags_link WHERE target_code IN ?)",
+			wantValues: 1,
+			wantOrder:  "t.id DESC",
+			wantSelect: "t.*",
+		},
+		{
+			name:       "hasall on relation field",
+			params:     url.Values{"filter": {`{"field":"tags","op":"hasall","value":["A","B","A"]}`}, "fields": {"code,tags"}},
+			wantWhere:  "t.id IN (SELECT entity_id FROM t_item_tags_link WHERE target_code IN ? GROUP BY entity_id HAVING COUNT(DISTINCT target_code) = ?)",
+			wantValues: 2,
+			wantOrder:  "t.id DESC",
+			wantSelect: "t.id, t.code",
+		},
+		{
+			name:       "json path equal",
+			params:     url.Values{"attrs.address.city": {"Paris"}},
+			wantWhere:  "JSON_UNQUOTE(JSON_EXTRACT(t.attrs, ?)) = ?",
+			wantValues: 2,
+			wantOrder:  "t.id DESC",
+			wantSelect: "t.*",
+		},
+		{
+			name:       "json path numeric comparison",
+			params:     url.Values{"filter": {`{"field":"attrs.items[0].qty","op":"gt","value":5}`}},
+			wantWhere:  "JSON_EXTRACT(t.attrs, ?) > ?",
+			wantValues: 2,
+			wantOrder:  "t.id DESC",
+			wantSelect: "t.*",
+		},
+		{
+			name:       "json path isnull",
+			params:     url.Values{"filter": {`{"field":"attrs.color","op":"isnull"}`}},
+			wantWhere:  "(JSON_EXTRACT(t.attrs, ?) IS NULL OR JSON_TYPE(JSON_EXTRACT(t.attrs, ?)) = 'NULL')",
+			wantValues: 2,
+			wantOrder:  "t.id DESC",
+			wantSelect: "t.*",
+		},
+		{name: "json field without path", params: url.Values{"attrs": {"x"}}, wantErr: true},
+		{name: "sort by json field", params: url.Values{"sort": {"attrs"}}, wantErr: true},
+		{name: "path on text field", params: url.Values{"code.a": {"x"}}, wantErr: true},
+		{name: "json path with quote", params: url.Values{"filter": {`{"field":"attrs.a\"b","op":"eq","value":"x"}`}}, wantErr: true},
+		{name: "json path with comma", params: url.Values{"filter": {`{"field":"attrs.a,b","op":"eq","value":"x"}`}}, wantErr: true},
+		{name: "like on relation field", params: url.Values{"tags like": {"%A%"}}, wantErr: true},
+		{name: "sort by relation field", params: url.Values{"sort": {"tags"}}, wantErr: true},
+		{name: "unknown filter field", params: url.Values{"password": {"x"}}, wantErr: true},
+		{name: "raw sql key", params: url.Values{"(code like ? or name like ?)": {"%a%,%b%"}}, wantErr: true},
+		{name: "unknown sort field", params: url.Values{"sort": {"id; drop table t"}}, wantErr: true},
+		{name: "unknown projection field", params: url.Values{"fields": {"code,secret"}}, wantErr: true},
+		{name: "number type mismatch", params: url.Values{"amount": {"abc"}}, wantErr: true},
+		{name: "like on number", params: url.Values{"filter": {`{"field":"amount","op":"contains","value":"1"}`}}, wantErr: true},
+		{name: "unsupported operator", params: url.Values{"filter": {`{"field":"code","op":"regexp","value":"a"}`}}, wantErr: true},
+		{name: "invalid json", params: url.Values{"filter": {`{"field":`}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := model.ParseEntityQuery(tt.params)
+			if err == nil {
+				var compiled *CompiledEntityQuery
+				compiled, err = compileEntityQuery(mysqlDialect{}, "item", query, columns, "t")
+				if err == nil {
+					assert.False(t, tt.wantErr)
+					assert.Equal(t, tt.wantWhere, compiled.Where)
+					assert.Len(t, compiled.Values, tt.wantValues)
+					assert.Equal(t, tt.wantOrder, compiled.Order)
+					assert.Equal(t, tt.wantSelect, compiled.Select)
+					return
+				}
+			}
+			assert.True(t, tt.wantErr, "unexpected error: %v", err)
+			assert.True(t, errors.Is(err, model.ErrInvalidQuery))
+		})
+	}
+}
+
+func TestCompileEntityQuery_JSONPath(t *testing.T) {
+	query := &model.EntityQuery{Filter: &model.EntityFilter{Field: "attrs.items[2].sku-code", Op: "startswith", Value: "A_"}}
+	compiled, err := compileEntityQuery(mysqlDialect{}, "item", query, map[string]string{"attrs": "JSON"}, "t")
+	assert.NoError(t, err)
+	assert.Equal(t, "JSON_UNQUOTE(JSON_EXTRACT(t.attrs, ?)) LIKE ?", compiled.Where)
+	assert.Equal(t, []any{`$."items"[2]."sku-code"`, `A\_%`}, compiled.Values)
+}
+
+func TestCompileEntityQuery_Dialect(t *testing.T) {
+	columns := map[string]string{"name": "Text", "amount": "Number", "attrs": "JSON"}
+	query := &model.EntityQuery{
+		Filter: &model.EntityFilter{And: []*model.EntityFilter{
+			{Field: "name", Op: model.QueryOpContains, Value: "a"},
+			{Field: "attrs.size", Op: model.QueryOpGt, Value: 10},
+		}},
+		Sort: []model.EntitySort{{Field: "amount", Desc: true}},
+	}
+
+	compiled, err := compileEntityQuery(postgresDialect{}, "item", query, columns, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "(name ILIKE ? AND (CAST(attrs AS jsonb) #> string_to_array(?, ',')) > to_jsonb(CAST(? AS double precision)))", compiled.Where)
+	assert.Equal(t, []any{"%a%", "size", float64(10)}, compiled.Values)
+	assert.Equal(t, "amount DESC NULLS LAST, id DESC", compiled.Order)
+
+	// SQLite 的条件可以直接执行
+	compiled, err = compileEntityQuery(sqliteDialect{}, "item", query, columns, "")
+	assert.NoError(t, err)
+	db, err := gorm.Open(gorm_sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("CREATE TABLE item (id integer PRIMARY KEY, name text, amount integer, attrs json)").Error)
+	require.NoError(t, db.Exec(`INSERT INTO item VALUES (1, 'Bag', 1, '{"size": 12}'), (2, 'Cap', 2, '{"size": 8}'), (3, 'Hat', 3, '{"size": 20}')`).Error)
+	var ids []uint
+	require.NoError(t, db.Table("item").Where(compiled.Where, compiled.Values...).Order(compiled.Order).Pluck("id", &ids).Error)
+	assert.Equal(t, []uint{3, 1}, ids)
+}
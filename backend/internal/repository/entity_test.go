@@ -0,0 +1,373 @@
+package repository_test
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+	"piemdm/pkg/log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gorm_sqlite "gorm.io/driver/sqlite"
+	gorm "gorm.io/gorm"
+)
+
+func setupEntityChunkTest(t *testing.T) repository.EntityRepository {
+	db, err := gorm.Open(gorm_sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	require.NoError(t, err)
+
+	require.NoError(t, db.Exec(`CREATE TABLE t_item (id INTEGER PRIMARY KEY, code TEXT, grade INTEGER, deleted_at DATETIME)`).Error)
+	// grade 含重复值与 NULL, 验证游标在相等值和空值处不丢行、不重复
+	grades := []any{3, nil, 1, 3, 2, nil, 1, 3, 2, 1, nil}
+	for i, grade := range grades {
+		require.NoError(t, db.Exec(`INSERT INTO t_item (id, code, grade) VALUES (?, ?, ?)`, i+1, fmt.Sprintf("C%02d", i+1), grade).Error)
+	}
+	require.NoError(t, db.Exec(`INSERT INTO t_item (id, code, grade, deleted_at) VALUES (99, 'DEL', 1, CURRENT_TIMESTAMP)`).Error)
+
+	logger := &log.Logger{Logger: slog.New(slog.NewTextHandler(os.Stdout, nil))}
+	repo := repository.NewRepository(db, nil, logger)
+	return repository.NewEntityRepository(repo, repository.NewBaseRepository(repo), nil)
+}
+
+func TestEntityRepository_FindChunk(t *testing.T) {
+	repo := setupEntityChunkTest(t)
+	columns := map[string]string{"id": "Number", "code": "Text", "grade": "Number"}
+
+	sorts := [][]model.EntitySort{
+		nil,
+		{{Field: "id"}},
+		{{Field: "grade"}},
+		{{Field: "grade", Desc: true}},
+		{{Field: "grade", Desc: true}, {Field: "code"}},
+	}
+	for _, sort := range sorts {
+		t.Run(fmt.Sprintf("%v", sort), func(t *testing.T) {
+			query, err := repo.CompileQuery("item", &model.EntityQuery{Sort: sort}, columns, "t")
+			require.NoError(t, err)
+
+			var total int64
+			want, err := repo.FindPage("item", 1, 100, &total, query)
+			require.NoError(t, err)
+			require.Len(t, want, 11)
+
+			var got []map[string]any
+			var last map[string]any
+			for {
+				chunk, err := repo.FindChunk("item", query, last, 4)
+				require.NoError(t, err)
+				if len(chunk) == 0 {
+					break
+				}
+				got = append(got, chunk...)
+				last = chunk[len(chunk)-1]
+			}
+
+			ids := func(rows []map[string]any) []any {
+				var result []any
+				for _, row := range rows {
+					result = append(result, row["id"])
+				}
+				return result
+			}
+			assert.Equal(t, ids(want), ids(got))
+		})
+	}
+
+	count, err := repo.Count("item", &repository.CompiledEntityQuery{Where: "t.grade = ?", Values: []any{1}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func setupEntityLinkTest(t *testing.T) (repository.EntityRepository, *gorm.DB) {
+	db, err := gorm.Open(gorm_sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.TableField{}))
+	require.NoError(t, db.Create([]*model.TableField{
+		{TableCode: "project", Code: "code", Name: "编码", Type: "Text", FieldType: "text", Length: 64, Status: "Normal"},
+		{TableCode: "project", Code: "tags", Name: "标签", Type: "Text", FieldType: "manytomany", Length: 500, Status: "Normal"},
+	}).Error)
+
+	logger := &log.Logger{Logger: slog.New(slog.NewTextHandler(os.Stdout, nil))}
+	repo := repository.NewRepository(db, nil, logger)
+	tfr := repository.NewTableFieldRepository(repo, repository.NewBaseRepository(repo))
+	require.NoError(t, tfr.Public("t_project", map[string]any{}))
+
+	// 历史数据: 多对多字段以 JSON 数组存储在数据表列中, 发布时迁移到关联表
+	require.NoError(t, db.Exec(`ALTER TABLE t_project ADD COLUMN tags TEXT`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO t_project (id, code, tags, status) VALUES (1, 'P1', '["A","X"]', 'Normal')`).Error)
+	tables, err := tfr.PublicLinks("project")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"t_project_tags_link", "t_project_tags_link_draft", "t_project_tags_link_log"}, tables)
+
+	return repository.NewEntityRepository(repo, repository.NewBaseRepository(repo), tfr), db
+}
+
+func TestEntityRepository_Links(t *testing.T) {
+	repo, db := setupEntityLinkTest(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_name", "tester")
+
+	legacy, err := repo.FindOne("project", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A", "X"}, legacy["tags"])
+
+	require.NoError(t, repo.Create(c, "project", map[string]any{"code": "P2", "tags": []any{"A", "B"}, "status": "Normal"}))
+	created, err := repo.Find("project", "id,code,tags", map[string]any{"code": "P2"})
+	require.NoError(t, err)
+	require.Len(t, created, 1)
+	assert.Equal(t, []string{"A", "B"}, created[0]["tags"])
+	id := uint(created[0]["id"].(int64))
+
+	// 查询出错时返回错误, 不当作没有匹配的记录
+	_, err = repo.Find("project", "id,missing", map[string]any{"code": "P2"})
+	assert.Error(t, err)
+
+	require.NoError(t, repo.Update(c, "project", map[string]any{"tags": `["C","B"]`}, map[string]any{"id": id}))
+	updated, err := repo.FindOne("project", id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"C", "B"}, updated["tags"])
+
+	var logs []map[string]any
+	require.NoError(t, db.Table("t_project_tags_link_log").Order("id").Find(&logs).Error)
+	var changes []string
+	for _, log := range logs {
+		changes = append(changes, fmt.Sprintf("%v %v %v", log["operation"], log["target_code"], log["update_by"]))
+	}
+	assert.Equal(t, []string{"Add A tester", "Add B tester", "Add C tester", "Remove A tester"}, changes)
+
+	columns := map[string]string{"id": "Number", "code": "Text", "tags": repository.LinkDataType}
+	filters := []struct {
+		filter *model.EntityFilter
+		want   []string
+	}{
+		{&model.EntityFilter{Field: "tags", Op: model.QueryOpHasAny, Value: []any{"A", "C"}}, []string{"P2", "P1"}},
+		{&model.EntityFilter{Field: "tags", Op: model.QueryOpHasAll, Value: "B,C"}, []string{"P2"}},
+		{&model.EntityFilter{Field: "tags", Op: model.QueryOpEq, Value: "X"}, []string{"P1"}},
+	}
+	for _, tt := range filters {
+		query, err := repo.CompileQuery("project", &model.EntityQuery{Filter: tt.filter, Fields: []string{"code", "tags"}}, columns, "t")
+		require.NoError(t, err)
+		var total int64
+		rows, err := repo.FindPage("project", 1, 10, &total, query)
+		require.NoError(t, err)
+		var codes []string
+		for _, row := range rows {
+			codes = append(codes, row["code"].(string))
+			assert.NotNil(t, row["tags"])
+		}
+		assert.Equal(t, tt.want, codes, "%s %v", tt.filter.Op, tt.filter.Value)
+	}
+
+	ids, err := repo.FindLinked("project", "tags", []string{"X"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1}, ids)
+}
+
+func TestEntityRepository_Version(t *testing.T) {
+	db, err := gorm.Open(gorm_sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.TableField{}))
+	require.NoError(t, db.Create(&model.TableField{TableCode: "product", Code: "name", Name: "名称", Type: "Text", FieldType: "text", Length: 64, Status: "Normal"}).Error)
+
+	logger := &log.Logger{Logger: slog.New(slog.NewTextHandler(os.Stdout, nil))}
+	base := repository.NewRepository(db, nil, logger)
+	tfr := repository.NewTableFieldRepository(base, repository.NewBaseRepository(base))
+	require.NoError(t, tfr.Public("t_product", map[string]any{}))
+	require.NoError(t, tfr.Public("t_product_draft", map[string]any{}))
+	repo := repository.NewEntityRepository(base, repository.NewBaseRepository(base), tfr)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	require.NoError(t, db.Exec(`INSERT INTO t_product (id, name, status) VALUES (1, 'A', 'Normal'), (2, 'B', 'Normal')`).Error)
+	version := func(id uint) any {
+		row, err := repo.FindOne("product", id)
+		require.NoError(t, err)
+		return row["version"]
+	}
+	assert.EqualValues(t, 0, version(1))
+
+	// 每次修改版本号加 1, 提交的 version 不写入
+	require.NoError(t, repo.Update(c, "product", map[string]any{"name": "A1", "version": 9}, map[string]any{"id": 1}))
+	assert.EqualValues(t, 1, version(1))
+	require.NoError(t, repo.Update(c, "product", map[string]any{"name": "A2"}, map[string]any{"id": 1, "version": 1}))
+	assert.EqualValues(t, 2, version(1))
+
+	// 版本不一致时不修改
+	err = repo.Update(c, "product", map[string]any{"name": "A3"}, map[string]any{"id": 1, "version": 1})
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+	row, err := repo.FindOne("product", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "A2", row["name"])
+
+	// 批量修改任一条版本不一致时整体回滚
+	err = repo.BatchUpdate(c, "product", []uint{1, 2}, map[uint]uint{1: 2, 2: 5}, map[string]any{"status": "Frozen"})
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+	rows, err := repo.Find("product", "id,status,version", map[string]any{"status": "Frozen"})
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+	require.NoError(t, repo.BatchUpdate(c, "product", []uint{1, 2}, map[uint]uint{1: 2}, map[string]any{"status": "Frozen"}))
+	assert.EqualValues(t, 3, version(1))
+	assert.EqualValues(t, 1, version(2))
+
+	// 草稿表的 version 是草稿依据的数据版本, 原样写入
+	require.NoError(t, repo.Create(c, "product_draft", map[string]any{"id": uint(10), "name": "A4", "version": uint(3)}))
+	require.NoError(t, repo.Update(c, "product_draft", map[string]any{"draft_status": "Published", "version": uint(3)}, map[string]any{"id": 10}))
+	draft, err := repo.Find("product_draft", "version", map[string]any{"id": 10})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, draft[0]["version"])
+
+	// FindWithDeleted 包含已删除的记录
+	require.NoError(t, db.Exec(`UPDATE t_product SET deleted_at = CURRENT_TIMESTAMP WHERE id = 2`).Error)
+	rows, err = repo.Find("product", "id", map[string]any{"id in": []uint{1, 2}})
+	require.NoError(t, err)
+	assert.Len(t, rows, 1)
+	rows, err = repo.FindWithDeleted("product", "id,deleted_at", map[string]any{"id in": []uint{1, 2}})
+	require.NoError(t, err)
+	assert.Len(t, rows, 2)
+}
+
+func TestEntityRepository_Trash(t *testing.T) {
+	repo, db := setupEntityLinkTest(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_name", "tester")
+	require.NoError(t, repo.Create(c, "project", map[string]any{"code": "P2", "tags": []any{"B"}, "status": "Normal"}))
+
+	// 软删除记录删除人和原因, 已删除的记录不重复删除
+	require.NoError(t, repo.BatchDelete(c, "project", []uint{1, 2}, "停用"))
+	c.Set("user_name", "other")
+	require.NoError(t, repo.Delete(c, "project", 1, "重复删除"))
+	rows, err := repo.FindWithDeleted("project", "id,deleted_by,delete_reason,version", map[string]any{"id": 1})
+	require.NoError(t, err)
+	assert.Equal(t, "tester", rows[0]["deleted_by"])
+	assert.Equal(t, "停用", rows[0]["delete_reason"])
+	assert.EqualValues(t, 1, rows[0]["version"])
+
+	columns := map[string]string{"id": "Number", "code": "Text", "deleted_by": "Text"}
+	query, err := repo.CompileQuery("project", &model.EntityQuery{Sort: []model.EntitySort{{Field: "id"}}}, columns, "t")
+	require.NoError(t, err)
+	var total int64
+	rows, err = repo.FindPage("project", 1, 10, &total, query)
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+	rows, err = repo.FindDeletedPage("project", 1, 10, &total, query)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+	assert.Equal(t, "P1", rows[0]["code"])
+
+	// 恢复后清空删除信息
+	require.NoError(t, db.Exec(`UPDATE t_project SET status = 'Deleted' WHERE id = 1`).Error)
+	require.NoError(t, repo.Restore(c, "project", []uint{1}))
+	restored, err := repo.FindOne("project", 1)
+	require.NoError(t, err)
+	assert.Nil(t, restored["deleted_at"])
+	assert.Nil(t, restored["deleted_by"])
+	assert.Equal(t, "Normal", restored["status"])
+	assert.Equal(t, "other", restored["updated_by"])
+
+	// 彻底删除只删除回收站中的记录及其关联
+	require.NoError(t, repo.Purge(c, "project", []uint{1, 2}))
+	rows, err = repo.FindWithDeleted("project", "id", map[string]any{"id in": []uint{1, 2}})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.EqualValues(t, 1, rows[0]["id"])
+	var links []string
+	require.NoError(t, db.Table("t_project_tags_link").Order("target_code").Pluck("target_code", &links).Error)
+	assert.Equal(t, []string{"A", "X"}, links)
+
+	// 关联表不存在 (表结构尚未发布) 时仍可彻底删除
+	require.NoError(t, db.Exec(`DROP TABLE t_project_tags_link`).Error)
+	require.NoError(t, repo.Delete(c, "project", 1, "清理"))
+	require.NoError(t, repo.Purge(c, "project", []uint{1}))
+	rows, err = repo.FindWithDeleted("project", "id", map[string]any{"id": 1})
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+func TestEntityRepository_Tree(t *testing.T) {
+	repo, db := setupEntityLinkTest(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_name", "tester")
+	for _, column := range []string{"parent_id INTEGER", "level INTEGER", "path TEXT"} {
+		require.NoError(t, db.Exec("ALTER TABLE t_project ADD COLUMN "+column).Error)
+	}
+	// 1 为根节点 (parent_id 为空), 10 -> 11 -> 12, 13 为 parent_id 为 0 的根节点, 14 在回收站中
+	require.NoError(t, db.Exec(`UPDATE t_project SET level = 1, path = '/1/' WHERE id = 1`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO t_project (id, code, parent_id, level, path, status, version) VALUES
+		(10, 'A', 0, 1, '/10/', 'Normal', 0),
+		(11, 'B', 10, 2, '/10/11/', 'Normal', 0),
+		(12, 'C', 11, 3, '/10/11/12/', 'Normal', 0),
+		(13, 'D', 0, 1, '/13/', 'Normal', 0)`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO t_project (id, code, parent_id, level, path, status, version, deleted_at) VALUES
+		(14, 'E', 11, 3, '/10/11/14/', 'Normal', 0, CURRENT_TIMESTAMP)`).Error)
+
+	ids := func(rows []map[string]any) []int64 {
+		var ids []int64
+		for _, row := range rows {
+			ids = append(ids, row["id"].(int64))
+		}
+		return ids
+	}
+	roots, err := repo.FindChildren("project", []uint{0}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 10, 13}, ids(roots))
+	children, err := repo.FindChildren("project", []uint{11}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{12}, ids(children))
+	children, err = repo.FindChildren("project", []uint{11}, true)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{12, 14}, ids(children))
+
+	// 版本不一致时整体不修改
+	descendants := []*repository.TreePath{{ID: 12, Level: 3, Path: "/13/11/12/"}, {ID: 14, Level: 3, Path: "/13/11/14/"}}
+	entity := map[string]any{"parent_id": 13, "level": 2, "path": "/13/11/"}
+	err = repo.MoveSubtree(c, "project", entity, map[string]any{"id": 11, "version": 5}, descendants)
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+	rows, err := repo.FindWithDeleted("project", "id,parent_id,level,path,version", map[string]any{"id in": []uint{11, 12, 14}})
+	require.NoError(t, err)
+	assert.Equal(t, "/10/11/12/", rows[1]["path"])
+
+	require.NoError(t, repo.MoveSubtree(c, "project", entity, map[string]any{"id": 11, "version": 0}, descendants))
+	rows, err = repo.FindWithDeleted("project", "id,parent_id,level,path,version", map[string]any{"id in": []uint{11, 12, 14}})
+	require.NoError(t, err)
+	var moved []string
+	for _, row := range rows {
+		moved = append(moved, fmt.Sprintf("%v %v %v %v v%v", row["id"], row["parent_id"], row["level"], row["path"], row["version"]))
+	}
+	assert.Equal(t, []string{"11 13 2 /13/11/ v1", "12 11 3 /13/11/12/ v1", "14 11 3 /13/11/14/ v1"}, moved)
+}
+
+func TestEntityRepository_Transaction(t *testing.T) {
+	repo, _ := setupEntityLinkTest(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_name", "tester")
+
+	// 任一写入失败时整体回滚, 包括多对多关联
+	err := repo.Transaction(c, func(tx repository.EntityRepository) error {
+		if err := tx.Create(c, "project", map[string]any{"id": uint(2), "code": "P2", "tags": []string{"B"}}); err != nil {
+			return err
+		}
+		return tx.Update(c, "project", map[string]any{"code": "P1-X"}, map[string]any{"id": 1, "version": 5})
+	})
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+	rows, err := repo.Find("project", "id,code", map[string]any{"id in": []uint{1, 2}})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "P1", rows[0]["code"])
+
+	require.NoError(t, repo.Transaction(c, func(tx repository.EntityRepository) error {
+		if err := tx.Create(c, "project", map[string]any{"id": uint(2), "code": "P2", "tags": []string{"B"}}); err != nil {
+			return err
+		}
+		return tx.Update(c, "project", map[string]any{"code": "P1-X"}, map[string]any{"id": 1, "version": 0})
+	}))
+	rows, err = repo.Find("project", "id,code,tags", map[string]any{"id in": []uint{1, 2}})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "P1-X", rows[0]["code"])
+	assert.Equal(t, []string{"B"}, rows[1]["tags"])
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TreePath 树形节点的层级和路径, 路径为根节点到该节点的 id, 如 /1/5/12/
+type TreePath struct {
+	ID    uint
+	Level int
+	Path  string
+}
+
+// FindChildren 查询 parentIDs 的子节点, 按 id 排序; parentIDs 含 0 时包括根节点 (parent_id 为空或 0)
+// withDeleted 为 true 时包括已删除的记录, 用于移动子树时同时改写回收站中的后代
+func (r *entityRepository) FindChildren(tableCode string, parentIDs []uint, withDeleted bool) ([]map[string]any, error) {
+	table := r.getTableName(tableCode)
+	var entities []map[string]any
+	if len(parentIDs) == 0 {
+		return entities, nil
+	}
+
+	selectString, links := r.selectLinks(tableCode, "*")
+	db := r.db.Table(table).Select(selectString)
+	roots := false
+	for _, id := range parentIDs {
+		roots = roots || id == 0
+	}
+	if roots {
+		db = db.Where("parent_id in ? OR parent_id is null", parentIDs)
+	} else {
+		db = db.Where("parent_id in ?", parentIDs)
+	}
+	if !withDeleted {
+		db = db.Where("deleted_at is null")
+	}
+	if err := db.Order("id").Find(&entities).Error; err != nil {
+		return nil, err
+	}
+	if err := r.attachLinks(tableCode, links, entities); err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// MoveSubtree 移动子树: 在同一事务中修改节点 (含新的 parent_id、level、path), 并改写后代的 level 和 path
+// where 中带 version 时按版本修改节点, 版本不一致返回 ErrVersionConflict, 后代不改写
+func (r *entityRepository) MoveSubtree(c *gin.Context, tableCode string, entity map[string]any, where map[string]any, descendants []*TreePath) error {
+	table := r.getTableName(tableCode)
+	_, versioned := where["version"]
+	entity, links := r.splitLinks(tableCode, entity)
+	userName, now := c.GetString("user_name"), time.Now()
+
+	return r.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		result := tx.Table(table).Where(where).Updates(bumpVersion(tableCode, entity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if versioned && result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		if len(links) > 0 {
+			if id, ok := linkOwnerID(where["id"]); ok {
+				if err := r.saveLinks(tx, c, tableCode, id, links); err != nil {
+					return err
+				}
+			}
+		}
+		for _, node := range descendants {
+			err := tx.Table(table).Where("id = ?", node.ID).Updates(bumpVersion(tableCode, map[string]any{
+				"level":      node.Level,
+				"path":       node.Path,
+				"updated_by": userName,
+				"updated_at": now,
+			})).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
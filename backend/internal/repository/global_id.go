@@ -2,6 +2,8 @@ package repository
 
 import (
 	"piemdm/internal/model"
+
+	"gorm.io/gorm"
 )
 
 type GlobalIdRepository interface {
@@ -106,9 +108,14 @@ func (r *globalIdRepository) BatchDelete(ids []uint) error {
 }
 
 // GetNewID 获取数据库实现的递增唯一ID
+// 在同一事务中递增并读取, 不依赖 MySQL 的 LAST_INSERT_ID, 各数据库通用
 func (r *globalIdRepository) GetNewID(identifier string) uint {
 	var newID uint
-	r.db.Exec("UPDATE global_ids SET last_id=LAST_INSERT_ID(last_id+step) WHERE identifier=?", identifier)
-	r.db.Raw("SELECT LAST_INSERT_ID()").Scan(&newID)
+	r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("UPDATE global_ids SET last_id = last_id + step WHERE identifier = ?", identifier).Error; err != nil {
+			return err
+		}
+		return tx.Raw("SELECT last_id FROM global_ids WHERE identifier = ?", identifier).Scan(&newID).Error
+	})
 	return newID
 }
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"piemdm/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type HierarchyRepository interface {
+	// 层级
+	FindOne(id uint) (*model.Hierarchy, error)
+	FindByCode(code string) (*model.Hierarchy, error)
+	Find(where map[string]any) ([]*model.Hierarchy, error)
+	FindPage(page, pageSize int, total *int64, where map[string]any) ([]*model.Hierarchy, error)
+	Create(c *gin.Context, hierarchy *model.Hierarchy) error
+	Update(c *gin.Context, hierarchy *model.Hierarchy) error
+	Delete(c *gin.Context, id uint) error
+
+	// 版本
+	FindVersion(hierarchyCode, version string) (*model.HierarchyVersion, error)
+	FindVersions(where map[string]any) ([]*model.HierarchyVersion, error)
+	CreateVersion(c *gin.Context, version *model.HierarchyVersion, nodes []*model.HierarchyNode) error
+	UpdateVersion(c *gin.Context, version *model.HierarchyVersion) error
+	DeleteVersion(c *gin.Context, id uint) error
+
+	// 节点
+	FindNodes(versionID uint) ([]*model.HierarchyNode, error)
+	ReplaceNodes(c *gin.Context, versionID uint, nodes []*model.HierarchyNode) error
+}
+
+type hierarchyRepository struct {
+	*Repository
+	source Base
+}
+
+func NewHierarchyRepository(repository *Repository, source Base) HierarchyRepository {
+	return &hierarchyRepository{
+		Repository: repository,
+		source:     source,
+	}
+}
+
+func (r *hierarchyRepository) FindOne(id uint) (*model.Hierarchy, error) {
+	var hierarchy model.Hierarchy
+	if err := r.source.FirstById(&hierarchy, id); err != nil {
+		return nil, err
+	}
+	return &hierarchy, nil
+}
+
+func (r *hierarchyRepository) FindByCode(code string) (*model.Hierarchy, error) {
+	var hierarchy model.Hierarchy
+	if err := r.db.Where("code = ?", code).First(&hierarchy).Error; err != nil {
+		return nil, err
+	}
+	return &hierarchy, nil
+}
+
+func (r *hierarchyRepository) Find(where map[string]any) ([]*model.Hierarchy, error) {
+	var hierarchies []*model.Hierarchy
+	if err := r.db.Where(where).Order("id").Find(&hierarchies).Error; err != nil {
+		return nil, err
+	}
+	return hierarchies, nil
+}
+
+func (r *hierarchyRepository) FindPage(page, pageSize int, total *int64, where map[string]any) ([]*model.Hierarchy, error) {
+	var hierarchies []*model.Hierarchy
+	var hierarchy model.Hierarchy
+
+	err := r.source.FindPage(hierarchy, &hierarchies, page, pageSize, total, where, []string{}, "id desc")
+	if err != nil {
+		r.logger.Error("获取层级失败", "err", err)
+	}
+	return hierarchies, nil
+}
+
+func (r *hierarchyRepository) Create(c *gin.Context, hierarchy *model.Hierarchy) error {
+	return r.db.WithContext(c).Create(hierarchy).Error
+}
+
+func (r *hierarchyRepository) Update(c *gin.Context, hierarchy *model.Hierarchy) error {
+	return r.db.WithContext(c).Model(&model.Hierarchy{}).Where("id = ?", hierarchy.ID).Updates(hierarchy).Error
+}
+
+// Delete 删除层级及其全部版本和节点
+func (r *hierarchyRepository) Delete(c *gin.Context, id uint) error {
+	return r.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var hierarchy model.Hierarchy
+		if err := tx.Where("id = ?", id).First(&hierarchy).Error; err != nil {
+			return err
+		}
+		versions := tx.Model(&model.HierarchyVersion{}).Select("id").Where("hierarchy_code = ?", hierarchy.Code)
+		if err := tx.Where("version_id in (?)", versions).Delete(&model.HierarchyNode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("hierarchy_code = ?", hierarchy.Code).Delete(&model.HierarchyVersion{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&hierarchy).Error
+	})
+}
+
+func (r *hierarchyRepository) FindVersion(hierarchyCode, version string) (*model.HierarchyVersion, error) {
+	var item model.HierarchyVersion
+	err := r.db.Where("hierarchy_code = ? AND version = ?", hierarchyCode, version).First(&item).Error
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *hierarchyRepository) FindVersions(where map[string]any) ([]*model.HierarchyVersion, error) {
+	var versions []*model.HierarchyVersion
+	if err := r.db.Where(where).Order("valid_from, id").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// CreateVersion 新增版本及其节点 (如复制自其他版本的节点)
+func (r *hierarchyRepository) CreateVersion(c *gin.Context, version *model.HierarchyVersion, nodes []*model.HierarchyNode) error {
+	return r.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(version).Error; err != nil {
+			return err
+		}
+		return createHierarchyNodes(tx, version.ID, nodes)
+	})
+}
+
+func (r *hierarchyRepository) UpdateVersion(c *gin.Context, version *model.HierarchyVersion) error {
+	// 有效期、审批编码可以清空, 按字段全部保存
+	return r.db.WithContext(c).Model(&model.HierarchyVersion{}).Where("id = ?", version.ID).
+		Select("valid_from", "valid_to", "status", "approval_code", "description", "updated_by").
+		Updates(version).Error
+}
+
+// DeleteVersion 删除版本及其节点
+func (r *hierarchyRepository) DeleteVersion(c *gin.Context, id uint) error {
+	return r.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("version_id = ?", id).Delete(&model.HierarchyNode{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.HierarchyVersion{}, id).Error
+	})
+}
+
+func (r *hierarchyRepository) FindNodes(versionID uint) ([]*model.HierarchyNode, error) {
+	var nodes []*model.HierarchyNode
+	if err := r.db.Where("version_id = ?", versionID).Order("parent_id, sort, entity_id").Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// ReplaceNodes 在同一事务中用 nodes 替换版本的全部节点
+func (r *hierarchyRepository) ReplaceNodes(c *gin.Context, versionID uint, nodes []*model.HierarchyNode) error {
+	return r.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("version_id = ?", versionID).Delete(&model.HierarchyNode{}).Error; err != nil {
+			return err
+		}
+		return createHierarchyNodes(tx, versionID, nodes)
+	})
+}
+
+// createHierarchyNodes 按批写入版本的节点
+func createHierarchyNodes(tx *gorm.DB, versionID uint, nodes []*model.HierarchyNode) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+	rows := make([]*model.HierarchyNode, len(nodes))
+	for i, node := range nodes {
+		rows[i] = &model.HierarchyNode{VersionID: versionID, EntityID: node.EntityID, ParentID: node.ParentID, Sort: node.Sort}
+	}
+	return tx.CreateInBatches(rows, 500).Error
+}
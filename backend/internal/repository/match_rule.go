@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"piemdm/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type MatchRuleRepository interface {
+	// 匹配规则
+	FindOne(id uint) (*model.MatchRule, error)
+	Find(where map[string]any) ([]*model.MatchRule, error)
+	FindPage(page, pageSize int, total *int64, where map[string]any) ([]*model.MatchRule, error)
+	Create(c *gin.Context, rule *model.MatchRule) error
+	Update(c *gin.Context, rule *model.MatchRule) error
+	Delete(c *gin.Context, id uint) error
+
+	// 疑似重复分组
+	FindCluster(id uint) (*model.DuplicateCluster, error)
+	FindClusters(where map[string]any) ([]*model.DuplicateCluster, error)
+	FindClusterPage(page, pageSize int, total *int64, where map[string]any) ([]*model.DuplicateCluster, error)
+	ReplaceClusters(c *gin.Context, tableCode, ruleCode string, clusters []*model.DuplicateCluster) error
+	UpdateCluster(c *gin.Context, cluster *model.DuplicateCluster) error
+}
+
+type matchRuleRepository struct {
+	*Repository
+	source Base
+}
+
+func NewMatchRuleRepository(repository *Repository, source Base) MatchRuleRepository {
+	return &matchRuleRepository{
+		Repository: repository,
+		source:     source,
+	}
+}
+
+func (r *matchRuleRepository) FindOne(id uint) (*model.MatchRule, error) {
+	var rule model.MatchRule
+	if err := r.source.FirstById(&rule, id); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *matchRuleRepository) Find(where map[string]any) ([]*model.MatchRule, error) {
+	var rules []*model.MatchRule
+	if err := r.db.Where(where).Order("sort, id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *matchRuleRepository) FindPage(page, pageSize int, total *int64, where map[string]any) ([]*model.MatchRule, error) {
+	var rules []*model.MatchRule
+	var rule model.MatchRule
+
+	err := r.source.FindPage(rule, &rules, page, pageSize, total, where, []string{}, "id desc")
+	if err != nil {
+		r.logger.Error("获取匹配规则失败", "err", err)
+	}
+	return rules, nil
+}
+
+func (r *matchRuleRepository) Create(c *gin.Context, rule *model.MatchRule) error {
+	return r.db.WithContext(c).Create(rule).Error
+}
+
+func (r *matchRuleRepository) Update(c *gin.Context, rule *model.MatchRule) error {
+	return r.db.WithContext(c).Model(&model.MatchRule{}).Where("id = ?", rule.ID).Updates(rule).Error
+}
+
+func (r *matchRuleRepository) Delete(c *gin.Context, id uint) error {
+	rule := model.MatchRule{ID: id}
+	return r.db.WithContext(c).Delete(&rule).Error
+}
+
+func (r *matchRuleRepository) FindCluster(id uint) (*model.DuplicateCluster, error) {
+	var cluster model.DuplicateCluster
+	if err := r.db.Preload("Members").Where("id = ?", id).First(&cluster).Error; err != nil {
+		return nil, err
+	}
+	return &cluster, nil
+}
+
+func (r *matchRuleRepository) FindClusters(where map[string]any) ([]*model.DuplicateCluster, error) {
+	var clusters []*model.DuplicateCluster
+	if err := r.db.Preload("Members").Where(where).Order("id").Find(&clusters).Error; err != nil {
+		return nil, err
+	}
+	return clusters, nil
+}
+
+func (r *matchRuleRepository) FindClusterPage(page, pageSize int, total *int64, where map[string]any) ([]*model.DuplicateCluster, error) {
+	var clusters []*model.DuplicateCluster
+	var cluster model.DuplicateCluster
+
+	err := r.source.FindPage(cluster, &clusters, page, pageSize, total, where, []string{"Members"}, "score desc, id")
+	if err != nil {
+		r.logger.Error("获取疑似重复分组失败", "err", err)
+		return nil, err
+	}
+	return clusters, nil
+}
+
+// ReplaceClusters 在同一事务中用新的分组替换表和规则的待处理分组, 已确认或已排除的分组保留
+func (r *matchRuleRepository) ReplaceClusters(c *gin.Context, tableCode, ruleCode string, clusters []*model.DuplicateCluster) error {
+	return r.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		open := tx.Model(&model.DuplicateCluster{}).Select("id").
+			Where("table_code = ? AND rule_code = ? AND status = ?", tableCode, ruleCode, model.DuplicateClusterOpen)
+		if err := tx.Where("cluster_id in (?)", open).Delete(&model.DuplicateClusterMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("table_code = ? AND rule_code = ? AND status = ?", tableCode, ruleCode, model.DuplicateClusterOpen).
+			Delete(&model.DuplicateCluster{}).Error; err != nil {
+			return err
+		}
+		if len(clusters) == 0 {
+			return nil
+		}
+		// 分组和成员一起写入
+		return tx.CreateInBatches(clusters, 200).Error
+	})
+}
+
+func (r *matchRuleRepository) UpdateCluster(c *gin.Context, cluster *model.DuplicateCluster) error {
+	return r.db.WithContext(c).Model(&model.DuplicateCluster{}).Where("id = ?", cluster.ID).
+		Select("status", "reviewed_by", "reviewed_at").
+		Updates(cluster).Error
+}
@@ -0,0 +1,454 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"piemdm/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ModelPackageRepository interface {
+	Export(tableCodes []string) (*model.ModelPackage, error)
+	FindApprovalDefinitions(codes []string) ([]*model.PackageApprovalDefinition, error)
+	ExistingCodes(m any, codes []string) (map[string]bool, error)
+	Import(c *gin.Context, pkg *model.ModelPackage) error
+}
+
+type modelPackageRepository struct {
+	*Repository
+	source Base
+}
+
+func NewModelPackageRepository(repository *Repository, source Base) ModelPackageRepository {
+	return &modelPackageRepository{
+		Repository: repository,
+		source:     source,
+	}
+}
+
+// Export 导出表的模型定义, tableCodes 为 nil 时导出全部表, 不存在的表忽略
+func (r *modelPackageRepository) Export(tableCodes []string) (*model.ModelPackage, error) {
+	var tables []*model.Table
+	query := r.db.Where("status <> ?", "Deleted")
+	if tableCodes != nil {
+		query = query.Where("code IN ?", tableCodes)
+	}
+	if err := query.Order("code").Find(&tables).Error; err != nil {
+		return nil, err
+	}
+
+	pkg := &model.ModelPackage{Format: model.ModelPackageFormat, Version: model.ModelPackageVersion}
+	var defCodes []string
+	seen := make(map[string]bool)
+	for _, table := range tables {
+		packageTable, err := r.exportTable(table)
+		if err != nil {
+			return nil, fmt.Errorf("导出表 %s 失败: %w", table.Code, err)
+		}
+		for _, approval := range packageTable.Approvals {
+			if !seen[approval.ApprovalDefCode] {
+				seen[approval.ApprovalDefCode] = true
+				defCodes = append(defCodes, approval.ApprovalDefCode)
+			}
+		}
+		pkg.Tables = append(pkg.Tables, packageTable)
+	}
+
+	defs, err := r.FindApprovalDefinitions(defCodes)
+	if err != nil {
+		return nil, err
+	}
+	pkg.ApprovalDefinitions = defs
+	return pkg, nil
+}
+
+func (r *modelPackageRepository) exportTable(table *model.Table) (*model.PackageTable, error) {
+	packageTable := &model.PackageTable{
+		Code:        table.Code,
+		Name:        table.Name,
+		DisplayMode: table.DisplayMode,
+		TableType:   table.TableType,
+		ParentTable: table.ParentTable,
+		ParentField: table.ParentField,
+		SelfField:   table.SelfField,
+		TreeTable:   table.TreeTable,
+		Sort:        table.Sort,
+		Description: table.Description,
+		Status:      table.Status,
+	}
+
+	var fields []*model.TableField
+	if err := r.db.Where("table_code = ? AND status <> ?", table.Code, "Deleted").Order("code").Find(&fields).Error; err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		packageTable.Fields = append(packageTable.Fields, &model.PackageField{
+			Code:          field.Code,
+			Name:          field.Name,
+			FieldType:     field.FieldType,
+			Type:          field.Type,
+			Length:        field.Length,
+			Required:      field.Required,
+			IsIndex:       field.IsIndex,
+			IsUnique:      field.IsUnique,
+			IndexName:     field.IndexName,
+			IndexPriority: field.IndexPriority,
+			Description:   field.Description,
+			IsFilter:      field.IsFilter,
+			IsShow:        field.IsShow,
+			GroupName:     field.GroupName,
+			Sort:          field.Sort,
+			Options:       field.Options,
+			Status:        field.Status,
+		})
+	}
+
+	var groups []*model.TableFieldGroup
+	if err := r.db.Where("table_code = ? AND status <> ?", table.Code, "Deleted").Order("code").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		packageTable.FieldGroups = append(packageTable.FieldGroups, &model.PackageFieldGroup{
+			Code:        group.Code,
+			Name:        group.Name,
+			View:        group.View,
+			Sort:        group.Sort,
+			Collapsible: group.Collapsible,
+			Collapsed:   group.Collapsed,
+			ViewRoles:   group.ViewRoles,
+			EditRoles:   group.EditRoles,
+			Description: group.Description,
+			Status:      group.Status,
+		})
+	}
+
+	// 表本身和合并记录的审批绑定
+	var approvals []*model.TableApprovalDefinition
+	if err := r.db.Where("entity_code IN ? AND status <> ?", tableEntityCodes(table.Code), "Deleted").
+		Order("entity_code, operation").Find(&approvals).Error; err != nil {
+		return nil, err
+	}
+	for _, approval := range approvals {
+		packageTable.Approvals = append(packageTable.Approvals, &model.PackageTableApproval{
+			EntityCode:      approval.EntityCode,
+			Operation:       approval.Operation,
+			ApprovalDefCode: approval.ApprovalDefCode,
+			Description:     approval.Description,
+			Status:          approval.Status,
+		})
+	}
+
+	// 数据权限按角色编码导出
+	if err := r.db.Model(&model.TablePermission{}).
+		Select("roles.code AS role_code, table_permissions.operation, table_permissions.status").
+		Joins("JOIN roles ON roles.id = table_permissions.role_id AND roles.deleted_at IS NULL").
+		Where("table_permissions.table_code = ? AND table_permissions.status <> ?", table.Code, "Deleted").
+		Order("roles.code, table_permissions.operation").
+		Scan(&packageTable.Permissions).Error; err != nil {
+		return nil, err
+	}
+	return packageTable, nil
+}
+
+// tableEntityCodes 审批绑定中属于表的实体编码
+func tableEntityCodes(tableCode string) []string {
+	return []string{tableCode, model.MergeApprovalEntity(tableCode)}
+}
+
+// FindApprovalDefinitions 按编码查询审批定义及其节点
+func (r *modelPackageRepository) FindApprovalDefinitions(codes []string) ([]*model.PackageApprovalDefinition, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	var defs []*model.ApprovalDefinition
+	if err := r.db.Where("code IN ? AND status <> ?", codes, model.ApprovalDefStatusDeleted).Order("code").Find(&defs).Error; err != nil {
+		return nil, err
+	}
+	var nodes []*model.ApprovalNode
+	if err := r.db.Where("approval_def_code IN ? AND status <> ?", codes, model.ApprovalDefStatusDeleted).
+		Order("approval_def_code, sort_order, node_code").Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.PackageApprovalDefinition, 0, len(defs))
+	byCode := make(map[string]*model.PackageApprovalDefinition, len(defs))
+	for _, def := range defs {
+		packageDef := &model.PackageApprovalDefinition{
+			Code:        def.Code,
+			Name:        def.Name,
+			FormData:    def.FormData,
+			NodeList:    def.NodeList,
+			Description: def.Description,
+			Platform:    def.Platform,
+			Status:      def.Status,
+		}
+		byCode[def.Code] = packageDef
+		result = append(result, packageDef)
+	}
+	for _, node := range nodes {
+		if def, ok := byCode[node.ApprovalDefCode]; ok {
+			def.Nodes = append(def.Nodes, &model.PackageApprovalNode{
+				NodeCode:        node.NodeCode,
+				NodeName:        node.NodeName,
+				NodeType:        node.NodeType,
+				Description:     node.Description,
+				SortOrder:       node.SortOrder,
+				ApproverType:    node.ApproverType,
+				ApproverConfig:  node.ApproverConfig,
+				ConditionConfig: node.ConditionConfig,
+				Status:          node.Status,
+			})
+		}
+	}
+	return result, nil
+}
+
+// ExistingCodes 返回已存在的编码, 用于导入前校验引用
+func (r *modelPackageRepository) ExistingCodes(m any, codes []string) (map[string]bool, error) {
+	existing := make(map[string]bool, len(codes))
+	if len(codes) == 0 {
+		return existing, nil
+	}
+	var found []string
+	if err := r.db.Model(m).Where("code IN ?", codes).Pluck("code", &found).Error; err != nil {
+		return nil, err
+	}
+	for _, code := range found {
+		existing[code] = true
+	}
+	return existing, nil
+}
+
+// Import 在一个事务中按编码创建或更新包中的定义
+// 包中表的字段、字段组、审批绑定和数据权限以包为准, 包中没有的标记为已删除; 包外的表不受影响
+func (r *modelPackageRepository) Import(c *gin.Context, pkg *model.ModelPackage) error {
+	return r.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		for _, def := range pkg.ApprovalDefinitions {
+			if err := importApprovalDefinition(tx, c.GetString("user_name"), def); err != nil {
+				return fmt.Errorf("导入审批定义 %s 失败: %w", def.Code, err)
+			}
+		}
+		for _, table := range pkg.Tables {
+			if err := importTable(tx, table); err != nil {
+				return fmt.Errorf("导入表 %s 失败: %w", table.Code, err)
+			}
+		}
+		return nil
+	})
+}
+
+// findUnscoped 按条件查询记录, 包括已删除的记录, 以便复用唯一编码
+func findUnscoped(tx *gorm.DB, dest any, query string, args ...any) (bool, error) {
+	err := tx.Unscoped().Where(query, args...).First(dest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// saveUnscoped 保存记录, 已删除的记录同时恢复
+func saveUnscoped(tx *gorm.DB, value any) error {
+	return tx.Unscoped().Save(value).Error
+}
+
+// deleteMissing 删除包中没有的子对象: 标记为已删除并软删除
+func deleteMissing(tx *gorm.DB, m any, kept []uint, query string, args ...any) error {
+	db := tx.Model(m).Where(query, args...)
+	if len(kept) > 0 {
+		db = db.Where("id NOT IN ?", kept)
+	}
+	var ids []uint
+	if err := db.Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Model(m).Where("id IN ?", ids).Update("status", "Deleted").Error; err != nil {
+		return err
+	}
+	return tx.Where("id IN ?", ids).Delete(m).Error
+}
+
+func importApprovalDefinition(tx *gorm.DB, user string, def *model.PackageApprovalDefinition) error {
+	var existing model.ApprovalDefinition
+	found, err := findUnscoped(tx, &existing, "code = ?", def.Code)
+	if err != nil {
+		return err
+	}
+	existing.Code = def.Code
+	existing.Name = def.Name
+	existing.FormData = def.FormData
+	existing.NodeList = def.NodeList
+	existing.Description = def.Description
+	existing.Platform = def.Platform
+	existing.Status = def.Status
+	existing.DeletedAt = gorm.DeletedAt{}
+	if found {
+		err = saveUnscoped(tx, &existing)
+	} else {
+		// 内置审批定义创建时会生成新编码, 跳过钩子保留包中的编码, 审批绑定和审批记录按编码关联
+		existing.CreatedBy = user
+		err = tx.Session(&gorm.Session{SkipHooks: true}).Create(&existing).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	var kept []uint
+	for _, node := range def.Nodes {
+		var existingNode model.ApprovalNode
+		if _, err := findUnscoped(tx, &existingNode, "approval_def_code = ? AND node_code = ?", def.Code, node.NodeCode); err != nil {
+			return err
+		}
+		existingNode.ApprovalDefCode = def.Code
+		existingNode.NodeCode = node.NodeCode
+		existingNode.NodeName = node.NodeName
+		existingNode.NodeType = node.NodeType
+		existingNode.Description = node.Description
+		existingNode.SortOrder = node.SortOrder
+		existingNode.ApproverType = node.ApproverType
+		existingNode.ApproverConfig = node.ApproverConfig
+		existingNode.ConditionConfig = node.ConditionConfig
+		existingNode.Status = node.Status
+		existingNode.DeletedAt = gorm.DeletedAt{}
+		if err := saveUnscoped(tx, &existingNode); err != nil {
+			return fmt.Errorf("节点 %s: %w", node.NodeCode, err)
+		}
+		kept = append(kept, existingNode.ID)
+	}
+	return deleteMissing(tx, &model.ApprovalNode{}, kept, "approval_def_code = ?", def.Code)
+}
+
+func importTable(tx *gorm.DB, table *model.PackageTable) error {
+	var existing model.Table
+	if _, err := findUnscoped(tx, &existing, "code = ?", table.Code); err != nil {
+		return err
+	}
+	existing.Code = table.Code
+	existing.Name = table.Name
+	existing.DisplayMode = table.DisplayMode
+	existing.TableType = table.TableType
+	existing.ParentTable = table.ParentTable
+	existing.ParentField = table.ParentField
+	existing.SelfField = table.SelfField
+	existing.TreeTable = table.TreeTable
+	existing.Sort = table.Sort
+	existing.Description = table.Description
+	existing.Status = table.Status
+	existing.DeletedAt = gorm.DeletedAt{}
+	if err := saveUnscoped(tx, &existing); err != nil {
+		return err
+	}
+
+	// 字段
+	var kept []uint
+	for _, field := range table.Fields {
+		var existingField model.TableField
+		if _, err := findUnscoped(tx, &existingField, "table_code = ? AND code = ?", table.Code, field.Code); err != nil {
+			return err
+		}
+		existingField.TableCode = table.Code
+		existingField.Code = field.Code
+		existingField.Name = field.Name
+		existingField.FieldType = field.FieldType
+		existingField.Type = field.Type
+		existingField.Length = field.Length
+		existingField.Required = field.Required
+		existingField.IsIndex = field.IsIndex
+		existingField.IsUnique = field.IsUnique
+		existingField.IndexName = field.IndexName
+		existingField.IndexPriority = field.IndexPriority
+		existingField.Description = field.Description
+		existingField.IsFilter = field.IsFilter
+		existingField.IsShow = field.IsShow
+		existingField.GroupName = field.GroupName
+		existingField.Sort = field.Sort
+		existingField.Options = field.Options
+		existingField.Status = field.Status
+		existingField.DeletedAt = gorm.DeletedAt{}
+		if err := saveUnscoped(tx, &existingField); err != nil {
+			return fmt.Errorf("字段 %s: %w", field.Code, err)
+		}
+		kept = append(kept, existingField.ID)
+	}
+	if err := deleteMissing(tx, &model.TableField{}, kept, "table_code = ?", table.Code); err != nil {
+		return err
+	}
+
+	// 字段组
+	kept = nil
+	for _, group := range table.FieldGroups {
+		var existingGroup model.TableFieldGroup
+		if _, err := findUnscoped(tx, &existingGroup, "code = ?", group.Code); err != nil {
+			return err
+		}
+		existingGroup.Code = group.Code
+		existingGroup.TableCode = table.Code
+		existingGroup.Name = group.Name
+		existingGroup.View = group.View
+		existingGroup.Sort = group.Sort
+		existingGroup.Collapsible = group.Collapsible
+		existingGroup.Collapsed = group.Collapsed
+		existingGroup.ViewRoles = group.ViewRoles
+		existingGroup.EditRoles = group.EditRoles
+		existingGroup.Description = group.Description
+		existingGroup.Status = group.Status
+		existingGroup.DeletedAt = gorm.DeletedAt{}
+		if err := saveUnscoped(tx, &existingGroup); err != nil {
+			return fmt.Errorf("字段组 %s: %w", group.Code, err)
+		}
+		kept = append(kept, existingGroup.ID)
+	}
+	if err := deleteMissing(tx, &model.TableFieldGroup{}, kept, "table_code = ?", table.Code); err != nil {
+		return err
+	}
+
+	// 审批绑定
+	kept = nil
+	for _, approval := range table.Approvals {
+		var existingApproval model.TableApprovalDefinition
+		if _, err := findUnscoped(tx, &existingApproval, "entity_code = ? AND operation = ?", approval.EntityCode, approval.Operation); err != nil {
+			return err
+		}
+		existingApproval.EntityCode = approval.EntityCode
+		existingApproval.Operation = approval.Operation
+		existingApproval.ApprovalDefCode = approval.ApprovalDefCode
+		existingApproval.Description = approval.Description
+		existingApproval.Status = approval.Status
+		existingApproval.DeletedAt = gorm.DeletedAt{}
+		if err := saveUnscoped(tx, &existingApproval); err != nil {
+			return fmt.Errorf("审批绑定 %s/%s: %w", approval.EntityCode, approval.Operation, err)
+		}
+		kept = append(kept, existingApproval.ID)
+	}
+	if err := deleteMissing(tx, &model.TableApprovalDefinition{}, kept, "entity_code IN ?", tableEntityCodes(table.Code)); err != nil {
+		return err
+	}
+
+	// 数据权限
+	kept = nil
+	for _, permission := range table.Permissions {
+		var role model.Role
+		if err := tx.Where("code = ?", permission.RoleCode).First(&role).Error; err != nil {
+			return fmt.Errorf("角色 %s: %w", permission.RoleCode, err)
+		}
+		var existingPermission model.TablePermission
+		if _, err := findUnscoped(tx, &existingPermission, "role_id = ? AND table_code = ? AND operation = ?", role.ID, table.Code, permission.Operation); err != nil {
+			return err
+		}
+		existingPermission.RoleID = role.ID
+		existingPermission.TableCode = table.Code
+		existingPermission.Operation = permission.Operation
+		existingPermission.Status = permission.Status
+		existingPermission.DeletedAt = gorm.DeletedAt{}
+		if err := saveUnscoped(tx, &existingPermission); err != nil {
+			return fmt.Errorf("数据权限 %s/%s: %w", permission.RoleCode, permission.Operation, err)
+		}
+		kept = append(kept, existingPermission.ID)
+	}
+	return deleteMissing(tx, &model.TablePermission{}, kept, "table_code = ?", table.Code)
+}
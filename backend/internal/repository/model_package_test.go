@@ -0,0 +1,93 @@
+package repository_test
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+	"piemdm/pkg/log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gorm_sqlite "gorm.io/driver/sqlite"
+	gorm "gorm.io/gorm"
+)
+
+func newModelPackageDB(t *testing.T, name string) *gorm.DB {
+	db, err := gorm.Open(gorm_sqlite.Open(fmt.Sprintf("file:%s_%s?mode=memory&cache=shared", t.Name(), name)), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Table{}, &model.TableField{}, &model.TableFieldGroup{},
+		&model.TableApprovalDefinition{}, &model.TablePermission{}, &model.Permission{}, &model.Role{},
+		&model.ApprovalDefinition{}, &model.ApprovalNode{}))
+	require.NoError(t, db.Create(&model.Role{Code: "editor", Name: "编辑"}).Error)
+	return db
+}
+
+func TestModelPackageRepository_ExportImport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := &log.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_name", "tester")
+
+	// 源环境
+	source := newModelPackageDB(t, "source")
+	require.NoError(t, source.Create(&model.Table{Code: "product", Name: "产品", DisplayMode: "List", TableType: "Entity"}).Error)
+	require.NoError(t, source.Create([]*model.TableField{
+		{TableCode: "product", Code: "code", Name: "编码", Type: "Text", FieldType: "text", Length: 64, Status: "Normal"},
+		{TableCode: "product", Code: "qty", Name: "数量", Type: "Integer", FieldType: "number", Status: "Normal"},
+	}).Error)
+	require.NoError(t, source.Create(&model.TableFieldGroup{Code: "product_base", Name: "基本信息", TableCode: "product"}).Error)
+	def := &model.ApprovalDefinition{Name: "产品审批"}
+	require.NoError(t, source.Create(def).Error)
+	require.NoError(t, source.Create(&model.ApprovalNode{ApprovalDefCode: def.Code, NodeCode: "start", NodeName: "开始", NodeType: model.NodeTypeStart}).Error)
+	require.NoError(t, source.Create(&model.TableApprovalDefinition{EntityCode: "product", Operation: "Create", ApprovalDefCode: def.Code}).Error)
+	var role model.Role
+	require.NoError(t, source.Where("code = ?", "editor").First(&role).Error)
+	require.NoError(t, source.Create(&model.TablePermission{RoleID: role.ID, TableCode: "product", Operation: "All"}).Error)
+
+	sourceRepo := repository.NewRepository(source, nil, logger)
+	pkg, err := repository.NewModelPackageRepository(sourceRepo, repository.NewBaseRepository(sourceRepo)).Export([]string{"product"})
+	require.NoError(t, err)
+	require.Len(t, pkg.Tables, 1)
+	table := pkg.Tables[0]
+	assert.Len(t, table.Fields, 2)
+	assert.Len(t, table.FieldGroups, 1)
+	require.Len(t, table.Permissions, 1)
+	assert.Equal(t, "editor", table.Permissions[0].RoleCode)
+	require.Len(t, pkg.ApprovalDefinitions, 1)
+	assert.Equal(t, def.Code, pkg.ApprovalDefinitions[0].Code)
+	assert.Len(t, pkg.ApprovalDefinitions[0].Nodes, 1)
+
+	// 目标环境导入, 审批定义保留编码, 数据权限按角色编码关联
+	target := newModelPackageDB(t, "target")
+	targetRepo := repository.NewRepository(target, nil, logger)
+	repo := repository.NewModelPackageRepository(targetRepo, repository.NewBaseRepository(targetRepo))
+	require.NoError(t, repo.Import(c, pkg))
+
+	imported, err := repo.Export([]string{"product"})
+	require.NoError(t, err)
+	assert.Equal(t, pkg.Tables, imported.Tables)
+	assert.Equal(t, pkg.ApprovalDefinitions, imported.ApprovalDefinitions)
+
+	// 包中删除的字段标记为已删除, 再次导入时恢复
+	fields := table.Fields
+	table.Fields = fields[:1]
+	require.NoError(t, repo.Import(c, pkg))
+	var qty model.TableField
+	require.NoError(t, target.Unscoped().Where("code = ?", "qty").First(&qty).Error)
+	assert.Equal(t, "Deleted", qty.Status)
+	assert.True(t, qty.DeletedAt.Valid)
+
+	table.Fields = fields
+	require.NoError(t, repo.Import(c, pkg))
+	require.NoError(t, target.Where("code = ?", "qty").First(&qty).Error)
+	assert.Equal(t, "Normal", qty.Status)
+	var count int64
+	require.NoError(t, target.Unscoped().Model(&model.TableField{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+}
@@ -6,6 +6,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"piemdm/pkg/log"
@@ -13,29 +14,36 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 )
 
 type Repository struct {
-	db     *gorm.DB
-	rdb    *redis.Client
-	logger *log.Logger
+	db      *gorm.DB
+	rdb     *redis.Client
+	logger  *log.Logger
+	dialect dialect // 连接的数据库的方言, 用于构建查询条件和编译结构化查询
 }
 
 func NewRepository(db *gorm.DB, rdb *redis.Client, logger *log.Logger) *Repository {
 	return &Repository{
-		db:     db,
-		rdb:    rdb,
-		logger: logger,
+		db:      db,
+		rdb:     rdb,
+		logger:  logger,
+		dialect: dialectOf(db),
 	}
 }
 
-// DB
+// DB 按 data.db.driver 连接 MySQL (默认)、PostgreSQL 或 SQLite; 未配置 data.db.dsn 时使用旧的 data.mysql 配置
 func NewDB(conf *viper.Viper) *gorm.DB {
-
-	dsn := conf.GetString("data.mysql.dsn")
+	prefix := "data.db"
+	if conf.GetString(prefix+".dsn") == "" {
+		prefix = "data.mysql"
+	}
+	dsn := conf.GetString(prefix + ".dsn")
 
 	// 1. 基础配置
 	gormConfig := &gorm.Config{
@@ -66,12 +74,11 @@ func NewDB(conf *viper.Viper) *gorm.DB {
 	}
 
 	// 2. 打开数据库连接
-	db, err := gorm.Open(mysql.New(mysql.Config{
-		DSN:               dsn,
-		DefaultStringSize: 256, // 如果数据库较新，可以直接用 256；兼容老库用 191
-		// 参考https://cloud.tencent.com/developer/article/1917039
-	}), gormConfig)
-
+	dialector, err := openDialector(conf.GetString(prefix+".driver"), dsn)
+	if err != nil {
+		panic(err.Error())
+	}
+	db, err := gorm.Open(dialector, gormConfig)
 	if err != nil {
 		panic("failed to connect database: " + err.Error())
 	}
@@ -108,10 +115,10 @@ func NewDB(conf *viper.Viper) *gorm.DB {
 	// }
 	// SetMaxIdleConns 用于设置连接池中空闲连接的最大数量。
 	// 根据并发量设置，一般设为 MaxOpenConns 的 30%-50%
-	sqlDB.SetMaxIdleConns(conf.GetInt("data.mysql.max_conn"))
+	sqlDB.SetMaxIdleConns(conf.GetInt(prefix + ".max_conn"))
 	// SetMaxOpenConns 设置打开数据库连接的最大数量。
 	// 根据数据库规格和微服务实例数计算，防止打爆数据库
-	sqlDB.SetMaxOpenConns(conf.GetInt("data.mysql.max_open"))
+	sqlDB.SetMaxOpenConns(conf.GetInt(prefix + ".max_open"))
 	// SetConnMaxLifetime 设置了连接可复用的最大时间。
 	// 必须小于 MySQL 服务端 wait_timeout (默认 8小时)，通常设置为 1小时或更短
 	// 如果不设置，由于网络波动或防火墙切断，连接可能会在服务端断开，导致 "invalid connection" 错误
@@ -121,6 +128,24 @@ func NewDB(conf *viper.Viper) *gorm.DB {
 	return db
 }
 
+// openDialector 数据库驱动: mysql (默认)、postgres、sqlite
+func openDialector(driver, dsn string) (gorm.Dialector, error) {
+	switch strings.ToLower(driver) {
+	case "", "mysql":
+		return mysql.New(mysql.Config{
+			DSN:               dsn,
+			DefaultStringSize: 256, // 如果数据库较新，可以直接用 256；兼容老库用 191
+			// 参考https://cloud.tencent.com/developer/article/1917039
+		}), nil
+	case "postgres", "postgresql":
+		return postgres.Open(dsn), nil
+	case "sqlite", "sqlite3":
+		// SQLite 用于单机部署和试用, dsn 为数据库文件, 如 piemdm.db?_busy_timeout=5000&_journal_mode=WAL
+		return sqlite.Open(dsn), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver: %s", driver)
+}
+
 // Redis
 func NewRedis(conf *viper.Viper) *redis.Client {
 	rdb := redis.NewClient(&redis.Options{
@@ -0,0 +1,638 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"piemdm/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// schemaChangeOrder 变更的执行顺序: 先删除旧索引和重命名, 最后建索引
+var schemaChangeOrder = map[string]int{
+	model.SchemaCreateTable:   0,
+	model.SchemaDropIndex:     1,
+	model.SchemaRenameColumn:  2,
+	model.SchemaConvertColumn: 3,
+	model.SchemaAlterColumn:   4,
+	model.SchemaAddColumn:     5,
+	model.SchemaDropColumn:    6,
+	model.SchemaAddIndex:      7,
+}
+
+// columnSpec 列类型, 用于比较字段定义与数据库中的列
+type columnSpec struct {
+	kind      string // Text Integer Decimal Date DateTime JSON
+	length    int    // Text 的长度, 0 表示不限或未知
+	precision int    // Decimal 的精度, 0 表示未知
+	scale     int
+}
+
+func (s columnSpec) String() string {
+	switch s.kind {
+	case "Text":
+		if s.length > 0 {
+			return fmt.Sprintf("varchar(%d)", s.length)
+		}
+		return "text"
+	case "Integer":
+		return "bigint"
+	case "Decimal":
+		if s.precision > 0 {
+			return fmt.Sprintf("decimal(%d,%d)", s.precision, s.scale)
+		}
+		return "decimal"
+	case "Date":
+		return "date"
+	case "DateTime":
+		return "datetime"
+	case "JSON":
+		return "json"
+	}
+	return s.kind
+}
+
+func (s columnSpec) numeric() bool {
+	return s.kind == "Integer" || s.kind == "Decimal"
+}
+
+// sameKind 判断是否同类列, 同类列只需修改长度或精度
+func (s columnSpec) sameKind(other columnSpec) bool {
+	if s.kind == other.kind {
+		return true
+	}
+	// 不支持 json 的数据库以不限长度的文本存储 JSON
+	return s.kind == "JSON" && other.kind == "Text" && other.length == 0
+}
+
+// fieldColumnSpec 字段定义对应的列类型, 与 buildMigrationStruct 一致
+func fieldColumnSpec(field *model.TableField) columnSpec {
+	switch field.Type {
+	case "Date":
+		return columnSpec{kind: "Date"}
+	case "DateTime":
+		return columnSpec{kind: "DateTime"}
+	case "Number":
+		if field.Options != nil && field.Options.Validation != nil &&
+			field.Options.Validation.Precision != nil && field.Options.Validation.Scale != nil {
+			return columnSpec{
+				kind:      "Decimal",
+				precision: *field.Options.Validation.Precision,
+				scale:     *field.Options.Validation.Scale,
+			}
+		}
+		return columnSpec{kind: "Integer"}
+	case "JSON":
+		return columnSpec{kind: "JSON"}
+	}
+	length := field.Length
+	if length <= 0 {
+		length = 255
+	}
+	return columnSpec{kind: "Text", length: length}
+}
+
+// databaseColumnSpec 数据库中的列类型
+func databaseColumnSpec(column gorm.ColumnType) columnSpec {
+	switch strings.ToLower(column.DatabaseTypeName()) {
+	case "varchar", "char", "bpchar":
+		length, _ := column.Length()
+		return columnSpec{kind: "Text", length: int(length)}
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "int2", "int4", "int8":
+		return columnSpec{kind: "Integer"}
+	case "decimal", "numeric":
+		precision, scale, _ := column.DecimalSize()
+		return columnSpec{kind: "Decimal", precision: int(precision), scale: int(scale)}
+	case "float", "double", "real", "float4", "float8":
+		return columnSpec{kind: "Decimal"}
+	case "date":
+		return columnSpec{kind: "Date"}
+	case "datetime", "timestamp", "timestamptz":
+		return columnSpec{kind: "DateTime"}
+	case "json", "jsonb":
+		return columnSpec{kind: "JSON"}
+	}
+	return columnSpec{kind: "Text"}
+}
+
+// columnTypeString 数据库中的列类型描述
+func columnTypeString(column gorm.ColumnType) string {
+	if columnType, ok := column.ColumnType(); ok && columnType != "" {
+		return columnType
+	}
+	return strings.ToLower(column.DatabaseTypeName())
+}
+
+// schemaLoss 返回计划中丢失数据或冲突的说明, 没有时返回空
+func schemaLoss(plan *model.SchemaTablePlan) string {
+	var warnings []string
+	for _, change := range plan.Changes {
+		if change.Lost > 0 || change.Conflicts > 0 {
+			warnings = append(warnings, change.Kind+" "+change.Column+change.Index+": "+change.Warning)
+		}
+	}
+	return strings.Join(warnings, "; ")
+}
+
+// PlanSchema 比较数据库中的表结构与字段定义, 生成变更计划并统计受影响的行数, 不修改数据库
+func (r *tableFieldRepository) PlanSchema(tableName string, fields []*model.TableField) (*model.SchemaTablePlan, error) {
+	plan := &model.SchemaTablePlan{Table: tableName}
+	migrator := r.db.Migrator()
+	if !migrator.HasTable(tableName) {
+		plan.Changes = []*model.SchemaChange{{Kind: model.SchemaCreateTable}}
+		return plan, nil
+	}
+	plan.Exists = true
+	if err := r.db.Table(tableName).Count(&plan.Rows).Error; err != nil {
+		return nil, err
+	}
+
+	wanted, err := r.parseMigrationStruct(tableName, fields)
+	if err != nil {
+		return nil, err
+	}
+	columnTypes, err := migrator.ColumnTypes(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	business := make(map[string]*model.TableField)
+	links := make(map[string]bool)
+	for _, field := range fields {
+		if field.FieldType == linkFieldType {
+			// 多对多字段存储在关联表中, 旧的 JSON 列由 PublicLinks 迁移
+			links[field.Code] = true
+			continue
+		}
+		business[field.Code] = field
+	}
+	existing := make(map[string]gorm.ColumnType)
+	var extras []gorm.ColumnType // 字段定义中没有的列
+	for _, columnType := range columnTypes {
+		existing[columnType.Name()] = columnType
+		if _, ok := wanted.FieldsByDBName[columnType.Name()]; !ok && !links[columnType.Name()] {
+			extras = append(extras, columnType)
+		}
+	}
+
+	renamed := make(map[string]string) // 新列名 -> 旧列名
+	for _, field := range wanted.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		tableField := business[field.DBName]
+		have, ok := existing[field.DBName]
+		if !ok && tableField != nil {
+			// 数据库中有注释 (字段名称) 相同的同类列, 视为重命名
+			for i, extra := range extras {
+				comment, _ := extra.Comment()
+				if comment != "" && comment == tableField.Name && fieldColumnSpec(tableField).sameKind(databaseColumnSpec(extra)) {
+					plan.Changes = append(plan.Changes, &model.SchemaChange{
+						Kind:   model.SchemaRenameColumn,
+						Column: field.DBName,
+						From:   extra.Name(),
+						Before: extra.Name(),
+						After:  field.DBName,
+					})
+					renamed[field.DBName] = extra.Name()
+					have, ok = extra, true
+					extras = append(extras[:i], extras[i+1:]...)
+					break
+				}
+			}
+		}
+		if !ok {
+			after := string(field.DataType)
+			if tableField != nil {
+				after = fieldColumnSpec(tableField).String()
+			} else if dataType := r.db.Dialector.DataTypeOf(field); dataType != "" {
+				after = dataType
+			}
+			plan.Changes = append(plan.Changes, &model.SchemaChange{
+				Kind:     model.SchemaAddColumn,
+				Column:   field.DBName,
+				After:    after,
+				Affected: plan.Rows,
+			})
+			continue
+		}
+		// 系统列只新增不修改
+		if tableField == nil {
+			continue
+		}
+		change, err := r.planColumnChange(tableName, have, tableField, plan.Rows)
+		if err != nil {
+			return nil, err
+		}
+		if change != nil {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+
+	for _, extra := range extras {
+		column := r.db.Statement.Quote(extra.Name())
+		change := &model.SchemaChange{
+			Kind:   model.SchemaDropColumn,
+			Column: extra.Name(),
+			Before: columnTypeString(extra),
+		}
+		where := column + " IS NOT NULL"
+		if databaseColumnSpec(extra).kind == "Text" {
+			where += " AND " + column + " <> ''"
+		}
+		if err := r.db.Table(tableName).Where(where).Count(&change.Lost).Error; err != nil {
+			return nil, err
+		}
+		if change.Lost > 0 {
+			change.Warning = fmt.Sprintf("%d 行有值, 删除列后数据丢失", change.Lost)
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	indexChanges, err := r.planIndexes(tableName, wanted, business, existing, renamed)
+	if err != nil {
+		return nil, err
+	}
+	plan.Changes = append(plan.Changes, indexChanges...)
+
+	sort.SliceStable(plan.Changes, func(i, j int) bool {
+		return schemaChangeOrder[plan.Changes[i].Kind] < schemaChangeOrder[plan.Changes[j].Kind]
+	})
+	return plan, nil
+}
+
+// parseMigrationStruct 解析表结构对应的 gorm schema, 索引名按实际表名生成
+func (r *tableFieldRepository) parseMigrationStruct(tableName string, fields []*model.TableField) (*schema.Schema, error) {
+	instance, err := r.buildMigrationStruct(tableName, fields)
+	if err != nil {
+		return nil, err
+	}
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.ParseWithSpecialTableName(instance, tableName); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// planColumnChange 比较业务字段与数据库中的列, 无变化时返回 nil
+func (r *tableFieldRepository) planColumnChange(tableName string, have gorm.ColumnType, field *model.TableField, rows int64) (*model.SchemaChange, error) {
+	want := fieldColumnSpec(field)
+	got := databaseColumnSpec(have)
+	column := r.db.Statement.Quote(have.Name())
+	change := &model.SchemaChange{
+		Kind:     model.SchemaAlterColumn,
+		Column:   field.Code,
+		Before:   columnTypeString(have),
+		After:    want.String(),
+		Affected: rows,
+	}
+
+	var lost string
+	var args []any
+	switch {
+	case want.sameKind(got):
+		changed := false
+		if want.kind == "Text" && got.length > 0 && got.length != want.length {
+			changed = true
+			if want.length < got.length {
+				lost = fmt.Sprintf("%s > %d", dialectOf(r.db).length(column), want.length)
+			}
+		}
+		if want.kind == "Decimal" && got.precision > 0 && (got.precision != want.precision || got.scale != want.scale) {
+			changed = true
+			lost = decimalLoss(column, got, want)
+		}
+		// 业务字段允许为空
+		if nullable, ok := have.Nullable(); ok && !nullable {
+			changed = true
+		}
+		if !changed {
+			return nil, nil
+		}
+	case want.numeric() && got.numeric():
+		if want.kind == "Integer" {
+			lost = fmt.Sprintf("%s <> ROUND(%s)", column, column)
+		} else {
+			lost = decimalLoss(column, got, want)
+		}
+	default:
+		change.Kind = model.SchemaConvertColumn
+		conversion := r.columnConversion(column, got, want)
+		lost, args = "NOT ("+conversion.valid+")", conversion.args
+		if conversion.lossy != "" {
+			lost += " OR " + conversion.lossy
+		}
+	}
+	if lost == "" {
+		return change, nil
+	}
+
+	where := column + " IS NOT NULL"
+	if got.kind == "Text" {
+		where += " AND " + column + " <> ''"
+	}
+	if err := r.db.Table(tableName).Where(where+" AND ("+lost+")", args...).Count(&change.Lost).Error; err != nil {
+		return nil, err
+	}
+	if change.Lost > 0 {
+		if change.Kind == model.SchemaConvertColumn {
+			change.Warning = fmt.Sprintf("%d 行不能完整转换为 %s, 不能转换的值被清空", change.Lost, want)
+		} else {
+			change.Warning = fmt.Sprintf("%d 行超出 %s, 将被截断或清空", change.Lost, want)
+		}
+	}
+	return change, nil
+}
+
+// decimalLoss 修改为 decimal(p,s) 时丢失数据的条件: 小数位被舍入或整数位超出
+func decimalLoss(column string, got, want columnSpec) string {
+	var conditions []string
+	if got.kind != "Integer" && (got.precision == 0 || got.scale > want.scale) {
+		conditions = append(conditions, fmt.Sprintf("%s <> ROUND(%s, %d)", column, column, want.scale))
+	}
+	if got.kind == "Integer" || got.precision == 0 || got.precision-got.scale > want.precision-want.scale {
+		conditions = append(conditions, fmt.Sprintf("ABS(%s) >= %s", column, decimalLimit(want)))
+	}
+	return strings.Join(conditions, " OR ")
+}
+
+// decimalLimit decimal(p,s) 整数部分的上限
+func decimalLimit(spec columnSpec) string {
+	return strconv.FormatFloat(math.Pow10(spec.precision-spec.scale), 'f', -1, 64)
+}
+
+// columnConversion 修改列类型时转换数据的方式
+type columnConversion struct {
+	valid string // 可以转换的条件, 不满足时转换为 NULL
+	args  []any
+	value string // 转换表达式
+	lossy string // 转换后丢失部分数据的条件
+}
+
+func (r *tableFieldRepository) columnConversion(column string, from, to columnSpec) columnConversion {
+	d := dialectOf(r.db)
+	conversion := columnConversion{valid: "1 = 1", value: column}
+	switch {
+	case to.kind == "Text":
+		text := d.cast(column, to)
+		conversion.value = text
+		if to.length > 0 {
+			conversion.value = d.truncate(text, to.length)
+			conversion.lossy = fmt.Sprintf("%s > %d", d.length(text), to.length)
+		}
+		return conversion
+	case from.kind == "Text":
+		conversion.valid, conversion.args = d.textPattern(column, to)
+	case from.kind == "Date" && to.kind == "DateTime":
+	case from.kind == "DateTime" && to.kind == "Date":
+		conversion.lossy = fmt.Sprintf("%s <> '00:00:00'", d.timeOfDay(column))
+	default:
+		conversion.valid = "1 = 0"
+	}
+	conversion.value = d.cast(column, to)
+	return conversion
+}
+
+// planIndexes 比较数据库中的索引与字段定义的索引
+// 只删除全部由业务列组成的索引, 系统列上的索引和手工创建的其它索引保留
+func (r *tableFieldRepository) planIndexes(tableName string, wanted *schema.Schema, business map[string]*model.TableField,
+	existing map[string]gorm.ColumnType, renamed map[string]string,
+) ([]*model.SchemaChange, error) {
+	indexes, err := r.db.Migrator().GetIndexes(tableName)
+	if err != nil {
+		return nil, err
+	}
+	haveIndexes := make(map[string]gorm.Index)
+	for _, index := range indexes {
+		haveIndexes[index.Name()] = index
+	}
+
+	var changes []*model.SchemaChange
+	wantIndexes := make(map[string]bool)
+	for _, index := range wanted.ParseIndexes() {
+		wantIndexes[index.Name] = true
+		var columns []string
+		for _, option := range index.Fields {
+			columns = append(columns, option.DBName)
+		}
+		unique := index.Class == "UNIQUE"
+		if have, ok := haveIndexes[index.Name]; ok {
+			haveUnique, _ := have.Unique()
+			if strings.Join(have.Columns(), ",") == strings.Join(columns, ",") && haveUnique == unique {
+				continue
+			}
+			changes = append(changes, &model.SchemaChange{
+				Kind:   model.SchemaDropIndex,
+				Index:  index.Name,
+				Before: describeIndex(have.Columns(), haveUnique),
+			})
+		}
+		change := &model.SchemaChange{Kind: model.SchemaAddIndex, Index: index.Name, After: describeIndex(columns, unique)}
+		if unique {
+			if change.Conflicts, err = r.countDuplicates(tableName, columns, existing, renamed); err != nil {
+				return nil, err
+			}
+			if change.Conflicts > 0 {
+				change.Warning = fmt.Sprintf("%d 行存在重复值, 不能创建唯一索引", change.Conflicts)
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	for _, index := range indexes {
+		if wantIndexes[index.Name()] {
+			continue
+		}
+		if primary, _ := index.PrimaryKey(); primary || index.Name() == "PRIMARY" || strings.HasPrefix(index.Name(), "sqlite_") {
+			continue
+		}
+		owned := len(index.Columns()) > 0
+		for _, column := range index.Columns() {
+			if business[column] == nil && !isRenamedFrom(renamed, column) {
+				owned = false
+			}
+		}
+		if !owned {
+			continue
+		}
+		unique, _ := index.Unique()
+		changes = append(changes, &model.SchemaChange{
+			Kind:   model.SchemaDropIndex,
+			Index:  index.Name(),
+			Before: describeIndex(index.Columns(), unique),
+		})
+	}
+	return changes, nil
+}
+
+func isRenamedFrom(renamed map[string]string, column string) bool {
+	for _, from := range renamed {
+		if from == column {
+			return true
+		}
+	}
+	return false
+}
+
+func describeIndex(columns []string, unique bool) string {
+	if unique {
+		return "UNIQUE(" + strings.Join(columns, ",") + ")"
+	}
+	return "(" + strings.Join(columns, ",") + ")"
+}
+
+// countDuplicates 统计唯一索引列上重复值的行数, 新增的列没有数据不统计
+func (r *tableFieldRepository) countDuplicates(tableName string, columns []string, existing map[string]gorm.ColumnType, renamed map[string]string) (int64, error) {
+	var quoted, conditions []string
+	for _, column := range columns {
+		if from, ok := renamed[column]; ok {
+			column = from
+		}
+		if _, ok := existing[column]; !ok {
+			return 0, nil
+		}
+		quoted = append(quoted, r.db.Statement.Quote(column))
+		conditions = append(conditions, r.db.Statement.Quote(column)+" IS NOT NULL")
+	}
+	var total int64
+	err := r.db.Raw(fmt.Sprintf(
+		"SELECT COALESCE(SUM(n), 0) FROM (SELECT COUNT(*) AS n FROM %s WHERE %s GROUP BY %s HAVING COUNT(*) > 1) d",
+		r.db.Statement.Quote(tableName), strings.Join(conditions, " AND "), strings.Join(quoted, ", "),
+	)).Scan(&total).Error
+	return total, err
+}
+
+// ApplySchema 在同一事务中执行各表的变更计划并记录表结构版本, version 为空时不记录
+// MySQL 的 DDL 会隐式提交事务, 失败时已执行的变更不能回滚; PostgreSQL 和 SQLite 整体回滚
+func (r *tableFieldRepository) ApplySchema(plans []*model.SchemaTablePlan, fields []*model.TableField, version *model.SchemaVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, plan := range plans {
+			if err := r.applyTablePlan(tx, plan, fields); err != nil {
+				return fmt.Errorf("%s: %w", plan.Table, err)
+			}
+		}
+		if version == nil {
+			return nil
+		}
+		return tx.Create(version).Error
+	})
+}
+
+func (r *tableFieldRepository) applyTablePlan(tx *gorm.DB, plan *model.SchemaTablePlan, fields []*model.TableField) error {
+	instance, err := r.buildMigrationStruct(plan.Table, fields)
+	if err != nil {
+		return err
+	}
+	migrator := tx.Table(plan.Table).Migrator()
+	if !plan.Exists {
+		return migrator.CreateTable(instance)
+	}
+
+	columnTypes, err := migrator.ColumnTypes(plan.Table)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]gorm.ColumnType)
+	for _, columnType := range columnTypes {
+		existing[columnType.Name()] = columnType
+	}
+	business := make(map[string]*model.TableField)
+	for _, field := range fields {
+		business[field.Code] = field
+	}
+
+	for _, change := range plan.Changes {
+		switch change.Kind {
+		case model.SchemaDropIndex:
+			err = migrator.DropIndex(instance, change.Index)
+		case model.SchemaRenameColumn:
+			err = migrator.RenameColumn(instance, change.From, change.Column)
+			existing[change.Column] = existing[change.From]
+		case model.SchemaConvertColumn:
+			err = r.convertColumn(tx, plan.Table, instance, existing[change.Column], business[change.Column])
+		case model.SchemaAlterColumn:
+			err = r.alterColumn(tx, plan.Table, instance, business[change.Column])
+		case model.SchemaAddColumn:
+			err = migrator.AddColumn(instance, change.Column)
+		case model.SchemaDropColumn:
+			err = migrator.DropColumn(instance, change.Column)
+		case model.SchemaAddIndex:
+			if !migrator.HasIndex(instance, change.Index) {
+				err = migrator.CreateIndex(instance, change.Index)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s %s%s: %w", change.Kind, change.Column, change.Index, err)
+		}
+	}
+
+	// 修改列类型时旧列上的索引随旧列删除, 按定义补建
+	wanted, err := r.parseMigrationStruct(plan.Table, fields)
+	if err != nil {
+		return err
+	}
+	for _, index := range wanted.ParseIndexes() {
+		if !migrator.HasIndex(instance, index.Name) {
+			if err := migrator.CreateIndex(instance, index.Name); err != nil {
+				return fmt.Errorf("%s %s: %w", model.SchemaAddIndex, index.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// alterColumn 修改长度或精度, 先截断或清空超出的值
+func (r *tableFieldRepository) alterColumn(tx *gorm.DB, tableName string, instance any, field *model.TableField) error {
+	want := fieldColumnSpec(field)
+	column := tx.Statement.Quote(field.Code)
+	table := tx.Statement.Quote(tableName)
+	switch want.kind {
+	case "Text":
+		d := dialectOf(tx)
+		if err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s > %d",
+			table, column, d.truncate(column, want.length), d.length(column), want.length)).Error; err != nil {
+			return err
+		}
+	case "Decimal":
+		if err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = NULL WHERE ABS(%s) >= %s",
+			table, column, column, decimalLimit(want))).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Table(tableName).Migrator().AlterColumn(instance, field.Code)
+}
+
+// convertColumn 修改列类型: 旧列改名, 按新类型新建列, 复制并转换数据后删除旧列
+// 不能转换的值为 NULL
+func (r *tableFieldRepository) convertColumn(tx *gorm.DB, tableName string, instance any, have gorm.ColumnType, field *model.TableField) error {
+	if have == nil || field == nil {
+		return fmt.Errorf("column not found")
+	}
+	migrator := tx.Table(tableName).Migrator()
+	backup := field.Code + "__old"
+	if err := migrator.RenameColumn(instance, have.Name(), backup); err != nil {
+		return err
+	}
+	if err := migrator.AddColumn(instance, field.Code); err != nil {
+		return err
+	}
+
+	from := databaseColumnSpec(have)
+	old := tx.Statement.Quote(backup)
+	conversion := r.columnConversion(old, from, fieldColumnSpec(field))
+	valid := conversion.valid
+	if from.kind == "Text" {
+		valid = old + " <> '' AND " + valid
+	}
+	sql := fmt.Sprintf("UPDATE %s SET %s = CASE WHEN %s THEN %s END WHERE %s IS NOT NULL",
+		tx.Statement.Quote(tableName), tx.Statement.Quote(field.Code), valid, conversion.value, old)
+	if err := tx.Exec(sql, conversion.args...).Error; err != nil {
+		return err
+	}
+	return migrator.DropColumn(instance, backup)
+}
@@ -0,0 +1,94 @@
+package repository_test
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+	"piemdm/pkg/log"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gorm_sqlite "gorm.io/driver/sqlite"
+	gorm "gorm.io/gorm"
+)
+
+func TestTableFieldRepository_PlanAndApplySchema(t *testing.T) {
+	db, err := gorm.Open(gorm_sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.TableField{}, &model.SchemaVersion{}))
+	require.NoError(t, db.Create([]*model.TableField{
+		{TableCode: "product", Code: "code", Name: "编码", Type: "Text", FieldType: "text", Length: 64, Status: "Normal"},
+		{TableCode: "product", Code: "qty", Name: "数量", Type: "Text", FieldType: "text", Length: 32, Status: "Normal"},
+		{TableCode: "product", Code: "legacy", Name: "旧字段", Type: "Text", FieldType: "text", Length: 32, Status: "Normal"},
+	}).Error)
+
+	logger := &log.Logger{Logger: slog.New(slog.NewTextHandler(os.Stdout, nil))}
+	repo := repository.NewRepository(db, nil, logger)
+	tfr := repository.NewTableFieldRepository(repo, repository.NewBaseRepository(repo))
+	require.NoError(t, tfr.Public("t_product", map[string]any{}))
+	require.NoError(t, db.Exec(`INSERT INTO t_product (id, code, qty, legacy) VALUES
+		(1, 'A', '12', 'x'), (2, 'A', 'many', NULL), (3, 'B', NULL, NULL)`).Error)
+
+	// qty 改为数字, 删除 legacy, 新增 remark, code 改为唯一
+	require.NoError(t, db.Model(&model.TableField{}).Where("code = ?", "qty").Update("type", "Number").Error)
+	require.NoError(t, db.Model(&model.TableField{}).Where("code = ?", "legacy").Update("status", "Deleted").Error)
+	require.NoError(t, db.Model(&model.TableField{}).Where("code = ?", "code").
+		Updates(map[string]any{"is_unique": "Yes", "index_name": "uk_product_code"}).Error)
+	require.NoError(t, db.Create(&model.TableField{
+		TableCode: "product", Code: "remark", Name: "备注", Type: "Text", FieldType: "text", Status: "Normal",
+	}).Error)
+
+	var fields []*model.TableField
+	require.NoError(t, db.Where("table_code = ? AND status = ?", "product", "Normal").Find(&fields).Error)
+	plan, err := tfr.PlanSchema("t_product", fields)
+	require.NoError(t, err)
+	assert.True(t, plan.Exists)
+	assert.Equal(t, int64(3), plan.Rows)
+
+	changes := make(map[string]*model.SchemaChange)
+	var kinds []string
+	for _, change := range plan.Changes {
+		changes[change.Column+change.Index] = change
+		kinds = append(kinds, change.Kind)
+	}
+	assert.Equal(t, []string{model.SchemaConvertColumn, model.SchemaAddColumn, model.SchemaDropColumn, model.SchemaAddIndex}, kinds)
+	assert.Equal(t, int64(1), changes["qty"].Lost, "many 不能转换为数字")
+	assert.Equal(t, "bigint", changes["qty"].After)
+	assert.Equal(t, int64(3), changes["remark"].Affected)
+	assert.Equal(t, int64(1), changes["legacy"].Lost)
+	// SQLite 的索引名称在库内唯一, 加上表名
+	assert.Equal(t, int64(2), changes["t_product_uk_product_code"].Conflicts)
+
+	// 会丢失数据的变更不能通过 Public 执行
+	assert.Error(t, tfr.Public("t_product", map[string]any{}))
+
+	// 消除重复值后执行计划并记录版本
+	require.NoError(t, db.Exec(`UPDATE t_product SET code = 'C' WHERE id = 2`).Error)
+	plan, err = tfr.PlanSchema("t_product", fields)
+	require.NoError(t, err)
+	require.NoError(t, tfr.ApplySchema([]*model.SchemaTablePlan{plan}, fields, &model.SchemaVersion{
+		TableCode: "product", Version: 2, Status: model.SchemaVersionApplied,
+	}))
+
+	var rows []map[string]any
+	require.NoError(t, db.Raw(`SELECT * FROM t_product ORDER BY id`).Scan(&rows).Error)
+	require.Len(t, rows, 3)
+	assert.EqualValues(t, 12, rows[0]["qty"])
+	assert.Nil(t, rows[1]["qty"])
+	assert.NotContains(t, rows[0], "legacy")
+	assert.Contains(t, rows[0], "remark")
+	assert.True(t, db.Migrator().HasIndex("t_product", "t_product_uk_product_code"))
+
+	var versions []model.SchemaVersion
+	require.NoError(t, db.Find(&versions).Error)
+	require.Len(t, versions, 1)
+
+	// 执行后表结构与定义一致
+	plan, err = tfr.PlanSchema("t_product", fields)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Changes)
+}
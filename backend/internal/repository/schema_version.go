@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"errors"
+
+	"piemdm/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type SchemaVersionRepository interface {
+	Latest(tableCode string) (*model.SchemaVersion, error)
+	FindPage(page, pageSize int, total *int64, where map[string]any) ([]*model.SchemaVersion, error)
+	Create(version *model.SchemaVersion) error
+}
+
+type schemaVersionRepository struct {
+	*Repository
+	source Base
+}
+
+func NewSchemaVersionRepository(repository *Repository, source Base) SchemaVersionRepository {
+	return &schemaVersionRepository{
+		Repository: repository,
+		source:     source,
+	}
+}
+
+// Latest 返回表最新的已发布版本, 没有时返回 nil
+func (r *schemaVersionRepository) Latest(tableCode string) (*model.SchemaVersion, error) {
+	var version model.SchemaVersion
+	err := r.db.Where("table_code = ? AND status = ?", tableCode, model.SchemaVersionApplied).
+		Order("version desc").First(&version).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+func (r *schemaVersionRepository) FindPage(page, pageSize int, total *int64, where map[string]any) ([]*model.SchemaVersion, error) {
+	var versions []*model.SchemaVersion
+	var version model.SchemaVersion
+
+	err := r.source.FindPage(version, &versions, page, pageSize, total, where, []string{}, "id desc")
+	if err != nil {
+		r.logger.Error("获取表结构版本失败", "err", err)
+	}
+	return versions, nil
+}
+
+// Create 记录发布失败的版本, 发布成功的版本由 ApplySchema 在事务中记录
+func (r *schemaVersionRepository) Create(version *model.SchemaVersion) error {
+	return r.db.Create(version).Error
+}
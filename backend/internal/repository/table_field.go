@@ -22,6 +22,9 @@ type TableFieldRepository interface {
 	Delete(c *gin.Context, id uint) (*model.TableField, error)
 	BatchDelete(c *gin.Context, ids []uint) error
 	Public(tableName string, entity any) error
+	PlanSchema(tableName string, fields []*model.TableField) (*model.SchemaTablePlan, error)
+	ApplySchema(plans []*model.SchemaTablePlan, fields []*model.TableField, version *model.SchemaVersion) error
+	PublicLinks(tableCode string) ([]string, error)
 	BuildEntity(tableCode string) map[string]any
 	GetTableOptions(tableCode string, filter map[string]any) ([]map[string]any, error)
 }
@@ -152,30 +155,28 @@ func (r *tableFieldRepository) Public(tableName string, entity any) error {
 		return fmt.Errorf("unsupported entity type: %T", entity)
 	}
 
-	// 构建用于AutoMigrate的临时结构体
-	migrationStruct, err := r.buildMigrationStruct(tableName)
+	tableFields, err := r.publicFields(tableName)
 	if err != nil {
-		return fmt.Errorf("failed to build migration struct: %v", err)
+		return err
 	}
-
-	// 使用GORM AutoMigrate同步表结构
-	if err = r.db.Table(tableName).AutoMigrate(migrationStruct); err != nil {
-		return fmt.Errorf("failed to migrate table: %v", err)
+	// 只执行不丢失数据的变更, 需要确认的变更由 PlanSchema 预览后通过 ApplySchema 执行
+	plan, err := r.PlanSchema(tableName, tableFields)
+	if err != nil {
+		return fmt.Errorf("failed to plan table schema: %v", err)
 	}
-
-	// GORM AutoMigrate 不会修改已存在列的 NOT NULL 约束
-	// 需要手动移除业务字段的 NOT NULL 约束
-	if err := r.removeNotNullConstraints(tableName); err != nil {
-		r.logger.Warn("移除 NOT NULL 约束时出现警告", "table", tableName, "error", err)
-		// 不中断流程,继续执行
+	if lost := schemaLoss(plan); lost != "" {
+		return fmt.Errorf("table %s: %s", tableName, lost)
+	}
+	if err := r.ApplySchema([]*model.SchemaTablePlan{plan}, tableFields, nil); err != nil {
+		return fmt.Errorf("failed to migrate table: %v", err)
 	}
 
 	r.logger.Info("表结构同步完成", "table", tableName)
 	return nil
 }
 
-// buildMigrationStruct 构建用于AutoMigrate的临时结构体
-func (r *tableFieldRepository) buildMigrationStruct(tableName string) (any, error) {
+// publicFields 查询表的字段定义
+func (r *tableFieldRepository) publicFields(tableName string) ([]*model.TableField, error) {
 	// 从tableName提取tableCode
 	tableCode := strings.TrimPrefix(tableName, "t_")
 	tableCode = strings.TrimSuffix(tableCode, "_draft")
@@ -186,13 +187,18 @@ func (r *tableFieldRepository) buildMigrationStruct(tableName string) (any, erro
 	where["status"] = "Normal"
 	where["table_code"] = tableCode
 
-	tableFields, err := r.Find("code,name,type,length,required,is_index,is_unique,index_name,index_priority,options", where)
+	tableFields, err := r.Find("", where)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get table fields: %v", err)
 	}
+	return tableFields, nil
+}
 
+// buildMigrationStruct 按字段定义构建表结构对应的临时结构体
+func (r *tableFieldRepository) buildMigrationStruct(tableName string, tableFields []*model.TableField) (any, error) {
 	// 使用reflect动态构建结构体字段
 	var structFields []reflect.StructField
+	d := dialectOf(r.db)
 
 	// 添加ID主键
 	structFields = append(structFields, reflect.StructField{
@@ -203,6 +209,10 @@ func (r *tableFieldRepository) buildMigrationStruct(tableName string) (any, erro
 
 	// 添加业务字段
 	for _, field := range tableFields {
+		// 多对多字段存储在关联表中, 见 PublicLinks
+		if field.FieldType == linkFieldType {
+			continue
+		}
 		fieldName := r.toCamelCase(field.Code)
 		var fieldType reflect.Type
 		var gormTag string
@@ -235,11 +245,11 @@ func (r *tableFieldRepository) buildMigrationStruct(tableName string) (any, erro
 		case "Date":
 			// 日期类型,只存储日期部分
 			fieldType = reflect.TypeOf(time.Time{})
-			gormTag = fmt.Sprintf(`gorm:"column:%s;type:date;comment:%s"`, field.Code, field.Name)
+			gormTag = fmt.Sprintf(`column:%s;type:date;comment:%s`, field.Code, field.Name)
 		case "DateTime":
 			// 日期时间类型,存储日期+时间
 			fieldType = reflect.TypeOf(time.Time{})
-			gormTag = fmt.Sprintf(`gorm:"column:%s;type:datetime(3);comment:%s"`, field.Code, field.Name)
+			gormTag = fmt.Sprintf(`column:%s;type:%s;comment:%s`, field.Code, d.dateTimeType(), field.Name)
 		case "Number":
 			// 检查是否为 decimal 类型（通过 options.validation 中的 precision 和 scale 判断）
 			if field.Options != nil && field.Options.Validation != nil &&
@@ -248,18 +258,22 @@ func (r *tableFieldRepository) buildMigrationStruct(tableName string) (any, erro
 				fieldType = reflect.TypeOf(float64(0))
 				precision := *field.Options.Validation.Precision
 				scale := *field.Options.Validation.Scale
-				gormTag = fmt.Sprintf(`gorm:"column:%s;type:decimal(%d,%d);default:0;comment:%s"`, field.Code, precision, scale, field.Name)
+				gormTag = fmt.Sprintf(`column:%s;type:decimal(%d,%d);default:0;comment:%s`, field.Code, precision, scale, field.Name)
 			} else {
 				// integer 类型使用 int
 				fieldType = reflect.TypeOf(0)
-				gormTag = fmt.Sprintf(`gorm:"column:%s;default:0;comment:%s"`, field.Code, field.Name)
+				gormTag = fmt.Sprintf(`column:%s;default:0;comment:%s`, field.Code, field.Name)
 			}
+		case "JSON":
+			// JSON 类型使用数据库原生 JSON 列, 支持按路径查询
+			fieldType = reflect.TypeOf("")
+			gormTag = fmt.Sprintf(`column:%s;type:%s;comment:%s`, field.Code, d.jsonType(), field.Name)
 		default: // "Text"
 			fieldType = reflect.TypeOf("")
 			if field.Length > 0 {
-				gormTag = fmt.Sprintf(`gorm:"column:%s;size:%d;comment:%s"`, field.Code, field.Length, field.Name)
+				gormTag = fmt.Sprintf(`column:%s;size:%d;comment:%s`, field.Code, field.Length, field.Name)
 			} else {
-				gormTag = fmt.Sprintf(`gorm:"column:%s;size:255;comment:%s"`, field.Code, field.Name)
+				gormTag = fmt.Sprintf(`column:%s;size:255;comment:%s`, field.Code, field.Name)
 			}
 		}
 
@@ -277,19 +291,19 @@ func (r *tableFieldRepository) buildMigrationStruct(tableName string) (any, erro
 		// 添加索引标签
 		if field.IsIndex == "Yes" && !strings.HasSuffix(tableName, "_draft") {
 			if field.IndexName != "" {
-				gormTag += fmt.Sprintf(`;index:%s`, field.IndexName)
+				gormTag += fmt.Sprintf(`;index:%s`, d.indexName(tableName, field.IndexName))
 			}
 		}
 		if field.IsUnique == "Yes" && !strings.HasSuffix(tableName, "_draft") {
 			if field.IndexName != "" {
-				gormTag += fmt.Sprintf(`;uniqueIndex:%s`, field.IndexName)
+				gormTag += fmt.Sprintf(`;uniqueIndex:%s`, d.indexName(tableName, field.IndexName))
 			}
 		}
 
 		structFields = append(structFields, reflect.StructField{
 			Name: fieldName,
 			Type: fieldType,
-			Tag:  reflect.StructTag(gormTag),
+			Tag:  reflect.StructTag(`gorm:"` + gormTag + `"`),
 		})
 	}
 
@@ -342,6 +356,8 @@ func (r *tableFieldRepository) buildMigrationStruct(tableName string) (any, erro
 		// 0分发失败,1成功,2未发送
 		// 是否改为0未分发，1分发成功，2分发失败(多个分发任务，有失败的任务，分发失败)。
 		{Name: "SendStatus", Type: reflect.TypeOf(0), Tag: `gorm:"column:send_status;default:0"`},
+		// ===版本号=== 乐观锁, 数据表每次修改加 1; 草稿表中为草稿依据的数据版本, 发布时与数据表比较
+		{Name: "Version", Type: reflect.TypeOf(uint(0)), Tag: `gorm:"column:version;not null;default:0"`},
 	}
 
 	// 如果是draft表,添加draft相关字段
@@ -377,6 +393,9 @@ func (r *tableFieldRepository) buildMigrationStruct(tableName string) (any, erro
 		reflect.StructField{Name: "CreatedAt", Type: reflect.TypeOf(time.Time{}), Tag: `gorm:"column:created_at"`},
 		reflect.StructField{Name: "UpdatedAt", Type: reflect.TypeOf(time.Time{}), Tag: `gorm:"column:updated_at"`},
 		reflect.StructField{Name: "DeletedAt", Type: reflect.TypeOf((*time.Time)(nil)), Tag: `gorm:"column:deleted_at;index"`},
+		// 回收站: 删除人和删除原因, 恢复时清空
+		reflect.StructField{Name: "DeletedBy", Type: reflect.TypeOf((*string)(nil)), Tag: `gorm:"column:deleted_by;size:64"`},
+		reflect.StructField{Name: "DeleteReason", Type: reflect.TypeOf((*string)(nil)), Tag: `gorm:"column:delete_reason;size:255"`},
 	)
 
 	// 创建结构体类型
@@ -388,78 +407,6 @@ func (r *tableFieldRepository) buildMigrationStruct(tableName string) (any, erro
 	return instance, nil
 }
 
-// removeNotNullConstraints 移除业务字段的 NOT NULL 约束
-// GORM AutoMigrate 不会修改已存在列的约束,需要手动执行 ALTER TABLE
-func (r *tableFieldRepository) removeNotNullConstraints(tableName string) error {
-	// 从tableName提取tableCode
-	tableCode := strings.TrimPrefix(tableName, "t_")
-	tableCode = strings.TrimSuffix(tableCode, "_draft")
-	tableCode = strings.TrimSuffix(tableCode, "_log")
-
-	// 查询table_field定义
-	where := make(map[string]any)
-	where["status"] = "Normal"
-	where["table_code"] = tableCode
-
-	tableFields, err := r.Find("code,name,type,length,options", where)
-	if err != nil {
-		return fmt.Errorf("failed to get table fields: %v", err)
-	}
-
-	// 检查表是否存在
-	var tableExists bool
-	err = r.db.Raw("SELECT COUNT(*) > 0 FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", tableName).Scan(&tableExists).Error
-	if err != nil || !tableExists {
-		// 表不存在,无需处理
-		return nil
-	}
-
-	// 为每个业务字段移除 NOT NULL 约束
-	for _, field := range tableFields {
-		var columnType string
-
-		// 构建正确的列类型定义
-		switch field.Type {
-		case "Date":
-			columnType = "date"
-		case "DateTime":
-			columnType = "datetime(3)"
-		case "Number":
-			// 检查是否为 decimal 类型
-			if field.Options != nil && field.Options.Validation != nil &&
-				field.Options.Validation.Precision != nil && field.Options.Validation.Scale != nil {
-				precision := *field.Options.Validation.Precision
-				scale := *field.Options.Validation.Scale
-				columnType = fmt.Sprintf("decimal(%d,%d) DEFAULT 0", precision, scale)
-			} else {
-				columnType = "bigint DEFAULT 0"
-			}
-		case "Text":
-			if field.Length > 0 {
-				columnType = fmt.Sprintf("varchar(%d)", field.Length)
-			} else {
-				columnType = "varchar(255)"
-			}
-		default:
-			columnType = "varchar(255)"
-		}
-
-		// 添加 COMMENT
-		if field.Name != "" {
-			columnType += fmt.Sprintf(" COMMENT '%s'", field.Name)
-		}
-
-		// 执行 ALTER TABLE MODIFY COLUMN 移除 NOT NULL
-		sql := fmt.Sprintf("ALTER TABLE `%s` MODIFY COLUMN `%s` %s", tableName, field.Code, columnType)
-		if err := r.db.Exec(sql).Error; err != nil {
-			r.logger.Warn("移除字段 NOT NULL 约束失败", "table", tableName, "field", field.Code, "error", err)
-			// 继续处理其他字段
-		}
-	}
-
-	return nil
-}
-
 // toCamelCase 将字段代码转换为合法的 Go 驼峰命名标识符
 // 处理下划线(_)、连字符(-)等分隔符,移除非法字符
 // 例如: "fie-1222" -> "Fie1222", "user_name" -> "UserName"
@@ -517,7 +464,7 @@ func (r *tableFieldRepository) BuildEntity(tableCode string) map[string]any {
 	}
 	tableName := fmt.Sprintf("t_%s", tableCode)
 
-	sel := "code,type,length,required,is_index,is_unique,index_name,index_priority,options"
+	sel := "code,type,field_type,length,required,is_index,is_unique,index_name,index_priority,options"
 	tableFields, err := r.Find(sel, where)
 	if err != nil {
 		r.logger.Error("获取表字段失败", "error", err)
@@ -529,6 +476,10 @@ func (r *tableFieldRepository) BuildEntity(tableCode string) map[string]any {
 	// 添加业务字段
 	for _, tableField := range tableFields {
 		fieldCode := tableField.Code
+		// 多对多字段存储在关联表中
+		if tableField.FieldType == linkFieldType {
+			continue
+		}
 
 		// MySQL数据类型 ---------------------------------------------------------
 		// 类型	大小	范围（有符号）	范围（无符号）	用途
@@ -566,6 +517,9 @@ func (r *tableFieldRepository) BuildEntity(tableCode string) map[string]any {
 			} else {
 				entity[fieldCode] = 0
 			}
+		case "JSON":
+			// JSON 列不接受空字符串, 未填写时为 NULL
+			entity[fieldCode] = nil
 		default: // "Text"
 			entity[fieldCode] = ""
 		}
@@ -619,6 +573,8 @@ func (r *tableFieldRepository) BuildEntity(tableCode string) map[string]any {
 	// 0分发失败,1成功,2未发送
 	// 是否改为0未分发，1分发成功，2分发失败(多个分发任务，有失败的任务，分发失败)。
 	entity["send_status"] = 0
+	// ===版本号=== 乐观锁, 新增时为 0
+	entity["version"] = uint(0)
 
 	// 添加流程字段(仅draft表)
 	if strings.HasSuffix(tableName, "_draft") {
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"piemdm/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TableFieldGroupRepository interface {
+	FindOne(id uint) (*model.TableFieldGroup, error)
+	Find(where map[string]any) ([]*model.TableFieldGroup, error)
+	FindPage(page, pageSize int, total *int64, where map[string]any) ([]*model.TableFieldGroup, error)
+	Create(c *gin.Context, group *model.TableFieldGroup) error
+	Update(c *gin.Context, group *model.TableFieldGroup) error
+	Delete(c *gin.Context, id uint) error
+}
+
+type tableFieldGroupRepository struct {
+	*Repository
+	source Base
+}
+
+func NewTableFieldGroupRepository(repository *Repository, source Base) TableFieldGroupRepository {
+	return &tableFieldGroupRepository{
+		Repository: repository,
+		source:     source,
+	}
+}
+
+func (r *tableFieldGroupRepository) FindOne(id uint) (*model.TableFieldGroup, error) {
+	var group model.TableFieldGroup
+	if err := r.source.FirstById(&group, id); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// Find 按显示顺序返回字段组
+func (r *tableFieldGroupRepository) Find(where map[string]any) ([]*model.TableFieldGroup, error) {
+	var groups []*model.TableFieldGroup
+	if err := r.db.Where(where).Order("sort, id").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (r *tableFieldGroupRepository) FindPage(page, pageSize int, total *int64, where map[string]any) ([]*model.TableFieldGroup, error) {
+	var groups []*model.TableFieldGroup
+	var group model.TableFieldGroup
+
+	err := r.source.FindPage(group, &groups, page, pageSize, total, where, []string{}, "sort, id")
+	if err != nil {
+		r.logger.Error("获取字段组失败", "err", err)
+	}
+	return groups, nil
+}
+
+func (r *tableFieldGroupRepository) Create(c *gin.Context, group *model.TableFieldGroup) error {
+	return r.db.WithContext(c).Create(group).Error
+}
+
+// Update 修改字段组, 角色可以清空, 所以按字段名更新
+func (r *tableFieldGroupRepository) Update(c *gin.Context, group *model.TableFieldGroup) error {
+	return r.db.WithContext(c).Model(&model.TableFieldGroup{}).Where("id = ?", group.ID).
+		Select("name", "view", "sort", "collapsible", "collapsed", "view_roles", "edit_roles", "description", "status", "updated_by").
+		Updates(group).Error
+}
+
+func (r *tableFieldGroupRepository) Delete(c *gin.Context, id uint) error {
+	group := model.TableFieldGroup{ID: id}
+	return r.db.WithContext(c).Delete(&group).Error
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"piemdm/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TableRelationRepository interface {
+	FindOne(id uint) (*model.TableRelation, error)
+	Find(where map[string]any) ([]*model.TableRelation, error)
+	FindPage(page, pageSize int, total *int64, where map[string]any) ([]*model.TableRelation, error)
+	Create(c *gin.Context, relation *model.TableRelation) error
+	Update(c *gin.Context, relation *model.TableRelation) error
+	Delete(c *gin.Context, id uint) error
+}
+
+type tableRelationRepository struct {
+	*Repository
+	source Base
+}
+
+func NewTableRelationRepository(repository *Repository, source Base) TableRelationRepository {
+	return &tableRelationRepository{
+		Repository: repository,
+		source:     source,
+	}
+}
+
+func (r *tableRelationRepository) FindOne(id uint) (*model.TableRelation, error) {
+	var relation model.TableRelation
+	if err := r.source.FirstById(&relation, id); err != nil {
+		return nil, err
+	}
+	return &relation, nil
+}
+
+// Find 按显示顺序返回关联视图
+func (r *tableRelationRepository) Find(where map[string]any) ([]*model.TableRelation, error) {
+	var relations []*model.TableRelation
+	if err := r.db.Where(where).Order("sort, id").Find(&relations).Error; err != nil {
+		return nil, err
+	}
+	return relations, nil
+}
+
+func (r *tableRelationRepository) FindPage(page, pageSize int, total *int64, where map[string]any) ([]*model.TableRelation, error) {
+	var relations []*model.TableRelation
+	var relation model.TableRelation
+
+	err := r.source.FindPage(relation, &relations, page, pageSize, total, where, []string{}, "sort, id")
+	if err != nil {
+		r.logger.Error("获取关联视图失败", "err", err)
+	}
+	return relations, nil
+}
+
+func (r *tableRelationRepository) Create(c *gin.Context, relation *model.TableRelation) error {
+	return r.db.WithContext(c).Create(relation).Error
+}
+
+// Update 修改关联视图, 显示顺序可以改为 0, 所以按字段名更新
+func (r *tableRelationRepository) Update(c *gin.Context, relation *model.TableRelation) error {
+	return r.db.WithContext(c).Model(&model.TableRelation{}).Where("id = ?", relation.ID).
+		Select("relation_table", "relation_name", "relation_code", "sort", "status", "updated_by").
+		Updates(relation).Error
+}
+
+func (r *tableRelationRepository) Delete(c *gin.Context, id uint) error {
+	relation := model.TableRelation{ID: id}
+	return r.db.WithContext(c).Delete(&relation).Error
+}
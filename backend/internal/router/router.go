@@ -51,6 +51,11 @@ func NewServerHTTP(
 	tableApprovalDefinition handler.TableApprovalDefinitionHandler,
 	upload handler.UploadHandler,
 	tablePermission handler.TablePermissionHandler,
+	hierarchy handler.HierarchyHandler,
+	matchRule handler.MatchRuleHandler,
+	tableFieldGroup handler.TableFieldGroupHandler,
+	tableRelation handler.TableRelationHandler,
+	modelPackage handler.ModelPackageHandler,
 
 	// OpenAPI
 	openApi handler.OpenApiHandler,
@@ -125,6 +130,11 @@ func NewServerHTTP(
 		TableApprovalDefinition: tableApprovalDefinition,
 		Upload:                  upload,
 		TablePermission:         tablePermission,
+		Hierarchy:               hierarchy,
+		MatchRule:               matchRule,
+		TableFieldGroup:         tableFieldGroup,
+		TableRelation:           tableRelation,
+		ModelPackage:            modelPackage,
 
 		// OpenAPI
 		OpenApi: openApi,
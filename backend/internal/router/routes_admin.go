@@ -89,6 +89,54 @@ func registerAdminRoutes(r *gin.RouterGroup, h *Handlers) {
 			webhooks.DELETE("/batch", middleware.CasbinMiddleware(h.Enforcer, "webhook", "delete"), h.Webhook.BatchDelete)
 		}
 
+		// 层级相关路由
+		hierarchies := adminRouter.Group("/hierarchies")
+		{
+			hierarchies.GET("", middleware.CasbinMiddleware(h.Enforcer, "hierarchy", "list"), h.Hierarchy.List) // 不要使用 "/"
+			hierarchies.GET("/:id", middleware.CasbinMiddleware(h.Enforcer, "hierarchy", "list"), h.Hierarchy.Get)
+			hierarchies.POST("", middleware.CasbinMiddleware(h.Enforcer, "hierarchy", "create"), h.Hierarchy.Create) // 不要使用 "/"
+			hierarchies.PUT("/:id", middleware.CasbinMiddleware(h.Enforcer, "hierarchy", "update"), h.Hierarchy.Update)
+			hierarchies.DELETE("/:id", middleware.CasbinMiddleware(h.Enforcer, "hierarchy", "delete"), h.Hierarchy.Delete)
+		}
+
+		// 匹配规则相关路由
+		matchRules := adminRouter.Group("/match_rules")
+		{
+			matchRules.GET("", middleware.CasbinMiddleware(h.Enforcer, "match_rule", "list"), h.MatchRule.List) // 不要使用 "/"
+			matchRules.GET("/:id", middleware.CasbinMiddleware(h.Enforcer, "match_rule", "list"), h.MatchRule.Get)
+			matchRules.POST("", middleware.CasbinMiddleware(h.Enforcer, "match_rule", "create"), h.MatchRule.Create) // 不要使用 "/"
+			matchRules.PUT("/:id", middleware.CasbinMiddleware(h.Enforcer, "match_rule", "update"), h.MatchRule.Update)
+			matchRules.DELETE("/:id", middleware.CasbinMiddleware(h.Enforcer, "match_rule", "delete"), h.MatchRule.Delete)
+		}
+
+		// 字段组相关路由
+		tableFieldGroups := adminRouter.Group("/table_field_groups")
+		{
+			tableFieldGroups.GET("", middleware.CasbinMiddleware(h.Enforcer, "table_field_group", "list"), h.TableFieldGroup.List) // 不要使用 "/"
+			tableFieldGroups.GET("/:id", middleware.CasbinMiddleware(h.Enforcer, "table_field_group", "list"), h.TableFieldGroup.Get)
+			tableFieldGroups.POST("", middleware.CasbinMiddleware(h.Enforcer, "table_field_group", "create"), h.TableFieldGroup.Create) // 不要使用 "/"
+			tableFieldGroups.PUT("/:id", middleware.CasbinMiddleware(h.Enforcer, "table_field_group", "update"), h.TableFieldGroup.Update)
+			tableFieldGroups.DELETE("/:id", middleware.CasbinMiddleware(h.Enforcer, "table_field_group", "delete"), h.TableFieldGroup.Delete)
+		}
+
+		// 关联视图相关路由
+		tableRelations := adminRouter.Group("/table_relations")
+		{
+			tableRelations.GET("", middleware.CasbinMiddleware(h.Enforcer, "table_relation", "list"), h.TableRelation.List) // 不要使用 "/"
+			tableRelations.GET("/:id", middleware.CasbinMiddleware(h.Enforcer, "table_relation", "list"), h.TableRelation.Get)
+			tableRelations.POST("", middleware.CasbinMiddleware(h.Enforcer, "table_relation", "create"), h.TableRelation.Create) // 不要使用 "/"
+			tableRelations.PUT("/:id", middleware.CasbinMiddleware(h.Enforcer, "table_relation", "update"), h.TableRelation.Update)
+			tableRelations.DELETE("/:id", middleware.CasbinMiddleware(h.Enforcer, "table_relation", "delete"), h.TableRelation.Delete)
+		}
+
+		// 模型迁移相关路由
+		modelPackages := adminRouter.Group("/model_packages")
+		{
+			modelPackages.GET("/export", middleware.CasbinMiddleware(h.Enforcer, "model_package", "list"), h.ModelPackage.Export)
+			modelPackages.POST("/diff", middleware.CasbinMiddleware(h.Enforcer, "model_package", "list"), h.ModelPackage.Diff)
+			modelPackages.POST("/import", middleware.CasbinMiddleware(h.Enforcer, "model_package", "update"), h.ModelPackage.Import)
+		}
+
 		// 定时任务日志相关路由
 		webhookDeliveries := adminRouter.Group("/webhook_deliveries")
 		{
@@ -339,6 +387,8 @@ func registerAdminRoutes(r *gin.RouterGroup, h *Handlers) {
 			tableFields.PUT("/:id", middleware.CasbinMiddleware(h.Enforcer, "table_field", "update"), h.TableField.Update)
 			tableFields.DELETE("/:id", middleware.CasbinMiddleware(h.Enforcer, "table_field", "delete"), h.TableField.Delete)
 			tableFields.POST("/public", middleware.CasbinMiddleware(h.Enforcer, "table_field", "update"), h.TableField.Public)
+			tableFields.GET("/public/plan", middleware.CasbinMiddleware(h.Enforcer, "table_field", "list"), h.TableField.PlanPublic)
+			tableFields.GET("/schema_versions", middleware.CasbinMiddleware(h.Enforcer, "table_field", "list"), h.TableField.ListSchemaVersions)
 
 			// Batch operations
 			tableFields.POST("/batch", middleware.CasbinMiddleware(h.Enforcer, "table_field", "create"), h.TableField.BatchCreate)
@@ -361,10 +411,11 @@ func registerAdminRoutes(r *gin.RouterGroup, h *Handlers) {
 			tableApprovalDefinitons.DELETE("/batch_delete", middleware.CasbinMiddleware(h.Enforcer, "table_approval_def", "delete"), h.TableApprovalDefinition.BatchDelete)
 		}
 
-		// 实体统计相关路由
+		// 实体统计与回收站相关路由
 		entities := adminRouter.Group("/entities")
 		{
 			entities.GET("/statistics", h.Entity.GetStatistics)
+			entities.POST("/:table_code/purge", h.Entity.Purge)
 		}
 	}
 }
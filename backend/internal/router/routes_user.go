@@ -2,6 +2,7 @@ package router
 
 import (
 	"piemdm/internal/middleware"
+	"piemdm/internal/model"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,7 +21,13 @@ func registerUserRoutes(r *gin.RouterGroup, h *Handlers) {
 			entities.GET("/:table_code", h.Entity.List)
 			entities.GET("/:table_code/logs", h.Entity.ListEntityLogs)
 			entities.GET("/:table_code/histories", h.Entity.ListEntityHistories)
+			entities.GET("/:table_code/trash", h.Entity.ListTrash)
+			entities.POST("/:table_code/restore", h.Entity.Restore)
 			entities.GET("/:table_code/:id", h.Entity.Get)
+			entities.GET("/:table_code/:id/where-used", h.Entity.WhereUsed)
+			entities.GET("/:table_code/:id/relations", h.Entity.ListRelations)
+			entities.GET("/:table_code/:id/relations/:relation_id", h.Entity.ListRelated)
+			entities.POST("/:table_code/:id/rollback", h.Entity.Rollback)
 			entities.POST("/:table_code", h.Entity.Create)
 			entities.PUT("/:table_code/:id", h.Entity.Update)
 			entities.DELETE("/:table_code/:id", h.Entity.Delete)
@@ -30,12 +37,68 @@ func registerUserRoutes(r *gin.RouterGroup, h *Handlers) {
 			entities.PUT("/:table_code/batch", h.Entity.BatchUpdate)
 			entities.POST("/:table_code/batch_delete", h.Entity.BatchDelete)
 
+			// 生命周期操作
+			entities.POST("/:table_code/:id/freeze", h.Entity.Lifecycle(model.OperationFreeze))
+			entities.POST("/:table_code/:id/unfreeze", h.Entity.Lifecycle(model.OperationUnfreeze))
+			entities.POST("/:table_code/:id/lock", h.Entity.Lifecycle(model.OperationLock))
+			entities.POST("/:table_code/:id/unlock", h.Entity.Lifecycle(model.OperationUnlock))
+			entities.POST("/:table_code/:id/void", h.Entity.Lifecycle(model.OperationVoid))
+			entities.POST("/:table_code/:id/extend", h.Entity.Lifecycle(model.OperationExtend))
+			entities.POST("/:table_code/batch_freeze", h.Entity.Lifecycle(model.OperationBatchFreeze))
+			entities.POST("/:table_code/batch_unfreeze", h.Entity.Lifecycle(model.OperationBatchUnfreeze))
+			entities.POST("/:table_code/batch_lock", h.Entity.Lifecycle(model.OperationBatchLock))
+			entities.POST("/:table_code/batch_unlock", h.Entity.Lifecycle(model.OperationBatchUnlock))
+			entities.POST("/:table_code/batch_void", h.Entity.Lifecycle(model.OperationBatchVoid))
+			entities.POST("/:table_code/batch_extend", h.Entity.Lifecycle(model.OperationBatchExtend))
+
+			// 树形结构
+			entities.GET("/:table_code/tree", h.Entity.Tree)
+			entities.POST("/:table_code/:id/move", h.Entity.Move)
+			entities.GET("/:table_code/:id/ancestors", h.Entity.Ancestors)
+			entities.GET("/:table_code/:id/descendants", h.Entity.Descendants)
+			entities.GET("/:table_code/:id/siblings", h.Entity.Siblings)
+
+			// 单据 (抬头和行项目)
+			entities.POST("/:table_code/documents", h.Entity.CreateDocument)
+			entities.GET("/:table_code/:id/document", h.Entity.GetDocument)
+			entities.PUT("/:table_code/:id/document", h.Entity.UpdateDocument)
+
+			// 查重
+			entities.POST("/:table_code/duplicates/check", h.Entity.CheckDuplicates)
+			entities.POST("/:table_code/duplicates/scan", h.Entity.ScanDuplicates)
+			entities.GET("/:table_code/duplicates", h.Entity.ListDuplicateClusters)
+			entities.GET("/:table_code/duplicates/:id", h.Entity.GetDuplicateCluster)
+			entities.PUT("/:table_code/duplicates/:id", h.Entity.ReviewDuplicateCluster)
+
+			// 合并
+			entities.POST("/:table_code/merges/preview", h.Entity.PreviewMerge)
+			entities.POST("/:table_code/merges", h.Entity.Merge)
+			entities.GET("/:table_code/merges", h.Entity.ListMerges)
+			entities.GET("/:table_code/merges/:id", h.Entity.GetMerge)
+			entities.POST("/:table_code/merges/:id/unmerge", h.Entity.Unmerge)
+
 			// entity
 			entities.POST("/:table_code/import", h.Entity.Import)
+			entities.GET("/:table_code/jobs/:code", h.Entity.GetJob)
 			entities.GET("/:table_code/export", h.Entity.Export)
 			entities.GET("/:table_code/template", h.Entity.Template)
 		}
 
+		// 层级 (版本、节点和查询)
+		hierarchies := userRouter.Group("/hierarchies")
+		{
+			hierarchies.GET("", h.Hierarchy.FindByTable) // ?table_code=
+			hierarchies.GET("/:code/tree", h.Hierarchy.Tree)
+			hierarchies.GET("/:code/flat", h.Hierarchy.Flatten)
+			hierarchies.GET("/:code/versions", h.Hierarchy.Versions)
+			hierarchies.POST("/:code/versions", h.Hierarchy.CreateVersion)
+			hierarchies.PUT("/:code/versions/:version", h.Hierarchy.UpdateVersion)
+			hierarchies.DELETE("/:code/versions/:version", h.Hierarchy.DeleteVersion)
+			hierarchies.PUT("/:code/versions/:version/nodes", h.Hierarchy.SaveNodes)
+			hierarchies.POST("/:code/versions/:version/move", h.Hierarchy.MoveNode)
+			hierarchies.POST("/:code/versions/:version/publish", h.Hierarchy.Publish)
+		}
+
 		// 工作流相关路由
 		tables := userRouter.Group("/tables")
 		{
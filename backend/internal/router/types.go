@@ -40,6 +40,11 @@ type Handlers struct {
 	TableApprovalDefinition handler.TableApprovalDefinitionHandler
 	Upload                  handler.UploadHandler
 	TablePermission         handler.TablePermissionHandler // 新增
+	Hierarchy               handler.HierarchyHandler
+	MatchRule               handler.MatchRuleHandler
+	TableFieldGroup         handler.TableFieldGroupHandler
+	TableRelation           handler.TableRelationHandler
+	ModelPackage            handler.ModelPackageHandler
 
 	// OpenAPI Handler
 	OpenApi handler.OpenApiHandler
@@ -89,6 +89,15 @@ type ApprovalService interface {
 	// 统一检查方法
 	CheckExistingActiveDraft(c *gin.Context, tableCodeDraft string, entityID any) error
 
+	// 引用完整性
+	FindReferences(c *gin.Context, tableCode string, ids []uint) ([]*EntityReference, error)
+	CheckDeleteReferences(c *gin.Context, tableCode string, ids []uint) error
+	ApplyDeleteReferences(c *gin.Context, tableCode, reason string, records []map[string]any) error
+
+	// 记录合并
+	ApplyMerge(c *gin.Context, merge *model.EntityMerge) error
+	RevertMerge(c *gin.Context, merge *model.EntityMerge, reason string) error
+
 	// 统计方法
 	GetApprovalStatisticsFlow(applicantID string) (map[string]int64, error)
 	GetExpiredApprovalsFlow() ([]*model.Approval, error)
@@ -126,6 +135,15 @@ type approvalService struct {
 
 	// 自动编码服务
 	autocodeService AutocodeService
+
+	// 表定义, 用于查询单据的行项目表
+	tableRepository repository.TableRepository
+
+	// 层级, 用于发布审批通过的层级版本
+	hierarchyRepository repository.HierarchyRepository
+
+	// 记录合并, 用于执行审批通过的合并
+	entityMergeRepository repository.EntityMergeRepository
 }
 
 func NewApprovalService(
@@ -148,6 +166,9 @@ func NewApprovalService(
 	notificationService notification.NotificationService,
 	feishuIntegrationService *feishu.Service,
 	autocodeService AutocodeService,
+	tableRepository repository.TableRepository,
+	hierarchyRepository repository.HierarchyRepository,
+	entityMergeRepository repository.EntityMergeRepository,
 ) ApprovalService {
 	s := &approvalService{
 		Service:                           service,
@@ -169,6 +190,9 @@ func NewApprovalService(
 		notificationService:               notificationService,
 		feishuIntegrationService:          feishuIntegrationService,
 		autocodeService:                   autocodeService,
+		tableRepository:                   tableRepository,
+		hierarchyRepository:               hierarchyRepository,
+		entityMergeRepository:             entityMergeRepository,
 	}
 
 	// 注册飞书回调
@@ -210,6 +234,7 @@ func (s *approvalService) createDummyContext(ctx context.Context, userName strin
 	c.Request, _ = http.NewRequestWithContext(ctx, "POST", "/feishu/callback", nil)
 	c.Set("user_id", uint(0))
 	c.Set("user_name", userName)
+	c.Set("source", model.EntityLogSourceFeishu)
 	return c
 }
 
@@ -372,6 +397,7 @@ func (s *approvalService) HandleExternalStatusUpdate(ctx context.Context, extern
 		// Mock Context since we are in background
 		c := &gin.Context{}
 		c.Set("user_name", "System/Feishu")
+		c.Set("source", model.EntityLogSourceFeishu)
 
 		if newStatus == model.ApprovalStatusApproved {
 			return s.completeApprovalFlow(c, approval)
@@ -462,6 +488,13 @@ func (s *approvalService) processApprovalTask(c *gin.Context, taskId uint, actio
 		return fmt.Errorf("获取审批节点失败: %v", err)
 	}
 
+	// 修改的数据在提交审批后已被其他人修改时不能通过, 只能驳回后重新提交
+	if action == "APPROVE" {
+		if err := s.checkDraftVersions(approval); err != nil {
+			return err
+		}
+	}
+
 	// 5. 更新当前任务状态
 	if err := s.updateTaskStatus(task, action, comment, currentUser); err != nil {
 		return fmt.Errorf("更新任务状态失败: %v", err)
@@ -608,7 +641,13 @@ func (s *approvalService) handleRejection(c *gin.Context, approval *model.Approv
 		s.logger.Error("取消待处理任务失败", "error", err)
 	}
 
-	// 3. 更新draft状态
+	// 3. 更新draft状态, 层级版本恢复为编辑中, 合并不再执行
+	if _, ok := model.HierarchyCodeOf(approval.EntityCode); ok {
+		return s.revertHierarchy(c, approval)
+	}
+	if _, ok := model.MergeTableOf(approval.EntityCode); ok {
+		return s.rejectMerge(c, approval)
+	}
 	tableCodeDraft := fmt.Sprintf("%s_draft", approval.EntityCode)
 	updateMap := map[string]any{
 		"draft_status": "Drafted",
@@ -647,7 +686,7 @@ func (s *approvalService) completeApprovalFlow(c *gin.Context, approval *model.A
 	s.logger.Debug("service-approval-completeApprovalFlow2", "approval", approval)
 	// 2. 执行审批通过后的业务逻辑
 	if err := s.approved(c, approval, c.GetString("user_name")); err != nil {
-		return fmt.Errorf("执行审批通过逻辑失败: %v", err)
+		return fmt.Errorf("执行审批通过逻辑失败: %w", err)
 	}
 
 	// 审批流程完成
@@ -1824,6 +1863,9 @@ func (s *approvalService) convertToStringInApproval(value any) string {
 		return "false"
 	case time.Time:
 		return v.Format("2006-01-02 15:04:05")
+	case []string:
+		data, _ := json.Marshal(v)
+		return string(data)
 	default:
 		return fmt.Sprintf("%v", v)
 	}
@@ -1944,7 +1986,18 @@ func (s *approvalService) evaluateIsEmptyInApproval(fieldValue any) bool {
 }
 
 func (s *approvalService) approved(c *gin.Context, approval *model.Approval, userName string) error {
-	tableDraft := approval.EntityCode + "_draft"
+	if _, ok := model.HierarchyCodeOf(approval.EntityCode); ok {
+		return s.publishHierarchy(c, approval)
+	}
+	if _, ok := model.MergeTableOf(approval.EntityCode); ok {
+		return s.approveMerge(c, approval)
+	}
+	return s.publishDocument(c, approval)
+}
+
+// publishDrafts 发布审批实例在表 tableCode 中的草稿
+func (s *approvalService) publishDrafts(c *gin.Context, approval *model.Approval, tableCode string) error {
+	tableDraft := tableCode + "_draft"
 
 	draftMap := make(map[string]any)
 	draftMap["approval_code"] = approval.Code
@@ -1983,8 +2036,6 @@ func (s *approvalService) approved(c *gin.Context, approval *model.Approval, use
 		delete(draft, "instance_code")
 		delete(draft, "operation")
 
-		tableCode := approval.EntityCode
-
 		// 处理 data(entiry) 表中的数据
 		// 注意：draft["operation"] 可能包含历史遗留的 operation 代码或 operation 名称
 		switch operation {
@@ -1992,9 +2043,22 @@ func (s *approvalService) approved(c *gin.Context, approval *model.Approval, use
 		// "Create", "BatchCreate" - operation 名称
 		case "C", "MC", "Create", "BatchCreate":
 			s.logger.Debug("service-approval-approved333: ", "tableCode", tableCode, "draft", draft)
+			// 发布时按最新的字段值重新计算公式字段
+			tableFields, err := s.tableFieldService.Find("", map[string]any{"table_code": tableCode})
+			if err != nil {
+				return err
+			}
+			if err := applyPublishFormulas(tableFields, draft, nil); err != nil {
+				return err
+			}
 			if err := s.entityRepository.Create(c, tableCode, draft); err != nil {
 				return fmt.Errorf("create entity error: %s", err.Error())
 			}
+			createdBy, _ := draft["created_by"].(string)
+			event := entityEvent{Operation: operation, Reason: approval.Description, ApprovalCode: approval.Code, UpdateBy: createdBy}
+			if err := writeEntityLog(c, s.entityLogService, tableCode, tableFields, event, where["id"].(uint), nil, draft); err != nil {
+				return err
+			}
 		// "U", "MU" - 历史遗留的 Update 代码（正确）
 		// "Update", "BatchUpdate" - operation 名称
 		case "U", "MU", "Update", "BatchUpdate":
@@ -2005,16 +2069,17 @@ func (s *approvalService) approved(c *gin.Context, approval *model.Approval, use
 			if err != nil {
 				return err
 			}
+			// 提交审批后记录被锁定或作废时不能发布; 修改不改变状态
+			if err := checkStatusTransition(origin, operation); err != nil {
+				return err
+			}
 
 			draft["id"] = where["id"]
 			draft["updated_by"] = draft["created_by"]
 			draft["updated_at"] = time.Now()
 			delete(draft, "created_by")
 			delete(draft, "created_at")
-			// 修改数据
-			if err := s.entityRepository.Update(c, tableCode, draft, where); err != nil {
-				return fmt.Errorf("change Entity Error: %s", err.Error())
-			}
+			delete(draft, "status")
 
 			fieldWhere := map[string]any{}
 			fieldWhere["table_code"] = tableCode
@@ -2023,48 +2088,43 @@ func (s *approvalService) approved(c *gin.Context, approval *model.Approval, use
 				return err
 			}
 
-			field := make(map[string]string)
-			// 整理字段编码和字段名称
-			for _, value := range tableFields {
-				field[value.Code] = value.Name
+			// 草稿依据的版本与数据表当前版本不一致时, 数据在提交审批后已被其他人修改, 不覆盖
+			version, _ := uintValue(draft["version"])
+			if conflict := versionConflict(tableFields, id, version, origin, draft); conflict != nil {
+				return &ConflictError{Conflicts: []*EntityConflict{conflict}}
 			}
-			// 添加系统字段的中文名称映射
-			field["action"] = "操作代码"
-			field["operation"] = "操作类型"
-			field["status"] = "状态"
-			field["send_status"] = "发送状态"
-			exclude := []string{
-				"id",
-				"updated_at",
-				"updated_by",
-				"created_at",
-				"created_by",
-				"deleted_at",
+			where["version"] = version
+
+			// 发布时按最新的字段值重新计算公式字段, 草稿中没有的字段取当前记录的值
+			if err := applyPublishFormulas(tableFields, draft, origin); err != nil {
+				return err
 			}
-			for key := range draft {
-				var excluded bool
-				for _, v := range exclude {
-					if key == v {
-						excluded = true
-					}
-				}
-				// 比较原来的值和现在的值有什么变化，如果并变化则记录日志
-				if draft[key] != origin[key] && !excluded {
-					var entityLog model.EntityLog
-					entityLog.EntityID = uint(origin["id"].(uint64))
-					entityLog.FieldCode = key
-					entityLog.FieldName = field[key]
-					entityLog.BeforeUpdate = s.convertToStringInApproval(origin[key])
-					entityLog.AfterUpdate = s.convertToStringInApproval(draft[key])
-					entityLog.UpdateBy = draft["updated_by"].(string)
-					entityLog.Reason = approval.Description
-
-					err := s.entityLogService.Create(c, tableCode, &entityLog)
-					if err != nil {
-						return err
-					}
+
+			// 修改了父节点时检查是否形成环, 并在同一事务中改写节点及其后代的 level 和 path
+			var descendants []*repository.TreePath
+			if hasTreeFields(tableFields) && treeMoved(origin, draft) {
+				if descendants, err = planTreeMove(s.entityRepository, tableCode, draft); err != nil {
+					return err
 				}
 			}
+
+			// 修改数据
+			if len(descendants) > 0 {
+				err = s.entityRepository.MoveSubtree(c, tableCode, draft, where, descendants)
+			} else {
+				err = s.entityRepository.Update(c, tableCode, draft, where)
+			}
+			if err != nil {
+				err = versionError(err, s.entityRepository, tableFields, tableCode, map[uint]uint{id: version}, draft)
+				return fmt.Errorf("change Entity Error: %w", err)
+			}
+
+			// 记录变更历史, 修改人为提交草稿的用户
+			updatedBy, _ := draft["updated_by"].(string)
+			event := entityEvent{Operation: operation, Reason: approval.Description, ApprovalCode: approval.Code, UpdateBy: updatedBy}
+			if err := writeEntityLog(c, s.entityLogService, tableCode, tableFields, event, id, origin, draft); err != nil {
+				return err
+			}
 		// 注意：这里混合了历史遗留的 operation 代码和 operation 名称
 		// "F", "MF" - 历史遗留的 Freeze 代码（根据 GetOperationInfo 应该是 "B"）
 		// "T", "MT" - 历史遗留的 Terminate 代码
@@ -2073,22 +2133,28 @@ func (s *approvalService) approved(c *gin.Context, approval *model.Approval, use
 		// "MUL" - 历史遗留的批量 Unlock 代码（根据 GetOperationInfo 应该是 "U"）
 		// "MUF" - 历史遗留的批量 Unfreeze 代码（根据 GetOperationInfo 应该是 "C"）
 		// "D", "MD" - Delete 代码（正确）
-		// "Freeze", "Unfreeze", "Delete", "Lock", "Unlock" - operation 名称
-		case "F", "MF", "T", "MT", "L", "ML", "UL", "MUL", "MUF", "D", "MD", "Freeze", "Unfreeze", "Delete", "Lock", "Unlock", "BatchFreeze", "BatchUnfreeze", "BatchDelete", "BatchLock", "BatchUnlock":
+		// "Freeze", "Unfreeze", "Delete", "Lock", "Unlock", "Void", "Extend" - operation 名称
+		case "F", "MF", "T", "MT", "L", "ML", "UL", "MUL", "MUF", "D", "MD", "Freeze", "Unfreeze", "Delete", "Lock", "Unlock", "Void", "Extend",
+			"BatchFreeze", "BatchUnfreeze", "BatchDelete", "BatchLock", "BatchUnlock", "BatchVoid", "BatchExtend":
 
 			id := where["id"].(uint)
 			origin, err := s.entityRepository.FindOne(tableCode, id)
 			if err != nil {
 				return err
 			}
+			// 提交审批后记录状态已变化, 不再允许该操作时不能发布
+			if err := checkStatusTransition(origin, operation); err != nil {
+				return err
+			}
 
 			entityMap := make(map[string]any)
 			entityMap["status"] = draft["status"]
-			entityMap["operation"] = draft["operation"]
+			entityMap["operation"] = operation
 			entityMap["updated_by"] = draft["created_by"]
 			entityMap["updated_at"] = time.Now()
-			if entityMap["operation"] == "D" || entityMap["operation"] == "MD" {
-				entityMap["deleted_at"] = time.Now()
+			if isDeleteOperation(operation) {
+				deletedBy, _ := draft["created_by"].(string)
+				markDeleted(entityMap, deletedBy, approval.Description)
 			}
 
 			if err := s.entityRepository.Update(c, tableCode, entityMap, where); err != nil {
@@ -2102,44 +2168,21 @@ func (s *approvalService) approved(c *gin.Context, approval *model.Approval, use
 				return err
 			}
 
-			field := map[string]string{}
-			for _, value := range tableFields {
-				field[value.Code] = value.Name
+			// 记录变更历史, 删除时记录删除前的全部字段
+			updatedBy, _ := entityMap["updated_by"].(string)
+			event := entityEvent{Operation: operation, Reason: approval.Description, ApprovalCode: approval.Code, UpdateBy: updatedBy}
+			after := entityMap
+			if isDeleteOperation(operation) {
+				after = nil
 			}
-			// 添加系统字段的中文名称映射
-			field["action"] = "操作代码"
-			field["operation"] = "操作类型"
-			field["status"] = "状态"
-			field["send_status"] = "发送状态"
-			exclude := []string{
-				"id",
-				"updated_at",
-				"updated_by",
-				"created_at",
-				"created_by",
-				"deleted_at",
+			if err := writeEntityLog(c, s.entityLogService, tableCode, tableFields, event, id, origin, after); err != nil {
+				return err
 			}
-			for key := range entityMap {
-				var excluded bool
-				for _, v := range exclude {
-					if key == v {
-						excluded = true
-					}
-				}
-				if entityMap[key] != origin[key] && !excluded {
-					var entityLog model.EntityLog
-					entityLog.EntityID = uint(origin["id"].(uint64))
-					entityLog.FieldCode = key
-					entityLog.FieldName = field[key]
-					entityLog.BeforeUpdate = s.convertToStringInApproval(origin[key])
-					entityLog.AfterUpdate = s.convertToStringInApproval(entityMap[key])
-					entityLog.UpdateBy = entityMap["updated_by"].(string)
-					entityLog.Reason = approval.Description
-
-					err := s.entityLogService.Create(c, tableCode, &entityLog)
-					if err != nil {
-						return err
-					}
+
+			// 删除生效后处理引用: 清空引用值或级联删除
+			if isDeleteOperation(operation) {
+				if err := s.ApplyDeleteReferences(c, tableCode, approval.Description, []map[string]any{origin}); err != nil {
+					return err
 				}
 			}
 		case "E":
@@ -2163,7 +2206,7 @@ func (s *approvalService) approved(c *gin.Context, approval *model.Approval, use
 				HookID:       webhookItem.ID,
 				ApprovalCode: approval.Code,
 				TableCode:    tableCode,
-				EntityID:     uint(draft["id"].(int64)),
+				EntityID:     where["id"].(uint),
 			}
 			s.SaveToQueue(webhookReq)
 		}
@@ -2299,8 +2342,12 @@ func (s *approvalService) GetOperationInfo(operation string, operationInfo *map[
 		info["action"] = "D"
 	case "BatchExtend": // 批量扩展
 		info["operationName"] = "批量扩展"
-		info["status"] = "Normal"
+		info["status"] = "Extended"
 		info["action"] = "I"
+	case "BatchVoid": // 批量作废
+		info["operationName"] = "批量作废"
+		info["status"] = "Voided"
+		info["action"] = "U"
 	default:
 		return fmt.Errorf("operation %s not in list。", operation)
 	}
@@ -3527,6 +3574,11 @@ func (s *approvalService) CreateDraftWithApproval(c *gin.Context, tableCode, rea
 		return err
 	}
 
+	// 字段校验, 校验不通过不生成草稿
+	if err := validateEntity(s.tableFieldService, s.entityRepository, tableCode, entityMap, false); err != nil {
+		return err
+	}
+
 	// 生成entity struct iterface
 	where := make(map[string]any)
 	where["table_code"] = tableCodeDraft
@@ -3602,6 +3654,11 @@ func (s *approvalService) UpdateDraftWithApproval(c *gin.Context, tableCode, rea
 
 	tableCodeDraft := fmt.Sprintf("%s_draft", tableCode)
 
+	// 字段校验 (只校验提交的字段)
+	if err := validateEntity(s.tableFieldService, s.entityRepository, tableCode, entityMap, true); err != nil {
+		return err
+	}
+
 	// Check if there is an active draft for this entity
 	if err := s.CheckExistingActiveDraft(c, tableCodeDraft, entityMap["id"]); err != nil {
 		return err
@@ -3624,6 +3681,15 @@ func (s *approvalService) UpdateDraftWithApproval(c *gin.Context, tableCode, rea
 	for k, v := range entityMap {
 		entity[k] = v
 	}
+	// 草稿依据的数据版本: 未提交版本号时取数据表当前版本, 发布时与数据表比较
+	if _, ok := uintValue(entityMap["version"]); !ok {
+		id, _ := entityMap["id"].(uint)
+		origin, err := s.entityRepository.FindOne(tableCode, id)
+		if err != nil {
+			return fmt.Errorf("获取原始数据失败: %v", err)
+		}
+		entity["version"] = origin["version"]
+	}
 	entity["operation"] = operation
 	entity["action"] = operationInfo["action"]
 	entity["send_status"] = 0
@@ -3769,181 +3835,45 @@ func (s *approvalService) UpdateByIdsWithApproval(c *gin.Context, tableCode, rea
 // 	return s.UpdateApprovalFlow(c, tableCode, approvalInfo)
 // }
 
-// ImportWithApproval 导入数据并启动审批流程
-func (s *approvalService) ImportWithApproval(c *gin.Context, tableCode, reason, operation string, r io.Reader) error {
-	s.logger.Debug("s ImportWithApproval0: ",
+// ImportWithApproval 将导入行保存到草稿箱并启动一个审批流程
+// 已有错误的行跳过; 单行保存失败时记录到该行的 Err, 不影响其他行
+// 没有可提交的行时不创建审批实例, 返回空审批编码
+func (s *approvalService) ImportWithApproval(c *gin.Context, tableCode, reason, operation string, rows []*ImportRow) (string, error) {
+	s.logger.Debug("s ImportWithApproval: ",
 		"tableCode", tableCode,
 		"reason", reason,
-		"operation", operation)
+		"operation", operation,
+		"rows", len(rows))
 
 	operationInfo := map[string]string{}
 	if err := s.GetOperationInfo(operation, &operationInfo); err != nil {
-		return err
-	}
-	s.logger.Debug("h ImportWithApproval: ",
-		"tableCode", tableCode,
-		"reason", reason,
-		"operation", operation)
-
-	// TODO 如果是 draft，需要转换成主表名，然后取表定义
-	// fieldTable := tableCode
-	// if strings.HasSuffix(tableCode, "_draft") {
-	// 	fieldTable = tableCode[0 : len(tableCode)-6]
-	// }
-
-	xlsx, err := excelize.OpenReader(r)
-	if err != nil {
-		return err
+		return "", err
 	}
 
-	rows := xlsx.GetRows("Sheet1")
 	// generate uuid for approval instance
 	uuid := uuid.New()
 	approvalCode := strings.ToUpper(uuid.String())
 
 	tableCodeDraft := fmt.Sprintf("%s_draft", tableCode)
-	s.logger.Debug("s ImportWithApproval1: ", "tableCodeDraft", tableCodeDraft)
 
-	// Fetch field definitions for type mapping
-	fieldWhere := map[string]any{"table_code": tableCode} // Use existing tableCode
-	// Query both FieldType (UI) and Type (DB)
-	tFields, err := s.tableFieldService.Find("code,field_type,type", fieldWhere)
-	if err != nil {
-		s.logger.Error("ImportWithApproval: fetch fields failed", "err", err)
-		return err
-	}
-
-	// Map field code to both types
-	fieldTypeMap := make(map[string]struct {
-		uiType string
-		dbType string
-	})
-
-	for _, f := range tFields {
-		fieldTypeMap[f.Code] = struct{ uiType, dbType string }{
-			uiType: f.FieldType,
-			dbType: f.Type,
-		}
-	}
-
-	s.logger.Debug("ImportWithApproval - FieldTypeMap", "count", len(fieldTypeMap), "sample", fmt.Sprintf("%v", tFields[0]))
-
-	var fields []string
 	var importedRecords []any
 	// 保存到草稿箱，并提交审批流
-	for irow, row := range rows {
-		// 生成entity struce iterface
-		entity := s.tableFieldRepository.BuildEntity(tableCodeDraft)
-		entityMap := make(map[string]any)
-		if irow == 0 {
-			fields = append(fields, row...)
-		} else {
-			for index, cell := range row {
-				if index >= len(fields) {
-					break
-				}
-				fieldCode := fields[index]
-
-				// 解决编码从文本转到unit
-				if fieldCode == "ID" || fieldCode == "id" {
-					if num, err := strconv.Atoi(cell); err == nil {
-						entityMap[fieldCode] = num
-						continue
-					}
-				}
-
-				// Handle empty strings based on field type
-				if cell == "" {
-					if types, ok := fieldTypeMap[fieldCode]; ok {
-						uiType := strings.ToLower(types.uiType)
-						dbType := strings.ToLower(types.dbType)
-
-						// Check both UI type and DB type for indicators of numeric/date values
-						isDateOrNum := false
-						if strings.Contains(uiType, "date") ||
-							strings.Contains(uiType, "time") ||
-							strings.Contains(uiType, "int") ||
-							strings.Contains(uiType, "number") ||
-							strings.Contains(uiType, "decimal") ||
-							strings.Contains(uiType, "float") ||
-							strings.Contains(uiType, "double") {
-							isDateOrNum = true
-						}
-
-						if !isDateOrNum {
-							if strings.Contains(dbType, "date") ||
-								strings.Contains(dbType, "time") ||
-								strings.Contains(dbType, "int") ||
-								strings.Contains(dbType, "decimal") ||
-								strings.Contains(dbType, "float") ||
-								strings.Contains(dbType, "double") {
-								isDateOrNum = true
-							}
-						}
-
-						if isDateOrNum {
-							entityMap[fieldCode] = nil
-							continue
-						}
-					} else {
-						// Only log if it's not a known system/ignored field or likely header mismatch
-						// But honestly, fields in Excel not in DB is normal if template is old or changed.
-						// s.logger.Warn("ImportWithApproval: field not found in definitions", "field", fieldCode, "table", tableCode)
-					}
-				}
-
-				entityMap[fieldCode] = cell
-			}
-
-			// If this is an update operation, check for existing active draft
-			if operation != "BatchCreate" {
-				if idVal, ok := entityMap["id"]; ok {
-					if err := s.CheckExistingActiveDraft(c, tableCodeDraft, idVal); err != nil {
-						return err
-					}
-				}
-			}
-
-			// 生成 draft global id
-			draftGid := s.globalIdService.GetNewID("entity_draft")
-			// 生成 entity global id
-			gid := s.globalIdService.GetNewID("entity")
-			// 合并entityMap到entity - 使用snake_case字段名
-			for k, v := range entityMap {
-				entity[k] = v
-			}
-			entity["operation"] = operation
-			entity["action"] = operationInfo["action"]
-			entity["send_status"] = 0
-			// new global id for new entity
-			if operation == "BatchCreate" {
-				entity["entity_id"] = gid
-			} else {
-				entity["entity_id"] = entityMap["id"]
-			}
-			entity["id"] = draftGid
-			entity["approval_code"] = approvalCode
-			entity["draft_status"] = "Pending"
-			entity["status"] = operationInfo["status"]
-			entity["created_by"] = "jasen"
-			s.logger.Debug("s ImportWithApproval2: ", "entity", entity)
-
-			// 生成自动编码字段 (使用 Draft 表名)
-			// Fix: 使用主表名查找配置，但写入 entity (将会存入 draft 表)
-			// 这里的 entityMap 是 draft 的数据，但字段名应该和主表一致
-			// 注意: 我们传入 tableCode (主表) 给 generateAutocodes 来查找配置
-			if err := s.generateAutocodes(c, tableCode, entity); err != nil {
-				return err
-			}
-
-			if err := s.entityRepository.Create(c, tableCodeDraft, entity); err != nil {
-				return err
-			}
-
-			// Add to imported records for approval summary
-			// Fix: Use entity instead of entityMap to ensure we have generated/restored fields
-			importedRecords = append(importedRecords, entity)
+	for _, row := range rows {
+		if row.Err != nil {
+			continue
+		}
+		entity, err := s.importDraft(c, tableCode, tableCodeDraft, operation, approvalCode, operationInfo, row.Data)
+		if err != nil {
+			row.Err = err
+			continue
 		}
+		// Add to imported records for approval summary
+		// Fix: Use entity instead of entityMap to ensure we have generated/restored fields
+		importedRecords = append(importedRecords, entity)
+	}
+
+	if len(importedRecords) == 0 {
+		return "", nil
 	}
 
 	// 保存审批实例
@@ -3959,7 +3889,70 @@ func (s *approvalService) ImportWithApproval(c *gin.Context, tableCode, reason,
 	formData := map[string]any{
 		"records": importedRecords,
 	}
-	return s.CreateApprovalFlow(c, tableCode, approvalInfo, formData)
+	if err := s.CreateApprovalFlow(c, tableCode, approvalInfo, formData); err != nil {
+		return "", err
+	}
+	return approvalCode, nil
+}
+
+// importDraft 保存一条导入数据到草稿表
+func (s *approvalService) importDraft(c *gin.Context, tableCode, tableCodeDraft, operation, approvalCode string, operationInfo map[string]string, entityMap map[string]any) (map[string]any, error) {
+	// 生成entity struce iterface
+	entity := s.tableFieldRepository.BuildEntity(tableCodeDraft)
+	// If this is an update operation, check for existing active draft
+	if operation != "BatchCreate" {
+		if idVal, ok := entityMap["id"]; ok {
+			if err := s.CheckExistingActiveDraft(c, tableCodeDraft, idVal); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 生成 draft global id
+	draftGid := s.globalIdService.GetNewID("entity_draft")
+	// 合并entityMap到entity - 使用snake_case字段名
+	for k, v := range entityMap {
+		entity[k] = v
+	}
+	// 修改的草稿依据数据表当前版本, 发布时与数据表比较 (同 UpdateDraftWithApproval)
+	if _, ok := uintValue(entityMap["version"]); !ok && operation != "BatchCreate" {
+		id, err := importRowID(entityMap)
+		if err != nil {
+			return nil, err
+		}
+		origin, err := s.entityRepository.FindOne(tableCode, id)
+		if err != nil {
+			return nil, fmt.Errorf("获取原始数据失败: %v", err)
+		}
+		entity["version"] = origin["version"]
+	}
+	entity["operation"] = operation
+	entity["action"] = operationInfo["action"]
+	entity["send_status"] = 0
+	// new global id for new entity
+	if operation == "BatchCreate" {
+		entity["entity_id"] = s.globalIdService.GetNewID("entity")
+	} else {
+		entity["entity_id"] = entityMap["id"]
+	}
+	entity["id"] = draftGid
+	entity["approval_code"] = approvalCode
+	entity["draft_status"] = "Pending"
+	entity["status"] = operationInfo["status"]
+	entity["created_by"] = c.GetString("user_name")
+
+	// 生成自动编码字段 (使用 Draft 表名)
+	// Fix: 使用主表名查找配置，但写入 entity (将会存入 draft 表)
+	// 这里的 entityMap 是 draft 的数据，但字段名应该和主表一致
+	// 注意: 我们传入 tableCode (主表) 给 generateAutocodes 来查找配置
+	if err := s.generateAutocodes(c, tableCode, entity); err != nil {
+		return nil, err
+	}
+
+	if err := s.entityRepository.Create(c, tableCodeDraft, entity); err != nil {
+		return nil, err
+	}
+	return entity, nil
 }
 
 // CheckExistingActiveDraft 检查是否存在处于审批中的草稿
@@ -4146,7 +4139,13 @@ func (s *approvalService) CancelApprovalFlow(c *gin.Context, approvalCode, reaso
 		}
 	}
 
-	// 更新draft状态
+	// 更新draft状态, 层级版本恢复为编辑中, 合并不再执行
+	if _, ok := model.HierarchyCodeOf(approval.EntityCode); ok {
+		return s.revertHierarchy(c, approval)
+	}
+	if _, ok := model.MergeTableOf(approval.EntityCode); ok {
+		return s.rejectMerge(c, approval)
+	}
 	tableCodeDraft := fmt.Sprintf("%s_draft", approval.EntityCode)
 	updateMap := map[string]any{
 		"draft_status": "Drafted",
@@ -4389,6 +4388,21 @@ func (s *approvalService) CompleteApprovalFlow(c *gin.Context, approvalCode, res
 		return err
 	}
 
+	// 层级版本没有草稿表: 拒绝、撤回时恢复为编辑中
+	if _, ok := model.HierarchyCodeOf(approval.EntityCode); ok {
+		if result == model.ApprovalStatusRejected || result == model.ApprovalStatusCanceled {
+			return s.revertHierarchy(c, approval)
+		}
+		return nil
+	}
+	// 合并没有草稿表: 拒绝、撤回时不再执行
+	if _, ok := model.MergeTableOf(approval.EntityCode); ok {
+		if result == model.ApprovalStatusRejected || result == model.ApprovalStatusCanceled {
+			return s.rejectMerge(c, approval)
+		}
+		return nil
+	}
+
 	// 同步更新关联实体的 draft_status
 	if approval.EntityCode != "" {
 		tableCodeDraft := fmt.Sprintf("%s_draft", approval.EntityCode)
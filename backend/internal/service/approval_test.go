@@ -14,10 +14,12 @@ import (
 	"piemdm/pkg/jwt"
 	"piemdm/pkg/log"
 	mock_repository "piemdm/test/mocks/repository"
+	mock_service "piemdm/test/mocks/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func setupApprovalService(t *testing.T) (service.ApprovalService, *mock_repository.MockApprovalRepository, *mock_repository.MockApprovalDefinitionRepository, *mock_repository.MockApprovalNodeRepository, *mock_repository.MockApprovalTaskRepository, *gomock.Controller) {
@@ -34,7 +36,7 @@ func setupApprovalService(t *testing.T) (service.ApprovalService, *mock_reposito
 
 	baseService := service.NewService(logger, &sid.Sid{}, &jwt.JWT{})
 
-	approvalService := service.NewApprovalService(baseService, mockApprovalRepo, nil, nil, nil, nil, nil, nil, nil, nil, mockDefRepo, nil, mockNodeRepo, nil, mockTaskRepo, nil, nil, nil, nil)
+	approvalService := service.NewApprovalService(baseService, mockApprovalRepo, nil, nil, nil, nil, nil, nil, nil, nil, mockDefRepo, nil, mockNodeRepo, nil, mockTaskRepo, nil, nil, nil, nil, nil, nil, nil)
 	return approvalService, mockApprovalRepo, mockDefRepo, mockNodeRepo, mockTaskRepo, ctrl
 }
 
@@ -165,3 +167,96 @@ func TestApprovalService_StartApproval(t *testing.T) {
 	assert.Equal(t, "start", approval.CurrentTaskID)
 }
 */
+
+// noWebhooks 没有配置 Webhook
+type noWebhooks struct{ service.WebhookService }
+
+func (noWebhooks) Find(string, map[string]any) ([]*model.Webhook, error) { return nil, nil }
+
+// 导入修改提交审批: 草稿依据记录当前的版本号, 审批通过后按该版本修改
+func TestApprovalService_ApproveImportedUpdate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	approvalRepo := mock_repository.NewMockApprovalRepository(ctrl)
+	taskService := mock_service.NewMockApprovalTaskService(ctrl)
+	defService := mock_service.NewMockApprovalDefinitionService(ctrl)
+	nodeService := mock_service.NewMockApprovalNodeService(ctrl)
+	entityRepo := mock_repository.NewMockEntityRepository(ctrl)
+	fieldService := mock_service.NewMockTableFieldService(ctrl)
+	logService := mock_service.NewMockEntityLogService(ctrl)
+	fieldRepo := mock_repository.NewMockTableFieldRepository(ctrl)
+	tableApprovalRepo := mock_repository.NewMockTableApprovalDefinitionRepository(ctrl)
+	globalIdService := mock_service.NewMockGlobalIdService(ctrl)
+	taskRepo := mock_repository.NewMockApprovalTaskRepository(ctrl)
+	autocodeService := mock_service.NewMockAutocodeService(ctrl)
+	logger := &log.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s := service.NewApprovalService(service.NewService(logger, &sid.Sid{}, &jwt.JWT{}),
+		approvalRepo, taskService, defService, nodeService, entityRepo, fieldService, logService, noWebhooks{}, fieldRepo,
+		nil, tableApprovalRepo, nil, globalIdService, taskRepo, nil, nil, nil, autocodeService, nil, nil, nil)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_name", "tester")
+
+	origin := map[string]any{"id": uint64(1), "name": "A", "status": "Normal", "version": uint64(3)}
+	entityRepo.EXPECT().FindOne("product", uint(1)).Return(origin, nil).AnyTimes()
+	fieldService.EXPECT().Find("", map[string]any{"table_code": "product"}).
+		Return([]*model.TableField{{TableCode: "product", Code: "name", Name: "名称", Type: "Text"}}, nil).AnyTimes()
+
+	// 导入: 草稿按 BuildEntity 生成, 版本号为 0, 保存前取记录的当前版本
+	fieldRepo.EXPECT().BuildEntity("product_draft").Return(map[string]any{"name": "", "version": 0})
+	entityRepo.EXPECT().Find("product_draft", "id,approval_code", gomock.Any()).Return(nil, nil)
+	globalIdService.EXPECT().GetNewID("entity_draft").Return(uint(100))
+	autocodeService.EXPECT().GenerateOrRestoreAutocodes(c, "product", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	var draft map[string]any
+	entityRepo.EXPECT().Create(c, "product_draft", gomock.Any()).DoAndReturn(func(_ *gin.Context, _ string, entity any) error {
+		draft = entity.(map[string]any)
+		return nil
+	})
+	tableApprovalRepo.EXPECT().List("product", model.ImportOperationUpdate).Return(nil, nil)
+
+	rows := []*service.ImportRow{{Line: 2, Operation: model.ImportOperationUpdate, Data: map[string]any{"id": int64(1), "name": "B"}}}
+	approvalCode, err := s.ImportWithApproval(c, "product", "导入", model.ImportOperationUpdate, rows)
+	require.NoError(t, err)
+	require.NoError(t, rows[0].Err)
+	assert.Equal(t, uint64(3), draft["version"])
+
+	// 审批通过: 版本一致, 按草稿的版本号修改记录
+	approval := &model.Approval{Code: approvalCode, ApprovalDefCode: "D1", EntityCode: "product", Status: model.ApprovalStatusPending}
+	task := &model.ApprovalTask{ID: 1, ApprovalCode: approvalCode, NodeCode: "n1", AssigneeName: "tester", Status: model.TaskStatusPending}
+	taskService.EXPECT().First(gomock.Any()).Return(task, nil).Times(2)
+	taskService.EXPECT().Update(task).Return(nil)
+	taskService.EXPECT().GetByApprovalCode(approvalCode).Return(nil, nil)
+	taskRepo.EXPECT().FindByApprovalCode(approvalCode).Return([]*model.ApprovalTask{task}, nil).AnyTimes()
+	approvalRepo.EXPECT().First(map[string]any{"code": approvalCode}).Return(approval, nil)
+	approvalRepo.EXPECT().Update(c, approval).Return(nil)
+	defService.EXPECT().First(gomock.Any()).Return(&model.ApprovalDefinition{Code: "D1"}, nil)
+	nodeService.EXPECT().List(1, 100, gomock.Any(), gomock.Any()).Return([]*model.ApprovalNode{
+		{NodeCode: "start", NodeType: "START", SortOrder: 0},
+		{NodeCode: "n1", NodeType: "APPROVAL", SortOrder: 1},
+	}, nil)
+
+	stored := map[string]any{}
+	for k, v := range draft {
+		stored[k] = v
+	}
+	stored["entity_id"] = int64(1)
+	entityRepo.EXPECT().Find("product_draft", "*", map[string]any{"approval_code": approvalCode}).
+		DoAndReturn(func(string, string, map[string]any) ([]map[string]any, error) {
+			row := make(map[string]any, len(stored))
+			for k, v := range stored {
+				row[k] = v
+			}
+			return []map[string]any{row}, nil
+		}).Times(2)
+	entityRepo.EXPECT().Update(c, "product_draft", gomock.Any(), gomock.Any()).Return(nil)
+	entityRepo.EXPECT().Update(c, "product", gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, _ string, entity any, where map[string]any) error {
+			assert.Equal(t, "B", entity.(map[string]any)["name"])
+			assert.Equal(t, map[string]any{"id": uint(1), "version": uint(3)}, where)
+			return nil
+		})
+	logService.EXPECT().Create(c, "product", gomock.Any()).Return(nil)
+
+	require.NoError(t, s.ApproveTask(c, 1, "同意"))
+}
@@ -1,8 +1,6 @@
 package service
 
 import (
-	"io"
-
 	"piemdm/internal/model"
 
 	"github.com/gin-gonic/gin"
@@ -37,5 +35,6 @@ type ApprovalWorkflowService interface {
 	CreateDraftWithApproval(c *gin.Context, tableCode, reason string, entityMap map[string]any) error
 	UpdateDraftWithApproval(c *gin.Context, tableCode, reason string, entityMap map[string]any) error
 	UpdateByIdsWithApproval(c *gin.Context, tableCode, reason string, ids []uint, entityMap map[string]any) error
-	ImportWithApproval(c *gin.Context, tableCode, reason, operation string, r io.Reader) error
+	ImportWithApproval(c *gin.Context, tableCode, reason, operation string, rows []*ImportRow) (string, error)
+	SubmitDocumentWithApproval(c *gin.Context, tableCode, reason, operation string, drafts []*DocumentDraft) (string, error)
 }
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"piemdm/internal/model"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// matchValue 字段值预先计算的各种比较形式, 查重时每条记录只计算一次
+type matchValue struct {
+	text     string              // 原始文本 (去掉首尾空白)
+	norm     string              // 规范化文本: 全角转半角、小写, 标点和空白合并为一个空格
+	phonetic string              // 发音编码: 拉丁字母的词取 Soundex, 其他词保持不变
+	tokens   map[string]struct{} // 词集合, 中文按相邻两字切分
+}
+
+// matchProfile 记录在一条匹配规则下的字段值
+type matchProfile map[string]matchValue
+
+// matchText 字段值转为文本
+func matchText(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case []byte:
+		return strings.TrimSpace(string(v))
+	}
+	return strings.TrimSpace(fmt.Sprint(value))
+}
+
+// normalizeMatchText 全角转半角、小写, 只保留字母和数字, 其余字符合并为一个空格
+func normalizeMatchText(text string) string {
+	var b strings.Builder
+	separated := false
+	for _, r := range strings.ToLower(norm.NFKC.String(text)) {
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+			separated = true
+			continue
+		}
+		if separated && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		separated = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func newMatchValue(value any) matchValue {
+	v := matchValue{text: matchText(value)}
+	v.norm = normalizeMatchText(v.text)
+	words := strings.Fields(v.norm)
+	codes := make([]string, len(words))
+	v.tokens = make(map[string]struct{}, len(words))
+	for i, word := range words {
+		codes[i] = soundex(word)
+		for _, token := range splitMatchTokens(word) {
+			v.tokens[token] = struct{}{}
+		}
+	}
+	v.phonetic = strings.Join(codes, " ")
+	return v
+}
+
+func (v matchValue) empty() bool {
+	return v.text == ""
+}
+
+// newMatchProfile 取记录中规则字段的值
+func newMatchProfile(fields model.MatchFields, record map[string]any) matchProfile {
+	profile := make(matchProfile, len(fields))
+	for _, field := range fields {
+		if _, ok := profile[field.Field]; !ok {
+			profile[field.Field] = newMatchValue(record[field.Field])
+		}
+	}
+	return profile
+}
+
+// score 两条记录的加权相似度 (0~1) 和各字段的相似度; 两条记录都为空的字段不参与计算
+func (p matchProfile) score(fields model.MatchFields, other matchProfile) (float64, map[string]float64) {
+	var total, weights float64
+	detail := make(map[string]float64, len(fields))
+	for _, field := range fields {
+		a, b := p[field.Field], other[field.Field]
+		if a.empty() && b.empty() {
+			continue
+		}
+		weight := field.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		similarity := 0.0
+		if !a.empty() && !b.empty() {
+			similarity = matchSimilarity(field.Method, a, b)
+		}
+		detail[field.Field] = similarity
+		total += similarity * weight
+		weights += weight
+	}
+	if weights == 0 {
+		return 0, detail
+	}
+	return total / weights, detail
+}
+
+// matchSimilarity 按匹配方法计算两个非空值的相似度
+func matchSimilarity(method string, a, b matchValue) float64 {
+	equal := func(x, y string) float64 {
+		if x == y {
+			return 1
+		}
+		return 0
+	}
+	switch method {
+	case model.MatchMethodExact:
+		return equal(a.text, b.text)
+	case model.MatchMethodNormalized:
+		return equal(a.norm, b.norm)
+	case model.MatchMethodPhonetic:
+		return equal(a.phonetic, b.phonetic)
+	case model.MatchMethodEditDistance:
+		x, y := []rune(a.norm), []rune(b.norm)
+		longest := max(len(x), len(y))
+		if longest == 0 {
+			return 1
+		}
+		return 1 - float64(levenshtein(x, y))/float64(longest)
+	case model.MatchMethodToken:
+		if len(a.tokens) == 0 || len(b.tokens) == 0 {
+			return equal(a.norm, b.norm)
+		}
+		common := 0
+		for token := range a.tokens {
+			if _, ok := b.tokens[token]; ok {
+				common++
+			}
+		}
+		return float64(common) / float64(len(a.tokens)+len(b.tokens)-common)
+	}
+	return 0
+}
+
+// matchKeys 查重任务的分组键: 只比较至少有一个分组键相同的记录
+// 编辑距离取前后各 3 个字符, 使开头或结尾有差异的值仍能分到同一组
+func matchKeys(method string, v matchValue) []string {
+	if v.empty() {
+		return nil
+	}
+	switch method {
+	case model.MatchMethodExact:
+		return []string{v.text}
+	case model.MatchMethodNormalized:
+		return []string{v.norm}
+	case model.MatchMethodPhonetic:
+		return []string{v.phonetic}
+	case model.MatchMethodEditDistance:
+		runes := []rune(v.norm)
+		if len(runes) <= 3 {
+			return []string{v.norm}
+		}
+		return []string{"^" + string(runes[:3]), "$" + string(runes[len(runes)-3:])}
+	case model.MatchMethodToken:
+		keys := make([]string, 0, len(v.tokens))
+		for token := range v.tokens {
+			keys = append(keys, token)
+		}
+		return keys
+	}
+	return nil
+}
+
+// splitMatchTokens 切分规范化后的词: 中文等表意文字按相邻两字切分, 其余部分保持完整
+func splitMatchTokens(word string) []string {
+	var tokens []string
+	var ideographs []rune
+	var other strings.Builder
+	flushIdeographs := func() {
+		if len(ideographs) == 1 {
+			tokens = append(tokens, string(ideographs))
+		}
+		for i := 0; i+1 < len(ideographs); i++ {
+			tokens = append(tokens, string(ideographs[i:i+2]))
+		}
+		ideographs = ideographs[:0]
+	}
+	for _, r := range word {
+		if unicode.Is(unicode.Han, r) {
+			if other.Len() > 0 {
+				tokens = append(tokens, other.String())
+				other.Reset()
+			}
+			ideographs = append(ideographs, r)
+			continue
+		}
+		flushIdeographs()
+		other.WriteRune(r)
+	}
+	flushIdeographs()
+	if other.Len() > 0 {
+		tokens = append(tokens, other.String())
+	}
+	return tokens
+}
+
+// soundex 拉丁字母词的 Soundex 编码 (如 Robert、Rupert 均为 R163), 含其他字符的词原样返回
+func soundex(word string) string {
+	codes := map[rune]byte{
+		'b': '1', 'f': '1', 'p': '1', 'v': '1',
+		'c': '2', 'g': '2', 'j': '2', 'k': '2', 'q': '2', 's': '2', 'x': '2', 'z': '2',
+		'd': '3', 't': '3',
+		'l': '4',
+		'm': '5', 'n': '5',
+		'r': '6',
+	}
+	for _, r := range word {
+		if r < 'a' || r > 'z' {
+			return word
+		}
+	}
+	if word == "" {
+		return ""
+	}
+	result := []byte{byte(unicode.ToUpper(rune(word[0])))}
+	last := codes[rune(word[0])]
+	for _, r := range word[1:] {
+		code, ok := codes[r]
+		switch {
+		case ok && code != last:
+			result = append(result, code)
+		case r == 'h' || r == 'w':
+			// h、w 不分隔相同编码
+			continue
+		}
+		last = code
+		if len(result) == 4 {
+			break
+		}
+	}
+	for len(result) < 4 {
+		result = append(result, '0')
+	}
+	return string(result)
+}
+
+// levenshtein 编辑距离
+func levenshtein(a, b []rune) int {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"testing"
+
+	"piemdm/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeMatchText(t *testing.T) {
+	assert.Equal(t, "acme co ltd", normalizeMatchText("  ACME, Co.  Ltd. "))
+	assert.Equal(t, "abc 123", normalizeMatchText("ＡＢＣ－１２３"))
+	assert.Equal(t, "北京 科技有限公司", normalizeMatchText("北京（科技有限公司）"))
+}
+
+func TestSoundex(t *testing.T) {
+	assert.Equal(t, "R163", soundex("robert"))
+	assert.Equal(t, "R163", soundex("rupert"))
+	assert.Equal(t, "A261", soundex("ashcraft"))
+	assert.Equal(t, "T522", soundex("tymczak"))
+	assert.Equal(t, "P236", soundex("pfister"))
+	assert.Equal(t, "L000", soundex("lee"))
+	assert.Equal(t, "北京", soundex("北京"))
+}
+
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 3, levenshtein([]rune("kitten"), []rune("sitting")))
+	assert.Equal(t, 0, levenshtein([]rune("上海"), []rune("上海")))
+	assert.Equal(t, 1, levenshtein([]rune("上海市"), []rune("上海")))
+	assert.Equal(t, 2, levenshtein(nil, []rune("ab")))
+}
+
+func TestSplitMatchTokens(t *testing.T) {
+	assert.Equal(t, []string{"北京", "京科", "科技"}, splitMatchTokens("北京科技"))
+	assert.Equal(t, []string{"abc", "北京", "2024"}, splitMatchTokens("abc北京2024"))
+	assert.Equal(t, []string{"京"}, splitMatchTokens("京"))
+}
+
+func TestMatchSimilarity(t *testing.T) {
+	value := func(text string) matchValue { return newMatchValue(text) }
+
+	tests := []struct {
+		method string
+		a, b   string
+		want   float64
+	}{
+		{model.MatchMethodExact, "Acme", "Acme", 1},
+		{model.MatchMethodExact, "Acme", "ACME", 0},
+		{model.MatchMethodNormalized, "Acme Ltd.", "ACME  LTD", 1},
+		{model.MatchMethodPhonetic, "Robert Smith", "Rupert Smyth", 1},
+		{model.MatchMethodPhonetic, "Robert", "Richard", 0},
+		{model.MatchMethodEditDistance, "kitten", "sitting", 1 - 3.0/7},
+		{model.MatchMethodToken, "Acme Trading Ltd", "Ltd Acme Trading", 1},
+		{model.MatchMethodToken, "acme trading", "acme holdings", 1.0 / 3},
+		{model.MatchMethodToken, "北京科技", "北京科技有限", 3.0 / 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.a+" "+tt.b, func(t *testing.T) {
+			assert.InDelta(t, tt.want, matchSimilarity(tt.method, value(tt.a), value(tt.b)), 1e-9)
+		})
+	}
+}
+
+func TestMatchProfileScore(t *testing.T) {
+	fields := model.MatchFields{
+		{Field: "name", Method: model.MatchMethodNormalized, Weight: 3},
+		{Field: "city", Method: model.MatchMethodExact, Weight: 1},
+		{Field: "phone", Method: model.MatchMethodExact}, // 权重为 0 时按 1 计算
+	}
+	a := newMatchProfile(fields, map[string]any{"name": "Acme Ltd", "city": "Shanghai", "phone": nil})
+	b := newMatchProfile(fields, map[string]any{"name": "ACME LTD.", "city": "Beijing", "phone": ""})
+
+	// phone 两边都为空, 不参与计算
+	score, detail := a.score(fields, b)
+	assert.InDelta(t, 3.0/4, score, 1e-9)
+	assert.Equal(t, map[string]float64{"name": 1, "city": 0}, detail)
+
+	// 一边为空的字段相似度为 0
+	c := newMatchProfile(fields, map[string]any{"name": "Acme Ltd", "city": "Shanghai", "phone": "123"})
+	score, _ = a.score(fields, c)
+	assert.InDelta(t, 4.0/5, score, 1e-9)
+}
+
+func TestMatchKeys(t *testing.T) {
+	v := newMatchValue("Shanghai Acme")
+	assert.Equal(t, []string{"Shanghai Acme"}, matchKeys(model.MatchMethodExact, v))
+	assert.Equal(t, []string{"shanghai acme"}, matchKeys(model.MatchMethodNormalized, v))
+	assert.Equal(t, []string{"S520 A250"}, matchKeys(model.MatchMethodPhonetic, v))
+	assert.Equal(t, []string{"^sha", "$cme"}, matchKeys(model.MatchMethodEditDistance, v))
+	assert.ElementsMatch(t, []string{"shanghai", "acme"}, matchKeys(model.MatchMethodToken, v))
+	assert.Nil(t, matchKeys(model.MatchMethodExact, newMatchValue(nil)))
+}
@@ -3,6 +3,8 @@ package service
 import (
 	"fmt"
 	"io"
+	"maps"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -10,36 +12,81 @@ import (
 	"piemdm/internal/model"
 	"piemdm/internal/repository"
 
-	"github.com/360EntSecGroup-Skylar/excelize"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
-	"github.com/tealeg/xlsx"
 )
 
 type EntityService interface {
 	// Base CRUD
 	Get(c *gin.Context, tableCode string, id uint) (map[string]any, error)
-	List(c *gin.Context, tableCode string, page, pageSize int, total *int64, where map[string]any) ([]map[string]any, error)
+	List(c *gin.Context, tableCode string, page, pageSize int, total *int64, query *model.EntityQuery) ([]map[string]any, error)
+	Search(c *gin.Context, tableCode string, page, pageSize int, total *int64, query *model.EntityQuery) ([]map[string]any, error)
 	Create(c *gin.Context, tableCode string, entity any) error
 	Update(c *gin.Context, tableCode string, entity any, where map[string]any) error
 	Delete(c *gin.Context, tableCode string, reason string, id uint) error
 
 	// Batch operations
-	BatchUpdate(c *gin.Context, tableCode, reason string, ids []uint, entityMap map[string]any) error
+	BatchUpdate(c *gin.Context, tableCode, reason string, ids []uint, versions map[uint]uint, entityMap map[string]any) error
 	BatchDelete(c *gin.Context, tableCode, reason string, ids []uint) error
 
+	// 引用完整性
+	WhereUsed(c *gin.Context, tableCode string, id uint) ([]*EntityReference, error)
+
+	// 关联视图
+	ListRelations(c *gin.Context, tableCode string, id uint, pageSize int) ([]*EntityRelation, error)
+	ListRelated(c *gin.Context, tableCode string, id, relationID uint, page, pageSize int, total *int64, query *model.EntityQuery) ([]map[string]any, error)
+
 	// 历史与日志
 	FindLogPage(c *gin.Context, tableCode string, page, pageSize int, total *int64, where map[string]any) ([]map[string]any, error)
 	Find(c *gin.Context, tableCode, selectString string, where map[string]any) ([]map[string]any, error)
+	GetAsOf(c *gin.Context, tableCode string, id uint, asOf time.Time) (map[string]any, error)
+	ListAsOf(c *gin.Context, tableCode string, page, pageSize int, total *int64, query *model.EntityQuery, asOf time.Time) ([]map[string]any, error)
+	Rollback(c *gin.Context, tableCode, reason string, id uint, target RollbackTarget) error
+	// 回收站
+	ListTrash(c *gin.Context, tableCode string, page, pageSize int, total *int64, query *model.EntityQuery) ([]map[string]any, error)
+	Restore(c *gin.Context, tableCode, reason string, ids []uint) error
+	Purge(c *gin.Context, tableCode, reason string, ids []uint) (int, error)
+
+	// 生命周期操作
+	ChangeStatus(c *gin.Context, tableCode, operation, reason string, ids []uint, versions map[uint]uint) error
+
+	// 树形结构
+	Move(c *gin.Context, tableCode, reason string, id, parentID uint, versions map[uint]uint) error
+	Ancestors(c *gin.Context, tableCode string, id uint) ([]map[string]any, error)
+	Descendants(c *gin.Context, tableCode string, id uint, depth int) ([]map[string]any, error)
+	Siblings(c *gin.Context, tableCode string, id uint) ([]map[string]any, error)
+	Tree(c *gin.Context, tableCode string, rootID uint, depth int) ([]map[string]any, error)
+
+	// 单据 (抬头和行项目)
+	GetDocument(c *gin.Context, tableCode string, id uint) (*EntityDocument, error)
+	SaveDocument(c *gin.Context, tableCode, reason string, doc *EntityDocument) (uint, error)
 
 	// 草稿功能
 	CreateDraft(c *gin.Context, tableCode, reason string, entityMap map[string]any) error
 	UpdateDraft(c *gin.Context, tableCode, reason string, entityMap map[string]any) error
 
 	// 导入导出
-	Import(c *gin.Context, tableCode, reason, operation string, r io.Reader) error
-	Export(c *gin.Context, tableCode, filter string, where map[string]any) (string, error)
-	Template(c *gin.Context, tableCode, operation string) (string, error)
+	Import(c *gin.Context, tableCode string, opts ImportOptions, r io.Reader) (*model.EntityJob, error)
+	GetJob(c *gin.Context, tableCode, code string) (*model.EntityJob, error)
+	Export(c *gin.Context, tableCode string, query *model.EntityQuery, opts ExportOptions) (*model.EntityJob, error)
+	Template(c *gin.Context, tableCode, operation string, file FileOptions) (string, error)
+
+	// 公式字段
+	RecomputeFormulas(c *gin.Context, tableCode string) (*model.EntityJob, error)
+
+	// 查重
+	CheckDuplicates(c *gin.Context, tableCode string, entityMap map[string]any) ([]*DuplicateCandidate, error)
+	ScanDuplicates(c *gin.Context, tableCode, ruleCode string) (*model.EntityJob, error)
+	ListDuplicateClusters(c *gin.Context, tableCode string, page, pageSize int, total *int64, where map[string]any) ([]*model.DuplicateCluster, error)
+	GetDuplicateCluster(c *gin.Context, tableCode string, id uint) (*DuplicateClusterDetail, error)
+	ReviewDuplicateCluster(c *gin.Context, tableCode string, id uint, status string) error
+
+	// 合并
+	PreviewMerge(c *gin.Context, tableCode string, req *MergeRequest) (*MergePreview, error)
+	Merge(c *gin.Context, tableCode, reason string, req *MergeRequest) (*model.EntityMerge, error)
+	ListMerges(c *gin.Context, tableCode string, page, pageSize int, total *int64, where map[string]any) ([]*model.EntityMerge, error)
+	GetMerge(c *gin.Context, tableCode string, id uint) (*model.EntityMerge, error)
+	Unmerge(c *gin.Context, tableCode, reason string, id uint) error
 
 	// 其他
 	BuildEntity(c *gin.Context, tableCode string) map[string]any
@@ -58,6 +105,10 @@ type entityService struct {
 	autocodeService                   AutocodeService
 	tablePermissionService            TablePermissionService // 新增
 	tableRepository                   repository.TableRepository
+	entityJobService                  EntityJobService
+	matchRuleRepository               repository.MatchRuleRepository
+	entityMergeRepository             repository.EntityMergeRepository
+	tableRelationRepository           repository.TableRelationRepository
 	conf                              *viper.Viper
 }
 
@@ -72,6 +123,10 @@ func NewEntityService(service *Service,
 	autocodeService AutocodeService,
 	tablePermissionService TablePermissionService, // 新增
 	tableRepository repository.TableRepository,
+	entityJobService EntityJobService,
+	matchRuleRepository repository.MatchRuleRepository,
+	entityMergeRepository repository.EntityMergeRepository,
+	tableRelationRepository repository.TableRelationRepository,
 	conf *viper.Viper) EntityService {
 	return &entityService{
 		Service:                           service,
@@ -85,11 +140,20 @@ func NewEntityService(service *Service,
 		autocodeService:                   autocodeService,
 		tablePermissionService:            tablePermissionService, // 新增
 		tableRepository:                   tableRepository,
+		entityJobService:                  entityJobService,
+		matchRuleRepository:               matchRuleRepository,
+		entityMergeRepository:             entityMergeRepository,
+		tableRelationRepository:           tableRelationRepository,
 		conf:                              conf,
 	}
 }
 
 func (s *entityService) checkPermission(c *gin.Context, tableCode string) error {
+	return checkTablePermission(c, s.tablePermissionService, tableCode)
+}
+
+// checkTablePermission 检查当前登录用户能否访问表
+func checkTablePermission(c *gin.Context, tablePermissionService TablePermissionService, tableCode string) error {
 	// 获取当前登录用户ID (JWT中间件设置的是string类型)
 	userIdStr, exists := c.Get("user_id")
 	if !exists {
@@ -117,7 +181,7 @@ func (s *entityService) checkPermission(c *gin.Context, tableCode string) error
 		return fmt.Errorf("user not logged in")
 	}
 
-	has, err := s.tablePermissionService.CheckTablePermission(c, userId, tableCode)
+	has, err := tablePermissionService.CheckTablePermission(c, userId, tableCode)
 	if err != nil {
 		return err
 	}
@@ -127,39 +191,174 @@ func (s *entityService) checkPermission(c *gin.Context, tableCode string) error
 	return nil
 }
 
+// checkFieldAccess 按字段组权限检查提交的字段: 当前用户不能查看或不能修改的字段,
+// 新增时不能填写, 修改时不能改变 (允许提交原值); ids 为修改的记录, 为空表示新增
+func (s *entityService) checkFieldAccess(c *gin.Context, tableCode string, entityMap map[string]any, ids ...uint) error {
+	access, err := s.tableFieldService.GetFieldAccess(c, tableCode)
+	if err != nil {
+		return err
+	}
+	return s.checkEditable(access, tableCode, entityMap, ids...)
+}
+
+// checkEditable 按已查询的字段组权限检查提交的字段, 规则同 checkFieldAccess
+func (s *entityService) checkEditable(access *FieldAccess, tableCode string, entityMap map[string]any, ids ...uint) error {
+	if access == nil {
+		return nil
+	}
+	var protected []string
+	for code, value := range entityMap {
+		if !access.Editable(code) && logFieldValue(value) != "" {
+			protected = append(protected, code)
+		}
+	}
+	if len(protected) == 0 {
+		return nil
+	}
+	sort.Strings(protected)
+	if len(ids) == 0 {
+		return fmt.Errorf("没有权限填写字段: %s", strings.Join(protected, ", "))
+	}
+
+	rows, err := s.entityRepository.Find(tableCode, "*", map[string]any{"id": ids})
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		for _, code := range protected {
+			if logFieldValue(entityMap[code]) != logFieldValue(row[code]) {
+				return fmt.Errorf("没有权限修改字段: %s", code)
+			}
+		}
+	}
+	return nil
+}
+
+// visibleColumns 可查询字段中去掉当前用户按字段组权限不能查看的字段, 查询条件、排序和返回字段都不能使用这些字段
+// access 用于从查询结果中删除这些字段, 读取记录的接口都按它过滤
+func (s *entityService) visibleColumns(c *gin.Context, tableCode string) (map[string]string, *FieldAccess, error) {
+	columns, err := s.queryColumns(tableCode)
+	if err != nil {
+		return nil, nil, err
+	}
+	access, err := s.tableFieldService.GetFieldAccess(c, tableCode)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hideColumns(columns, access), access, nil
+}
+
+// hideColumns 复制可查询字段并去掉当前用户不能查看的字段
+func hideColumns(columns map[string]string, access *FieldAccess) map[string]string {
+	visible := maps.Clone(columns)
+	if access != nil {
+		for code := range access.Hidden {
+			delete(visible, code)
+		}
+	}
+	return visible
+}
+
+// Get 查询记录, 关联视图中的关联记录通过 ListRelations 查询
 func (s *entityService) Get(c *gin.Context, tableCode string, id uint) (map[string]any, error) {
 
 	entityMap, err := s.entityRepository.FindOne(tableCode, id)
 	if err != nil {
 		return nil, err
 	}
-
-	// TODO 获取其他视图数据
-	// // 获取关联数据
-	// entityRelations, err2 := s.entityRepository.Find("entity_253", "*", map[string]any{
-	// 	"VECode": entityMap["VECode"],
-	// })
-	// if err2 != nil {
-	// 	s.logger.Error("EntityService-Get", "err2", err2)
-	// }
-	// entityMap["LFM1"] = entityRelations
-
-	// entityRelations2, err3 := s.entityRepository.Find("entity_233", "*", map[string]any{
-	// 	"VECode": entityMap["VECode"],
-	// })
-	// if err3 != nil {
-	// 	s.logger.Error("EntityService-Get", "err3", err3)
-	// }
-	// entityMap["LFB1"] = entityRelations2
+	access, err := s.tableFieldService.GetFieldAccess(c, tableCode)
+	if err != nil {
+		return nil, err
+	}
+	access.Hide(entityMap)
 
 	return entityMap, err
 }
 
-func (s *entityService) List(c *gin.Context, tableCode string, page, pageSize int, total *int64, where map[string]any) ([]map[string]any, error) {
+func (s *entityService) List(c *gin.Context, tableCode string, page, pageSize int, total *int64, query *model.EntityQuery) ([]map[string]any, error) {
 	if err := s.checkPermission(c, tableCode); err != nil {
 		return nil, err
 	}
-	return s.entityRepository.FindPage(tableCode, page, pageSize, total, where)
+	return s.Search(c, tableCode, page, pageSize, total, query)
+}
+
+// Search 结构化查询, 不做用户表权限检查
+// 供 OpenAPI 等已在中间件完成鉴权的调用方使用
+func (s *entityService) Search(c *gin.Context, tableCode string, page, pageSize int, total *int64, query *model.EntityQuery) ([]map[string]any, error) {
+	columns, access, err := s.visibleColumns(c, tableCode)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := s.entityRepository.CompileQuery(tableCode, query, columns, "t")
+	if err != nil {
+		return nil, err
+	}
+	entities, err := s.entityRepository.FindPage(tableCode, page, pageSize, total, compiled)
+	if err != nil {
+		return nil, err
+	}
+	access.Hide(entities...)
+	return entities, nil
+}
+
+// queryColumns 构建可查询字段白名单: 已发布的业务字段 + 系统字段
+// 草稿表 (<code>_draft) 与日志表 (<code>_log) 使用各自的系统字段
+func (s *entityService) queryColumns(tableCode string) (map[string]string, error) {
+	if logTable, ok := strings.CutSuffix(tableCode, "_log"); ok && logTable != "" {
+		return map[string]string{
+			"id":            "Number",
+			"entity_id":     "Number",
+			"operation":     "Text",
+			"source":        "Text",
+			"approval_code": "Text",
+			"changes":       "JSON",
+			"field_code":    "Text",
+			"field_name":    "Text",
+			"before_update": "Text",
+			"after_update":  "Text",
+			"reason":        "Text",
+			"update_by":     "Text",
+			"updated_at":    "DateTime",
+		}, nil
+	}
+
+	baseTable, isDraft := strings.CutSuffix(tableCode, "_draft")
+	fields, err := s.tableFieldService.Find("code,type,field_type", map[string]any{
+		"table_code": baseTable,
+		"status":     "Normal",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取表字段失败: %v", err)
+	}
+
+	columns := map[string]string{
+		"id":          "Number",
+		"created_at":  "DateTime",
+		"updated_at":  "DateTime",
+		"created_by":  "Text",
+		"updated_by":  "Text",
+		"status":      "Text",
+		"operation":   "Text",
+		"action":      "Text",
+		"send_status": "Number",
+		"version":     "Number",
+	}
+	if isDraft {
+		columns["entity_id"] = "Number"
+		columns["approval_code"] = "Text"
+		columns["draft_status"] = "Text"
+	}
+	for _, field := range fields {
+		dataType := field.Type
+		switch {
+		case field.FieldType == "manytomany":
+			dataType = repository.LinkDataType
+		case dataType == "":
+			dataType = "Text"
+		}
+		columns[field.Code] = dataType
+	}
+	return columns, nil
 }
 
 func (s *entityService) FindLogPage(c *gin.Context, tableCode string, page, pageSize int, total *int64, where map[string]any) ([]map[string]any, error) {
@@ -175,6 +374,10 @@ func (s *entityService) CreateDraft(c *gin.Context, tableCode, reason string, en
 		return err
 	}
 
+	if err := s.checkFieldAccess(c, tableCode, entityMap); err != nil {
+		return err
+	}
+
 	// 设置操作类型,供 generateAutocodes 判断
 	if _, ok := entityMap["operation"]; !ok {
 		entityMap["operation"] = "Create"
@@ -184,6 +387,15 @@ func (s *entityService) CreateDraft(c *gin.Context, tableCode, reason string, en
 	if err := s.generateAutocodes(c, tableCode, entityMap); err != nil {
 		return err
 	}
+
+	// 按匹配规则查重
+	rules, err := s.findMatchRules(tableCode)
+	if err != nil {
+		return err
+	}
+	if err := s.checkDuplicates(c, tableCode, rules, entityMap); err != nil {
+		return err
+	}
 	return s.approvalService.CreateDraftWithApproval(c, tableCode, reason, entityMap)
 }
 
@@ -191,6 +403,34 @@ func (s *entityService) UpdateDraft(c *gin.Context, tableCode, reason string, en
 	if err := s.checkPermission(c, tableCode); err != nil {
 		return err
 	}
+	// 已锁定、已作废的数据不能修改
+	if id, ok := entityMap["id"].(uint); ok {
+		if err := s.checkStatus(tableCode, "Update", []uint{id}); err != nil {
+			return err
+		}
+		if err := s.checkFieldAccess(c, tableCode, entityMap, id); err != nil {
+			return err
+		}
+	}
+	// 提交了版本号时按版本修改 (乐观锁), 记录已被其他人修改时返回 ConflictError
+	var versions map[uint]uint
+	if version, ok := uintValue(entityMap["version"]); ok {
+		id, _ := entityMap["id"].(uint)
+		versions = map[uint]uint{id: version}
+		if err := s.checkVersions(tableCode, versions, entityMap); err != nil {
+			return err
+		}
+	} else {
+		delete(entityMap, "version")
+	}
+	// 修改父节点时检查是否形成环, 并计算节点及其后代新的 level 和 path
+	var descendants []*repository.TreePath
+	if _, ok := entityMap["parent_id"]; ok && s.isTreeTable(tableCode) {
+		var err error
+		if descendants, err = planTreeMove(s.entityRepository, tableCode, entityMap); err != nil {
+			return err
+		}
+	}
 	// TODO 需要查询是否绑定了流程
 	// 1. 如果没有绑定流程，直接更新数据表
 	// 2. 如果不绑定了流程，则走审批流程
@@ -205,16 +445,14 @@ func (s *entityService) UpdateDraft(c *gin.Context, tableCode, reason string, en
 	if len(tableApprovalDefs) <= 0 {
 		// 不走审批流程，直接修改原数据
 
-		// 验证唯一索引约束 (无审批流程)
-		if err := s.validateUniqueConstraints(c, "Update", tableCode, entityMap, false); err != nil {
+		// 字段校验 (只校验提交的字段)
+		if err := validateEntity(s.tableFieldService, s.entityRepository, tableCode, entityMap, true); err != nil {
 			return err
 		}
 
-		// 计算树形字段 (如果 parent_id 存在且变更)
-		if _, ok := entityMap["parent_id"]; ok {
-			if err := s.calculateTreeFields(c, tableCode, entityMap); err != nil {
-				return err
-			}
+		// 验证唯一索引约束 (无审批流程)
+		if err := s.validateUniqueConstraints(c, "Update", tableCode, entityMap, false); err != nil {
+			return err
 		}
 
 		// 1. 获取原始数据用于比较变更
@@ -235,74 +473,37 @@ func (s *entityService) UpdateDraft(c *gin.Context, tableCode, reason string, en
 			return fmt.Errorf("获取表字段失败: %v", err)
 		}
 
-		// 3. 构建字段Code到Name的映射
-		fieldMap := make(map[string]string)
-		for _, field := range tableFields {
-			fieldMap[field.Code] = field.Name
-		}
-
-		// 4. 记录变更日志
-		exclude := []string{
-			"id",
-			"table_code",
-			"reason",
-			"updated_at",
-			"updated_by",
-			"created_at",
-			"created_by",
-			"deleted_at",
-		}
-
-		for key := range entityMap {
-			// 检查是否是排除字段
-			excluded := false
-			for _, v := range exclude {
-				if key == v {
-					excluded = true
-					break
-				}
-			}
-
-			if excluded {
-				continue
-			}
-
-			// 比较原值和新值
-			originValue := fmt.Sprintf("%v", origin[key])
-			newValue := fmt.Sprintf("%v", entityMap[key])
-
-			if originValue != newValue {
-				// 创建变更日志
-				entityLog := model.EntityLog{
-					EntityID:     id,
-					FieldCode:    key,
-					FieldName:    fieldMap[key],
-					BeforeUpdate: originValue,
-					AfterUpdate:  newValue,
-					Reason:       reason,                   // 修改原因
-					UpdateBy:     c.GetString("user_name"), // 修改人
-				}
-
-				if err := s.entityLogService.Create(c, tableCode, &entityLog); err != nil {
-					s.logger.Error("创建变更日志失败", "error", err, "field", key)
-					// 日志记录失败不应该阻断更新流程
-				}
-			}
-		}
-
-		// 5. 更新 entity
+		// 3. 更新 entity
 		// 创建一个新的map,只包含应该更新的字段
 		updateMap := make(map[string]any)
 		for key, value := range entityMap {
-			// 排除不应该更新到数据库的字段
-			if key != "table_code" && key != "reason" && key != "id" {
+			// 排除不应该更新到数据库的字段, 状态只能由生命周期操作改变
+			if key != "table_code" && key != "reason" && key != "id" && key != "version" && key != "status" {
 				updateMap[key] = value
 			}
 		}
 
 		whereMap := make(map[string]any)
 		whereMap["id"] = entityMap["id"]
-		return s.entityRepository.Update(c, tableCode, updateMap, whereMap)
+		if version, ok := versions[id]; ok {
+			whereMap["version"] = version
+		}
+		// 移动了有后代的节点时, 在同一事务中改写后代的 level 和 path
+		if len(descendants) > 0 {
+			err = s.entityRepository.MoveSubtree(c, tableCode, updateMap, whereMap, descendants)
+		} else {
+			err = s.entityRepository.Update(c, tableCode, updateMap, whereMap)
+		}
+		if err != nil {
+			return versionError(err, s.entityRepository, tableFields, tableCode, versions, entityMap)
+		}
+
+		// 4. 记录变更历史, 日志记录失败不应该阻断更新流程
+		event := entityEvent{Operation: operation, Reason: reason}
+		if err := writeEntityLog(c, s.entityLogService, tableCode, tableFields, event, id, origin, updateMap); err != nil {
+			s.logger.Error("创建变更日志失败", "error", err, "id", id)
+		}
+		return nil
 	}
 
 	// 验证唯一索引约束 (有审批流程)
@@ -316,7 +517,8 @@ func (s *entityService) UpdateDraft(c *gin.Context, tableCode, reason string, en
 	// return nil
 }
 
-func (s *entityService) BatchUpdate(c *gin.Context, tableCode, reason string, ids []uint, entityMap map[string]any) error {
+// BatchUpdate 批量修改, versions 为 id 对应的版本号, 提交了版本号的记录按版本修改 (乐观锁)
+func (s *entityService) BatchUpdate(c *gin.Context, tableCode, reason string, ids []uint, versions map[uint]uint, entityMap map[string]any) error {
 	if err := s.checkPermission(c, tableCode); err != nil {
 		return err
 	}
@@ -326,20 +528,29 @@ func (s *entityService) BatchUpdate(c *gin.Context, tableCode, reason string, id
 		return fmt.Errorf("operation is required")
 	}
 
-	// 检查是否包含已删除的数据
-	if operation == "BatchFreeze" || operation == "BatchUnfreeze" {
-		where := map[string]any{
-			"id in": ids,
-		}
-		// 只查询 status 字段
-		entities, err := s.entityRepository.Find(tableCode, "status", where)
-		if err != nil {
-			return fmt.Errorf("查询数据状态失败: %v", err)
-		}
-		for _, ent := range entities {
-			if status, ok := ent["status"].(string); ok && status == "Deleted" {
-				return fmt.Errorf("包含已删除的数据，无法进行操作")
-			}
+	// 字段校验 (只校验提交的字段)
+	if err := validateEntity(s.tableFieldService, s.entityRepository, tableCode, entityMap, true); err != nil {
+		return err
+	}
+	if err := s.checkFieldAccess(c, tableCode, entityMap, ids...); err != nil {
+		return err
+	}
+
+	// 记录已被其他人修改时返回 ConflictError
+	if err := s.checkVersions(tableCode, versions, entityMap); err != nil {
+		return err
+	}
+
+	// 检查状态转换: 如已锁定、已作废的数据不能修改, 只有已冻结的数据可以解冻
+	if err := s.checkStatus(tableCode, operation, ids); err != nil {
+		return err
+	}
+
+	// 删除前检查引用, 被 restrict 关系字段引用的数据不能删除
+	deleting := isDeleteOperation(operation)
+	if deleting {
+		if err := s.approvalService.CheckDeleteReferences(c, tableCode, ids); err != nil {
+			return err
 		}
 	}
 
@@ -352,6 +563,14 @@ func (s *entityService) BatchUpdate(c *gin.Context, tableCode, reason string, id
 
 	// 如果没有审批流程,直接更新主表数据
 	if len(tableApprovalDefs) == 0 {
+		// 删除时保留删除前的数据, 用于处理引用
+		var deleted []map[string]any
+		if deleting {
+			if deleted, err = s.entityRepository.Find(tableCode, "*", map[string]any{"id in": ids}); err != nil {
+				return err
+			}
+		}
+
 		// 调用 GetOperationInfo 获取操作信息
 		operationInfo := make(map[string]string)
 		if err := s.approvalService.GetOperationInfo(operation, &operationInfo); err != nil {
@@ -361,7 +580,15 @@ func (s *entityService) BatchUpdate(c *gin.Context, tableCode, reason string, id
 		// 设置必要字段 - 使用小写下划线,直接更新数据库
 		entityMap["action"] = operationInfo["action"]
 		entityMap["status"] = operationInfo["status"]
+		if isUpdateOperation(operation) {
+			// 修改不改变状态, 状态只能由生命周期操作改变
+			delete(entityMap, "status")
+		}
 		entityMap["updated_by"] = c.GetString("user_name")
+		// 删除为软删除, 记录删除人和原因, 可从回收站恢复
+		if deleting {
+			markDeleted(entityMap, c.GetString("user_name"), reason)
+		}
 
 		// 获取表字段定义用于变更日志
 		fieldWhere := map[string]any{}
@@ -370,514 +597,180 @@ func (s *entityService) BatchUpdate(c *gin.Context, tableCode, reason string, id
 		if err != nil {
 			s.logger.Error("获取表字段失败", "error", err)
 		}
-
-		// 构建字段Code到Name的映射
-		fieldMap := make(map[string]string)
+		// 公式字段由 recomputeByIds 计算, 忽略提交的值
 		for _, field := range tableFields {
-			fieldMap[field.Code] = field.Name
-		}
-		// 添加系统字段映射
-		fieldMap["status"] = "状态"
-		fieldMap["action"] = "操作"
-		fieldMap["operation"] = "操作类型"
-
-		// 为每个ID记录变更日志
-		for _, id := range ids {
-			// 获取原始数据
-			origin, err := s.entityRepository.FindOne(tableCode, id)
-			if err != nil {
-				s.logger.Error("获取原始数据失败", "error", err, "id", id)
-				continue
+			if isFormulaField(field) {
+				delete(entityMap, field.Code)
 			}
+		}
 
-			// 记录变更日志
-			exclude := []string{
-				"id",
-				"table_code",
-				"reason",
-				"updated_at",
-				"updated_by",
-				"created_at",
-				"created_by",
-				"deleted_at",
+		// 修改前的数据, 用于记录变更历史
+		origins := deleted
+		if !deleting {
+			if origins, err = s.entityRepository.Find(tableCode, "*", map[string]any{"id in": ids}); err != nil {
+				return err
 			}
+		}
 
-			for key := range entityMap {
-				// 检查是否是排除字段
-				excluded := false
-				for _, v := range exclude {
-					if key == v {
-						excluded = true
-						break
-					}
-				}
-
-				if excluded {
-					continue
-				}
-
-				// 比较原值和新值
-				originValue := fmt.Sprintf("%v", origin[key])
-				newValue := fmt.Sprintf("%v", entityMap[key])
-
-				if originValue != newValue {
-					// 创建变更日志
-					entityLog := model.EntityLog{
-						EntityID:     id,
-						FieldCode:    key,
-						FieldName:    fieldMap[key],
-						BeforeUpdate: originValue,
-						AfterUpdate:  newValue,
-						Reason:       reason,
-						UpdateBy:     c.GetString("user_name"),
-					}
+		// 直接更新主表
+		if err := s.entityRepository.BatchUpdate(c, tableCode, ids, versions, entityMap); err != nil {
+			return versionError(err, s.entityRepository, tableFields, tableCode, versions, entityMap)
+		}
 
-					if err := s.entityLogService.Create(c, tableCode, &entityLog); err != nil {
-						s.logger.Error("创建变更日志失败", "error", err, "field", key, "id", id)
-						// 日志记录失败不应该阻断更新流程
-					}
-				}
+		// 记录变更历史, 删除时记录删除前的全部字段; 日志记录失败不应该阻断更新流程
+		event := entityEvent{Operation: operation, Reason: reason}
+		for _, origin := range origins {
+			id, err := importRowID(origin)
+			if err != nil {
+				continue
+			}
+			after := entityMap
+			if deleting {
+				after = nil
+			}
+			if err := writeEntityLog(c, s.entityLogService, tableCode, tableFields, event, id, origin, after); err != nil {
+				s.logger.Error("创建变更日志失败", "error", err, "id", id)
 			}
 		}
 
-		// 直接更新主表
-		return s.entityRepository.BatchUpdate(c, tableCode, ids, entityMap)
+		// 修改的字段被公式引用时, 逐条重算公式字段
+		if err := s.recomputeByIds(c, tableCode, ids, tableFields, entityMap); err != nil {
+			return err
+		}
+		if deleting {
+			return s.approvalService.ApplyDeleteReferences(c, tableCode, reason, deleted)
+		}
+		return nil
 	}
 
 	// 有审批流程,走审批流程
 	return s.approvalService.UpdateByIdsWithApproval(c, tableCode, reason, ids, entityMap)
 }
 
-func (s *entityService) Delete(c *gin.Context, tableCode, reason string, id uint) error {
-	if err := s.checkPermission(c, tableCode); err != nil {
-		return err
+// checkVersions 比较提交的版本号与数据表当前版本, 有记录已被其他人修改时返回 ConflictError
+func (s *entityService) checkVersions(tableCode string, versions map[uint]uint, changes map[string]any) error {
+	if len(versions) == 0 {
+		return nil
+	}
+	tableFields, err := s.tableFieldService.Find("", map[string]any{"table_code": tableCode})
+	if err != nil {
+		return fmt.Errorf("获取表字段失败: %v", err)
 	}
-	return s.entityRepository.Delete(c, tableCode, id)
+	return checkVersions(s.entityRepository, tableFields, tableCode, versions, changes)
 }
 
-func (s *entityService) BatchDelete(c *gin.Context, tableCode, reason string, ids []uint) error {
-	if err := s.checkPermission(c, tableCode); err != nil {
-		return err
-	}
-	return s.entityRepository.BatchDelete(c, tableCode, ids)
+func (s *entityService) Delete(c *gin.Context, tableCode, reason string, id uint) error {
+	return s.BatchDelete(c, tableCode, reason, []uint{id})
 }
 
-func (s *entityService) Import(c *gin.Context, tableCode, reason, operation string, r io.Reader) error {
+func (s *entityService) BatchDelete(c *gin.Context, tableCode, reason string, ids []uint) error {
 	if err := s.checkPermission(c, tableCode); err != nil {
 		return err
 	}
-	// 检查是否有审批流程定义
-	tableApprovalDefs, err := s.tableApprovalDefinitionRepository.List(tableCode, operation)
-	if err != nil {
-		s.logger.Error("查询审批流程定义失败", "err", err)
+	if err := s.checkStatus(tableCode, "BatchDelete", ids); err != nil {
 		return err
 	}
-
-	// 如果没有审批流程,直接导入到主表
-	if len(tableApprovalDefs) == 0 {
-		return s.importDirect(c, tableCode, reason, operation, r)
-	}
-
-	// 有审批流程,走审批流程
-	return s.approvalService.ImportWithApproval(c, tableCode, reason, operation, r)
-}
-
-// importDirect 直接导入数据到主表(无审批流程)
-func (s *entityService) importDirect(c *gin.Context, tableCode, reason, operation string, r io.Reader) error {
-	// 获取操作信息
-	operationInfo := make(map[string]string)
-	if err := s.approvalService.GetOperationInfo(operation, &operationInfo); err != nil {
+	if err := s.approvalService.CheckDeleteReferences(c, tableCode, ids); err != nil {
 		return err
 	}
-
-	// Fetch field definitions for type mapping
-	fieldWhere := map[string]any{"table_code": tableCode}
-	tFields, err := s.tableFieldService.Find("code,field_type,type", fieldWhere)
+	deleted, err := s.entityRepository.Find(tableCode, "*", map[string]any{"id in": ids})
 	if err != nil {
-		s.logger.Error("importDirect: fetch fields failed", "err", err)
 		return err
 	}
-	fieldTypeMap := make(map[string]struct {
-		uiType string
-		dbType string
-	})
-
-	for _, f := range tFields {
-		fieldTypeMap[f.Code] = struct{ uiType, dbType string }{
-			uiType: f.FieldType,
-			dbType: f.Type,
-		}
-	}
-
-	// 打开 Excel 文件
-	xlsx, err := excelize.OpenReader(r)
-	if err != nil {
+	if err := s.entityRepository.BatchDelete(c, tableCode, ids, reason); err != nil {
 		return err
 	}
 
-	rows := xlsx.GetRows("Sheet1")
-	var fields []string
-
-	// 遍历所有行
-	for irow, row := range rows {
-		if irow == 0 {
-			// 第一行是字段名
-			fields = append(fields, row...)
-		} else {
-			// 构建实体数据
-			entityMap := make(map[string]any)
-			for index, cell := range row {
-				if index >= len(fields) {
-					break
-				}
-				fieldCode := fields[index]
-
-				// 处理 ID 字段
-				if fieldCode == "ID" || fieldCode == "id" {
-					if num, err := strconv.Atoi(cell); err == nil {
-						entityMap[fieldCode] = num
-						continue
-					}
-				}
-
-				// Handle empty strings based on field type
-				if cell == "" {
-					if types, ok := fieldTypeMap[fieldCode]; ok {
-						uiType := strings.ToLower(types.uiType)
-						dbType := strings.ToLower(types.dbType)
-
-						isDateOrNum := false
-						if strings.Contains(uiType, "date") ||
-							strings.Contains(uiType, "time") ||
-							strings.Contains(uiType, "int") ||
-							strings.Contains(uiType, "number") ||
-							strings.Contains(uiType, "decimal") ||
-							strings.Contains(uiType, "float") ||
-							strings.Contains(uiType, "double") {
-							isDateOrNum = true
-						}
-
-						if !isDateOrNum {
-							if strings.Contains(dbType, "date") ||
-								strings.Contains(dbType, "time") ||
-								strings.Contains(dbType, "int") ||
-								strings.Contains(dbType, "decimal") ||
-								strings.Contains(dbType, "float") ||
-								strings.Contains(dbType, "double") {
-								isDateOrNum = true
-							}
-						}
-
-						if isDateOrNum {
-							entityMap[fieldCode] = nil
-							continue
-						}
-					}
-				}
-
-				entityMap[fieldCode] = cell
-			}
-
-			// 根据操作类型选择创建或更新
-			if operation == "BatchCreate" {
-				// 新增操作 - 使用snake_case以匹配数据库列名
-				gid := s.globalIdService.GetNewID("entity")
-				entityMap["id"] = gid
-				entityMap["operation"] = operation
-				entityMap["action"] = operationInfo["action"]
-				entityMap["status"] = operationInfo["status"]
-				// 生成 entity global id
-				entityMap["entity_id"] = gid
-				entityMap["created_by"] = c.GetString("user_name") // Assuming user_name is the creator
-				entityMap["send_status"] = 0
-				entityMap["updated_by"] = c.GetString("user_name")
-
-				// 生成自动编码
-				if err := s.generateAutocodes(c, tableCode, entityMap); err != nil {
-					return err
-				}
-
-				if err := s.entityRepository.Create(c, tableCode, entityMap); err != nil {
-					return err
-				}
-			} else if operation == "BatchUpdate" {
-				// 更新操作 - 使用snake_case以匹配数据库列名
-				if entityMap["id"] == nil {
-					return fmt.Errorf("BatchUpdate requires id field")
-				}
-
-				// 获取ID
-				var id uint
-				switch v := entityMap["id"].(type) {
-				case string:
-					// 从Excel读取的可能是字符串,需要转换
-					idInt, err := strconv.ParseUint(v, 10, 64)
-					if err != nil {
-						return fmt.Errorf("invalid id value: %s", v)
-					}
-					id = uint(idInt)
-				case int:
-					id = uint(v)
-				case uint:
-					id = v
-				case float64:
-					// Excel数字可能被解析为float64
-					id = uint(v)
-				default:
-					return fmt.Errorf("invalid id type: %T", v)
-				}
-
-				// 1. 获取原始数据用于比较变更
-				origin, err := s.entityRepository.FindOne(tableCode, id)
-				if err != nil {
-					s.logger.Error("获取原始数据失败", "error", err, "id", id)
-					return err
-				}
-
-				// 2. 获取表字段定义
-				fieldWhere := map[string]any{}
-				fieldWhere["table_code"] = tableCode
-				tableFields, err := s.tableFieldService.Find("", fieldWhere)
-				if err != nil {
-					s.logger.Error("获取表字段失败", "error", err)
-					return fmt.Errorf("获取表字段失败: %v", err)
-				}
-
-				// 3. 构建字段Code到Name的映射
-				fieldMap := make(map[string]string)
-				for _, field := range tableFields {
-					fieldMap[field.Code] = field.Name
-				}
-
-				// 4. 记录变更日志
-				exclude := []string{
-					"id",
-					"table_code",
-					"reason",
-					"updated_at",
-					"updated_by",
-					"created_at",
-					"created_by",
-					"deleted_at",
-					"action",
-				}
-
-				for key := range entityMap {
-					// 检查是否是排除字段
-					excluded := false
-					for _, v := range exclude {
-						if key == v {
-							excluded = true
-							break
-						}
-					}
-
-					if excluded {
-						continue
-					}
-
-					// 比较原值和新值
-					originValue := fmt.Sprintf("%v", origin[key])
-					newValue := fmt.Sprintf("%v", entityMap[key])
-
-					if originValue != newValue {
-						// 创建变更日志
-						entityLog := model.EntityLog{
-							EntityID:     id,
-							FieldCode:    key,
-							FieldName:    fieldMap[key],
-							BeforeUpdate: originValue,
-							AfterUpdate:  newValue,
-							Reason:       reason,                   // 修改原因
-							UpdateBy:     c.GetString("user_name"), // 修改人
-						}
-
-						if err := s.entityLogService.Create(c, tableCode, &entityLog); err != nil {
-							s.logger.Error("创建变更日志失败", "error", err, "field", key)
-							// 日志记录失败不应该阻断更新流程
-						}
-					}
-				}
-
-				// 5. 设置必要字段
-				entityMap["action"] = operationInfo["action"]
-				entityMap["status"] = operationInfo["status"]
-				entityMap["updated_by"] = c.GetString("user_name")
-
-				// 6. 构建 where 条件
-				where := map[string]any{
-					"id": id,
-				}
-
-				// 7. 删除 id 字段,避免更新 id
-				delete(entityMap, "id")
-
-				// 8. 更新数据
-				if err := s.entityRepository.Update(c, tableCode, entityMap, where); err != nil {
-					return err
-				}
-			}
-		}
-	}
-
-	return nil
-}
-
-func (s *entityService) Export(c *gin.Context, tableCode, filter string, where map[string]any) (string, error) {
-	// get table fields
-	stfWhere := make(map[string]any)
-	stfWhere["status"] = "Normal"
-	stfWhere["table_code"] = tableCode
-	tableFields, err := s.tableFieldService.Find("*", stfWhere)
-	if err != nil {
-		s.logger.Error("tableFieldService.Find", "err", err)
-	}
-
-	sel := "*"
-	// Get all data for export
-	entities, _ := s.entityRepository.Find(tableCode, sel, where)
-
-	// create xlsx file
-	file := xlsx.NewFile()
-	sheet, err := file.AddSheet("Sheet1")
+	// 记录变更历史: 删除前的全部字段
+	tableFields, err := s.tableFieldService.Find("", map[string]any{"table_code": tableCode})
 	if err != nil {
-		return "", err
+		s.logger.Error("获取表字段失败", "error", err)
 	}
-	row := sheet.AddRow()
-
-	var cell *xlsx.Cell
-	cell = row.AddCell()
-	// Style of Cell 00D5DAE9
-	fill := xlsx.NewFill("solid", "00D0D0D0", "00000000")
-	border := *xlsx.NewBorder("thin", "thin", "thin", "thin")
-	border.LeftColor = "00000000"
-	border.RightColor = "00000000"
-	border.TopColor = "00000000"
-	border.BottomColor = "00000000"
-	// Style of Table Header
-	styleHeader := xlsx.NewStyle()
-	styleHeader.Fill = *fill
-	styleHeader.Border = border
-	// Style of Table body
-	styleBody := xlsx.NewStyle()
-	styleBody.Border = border
-	// Set cell for id
-	cell.SetStyle(styleHeader)
-	cell.Value = "id"
-	for _, field := range tableFields {
-		cell = row.AddCell()
-		cell.SetStyle(styleHeader)
-		cell.Value = field.Code
-	}
-
-	for _, v := range entities {
-		var values []string
-		values = append(values, strconv.FormatUint(v["id"].(uint64), 10))
-		for _, field := range tableFields {
-			if v[field.Code] != nil {
-				// 根据实际类型转换为字符串
-				switch val := v[field.Code].(type) {
-				case string:
-					values = append(values, val)
-				case int, int8, int16, int32, int64:
-					values = append(values, fmt.Sprintf("%d", val))
-				case uint, uint8, uint16, uint32, uint64:
-					values = append(values, fmt.Sprintf("%d", val))
-				case float32, float64:
-					values = append(values, fmt.Sprintf("%v", val))
-				case bool:
-					values = append(values, fmt.Sprintf("%t", val))
-				default:
-					values = append(values, fmt.Sprintf("%v", val))
-				}
-			} else {
-				values = append(values, "")
-			}
+	event := entityEvent{Operation: "Delete", Reason: reason}
+	for _, origin := range deleted {
+		id, err := importRowID(origin)
+		if err != nil {
+			continue
 		}
-
-		row = sheet.AddRow()
-		for _, value := range values {
-			cell = row.AddCell()
-			cell.SetStyle(styleBody)
-			cell.Value = value
+		if err := writeEntityLog(c, s.entityLogService, tableCode, tableFields, event, id, origin, nil); err != nil {
+			s.logger.Error("创建变更日志失败", "error", err, "id", id)
 		}
 	}
-	row = sheet.AddRow()
-	cell = row.AddCell()
-	cell.SetStyle(styleBody)
-	cell.Value = "Export " + strconv.Itoa(len(entities)) + " pieces of data."
-
-	time := strconv.Itoa(int(time.Now().Unix()))
-	filename := tableCode + "-" + time + ".xlsx"
+	return s.approvalService.ApplyDeleteReferences(c, tableCode, reason, deleted)
+}
 
-	fullPath := s.conf.GetString("app.runtime-root-path") + s.conf.GetString("app.export-save-path") + filename
-	err = file.Save(fullPath)
-	if err != nil {
-		return "", err
+// WhereUsed 查询引用指定记录的数据, 按引用表和关系字段分组
+func (s *entityService) WhereUsed(c *gin.Context, tableCode string, id uint) ([]*EntityReference, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
 	}
-
-	return filename, nil
+	if _, err := s.entityRepository.FindOne(tableCode, id); err != nil {
+		return nil, fmt.Errorf("记录 %d 不存在", id)
+	}
+	return s.approvalService.FindReferences(c, tableCode, []uint{id})
 }
 
-func (s *entityService) Template(c *gin.Context, tableCode, operation string) (string, error) {
+// Template 生成导入模板: 只有表头 (JSON 为一条字段值为 null 的示例记录), 修改模板带 id 列
+// 字段按字段组排列, 有字段组时 Excel 模板第一行为字段组, 第二行为表头; 当前用户不能修改的字段不在模板中
+func (s *entityService) Template(c *gin.Context, tableCode, operation string, file FileOptions) (string, error) {
 	// get table fields
-	stfWhere := make(map[string]any)
-	stfWhere["status"] = "Normal"
-	stfWhere["table_code"] = tableCode
-	tableFields, err := s.tableFieldService.Find("*", stfWhere)
+	tableFields, err := s.tableFieldService.GetTableFields(c, tableCode)
 	if err != nil {
-		s.logger.Error("tableFieldService.Find", "err", err)
+		s.logger.Error("tableFieldService.GetTableFields", "err", err)
 	}
 
-	// create xlsx file
-	file := xlsx.NewFile()
-	sheet, err := file.AddSheet("Sheet1")
-	if err != nil {
-		return "", err
-	}
-	row := sheet.AddRow()
-
-	var cell *xlsx.Cell
-	// Style of Cell 00D5DAE9
-	fill := xlsx.NewFill("solid", "00D0D0D0", "00000000")
-	border := *xlsx.NewBorder("thin", "thin", "thin", "thin")
-	border.LeftColor = "00000000"
-	border.RightColor = "00000000"
-	border.TopColor = "00000000"
-	border.BottomColor = "00000000"
-	// Style of Table Header
-	styleHeader := xlsx.NewStyle()
-	styleHeader.Fill = *fill
-	styleHeader.Border = border
-	// Style of Table body
-	styleBody := xlsx.NewStyle()
-	styleBody.Border = border
+	header := make([]string, 0, len(tableFields)+1)
+	bands := make([]string, 0, len(tableFields)+1)
+	grouped := false
 	if operation == "BatchUpdate" {
 		// Set cell for id
-		cell = row.AddCell()
-		cell.SetStyle(styleHeader)
-		cell.Value = "id"
+		header = append(header, "id")
+		bands = append(bands, "")
 	}
 	for _, field := range tableFields {
-		cell = row.AddCell()
-		cell.SetStyle(styleHeader)
-		cell.Value = field.Code
+		// 公式字段由系统计算, 导入时不需要填写
+		if field.IsSystem || field.Readonly || field.FieldType == formulaFieldType {
+			continue
+		}
+		header = append(header, field.Code)
+		if field.Group != nil {
+			bands = append(bands, field.Group.Name)
+			grouped = true
+		} else {
+			bands = append(bands, "")
+		}
 	}
-	row = sheet.AddRow()
-	cell = row.AddCell()
-	cell.SetStyle(styleBody)
 
 	time := strconv.Itoa(int(time.Now().Unix()))
-	filename := tableCode + "-create-template-" + time + ".xlsx"
-	if operation == "BatchUpdate" {
-		filename = tableCode + "-update-template-" + time + ".xlsx"
+	filename := tableCode + "-create-template-" + time + "." + file.Ext()
+	switch operation {
+	case "BatchUpdate":
+		filename = tableCode + "-update-template-" + time + "." + file.Ext()
+	case model.ImportOperationUpsert:
+		// Upsert 按唯一索引匹配, 模板与新增一致, 不含 id
+		filename = tableCode + "-upsert-template-" + time + "." + file.Ext()
 	}
 
 	fullPath := s.conf.GetString("app.runtime-root-path") + s.conf.GetString("app.export-save-path") + filename
-	err = file.Save(fullPath)
+	var w recordWriter
+	if grouped && (file.Format == FileFormatXLSX || file.Format == "") {
+		w, err = newXLSXBandWriter(fullPath, bands, header)
+	} else {
+		w, err = newRecordWriter(fullPath, file, header)
+	}
 	if err != nil {
 		return "", err
 	}
+	// JSON 格式没有表头行, 写入一条空记录说明字段; 空记录导入时会被忽略
+	if file.Format == FileFormatJSON || file.Format == FileFormatNDJSON {
+		if err := w.Write(make([]any, len(header))); err != nil {
+			w.Close()
+			return "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
 
 	return filename, nil
 }
@@ -885,8 +778,7 @@ func (s *entityService) Template(c *gin.Context, tableCode, operation string) (s
 // calculateTreeFields 计算树形结构的 level 和 path
 func (s *entityService) calculateTreeFields(c *gin.Context, tableCode string, entityMap map[string]any) error {
 	// 1. 检查表是否为树形结构
-	tables, err := s.tableRepository.Find("", map[string]any{"code": tableCode})
-	if err != nil || len(tables) == 0 || tables[0].DisplayMode != "Tree" {
+	if !s.isTreeTable(tableCode) {
 		return nil
 	}
 
@@ -1009,44 +901,79 @@ func (s *entityService) Create(c *gin.Context, tableCode string, entity any) err
 	}
 	// 如果传入的是 map,需要设置必要的字段
 	if entityMap, ok := entity.(map[string]any); ok {
-		// 设置操作类型
-		operation := "Create"
-
-		// 调用 GetOperationInfo 获取操作信息
-		operationInfo := make(map[string]string)
-		if err := s.approvalService.GetOperationInfo(operation, &operationInfo); err != nil {
+		if err := s.prepareCreate(c, tableCode, entityMap, false); err != nil {
 			return err
 		}
+	}
 
-		// 生成全局唯一 ID
-		gid := s.globalIdService.GetNewID("entity")
-
-		// 设置必要字段 - 使用snake_case以匹配数据库列名
-		entityMap["id"] = gid
-		entityMap["operation"] = operation
-		entityMap["action"] = operationInfo["action"]
-		entityMap["status"] = operationInfo["status"]
-		entityMap["send_status"] = 0
-		entityMap["created_by"] = c.GetString("user_name")
-		entityMap["updated_by"] = c.GetString("user_name")
+	if err := s.entityRepository.Create(c, tableCode, entity); err != nil {
+		return err
+	}
 
-		// 计算树形字段
-		if err := s.calculateTreeFields(c, tableCode, entityMap); err != nil {
-			return err
+	// 记录变更历史: 新增的全部字段
+	if entityMap, ok := entity.(map[string]any); ok {
+		tableFields, err := s.tableFieldService.Find("", map[string]any{"table_code": tableCode})
+		if err != nil {
+			s.logger.Error("获取表字段失败", "error", err)
 		}
-
-		// 生成自动编码字段
-		if err := s.generateAutocodes(c, tableCode, entityMap); err != nil {
-			return err
+		id, _ := uintValue(entityMap["id"])
+		if err := writeEntityLog(c, s.entityLogService, tableCode, tableFields, entityEvent{Operation: "Create"}, id, nil, entityMap); err != nil {
+			s.logger.Error("创建变更日志失败", "error", err, "id", id)
 		}
+	}
+	return nil
+}
 
-		// 验证唯一索引约束 (无审批流程)
-		if err := s.validateUniqueConstraints(c, "Create", tableCode, entityMap, false); err != nil {
-			return err
-		}
+// prepareCreate 新增前设置系统字段 (id、操作、状态、创建人), 计算树形字段、生成自动编码并校验
+// withApproval 为 true 时按提交审批校验唯一索引
+func (s *entityService) prepareCreate(c *gin.Context, tableCode string, entityMap map[string]any, withApproval bool) error {
+	// 设置操作类型
+	operation := "Create"
+
+	// 调用 GetOperationInfo 获取操作信息
+	operationInfo := make(map[string]string)
+	if err := s.approvalService.GetOperationInfo(operation, &operationInfo); err != nil {
+		return err
 	}
 
-	return s.entityRepository.Create(c, tableCode, entity)
+	// 生成全局唯一 ID
+	gid := s.globalIdService.GetNewID("entity")
+
+	// 设置必要字段 - 使用snake_case以匹配数据库列名
+	entityMap["id"] = gid
+	entityMap["operation"] = operation
+	entityMap["action"] = operationInfo["action"]
+	entityMap["status"] = operationInfo["status"]
+	entityMap["send_status"] = 0
+	entityMap["created_by"] = c.GetString("user_name")
+	entityMap["updated_by"] = c.GetString("user_name")
+
+	// 计算树形字段
+	if err := s.calculateTreeFields(c, tableCode, entityMap); err != nil {
+		return err
+	}
+
+	// 生成自动编码字段
+	if err := s.generateAutocodes(c, tableCode, entityMap); err != nil {
+		return err
+	}
+
+	// 字段校验
+	if err := validateEntity(s.tableFieldService, s.entityRepository, tableCode, entityMap, false); err != nil {
+		return err
+	}
+
+	// 验证唯一索引约束
+	if err := s.validateUniqueConstraints(c, "Create", tableCode, entityMap, withApproval); err != nil {
+		return err
+	}
+
+	// 按匹配规则查重
+	rules, err := s.findMatchRules(tableCode)
+	if err != nil {
+		return err
+	}
+	return s.checkDuplicates(c, tableCode, rules, entityMap)
 }
 
 func (s *entityService) Update(c *gin.Context, tableCode string, entity any, where map[string]any) error {
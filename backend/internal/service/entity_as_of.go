@@ -0,0 +1,604 @@
+package service
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// asOfMaxRows 历史数据列表最多读取的当前记录数, 超过时需要增加过滤条件
+const asOfMaxRows = 10000
+
+// RollbackTarget 回滚目标: 指定时间点, 或指定变更记录之后的版本 (LogID 优先)
+type RollbackTarget struct {
+	AsOf  time.Time
+	LogID uint
+}
+
+func (t RollbackTarget) String() string {
+	if t.LogID > 0 {
+		return fmt.Sprintf("变更记录 %d", t.LogID)
+	}
+	return t.AsOf.Format(fileDateTimeLayout)
+}
+
+// GetAsOf 读取记录在指定时间点的数据, 由当前数据逆序撤销之后的变更事件得到
+func (s *entityService) GetAsOf(c *gin.Context, tableCode string, id uint, asOf time.Time) (map[string]any, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
+	}
+	definitions, err := s.asOfFields(tableCode)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.entityRepository.FindWithDeleted(tableCode, "*", map[string]any{"id": id})
+	if err != nil {
+		return nil, err
+	}
+	var row map[string]any
+	if len(rows) > 0 {
+		row = rows[0]
+	}
+	logs, err := s.entityLogsAfter(tableCode, map[string]any{"entity_id": id, "updated_at >": asOf})
+	if err != nil {
+		return nil, err
+	}
+
+	entity, ok := entityAsOf(definitions, row, logs[id], asOf)
+	if !ok {
+		return nil, fmt.Errorf("%w: 记录 %d 在 %s 时不存在", gorm.ErrRecordNotFound, id, asOf.Format(fileDateTimeLayout))
+	}
+	entity["id"] = id
+	access, err := s.tableFieldService.GetFieldAccess(c, tableCode)
+	if err != nil {
+		return nil, err
+	}
+	access.Hide(entity)
+	return entity, nil
+}
+
+// ListAsOf 按指定时间点的数据查询列表
+// 当前符合条件且之后没有变更的记录直接返回; 之后有变更的记录 (含已删除的) 先还原, 再在内存中按条件过滤、排序和分页
+func (s *entityService) ListAsOf(c *gin.Context, tableCode string, page, pageSize int, total *int64, query *model.EntityQuery, asOf time.Time) ([]map[string]any, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
+	}
+	if query == nil {
+		query = &model.EntityQuery{}
+	}
+	columns, access, err := s.visibleColumns(c, tableCode)
+	if err != nil {
+		return nil, err
+	}
+	// 校验返回字段, 读取时返回全部字段以便在内存中过滤和排序
+	if _, err := s.entityRepository.CompileQuery(tableCode, query, columns, "t"); err != nil {
+		return nil, err
+	}
+	compiled, err := s.entityRepository.CompileQuery(tableCode, &model.EntityQuery{Filter: query.Filter, Sort: query.Sort}, columns, "t")
+	if err != nil {
+		return nil, err
+	}
+	definitions, err := s.asOfFields(tableCode)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := s.entityLogsAfter(tableCode, map[string]any{"updated_at >": asOf})
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.entityRepository.FindChunk(tableCode, compiled, nil, asOfMaxRows+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(current) > asOfMaxRows {
+		return nil, fmt.Errorf("%w: 符合条件的记录超过 %d 条, 请增加过滤条件", model.ErrInvalidQuery, asOfMaxRows)
+	}
+	var entities []map[string]any
+	for _, row := range current {
+		id, _ := uintValue(row["id"])
+		if _, changed := logs[id]; changed || createdAfter(row, asOf) {
+			continue
+		}
+		delete(row, "version")
+		entities = append(entities, row)
+	}
+
+	if len(logs) > 0 {
+		ids := slices.Sorted(maps.Keys(logs))
+		rows, err := s.entityRepository.FindWithDeleted(tableCode, "*", map[string]any{"id in": ids})
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[uint]map[string]any, len(rows))
+		for _, row := range rows {
+			if id, ok := uintValue(row["id"]); ok {
+				byID[id] = row
+			}
+		}
+		for _, id := range ids {
+			entity, ok := entityAsOf(definitions, byID[id], logs[id], asOf)
+			if !ok {
+				continue
+			}
+			entity["id"] = id
+			matched, err := matchAsOfFilter(query.Filter, columns, definitions, entity)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				entities = append(entities, entity)
+			}
+		}
+	}
+
+	sortAsOfRows(entities, compiled.Sort, columns, definitions)
+	*total = int64(len(entities))
+	start := min((page-1)*pageSize, len(entities))
+	end := min(start+pageSize, len(entities))
+	entities = entities[start:end]
+
+	if len(query.Fields) > 0 {
+		for i, entity := range entities {
+			projected := map[string]any{"id": entity["id"]}
+			for _, field := range query.Fields {
+				projected[field] = entity[field]
+			}
+			entities[i] = projected
+		}
+	}
+	access.Hide(entities...)
+	return entities, nil
+}
+
+// Rollback 将记录恢复到指定时间点或指定变更记录之后的版本
+// 回滚作为一次普通修改提交: 表配置了修改审批流程时生成草稿提交审批, 否则直接修改; 公式字段和状态不回滚
+func (s *entityService) Rollback(c *gin.Context, tableCode, reason string, id uint, target RollbackTarget) error {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return err
+	}
+	if target.LogID == 0 && target.AsOf.IsZero() {
+		return fmt.Errorf("请指定回滚的时间点或变更记录")
+	}
+	current, err := s.entityRepository.FindOne(tableCode, id)
+	if err != nil {
+		return err
+	}
+	if !isEmptyValue(current["deleted_at"]) {
+		return fmt.Errorf("记录 %d 已删除, 不能回滚", id)
+	}
+	definitions, err := s.asOfFields(tableCode)
+	if err != nil {
+		return err
+	}
+
+	where := map[string]any{"entity_id": id}
+	if target.LogID > 0 {
+		where["id >"] = target.LogID
+	} else {
+		where["updated_at >"] = target.AsOf
+	}
+	logs, err := s.entityLogsAfter(tableCode, where)
+	if err != nil {
+		return err
+	}
+	previous, ok := entityAsOf(definitions, current, logs[id], target.AsOf)
+	if !ok {
+		return fmt.Errorf("记录 %d 在%s时不存在, 不能回滚", id, target)
+	}
+
+	entityMap := make(map[string]any)
+	for _, field := range definitions {
+		if isFormulaField(field) {
+			continue
+		}
+		if historyEqual(historyValue(field, current[field.Code]), historyValue(field, previous[field.Code])) {
+			continue
+		}
+		value := previous[field.Code]
+		if codes, ok := value.([]string); ok {
+			data, _ := json.Marshal(codes)
+			value = string(data)
+		}
+		entityMap[field.Code] = value
+	}
+	if len(entityMap) == 0 {
+		return fmt.Errorf("记录 %d 与%s时的数据一致, 无需回滚", id, target)
+	}
+
+	if reason == "" {
+		reason = "回滚到" + target.String()
+	}
+	entityMap["id"] = id
+	entityMap["table_code"] = tableCode
+	if version, ok := uintValue(current["version"]); ok {
+		entityMap["version"] = version
+	}
+	return s.UpdateDraft(c, tableCode, reason, entityMap)
+}
+
+// asOfFields 已发布的表字段, 按字段编码索引
+func (s *entityService) asOfFields(tableCode string) (map[string]*model.TableField, error) {
+	fields, err := s.tableFieldService.Find("", map[string]any{"table_code": tableCode, "status": "Normal"})
+	if err != nil {
+		return nil, fmt.Errorf("获取表字段失败: %v", err)
+	}
+	definitions := make(map[string]*model.TableField, len(fields))
+	for _, field := range fields {
+		definitions[field.Code] = field
+	}
+	return definitions, nil
+}
+
+// entityLogsAfter 读取变更事件, 按记录分组, 每组按 id 倒序 (最新的在前)
+func (s *entityService) entityLogsAfter(tableCode string, where map[string]any) (map[uint][]map[string]any, error) {
+	rows, err := s.entityRepository.Find(tableCode+"_log", "*", where)
+	if err != nil {
+		return nil, fmt.Errorf("读取变更历史失败: %v", err)
+	}
+	logs := make(map[uint][]map[string]any)
+	for _, row := range rows {
+		id, _ := uintValue(row["entity_id"])
+		logs[id] = append(logs[id], row)
+	}
+	for _, group := range logs {
+		slices.SortFunc(group, func(a, b map[string]any) int {
+			idA, _ := uintValue(a["id"])
+			idB, _ := uintValue(b["id"])
+			return cmp.Compare(idB, idA)
+		})
+	}
+	return logs, nil
+}
+
+// entityAsOf 由当前数据逆序撤销变更事件, 得到事件发生前的数据; 记录当时不存在时返回 false
+// row 为当前数据 (含已删除的), 物理删除的记录为 nil, 由删除事件中记录的字段还原
+// 撤销新增、恢复事件表示记录当时尚未创建或在回收站中, 撤销删除事件表示记录当时存在; 没有新增事件的旧数据按 created_at 判断
+func entityAsOf(definitions map[string]*model.TableField, row map[string]any, logs []map[string]any, asOf time.Time) (map[string]any, bool) {
+	exists := row != nil && isEmptyValue(row["deleted_at"])
+	entity := maps.Clone(row)
+	if entity == nil {
+		entity = make(map[string]any)
+	}
+	for _, entityLog := range logs {
+		switch entityLog["operation"] {
+		case "Create", "Restore":
+			exists = false
+		case "Delete":
+			exists = true
+		}
+		for code, before := range entityLogBefore(entityLog) {
+			entity[code] = asOfValue(definitions[code], before)
+		}
+	}
+	if !exists || createdAfter(entity, asOf) {
+		return nil, false
+	}
+	entity["deleted_at"] = nil
+	delete(entity, "version")
+	return entity, true
+}
+
+// entityLogBefore 变更事件中各字段修改前的值
+// 旧版按字段记录的日志取 before_update, JSON 路径日志 (字段编码含 .) 不参与还原
+func entityLogBefore(entityLog map[string]any) map[string]any {
+	values := make(map[string]any)
+	var changes []byte
+	switch v := entityLog["changes"].(type) {
+	case string:
+		changes = []byte(v)
+	case []byte:
+		changes = v
+	case json.RawMessage:
+		changes = v
+	}
+	if len(bytes.TrimSpace(changes)) > 0 && string(bytes.TrimSpace(changes)) != "null" {
+		var fieldChanges map[string]struct {
+			Before any `json:"before"`
+		}
+		decoder := json.NewDecoder(bytes.NewReader(changes))
+		decoder.UseNumber()
+		if err := decoder.Decode(&fieldChanges); err == nil {
+			for code, change := range fieldChanges {
+				values[code] = change.Before
+			}
+		}
+		return values
+	}
+
+	code, _ := entityLog["field_code"].(string)
+	if code == "" || strings.Contains(code, ".") {
+		return values
+	}
+	before := fmt.Sprint(entityLog["before_update"])
+	switch v := entityLog["before_update"].(type) {
+	case nil:
+		before = ""
+	case []byte:
+		before = string(v)
+	}
+	if before == "" || before == "<nil>" {
+		values[code] = nil
+	} else {
+		values[code] = before
+	}
+	return values
+}
+
+// asOfValue 将变更历史中记录的值转换为数据表中的格式: JSON 字段为 JSON 文本, 多对多字段为编码数组
+func asOfValue(field *model.TableField, value any) any {
+	if field == nil || value == nil {
+		return value
+	}
+	if field.FieldType == "manytomany" {
+		codes := referenceValues(value, true)
+		if codes == nil {
+			codes = []string{}
+		}
+		return codes
+	}
+	if isJSONField(field) {
+		if s, ok := value.(string); ok {
+			return s
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(data)
+	}
+	return value
+}
+
+// isEmptyValue 判断 deleted_at 等可空字段是否为空
+func isEmptyValue(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case time.Time:
+		return v.IsZero()
+	case *time.Time:
+		return v == nil || v.IsZero()
+	case string:
+		return v == ""
+	case []byte:
+		return len(v) == 0
+	}
+	return false
+}
+
+// createdAfter 记录的创建时间是否晚于 asOf, asOf 为零值时不判断
+func createdAfter(row map[string]any, asOf time.Time) bool {
+	if asOf.IsZero() {
+		return false
+	}
+	switch v := row["created_at"].(type) {
+	case time.Time:
+		return v.After(asOf)
+	case *time.Time:
+		return v != nil && v.After(asOf)
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, fileDateTimeLayout} {
+			if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+				return t.After(asOf)
+			}
+		}
+	}
+	return false
+}
+
+// asOfField 查询字段的定义, 系统字段按查询字段白名单中的数据类型构造
+func asOfField(definitions map[string]*model.TableField, columns map[string]string, code string) *model.TableField {
+	if field, ok := definitions[code]; ok {
+		return field
+	}
+	return &model.TableField{Code: code, Type: columns[code]}
+}
+
+// matchAsOfFilter 在内存中按结构化查询条件过滤还原后的数据, 语义与 SQL 查询一致 (空值不满足比较条件)
+// 查询已由 CompileQuery 校验; 不支持按 JSON 路径过滤
+func matchAsOfFilter(filter *model.EntityFilter, columns map[string]string, definitions map[string]*model.TableField, row map[string]any) (bool, error) {
+	if filter == nil {
+		return true, nil
+	}
+	if filter.IsGroup() {
+		for _, child := range filter.And {
+			matched, err := matchAsOfFilter(child, columns, definitions, row)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		if len(filter.Or) == 0 {
+			return true, nil
+		}
+		for _, child := range filter.Or {
+			matched, err := matchAsOfFilter(child, columns, definitions, row)
+			if err != nil {
+				return false, err
+			}
+			if matched {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	dataType, ok := columns[filter.Field]
+	if !ok {
+		return false, fmt.Errorf("%w: field %q cannot be used in as_of queries", model.ErrInvalidQuery, filter.Field)
+	}
+	value := historyValue(asOfField(definitions, columns, filter.Field), row[filter.Field])
+	op := strings.ToLower(filter.Op)
+
+	switch op {
+	case model.QueryOpIsNull:
+		return value == nil, nil
+	case model.QueryOpNotNull:
+		return value != nil, nil
+	}
+	if value == nil {
+		return false, nil
+	}
+
+	if dataType == repository.LinkDataType {
+		codes, _ := value.([]string)
+		var items []any
+		if s, ok := filter.Value.(string); ok && op == model.QueryOpEq {
+			items = []any{s}
+		} else {
+			items = asOfValueList(filter.Value)
+		}
+		count := 0
+		for _, item := range items {
+			if slices.Contains(codes, strings.TrimSpace(fmt.Sprint(item))) {
+				count++
+			}
+		}
+		if op == model.QueryOpHasAll {
+			return count == len(items), nil
+		}
+		return count > 0, nil
+	}
+
+	switch op {
+	case model.QueryOpEq:
+		return compareAsOfValues(dataType, value, filter.Value) == 0, nil
+	case model.QueryOpNe:
+		return compareAsOfValues(dataType, value, filter.Value) != 0, nil
+	case model.QueryOpGt:
+		return compareAsOfValues(dataType, value, filter.Value) > 0, nil
+	case model.QueryOpGte:
+		return compareAsOfValues(dataType, value, filter.Value) >= 0, nil
+	case model.QueryOpLt:
+		return compareAsOfValues(dataType, value, filter.Value) < 0, nil
+	case model.QueryOpLte:
+		return compareAsOfValues(dataType, value, filter.Value) <= 0, nil
+	case model.QueryOpIn, model.QueryOpNin:
+		in := slices.ContainsFunc(asOfValueList(filter.Value), func(item any) bool {
+			return compareAsOfValues(dataType, value, item) == 0
+		})
+		return in == (op == model.QueryOpIn), nil
+	case model.QueryOpBetween:
+		bounds := asOfValueList(filter.Value)
+		if len(bounds) != 2 {
+			return false, fmt.Errorf("%w: field %q expects [from, to]", model.ErrInvalidQuery, filter.Field)
+		}
+		return compareAsOfValues(dataType, value, bounds[0]) >= 0 && compareAsOfValues(dataType, value, bounds[1]) <= 0, nil
+	case model.QueryOpLike, model.QueryOpContains, model.QueryOpStartsWith, model.QueryOpEndsWith:
+		text := strings.ToLower(fmt.Sprint(value))
+		pattern := strings.ToLower(fmt.Sprint(filter.Value))
+		switch op {
+		case model.QueryOpContains:
+			return strings.Contains(text, pattern), nil
+		case model.QueryOpStartsWith:
+			return strings.HasPrefix(text, pattern), nil
+		case model.QueryOpEndsWith:
+			return strings.HasSuffix(text, pattern), nil
+		}
+		expr := strings.NewReplacer("%", ".*", "_", ".").Replace(regexp.QuoteMeta(pattern))
+		return regexp.MustCompile("(?s)^" + expr + "$").MatchString(text), nil
+	}
+	return false, fmt.Errorf("%w: unsupported operator %q", model.ErrInvalidQuery, filter.Op)
+}
+
+// asOfValueList 列表类查询值, 字符串按逗号分隔
+func asOfValueList(value any) []any {
+	switch v := value.(type) {
+	case []any:
+		return v
+	case []string:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return items
+	case string:
+		var items []any
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	return []any{value}
+}
+
+// compareAsOfValues 比较两个值, 数值字段按数值比较, 其它按文本比较; 空值最小
+func compareAsOfValues(dataType string, a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if dataType == "Number" {
+		x, okA := asOfNumber(a)
+		y, okB := asOfNumber(b)
+		if okA && okB {
+			return cmp.Compare(x, y)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// asOfNumber 转换为数值
+func asOfNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// sortAsOfRows 按编译后的排序字段在内存中排序, 空值按 MySQL 规则: 升序在前, 降序在后
+func sortAsOfRows(rows []map[string]any, sorts []repository.CompiledSort, columns map[string]string, definitions map[string]*model.TableField) {
+	slices.SortStableFunc(rows, func(a, b map[string]any) int {
+		for _, sort := range sorts {
+			field := asOfField(definitions, columns, sort.Field)
+			result := compareAsOfValues(columns[sort.Field], historyValue(field, a[sort.Field]), historyValue(field, b[sort.Field]))
+			if sort.Desc {
+				result = -result
+			}
+			if result != 0 {
+				return result
+			}
+		}
+		return 0
+	})
+}
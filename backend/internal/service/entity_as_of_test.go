@@ -0,0 +1,105 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func asOfTestFields() map[string]*model.TableField {
+	definitions := make(map[string]*model.TableField)
+	for _, field := range historyTestFields() {
+		definitions[field.Code] = field
+	}
+	return definitions
+}
+
+func TestEntityAsOf(t *testing.T) {
+	definitions := asOfTestFields()
+	asOf := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	row := map[string]any{
+		"id":         uint64(1),
+		"name":       "C",
+		"price":      "3",
+		"tags":       []string{"x", "y"},
+		"attrs":      `{"color":"blue"}`,
+		"status":     "Frozen",
+		"version":    uint64(5),
+		"created_at": time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local),
+	}
+	// 按 id 倒序: 最新的事件先撤销
+	logs := []map[string]any{
+		{"id": uint64(12), "operation": "Update", "changes": `{"status":{"before":"Normal","after":"Frozen"}}`},
+		{"id": uint64(11), "operation": "Update", "changes": []byte(`{"name":{"before":"B","after":"C"},"tags":{"before":["x"],"after":["x","y"]},"attrs":{"before":{"color":"red"},"after":{"color":"blue"}}}`)},
+		{"id": uint64(10), "field_code": "price", "before_update": "2", "after_update": "3"}, // 旧版按字段记录的日志
+		{"id": uint64(9), "field_code": "attrs.color", "before_update": "green"},             // 旧版 JSON 路径日志不参与还原
+	}
+
+	entity, ok := entityAsOf(definitions, row, logs, asOf)
+	require.True(t, ok)
+	assert.Equal(t, "B", entity["name"])
+	assert.Equal(t, "2", entity["price"])
+	assert.Equal(t, []string{"x"}, entity["tags"])
+	assert.Equal(t, `{"color":"red"}`, entity["attrs"])
+	assert.Equal(t, "Normal", entity["status"])
+	assert.NotContains(t, entity, "version")
+	assert.Equal(t, "C", row["name"], "不修改当前数据")
+
+	// 之后才创建的记录: 撤销新增事件, 或没有新增事件时按 created_at 判断
+	_, ok = entityAsOf(definitions, row, []map[string]any{{"id": uint64(1), "operation": "Create", "changes": `{"name":{"before":null,"after":"A"}}`}}, asOf)
+	assert.False(t, ok)
+	_, ok = entityAsOf(definitions, row, nil, time.Date(2023, 12, 1, 0, 0, 0, 0, time.Local))
+	assert.False(t, ok)
+
+	// 之后被物理删除的记录: 由删除事件中记录的字段还原
+	entity, ok = entityAsOf(definitions, nil, []map[string]any{{"id": uint64(20), "operation": "Delete", "changes": `{"name":{"before":"D","after":null}}`}}, asOf)
+	require.True(t, ok)
+	assert.Equal(t, "D", entity["name"])
+
+	// 已删除且删除事件早于时间点的记录不存在
+	deleted := map[string]any{"id": uint64(2), "deleted_at": time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local)}
+	_, ok = entityAsOf(definitions, deleted, nil, asOf)
+	assert.False(t, ok)
+}
+
+func TestMatchAsOfFilter(t *testing.T) {
+	definitions := asOfTestFields()
+	columns := map[string]string{"id": "Number", "name": "Text", "price": "Number", "launched": "Date", "tags": repository.LinkDataType, "attrs": "JSON", "status": "Text"}
+	row := map[string]any{"id": uint64(1), "name": "Apple", "price": "10", "launched": "2024-01-02", "tags": []string{"x", "y"}, "status": "Normal"}
+
+	cases := []struct {
+		filter *model.EntityFilter
+		want   bool
+	}{
+		{&model.EntityFilter{Field: "name", Op: model.QueryOpEq, Value: "Apple"}, true},
+		{&model.EntityFilter{Field: "name", Op: model.QueryOpLike, Value: "ap%"}, true},
+		{&model.EntityFilter{Field: "name", Op: model.QueryOpContains, Value: "PL"}, true},
+		{&model.EntityFilter{Field: "price", Op: model.QueryOpGt, Value: 9.5}, true},
+		{&model.EntityFilter{Field: "price", Op: model.QueryOpIn, Value: []any{"2", "10"}}, true},
+		{&model.EntityFilter{Field: "launched", Op: model.QueryOpBetween, Value: []any{"2024-01-01", "2024-01-31"}}, true},
+		{&model.EntityFilter{Field: "attrs", Op: model.QueryOpIsNull}, true},
+		{&model.EntityFilter{Field: "attrs", Op: model.QueryOpNe, Value: "x"}, false}, // 空值不满足比较条件
+		{&model.EntityFilter{Field: "tags", Op: model.QueryOpHasAny, Value: []any{"y", "z"}}, true},
+		{&model.EntityFilter{Field: "tags", Op: model.QueryOpHasAll, Value: []any{"y", "z"}}, false},
+		{&model.EntityFilter{Or: []*model.EntityFilter{
+			{Field: "status", Op: model.QueryOpEq, Value: "Frozen"},
+			{And: []*model.EntityFilter{
+				{Field: "price", Op: model.QueryOpLte, Value: "10"},
+				{Field: "name", Op: model.QueryOpStartsWith, Value: "A"},
+			}},
+		}}, true},
+	}
+	for _, tc := range cases {
+		matched, err := matchAsOfFilter(tc.filter, columns, definitions, row)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, matched, "%+v", tc.filter)
+	}
+
+	_, err := matchAsOfFilter(&model.EntityFilter{Field: "attrs.color", Op: model.QueryOpEq, Value: "red"}, columns, definitions, row)
+	assert.ErrorIs(t, err, model.ErrInvalidQuery)
+}
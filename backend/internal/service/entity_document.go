@@ -0,0 +1,494 @@
+package service
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// documentItemsColumn 导入导出文件中保存行项目的列, 值为按行项目表分组的 JSON, 如 {"order_item": [{...}]}
+const documentItemsColumn = "items"
+
+// EntityDocument 单据: 抬头记录及其行项目, 行项目按行项目表编码分组
+// 修改时提交的行项目表整体替换: 带 id 的行修改, 不带 id 的行新增, 未提交的已有行删除; 未提交的行项目表不变
+type EntityDocument struct {
+	Header map[string]any              `json:"header"`
+	Items  map[string][]map[string]any `json:"items,omitempty"`
+}
+
+// DocumentDraft 单据中一条记录的写入: 抬头或行项目的新增、修改、删除
+type DocumentDraft struct {
+	TableCode string
+	Operation string         // Create、Update、Delete
+	EntityID  uint           // 记录 id, 新增时为预先生成的 id
+	Entity    map[string]any // 新增的全部字段或修改的字段, 删除时为空
+	Origin    map[string]any // 修改、删除前的记录
+	Version   uint           // 修改、删除依据的数据版本
+}
+
+// itemTable 行项目表: 行项目的 SelfField 保存抬头 ParentField 的值
+type itemTable struct {
+	code        string
+	parentField string // 抬头表字段
+	selfField   string // 行项目表字段
+}
+
+// findItemTables 查询表的行项目表 (TableType 为 Item 且 ParentTable 为该表), 按表排序返回
+func findItemTables(tableRepository repository.TableRepository, tableCode string) ([]*itemTable, error) {
+	tables, err := tableRepository.Find("", map[string]any{"table_type": "Item", "parent_table": tableCode})
+	if err != nil {
+		return nil, fmt.Errorf("查询行项目表失败: %v", err)
+	}
+	items := make([]*itemTable, 0, len(tables))
+	for _, table := range tables {
+		if table.ParentField == "" || table.SelfField == "" {
+			return nil, fmt.Errorf("行项目表 %s 未配置关联字段", table.Code)
+		}
+		items = append(items, &itemTable{code: table.Code, parentField: table.ParentField, selfField: table.SelfField})
+	}
+	return items, nil
+}
+
+// documentKey 抬头关联字段的值, 用于查询行项目
+func documentKey(value any) any {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return value
+}
+
+// itemLines 查询抬头的行项目, 抬头关联字段为空时没有行项目
+func itemLines(repo repository.EntityRepository, item *itemTable, key any) ([]map[string]any, error) {
+	if key = documentKey(key); key == nil || key == "" {
+		return nil, nil
+	}
+	return repo.Find(item.code, "*", map[string]any{item.selfField: key})
+}
+
+// documentTables 单据的行项目表, 不是单据抬头表 (没有行项目表) 时返回 ErrInvalidQuery
+func (s *entityService) documentTables(tableCode string) ([]*itemTable, error) {
+	items, err := findItemTables(s.tableRepository, tableCode)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: 表 %s 没有行项目表", model.ErrInvalidQuery, tableCode)
+	}
+	return items, nil
+}
+
+// GetDocument 查询单据: 抬头记录及其全部行项目
+func (s *entityService) GetDocument(c *gin.Context, tableCode string, id uint) (*EntityDocument, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
+	}
+	items, err := s.documentTables(tableCode)
+	if err != nil {
+		return nil, err
+	}
+	header, err := s.entityRepository.FindOne(tableCode, id)
+	if err != nil || header == nil || header["deleted_at"] != nil {
+		return nil, fmt.Errorf("记录 %d 不存在", id)
+	}
+	doc := &EntityDocument{Header: header, Items: make(map[string][]map[string]any, len(items))}
+	for _, item := range items {
+		lines, err := itemLines(s.entityRepository, item, header[item.parentField])
+		if err != nil {
+			return nil, err
+		}
+		if lines == nil {
+			lines = []map[string]any{}
+		}
+		doc.Items[item.code] = lines
+	}
+	return doc, nil
+}
+
+// SaveDocument 新增或修改单据 (抬头带 id 时为修改), 返回抬头记录的 id
+// 抬头表配置了对应操作的审批流程时整张单据提交一个审批, 否则在同一事务中写入抬头和行项目
+func (s *entityService) SaveDocument(c *gin.Context, tableCode, reason string, doc *EntityDocument) (uint, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return 0, err
+	}
+	if doc == nil || doc.Header == nil {
+		return 0, fmt.Errorf("单据抬头不能为空")
+	}
+	operation := "Create"
+	if id, ok := uintValue(doc.Header["id"]); ok && id > 0 {
+		operation = "Update"
+	}
+	tableApprovalDefs, err := s.tableApprovalDefinitionRepository.List(tableCode, operation)
+	if err != nil {
+		s.logger.Error("查询审批流程定义失败", "err", err)
+	}
+	withApproval := len(tableApprovalDefs) > 0
+
+	drafts, err := s.planDocument(c, tableCode, doc, withApproval)
+	if err != nil {
+		return 0, err
+	}
+	id := drafts[0].EntityID
+	if withApproval {
+		_, err = s.approvalService.SubmitDocumentWithApproval(c, tableCode, reason, operation, drafts)
+		return id, err
+	}
+	return id, s.applyDocument(c, reason, drafts)
+}
+
+// planDocument 校验单据并生成抬头和行项目的写入, 第一条为抬头
+func (s *entityService) planDocument(c *gin.Context, tableCode string, doc *EntityDocument, withApproval bool) ([]*DocumentDraft, error) {
+	items, err := s.documentTables(tableCode)
+	if err != nil {
+		return nil, err
+	}
+	for code := range doc.Items {
+		if !slices.ContainsFunc(items, func(item *itemTable) bool { return item.code == code }) {
+			return nil, fmt.Errorf("%w: %s 不是表 %s 的行项目表", model.ErrInvalidQuery, code, tableCode)
+		}
+	}
+	for _, item := range items {
+		if err := s.checkPermission(c, item.code); err != nil {
+			return nil, err
+		}
+	}
+
+	header, err := s.planRecord(c, tableCode, doc.Header, nil, withApproval)
+	if err != nil {
+		return nil, err
+	}
+	drafts := []*DocumentDraft{header}
+	for _, item := range items {
+		lines, submitted := doc.Items[item.code]
+		itemDrafts, err := s.planItems(c, item, header, lines, submitted, withApproval)
+		if err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, itemDrafts...)
+	}
+	return drafts, nil
+}
+
+// planRecord 校验单据中的一条记录: 不带 id 时新增, 带 id 时修改; existing 为行项目表已有的行, 修改的行必须在其中
+func (s *entityService) planRecord(c *gin.Context, tableCode string, record map[string]any, existing map[uint]map[string]any, withApproval bool) (*DocumentDraft, error) {
+	record = maps.Clone(record)
+	delete(record, "table_code")
+	delete(record, "reason")
+	id, ok := uintValue(record["id"])
+	if !ok || id == 0 {
+		if err := s.prepareCreate(c, tableCode, record, withApproval); err != nil {
+			return nil, err
+		}
+		id, _ = uintValue(record["id"])
+		return &DocumentDraft{TableCode: tableCode, Operation: "Create", EntityID: id, Entity: record}, nil
+	}
+	record["id"] = id
+
+	origin, found := existing[id]
+	if existing == nil {
+		var err error
+		if origin, err = s.entityRepository.FindOne(tableCode, id); err != nil || origin == nil {
+			return nil, fmt.Errorf("记录 %d 不存在", id)
+		}
+		found = origin["deleted_at"] == nil
+	}
+	if !found {
+		return nil, fmt.Errorf("记录 %d 不存在或不属于该单据", id)
+	}
+	// 已锁定、已作废的数据不能修改
+	if err := checkStatusTransition(origin, "Update"); err != nil {
+		return nil, err
+	}
+	// 单据中修改树形节点的父节点需要改写子树, 使用移动接口
+	if treeMoved(origin, record) {
+		return nil, fmt.Errorf("单据不能修改记录 %d 的父节点, 请使用移动接口", id)
+	}
+
+	tableFields, err := s.tableFieldService.Find("", map[string]any{"table_code": tableCode})
+	if err != nil {
+		return nil, fmt.Errorf("获取表字段失败: %v", err)
+	}
+	// 提交了版本号时按版本修改 (乐观锁), 否则按读取时的版本修改
+	version, _ := uintValue(origin["version"])
+	if submitted, ok := uintValue(record["version"]); ok {
+		if conflict := versionConflict(tableFields, id, submitted, origin, record); conflict != nil {
+			return nil, &ConflictError{Conflicts: []*EntityConflict{conflict}}
+		}
+	}
+	if err := validateEntity(s.tableFieldService, s.entityRepository, tableCode, record, true); err != nil {
+		return nil, err
+	}
+	if err := s.validateUniqueConstraints(c, "Update", tableCode, record, withApproval); err != nil {
+		return nil, err
+	}
+
+	// 状态只能由生命周期操作改变
+	changes := make(map[string]any, len(record))
+	for key, value := range record {
+		if key != "id" && key != "version" && key != "status" {
+			changes[key] = value
+		}
+	}
+	changes["updated_by"] = c.GetString("user_name")
+	changes["updated_at"] = time.Now()
+	return &DocumentDraft{TableCode: tableCode, Operation: "Update", EntityID: id, Entity: changes, Origin: origin, Version: version}, nil
+}
+
+// planItems 生成一个行项目表的写入, 行项目的关联字段取抬头关联字段的值
+// 修改抬头关联字段时, 未提交的行项目表的已有行同步修改关联字段
+func (s *entityService) planItems(c *gin.Context, item *itemTable, header *DocumentDraft, lines []map[string]any, submitted, withApproval bool) ([]*DocumentDraft, error) {
+	key, ok := header.Entity[item.parentField]
+	if !ok {
+		key = header.Origin[item.parentField]
+	}
+	key = documentKey(key)
+	if submitted && (key == nil || key == "") {
+		return nil, fmt.Errorf("抬头字段 %s 为空, 不能保存行项目 %s", item.parentField, item.code)
+	}
+
+	var existing []map[string]any
+	if header.Operation == "Update" {
+		var err error
+		if existing, err = itemLines(s.entityRepository, item, header.Origin[item.parentField]); err != nil {
+			return nil, err
+		}
+	}
+	byID := make(map[uint]map[string]any, len(existing))
+	for _, line := range existing {
+		if id, err := importRowID(line); err == nil {
+			byID[id] = line
+		}
+	}
+
+	var drafts []*DocumentDraft
+	if !submitted {
+		if historyEqual(documentKey(header.Origin[item.parentField]), key) {
+			return nil, nil
+		}
+		for _, line := range existing {
+			id, _ := importRowID(line)
+			version, _ := uintValue(line["version"])
+			drafts = append(drafts, &DocumentDraft{
+				TableCode: item.code,
+				Operation: "Update",
+				EntityID:  id,
+				Entity:    map[string]any{item.selfField: key, "updated_by": c.GetString("user_name"), "updated_at": time.Now()},
+				Origin:    line,
+				Version:   version,
+			})
+		}
+		return drafts, nil
+	}
+
+	kept := make(map[uint]bool, len(lines))
+	for i, line := range lines {
+		line = maps.Clone(line)
+		line[item.selfField] = key
+		draft, err := s.planRecord(c, item.code, line, byID, withApproval)
+		if err != nil {
+			return nil, fmt.Errorf("行项目 %s 第 %d 行: %w", item.code, i+1, err)
+		}
+		if kept[draft.EntityID] {
+			return nil, fmt.Errorf("行项目 %s 第 %d 行: 记录 %d 重复", item.code, i+1, draft.EntityID)
+		}
+		kept[draft.EntityID] = true
+		drafts = append(drafts, draft)
+	}
+
+	// 未提交的已有行删除, 被 restrict 关系字段引用的行不能删除
+	var deleted []uint
+	for _, line := range existing {
+		id, err := importRowID(line)
+		if err != nil || kept[id] {
+			continue
+		}
+		if err := checkStatusTransition(line, "Delete"); err != nil {
+			return nil, fmt.Errorf("行项目 %s: %w", item.code, err)
+		}
+		version, _ := uintValue(line["version"])
+		drafts = append(drafts, &DocumentDraft{TableCode: item.code, Operation: "Delete", EntityID: id, Origin: line, Version: version})
+		deleted = append(deleted, id)
+	}
+	if len(deleted) > 0 {
+		if err := s.approvalService.CheckDeleteReferences(c, item.code, deleted); err != nil {
+			return nil, err
+		}
+	}
+	return drafts, nil
+}
+
+// applyDocument 在同一事务中写入单据的抬头和行项目, 任一条失败时整体回滚
+// 提交后逐条记录变更历史, 并处理删除的行项目被引用的数据
+func (s *entityService) applyDocument(c *gin.Context, reason string, drafts []*DocumentDraft) error {
+	fields := make(map[string][]*model.TableField)
+	for _, draft := range drafts {
+		if _, ok := fields[draft.TableCode]; ok {
+			continue
+		}
+		tableFields, err := s.tableFieldService.Find("", map[string]any{"table_code": draft.TableCode})
+		if err != nil {
+			return fmt.Errorf("获取表字段失败: %v", err)
+		}
+		fields[draft.TableCode] = tableFields
+	}
+
+	err := s.entityRepository.Transaction(c, func(repo repository.EntityRepository) error {
+		for _, draft := range drafts {
+			var err error
+			switch draft.Operation {
+			case "Create":
+				err = repo.Create(c, draft.TableCode, draft.Entity)
+			case "Update":
+				where := map[string]any{"id": draft.EntityID, "version": draft.Version}
+				if err = repo.Update(c, draft.TableCode, draft.Entity, where); err != nil {
+					versions := map[uint]uint{draft.EntityID: draft.Version}
+					err = versionError(err, repo, fields[draft.TableCode], draft.TableCode, versions, draft.Entity)
+				}
+			case "Delete":
+				err = repo.Delete(c, draft.TableCode, draft.EntityID, reason)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 记录变更历史, 日志记录失败不应该阻断保存
+	deleted := make(map[string][]map[string]any)
+	for _, draft := range drafts {
+		event := entityEvent{Operation: draft.Operation, Reason: reason}
+		after := draft.Entity
+		if draft.Operation == "Delete" {
+			deleted[draft.TableCode] = append(deleted[draft.TableCode], draft.Origin)
+			after = nil
+		}
+		if err := writeEntityLog(c, s.entityLogService, draft.TableCode, fields[draft.TableCode], event, draft.EntityID, draft.Origin, after); err != nil {
+			s.logger.Error("创建变更日志失败", "error", err, "id", draft.EntityID)
+		}
+	}
+	for _, tableCode := range slices.Sorted(maps.Keys(deleted)) {
+		if err := s.approvalService.ApplyDeleteReferences(c, tableCode, reason, deleted[tableCode]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SubmitDocumentWithApproval 将单据的抬头和行项目保存到各自的草稿表并启动一个审批流程, 返回审批编码
+// 草稿共用一个审批编码, 审批通过后在同一事务中发布; 修改、删除的草稿为完整记录, 带依据的数据版本
+func (s *approvalService) SubmitDocumentWithApproval(c *gin.Context, tableCode, reason, operation string, drafts []*DocumentDraft) (string, error) {
+	operationInfo := make(map[string]string)
+	if err := s.GetOperationInfo(operation, &operationInfo); err != nil {
+		return "", err
+	}
+	// 单据中有记录在审批中时不能再次提交
+	for _, draft := range drafts {
+		if draft.Operation != "Create" {
+			if err := s.CheckExistingActiveDraft(c, draft.TableCode+"_draft", draft.EntityID); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	// 审批表单数据为抬头记录, 用于审批条件和摘要
+	formData := maps.Clone(drafts[0].Origin)
+	if formData == nil {
+		formData = make(map[string]any)
+	}
+	maps.Copy(formData, drafts[0].Entity)
+
+	approvalCode := strings.ToUpper(uuid.New().String())
+	rows := make([]map[string]any, len(drafts))
+	for i, draft := range drafts {
+		info := make(map[string]string)
+		if err := s.GetOperationInfo(draft.Operation, &info); err != nil {
+			return "", err
+		}
+		entity := s.tableFieldRepository.BuildEntity(draft.TableCode + "_draft")
+		for k := range entity {
+			if v, ok := draft.Origin[k]; ok {
+				entity[k] = v
+			}
+		}
+		maps.Copy(entity, draft.Entity)
+		entity["id"] = s.globalIdService.GetNewID("entity_draft")
+		entity["entity_id"] = draft.EntityID
+		entity["operation"] = draft.Operation
+		entity["action"] = info["action"]
+		entity["status"] = info["status"]
+		entity["send_status"] = 0
+		entity["version"] = draft.Version
+		entity["approval_code"] = approvalCode
+		entity["draft_status"] = "Pending"
+		entity["created_by"] = c.GetString("user_name")
+		entity["updated_by"] = c.GetString("user_name")
+		entity["created_at"] = time.Now()
+		entity["updated_at"] = time.Now()
+		entity["deleted_at"] = nil
+		rows[i] = entity
+	}
+
+	err := s.entityRepository.Transaction(c, func(repo repository.EntityRepository) error {
+		for i, draft := range drafts {
+			if err := repo.Create(c, draft.TableCode+"_draft", rows[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	approvalInfo := make(map[string]string)
+	approvalInfo["operation"] = operation
+	approvalInfo["approvalCode"] = approvalCode
+	approvalInfo["operationName"] = operationInfo["operationName"]
+	approvalInfo["action"] = operationInfo["action"]
+	approvalInfo["reason"] = reason
+	approvalInfo["entityCode"] = tableCode
+
+	if err := s.CreateApprovalFlow(c, tableCode, approvalInfo, formData); err != nil {
+		return "", err
+	}
+	return approvalCode, nil
+}
+
+// publishDocument 审批通过后在同一事务中发布单据抬头和各行项目表的草稿
+// 表没有行项目表时按单表发布
+func (s *approvalService) publishDocument(c *gin.Context, approval *model.Approval) error {
+	var items []*itemTable
+	if s.tableRepository != nil {
+		var err error
+		if items, err = findItemTables(s.tableRepository, approval.EntityCode); err != nil {
+			return err
+		}
+	}
+	if len(items) == 0 {
+		return s.publishDrafts(c, approval, approval.EntityCode)
+	}
+	return s.entityRepository.Transaction(c, func(repo repository.EntityRepository) error {
+		tx := *s
+		tx.entityRepository = repo
+		if err := tx.publishDrafts(c, approval, approval.EntityCode); err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := tx.publishDrafts(c, approval, item.code); err != nil {
+				return fmt.Errorf("行项目 %s: %w", item.code, err)
+			}
+		}
+		return nil
+	})
+}
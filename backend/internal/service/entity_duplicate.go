@@ -0,0 +1,582 @@
+package service
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxDuplicateCandidates = 1000   // 新增、导入时每条规则最多比较的已有记录数
+	maxDuplicateResults    = 20     // 最多返回的疑似重复记录数
+	maxDuplicateScanRows   = 200000 // 查重任务最多扫描的记录数
+	maxDuplicateBlock      = 1000   // 查重任务中同一分组键的记录超过该数量时不比较, 避免常见值导致两两比较过多
+	duplicateChunkSize     = 1000
+)
+
+// DuplicateCandidate 疑似重复的已有记录
+type DuplicateCandidate struct {
+	ID     uint               `json:"id"`
+	Rule   string             `json:"rule"`   // 命中的匹配规则编码
+	Action string             `json:"action"` // 命中规则的处理方式: Warn 提示 Block 禁止保存
+	Score  float64            `json:"score"`  // 加权相似度
+	Fields map[string]float64 `json:"fields"` // 各字段相似度
+	Record map[string]any     `json:"record"` // 已有记录
+}
+
+// DuplicateError 保存的数据与已有记录疑似重复
+// Blocked 为 false 时只命中了 Warn 规则, 确认后可以忽略 (ignore_duplicates) 继续保存
+type DuplicateError struct {
+	Candidates []*DuplicateCandidate `json:"candidates"`
+	Blocked    bool                  `json:"blocked"`
+}
+
+func (e *DuplicateError) Error() string {
+	items := make([]string, 0, len(e.Candidates))
+	for _, candidate := range e.Candidates {
+		items = append(items, fmt.Sprintf("id %d (%s %.2f)", candidate.ID, candidate.Rule, candidate.Score))
+	}
+	message := "存在疑似重复的数据: " + strings.Join(items, ", ")
+	if !e.Blocked {
+		message += "; 确认不是重复数据后可忽略提示继续保存"
+	}
+	return message
+}
+
+// DuplicateClusterDetail 疑似重复分组及其成员记录
+type DuplicateClusterDetail struct {
+	*model.DuplicateCluster
+	Records []map[string]any `json:"Records"` // 成员记录, 已删除的记录不返回
+}
+
+// findMatchRules 表启用的匹配规则
+func (s *entityService) findMatchRules(tableCode string) ([]*model.MatchRule, error) {
+	return s.matchRuleRepository.Find(map[string]any{
+		"table_code": tableCode,
+		"status":     "Normal",
+	})
+}
+
+// CheckDuplicates 按表的匹配规则查找与 entityMap 疑似重复的已有记录, 不保存数据
+func (s *entityService) CheckDuplicates(c *gin.Context, tableCode string, entityMap map[string]any) ([]*DuplicateCandidate, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
+	}
+	rules, err := s.findMatchRules(tableCode)
+	if err != nil {
+		return nil, err
+	}
+	return s.findDuplicates(tableCode, rules, entityMap)
+}
+
+// checkDuplicates 保存前查重: 命中 Block 规则时不能保存; 只命中 Warn 规则时, 请求确认忽略 (ignore_duplicates) 后可以保存
+func (s *entityService) checkDuplicates(c *gin.Context, tableCode string, rules []*model.MatchRule, entityMap map[string]any) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	candidates, err := s.findDuplicates(tableCode, rules, entityMap)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	blocked := slices.ContainsFunc(candidates, func(candidate *DuplicateCandidate) bool {
+		return candidate.Action == model.MatchActionBlock
+	})
+	if !blocked && c.GetBool("ignore_duplicates") {
+		return nil
+	}
+	return &DuplicateError{Candidates: candidates, Blocked: blocked}
+}
+
+// findDuplicates 按规则查找与 record 疑似重复的已有记录 (不含 record 本身)
+// 先按分组字段和匹配字段的前缀在数据库中筛选候选记录, 再逐条计算相似度
+// 同一记录命中多条规则时保留 Block 规则和相似度高的结果
+func (s *entityService) findDuplicates(tableCode string, rules []*model.MatchRule, record map[string]any) ([]*DuplicateCandidate, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	columns, err := s.queryColumns(tableCode)
+	if err != nil {
+		return nil, err
+	}
+	selfID, _ := uintValue(record["id"])
+
+	found := make(map[uint]*DuplicateCandidate)
+	for _, rule := range rules {
+		profile := newMatchProfile(rule.Fields, record)
+		filter := duplicateFilter(rule, profile, record, columns)
+		if filter == nil {
+			continue
+		}
+		query, err := s.entityRepository.CompileQuery(tableCode, &model.EntityQuery{
+			Filter: filter,
+			Fields: matchRuleColumns(rule, columns),
+		}, columns, "t")
+		if err != nil {
+			// 记录中的值不能作为查询条件时 (如数值字段填了文本), 跳过该规则, 由字段校验报告错误
+			s.logger.Warn("查重条件无效", "table", tableCode, "rule", rule.Code, "err", err)
+			continue
+		}
+		rows, err := s.entityRepository.FindChunk(tableCode, query, nil, maxDuplicateCandidates)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			id, ok := uintValue(row["id"])
+			if !ok || id == selfID {
+				continue
+			}
+			score, fields := profile.score(rule.Fields, newMatchProfile(rule.Fields, row))
+			if score < rule.Threshold {
+				continue
+			}
+			candidate := &DuplicateCandidate{ID: id, Rule: rule.Code, Action: rule.Action, Score: roundScore(score), Fields: fields}
+			if current, ok := found[id]; !ok || compareCandidates(candidate, current) < 0 {
+				found[id] = candidate
+			}
+		}
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	candidates := make([]*DuplicateCandidate, 0, len(found))
+	for _, candidate := range found {
+		candidates = append(candidates, candidate)
+	}
+	slices.SortFunc(candidates, compareCandidates)
+	if len(candidates) > maxDuplicateResults {
+		candidates = candidates[:maxDuplicateResults]
+	}
+
+	ids := make([]uint, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.ID
+	}
+	records, err := s.entityRepository.Find(tableCode, "*", map[string]any{"id": ids})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]map[string]any, len(records))
+	for _, r := range records {
+		if id, ok := uintValue(r["id"]); ok {
+			byID[id] = r
+		}
+	}
+	for _, candidate := range candidates {
+		candidate.Record = byID[candidate.ID]
+	}
+	return candidates, nil
+}
+
+// compareCandidates 排序: Block 规则在前, 相似度高的在前
+func compareCandidates(a, b *DuplicateCandidate) int {
+	aBlock, bBlock := a.Action == model.MatchActionBlock, b.Action == model.MatchActionBlock
+	if aBlock != bBlock {
+		if aBlock {
+			return -1
+		}
+		return 1
+	}
+	if a.Score != b.Score {
+		return cmp.Compare(b.Score, a.Score)
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+func roundScore(score float64) float64 {
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(score, 'f', 4, 64), 64)
+	return rounded
+}
+
+// matchRuleColumns 查重需要读取的字段: id、分组字段和匹配字段
+func matchRuleColumns(rule *model.MatchRule, columns map[string]string) []string {
+	selected := []string{"id"}
+	for _, code := range matchBlockFields(rule) {
+		if _, ok := columns[code]; ok && !slices.Contains(selected, code) {
+			selected = append(selected, code)
+		}
+	}
+	for _, field := range rule.Fields {
+		if dataType, ok := columns[field.Field]; ok && dataType != repository.LinkDataType && !slices.Contains(selected, field.Field) {
+			selected = append(selected, field.Field)
+		}
+	}
+	return selected
+}
+
+// matchBlockFields 规则的分组字段
+func matchBlockFields(rule *model.MatchRule) []string {
+	var fields []string
+	for _, code := range strings.Split(rule.BlockFields, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			fields = append(fields, code)
+		}
+	}
+	return fields
+}
+
+// duplicateFilter 候选记录的筛选条件: 分组字段值相同, 且任一匹配字段可能相似
+// 精确匹配和非文本字段按值相等筛选, 词匹配按包含最长的几个词筛选, 其他方法按首字符或末字符筛选
+// 记录的匹配字段都为空时返回 nil, 不需要查重
+func duplicateFilter(rule *model.MatchRule, profile matchProfile, record map[string]any, columns map[string]string) *model.EntityFilter {
+	filter := &model.EntityFilter{}
+	for _, code := range matchBlockFields(rule) {
+		if _, ok := columns[code]; !ok {
+			continue
+		}
+		if text := matchText(record[code]); text != "" {
+			filter.And = append(filter.And, &model.EntityFilter{Field: code, Op: model.QueryOpEq, Value: text})
+		}
+	}
+
+	var similar []*model.EntityFilter
+	for _, field := range rule.Fields {
+		dataType, ok := columns[field.Field]
+		value := profile[field.Field]
+		if !ok || dataType == repository.LinkDataType || dataType == "JSON" || value.empty() {
+			continue
+		}
+		if field.Method == model.MatchMethodExact || dataType != "Text" {
+			similar = append(similar, &model.EntityFilter{Field: field.Field, Op: model.QueryOpEq, Value: value.text})
+			continue
+		}
+		if field.Method == model.MatchMethodToken {
+			tokens := make([]string, 0, len(value.tokens))
+			for token := range value.tokens {
+				tokens = append(tokens, token)
+			}
+			slices.SortFunc(tokens, func(a, b string) int {
+				return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b))
+			})
+			for _, token := range tokens[:min(len(tokens), 3)] {
+				similar = append(similar, &model.EntityFilter{Field: field.Field, Op: model.QueryOpContains, Value: token})
+			}
+			continue
+		}
+		runes := []rune(value.text)
+		similar = append(similar, &model.EntityFilter{Field: field.Field, Op: model.QueryOpStartsWith, Value: string(runes[0])})
+		if field.Method == model.MatchMethodEditDistance {
+			similar = append(similar, &model.EntityFilter{Field: field.Field, Op: model.QueryOpEndsWith, Value: string(runes[len(runes)-1])})
+		}
+	}
+	if len(similar) == 0 {
+		return nil
+	}
+	filter.And = append(filter.And, &model.EntityFilter{Or: similar})
+	return filter
+}
+
+// ScanDuplicates 后台按匹配规则扫描全表, 生成疑似重复分组
+// 重新扫描时替换该规则待处理的分组, 已确认或已排除的分组保留, 与已排除分组成员相同的分组不再生成
+func (s *entityService) ScanDuplicates(c *gin.Context, tableCode, ruleCode string) (*model.EntityJob, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
+	}
+	rules, err := s.matchRuleRepository.Find(map[string]any{"table_code": tableCode, "code": ruleCode})
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("匹配规则 %s 不存在", ruleCode)
+	}
+	rule := rules[0]
+
+	columns, err := s.queryColumns(tableCode)
+	if err != nil {
+		return nil, err
+	}
+	query, err := s.entityRepository.CompileQuery(tableCode, &model.EntityQuery{Fields: matchRuleColumns(rule, columns)}, columns, "t")
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.entityRepository.Count(tableCode, query)
+	if err != nil {
+		return nil, err
+	}
+	if total > maxDuplicateScanRows {
+		return nil, fmt.Errorf("表 %s 有 %d 条记录, 查重任务最多扫描 %d 条", tableCode, total, maxDuplicateScanRows)
+	}
+
+	job := &model.EntityJob{
+		Type:      model.EntityJobTypeDuplicateScan,
+		TableCode: tableCode,
+		Operation: rule.Code,
+		Total:     int(total),
+	}
+	if err := s.entityJobService.Create(c, job); err != nil {
+		return nil, err
+	}
+	queued := snapshotJob(job)
+	go s.runDuplicateScanJob(c.Copy(), job, rule, query)
+	return queued, nil
+}
+
+// runDuplicateScanJob 后台执行查重任务
+func (s *entityService) runDuplicateScanJob(c *gin.Context, job *model.EntityJob, rule *model.MatchRule, query *repository.CompiledEntityQuery) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("查重任务异常", "job", job.Code, "panic", r)
+			_ = s.entityJobService.Fail(job, fmt.Errorf("%v", r))
+		}
+	}()
+
+	if err := s.entityJobService.Start(job); err != nil {
+		s.logger.Error("更新查重任务状态失败", "job", job.Code, "err", err)
+	}
+	clusters, err := s.scanDuplicates(c, job, rule, query)
+	if err == nil {
+		err = s.matchRuleRepository.ReplaceClusters(c, job.TableCode, rule.Code, clusters)
+	}
+	if err != nil {
+		s.logger.Error("查重任务失败", "job", job.Code, "err", err)
+		if err := s.entityJobService.Fail(job, err); err != nil {
+			s.logger.Error("更新查重任务状态失败", "job", job.Code, "err", err)
+		}
+		return
+	}
+
+	records := 0
+	for _, cluster := range clusters {
+		records += cluster.Size
+	}
+	job.Succeeded = job.Processed
+	summary := fmt.Sprintf("查重完成: 扫描 %d 行, 发现 %d 组疑似重复, 涉及 %d 条记录", job.Processed, len(clusters), records)
+	if job.Message != "" {
+		summary += "; " + job.Message
+	}
+	job.Message = truncateMessage(summary, 512)
+	if err := s.entityJobService.Finish(job, ""); err != nil {
+		s.logger.Error("更新查重任务状态失败", "job", job.Code, "err", err)
+	}
+}
+
+// scanDuplicates 分批读取全表, 按分组键归组后两两比较, 相似的记录用并查集合并为分组
+func (s *entityService) scanDuplicates(c *gin.Context, job *model.EntityJob, rule *model.MatchRule, query *repository.CompiledEntityQuery) ([]*model.DuplicateCluster, error) {
+	blockFields := matchBlockFields(rule)
+	var ids []uint
+	var profiles []matchProfile
+	blocks := make(map[string][]int)
+
+	var last map[string]any
+	for {
+		rows, err := s.entityRepository.FindChunk(job.TableCode, query, last, duplicateChunkSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			id, ok := uintValue(row["id"])
+			if !ok {
+				continue
+			}
+			prefix := make([]string, len(blockFields))
+			for i, code := range blockFields {
+				prefix[i] = matchText(row[code])
+			}
+			index := len(ids)
+			ids = append(ids, id)
+			profile := newMatchProfile(rule.Fields, row)
+			profiles = append(profiles, profile)
+
+			// 分组键: 分组字段的值 + 匹配字段 + 该字段按匹配方法生成的键
+			seen := make(map[string]bool)
+			for _, field := range rule.Fields {
+				for _, key := range matchKeys(field.Method, profile[field.Field]) {
+					key = strings.Join(append(prefix, field.Field, key), "\x00")
+					if !seen[key] {
+						seen[key] = true
+						blocks[key] = append(blocks[key], index)
+					}
+				}
+			}
+		}
+		job.Processed += len(rows)
+		if err := s.entityJobService.Save(job); err != nil {
+			s.logger.Warn("保存查重进度失败", "job", job.Code, "err", err)
+		}
+		if len(rows) < duplicateChunkSize {
+			break
+		}
+		last = rows[len(rows)-1]
+	}
+
+	parent := make([]int, len(ids))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	best := make(map[int]float64) // 记录 -> 与其他记录的最高相似度
+	compared := make(map[[2]int]bool)
+	skipped := 0
+	for _, members := range blocks {
+		if len(members) < 2 {
+			continue
+		}
+		if len(members) > maxDuplicateBlock {
+			skipped++
+			continue
+		}
+		for i := 0; i < len(members); i++ {
+			for j := i + 1; j < len(members); j++ {
+				pair := [2]int{members[i], members[j]}
+				if compared[pair] {
+					continue
+				}
+				compared[pair] = true
+				score, _ := profiles[pair[0]].score(rule.Fields, profiles[pair[1]])
+				if score < rule.Threshold {
+					continue
+				}
+				for _, k := range pair {
+					best[k] = max(best[k], score)
+				}
+				parent[find(pair[0])] = find(pair[1])
+			}
+		}
+	}
+	if skipped > 0 {
+		job.Message = fmt.Sprintf("%d 个分组键的记录超过 %d 条, 未比较", skipped, maxDuplicateBlock)
+	}
+
+	groups := make(map[int][]int)
+	for k := range best {
+		root := find(k)
+		groups[root] = append(groups[root], k)
+	}
+
+	// 已排除的分组不再生成
+	dismissed, err := s.matchRuleRepository.FindClusters(map[string]any{
+		"table_code": job.TableCode,
+		"rule_code":  rule.Code,
+		"status":     model.DuplicateClusterDismissed,
+	})
+	if err != nil {
+		return nil, err
+	}
+	dismissedKeys := make(map[string]bool, len(dismissed))
+	for _, cluster := range dismissed {
+		members := make([]uint, len(cluster.Members))
+		for i, member := range cluster.Members {
+			members[i] = member.EntityID
+		}
+		dismissedKeys[clusterKey(members)] = true
+	}
+
+	clusters := make([]*model.DuplicateCluster, 0, len(groups))
+	for _, group := range groups {
+		members := make([]uint, len(group))
+		for i, k := range group {
+			members[i] = ids[k]
+		}
+		if dismissedKeys[clusterKey(members)] {
+			continue
+		}
+		cluster := &model.DuplicateCluster{
+			TableCode: job.TableCode,
+			RuleCode:  rule.Code,
+			JobCode:   job.Code,
+			Size:      len(group),
+			Status:    model.DuplicateClusterOpen,
+		}
+		for _, k := range group {
+			score := roundScore(best[k])
+			cluster.Score = max(cluster.Score, score)
+			cluster.Members = append(cluster.Members, &model.DuplicateClusterMember{EntityID: ids[k], Score: score})
+		}
+		slices.SortFunc(cluster.Members, func(a, b *model.DuplicateClusterMember) int {
+			return cmp.Compare(a.EntityID, b.EntityID)
+		})
+		clusters = append(clusters, cluster)
+	}
+	slices.SortFunc(clusters, func(a, b *model.DuplicateCluster) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.Members[0].EntityID, b.Members[0].EntityID))
+	})
+	return clusters, nil
+}
+
+// clusterKey 分组成员的唯一标识, 与成员顺序无关
+func clusterKey(members []uint) string {
+	sorted := slices.Clone(members)
+	slices.Sort(sorted)
+	parts := make([]string, len(sorted))
+	for i, id := range sorted {
+		parts[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ListDuplicateClusters 分页查询表的疑似重复分组
+func (s *entityService) ListDuplicateClusters(c *gin.Context, tableCode string, page, pageSize int, total *int64, where map[string]any) ([]*model.DuplicateCluster, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
+	}
+	if where == nil {
+		where = make(map[string]any)
+	}
+	where["table_code"] = tableCode
+	return s.matchRuleRepository.FindClusterPage(page, pageSize, total, where)
+}
+
+// GetDuplicateCluster 查询疑似重复分组及其成员记录
+func (s *entityService) GetDuplicateCluster(c *gin.Context, tableCode string, id uint) (*DuplicateClusterDetail, error) {
+	cluster, err := s.findCluster(c, tableCode, id)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(cluster.Members))
+	for i, member := range cluster.Members {
+		ids[i] = member.EntityID
+	}
+	records, err := s.entityRepository.Find(tableCode, "*", map[string]any{"id": ids})
+	if err != nil {
+		return nil, err
+	}
+	return &DuplicateClusterDetail{DuplicateCluster: cluster, Records: records}, nil
+}
+
+// ReviewDuplicateCluster 确认 (Confirmed) 或排除 (Dismissed) 疑似重复分组
+func (s *entityService) ReviewDuplicateCluster(c *gin.Context, tableCode string, id uint, status string) error {
+	if status != model.DuplicateClusterConfirmed && status != model.DuplicateClusterDismissed {
+		return fmt.Errorf("不支持的分组状态: %s", status)
+	}
+	cluster, err := s.findCluster(c, tableCode, id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	cluster.Status = status
+	cluster.ReviewedBy = c.GetString("user_name")
+	cluster.ReviewedAt = &now
+	return s.matchRuleRepository.UpdateCluster(c, cluster)
+}
+
+func (s *entityService) findCluster(c *gin.Context, tableCode string, id uint) (*model.DuplicateCluster, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
+	}
+	cluster, err := s.matchRuleRepository.FindCluster(id)
+	if err != nil {
+		return nil, err
+	}
+	if cluster.TableCode != tableCode {
+		return nil, fmt.Errorf("duplicate cluster %d does not belong to table %s", id, tableCode)
+	}
+	return cluster, nil
+}
@@ -0,0 +1,157 @@
+package service_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+	"piemdm/internal/service"
+	mock_repository "piemdm/test/mocks/repository"
+	mock_service "piemdm/test/mocks/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type duplicateMocks struct {
+	entityRepo      *mock_repository.MockEntityRepository
+	matchRuleRepo   *mock_repository.MockMatchRuleRepository
+	approvalService *mock_service.MockApprovalService
+}
+
+// setupDuplicateService 表 customer 有字段 name、city, 已有记录:
+// 1 Acme Trading Ltd / Shanghai, 2 ACME TRADING LTD. / Shanghai, 3 Acme Holdings / Shanghai
+func setupDuplicateService(t *testing.T, action string) (service.EntityService, *duplicateMocks, *gin.Context) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	m := &duplicateMocks{
+		entityRepo:      mock_repository.NewMockEntityRepository(ctrl),
+		matchRuleRepo:   mock_repository.NewMockMatchRuleRepository(ctrl),
+		approvalService: mock_service.NewMockApprovalService(ctrl),
+	}
+	expectCompileQuery(m.entityRepo)
+	tableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	tableFieldService.EXPECT().GetFieldAccess(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	tableFieldService.EXPECT().Find("code,type,field_type", map[string]any{"table_code": "customer", "status": "Normal"}).
+		Return([]*model.TableField{{Code: "name", Type: "Text"}, {Code: "city", Type: "Text"}}, nil).AnyTimes()
+	permissionService := mock_service.NewMockTablePermissionService(ctrl)
+	permissionService.EXPECT().CheckTablePermission(gomock.Any(), uint(1), "customer").Return(true, nil).AnyTimes()
+	autocodeService := mock_service.NewMockAutocodeService(ctrl)
+	autocodeService.EXPECT().GenerateOrRestoreAutocodes(gomock.Any(), "customer", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	m.matchRuleRepo.EXPECT().Find(map[string]any{"table_code": "customer", "status": "Normal"}).Return([]*model.MatchRule{{
+		Code:        "customer_name",
+		TableCode:   "customer",
+		Fields:      model.MatchFields{{Field: "name", Method: model.MatchMethodNormalized, Weight: 1}},
+		BlockFields: "city",
+		Threshold:   0.9,
+		Action:      action,
+	}}, nil).AnyTimes()
+
+	existing := []map[string]any{
+		{"id": uint64(1), "name": "Acme Trading Ltd", "city": "Shanghai"},
+		{"id": uint64(2), "name": "ACME TRADING LTD.", "city": "Shanghai"},
+		{"id": uint64(3), "name": "Acme Holdings", "city": "Shanghai"},
+	}
+	m.entityRepo.EXPECT().FindChunk("customer", gomock.Any(), nil, gomock.Any()).
+		DoAndReturn(func(_ string, query *repository.CompiledEntityQuery, _ map[string]any, _ int) ([]map[string]any, error) {
+			// 按分组字段和匹配字段的首字符筛选候选记录
+			assert.Contains(t, query.Values, "Shanghai")
+			return existing, nil
+		}).AnyTimes()
+	m.entityRepo.EXPECT().Find("customer", "*", gomock.Any()).
+		DoAndReturn(func(_, _ string, where map[string]any) ([]map[string]any, error) {
+			var rows []map[string]any
+			for _, id := range where["id"].([]uint) {
+				rows = append(rows, existing[id-1])
+			}
+			return rows, nil
+		}).AnyTimes()
+
+	s := service.NewEntityService(
+		service.NewService(testLogger, nil, nil),
+		m.entityRepo,
+		tableFieldService,
+		nil,
+		nil,
+		m.approvalService,
+		nil,
+		nil,
+		autocodeService,
+		permissionService,
+		nil,
+		nil,
+		m.matchRuleRepo,
+		nil,
+		nil,
+		nil,
+	)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_id", uint(1))
+	c.Set("user_name", "tester")
+	return s, m, c
+}
+
+func TestEntityService_CheckDuplicates(t *testing.T) {
+	s, _, c := setupDuplicateService(t, model.MatchActionWarn)
+
+	// 修改记录 1 时不返回记录本身
+	candidates, err := s.CheckDuplicates(c, "customer", map[string]any{"id": uint(1), "name": "acme trading ltd", "city": "Shanghai"})
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+	assert.Equal(t, uint(2), candidates[0].ID)
+	assert.Equal(t, "customer_name", candidates[0].Rule)
+	assert.Equal(t, 1.0, candidates[0].Score)
+	assert.Equal(t, map[string]float64{"name": 1}, candidates[0].Fields)
+	assert.Equal(t, "ACME TRADING LTD.", candidates[0].Record["name"])
+}
+
+func TestEntityService_CreateDraft_Duplicates(t *testing.T) {
+	record := func() map[string]any {
+		return map[string]any{"name": "Acme Trading, Ltd", "city": "Shanghai"}
+	}
+
+	t.Run("命中 Warn 规则时返回疑似重复", func(t *testing.T) {
+		s, _, c := setupDuplicateService(t, model.MatchActionWarn)
+
+		err := s.CreateDraft(c, "customer", "新客户", record())
+		var duplicateErr *service.DuplicateError
+		require.True(t, errors.As(err, &duplicateErr), "err = %v", err)
+		assert.False(t, duplicateErr.Blocked)
+		require.Len(t, duplicateErr.Candidates, 2)
+		assert.Equal(t, uint(1), duplicateErr.Candidates[0].ID)
+		assert.Equal(t, uint(2), duplicateErr.Candidates[1].ID)
+	})
+
+	t.Run("确认忽略 Warn 规则后保存", func(t *testing.T) {
+		s, m, c := setupDuplicateService(t, model.MatchActionWarn)
+		c.Set("ignore_duplicates", true)
+		m.approvalService.EXPECT().CreateDraftWithApproval(c, "customer", "新客户", gomock.Any()).Return(nil)
+
+		require.NoError(t, s.CreateDraft(c, "customer", "新客户", record()))
+	})
+
+	t.Run("命中 Block 规则时不能忽略", func(t *testing.T) {
+		s, _, c := setupDuplicateService(t, model.MatchActionBlock)
+		c.Set("ignore_duplicates", true)
+
+		err := s.CreateDraft(c, "customer", "新客户", record())
+		var duplicateErr *service.DuplicateError
+		require.True(t, errors.As(err, &duplicateErr), "err = %v", err)
+		assert.True(t, duplicateErr.Blocked)
+	})
+
+	t.Run("没有相似的记录时保存", func(t *testing.T) {
+		s, m, c := setupDuplicateService(t, model.MatchActionBlock)
+		m.approvalService.EXPECT().CreateDraftWithApproval(c, "customer", "新客户", gomock.Any()).Return(nil)
+
+		require.NoError(t, s.CreateDraft(c, "customer", "新客户", map[string]any{"name": "Acme", "city": "Shanghai"}))
+	})
+}
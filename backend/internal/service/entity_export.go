@@ -0,0 +1,536 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// exportChunkSize 导出每批读取的行数
+const exportChunkSize = 1000
+
+// exportLabelSuffix 显示名称列后缀: 选择、关联字段在值列之后追加 <code>_label 列, 导入时忽略
+const exportLabelSuffix = "_label"
+
+// labelColumnPattern 关联配置中的字段编码格式
+var labelColumnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ExportOptions 导出参数
+type ExportOptions struct {
+	Labels    bool // 选择、关联字段附加显示名称列
+	Items     bool // 单据抬头表附加行项目列 (items), 值为按行项目表分组的 JSON
+	Relations bool // 附加关联视图列 (relations), 值为按关联模型分组的 JSON, 只包含当前用户有权限访问的关联模型
+	Async     bool // 后台执行, 立即返回任务, 完成后通过任务查询下载地址
+	File      FileOptions
+}
+
+// exportJob 一次导出任务的上下文
+type exportJob struct {
+	job     *model.EntityJob
+	file    FileOptions
+	query   *repository.CompiledEntityQuery
+	columns []exportColumn
+	labels  map[string]*labelSource // 字段编码 -> 显示名称来源
+	items   []*exportItems          // 行项目表, 不导出行项目时为空
+	views   []*relationView         // 关联视图, 不导出关联视图时为空
+}
+
+// exportItems 导出的行项目表及其值列
+type exportItems struct {
+	table   *itemTable
+	columns []exportColumn
+}
+
+// exportColumn 导出的值列
+type exportColumn struct {
+	code     string
+	dataType string
+}
+
+// labelSource 选择、关联字段的显示名称来源: 静态选项或关联表
+type labelSource struct {
+	target     string         // 关联表编码, 静态选项为空
+	valueField string         // 关联表中存储值的字段
+	labelField string         // 关联表中显示名称的字段
+	filter     map[string]any // 关联表过滤条件, 如字典编码
+	labels     map[string]string
+}
+
+// Export 按列表查询条件导出数据, 与列表使用相同的过滤、排序和字段选择
+// 按排序字段游标分批读取并逐批写入文件, 不一次性加载全部数据; 结果文件到期后自动清理
+func (s *entityService) Export(c *gin.Context, tableCode string, query *model.EntityQuery, opts ExportOptions) (*model.EntityJob, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
+	}
+
+	var views []*relationView
+	if opts.Relations {
+		var err error
+		if views, err = s.relationViews(c, tableCode); err != nil {
+			return nil, err
+		}
+	}
+	ej, err := s.prepareExport(c, tableCode, query, opts, views)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.entityRepository.Count(tableCode, ej.query)
+	if err != nil {
+		return nil, err
+	}
+	// 表头占一行
+	if opts.File.Ext() == FileFormatXLSX && total >= excelize.TotalRows {
+		return nil, fmt.Errorf("%w: 共 %d 条数据, %v", model.ErrInvalidQuery, total, errXLSXRowLimit)
+	}
+	ej.job = &model.EntityJob{
+		Type:      model.EntityJobTypeExport,
+		TableCode: tableCode,
+		Format:    opts.File.Ext(),
+		Total:     int(total),
+	}
+	if err := s.entityJobService.Create(c, ej.job); err != nil {
+		return nil, err
+	}
+
+	if opts.Async {
+		queued := snapshotJob(ej.job)
+		go s.runExportJob(ej)
+		return queued, nil
+	}
+
+	if err := s.executeExportJob(ej); err != nil {
+		if ferr := s.entityJobService.Fail(ej.job, err); ferr != nil {
+			s.logger.Error("更新导出任务状态失败", "job", ej.job.Code, "err", ferr)
+		}
+		return nil, err
+	}
+	return ej.job, nil
+}
+
+// prepareExport 确定导出列并编译查询
+// 未指定 fields 时导出 id 及当前用户可以查看的全部已发布字段; 指定时按指定顺序导出, id 始终在第一列
+func (s *entityService) prepareExport(c *gin.Context, tableCode string, query *model.EntityQuery, opts ExportOptions, views []*relationView) (*exportJob, error) {
+	tableFields, err := s.tableFieldService.Find("*", map[string]any{
+		"table_code": tableCode,
+		"status":     "Normal",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取表字段失败: %v", err)
+	}
+	fields := make(map[string]*model.TableField, len(tableFields))
+	for _, field := range tableFields {
+		fields[field.Code] = field
+	}
+
+	columnTypes, err := s.queryColumns(tableCode)
+	if err != nil {
+		return nil, err
+	}
+	access, err := s.tableFieldService.GetFieldAccess(c, tableCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if query == nil {
+		query = &model.EntityQuery{}
+	}
+	// 过滤、排序和导出字段只能使用当前用户可以查看的字段
+	if _, err := s.entityRepository.CompileQuery(tableCode, query, hideColumns(columnTypes, access), "t"); err != nil {
+		return nil, err
+	}
+	codes := []string{"id"}
+	if len(query.Fields) == 0 {
+		for _, field := range tableFields {
+			if access != nil && access.Hidden[field.Code] {
+				continue
+			}
+			codes = append(codes, field.Code)
+		}
+	} else {
+		for _, code := range query.Fields {
+			if !slices.Contains(codes, code) {
+				codes = append(codes, code)
+			}
+		}
+	}
+
+	var items []*exportItems
+	if opts.Items {
+		if items, err = s.prepareExportItems(c, tableCode); err != nil {
+			return nil, err
+		}
+	}
+
+	// 游标分批读取需要排序字段的值, 查询行项目和关联记录需要被关联字段的值
+	compileQuery := *query
+	if len(query.Fields) > 0 {
+		compileQuery.Fields = slices.Clone(codes)
+		for _, sort := range query.Sort {
+			if !slices.Contains(compileQuery.Fields, sort.Field) {
+				compileQuery.Fields = append(compileQuery.Fields, sort.Field)
+			}
+		}
+		for _, item := range items {
+			if !slices.Contains(compileQuery.Fields, item.table.parentField) {
+				compileQuery.Fields = append(compileQuery.Fields, item.table.parentField)
+			}
+		}
+		for _, view := range views {
+			if !slices.Contains(compileQuery.Fields, view.reference.valueField) {
+				compileQuery.Fields = append(compileQuery.Fields, view.reference.valueField)
+			}
+		}
+	}
+	compiled, err := s.entityRepository.CompileQuery(tableCode, &compileQuery, columnTypes, "t")
+	if err != nil {
+		return nil, err
+	}
+
+	ej := &exportJob{
+		file:   opts.File,
+		query:  compiled,
+		labels: make(map[string]*labelSource),
+		items:  items,
+		views:  views,
+	}
+	for _, code := range codes {
+		dataType := columnTypes[code]
+		if field, ok := fields[code]; ok {
+			dataType = fieldDataType(field)
+			if opts.Labels {
+				if source := newLabelSource(field); source != nil {
+					ej.labels[code] = source
+				}
+			}
+		}
+		ej.columns = append(ej.columns, exportColumn{code: code, dataType: dataType})
+	}
+	return ej, nil
+}
+
+// runExportJob 后台执行导出
+func (s *entityService) runExportJob(ej *exportJob) {
+	job := ej.job
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("导出任务异常", "job", job.Code, "panic", r)
+			_ = s.entityJobService.Fail(job, fmt.Errorf("%v", r))
+		}
+	}()
+
+	if err := s.executeExportJob(ej); err != nil {
+		s.logger.Error("导出任务失败", "job", job.Code, "err", err)
+		if err := s.entityJobService.Fail(job, err); err != nil {
+			s.logger.Error("更新导出任务状态失败", "job", job.Code, "err", err)
+		}
+	}
+}
+
+func (s *entityService) executeExportJob(ej *exportJob) error {
+	job := ej.job
+	if err := s.entityJobService.Start(job); err != nil {
+		s.logger.Error("更新导出任务状态失败", "job", job.Code, "err", err)
+	}
+
+	header := make([]string, 0, len(ej.columns)+len(ej.labels))
+	for _, column := range ej.columns {
+		header = append(header, column.code)
+		if _, ok := ej.labels[column.code]; ok {
+			header = append(header, column.code+exportLabelSuffix)
+		}
+	}
+	if len(ej.items) > 0 {
+		header = append(header, documentItemsColumn)
+	}
+	if len(ej.views) > 0 {
+		header = append(header, relationsColumn)
+	}
+
+	filename := fmt.Sprintf("%s-export-%s.%s", job.TableCode, strings.ToLower(job.Code), ej.file.Ext())
+	fullPath := s.entityJobService.ResultPath(filename)
+	w, err := newRecordWriter(fullPath, ej.file, header)
+	if err != nil {
+		return err
+	}
+	if err := s.writeExportRows(ej, w); err != nil {
+		w.Close()
+		os.Remove(fullPath)
+		return err
+	}
+	if err := w.Close(); err != nil {
+		os.Remove(fullPath)
+		return err
+	}
+
+	job.Succeeded = job.Processed
+	job.Message = fmt.Sprintf("导出完成: %d 行", job.Processed)
+	return s.entityJobService.Finish(job, filename)
+}
+
+// writeExportRows 按游标分批读取并写入, 每批保存一次进度
+func (s *entityService) writeExportRows(ej *exportJob, w recordWriter) error {
+	job := ej.job
+	values := make([]any, 0, len(ej.columns)+len(ej.labels))
+	var last map[string]any
+	for {
+		rows, err := s.entityRepository.FindChunk(job.TableCode, ej.query, last, exportChunkSize)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		if err := s.loadLabels(ej.labels, rows); err != nil {
+			return err
+		}
+		lines, err := s.loadItemLines(ej.items, rows)
+		if err != nil {
+			return err
+		}
+		related, err := s.loadRelationRecords(ej.views, rows)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			values = values[:0]
+			for _, column := range ej.columns {
+				values = append(values, exportFieldValue(column.dataType, row[column.code]))
+				if source, ok := ej.labels[column.code]; ok {
+					values = append(values, source.label(row[column.code]))
+				}
+			}
+			if len(ej.items) > 0 {
+				data, err := json.Marshal(documentItems(ej.items, lines, row))
+				if err != nil {
+					return err
+				}
+				values = append(values, json.RawMessage(data))
+			}
+			if len(ej.views) > 0 {
+				data, err := json.Marshal(relationRecords(ej.views, related, row))
+				if err != nil {
+					return err
+				}
+				values = append(values, json.RawMessage(data))
+			}
+			if err := w.Write(values); err != nil {
+				return err
+			}
+		}
+
+		job.Processed += len(rows)
+		if err := s.entityJobService.Save(job); err != nil {
+			s.logger.Warn("保存导出进度失败", "job", job.Code, "err", err)
+		}
+		if len(rows) < exportChunkSize {
+			return nil
+		}
+		last = rows[len(rows)-1]
+	}
+}
+
+// prepareExportItems 确定行项目表及其导出列 (id 及全部已发布字段)
+func (s *entityService) prepareExportItems(c *gin.Context, tableCode string) ([]*exportItems, error) {
+	tables, err := s.documentTables(tableCode)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*exportItems, 0, len(tables))
+	for _, table := range tables {
+		fields, err := s.tableFieldService.Find("*", map[string]any{"table_code": table.code, "status": "Normal"})
+		if err != nil {
+			return nil, fmt.Errorf("获取表字段失败: %v", err)
+		}
+		access, err := s.tableFieldService.GetFieldAccess(c, table.code)
+		if err != nil {
+			return nil, err
+		}
+		columns := []exportColumn{{code: "id", dataType: "Number"}}
+		for _, field := range fields {
+			if access != nil && access.Hidden[field.Code] {
+				continue
+			}
+			columns = append(columns, exportColumn{code: field.Code, dataType: fieldDataType(field)})
+		}
+		items = append(items, &exportItems{table: table, columns: columns})
+	}
+	return items, nil
+}
+
+// loadItemLines 查询本批抬头的行项目, 按行项目表和抬头关联字段的值分组
+func (s *entityService) loadItemLines(items []*exportItems, rows []map[string]any) (map[string]map[string][]map[string]any, error) {
+	lines := make(map[string]map[string][]map[string]any, len(items))
+	for _, item := range items {
+		var keys []string
+		for _, row := range rows {
+			if key := documentKey(row[item.table.parentField]); key != nil && key != "" {
+				keys = append(keys, fmt.Sprintf("%v", key))
+			}
+		}
+		grouped := make(map[string][]map[string]any)
+		lines[item.table.code] = grouped
+		if len(keys) == 0 {
+			continue
+		}
+		found, err := s.entityRepository.Find(item.table.code, "*", map[string]any{item.table.selfField: keys})
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range found {
+			key := fmt.Sprintf("%v", documentKey(line[item.table.selfField]))
+			values := make(map[string]any, len(item.columns))
+			for _, column := range item.columns {
+				values[column.code] = exportFieldValue(column.dataType, line[column.code])
+			}
+			grouped[key] = append(grouped[key], values)
+		}
+	}
+	return lines, nil
+}
+
+// documentItems 抬头记录的行项目, 没有行项目的表为空数组
+func documentItems(items []*exportItems, lines map[string]map[string][]map[string]any, row map[string]any) map[string][]map[string]any {
+	result := make(map[string][]map[string]any, len(items))
+	for _, item := range items {
+		key := fmt.Sprintf("%v", documentKey(row[item.table.parentField]))
+		result[item.table.code] = lines[item.table.code][key]
+		if result[item.table.code] == nil {
+			result[item.table.code] = []map[string]any{}
+		}
+	}
+	return result
+}
+
+// newLabelSource 根据字段配置确定显示名称来源, 非选择、关联字段返回 nil
+func newLabelSource(field *model.TableField) *labelSource {
+	options := field.Options
+	if options == nil {
+		return nil
+	}
+
+	source := &labelSource{valueField: "code", labelField: "name", labels: make(map[string]string)}
+	switch {
+	case options.Relation != nil && options.Relation.Target != "":
+		source.target = options.Relation.Target
+		source.filter = options.Relation.Filter
+		if options.Relation.ValueField != "" {
+			source.valueField = options.Relation.ValueField
+		}
+		if options.Relation.LabelField != "" {
+			source.labelField = options.Relation.LabelField
+		}
+	case options.DataSource != nil && options.DataSource.TargetTable != "":
+		source.target = options.DataSource.TargetTable
+		source.filter = options.DataSource.Filter
+		if options.DataSource.ValueField != "" {
+			source.valueField = options.DataSource.ValueField
+		}
+		if options.DataSource.LabelField != "" {
+			source.labelField = options.DataSource.LabelField
+		}
+	case options.DataSource != nil && len(options.DataSource.Options) > 0:
+		for _, item := range options.DataSource.Options {
+			source.labels[fmt.Sprintf("%v", item.Value)] = item.Label
+		}
+		return source
+	default:
+		return nil
+	}
+
+	if !labelColumnPattern.MatchString(source.target) ||
+		!labelColumnPattern.MatchString(source.valueField) ||
+		!labelColumnPattern.MatchString(source.labelField) {
+		return nil
+	}
+	return source
+}
+
+// loadLabels 查询本批数据中尚未加载的关联值的显示名称, 已查询过的值不再重复查询
+func (s *entityService) loadLabels(sources map[string]*labelSource, rows []map[string]any) error {
+	for code, source := range sources {
+		if source.target == "" {
+			continue
+		}
+
+		var missing []string
+		for _, row := range rows {
+			for _, value := range labelValues(row[code]) {
+				if _, ok := source.labels[value]; !ok && !slices.Contains(missing, value) {
+					missing = append(missing, value)
+				}
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		where := make(map[string]any, len(source.filter)+1)
+		for k, v := range source.filter {
+			where[k] = v
+		}
+		where[source.valueField] = missing
+		items, err := s.entityRepository.Find(source.target, source.valueField+","+source.labelField, where)
+		if err != nil {
+			return err
+		}
+		// 未找到的值也记录下来, 避免每批重复查询
+		for _, value := range missing {
+			source.labels[value] = ""
+		}
+		for _, item := range items {
+			source.labels[fmt.Sprintf("%v", exportFieldValue("Text", item[source.valueField]))] =
+				fmt.Sprintf("%v", exportFieldValue("Text", item[source.labelField]))
+		}
+	}
+	return nil
+}
+
+// label 字段值对应的显示名称, 多选值以逗号分隔
+func (l *labelSource) label(value any) string {
+	var labels []string
+	for _, v := range labelValues(value) {
+		if label := l.labels[v]; label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return strings.Join(labels, ", ")
+}
+
+// labelValues 拆分字段值, 多选字段以 JSON 数组字符串存储
+func labelValues(value any) []string {
+	str, ok := exportFieldValue("Text", value).(string)
+	if !ok {
+		if value == nil {
+			return nil
+		}
+		str = fmt.Sprintf("%v", value)
+	}
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return nil
+	}
+	if strings.HasPrefix(str, "[") {
+		var items []any
+		if err := json.Unmarshal([]byte(str), &items); err == nil {
+			values := make([]string, 0, len(items))
+			for _, item := range items {
+				if item != nil {
+					values = append(values, fmt.Sprintf("%v", item))
+				}
+			}
+			return values
+		}
+	}
+	return []string{str}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+
+	"piemdm/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLabelSource(t *testing.T) {
+	relation := newLabelSource(&model.TableField{Options: &model.FieldOptions{
+		Relation: &model.FieldRelation{Target: "dict_item", Filter: map[string]any{"dict_code": "color"}},
+	}})
+	require.NotNil(t, relation)
+	assert.Equal(t, "dict_item", relation.target)
+	assert.Equal(t, "code", relation.valueField)
+	assert.Equal(t, "name", relation.labelField)
+	assert.Equal(t, map[string]any{"dict_code": "color"}, relation.filter)
+
+	static := newLabelSource(&model.TableField{Options: &model.FieldOptions{
+		DataSource: &model.FieldDataSource{Type: "static", Options: []model.OptionItem{
+			{Label: "Red", Value: "R"}, {Label: "Blue", Value: 2},
+		}},
+	}})
+	require.NotNil(t, static)
+	assert.Equal(t, "Red", static.label("R"))
+	assert.Equal(t, "Red, Blue", static.label(`["R", 2]`))
+	assert.Equal(t, "", static.label("X"))
+	assert.Equal(t, "", static.label(nil))
+
+	// 非选择、关联字段, 以及关联配置中不合法的字段名
+	assert.Nil(t, newLabelSource(&model.TableField{}))
+	assert.Nil(t, newLabelSource(&model.TableField{Options: &model.FieldOptions{
+		Relation: &model.FieldRelation{Target: "item", LabelField: "name; drop table t"},
+	}}))
+}
+
+func TestLabelValues(t *testing.T) {
+	assert.Nil(t, labelValues(nil))
+	assert.Nil(t, labelValues(" "))
+	assert.Equal(t, []string{"A"}, labelValues([]byte("A")))
+	assert.Equal(t, []string{"A", "B"}, labelValues(`["A","B"]`))
+	assert.Equal(t, []string{"[not json"}, labelValues("[not json"))
+	assert.Equal(t, []string{"12"}, labelValues(int64(12)))
+}
@@ -0,0 +1,779 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"piemdm/internal/constants"
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+// 导入导出文件格式
+const (
+	FileFormatXLSX   = "xlsx"
+	FileFormatCSV    = "csv"
+	FileFormatJSON   = "json"   // JSON 数组, 每个元素为一条记录
+	FileFormatNDJSON = "ndjson" // 每行一个 JSON 对象
+)
+
+// CSV 文件编码
+const (
+	FileEncodingUTF8    = "utf-8"
+	FileEncodingUTF8BOM = "utf-8-bom" // 带 BOM, Excel 直接打开不乱码
+	FileEncodingGBK     = "gbk"
+	FileEncodingGB18030 = "gb18030"
+)
+
+// 日期、日期时间字段在文件中的格式
+const (
+	fileDateLayout     = "2006-01-02"
+	fileDateTimeLayout = "2006-01-02 15:04:05"
+)
+
+// fileDateLayouts 导入时日期、日期时间字段支持的输入格式
+var fileDateLayouts = []string{
+	fileDateTimeLayout,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	fileDateLayout,
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// FileOptions 导入导出文件格式参数
+type FileOptions struct {
+	Format    string // xlsx csv json ndjson, 默认 xlsx
+	Delimiter rune   // CSV 分隔符, 默认逗号
+	Encoding  string // CSV 编码: utf-8 utf-8-bom gbk gb18030, 默认 utf-8
+}
+
+// ParseFileOptions 解析并校验文件格式参数, 空值使用默认值
+// delimiter 支持单个字符, 以及 tab、\t 表示制表符
+func ParseFileOptions(format, delimiter, encoding string) (FileOptions, error) {
+	opts := FileOptions{
+		Format:    strings.ToLower(strings.TrimSpace(format)),
+		Delimiter: ',',
+		Encoding:  strings.ToLower(strings.TrimSpace(encoding)),
+	}
+
+	switch opts.Format {
+	case "":
+		opts.Format = FileFormatXLSX
+	case FileFormatXLSX, FileFormatCSV, FileFormatJSON, FileFormatNDJSON:
+	default:
+		return opts, fmt.Errorf("unsupported file format: %s, expected one of xlsx, csv, json, ndjson", format)
+	}
+
+	switch delimiter {
+	case "":
+	case "tab", `\t`:
+		opts.Delimiter = '\t'
+	default:
+		r, size := utf8.DecodeRuneInString(delimiter)
+		if size != len(delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+			return opts, fmt.Errorf("invalid csv delimiter: %q", delimiter)
+		}
+		opts.Delimiter = r
+	}
+
+	switch opts.Encoding {
+	case "", "utf8":
+		opts.Encoding = FileEncodingUTF8
+	case FileEncodingUTF8, FileEncodingUTF8BOM, FileEncodingGBK, FileEncodingGB18030:
+	default:
+		return opts, fmt.Errorf("unsupported csv encoding: %s, expected one of utf-8, utf-8-bom, gbk, gb18030", encoding)
+	}
+
+	return opts, nil
+}
+
+// DetectFileFormat 根据文件扩展名推断格式, 无法识别时返回空字符串
+func DetectFileFormat(filename string) string {
+	switch ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), ".")); ext {
+	case FileFormatXLSX, FileFormatCSV, FileFormatJSON, FileFormatNDJSON:
+		return ext
+	case "jsonl":
+		return FileFormatNDJSON
+	}
+	return ""
+}
+
+// Ext 文件扩展名 (不含点)
+func (o FileOptions) Ext() string {
+	if o.Format == "" {
+		return FileFormatXLSX
+	}
+	return o.Format
+}
+
+func (o FileOptions) textEncoding() encoding.Encoding {
+	switch o.Encoding {
+	case FileEncodingGBK:
+		return simplifiedchinese.GBK
+	case FileEncodingGB18030:
+		return simplifiedchinese.GB18030
+	}
+	return nil
+}
+
+// fileRecord 从文件读取的一条记录
+type fileRecord struct {
+	Line  int      // 行号 (表头为第 1 行); JSON 数组为记录序号
+	Cells []string // 按表头顺序排列的单元格
+}
+
+// readRecords 按格式读取文件, 返回表头及数据记录
+// JSON / NDJSON 以各记录键的并集 (按首次出现顺序) 作为表头, 值统一转换为字符串, 再按字段类型转换
+func readRecords(opts FileOptions, r io.Reader) ([]string, []fileRecord, error) {
+	switch opts.Format {
+	case FileFormatCSV:
+		return readCSVRecords(opts, r)
+	case FileFormatJSON, FileFormatNDJSON:
+		return readJSONRecords(opts, r)
+	default:
+		return readXLSXRecords(r)
+	}
+}
+
+func readXLSXRecords(r io.Reader) ([]string, []fileRecord, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	sheetRows, err := file.GetRows(xlsxSheet)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(sheetRows) == 0 {
+		return nil, nil, errors.New("导入文件 Sheet1 为空")
+	}
+
+	records := make([]fileRecord, 0, len(sheetRows)-1)
+	for i, cells := range sheetRows[1:] {
+		records = append(records, fileRecord{Line: i + 2, Cells: cells})
+	}
+	return sheetRows[0], records, nil
+}
+
+func readCSVRecords(opts FileOptions, r io.Reader) ([]string, []fileRecord, error) {
+	if enc := opts.textEncoding(); enc != nil {
+		r = transform.NewReader(r, enc.NewDecoder())
+	}
+	br := bufio.NewReader(r)
+	// 忽略 UTF-8 BOM
+	if head, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(head, utf8BOM) {
+		_, _ = br.Discard(len(utf8BOM))
+	}
+
+	reader := csv.NewReader(br)
+	reader.Comma = opts.Delimiter
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("导入文件为空")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var records []fileRecord
+	for {
+		cells, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, fileRecord{Line: line, Cells: cells})
+	}
+	return header, records, nil
+}
+
+func readJSONRecords(opts FileOptions, r io.Reader) ([]string, []fileRecord, error) {
+	var header []string
+	columns := make(map[string]int)
+	var objects []map[string]any
+	var lines []int
+
+	addObject := func(line int, keys []string, values map[string]any) {
+		for _, key := range keys {
+			if _, ok := columns[key]; !ok {
+				columns[key] = len(header)
+				header = append(header, key)
+			}
+		}
+		objects = append(objects, values)
+		lines = append(lines, line)
+	}
+
+	if opts.Format == FileFormatNDJSON {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			text := bytes.TrimSpace(scanner.Bytes())
+			if line == 1 {
+				text = bytes.TrimPrefix(text, utf8BOM)
+			}
+			if len(text) == 0 {
+				continue
+			}
+			dec := json.NewDecoder(bytes.NewReader(text))
+			dec.UseNumber()
+			keys, values, err := decodeJSONObject(dec)
+			if err != nil {
+				return nil, nil, fmt.Errorf("第 %d 行不是有效的 JSON 对象: %v", line, err)
+			}
+			addObject(line, keys, values)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		br := bufio.NewReader(r)
+		if head, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(head, utf8BOM) {
+			_, _ = br.Discard(len(utf8BOM))
+		}
+		dec := json.NewDecoder(br)
+		dec.UseNumber()
+		if err := expectJSONDelim(dec, '['); err != nil {
+			return nil, nil, fmt.Errorf("JSON 文件必须是对象数组: %v", err)
+		}
+		for index := 1; dec.More(); index++ {
+			keys, values, err := decodeJSONObject(dec)
+			if err != nil {
+				return nil, nil, fmt.Errorf("第 %d 条记录不是有效的 JSON 对象: %v", index, err)
+			}
+			addObject(index, keys, values)
+		}
+		if err := expectJSONDelim(dec, ']'); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(header) == 0 {
+		return nil, nil, errors.New("导入文件为空")
+	}
+
+	records := make([]fileRecord, 0, len(objects))
+	for i, values := range objects {
+		cells := make([]string, len(header))
+		for key, value := range values {
+			cells[columns[key]] = jsonCellString(value)
+		}
+		records = append(records, fileRecord{Line: lines[i], Cells: cells})
+	}
+	return header, records, nil
+}
+
+// decodeJSONObject 读取一个 JSON 对象, 保留键的出现顺序
+func decodeJSONObject(dec *json.Decoder) ([]string, map[string]any, error) {
+	if err := expectJSONDelim(dec, '{'); err != nil {
+		return nil, nil, err
+	}
+	var keys []string
+	values := make(map[string]any)
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("unexpected token %v", token)
+		}
+		var value any
+		if err := dec.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = value
+	}
+	if err := expectJSONDelim(dec, '}'); err != nil {
+		return nil, nil, err
+	}
+	return keys, values, nil
+}
+
+func expectJSONDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := token.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected %v, got %v", delim, token)
+	}
+	return nil
+}
+
+// jsonCellString 将 JSON 值转换为单元格字符串, 对象和数组保留为紧凑 JSON
+func jsonCellString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(b)
+	}
+}
+
+// fieldDataType 字段的数据类型: Text Number Date DateTime
+// 未设置 Type 时根据 FieldType 对应的预设推断
+func fieldDataType(field *model.TableField) string {
+	if field.Type != "" {
+		return field.Type
+	}
+	if preset, ok := constants.GetFieldPreset(field.FieldType); ok && preset.DataType != "" {
+		return preset.DataType
+	}
+	return "Text"
+}
+
+// coerceImportValue 按字段数据类型转换导入值, 各种文件格式共用
+// 数值字段转换为 int64 或 float64, 日期字段统一为 2006-01-02 / 2006-01-02 15:04:05, 空值转换为 nil
+func coerceImportValue(field *model.TableField, cell string) (any, error) {
+	// 多对多字段: JSON 数组或逗号分隔的目标编码
+	if field.FieldType == "manytomany" {
+		return repository.ParseLinkValues(cell), nil
+	}
+	dataType := fieldDataType(field)
+	if dataType == "Text" {
+		return cell, nil
+	}
+
+	value := strings.TrimSpace(cell)
+	if value == "" {
+		return nil, nil
+	}
+
+	switch dataType {
+	case "Number":
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("字段 '%s' 必须是数字, 实际为 %q", field.Name, cell)
+		}
+		return f, nil
+	case "Date", "DateTime":
+		for _, layout := range fileDateLayouts {
+			t, err := time.ParseInLocation(layout, value, time.Local)
+			if err != nil {
+				continue
+			}
+			if dataType == "Date" {
+				return t.Format(fileDateLayout), nil
+			}
+			return t.Format(fileDateTimeLayout), nil
+		}
+		return nil, fmt.Errorf("字段 '%s' 日期格式不正确, 应为 2006-01-02 或 2006-01-02 15:04:05, 实际为 %q", field.Name, cell)
+	}
+	return cell, nil
+}
+
+// exportFieldValue 按字段数据类型转换导出值, 各种文件格式共用
+// 数值保持数字类型 (JSON 中输出为 number), 日期格式与导入一致, 保证导出文件可直接重新导入
+func exportFieldValue(dataType string, value any) any {
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+	if value == nil {
+		return nil
+	}
+
+	switch dataType {
+	case "Number":
+		switch v := value.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			return v
+		case string:
+			if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return i
+			}
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f
+			}
+		}
+	case "JSON":
+		// JSON 字段在 JSON 导出文件中输出为嵌套的值, 在 xlsx / csv 中为 JSON 文本
+		if s, ok := value.(string); ok && json.Valid([]byte(s)) {
+			return json.RawMessage(s)
+		}
+	case "Date", "DateTime":
+		layout := fileDateTimeLayout
+		if dataType == "Date" {
+			layout = fileDateLayout
+		}
+		switch v := value.(type) {
+		case time.Time:
+			return v.Format(layout)
+		case *time.Time:
+			if v == nil {
+				return nil
+			}
+			return v.Format(layout)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return v
+	case []string:
+		// 多对多字段的目标编码, 与导入时的 JSON 数组格式一致
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// formatCellValue 将导出值转换为 xlsx / csv 单元格文本
+func formatCellValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.RawMessage:
+		return string(v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// recordWriter 按行写出导出、模板及导入结果文件
+type recordWriter interface {
+	// Write 写入一条记录, 值与表头一一对应
+	Write(values []any) error
+	Close() error
+}
+
+// newRecordWriter 创建指定格式的文件, xlsx / csv 立即写入表头, JSON 以表头作为对象键
+func newRecordWriter(fullPath string, opts FileOptions, header []string) (recordWriter, error) {
+	if opts.Format == FileFormatXLSX || opts.Format == "" {
+		return newXLSXRecordWriter(fullPath, header)
+	}
+
+	file, err := os.Create(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriter(file)
+
+	switch opts.Format {
+	case FileFormatCSV:
+		var w io.Writer = buf
+		var closers []io.Closer
+		switch opts.Encoding {
+		case FileEncodingUTF8BOM:
+			if _, err := buf.Write(utf8BOM); err != nil {
+				file.Close()
+				return nil, err
+			}
+		case FileEncodingGBK, FileEncodingGB18030:
+			tw := transform.NewWriter(buf, opts.textEncoding().NewEncoder())
+			w = tw
+			closers = append(closers, tw)
+		}
+		cw := csv.NewWriter(w)
+		cw.Comma = opts.Delimiter
+		rw := &csvRecordWriter{file: file, buf: buf, csv: cw, closers: closers}
+		if err := cw.Write(header); err != nil {
+			rw.Close()
+			return nil, err
+		}
+		return rw, nil
+	default:
+		rw := &jsonRecordWriter{file: file, buf: buf, header: header, lines: opts.Format == FileFormatNDJSON}
+		if !rw.lines {
+			if _, err := buf.WriteString("["); err != nil {
+				file.Close()
+				return nil, err
+			}
+		}
+		return rw, nil
+	}
+}
+
+// xlsxRecordWriter 使用与读取相同的 excelize 写入, 保证空单元格重新上传后仍为空
+// 按行流式写入, 数据较多时 excelize 将已写入的行暂存到临时文件, 不在内存中保留整个工作表
+type xlsxRecordWriter struct {
+	fullPath string
+	file     *excelize.File
+	stream   *excelize.StreamWriter
+	line     int
+	failed   int // 失败行样式
+}
+
+const xlsxSheet = "Sheet1"
+
+// errXLSXRowLimit 超出 xlsx 单个工作表的行数上限
+var errXLSXRowLimit = fmt.Errorf("xlsx 单个工作表最多 %d 行, 数据较多时请使用 csv 或 ndjson 格式", excelize.TotalRows)
+
+func newXLSXRecordWriter(fullPath string, header []string) (*xlsxRecordWriter, error) {
+	return newXLSXBandWriter(fullPath, nil, header)
+}
+
+// newXLSXBandWriter bands 不为空时第一行写字段组名称, 相邻同组的列合并为一个单元格, 第二行为表头
+func newXLSXBandWriter(fullPath string, bands, header []string) (*xlsxRecordWriter, error) {
+	file := excelize.NewFile()
+	w, err := initXLSXRecordWriter(fullPath, file, bands, header)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func initXLSXRecordWriter(fullPath string, file *excelize.File, bands, header []string) (*xlsxRecordWriter, error) {
+	styleHeader, err := file.NewStyle(&excelize.Style{Fill: excelize.Fill{Type: "pattern", Color: []string{"#D0D0D0"}, Pattern: 1}})
+	if err != nil {
+		return nil, err
+	}
+	styleFailed, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Color: "#FF0000"}})
+	if err != nil {
+		return nil, err
+	}
+	stream, err := file.NewStreamWriter(xlsxSheet)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &xlsxRecordWriter{fullPath: fullPath, file: file, stream: stream, failed: styleFailed}
+	if len(bands) > 0 {
+		styleBand, err := file.NewStyle(&excelize.Style{
+			Fill:      excelize.Fill{Type: "pattern", Color: []string{"#B0C4DE"}, Pattern: 1},
+			Alignment: &excelize.Alignment{Horizontal: "center"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		cells := make([]any, len(bands))
+		for i := range bands {
+			cells[i] = excelize.Cell{StyleID: styleBand}
+		}
+		var merges [][2]string
+		for start := 0; start < len(bands); {
+			end := start
+			for end+1 < len(bands) && bands[end+1] == bands[start] {
+				end++
+			}
+			cells[start] = excelize.Cell{StyleID: styleBand, Value: bands[start]}
+			if end > start {
+				hcell, _ := excelize.CoordinatesToCellName(start+1, 1)
+				vcell, _ := excelize.CoordinatesToCellName(end+1, 1)
+				merges = append(merges, [2]string{hcell, vcell})
+			}
+			start = end + 1
+		}
+		if err := w.writeRow(cells); err != nil {
+			return nil, err
+		}
+		for _, merge := range merges {
+			if err := stream.MergeCell(merge[0], merge[1]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	cells := make([]any, len(header))
+	for i, code := range header {
+		cells[i] = excelize.Cell{StyleID: styleHeader, Value: code}
+	}
+	if err := w.writeRow(cells); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *xlsxRecordWriter) Write(values []any) error {
+	return w.writeStyled(values, len(values), 0)
+}
+
+// writeFailed 写入失败行, 从第 fromColumn 列 (从 0 开始) 起标红
+func (w *xlsxRecordWriter) writeFailed(values []any, fromColumn int) error {
+	return w.writeStyled(values, fromColumn, w.failed)
+}
+
+// writeStyled 写入一行, 第 fromColumn 列及之后的单元格使用 style
+func (w *xlsxRecordWriter) writeStyled(values []any, fromColumn, style int) error {
+	cells := make([]any, len(values))
+	for i, value := range values {
+		if i >= fromColumn {
+			cells[i] = excelize.Cell{StyleID: style, Value: formatCellValue(value)}
+			continue
+		}
+		cells[i] = formatCellValue(value)
+	}
+	return w.writeRow(cells)
+}
+
+func (w *xlsxRecordWriter) writeRow(cells []any) error {
+	if w.line >= excelize.TotalRows {
+		return errXLSXRowLimit
+	}
+	w.line++
+	cell, err := excelize.CoordinatesToCellName(1, w.line)
+	if err != nil {
+		return err
+	}
+	return w.stream.SetRow(cell, cells)
+}
+
+func (w *xlsxRecordWriter) Close() error {
+	err := w.stream.Flush()
+	if err == nil {
+		err = w.file.SaveAs(w.fullPath)
+	}
+	// 删除流式写入的临时文件
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+type csvRecordWriter struct {
+	file    *os.File
+	buf     *bufio.Writer
+	csv     *csv.Writer
+	closers []io.Closer
+}
+
+func (w *csvRecordWriter) Write(values []any) error {
+	cells := make([]string, len(values))
+	for i, value := range values {
+		cells[i] = formatCellValue(value)
+	}
+	return w.csv.Write(cells)
+}
+
+func (w *csvRecordWriter) Close() error {
+	w.csv.Flush()
+	err := w.csv.Error()
+	for _, closer := range w.closers {
+		if cerr := closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if ferr := w.buf.Flush(); err == nil {
+		err = ferr
+	}
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// jsonRecordWriter 逐条写出 JSON 对象, 键顺序与表头一致
+type jsonRecordWriter struct {
+	file   *os.File
+	buf    *bufio.Writer
+	header []string
+	lines  bool // NDJSON
+	count  int
+}
+
+func (w *jsonRecordWriter) Write(values []any) error {
+	var b bytes.Buffer
+	if !w.lines {
+		if w.count > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("{")
+	for i, key := range w.header {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		var value any
+		if i < len(values) {
+			value = values[i]
+		}
+		v, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		b.Write(k)
+		b.WriteString(":")
+		b.Write(v)
+	}
+	b.WriteString("}")
+	if w.lines {
+		b.WriteString("\n")
+	}
+	w.count++
+	_, err := w.buf.Write(b.Bytes())
+	return err
+}
+
+func (w *jsonRecordWriter) Close() error {
+	var err error
+	if !w.lines {
+		_, err = w.buf.WriteString("\n]\n")
+	}
+	if ferr := w.buf.Flush(); err == nil {
+		err = ferr
+	}
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// logFieldValue 变更日志中记录的字段值, 多对多字段的目标编码以 JSON 数组记录
+func logFieldValue(value any) string {
+	switch v := value.(type) {
+	case []string, []any:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return fmt.Sprintf("%v", value)
+}
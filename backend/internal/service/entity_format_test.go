@@ -0,0 +1,212 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"piemdm/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestParseFileOptions(t *testing.T) {
+	opts, err := ParseFileOptions("", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, FileOptions{Format: FileFormatXLSX, Delimiter: ',', Encoding: FileEncodingUTF8}, opts)
+
+	opts, err = ParseFileOptions("CSV", "tab", "GBK")
+	require.NoError(t, err)
+	assert.Equal(t, FileOptions{Format: FileFormatCSV, Delimiter: '\t', Encoding: FileEncodingGBK}, opts)
+
+	_, err = ParseFileOptions("xls", "", "")
+	assert.Error(t, err)
+	_, err = ParseFileOptions("csv", ";;", "")
+	assert.Error(t, err)
+	_, err = ParseFileOptions("csv", "", "latin1")
+	assert.Error(t, err)
+
+	assert.Equal(t, FileFormatNDJSON, DetectFileFormat("items.jsonl"))
+	assert.Equal(t, "", DetectFileFormat("items.xls"))
+}
+
+func TestReadRecords(t *testing.T) {
+	t.Run("csv with bom and delimiter", func(t *testing.T) {
+		header, records, err := readRecords(FileOptions{Format: FileFormatCSV, Delimiter: ';'},
+			strings.NewReader("\ufeffcode;name\nA001;\"Alpha; Inc\"\n\nA002;Beta\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"code", "name"}, header)
+		require.Len(t, records, 2)
+		assert.Equal(t, fileRecord{Line: 2, Cells: []string{"A001", "Alpha; Inc"}}, records[0])
+		assert.Equal(t, 4, records[1].Line)
+	})
+
+	t.Run("csv gbk", func(t *testing.T) {
+		encoded, err := simplifiedchinese.GBK.NewEncoder().String("code,name\nA001,名称\n")
+		require.NoError(t, err)
+		_, records, err := readRecords(FileOptions{Format: FileFormatCSV, Delimiter: ',', Encoding: FileEncodingGBK}, strings.NewReader(encoded))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"A001", "名称"}, records[0].Cells)
+	})
+
+	t.Run("json keys union in first-seen order", func(t *testing.T) {
+		header, records, err := readRecords(FileOptions{Format: FileFormatJSON},
+			strings.NewReader(`[{"code":"A001","qty":12},{"code":"A002","tags":["x"],"active":true,"qty":null}]`))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"code", "qty", "tags", "active"}, header)
+		assert.Equal(t, []string{"A001", "12", "", ""}, records[0].Cells)
+		assert.Equal(t, []string{"A002", "", `["x"]`, "true"}, records[1].Cells)
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		_, records, err := readRecords(FileOptions{Format: FileFormatNDJSON},
+			strings.NewReader("{\"code\":\"A001\"}\n\n{\"code\":\"A002\"}\n"))
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, 3, records[1].Line)
+
+		_, _, err = readRecords(FileOptions{Format: FileFormatNDJSON}, strings.NewReader("{\"code\":\"A001\"}\n[1]\n"))
+		assert.ErrorContains(t, err, "第 2 行")
+	})
+}
+
+func TestCoerceImportValue(t *testing.T) {
+	number := &model.TableField{Code: "qty", Name: "数量", Type: "Number"}
+	date := &model.TableField{Code: "day", Name: "日期", Type: "Date"}
+	datetime := &model.TableField{Code: "at", Name: "时间", FieldType: "datetime"}
+	text := &model.TableField{Code: "name", Name: "名称", Type: "Text"}
+	tags := &model.TableField{Code: "tags", Name: "标签", Type: "Text", FieldType: "manytomany"}
+
+	tests := []struct {
+		name    string
+		field   *model.TableField
+		cell    string
+		want    any
+		wantErr bool
+	}{
+		{"integer", number, " 12 ", int64(12), false},
+		{"decimal", number, "1.5", 1.5, false},
+		{"empty number", number, "", nil, false},
+		{"invalid number", number, "abc", nil, true},
+		{"date", date, "2024-03-01T08:00:00+08:00", "2024-03-01", false},
+		{"slash date", date, "2024/03/01", "2024-03-01", false},
+		{"datetime by preset", datetime, "2024-03-01", "2024-03-01 00:00:00", false},
+		{"invalid date", date, "03-01", nil, true},
+		{"text keeps spaces", text, " a ", " a ", false},
+		{"manytomany json array", tags, `["A", "B"]`, []string{"A", "B"}, false},
+		{"manytomany comma separated", tags, "A, B,A", []string{"A", "B"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := coerceImportValue(tt.field, tt.cell)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExportFieldValue(t *testing.T) {
+	at := time.Date(2024, 3, 1, 8, 30, 0, 0, time.Local)
+	assert.Equal(t, "2024-03-01", exportFieldValue("Date", at))
+	assert.Equal(t, "2024-03-01 08:30:00", exportFieldValue("DateTime", at))
+	assert.Equal(t, uint64(7), exportFieldValue("Number", uint64(7)))
+	assert.Equal(t, 2.5, exportFieldValue("Number", []byte("2.5")))
+	assert.Equal(t, "abc", exportFieldValue("Text", []byte("abc")))
+	assert.Nil(t, exportFieldValue("Text", nil))
+	assert.Equal(t, `["A","B"]`, exportFieldValue("Text", []string{"A", "B"}))
+}
+
+func TestRecordWriterRoundTrip(t *testing.T) {
+	header := []string{"id", "name", "qty"}
+	values := [][]any{{uint64(1), "Alpha, Inc", int64(3)}, {uint64(2), nil, 1.25}}
+
+	formats := []FileOptions{
+		{Format: FileFormatXLSX},
+		{Format: FileFormatCSV, Delimiter: ',', Encoding: FileEncodingUTF8BOM},
+		{Format: FileFormatCSV, Delimiter: '\t', Encoding: FileEncodingGB18030},
+		{Format: FileFormatJSON},
+		{Format: FileFormatNDJSON},
+	}
+	for _, opts := range formats {
+		t.Run(opts.Format+"/"+opts.Encoding, func(t *testing.T) {
+			fullPath := filepath.Join(t.TempDir(), "export."+opts.Ext())
+			w, err := newRecordWriter(fullPath, opts, header)
+			require.NoError(t, err)
+			for _, row := range values {
+				require.NoError(t, w.Write(row))
+			}
+			require.NoError(t, w.Close())
+
+			file, err := os.Open(fullPath)
+			require.NoError(t, err)
+			defer file.Close()
+
+			gotHeader, records, err := readRecords(opts, file)
+			require.NoError(t, err)
+			assert.Equal(t, header, gotHeader)
+			require.Len(t, records, 2)
+			assert.Equal(t, []string{"1", "Alpha, Inc", "3"}, records[0].Cells)
+			assert.Equal(t, []string{"2", "", "1.25"}, records[1].Cells)
+		})
+	}
+}
+
+func TestWriteImportResultJSON(t *testing.T) {
+	fullPath := filepath.Join(t.TempDir(), "result.ndjson")
+	rows := []*ImportRow{
+		{Line: 1, Cells: []string{"A001"}, Operation: model.ImportOperationCreate},
+		{Line: 2, Cells: []string{"A002"}, Err: &ValidationError{Errors: []FieldError{{Message: "字段 '数量' 必须是数字"}}}},
+	}
+	opts := FileOptions{Format: FileFormatNDJSON}
+	require.NoError(t, writeImportResult(fullPath, opts, []string{"code"}, rows, &model.EntityJob{Operation: model.ImportOperationUpsert}))
+
+	content, err := os.ReadFile(fullPath)
+	require.NoError(t, err)
+	assert.Equal(t, `{"code":"A001","_status":"Succeeded","_message":"BatchCreate"}`+"\n"+
+		`{"code":"A002","_status":"Failed","_message":"字段 '数量' 必须是数字"}`+"\n", string(content))
+}
+
+func TestXLSXRecordWriter_RowLimit(t *testing.T) {
+	w, err := newXLSXRecordWriter(filepath.Join(t.TempDir(), "export.xlsx"), []string{"code"})
+	require.NoError(t, err)
+	defer w.Close()
+
+	// 跳到工作表的最后一行
+	w.line = excelize.TotalRows - 1
+	require.NoError(t, w.Write([]any{"A"}))
+	assert.ErrorIs(t, w.Write([]any{"B"}), errXLSXRowLimit)
+}
+
+func TestXLSXBandWriter(t *testing.T) {
+	fullPath := filepath.Join(t.TempDir(), "template.xlsx")
+	header := []string{"id", "code", "name", "price"}
+	w, err := newXLSXBandWriter(fullPath, []string{"", "基本信息", "基本信息", "价格"}, header)
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]any{1, "A001", "Alpha", 9.5}))
+	require.NoError(t, w.Close())
+
+	file, err := os.Open(fullPath)
+	require.NoError(t, err)
+	defer file.Close()
+	band, records, err := readRecords(FileOptions{Format: FileFormatXLSX}, file)
+	require.NoError(t, err)
+	assert.Equal(t, "基本信息", band[1])
+
+	// 导入时跳过字段组行, 以第二行为表头
+	fields := map[string]*model.TableField{"code": {Code: "code"}, "name": {Code: "name"}, "price": {Code: "price"}}
+	assert.False(t, hasFieldColumn(band, fields))
+	require.Len(t, records, 2)
+	assert.True(t, hasFieldColumn(records[0].Cells, fields))
+	assert.Equal(t, header, records[0].Cells)
+	assert.Equal(t, 3, records[1].Line)
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"piemdm/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FieldChange 变更历史中一个字段的变化
+// 值按字段类型记录: 数值为数字, 日期为文本, JSON 字段为 JSON 值, 多对多字段为编码数组, 空值为 null
+type FieldChange struct {
+	Name   string                  `json:"name,omitempty"`
+	Before any                     `json:"before"`
+	After  any                     `json:"after"`
+	Paths  map[string]*FieldChange `json:"paths,omitempty"` // JSON 字段按路径记录的变化, 如 address.city、tags[1]
+}
+
+// entityEvent 一次修改的事件信息
+type entityEvent struct {
+	Operation    string
+	Reason       string
+	ApprovalCode string
+	UpdateBy     string // 为空时取当前用户
+}
+
+// historyExclude 不记录变化的系统字段, 操作类型等记录在事件中
+var historyExclude = []string{
+	"id",
+	"table_code",
+	"reason",
+	"operation",
+	"action",
+	"send_status",
+	"version",
+	"updated_at",
+	"updated_by",
+	"created_at",
+	"created_by",
+	"deleted_at",
+	"deleted_by",
+	"delete_reason",
+	"entity_id",
+	"approval_code",
+	"draft_status",
+	"date_version",
+	"instance_code",
+}
+
+// historySource 修改的来源, 未设置时为 UI; 导入、飞书回调等在上下文中设置 source
+func historySource(c *gin.Context) string {
+	if source := c.GetString("source"); source != "" {
+		return source
+	}
+	return model.EntityLogSourceUI
+}
+
+// historyValue 按字段类型转换变更历史中记录的值, 没有字段定义 (如状态) 时按文本记录
+func historyValue(field *model.TableField, value any) any {
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+	if s, ok := value.(string); ok && strings.TrimSpace(s) == "" {
+		return nil
+	}
+	if value == nil {
+		return nil
+	}
+	if field == nil {
+		return exportFieldValue("Text", value)
+	}
+	if field.FieldType == "manytomany" {
+		if values := referenceValues(value, true); len(values) > 0 {
+			return values
+		}
+		return nil
+	}
+	if isJSONField(field) {
+		decoded, _, err := decodeJSONValue(value)
+		if err != nil {
+			return fmt.Sprintf("%v", value)
+		}
+		return decoded
+	}
+	return exportFieldValue(fieldDataType(field), value)
+}
+
+// historyEqual 比较两个记录值, 数字按数值比较, JSON 值忽略格式差异
+func historyEqual(a, b any) bool {
+	return reflect.DeepEqual(historyComparable(a), historyComparable(b))
+}
+
+// historyComparable 经 JSON 编码再解码, 统一数字和数组的类型
+func historyComparable(value any) any {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return string(data)
+	}
+	return out
+}
+
+// entityChanges 比较记录修改前后的值, 返回有变化的字段
+// 新增 (before 为 nil) 记录 after 中全部非空字段; 删除 (after 为 nil) 记录 before 中全部非空字段, 修改后的值为 null;
+// 修改只比较 after 中提交的字段. JSON 字段修改前后都是对象或数组时, 另按路径记录变化
+func entityChanges(fields []*model.TableField, before, after map[string]any) map[string]*FieldChange {
+	definitions := make(map[string]*model.TableField, len(fields))
+	names := map[string]string{"status": "状态"}
+	for _, field := range fields {
+		definitions[field.Code] = field
+		names[field.Code] = field.Name
+	}
+
+	keys := after
+	if after == nil {
+		keys = before
+	}
+	changes := make(map[string]*FieldChange)
+	for key := range keys {
+		if slices.Contains(historyExclude, key) {
+			continue
+		}
+		field := definitions[key]
+		beforeValue, afterValue := historyValue(field, before[key]), historyValue(field, after[key])
+		if historyEqual(beforeValue, afterValue) {
+			continue
+		}
+		change := &FieldChange{Name: names[key], Before: beforeValue, After: afterValue}
+		if field != nil && isJSONField(field) {
+			change.Paths = jsonPathChanges(beforeValue, afterValue)
+		}
+		changes[key] = change
+	}
+	return changes
+}
+
+// newEntityLog 生成一条记录的变更事件, 没有字段变化时返回 nil
+func newEntityLog(c *gin.Context, fields []*model.TableField, event entityEvent, id uint, before, after map[string]any) (*model.EntityLog, error) {
+	changes := entityChanges(fields, before, after)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return nil, fmt.Errorf("记录 %d 的变更内容无法转换为 JSON: %v", id, err)
+	}
+
+	updateBy := event.UpdateBy
+	if updateBy == "" {
+		updateBy = c.GetString("user_name")
+	}
+	return &model.EntityLog{
+		EntityID:     id,
+		Operation:    event.Operation,
+		Source:       historySource(c),
+		ApprovalCode: event.ApprovalCode,
+		Changes:      data,
+		Reason:       event.Reason,
+		UpdateBy:     updateBy,
+	}, nil
+}
+
+// writeEntityLog 写入一条记录的变更事件, 没有字段变化时不写入
+func writeEntityLog(c *gin.Context, entityLogService EntityLogService, tableCode string, fields []*model.TableField, event entityEvent, id uint, before, after map[string]any) error {
+	entityLog, err := newEntityLog(c, fields, event, id, before, after)
+	if err != nil || entityLog == nil {
+		return err
+	}
+	return entityLogService.Create(c, tableCode, entityLog)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"piemdm/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func historyTestFields() []*model.TableField {
+	return []*model.TableField{
+		{Code: "name", Name: "名称", Type: "Text"},
+		{Code: "price", Name: "价格", Type: "Number"},
+		{Code: "launched", Name: "上市日期", Type: "Date"},
+		{Code: "tags", Name: "标签", FieldType: "manytomany"},
+		jsonField("attrs", ""),
+	}
+}
+
+func TestEntityChanges(t *testing.T) {
+	fields := historyTestFields()
+	origin := map[string]any{
+		"id":       uint64(1),
+		"name":     "A",
+		"price":    "1.50",
+		"launched": time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		"tags":     []string{"x"},
+		"attrs":    `{"color":"red","size":1}`,
+		"status":   "Normal",
+		"version":  uint(3),
+	}
+
+	// 修改: 只比较提交的字段, 数值和 JSON 忽略格式差异
+	changes := entityChanges(fields, origin, map[string]any{
+		"name":       "A",
+		"price":      1.5,
+		"tags":       `["x","y"]`,
+		"attrs":      `{"size":1,"color":"blue"}`,
+		"updated_by": "bob",
+	})
+	data, err := json.Marshal(changes)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"tags": {"name": "标签", "before": ["x"], "after": ["x", "y"]},
+		"attrs": {"name": "attrs", "before": {"color": "red", "size": 1}, "after": {"color": "blue", "size": 1},
+			"paths": {"color": {"before": "red", "after": "blue"}}}
+	}`, string(data))
+
+	// 新增: 记录全部非空字段
+	changes = entityChanges(fields, nil, map[string]any{"id": uint(2), "name": "B", "price": int64(2), "launched": "", "status": "Normal"})
+	data, err = json.Marshal(changes)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name": {"name": "名称", "before": null, "after": "B"},
+		"price": {"name": "价格", "before": null, "after": 2},
+		"status": {"name": "状态", "before": null, "after": "Normal"}
+	}`, string(data))
+
+	// 删除: 记录删除前的全部字段
+	changes = entityChanges(fields, origin, nil)
+	assert.Len(t, changes, 6)
+	assert.Equal(t, "2024-01-02", changes["launched"].Before)
+	assert.Nil(t, changes["launched"].After)
+	assert.NotContains(t, changes, "version")
+}
+
+func TestNewEntityLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_name", "alice")
+
+	fields := historyTestFields()
+	event := entityEvent{Operation: "Update", Reason: "调价", ApprovalCode: "AP1"}
+	entityLog, err := newEntityLog(c, fields, event, 7, map[string]any{"price": 1}, map[string]any{"price": "2"})
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), entityLog.EntityID)
+	assert.Equal(t, "Update", entityLog.Operation)
+	assert.Equal(t, model.EntityLogSourceUI, entityLog.Source)
+	assert.Equal(t, "AP1", entityLog.ApprovalCode)
+	assert.Equal(t, "alice", entityLog.UpdateBy)
+	assert.JSONEq(t, `{"price":{"name":"价格","before":1,"after":2}}`, string(entityLog.Changes))
+
+	// 没有变化时不记录
+	entityLog, err = newEntityLog(c, fields, event, 7, map[string]any{"price": 1}, map[string]any{"price": "1.0"})
+	require.NoError(t, err)
+	assert.Nil(t, entityLog)
+
+	// 上下文中设置的来源和事件中指定的修改人
+	c.Set("source", model.EntityLogSourceImport)
+	event.UpdateBy = "bob"
+	entityLog, err = newEntityLog(c, fields, event, 7, nil, map[string]any{"name": "A"})
+	require.NoError(t, err)
+	assert.Equal(t, model.EntityLogSourceImport, entityLog.Source)
+	assert.Equal(t, "bob", entityLog.UpdateBy)
+}
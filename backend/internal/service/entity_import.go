@@ -0,0 +1,794 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"piemdm/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 导入结果文件在原始列之后追加的列, 重新上传时忽略
+const (
+	importStatusColumn  = "_status"
+	importMessageColumn = "_message"
+)
+
+// 导入行处理结果
+const (
+	ImportRowValid     = "Valid"     // 校验通过 (仅校验模式)
+	ImportRowSucceeded = "Succeeded" // 已写入或已提交审批
+	ImportRowFailed    = "Failed"    // 失败, 原因见 _message
+)
+
+// importProgressStep 每处理多少行保存一次任务进度
+const importProgressStep = 200
+
+// ImportOptions 导入参数
+type ImportOptions struct {
+	Operation  string // BatchCreate BatchUpdate Upsert
+	Reason     string // 原因
+	MatchIndex string // Upsert 匹配使用的唯一索引名称, 表只有一个唯一索引时可为空
+	DryRun     bool   // 仅校验, 不写入数据
+	// 忽略 Warn 匹配规则发现的疑似重复, 命中 Block 规则的行仍然失败
+	IgnoreDuplicates bool
+	File             FileOptions
+}
+
+// ImportRow 导入文件中的一个数据行
+type ImportRow struct {
+	Line      int            // 文件中的行号 (表头为第 1 行), JSON 数组为记录序号
+	Cells     []string       // 原始单元格, 用于生成结果文件
+	Data      map[string]any // 解析后的实体数据
+	Operation string         // 该行实际执行的操作: BatchCreate 或 BatchUpdate
+	Document  bool           // 带行项目的单据, 单独写入或提交审批
+	Err       error          // 处理失败原因, 为空表示成功
+}
+
+// importJob 一次导入任务的上下文
+type importJob struct {
+	job           *model.EntityJob
+	file          FileOptions                             // 导入文件格式, 结果文件使用相同格式
+	approvalOps   map[string]bool                         // 操作 -> 是否配置了审批流程
+	keyFields     []string                                // Upsert 匹配字段
+	operationInfo map[string]map[string]string            // 操作 -> 操作信息
+	fields        []*model.TableField                     // 参与校验的字段
+	references    *referenceChecker                       // 关系字段引用校验, 缓存已查询的值
+	formulas      *formulaSet                             // 公式字段, 没有时为 nil
+	itemFields    map[string]map[string]*model.TableField // 行项目表 -> 字段, 按需加载
+	approvalCodes []string                                // 单据提交的审批编码
+	matchRules    []*model.MatchRule                      // 新增行的查重规则
+	access        *FieldAccess                            // 导入用户的字段组权限, 没有限制时为 nil
+}
+
+func (s *entityService) Import(c *gin.Context, tableCode string, opts ImportOptions, r io.Reader) (*model.EntityJob, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
+	}
+
+	ij := &importJob{file: opts.File, approvalOps: make(map[string]bool)}
+	var operations []string
+	switch opts.Operation {
+	case model.ImportOperationCreate, model.ImportOperationUpdate:
+		operations = []string{opts.Operation}
+	case model.ImportOperationUpsert:
+		operations = []string{model.ImportOperationCreate, model.ImportOperationUpdate}
+		keyFields, matchIndex, err := s.findUpsertKeyFields(tableCode, opts.MatchIndex)
+		if err != nil {
+			return nil, err
+		}
+		ij.keyFields = keyFields
+		opts.MatchIndex = matchIndex
+	default:
+		return nil, fmt.Errorf("unsupported import operation: %s", opts.Operation)
+	}
+
+	// 请求结束后上传文件即被清理, 先读入内存再交给后台任务
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 检查是否有审批流程定义
+	for _, operation := range operations {
+		tableApprovalDefs, err := s.tableApprovalDefinitionRepository.List(tableCode, operation)
+		if err != nil {
+			s.logger.Error("查询审批流程定义失败", "err", err)
+			return nil, err
+		}
+		ij.approvalOps[operation] = len(tableApprovalDefs) > 0
+	}
+	if opts.Operation != model.ImportOperationUpdate {
+		if ij.matchRules, err = s.findMatchRules(tableCode); err != nil {
+			return nil, err
+		}
+	}
+	if ij.access, err = s.tableFieldService.GetFieldAccess(c, tableCode); err != nil {
+		return nil, err
+	}
+
+	ij.job = &model.EntityJob{
+		Type:       model.EntityJobTypeImport,
+		TableCode:  tableCode,
+		Operation:  opts.Operation,
+		Format:     opts.File.Ext(),
+		MatchIndex: opts.MatchIndex,
+		Reason:     opts.Reason,
+		DryRun:     opts.DryRun,
+	}
+	if err := s.entityJobService.Create(c, ij.job); err != nil {
+		return nil, err
+	}
+
+	// gin.Context 在请求结束后会被复用, 后台任务使用副本; 导入写入的变更历史来源为 Import
+	jobContext := c.Copy()
+	jobContext.Set("source", model.EntityLogSourceImport)
+	jobContext.Set("ignore_duplicates", opts.IgnoreDuplicates)
+	queued := snapshotJob(ij.job)
+	go s.runImportJob(jobContext, ij, data)
+
+	return queued, nil
+}
+
+// GetJob 获取实体后台任务
+func (s *entityService) GetJob(c *gin.Context, tableCode, code string) (*model.EntityJob, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
+	}
+	job, err := s.entityJobService.Get(code)
+	if err != nil {
+		return nil, err
+	}
+	if job.TableCode != tableCode {
+		return nil, fmt.Errorf("job %s does not belong to table %s", code, tableCode)
+	}
+	return job, nil
+}
+
+// runImportJob 后台执行导入: 逐行校验并写入, 单行失败不影响其他行, 最后生成结果文件
+func (s *entityService) runImportJob(c *gin.Context, ij *importJob, data []byte) {
+	job := ij.job
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("导入任务异常", "job", job.Code, "panic", r)
+			_ = s.entityJobService.Fail(job, fmt.Errorf("%v", r))
+		}
+	}()
+
+	if err := s.entityJobService.Start(job); err != nil {
+		s.logger.Error("更新导入任务状态失败", "job", job.Code, "err", err)
+	}
+
+	if err := s.executeImportJob(c, ij, data); err != nil {
+		s.logger.Error("导入任务失败", "job", job.Code, "err", err)
+		if err := s.entityJobService.Fail(job, err); err != nil {
+			s.logger.Error("更新导入任务状态失败", "job", job.Code, "err", err)
+		}
+	}
+}
+
+func (s *entityService) executeImportJob(c *gin.Context, ij *importJob, data []byte) error {
+	job := ij.job
+
+	ij.operationInfo = make(map[string]map[string]string)
+	for operation := range ij.approvalOps {
+		operationInfo := make(map[string]string)
+		if err := s.approvalService.GetOperationInfo(operation, &operationInfo); err != nil {
+			return err
+		}
+		ij.operationInfo[operation] = operationInfo
+	}
+
+	fields, err := findValidationFields(s.tableFieldService, job.TableCode)
+	if err != nil {
+		return err
+	}
+	ij.fields = fields
+	ij.references = newReferenceChecker(s.entityRepository, fields)
+	if ij.formulas, err = newFormulaSet(fields); err != nil {
+		return err
+	}
+
+	header, rows, err := s.parseImportFile(job.TableCode, ij.file, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	job.Total = len(rows)
+	if err := s.entityJobService.Save(job); err != nil {
+		return err
+	}
+
+	seenKeys := make(map[string]int)
+	for i, row := range rows {
+		row.Operation = job.Operation
+		if row.Err == nil && job.Operation == model.ImportOperationUpsert {
+			row.Err = s.resolveUpsertRow(ij, row, seenKeys)
+		}
+		if row.Err == nil {
+			row.Err = s.checkImportAccess(ij, row)
+		}
+		if items, ok := row.Data[documentItemsColumn].(string); ok && row.Err == nil {
+			delete(row.Data, documentItemsColumn)
+			if strings.TrimSpace(items) != "" {
+				row.Document = true
+				row.Err = s.importDocument(c, ij, row, items)
+			}
+		}
+		if row.Err == nil && !row.Document {
+			row.Err = s.checkImportRow(c, ij, row)
+		}
+		if row.Err == nil && !row.Document && !job.DryRun && !ij.approvalOps[row.Operation] {
+			row.Err = s.writeImportRow(c, ij, row)
+		}
+
+		job.Processed = i + 1
+		if job.Processed%importProgressStep == 0 {
+			countImportRows(job, rows[:job.Processed])
+			if err := s.entityJobService.Save(job); err != nil {
+				s.logger.Warn("保存导入进度失败", "job", job.Code, "err", err)
+			}
+		}
+	}
+
+	// 审批流程: 校验通过的行按操作统一生成草稿, 每个操作提交一个审批实例
+	if !job.DryRun {
+		approvalCodes := ij.approvalCodes
+		for _, operation := range []string{model.ImportOperationCreate, model.ImportOperationUpdate} {
+			if !ij.approvalOps[operation] {
+				continue
+			}
+			var operationRows []*ImportRow
+			for _, row := range rows {
+				if row.Operation == operation && !row.Document {
+					operationRows = append(operationRows, row)
+				}
+			}
+			if len(operationRows) == 0 {
+				continue
+			}
+			approvalCode, err := s.approvalService.ImportWithApproval(c, job.TableCode, job.Reason, operation, operationRows)
+			if err != nil {
+				return err
+			}
+			if approvalCode != "" {
+				approvalCodes = append(approvalCodes, approvalCode)
+			}
+		}
+		job.ApprovalCode = strings.Join(approvalCodes, ",")
+	}
+
+	countImportRows(job, rows)
+	switch {
+	case job.DryRun:
+		job.Message = fmt.Sprintf("校验完成: %d 行通过, %d 行失败", job.Succeeded, job.Failed)
+	case job.ApprovalCode != "":
+		job.Message = fmt.Sprintf("已提交审批 %s: %d 行, %d 行失败", job.ApprovalCode, job.Succeeded, job.Failed)
+	default:
+		job.Message = fmt.Sprintf("导入完成: %d 行成功, %d 行失败", job.Succeeded, job.Failed)
+	}
+
+	filename := fmt.Sprintf("%s-import-result-%s.%s", job.TableCode, strings.ToLower(job.Code), ij.file.Ext())
+	if err := writeImportResult(s.entityJobService.ResultPath(filename), ij.file, header, rows, job); err != nil {
+		return err
+	}
+	return s.entityJobService.Finish(job, filename)
+}
+
+// checkImportAccess 导入的列中不能有当前用户不能查看或不能修改的字段, 修改时允许提交原值
+func (s *entityService) checkImportAccess(ij *importJob, row *ImportRow) error {
+	if row.Operation != model.ImportOperationUpdate {
+		return s.checkEditable(ij.access, ij.job.TableCode, row.Data)
+	}
+	id, err := importRowID(row.Data)
+	if err != nil {
+		return err
+	}
+	return s.checkEditable(ij.access, ij.job.TableCode, row.Data, id)
+}
+
+// checkImportRow 校验单行数据, 不写入: 计算公式字段、字段校验、引用的记录是否存在、记录是否存在、唯一索引冲突、疑似重复
+func (s *entityService) checkImportRow(c *gin.Context, ij *importJob, row *ImportRow) error {
+	job := ij.job
+	isUpdate := row.Operation == model.ImportOperationUpdate
+	withApproval := ij.approvalOps[row.Operation]
+
+	// 公式字段按导入的值计算, 修改时未导入的字段取原记录的值
+	if ij.formulas != nil {
+		var origin map[string]any
+		if isUpdate {
+			id, err := importRowID(row.Data)
+			if err != nil {
+				return err
+			}
+			if origin, err = s.entityRepository.FindOne(job.TableCode, id); err != nil {
+				return fmt.Errorf("记录 %d 不存在", id)
+			}
+		}
+		if fieldErrors := ij.formulas.apply(row.Data, origin); len(fieldErrors) > 0 {
+			return &ValidationError{Errors: fieldErrors}
+		}
+	}
+
+	if fieldErrors := ValidateEntityValues(ij.fields, row.Data, isUpdate); len(fieldErrors) > 0 {
+		return &ValidationError{Errors: fieldErrors}
+	}
+	fieldErrors, err := ij.references.Check(row.Data)
+	if err != nil {
+		return err
+	}
+	if len(fieldErrors) > 0 {
+		return &ValidationError{Errors: fieldErrors}
+	}
+
+	if !isUpdate {
+		if err := s.validateUniqueConstraints(c, "Create", job.TableCode, row.Data, withApproval); err != nil {
+			return err
+		}
+		return s.checkDuplicates(c, job.TableCode, ij.matchRules, row.Data)
+	}
+
+	id, err := importRowID(row.Data)
+	if err != nil {
+		return err
+	}
+	origin, err := s.entityRepository.FindOne(job.TableCode, id)
+	if err != nil {
+		return fmt.Errorf("记录 %d 不存在", id)
+	}
+	// 已锁定、已作废的数据不能修改
+	if err := checkStatusTransition(origin, "Update"); err != nil {
+		return err
+	}
+	return s.validateUniqueConstraints(c, "Update", job.TableCode, row.Data, withApproval)
+}
+
+// writeImportRow 直接写入主表 (无审批流程)
+func (s *entityService) writeImportRow(c *gin.Context, ij *importJob, row *ImportRow) error {
+	job := ij.job
+	operationInfo := ij.operationInfo[row.Operation]
+	entityMap := make(map[string]any, len(row.Data))
+	for k, v := range row.Data {
+		entityMap[k] = v
+	}
+
+	if row.Operation == model.ImportOperationCreate {
+		// 新增操作 - 使用snake_case以匹配数据库列名
+		gid := s.globalIdService.GetNewID("entity")
+		entityMap["id"] = gid
+		entityMap["operation"] = row.Operation
+		entityMap["action"] = operationInfo["action"]
+		entityMap["status"] = operationInfo["status"]
+		// 生成 entity global id
+		entityMap["entity_id"] = gid
+		entityMap["created_by"] = c.GetString("user_name")
+		entityMap["send_status"] = 0
+		entityMap["updated_by"] = c.GetString("user_name")
+
+		// 生成自动编码
+		if err := s.generateAutocodes(c, job.TableCode, entityMap); err != nil {
+			return err
+		}
+
+		if err := s.entityRepository.Create(c, job.TableCode, entityMap); err != nil {
+			return err
+		}
+		event := entityEvent{Operation: row.Operation, Reason: job.Reason}
+		if err := writeEntityLog(c, s.entityLogService, job.TableCode, ij.fields, event, gid, nil, entityMap); err != nil {
+			s.logger.Error("创建变更日志失败", "error", err, "id", gid)
+		}
+		return nil
+	}
+
+	id, err := importRowID(entityMap)
+	if err != nil {
+		return err
+	}
+
+	// 1. 获取原始数据用于比较变更
+	origin, err := s.entityRepository.FindOne(job.TableCode, id)
+	if err != nil {
+		s.logger.Error("获取原始数据失败", "error", err, "id", id)
+		return err
+	}
+
+	// 2. 设置必要字段, 修改不改变状态
+	entityMap["action"] = operationInfo["action"]
+	delete(entityMap, "status")
+	entityMap["updated_by"] = c.GetString("user_name")
+
+	// 3. 删除 id 字段,避免更新 id
+	delete(entityMap, "id")
+
+	if err := s.entityRepository.Update(c, job.TableCode, entityMap, map[string]any{"id": id}); err != nil {
+		return err
+	}
+
+	// 4. 记录变更历史, 日志记录失败不应该阻断导入
+	event := entityEvent{Operation: row.Operation, Reason: job.Reason}
+	if err := writeEntityLog(c, s.entityLogService, job.TableCode, ij.fields, event, id, origin, entityMap); err != nil {
+		s.logger.Error("创建变更日志失败", "error", err, "id", id)
+	}
+	return nil
+}
+
+// importDocument 导入一张单据: 行数据为抬头, 行项目列为按行项目表分组的 JSON
+// 与单据接口相同, 每张单据在一个事务中写入, 配置了审批流程时每张单据提交一个审批
+func (s *entityService) importDocument(c *gin.Context, ij *importJob, row *ImportRow, cell string) error {
+	job := ij.job
+	items, err := s.parseImportItems(ij, cell)
+	if err != nil {
+		return err
+	}
+	header := row.Data
+	if row.Operation == model.ImportOperationUpdate {
+		id, err := importRowID(header)
+		if err != nil {
+			return err
+		}
+		header["id"] = id
+	} else {
+		delete(header, "id")
+	}
+
+	withApproval := ij.approvalOps[row.Operation]
+	drafts, err := s.planDocument(c, job.TableCode, &EntityDocument{Header: header, Items: items}, withApproval)
+	if err != nil || job.DryRun {
+		return err
+	}
+	if !withApproval {
+		return s.applyDocument(c, job.Reason, drafts)
+	}
+	approvalCode, err := s.approvalService.SubmitDocumentWithApproval(c, job.TableCode, job.Reason, drafts[0].Operation, drafts)
+	if err != nil {
+		return err
+	}
+	ij.approvalCodes = append(ij.approvalCodes, approvalCode)
+	return nil
+}
+
+// parseImportItems 解析行项目列, 行项目的值与单元格一样按行项目表的字段类型转换
+func (s *entityService) parseImportItems(ij *importJob, cell string) (map[string][]map[string]any, error) {
+	dec := json.NewDecoder(strings.NewReader(cell))
+	dec.UseNumber()
+	var raw map[string][]map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("行项目列不是合法的 JSON: %v", err)
+	}
+
+	items := make(map[string][]map[string]any, len(raw))
+	for code, lines := range raw {
+		fields, ok := ij.itemFields[code]
+		if !ok {
+			tFields, err := s.tableFieldService.Find("code,name,field_type,type", map[string]any{"table_code": code})
+			if err != nil {
+				return nil, err
+			}
+			fields = make(map[string]*model.TableField, len(tFields))
+			for _, f := range tFields {
+				fields[f.Code] = f
+			}
+			if ij.itemFields == nil {
+				ij.itemFields = make(map[string]map[string]*model.TableField)
+			}
+			ij.itemFields[code] = fields
+		}
+
+		parsed := make([]map[string]any, 0, len(lines))
+		for i, line := range lines {
+			entityMap := make(map[string]any, len(line))
+			var fieldErrors []FieldError
+			for key, value := range line {
+				field, ok := fields[key]
+				if !ok {
+					if value != nil {
+						entityMap[key] = jsonCellString(value)
+					}
+					continue
+				}
+				v, err := coerceImportValue(field, jsonCellString(value))
+				if err != nil {
+					fieldErrors = append(fieldErrors, FieldError{Field: field.Code, Name: field.Name, Message: err.Error()})
+					continue
+				}
+				entityMap[key] = v
+			}
+			if len(fieldErrors) > 0 {
+				return nil, fmt.Errorf("行项目 %s 第 %d 行: %w", code, i+1, &ValidationError{Errors: fieldErrors})
+			}
+			parsed = append(parsed, entityMap)
+		}
+		items[code] = parsed
+	}
+	return items, nil
+}
+
+// findUpsertKeyFields 获取 Upsert 匹配使用的唯一索引字段
+// matchIndex 为空时, 表必须只有一个唯一索引
+func (s *entityService) findUpsertKeyFields(tableCode, matchIndex string) ([]string, string, error) {
+	uniqueFields, err := s.tableFieldService.Find("code,is_unique,index_name", map[string]any{
+		"table_code": tableCode,
+		"is_unique":  "Yes",
+		"status":     "Normal",
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("查询唯一索引字段失败: %v", err)
+	}
+
+	// 与 validateUniqueConstraints 一致: 没有 index_name 时以字段编码作为索引名
+	var indexNames []string
+	indexes := make(map[string][]string)
+	for _, field := range uniqueFields {
+		indexName := field.IndexName
+		if indexName == "" {
+			indexName = field.Code
+		}
+		if _, ok := indexes[indexName]; !ok {
+			indexNames = append(indexNames, indexName)
+		}
+		indexes[indexName] = append(indexes[indexName], field.Code)
+	}
+
+	if len(indexNames) == 0 {
+		return nil, "", fmt.Errorf("表 %s 没有定义唯一索引, 不能按唯一索引导入", tableCode)
+	}
+	if matchIndex == "" {
+		if len(indexNames) > 1 {
+			return nil, "", fmt.Errorf("表 %s 有多个唯一索引 [%s], 请指定 match_index", tableCode, strings.Join(indexNames, ", "))
+		}
+		matchIndex = indexNames[0]
+	}
+	keyFields, ok := indexes[matchIndex]
+	if !ok {
+		return nil, "", fmt.Errorf("唯一索引 %s 不存在, 可选: [%s]", matchIndex, strings.Join(indexNames, ", "))
+	}
+	return keyFields, matchIndex, nil
+}
+
+// resolveUpsertRow 按唯一索引字段匹配已有记录, 决定该行新增还是修改
+// seenKeys 记录文件内已出现的匹配值及行号, 同一文件内的重复值视为错误
+func (s *entityService) resolveUpsertRow(ij *importJob, row *ImportRow, seenKeys map[string]int) error {
+	// 以匹配结果为准, 忽略文件中的 id 列
+	delete(row.Data, "id")
+	delete(row.Data, "ID")
+
+	where := make(map[string]any, len(ij.keyFields))
+	keyValues := make([]string, 0, len(ij.keyFields))
+	for _, code := range ij.keyFields {
+		value := row.Data[code]
+		if value == nil || strings.TrimSpace(fmt.Sprintf("%v", value)) == "" {
+			return fmt.Errorf("匹配字段 %s 不能为空", code)
+		}
+		where[code] = value
+		keyValues = append(keyValues, fmt.Sprintf("%v", value))
+	}
+
+	key := strings.Join(keyValues, "\x00")
+	if line, ok := seenKeys[key]; ok {
+		return fmt.Errorf("匹配字段值与第 %d 行重复", line)
+	}
+	seenKeys[key] = row.Line
+
+	// 查询失败时该行失败, 不能当作未匹配而新增
+	matches, err := s.entityRepository.Find(ij.job.TableCode, "id", where)
+	if err != nil {
+		return fmt.Errorf("查询匹配的记录失败: %v", err)
+	}
+	switch len(matches) {
+	case 0:
+		row.Operation = model.ImportOperationCreate
+	case 1:
+		row.Operation = model.ImportOperationUpdate
+		row.Data["id"] = matches[0]["id"]
+	default:
+		return fmt.Errorf("匹配到 %d 条记录, 无法确定要修改的记录", len(matches))
+	}
+	return nil
+}
+
+// parseImportFile 按文件格式解析导入文件, 第一行 (JSON 为对象键) 为字段编码
+// 单元格按字段数据类型转换, 转换失败记为该行错误; 整行为空的行忽略
+func (s *entityService) parseImportFile(tableCode string, file FileOptions, r io.Reader) ([]string, []*ImportRow, error) {
+	// Fetch field definitions for type mapping
+	tFields, err := s.tableFieldService.Find("code,name,field_type,type", map[string]any{"table_code": tableCode})
+	if err != nil {
+		return nil, nil, err
+	}
+	fields := make(map[string]*model.TableField, len(tFields))
+	for _, f := range tFields {
+		fields[f.Code] = f
+	}
+
+	header, records, err := readRecords(file, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 按字段组生成的模板第一行为字段组名称, 第二行才是字段编码
+	if !hasFieldColumn(header, fields) && len(records) > 0 && hasFieldColumn(records[0].Cells, fields) {
+		header, records = records[0].Cells, records[1:]
+	}
+
+	var rows []*ImportRow
+	for _, record := range records {
+		if isBlankRow(record.Cells) {
+			continue
+		}
+
+		entityMap := make(map[string]any)
+		var fieldErrors []FieldError
+		for index, cell := range record.Cells {
+			if index >= len(header) {
+				break
+			}
+			fieldCode := header[index]
+			if fieldCode == "" || fieldCode == importStatusColumn || fieldCode == importMessageColumn || fieldCode == relationsColumn {
+				continue
+			}
+
+			// 处理 ID 字段
+			if fieldCode == "ID" || fieldCode == "id" {
+				if num, err := strconv.Atoi(strings.TrimSpace(cell)); err == nil {
+					entityMap[fieldCode] = num
+					continue
+				}
+			}
+
+			field, ok := fields[fieldCode]
+			if !ok {
+				// 导出文件中的显示名称列
+				if base, isLabel := strings.CutSuffix(fieldCode, exportLabelSuffix); isLabel && fields[base] != nil {
+					continue
+				}
+				entityMap[fieldCode] = cell
+				continue
+			}
+			value, err := coerceImportValue(field, cell)
+			if err != nil {
+				fieldErrors = append(fieldErrors, FieldError{Field: field.Code, Name: field.Name, Message: err.Error()})
+				continue
+			}
+			entityMap[fieldCode] = value
+		}
+
+		row := &ImportRow{
+			Line:  record.Line,
+			Cells: record.Cells,
+			Data:  entityMap,
+		}
+		if len(fieldErrors) > 0 {
+			row.Err = &ValidationError{Errors: fieldErrors}
+		}
+		rows = append(rows, row)
+	}
+
+	return header, rows, nil
+}
+
+// hasFieldColumn 行中是否有字段编码或 id 列
+func hasFieldColumn(cells []string, fields map[string]*model.TableField) bool {
+	for _, cell := range cells {
+		if cell == "id" || cell == "ID" || fields[cell] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlankRow(cells []string) bool {
+	for _, cell := range cells {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// importRowID 获取修改操作的记录 ID
+func importRowID(entityMap map[string]any) (uint, error) {
+	switch v := entityMap["id"].(type) {
+	case nil:
+		return 0, fmt.Errorf("BatchUpdate requires id field")
+	case string:
+		// 从Excel读取的可能是字符串,需要转换
+		id, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid id value: %s", v)
+		}
+		return uint(id), nil
+	case int:
+		return uint(v), nil
+	case int64:
+		return uint(v), nil
+	case uint:
+		return v, nil
+	case uint64:
+		return uint(v), nil
+	case float64:
+		// Excel数字可能被解析为float64
+		return uint(v), nil
+	default:
+		return 0, fmt.Errorf("invalid id type: %T", v)
+	}
+}
+
+// countImportRows 统计成功、失败行数
+func countImportRows(job *model.EntityJob, rows []*ImportRow) {
+	job.Succeeded, job.Failed = 0, 0
+	for _, row := range rows {
+		if row.Err != nil {
+			job.Failed++
+		} else {
+			job.Succeeded++
+		}
+	}
+}
+
+// writeImportResult 生成导入结果文件: 原样回显每个数据行, 并追加状态和错误信息列
+// 结果文件与导入文件格式相同, 修正失败行后可直接重新上传, 追加列会被忽略
+// Upsert 时成功行的信息列注明实际执行的操作
+func writeImportResult(fullPath string, file FileOptions, header []string, rows []*ImportRow, job *model.EntityJob) error {
+	// 原文件已带结果列时去掉, 避免重复追加
+	columns := make([]int, 0, len(header))
+	codes := make([]string, 0, len(header)+2)
+	for index, code := range header {
+		if code == importStatusColumn || code == importMessageColumn {
+			continue
+		}
+		columns = append(columns, index)
+		codes = append(codes, code)
+	}
+	codes = append(codes, importStatusColumn, importMessageColumn)
+
+	w, err := newRecordWriter(fullPath, file, codes)
+	if err != nil {
+		return err
+	}
+	xw, _ := w.(*xlsxRecordWriter)
+
+	okStatus := ImportRowSucceeded
+	if job.DryRun {
+		okStatus = ImportRowValid
+	}
+
+	values := make([]any, 0, len(codes))
+	for _, importRow := range rows {
+		values = values[:0]
+		for _, index := range columns {
+			value := ""
+			if index < len(importRow.Cells) {
+				value = importRow.Cells[index]
+			}
+			values = append(values, value)
+		}
+
+		if importRow.Err != nil {
+			values = append(values, ImportRowFailed, importRow.Err.Error())
+			var err error
+			if xw != nil {
+				err = xw.writeFailed(values, len(columns))
+			} else {
+				err = w.Write(values)
+			}
+			if err != nil {
+				w.Close()
+				return err
+			}
+			continue
+		}
+		message := ""
+		if job.Operation == model.ImportOperationUpsert {
+			message = importRow.Operation
+		}
+		values = append(values, okStatus, message)
+		if err := w.Write(values); err != nil {
+			w.Close()
+			return err
+		}
+	}
+
+	return w.Close()
+}
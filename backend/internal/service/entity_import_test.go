@@ -0,0 +1,128 @@
+package service
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"piemdm/internal/model"
+	mock_repository "piemdm/test/mocks/repository"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWriteImportResult(t *testing.T) {
+	fullPath := filepath.Join(t.TempDir(), "result.xlsx")
+	// 重新上传的结果文件自带结果列, 不应重复追加
+	header := []string{"code", "name", importStatusColumn, importMessageColumn}
+	rows := []*ImportRow{
+		{Line: 2, Cells: []string{"A001", "Alpha", "Failed", "old error"}},
+		{Line: 3, Cells: []string{"A002"}, Err: &ValidationError{Errors: []FieldError{
+			{Field: "name", Name: "名称", Message: "字段 '名称' 为必填项"},
+		}}},
+		{Line: 4, Cells: []string{"A003", "Gamma"}, Err: errors.New("记录 3 不存在")},
+	}
+
+	require.NoError(t, writeImportResult(fullPath, FileOptions{Format: FileFormatXLSX}, header, rows, &model.EntityJob{Operation: model.ImportOperationCreate}))
+
+	file, err := excelize.OpenFile(fullPath)
+	require.NoError(t, err)
+	defer file.Close()
+	got, err := file.GetRows(xlsxSheet)
+	require.NoError(t, err)
+
+	// GetRows 不返回行尾的空单元格
+	require.Len(t, got, 4)
+	assert.Equal(t, []string{"code", "name", importStatusColumn, importMessageColumn}, got[0])
+	assert.Equal(t, []string{"A001", "Alpha", ImportRowSucceeded}, got[1])
+	assert.Equal(t, []string{"A002", "", ImportRowFailed, "字段 '名称' 为必填项"}, got[2])
+	assert.Equal(t, []string{"A003", "Gamma", ImportRowFailed, "记录 3 不存在"}, got[3])
+
+	// 失败行的结果列标红
+	for cell, red := range map[string]bool{"B4": false, "C4": true, "D4": true, "C2": false} {
+		styleID, err := file.GetCellStyle(xlsxSheet, cell)
+		require.NoError(t, err)
+		style, err := file.GetStyle(styleID)
+		require.NoError(t, err)
+		assert.Equal(t, red, style.Font != nil && style.Font.Color == "FF0000", cell)
+	}
+}
+
+func TestResolveUpsertRow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	entityRepo := mock_repository.NewMockEntityRepository(ctrl)
+	s := &entityService{entityRepository: entityRepo}
+	ij := &importJob{job: &model.EntityJob{TableCode: "product"}, keyFields: []string{"code"}}
+	seenKeys := make(map[string]int)
+
+	// 匹配到记录时修改该记录, 忽略文件中的 id
+	entityRepo.EXPECT().Find("product", "id", map[string]any{"code": "A"}).Return([]map[string]any{{"id": uint64(7)}}, nil)
+	row := &ImportRow{Line: 2, Operation: model.ImportOperationUpsert, Data: map[string]any{"code": "A", "id": "99"}}
+	require.NoError(t, s.resolveUpsertRow(ij, row, seenKeys))
+	assert.Equal(t, model.ImportOperationUpdate, row.Operation)
+	assert.Equal(t, uint64(7), row.Data["id"])
+
+	// 查询失败时该行失败, 不能按新增处理
+	entityRepo.EXPECT().Find("product", "id", map[string]any{"code": "B"}).Return(nil, errors.New("connection reset"))
+	row = &ImportRow{Line: 3, Operation: model.ImportOperationUpsert, Data: map[string]any{"code": "B"}}
+	assert.EqualError(t, s.resolveUpsertRow(ij, row, seenKeys), "查询匹配的记录失败: connection reset")
+	assert.Equal(t, model.ImportOperationUpsert, row.Operation)
+}
+
+func TestImportRowID(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		want    uint
+		wantErr bool
+	}{
+		{"int", 12, 12, false},
+		{"string", " 34 ", 34, false},
+		{"float", float64(56), 56, false},
+		{"missing", nil, 0, true},
+		{"not a number", "abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := importRowID(map[string]any{"id": tt.value})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, id)
+		})
+	}
+}
+
+func TestCheckImportAccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	entityRepo := mock_repository.NewMockEntityRepository(ctrl)
+	s := &entityService{entityRepository: entityRepo}
+	// 不能查看 cost, 不能修改 grade
+	ij := &importJob{
+		job:    &model.EntityJob{TableCode: "product"},
+		access: &FieldAccess{Hidden: map[string]bool{"cost": true}, Readonly: map[string]bool{"grade": true}},
+	}
+
+	err := s.checkImportAccess(ij, &ImportRow{Operation: model.ImportOperationCreate, Data: map[string]any{"name": "A", "cost": "10"}})
+	assert.EqualError(t, err, "没有权限填写字段: cost")
+	assert.NoError(t, s.checkImportAccess(ij, &ImportRow{Operation: model.ImportOperationCreate, Data: map[string]any{"name": "A", "cost": ""}}))
+
+	// 修改时可以提交原值
+	entityRepo.EXPECT().Find("product", "*", map[string]any{"id": []uint{1}}).
+		Return([]map[string]any{{"id": uint64(1), "grade": "A"}}, nil).Times(2)
+	assert.NoError(t, s.checkImportAccess(ij, &ImportRow{Operation: model.ImportOperationUpdate, Data: map[string]any{"id": "1", "grade": "A"}}))
+	err = s.checkImportAccess(ij, &ImportRow{Operation: model.ImportOperationUpdate, Data: map[string]any{"id": "1", "grade": "B"}})
+	assert.EqualError(t, err, "没有权限修改字段: grade")
+
+	// 没有字段组限制时不检查
+	ij.access = nil
+	assert.NoError(t, s.checkImportAccess(ij, &ImportRow{Operation: model.ImportOperationCreate, Data: map[string]any{"cost": "10"}}))
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// defaultJobResultTTL 任务结果文件默认保留时长
+const defaultJobResultTTL = 24 * time.Hour
+
+type EntityJobService interface {
+	Get(code string) (*model.EntityJob, error)
+	List(page, pageSize int, total *int64, where map[string]any) ([]*model.EntityJob, error)
+	Create(c *gin.Context, job *model.EntityJob) error
+	Save(job *model.EntityJob) error
+
+	// 任务状态流转
+	Start(job *model.EntityJob) error
+	Finish(job *model.EntityJob, resultFile string) error
+	Fail(job *model.EntityJob, err error) error
+
+	// ResultPath 结果文件的本地保存路径
+	ResultPath(filename string) string
+	// CleanExpired 清理已过期的结果文件
+	CleanExpired() error
+}
+
+type entityJobService struct {
+	*Service
+	entityJobRepository repository.EntityJobRepository
+	conf                *viper.Viper
+}
+
+func NewEntityJobService(service *Service, entityJobRepository repository.EntityJobRepository, conf *viper.Viper) EntityJobService {
+	return &entityJobService{
+		Service:             service,
+		entityJobRepository: entityJobRepository,
+		conf:                conf,
+	}
+}
+
+func (s *entityJobService) Get(code string) (*model.EntityJob, error) {
+	return s.entityJobRepository.FirstByCode(code)
+}
+
+func (s *entityJobService) List(page, pageSize int, total *int64, where map[string]any) ([]*model.EntityJob, error) {
+	return s.entityJobRepository.FindPage(page, pageSize, total, where)
+}
+
+func (s *entityJobService) Create(c *gin.Context, job *model.EntityJob) error {
+	// 顺带清理过期文件, 失败不影响新任务
+	if err := s.CleanExpired(); err != nil {
+		s.logger.Warn("清理过期任务文件失败", "err", err)
+	}
+
+	job.Code = strings.ToUpper(uuid.New().String())
+	job.Status = model.EntityJobStatusPending
+	job.CreatedBy = c.GetString("user_name")
+	return s.entityJobRepository.Create(job)
+}
+
+func (s *entityJobService) Save(job *model.EntityJob) error {
+	return s.entityJobRepository.Save(job)
+}
+
+func (s *entityJobService) Start(job *model.EntityJob) error {
+	now := time.Now()
+	job.Status = model.EntityJobStatusRunning
+	job.StartedAt = &now
+	return s.entityJobRepository.Save(job)
+}
+
+func (s *entityJobService) Finish(job *model.EntityJob, resultFile string) error {
+	now := time.Now()
+	expiresAt := now.Add(s.resultTTL())
+	job.Status = model.EntityJobStatusSucceeded
+	job.ResultFile = resultFile
+	job.FinishedAt = &now
+	job.ExpiresAt = &expiresAt
+	return s.entityJobRepository.Save(job)
+}
+
+func (s *entityJobService) Fail(job *model.EntityJob, err error) error {
+	now := time.Now()
+	job.Status = model.EntityJobStatusFailed
+	job.FinishedAt = &now
+	job.Message = truncateMessage(err.Error(), 512)
+	return s.entityJobRepository.Save(job)
+}
+
+func (s *entityJobService) ResultPath(filename string) string {
+	return s.conf.GetString("app.runtime-root-path") + s.conf.GetString("app.export-save-path") + filename
+}
+
+func (s *entityJobService) CleanExpired() error {
+	jobs, err := s.entityJobRepository.FindExpired(time.Now())
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if err := os.Remove(s.ResultPath(job.ResultFile)); err != nil && !os.IsNotExist(err) {
+			s.logger.Warn("删除过期任务文件失败", "file", job.ResultFile, "err", err)
+			continue
+		}
+		job.ResultFile = ""
+		if err := s.entityJobRepository.Save(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resultTTL 结果文件保留时长, 配置项 app.job-result-ttl (如 24h)
+func (s *entityJobService) resultTTL() time.Duration {
+	if ttl := s.conf.GetDuration("app.job-result-ttl"); ttl > 0 {
+		return ttl
+	}
+	return defaultJobResultTTL
+}
+
+// snapshotJob 启动后台任务前的任务副本, 返回给调用方; 原任务由后台任务继续修改
+func snapshotJob(job *model.EntityJob) *model.EntityJob {
+	snapshot := *job
+	return &snapshot
+}
+
+// truncateMessage 按字符截断信息, 避免超出字段长度
+func truncateMessage(message string, size int) string {
+	runes := []rune(message)
+	if len(runes) <= size {
+		return message
+	}
+	return string(runes[:size])
+}
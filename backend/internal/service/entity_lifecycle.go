@@ -0,0 +1,61 @@
+package service
+
+import (
+	"fmt"
+
+	"piemdm/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatusError 记录当前状态不允许执行操作, 如修改已锁定的数据、解冻未冻结的数据
+type StatusError struct {
+	ID        uint   `json:"id"`
+	Status    string `json:"status"`
+	Operation string `json:"operation"`
+}
+
+func (e *StatusError) Error() string {
+	status := e.Status
+	if status == "" {
+		status = model.StatusNormal
+	}
+	operation := model.ConvertLegacyCodeToOperation(e.Operation)
+	return fmt.Sprintf("记录 %d 当前状态为 %s, 不能%s", e.ID, status, model.GetOperationNameByOperation(operation))
+}
+
+// checkStatusTransition 检查记录当前状态能否执行操作, 不能时返回 StatusError
+func checkStatusTransition(row map[string]any, operation string) error {
+	status, _ := row["status"].(string)
+	if model.CanTransitionEntityStatus(status, operation) {
+		return nil
+	}
+	id, _ := importRowID(row)
+	return &StatusError{ID: id, Status: status, Operation: operation}
+}
+
+// checkStatus 按状态转换规则检查记录能否执行操作
+func (s *entityService) checkStatus(tableCode, operation string, ids []uint) error {
+	rows, err := s.entityRepository.Find(tableCode, "id,status", map[string]any{"id in": ids})
+	if err != nil {
+		return fmt.Errorf("查询数据状态失败: %v", err)
+	}
+	for _, row := range rows {
+		if err := checkStatusTransition(row, operation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ChangeStatus 执行生命周期操作 (冻结、解冻、锁定、解锁、作废、扩展)
+// 与批量修改相同: 表配置了该操作的审批流程时提交审批, 否则直接生效并记录变更历史
+func (s *entityService) ChangeStatus(c *gin.Context, tableCode, operation, reason string, ids []uint, versions map[uint]uint) error {
+	if !model.IsLifecycleOperation(operation) {
+		return fmt.Errorf("不支持的生命周期操作: %s", operation)
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("请选择要%s的记录", model.GetOperationNameByOperation(operation))
+	}
+	return s.BatchUpdate(c, tableCode, reason, ids, versions, map[string]any{"operation": operation})
+}
@@ -0,0 +1,864 @@
+package service
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"piemdm/internal/constants"
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxMergeRecords 一次最多合并的记录数 (含主记录)
+const maxMergeRecords = 20
+
+// MergeRequest 合并请求: 被合并记录的值按字段的保留规则写入主记录
+type MergeRequest struct {
+	SurvivorID uint            `json:"survivor_id"` // 主记录
+	MergedIDs  []uint          `json:"merged_ids"`  // 被合并记录, 合并后作废
+	Picks      map[string]uint `json:"picks"`       // 人工选择: 字段编码 -> 取值的记录 id, 优先于保留规则
+}
+
+// MergePreview 合并预览, 不修改数据
+type MergePreview struct {
+	Survivor   map[string]any     `json:"survivor"`   // 合并后的主记录
+	Values     map[string]any     `json:"values"`     // 主记录有变化的字段值
+	Sources    map[string]uint    `json:"sources"`    // 有变化的字段取值的记录 id
+	Records    []map[string]any   `json:"records"`    // 参与合并的记录, 主记录在前
+	References []*EntityReference `json:"references"` // 引用被合并记录的数据, 合并后改为引用主记录
+}
+
+// MergeReference 合并时改为引用主记录的一个字段值, 取消合并时恢复
+type MergeReference struct {
+	TableCode string `json:"table_code"`
+	FieldCode string `json:"field_code"`
+	EntityID  uint   `json:"entity_id"`
+	Before    any    `json:"before"`
+	After     any    `json:"after"`
+}
+
+// mergePlan 按保留规则计算出的合并结果
+type mergePlan struct {
+	records  []map[string]any // 主记录在前
+	values   map[string]any
+	sources  map[string]uint
+	survivor map[string]any
+}
+
+// mergeFields 参与合并的字段: 系统字段、自动编码、公式和唯一字段保留主记录的值
+// 唯一字段取被合并记录的值会与作废的记录冲突
+func mergeFields(fields []*model.TableField) []*model.TableField {
+	var result []*model.TableField
+	for _, field := range fields {
+		if constants.IsSystemFieldCode(field.Code) || field.FieldType == "autocode" || isFormulaField(field) || field.IsUnique == "Yes" {
+			continue
+		}
+		result = append(result, field)
+	}
+	return result
+}
+
+// survivorshipOf 字段的保留规则, 未配置时为默认规则
+func survivorshipOf(field *model.TableField) *model.FieldSurvivorship {
+	if field.Options != nil && field.Options.Survivorship != nil {
+		return field.Options.Survivorship
+	}
+	return &model.FieldSurvivorship{}
+}
+
+// recordUpdatedAt 记录的最后修改时间, 没有时取创建时间
+func recordUpdatedAt(row map[string]any) time.Time {
+	for _, key := range []string{"updated_at", "created_at"} {
+		switch v := row[key].(type) {
+		case time.Time:
+			return v
+		case *time.Time:
+			if v != nil {
+				return *v
+			}
+		case string:
+			for _, layout := range []string{time.RFC3339Nano, fileDateTimeLayout} {
+				if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+					return t
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// mergeValueEmpty 字段值是否为空, 多值字段没有值时也为空
+func mergeValueEmpty(field *model.TableField, value any) bool {
+	return historyValue(field, value) == nil
+}
+
+// mergeValueSize 字段值的完整程度: 多值字段为值的个数, 其它为文本长度
+func mergeValueSize(field *model.TableField, value any) int {
+	switch v := historyValue(field, value).(type) {
+	case nil:
+		return 0
+	case []string:
+		return len(v)
+	case []any:
+		return len(v)
+	case string:
+		return len([]rune(v))
+	default:
+		return len(fmt.Sprintf("%v", v))
+	}
+}
+
+// surviveField 按保留规则选择字段取值的记录, records 中主记录在前
+// 按最近修改排序时修改时间相同的记录保持原顺序, 即优先主记录
+func surviveField(field *model.TableField, records []map[string]any) map[string]any {
+	rule := survivorshipOf(field)
+	var candidates []map[string]any
+	for _, record := range records {
+		if !mergeValueEmpty(field, record[field.Code]) {
+			candidates = append(candidates, record)
+		}
+	}
+	survivor := records[0]
+	if len(candidates) == 0 {
+		return survivor
+	}
+	byRecent := func(a, b map[string]any) int {
+		return recordUpdatedAt(b).Compare(recordUpdatedAt(a))
+	}
+
+	switch rule.Strategy {
+	case model.SurvivorshipMostRecent:
+		slices.SortStableFunc(candidates, byRecent)
+	case model.SurvivorshipSourcePriority:
+		rank := func(record map[string]any) int {
+			source := fmt.Sprintf("%v", exportFieldValue("Text", record[rule.SourceField]))
+			if i := slices.Index(rule.Sources, source); i >= 0 {
+				return i
+			}
+			return len(rule.Sources)
+		}
+		slices.SortStableFunc(candidates, func(a, b map[string]any) int {
+			return cmp.Or(cmp.Compare(rank(a), rank(b)), byRecent(a, b))
+		})
+	case model.SurvivorshipMostComplete:
+		slices.SortStableFunc(candidates, func(a, b map[string]any) int {
+			return cmp.Compare(mergeValueSize(field, b[field.Code]), mergeValueSize(field, a[field.Code]))
+		})
+	default:
+		// 默认规则和人工选择 (未选择时): 保留主记录的值, 为空时取最近修改的记录的非空值
+		if !mergeValueEmpty(field, survivor[field.Code]) {
+			return survivor
+		}
+		slices.SortStableFunc(candidates, byRecent)
+	}
+	return candidates[0]
+}
+
+// planMerge 计算合并后主记录的值, picks 为人工选择的取值记录
+func planMerge(fields []*model.TableField, records []map[string]any, picks map[string]uint) (*mergePlan, error) {
+	fields = mergeFields(fields)
+	byID := make(map[uint]map[string]any, len(records))
+	for _, record := range records {
+		id, _ := uintValue(record["id"])
+		byID[id] = record
+	}
+	definitions := make(map[string]*model.TableField, len(fields))
+	for _, field := range fields {
+		definitions[field.Code] = field
+	}
+
+	var errs []FieldError
+	for code, id := range picks {
+		if definitions[code] == nil {
+			errs = append(errs, FieldError{Field: code, Message: fmt.Sprintf("字段 %s 不能选择取值", code)})
+		} else if byID[id] == nil {
+			errs = append(errs, FieldError{Field: code, Message: fmt.Sprintf("字段 %s 选择的记录 %d 不在合并范围内", code, id)})
+		}
+	}
+	if len(errs) > 0 {
+		slices.SortFunc(errs, func(a, b FieldError) int { return cmp.Compare(a.Field, b.Field) })
+		return nil, &ValidationError{Errors: errs}
+	}
+
+	survivor := records[0]
+	plan := &mergePlan{
+		records:  records,
+		values:   make(map[string]any),
+		sources:  make(map[string]uint),
+		survivor: maps.Clone(survivor),
+	}
+	for _, field := range fields {
+		source := surviveField(field, records)
+		if id, ok := picks[field.Code]; ok {
+			source = byID[id]
+		}
+		value := source[field.Code]
+		if historyEqual(historyValue(field, survivor[field.Code]), historyValue(field, value)) {
+			continue
+		}
+		plan.values[field.Code] = value
+		plan.sources[field.Code], _ = uintValue(source["id"])
+		plan.survivor[field.Code] = value
+	}
+	return plan, nil
+}
+
+// orderMergeRecords 按 ids 的顺序排列记录, 缺少记录时返回错误
+func orderMergeRecords(rows []map[string]any, ids []uint) ([]map[string]any, error) {
+	byID := make(map[uint]map[string]any, len(rows))
+	for _, row := range rows {
+		id, _ := uintValue(row["id"])
+		byID[id] = row
+	}
+	records := make([]map[string]any, 0, len(ids))
+	for _, id := range ids {
+		row, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("记录 %d 不存在", id)
+		}
+		records = append(records, row)
+	}
+	return records, nil
+}
+
+// checkMergeStatus 主记录必须可以修改, 被合并记录必须可以作废
+func checkMergeStatus(records []map[string]any) error {
+	for i, record := range records {
+		operation := model.OperationVoid
+		if i == 0 {
+			operation = model.OperationUpdate
+		}
+		if err := checkStatusTransition(record, operation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadMerge 读取参与合并的记录并按保留规则计算合并结果
+func (s *entityService) loadMerge(c *gin.Context, tableCode string, req *MergeRequest) (*mergePlan, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
+	}
+	if req.SurvivorID == 0 || len(req.MergedIDs) == 0 {
+		return nil, fmt.Errorf("请选择主记录和被合并的记录")
+	}
+	ids := append([]uint{req.SurvivorID}, req.MergedIDs...)
+	if len(ids) > maxMergeRecords {
+		return nil, fmt.Errorf("一次最多合并 %d 条记录", maxMergeRecords)
+	}
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return nil, fmt.Errorf("记录 %d 重复", id)
+		}
+		seen[id] = true
+	}
+
+	rows, err := s.entityRepository.Find(tableCode, "*", map[string]any{"id in": ids})
+	if err != nil {
+		return nil, err
+	}
+	records, err := orderMergeRecords(rows, ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMergeStatus(records); err != nil {
+		return nil, err
+	}
+	fields, err := s.tableFieldService.Find("", map[string]any{"table_code": tableCode, "status": "Normal"})
+	if err != nil {
+		return nil, fmt.Errorf("获取表字段失败: %v", err)
+	}
+	return planMerge(fields, records, req.Picks)
+}
+
+// PreviewMerge 预览合并结果和需要改为引用主记录的数据
+func (s *entityService) PreviewMerge(c *gin.Context, tableCode string, req *MergeRequest) (*MergePreview, error) {
+	plan, err := s.loadMerge(c, tableCode, req)
+	if err != nil {
+		return nil, err
+	}
+	references, err := s.approvalService.FindReferences(c, tableCode, req.MergedIDs)
+	if err != nil {
+		return nil, err
+	}
+	return &MergePreview{
+		Survivor:   plan.survivor,
+		Values:     plan.values,
+		Sources:    plan.sources,
+		Records:    plan.records,
+		References: references,
+	}, nil
+}
+
+// Merge 合并记录: 表配置了合并审批流程 (实体编码 merge:<表编码>, 操作 Merge) 时提交审批, 审批通过后合并; 否则直接合并
+// 合并时主记录按保留规则更新, 引用被合并记录的关系字段改为引用主记录, 被合并记录作废
+func (s *entityService) Merge(c *gin.Context, tableCode, reason string, req *MergeRequest) (*model.EntityMerge, error) {
+	plan, err := s.loadMerge(c, tableCode, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPendingMerges(tableCode, append([]uint{req.SurvivorID}, req.MergedIDs...)); err != nil {
+		return nil, err
+	}
+
+	mergedIDs, _ := json.Marshal(req.MergedIDs)
+	values, err := json.Marshal(plan.values)
+	if err != nil {
+		return nil, fmt.Errorf("合并的字段值无法转换为 JSON: %v", err)
+	}
+	sources, _ := json.Marshal(plan.sources)
+	// 合并的字段值按主记录当前版本计算, 合并生效时主记录版本不一致则不合并
+	survivorVersion, _ := uintValue(plan.records[0]["version"])
+	merge := &model.EntityMerge{
+		Code:            strings.ToUpper(uuid.New().String()),
+		TableCode:       tableCode,
+		SurvivorID:      req.SurvivorID,
+		SurvivorVersion: survivorVersion,
+		MergedIDs:       mergedIDs,
+		Values:          values,
+		Sources:         sources,
+		Status:          model.EntityMergePending,
+		Reason:          reason,
+		CreatedBy:       c.GetString("user_name"),
+		UpdatedBy:       c.GetString("user_name"),
+	}
+
+	entityCode := model.MergeApprovalEntity(tableCode)
+	tableApprovalDefs, err := s.tableApprovalDefinitionRepository.List(entityCode, model.OperationMerge)
+	if err != nil {
+		s.logger.Error("查询审批流程定义失败", "err", err)
+	}
+	if len(tableApprovalDefs) > 0 {
+		merge.ApprovalCode = strings.ToUpper(uuid.New().String())
+	}
+	if err := s.entityMergeRepository.Create(c, merge); err != nil {
+		return nil, err
+	}
+	if len(tableApprovalDefs) == 0 {
+		if err := s.approvalService.ApplyMerge(c, merge); err != nil {
+			s.rejectFailedMerge(c, merge)
+			return nil, err
+		}
+		return merge, nil
+	}
+
+	approvalInfo := map[string]string{
+		"operation":     model.OperationMerge,
+		"approvalCode":  merge.ApprovalCode,
+		"operationName": "合并",
+		"action":        model.ActionUpdate,
+		"reason":        reason,
+		"entityCode":    entityCode,
+	}
+	formData := map[string]any{
+		"table_code":  tableCode,
+		"merge_code":  merge.Code,
+		"survivor_id": req.SurvivorID,
+		"merged_ids":  req.MergedIDs,
+		"values":      plan.values,
+	}
+	if err := s.approvalService.CreateApprovalFlow(c, entityCode, approvalInfo, formData); err != nil {
+		s.rejectFailedMerge(c, merge)
+		return nil, err
+	}
+	return merge, nil
+}
+
+// rejectFailedMerge 合并或提交审批失败时合并记录改为 Rejected, 以免记录一直处于审批中而不能再次合并
+func (s *entityService) rejectFailedMerge(c *gin.Context, merge *model.EntityMerge) {
+	merge.Status = model.EntityMergeRejected
+	if err := s.entityMergeRepository.Update(c, merge); err != nil {
+		s.logger.Error("更新合并状态失败", "err", err, "merge", merge.Code)
+	}
+}
+
+// checkPendingMerges 记录已在审批中的合并里时不能再次合并
+func (s *entityService) checkPendingMerges(tableCode string, ids []uint) error {
+	pending, err := s.entityMergeRepository.Find(map[string]any{
+		"table_code": tableCode,
+		"status":     model.EntityMergePending,
+	})
+	if err != nil {
+		return err
+	}
+	for _, merge := range pending {
+		for _, id := range mergeRecordIDs(merge) {
+			if slices.Contains(ids, id) {
+				return fmt.Errorf("记录 %d 在审批中的合并 %s 中", id, merge.Code)
+			}
+		}
+	}
+	return nil
+}
+
+// mergeRecordIDs 合并涉及的记录 id, 主记录在前
+func mergeRecordIDs(merge *model.EntityMerge) []uint {
+	var mergedIDs []uint
+	_ = json.Unmarshal(merge.MergedIDs, &mergedIDs)
+	return append([]uint{merge.SurvivorID}, mergedIDs...)
+}
+
+// ListMerges 分页查询表的合并记录
+func (s *entityService) ListMerges(c *gin.Context, tableCode string, page, pageSize int, total *int64, where map[string]any) ([]*model.EntityMerge, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
+	}
+	if where == nil {
+		where = make(map[string]any)
+	}
+	where["table_code"] = tableCode
+	return s.entityMergeRepository.FindPage(page, pageSize, total, where)
+}
+
+// GetMerge 查询合并记录
+func (s *entityService) GetMerge(c *gin.Context, tableCode string, id uint) (*model.EntityMerge, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
+	}
+	merge, err := s.entityMergeRepository.FindOne(id)
+	if err != nil {
+		return nil, err
+	}
+	if merge.TableCode != tableCode {
+		return nil, fmt.Errorf("merge %d does not belong to table %s", id, tableCode)
+	}
+	return merge, nil
+}
+
+// Unmerge 取消合并: 恢复主记录被合并修改的字段、被合并记录的状态和被改为引用主记录的数据
+// 合并后又被修改过的值保留当前值
+func (s *entityService) Unmerge(c *gin.Context, tableCode, reason string, id uint) error {
+	merge, err := s.GetMerge(c, tableCode, id)
+	if err != nil {
+		return err
+	}
+	if merge.Status != model.EntityMergeMerged {
+		return fmt.Errorf("合并 %s 的状态为 %s, 不能取消", merge.Code, merge.Status)
+	}
+	return s.approvalService.RevertMerge(c, merge, reason)
+}
+
+// mergeSnapshot 合并前的记录: 参与合并的字段按变更历史的格式保存, 以便恢复时写回
+func mergeSnapshot(fields []*model.TableField, records []map[string]any) []map[string]any {
+	snapshot := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		item := map[string]any{
+			"id":        record["id"],
+			"status":    record["status"],
+			"action":    record["action"],
+			"operation": record["operation"],
+		}
+		for _, field := range fields {
+			item[field.Code] = historyValue(field, record[field.Code])
+		}
+		snapshot = append(snapshot, item)
+	}
+	return snapshot
+}
+
+// restoreValue 将快照中的值转换为写入数据表的值: 多值字段 (多对多除外) 以 JSON 数组存储
+func restoreValue(field *model.TableField, value any) any {
+	if items, ok := value.([]any); ok && (field == nil || field.FieldType != "manytomany") {
+		data, _ := json.Marshal(items)
+		return string(data)
+	}
+	return value
+}
+
+// mergeReferenceValue 引用字段的值中被合并记录的值替换为主记录的值, 多值字段去除重复
+func mergeReferenceValue(ref *fieldReference, value any, merged []string, target string) any {
+	if !ref.multiple {
+		return target
+	}
+	var values []string
+	for _, item := range referenceValues(value, true) {
+		if slices.Contains(merged, item) {
+			item = target
+		}
+		if !slices.Contains(values, item) {
+			values = append(values, item)
+		}
+	}
+	if ref.field.FieldType == "manytomany" {
+		return values
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// ApplyMerge 执行合并: 在同一事务中更新主记录、改为引用主记录、作废被合并记录, 并保存合并前的快照
+func (s *approvalService) ApplyMerge(c *gin.Context, merge *model.EntityMerge) error {
+	tableCode := merge.TableCode
+	ids := mergeRecordIDs(merge)
+	var values map[string]any
+	if err := json.Unmarshal(merge.Values, &values); err != nil {
+		return fmt.Errorf("合并的字段值无效: %v", err)
+	}
+
+	rows, err := s.entityRepository.Find(tableCode, "*", map[string]any{"id in": ids})
+	if err != nil {
+		return err
+	}
+	records, err := orderMergeRecords(rows, ids)
+	if err != nil {
+		return err
+	}
+	if err := checkMergeStatus(records); err != nil {
+		return err
+	}
+	fields, err := s.tableFieldService.Find("", map[string]any{"table_code": tableCode, "status": "Normal"})
+	if err != nil {
+		return fmt.Errorf("获取表字段失败: %v", err)
+	}
+
+	// 提交合并后主记录已被修改时不合并, 避免覆盖其他人的修改
+	survivor, merged := records[0], records[1:]
+	if conflict := versionConflict(fields, merge.SurvivorID, merge.SurvivorVersion, survivor, values); conflict != nil {
+		return &ConflictError{Conflicts: []*EntityConflict{conflict}}
+	}
+
+	// 被合并记录的引用改为引用主记录, 参与合并的记录之间的引用不修改
+	exclude := make(map[string]bool, len(records))
+	for _, record := range records {
+		exclude[referenceKey(tableCode, record)] = true
+	}
+	references, err := s.findReferences(tableCode, merged, exclude)
+	if err != nil {
+		return err
+	}
+	after := maps.Clone(survivor)
+	maps.Copy(after, values)
+	var changes []*MergeReference
+	for _, ref := range references {
+		target := labelValues(after[ref.reference.valueField])
+		if len(target) != 1 {
+			return fmt.Errorf("主记录的 %s 为空, 不能替换 %s.%s 的引用", ref.reference.valueField, ref.TableCode, ref.FieldCode)
+		}
+		for _, row := range ref.Records {
+			id, err := importRowID(row)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, &MergeReference{
+				TableCode: ref.TableCode,
+				FieldCode: ref.FieldCode,
+				EntityID:  id,
+				Before:    historyValue(ref.reference.field, row[ref.FieldCode]),
+				After:     mergeReferenceValue(ref.reference, row[ref.FieldCode], ref.values, target[0]),
+			})
+		}
+	}
+
+	userName := c.GetString("user_name")
+	now := time.Now()
+	survivorMap := maps.Clone(values)
+	maps.Copy(survivorMap, map[string]any{
+		"operation":  model.OperationMerge,
+		"action":     model.ActionUpdate,
+		"updated_by": userName,
+		"updated_at": now,
+	})
+	voidMap := map[string]any{
+		"status":     model.StatusVoided,
+		"operation":  model.OperationMerge,
+		"action":     model.ActionUpdate,
+		"updated_by": userName,
+		"updated_at": now,
+	}
+	mergedIDs := ids[1:]
+	applied := *merge
+	applied.Snapshot, _ = json.Marshal(mergeSnapshot(mergeFields(fields), records))
+	applied.References, _ = json.Marshal(changes)
+	applied.Status = model.EntityMergeMerged
+	applied.MergedAt = &now
+	applied.UpdatedBy = userName
+	err = s.entityMergeRepository.Transaction(c, s.entityRepository, func(repo repository.EntityRepository, merges repository.EntityMergeRepository) error {
+		where := map[string]any{"id": merge.SurvivorID, "version": merge.SurvivorVersion}
+		if err := repo.Update(c, tableCode, survivorMap, where); err != nil {
+			versions := map[uint]uint{merge.SurvivorID: merge.SurvivorVersion}
+			return fmt.Errorf("更新主记录失败: %w", versionError(err, repo, fields, tableCode, versions, values))
+		}
+		if err := repo.BatchUpdate(c, tableCode, mergedIDs, nil, voidMap); err != nil {
+			return fmt.Errorf("作废被合并记录失败: %v", err)
+		}
+		for _, change := range changes {
+			entityMap := map[string]any{change.FieldCode: change.After, "updated_by": userName, "updated_at": now}
+			if err := repo.Update(c, change.TableCode, entityMap, map[string]any{"id": change.EntityID}); err != nil {
+				return fmt.Errorf("替换引用失败: %v", err)
+			}
+		}
+		// 合并记录与数据在同一事务中保存, 保证合并后一定有取消合并所需的快照
+		return merges.Update(c, &applied)
+	})
+	if err != nil {
+		return err
+	}
+	*merge = applied
+
+	// 记录变更历史, 日志记录失败不应该阻断合并
+	event := entityEvent{Operation: model.OperationMerge, Reason: merge.Reason, ApprovalCode: merge.ApprovalCode}
+	if err := writeEntityLog(c, s.entityLogService, tableCode, fields, event, merge.SurvivorID, survivor, survivorMap); err != nil {
+		s.logger.Error("创建变更日志失败", "error", err, "id", merge.SurvivorID)
+	}
+	for i, record := range merged {
+		if err := writeEntityLog(c, s.entityLogService, tableCode, fields, event, mergedIDs[i], record, voidMap); err != nil {
+			s.logger.Error("创建变更日志失败", "error", err, "id", mergedIDs[i])
+		}
+	}
+	s.writeMergeReferenceLogs(c, references, changes, event)
+	return nil
+}
+
+// RevertMerge 取消合并, 恢复合并前快照中的值; 合并后又被修改过的值保留当前值
+func (s *approvalService) RevertMerge(c *gin.Context, merge *model.EntityMerge, reason string) error {
+	tableCode := merge.TableCode
+	var snapshot []map[string]any
+	var changes []*MergeReference
+	var values map[string]any
+	if err := json.Unmarshal(merge.Snapshot, &snapshot); err != nil || len(snapshot) == 0 {
+		return fmt.Errorf("合并 %s 没有合并前的快照, 不能取消", merge.Code)
+	}
+	if err := json.Unmarshal(merge.Values, &values); err != nil {
+		return fmt.Errorf("合并的字段值无效: %v", err)
+	}
+	if len(merge.References) > 0 {
+		if err := json.Unmarshal(merge.References, &changes); err != nil {
+			return fmt.Errorf("合并的引用无效: %v", err)
+		}
+	}
+
+	ids := mergeRecordIDs(merge)
+	rows, err := s.entityRepository.Find(tableCode, "*", map[string]any{"id in": ids})
+	if err != nil {
+		return err
+	}
+	records, err := orderMergeRecords(rows, ids)
+	if err != nil {
+		return err
+	}
+	fields, err := s.tableFieldService.Find("", map[string]any{"table_code": tableCode, "status": "Normal"})
+	if err != nil {
+		return fmt.Errorf("获取表字段失败: %v", err)
+	}
+	definitions := make(map[string]*model.TableField, len(fields))
+	for _, field := range fields {
+		definitions[field.Code] = field
+	}
+
+	userName := c.GetString("user_name")
+	now := time.Now()
+	audit := map[string]any{
+		"operation":  model.OperationUnmerge,
+		"action":     model.ActionUpdate,
+		"updated_by": userName,
+		"updated_at": now,
+	}
+
+	// 主记录: 只恢复合并写入后未再修改的字段
+	survivorMap := maps.Clone(audit)
+	for code, value := range values {
+		field := definitions[code]
+		if field == nil {
+			continue
+		}
+		if !historyEqual(historyValue(field, records[0][code]), historyValue(field, value)) {
+			s.logger.Warn("合并后字段已修改, 保留当前值", "table", tableCode, "id", merge.SurvivorID, "field", code)
+			continue
+		}
+		survivorMap[code] = restoreValue(field, snapshot[0][code])
+	}
+
+	// 被合并记录: 仍为作废状态的恢复合并前的状态
+	restored := make(map[uint]map[string]any)
+	for i, record := range records[1:] {
+		if status, _ := record["status"].(string); status != model.StatusVoided || i+1 >= len(snapshot) {
+			s.logger.Warn("被合并记录已不是作废状态, 不恢复", "table", tableCode, "id", ids[i+1], "status", status)
+			continue
+		}
+		entityMap := maps.Clone(audit)
+		entityMap["status"] = snapshot[i+1]["status"]
+		restored[ids[i+1]] = entityMap
+	}
+
+	// 引用: 只恢复仍引用主记录的值
+	type revertedReference struct {
+		*MergeReference
+		field *model.TableField
+		row   map[string]any
+	}
+	var reverted []*revertedReference
+	for _, change := range changes {
+		field, err := s.referenceField(change.TableCode, change.FieldCode)
+		if err != nil {
+			return err
+		}
+		rows, err := s.entityRepository.Find(change.TableCode, "*", map[string]any{"id": change.EntityID})
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 || !historyEqual(historyValue(field, rows[0][change.FieldCode]), historyValue(field, change.After)) {
+			s.logger.Warn("合并后引用已修改, 保留当前值", "table", change.TableCode, "id", change.EntityID, "field", change.FieldCode)
+			continue
+		}
+		reverted = append(reverted, &revertedReference{
+			MergeReference: &MergeReference{
+				TableCode: change.TableCode,
+				FieldCode: change.FieldCode,
+				EntityID:  change.EntityID,
+				After:     restoreValue(field, change.Before),
+			},
+			field: field,
+			row:   rows[0],
+		})
+	}
+
+	unmerged := *merge
+	unmerged.Status = model.EntityMergeReverted
+	unmerged.RevertedBy = userName
+	unmerged.RevertedAt = &now
+	unmerged.UpdatedBy = userName
+	err = s.entityMergeRepository.Transaction(c, s.entityRepository, func(repo repository.EntityRepository, merges repository.EntityMergeRepository) error {
+		if err := repo.Update(c, tableCode, survivorMap, map[string]any{"id": merge.SurvivorID}); err != nil {
+			return fmt.Errorf("恢复主记录失败: %v", err)
+		}
+		for id, entityMap := range restored {
+			if err := repo.Update(c, tableCode, entityMap, map[string]any{"id": id}); err != nil {
+				return fmt.Errorf("恢复被合并记录失败: %v", err)
+			}
+		}
+		for _, change := range reverted {
+			entityMap := map[string]any{change.FieldCode: change.After, "updated_by": userName, "updated_at": now}
+			if err := repo.Update(c, change.TableCode, entityMap, map[string]any{"id": change.EntityID}); err != nil {
+				return fmt.Errorf("恢复引用失败: %v", err)
+			}
+		}
+		return merges.Update(c, &unmerged)
+	})
+	if err != nil {
+		return err
+	}
+	*merge = unmerged
+
+	event := entityEvent{Operation: model.OperationUnmerge, Reason: reason}
+	if err := writeEntityLog(c, s.entityLogService, tableCode, fields, event, merge.SurvivorID, records[0], survivorMap); err != nil {
+		s.logger.Error("创建变更日志失败", "error", err, "id", merge.SurvivorID)
+	}
+	for i, record := range records[1:] {
+		if entityMap, ok := restored[ids[i+1]]; ok {
+			if err := writeEntityLog(c, s.entityLogService, tableCode, fields, event, ids[i+1], record, entityMap); err != nil {
+				s.logger.Error("创建变更日志失败", "error", err, "id", ids[i+1])
+			}
+		}
+	}
+	for _, change := range reverted {
+		after := map[string]any{change.FieldCode: change.After}
+		if err := writeEntityLog(c, s.entityLogService, change.TableCode, []*model.TableField{change.field}, event, change.EntityID, change.row, after); err != nil {
+			s.logger.Error("创建变更日志失败", "error", err, "table", change.TableCode, "id", change.EntityID)
+		}
+	}
+	return nil
+}
+
+// referenceField 引用字段的定义
+func (s *approvalService) referenceField(tableCode, fieldCode string) (*model.TableField, error) {
+	fields, err := s.tableFieldService.Find("", map[string]any{"table_code": tableCode, "code": fieldCode})
+	if err != nil {
+		return nil, fmt.Errorf("获取表字段失败: %v", err)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("字段 %s.%s 不存在", tableCode, fieldCode)
+	}
+	return fields[0], nil
+}
+
+// writeMergeReferenceLogs 记录改为引用主记录的变更历史
+func (s *approvalService) writeMergeReferenceLogs(c *gin.Context, references []*EntityReference, changes []*MergeReference, event entityEvent) {
+	rows := make(map[string]map[string]any)
+	fields := make(map[string]*model.TableField)
+	for _, ref := range references {
+		fields[ref.TableCode+"."+ref.FieldCode] = ref.reference.field
+		for _, row := range ref.Records {
+			id, _ := importRowID(row)
+			rows[fmt.Sprintf("%s:%d", ref.TableCode, id)] = row
+		}
+	}
+	for _, change := range changes {
+		field := fields[change.TableCode+"."+change.FieldCode]
+		row := rows[fmt.Sprintf("%s:%d", change.TableCode, change.EntityID)]
+		if err := writeEntityLog(c, s.entityLogService, change.TableCode, []*model.TableField{field}, event, change.EntityID, row, map[string]any{change.FieldCode: change.After}); err != nil {
+			s.logger.Error("创建变更日志失败", "error", err, "table", change.TableCode, "id", change.EntityID)
+		}
+	}
+}
+
+// approveMerge 审批通过后执行合并
+func (s *approvalService) approveMerge(c *gin.Context, approval *model.Approval) error {
+	merges, err := s.entityMergeRepository.Find(map[string]any{"approval_code": approval.Code})
+	if err != nil {
+		return err
+	}
+	for _, merge := range merges {
+		if merge.Status != model.EntityMergePending {
+			continue
+		}
+		if err := s.ApplyMerge(c, merge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkMergeVersions 审批通过前检查合并依据的主记录版本, 主记录在提交合并后已被其他人修改时返回 ConflictError
+func (s *approvalService) checkMergeVersions(approval *model.Approval) error {
+	merges, err := s.entityMergeRepository.Find(map[string]any{"approval_code": approval.Code})
+	if err != nil {
+		return err
+	}
+	for _, merge := range merges {
+		if merge.Status != model.EntityMergePending {
+			continue
+		}
+		var values map[string]any
+		if err := json.Unmarshal(merge.Values, &values); err != nil {
+			return fmt.Errorf("合并的字段值无效: %v", err)
+		}
+		fields, err := s.tableFieldService.Find("", map[string]any{"table_code": merge.TableCode, "status": "Normal"})
+		if err != nil {
+			return fmt.Errorf("获取表字段失败: %v", err)
+		}
+		versions := map[uint]uint{merge.SurvivorID: merge.SurvivorVersion}
+		if err := checkVersions(s.entityRepository, fields, merge.TableCode, versions, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rejectMerge 审批拒绝或撤回后, 合并不再执行
+func (s *approvalService) rejectMerge(c *gin.Context, approval *model.Approval) error {
+	merges, err := s.entityMergeRepository.Find(map[string]any{"approval_code": approval.Code})
+	if err != nil {
+		return err
+	}
+	for _, merge := range merges {
+		if merge.Status != model.EntityMergePending {
+			continue
+		}
+		merge.Status = model.EntityMergeRejected
+		merge.UpdatedBy = c.GetString("user_name")
+		if err := s.entityMergeRepository.Update(c, merge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
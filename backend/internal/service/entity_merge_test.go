@@ -0,0 +1,260 @@
+package service_test
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+	"piemdm/internal/service"
+	"piemdm/pkg/helper/sid"
+	"piemdm/pkg/jwt"
+	"piemdm/pkg/log"
+	mock_repository "piemdm/test/mocks/repository"
+	mock_service "piemdm/test/mocks/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mergeMocks struct {
+	entityRepo   *mock_repository.MockEntityRepository
+	fieldService *mock_service.MockTableFieldService
+	logService   *mock_service.MockEntityLogService
+	mergeRepo    *mock_repository.MockEntityMergeRepository
+}
+
+// setupMergeService 表 customer 有字段 name、email, sales_order.customer 按 code 引用 customer
+func setupMergeService(t *testing.T) (service.ApprovalService, *mergeMocks, *gin.Context) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	m := &mergeMocks{
+		entityRepo:   mock_repository.NewMockEntityRepository(ctrl),
+		fieldService: mock_service.NewMockTableFieldService(ctrl),
+		logService:   mock_service.NewMockEntityLogService(ctrl),
+		mergeRepo:    mock_repository.NewMockEntityMergeRepository(ctrl),
+	}
+	reference := relationField("sales_order", "customer", "belongsto", "")
+	m.fieldService.EXPECT().Find("", map[string]any{
+		"status":     "Normal",
+		"field_type": []string{"belongsto", "hasmany", "manytomany"},
+	}).Return([]*model.TableField{reference}, nil).AnyTimes()
+	m.fieldService.EXPECT().Find("", map[string]any{"table_code": "customer", "status": "Normal"}).
+		Return([]*model.TableField{
+			{TableCode: "customer", Code: "name", Name: "名称", Type: "Text"},
+			{TableCode: "customer", Code: "email", Name: "邮箱", Type: "Text"},
+		}, nil).AnyTimes()
+	m.fieldService.EXPECT().Find("", map[string]any{"table_code": "sales_order", "code": "customer"}).
+		Return([]*model.TableField{reference}, nil).AnyTimes()
+	m.entityRepo.EXPECT().Transaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, fn func(repo repository.EntityRepository) error) error {
+			return fn(m.entityRepo)
+		}).AnyTimes()
+	m.mergeRepo.EXPECT().Transaction(gomock.Any(), m.entityRepo, gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, _ repository.EntityRepository, fn func(repository.EntityRepository, repository.EntityMergeRepository) error) error {
+			return fn(m.entityRepo, m.mergeRepo)
+		}).AnyTimes()
+
+	logger := &log.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	baseService := service.NewService(logger, &sid.Sid{}, &jwt.JWT{})
+	s := service.NewApprovalService(baseService, nil, nil, nil, nil, m.entityRepo, m.fieldService, m.logService, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, m.mergeRepo)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_name", "tester")
+	return s, m, c
+}
+
+func TestApprovalService_ApplyAndRevertMerge(t *testing.T) {
+	s, m, c := setupMergeService(t)
+	merge := &model.EntityMerge{
+		Code:            "M1",
+		TableCode:       "customer",
+		SurvivorID:      1,
+		SurvivorVersion: 3,
+		MergedIDs:       json.RawMessage(`[2]`),
+		Values:          json.RawMessage(`{"email":"acme@example.com"}`),
+		Status:          model.EntityMergePending,
+	}
+	customers := []map[string]any{
+		{"id": uint64(2), "code": "C2", "name": "ACME", "email": "acme@example.com", "status": "Normal", "version": uint64(1)},
+		{"id": uint64(1), "code": "C1", "name": "Acme", "email": "", "status": "Normal", "version": uint64(3)},
+	}
+
+	// 合并: 更新主记录、作废被合并记录、sales_order 改为引用 C1
+	m.entityRepo.EXPECT().Find("customer", "*", map[string]any{"id in": []uint{1, 2}}).Return(customers, nil)
+	m.entityRepo.EXPECT().Find("sales_order", "*", map[string]any{
+		"customer": []string{"C2"}, "status <>": "Deleted",
+	}).Return([]map[string]any{{"id": uint64(10), "customer": "C2"}}, nil)
+	m.entityRepo.EXPECT().Update(c, "customer", gomock.Any(), map[string]any{"id": uint(1), "version": uint(3)}).
+		DoAndReturn(func(_ *gin.Context, _ string, entity any, _ map[string]any) error {
+			entityMap := entity.(map[string]any)
+			assert.Equal(t, "acme@example.com", entityMap["email"])
+			assert.Equal(t, model.OperationMerge, entityMap["operation"])
+			return nil
+		})
+	m.entityRepo.EXPECT().BatchUpdate(c, "customer", []uint{2}, gomock.Nil(), gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, _ string, _ []uint, _ map[uint]uint, entityMap map[string]any) error {
+			assert.Equal(t, model.StatusVoided, entityMap["status"])
+			return nil
+		})
+	m.entityRepo.EXPECT().Update(c, "sales_order", gomock.Any(), map[string]any{"id": uint(10)}).
+		DoAndReturn(func(_ *gin.Context, _ string, entity any, _ map[string]any) error {
+			assert.Equal(t, "C1", entity.(map[string]any)["customer"])
+			return nil
+		})
+	m.mergeRepo.EXPECT().Update(c, gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, saved *model.EntityMerge) error {
+			assert.Equal(t, model.EntityMergeMerged, saved.Status)
+			assert.NotEmpty(t, saved.Snapshot)
+			return nil
+		})
+	var operations []string
+	m.logService.EXPECT().Create(c, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, tableCode string, entityLog *model.EntityLog) error {
+			operations = append(operations, tableCode+":"+entityLog.Operation)
+			return nil
+		}).Times(3)
+
+	require.NoError(t, s.ApplyMerge(c, merge))
+	assert.Equal(t, model.EntityMergeMerged, merge.Status)
+	assert.NotNil(t, merge.MergedAt)
+	assert.JSONEq(t, `[{"table_code":"sales_order","field_code":"customer","entity_id":10,"before":"C2","after":"C1"}]`, string(merge.References))
+	assert.Equal(t, []string{"customer:Merge", "customer:Merge", "sales_order:Merge"}, operations)
+
+	// 取消合并: 主记录的 name 合并后被修改过, 保留当前值; 邮箱、状态和引用恢复
+	merge.Values = json.RawMessage(`{"email":"acme@example.com","name":"Acme"}`)
+	m.entityRepo.EXPECT().Find("customer", "*", map[string]any{"id in": []uint{1, 2}}).Return([]map[string]any{
+		{"id": uint64(1), "code": "C1", "name": "Acme Group", "email": "acme@example.com", "status": "Normal"},
+		{"id": uint64(2), "code": "C2", "name": "ACME", "email": "acme@example.com", "status": model.StatusVoided},
+	}, nil)
+	m.entityRepo.EXPECT().Find("sales_order", "*", map[string]any{"id": uint(10)}).
+		Return([]map[string]any{{"id": uint64(10), "customer": "C1"}}, nil)
+	m.entityRepo.EXPECT().Update(c, "customer", gomock.Any(), map[string]any{"id": uint(1)}).
+		DoAndReturn(func(_ *gin.Context, _ string, entity any, _ map[string]any) error {
+			entityMap := entity.(map[string]any)
+			assert.Contains(t, entityMap, "email")
+			assert.Nil(t, entityMap["email"])
+			assert.NotContains(t, entityMap, "name")
+			return nil
+		})
+	m.entityRepo.EXPECT().Update(c, "customer", gomock.Any(), map[string]any{"id": uint(2)}).
+		DoAndReturn(func(_ *gin.Context, _ string, entity any, _ map[string]any) error {
+			assert.Equal(t, "Normal", entity.(map[string]any)["status"])
+			return nil
+		})
+	m.entityRepo.EXPECT().Update(c, "sales_order", gomock.Any(), map[string]any{"id": uint(10)}).
+		DoAndReturn(func(_ *gin.Context, _ string, entity any, _ map[string]any) error {
+			assert.Equal(t, "C2", entity.(map[string]any)["customer"])
+			return nil
+		})
+	m.mergeRepo.EXPECT().Update(c, gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, saved *model.EntityMerge) error {
+			assert.Equal(t, model.EntityMergeReverted, saved.Status)
+			return nil
+		})
+	operations = nil
+	m.logService.EXPECT().Create(c, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, tableCode string, entityLog *model.EntityLog) error {
+			operations = append(operations, tableCode+":"+entityLog.Operation)
+			return nil
+		}).Times(3)
+
+	require.NoError(t, s.RevertMerge(c, merge, "误合并"))
+	assert.Equal(t, model.EntityMergeReverted, merge.Status)
+	assert.Equal(t, "tester", merge.RevertedBy)
+	assert.Equal(t, []string{"customer:Unmerge", "customer:Unmerge", "sales_order:Unmerge"}, operations)
+}
+
+func TestApprovalService_ApplyMerge_Status(t *testing.T) {
+	s, m, c := setupMergeService(t)
+	merge := &model.EntityMerge{
+		TableCode:  "customer",
+		SurvivorID: 1,
+		MergedIDs:  json.RawMessage(`[2]`),
+		Values:     json.RawMessage(`{}`),
+	}
+	// 已锁定的记录不能作废
+	m.entityRepo.EXPECT().Find("customer", "*", map[string]any{"id in": []uint{1, 2}}).Return([]map[string]any{
+		{"id": uint64(1), "code": "C1", "status": "Normal"},
+		{"id": uint64(2), "code": "C2", "status": model.StatusLocked},
+	}, nil)
+
+	err := s.ApplyMerge(c, merge)
+	var statusErr *service.StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, uint(2), statusErr.ID)
+}
+
+func TestApprovalService_ApplyMerge_VersionConflict(t *testing.T) {
+	s, m, c := setupMergeService(t)
+	merge := &model.EntityMerge{
+		TableCode:       "customer",
+		SurvivorID:      1,
+		SurvivorVersion: 3,
+		MergedIDs:       json.RawMessage(`[2]`),
+		Values:          json.RawMessage(`{"email":"acme@example.com"}`),
+		Status:          model.EntityMergePending,
+	}
+	// 提交合并后主记录被修改, 不更新数据也不保存合并记录
+	m.entityRepo.EXPECT().Find("customer", "*", map[string]any{"id in": []uint{1, 2}}).Return([]map[string]any{
+		{"id": uint64(1), "code": "C1", "email": "acme@corp.com", "status": "Normal", "version": uint64(4)},
+		{"id": uint64(2), "code": "C2", "email": "acme@example.com", "status": "Normal", "version": uint64(1)},
+	}, nil)
+
+	err := s.ApplyMerge(c, merge)
+	var conflictErr *service.ConflictError
+	require.ErrorAs(t, err, &conflictErr)
+	require.Len(t, conflictErr.Conflicts, 1)
+	assert.Equal(t, uint(4), conflictErr.Conflicts[0].CurrentVersion)
+	assert.Equal(t, []service.FieldDiff{{Field: "email", Name: "邮箱", Submitted: "acme@example.com", Current: "acme@corp.com"}}, conflictErr.Conflicts[0].Diff)
+	assert.Equal(t, model.EntityMergePending, merge.Status)
+}
+
+func TestEntityService_Merge_ApplyFailed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	entityRepo := mock_repository.NewMockEntityRepository(ctrl)
+	fieldService := mock_service.NewMockTableFieldService(ctrl)
+	approvalDefRepo := mock_repository.NewMockTableApprovalDefinitionRepository(ctrl)
+	approvalService := mock_service.NewMockApprovalService(ctrl)
+	permissionService := mock_service.NewMockTablePermissionService(ctrl)
+	mergeRepo := mock_repository.NewMockEntityMergeRepository(ctrl)
+	s := service.NewEntityService(service.NewService(testLogger, nil, nil), entityRepo, fieldService, nil, approvalDefRepo, approvalService, nil, nil, nil, permissionService, nil, nil, nil, mergeRepo, nil, nil)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_id", uint(1))
+	c.Set("user_name", "tester")
+
+	permissionService.EXPECT().CheckTablePermission(c, uint(1), "customer").Return(true, nil)
+	entityRepo.EXPECT().Find("customer", "*", map[string]any{"id in": []uint{1, 2}}).Return([]map[string]any{
+		{"id": uint64(1), "code": "C1", "name": "Acme", "status": "Normal", "version": uint64(3)},
+		{"id": uint64(2), "code": "C2", "name": "ACME", "status": "Normal", "version": uint64(1)},
+	}, nil)
+	fieldService.EXPECT().Find("", map[string]any{"table_code": "customer", "status": "Normal"}).
+		Return([]*model.TableField{{TableCode: "customer", Code: "name", Name: "名称", Type: "Text"}}, nil)
+	mergeRepo.EXPECT().Find(map[string]any{"table_code": "customer", "status": model.EntityMergePending}).Return(nil, nil)
+	approvalDefRepo.EXPECT().List(model.MergeApprovalEntity("customer"), model.OperationMerge).Return(nil, nil)
+	mergeRepo.EXPECT().Create(c, gomock.Any()).DoAndReturn(func(_ *gin.Context, merge *model.EntityMerge) error {
+		assert.Equal(t, uint(3), merge.SurvivorVersion)
+		return nil
+	})
+	// 没有审批流程时直接合并, 合并失败后合并记录不能一直处于审批中
+	applyErr := &service.ConflictError{Conflicts: []*service.EntityConflict{{ID: 1, Version: 3, CurrentVersion: 4}}}
+	approvalService.EXPECT().ApplyMerge(c, gomock.Any()).Return(applyErr)
+	mergeRepo.EXPECT().Update(c, gomock.Any()).DoAndReturn(func(_ *gin.Context, merge *model.EntityMerge) error {
+		assert.Equal(t, model.EntityMergeRejected, merge.Status)
+		return nil
+	})
+
+	merge, err := s.Merge(c, "customer", "重复客户", &service.MergeRequest{SurvivorID: 1, MergedIDs: []uint{2}})
+	assert.ErrorIs(t, err, applyErr)
+	assert.Nil(t, merge)
+}
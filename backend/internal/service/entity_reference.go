@@ -0,0 +1,531 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"piemdm/internal/constants"
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// referenceDeletedStatus 已删除状态的记录不能被引用, 也不计入引用
+const referenceDeletedStatus = "Deleted"
+
+// isDeleteOperation 判断是否为删除操作, 兼容历史遗留的操作代码
+func isDeleteOperation(operation string) bool {
+	switch operation {
+	case "D", "MD", "Delete", "BatchDelete":
+		return true
+	}
+	return false
+}
+
+// fieldReference 关系字段(多对一、一对多、多对多)的引用配置
+type fieldReference struct {
+	field      *model.TableField
+	target     string         // 被引用表编码
+	valueField string         // 被引用表中存储值的字段
+	filter     map[string]any // 被引用表过滤条件
+	multiple   bool           // 引用多条记录, 多对多字段存储在关联表, 其它以 JSON 数组存储
+	onDelete   string         // 被引用记录删除时的处理方式
+}
+
+// referenceOf 解析关系字段的引用配置, 非关系字段或未配置关联表返回 nil
+func referenceOf(field *model.TableField) *fieldReference {
+	if !constants.IsRelationFieldType(field.FieldType) || field.Options == nil {
+		return nil
+	}
+
+	ref := &fieldReference{field: field, valueField: "code", onDelete: model.RelationOnDeleteRestrict}
+	options := field.Options
+	switch {
+	case options.Relation != nil && options.Relation.Target != "":
+		ref.target = options.Relation.Target
+		ref.filter = options.Relation.Filter
+		ref.multiple = options.Relation.Multiple
+		if options.Relation.ValueField != "" {
+			ref.valueField = options.Relation.ValueField
+		}
+		if options.Relation.OnDelete != "" {
+			ref.onDelete = options.Relation.OnDelete
+		}
+	case options.DataSource != nil && options.DataSource.TargetTable != "":
+		ref.target = options.DataSource.TargetTable
+		ref.filter = options.DataSource.Filter
+		if options.DataSource.ValueField != "" {
+			ref.valueField = options.DataSource.ValueField
+		}
+	default:
+		return nil
+	}
+	// 一对多、多对多字段存储多个被引用记录的值
+	ref.multiple = ref.multiple || field.FieldType != "belongsto"
+
+	if !labelColumnPattern.MatchString(ref.target) ||
+		!labelColumnPattern.MatchString(ref.valueField) ||
+		!labelColumnPattern.MatchString(field.Code) {
+		return nil
+	}
+	return ref
+}
+
+// matchFilter 被引用记录是否满足关联过滤条件 (如字典编码)
+func (r *fieldReference) matchFilter(record map[string]any) bool {
+	for k, v := range r.filter {
+		if fmt.Sprintf("%v", exportFieldValue("Text", record[k])) != fmt.Sprintf("%v", v) {
+			return false
+		}
+	}
+	return true
+}
+
+// referenceValues 拆分关系字段的值: 多值字段支持 JSON 数组、数组和逗号分隔
+func referenceValues(value any, multiple bool) []string {
+	var values []string
+	switch v := value.(type) {
+	case []string:
+		values = v
+	case []any:
+		for _, item := range v {
+			if item != nil {
+				values = append(values, fmt.Sprintf("%v", item))
+			}
+		}
+	default:
+		values = labelValues(value)
+		if multiple && len(values) == 1 && !strings.HasPrefix(values[0], "[") {
+			values = strings.Split(values[0], ",")
+		}
+	}
+
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" && !slices.Contains(result, v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// referenceChecker 校验关系字段引用的记录存在于被引用表
+// 已查询过的值会缓存, 导入时避免逐行重复查询
+type referenceChecker struct {
+	entityRepository repository.EntityRepository
+	references       []*fieldReference
+	found            map[string]map[string]bool // 字段编码 -> 值 -> 是否存在
+}
+
+func newReferenceChecker(entityRepository repository.EntityRepository, fields []*model.TableField) *referenceChecker {
+	rc := &referenceChecker{
+		entityRepository: entityRepository,
+		found:            make(map[string]map[string]bool),
+	}
+	for _, field := range fields {
+		if ref := referenceOf(field); ref != nil {
+			rc.references = append(rc.references, ref)
+			rc.found[field.Code] = make(map[string]bool)
+		}
+	}
+	return rc
+}
+
+// Check 校验 entityMap 中出现的关系字段, 未提交或为空的字段不校验 (必填由字段校验处理)
+func (rc *referenceChecker) Check(entityMap map[string]any) ([]FieldError, error) {
+	var fieldErrors []FieldError
+	for _, ref := range rc.references {
+		value, exists := entityMap[ref.field.Code]
+		if !exists {
+			continue
+		}
+		values := referenceValues(value, ref.multiple)
+		if len(values) == 0 {
+			continue
+		}
+		if !ref.multiple && len(values) > 1 {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   ref.field.Code,
+				Name:    ref.field.Name,
+				Message: fmt.Sprintf("字段 '%s' 只能引用一条记录", ref.field.Name),
+			})
+			continue
+		}
+
+		missing, err := rc.missing(ref, values)
+		if err != nil {
+			return nil, err
+		}
+		if len(missing) > 0 {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   ref.field.Code,
+				Name:    ref.field.Name,
+				Message: fmt.Sprintf("字段 '%s' 引用的记录不存在: %s", ref.field.Name, strings.Join(missing, ", ")),
+			})
+		}
+	}
+	return fieldErrors, nil
+}
+
+// missing 返回在被引用表中不存在的值
+func (rc *referenceChecker) missing(ref *fieldReference, values []string) ([]string, error) {
+	found := rc.found[ref.field.Code]
+	var unknown []string
+	for _, value := range values {
+		if _, ok := found[value]; !ok {
+			unknown = append(unknown, value)
+		}
+	}
+
+	if len(unknown) > 0 {
+		where := make(map[string]any, len(ref.filter)+2)
+		for k, v := range ref.filter {
+			where[k] = v
+		}
+		where[ref.valueField] = unknown
+		where["status <>"] = referenceDeletedStatus
+		// 查询失败时返回错误, 不能当作引用的记录不存在
+		items, err := rc.entityRepository.Find(ref.target, ref.valueField, where)
+		if err != nil {
+			return nil, fmt.Errorf("查询字段 '%s' 引用的记录失败: %v", ref.field.Name, err)
+		}
+		for _, value := range unknown {
+			found[value] = false
+		}
+		for _, item := range items {
+			found[fmt.Sprintf("%v", exportFieldValue("Text", item[ref.valueField]))] = true
+		}
+	}
+
+	var missing []string
+	for _, value := range values {
+		if !found[value] {
+			missing = append(missing, value)
+		}
+	}
+	return missing, nil
+}
+
+// EntityReference 一个关系字段对被查询记录的引用
+type EntityReference struct {
+	TableCode string           `json:"table_code"` // 引用表
+	FieldCode string           `json:"field_code"` // 引用字段
+	FieldName string           `json:"field_name"`
+	OnDelete  string           `json:"on_delete"` // 被引用记录删除时的处理方式
+	Total     int              `json:"total"`
+	Records   []map[string]any `json:"records"` // 引用记录
+
+	reference *fieldReference
+	values    []string // 被引用的值
+}
+
+// ReferenceError 记录仍被其它数据引用 (删除处理方式为 restrict), 不能删除
+type ReferenceError struct {
+	References []*EntityReference `json:"references"`
+}
+
+func (e *ReferenceError) Error() string {
+	messages := make([]string, 0, len(e.References))
+	for _, ref := range e.References {
+		messages = append(messages, fmt.Sprintf("%s.%s %d 条", ref.TableCode, ref.FieldName, ref.Total))
+	}
+	return "记录被其它数据引用, 不能删除: " + strings.Join(messages, "; ")
+}
+
+// referenceKey 记录在引用检查中的唯一标识
+func referenceKey(tableCode string, record map[string]any) string {
+	id, _ := importRowID(record)
+	return fmt.Sprintf("%s:%d", tableCode, id)
+}
+
+// FindReferences 查询引用指定记录的数据 (where used), 按引用表和字段分组
+func (s *approvalService) FindReferences(c *gin.Context, tableCode string, ids []uint) ([]*EntityReference, error) {
+	records, err := s.entityRepository.Find(tableCode, "*", map[string]any{"id in": ids})
+	if err != nil {
+		return nil, err
+	}
+	return s.findReferences(tableCode, records, nil)
+}
+
+// CheckDeleteReferences 删除前检查引用: 存在 restrict 引用时返回 ReferenceError
+// cascade 引用会继续检查被级联删除记录的引用
+func (s *approvalService) CheckDeleteReferences(c *gin.Context, tableCode string, ids []uint) error {
+	records, err := s.entityRepository.Find(tableCode, "*", map[string]any{"id in": ids})
+	if err != nil {
+		return err
+	}
+
+	var restricted []*EntityReference
+	if err := s.collectRestricted(tableCode, records, make(map[string]bool), &restricted); err != nil {
+		return err
+	}
+	if len(restricted) > 0 {
+		return &ReferenceError{References: restricted}
+	}
+	return nil
+}
+
+func (s *approvalService) collectRestricted(tableCode string, records []map[string]any, visited map[string]bool, restricted *[]*EntityReference) error {
+	var pending []map[string]any
+	for _, record := range records {
+		if key := referenceKey(tableCode, record); !visited[key] {
+			visited[key] = true
+			pending = append(pending, record)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	references, err := s.findReferences(tableCode, pending, visited)
+	if err != nil {
+		return err
+	}
+	for _, ref := range references {
+		switch ref.OnDelete {
+		case model.RelationOnDeleteSetNull:
+		case model.RelationOnDeleteCascade:
+			if err := s.collectRestricted(ref.TableCode, ref.Records, visited, restricted); err != nil {
+				return err
+			}
+		default:
+			*restricted = append(*restricted, ref)
+		}
+	}
+	return nil
+}
+
+// ApplyDeleteReferences 记录删除生效后处理引用: setNull 清空引用值, cascade 级联删除引用记录
+// records 为删除前的记录; restrict 引用已在提交删除时检查, 此后新增的引用只记录警告
+func (s *approvalService) ApplyDeleteReferences(c *gin.Context, tableCode, reason string, records []map[string]any) error {
+	exclude := make(map[string]bool, len(records))
+	for _, record := range records {
+		exclude[referenceKey(tableCode, record)] = true
+	}
+	references, err := s.findReferences(tableCode, records, exclude)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range references {
+		switch ref.OnDelete {
+		case model.RelationOnDeleteSetNull:
+			if err := s.clearReferences(c, ref, reason); err != nil {
+				return err
+			}
+		case model.RelationOnDeleteCascade:
+			if err := s.cascadeDelete(c, ref.TableCode, reason, ref.Records); err != nil {
+				return err
+			}
+		default:
+			s.logger.Warn("被删除的记录仍被引用", "table", tableCode, "ref_table", ref.TableCode, "ref_field", ref.FieldCode, "total", ref.Total)
+		}
+	}
+	return nil
+}
+
+// findReferences 查询引用 records 的全部关系字段及引用记录, exclude 中的记录不计入
+func (s *approvalService) findReferences(tableCode string, records []map[string]any, exclude map[string]bool) ([]*EntityReference, error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+	fields, err := s.tableFieldService.Find("", map[string]any{
+		"status":     "Normal",
+		"field_type": constants.RelationFieldTypes(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取关系字段失败: %v", err)
+	}
+
+	var references []*EntityReference
+	for _, field := range fields {
+		ref := referenceOf(field)
+		if ref == nil || ref.target != tableCode {
+			continue
+		}
+
+		var values []string
+		for _, record := range records {
+			if !ref.matchFilter(record) {
+				continue
+			}
+			for _, value := range labelValues(record[ref.valueField]) {
+				if !slices.Contains(values, value) {
+					values = append(values, value)
+				}
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		// 查询失败时返回错误, 不能当作没有引用而继续删除
+		rows, err := findReferencingRecords(s.entityRepository, ref, values)
+		if err != nil {
+			return nil, fmt.Errorf("查询 %s.%s 的引用记录失败: %v", field.TableCode, field.Code, err)
+		}
+		kept := rows[:0]
+		for _, row := range rows {
+			if !exclude[referenceKey(field.TableCode, row)] {
+				kept = append(kept, row)
+			}
+		}
+		if len(kept) == 0 {
+			continue
+		}
+
+		references = append(references, &EntityReference{
+			TableCode: field.TableCode,
+			FieldCode: field.Code,
+			FieldName: field.Name,
+			OnDelete:  ref.onDelete,
+			Total:     len(kept),
+			Records:   kept,
+			reference: ref,
+			values:    values,
+		})
+	}
+	return references, nil
+}
+
+// findReferencingRecords 查询关系字段值包含 values 的记录
+// 多对多字段从关联表查询; 其它多值字段以 JSON 数组存储, 按值逐个模糊匹配后再精确比对
+func findReferencingRecords(repo repository.EntityRepository, ref *fieldReference, values []string) ([]map[string]any, error) {
+	code := ref.field.Code
+	if !ref.multiple {
+		return repo.Find(ref.field.TableCode, "*", map[string]any{
+			code:        values,
+			"status <>": referenceDeletedStatus,
+		})
+	}
+	if ref.field.FieldType == "manytomany" {
+		ids, err := repo.FindLinked(ref.field.TableCode, code, values)
+		if err != nil || len(ids) == 0 {
+			return nil, err
+		}
+		return repo.Find(ref.field.TableCode, "*", map[string]any{
+			"id in":     ids,
+			"status <>": referenceDeletedStatus,
+		})
+	}
+
+	var rows []map[string]any
+	seen := make(map[string]bool)
+	for _, value := range values {
+		items, err := repo.Find(ref.field.TableCode, "*", map[string]any{
+			code + " like": "%" + value + "%",
+			"status <>":    referenceDeletedStatus,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			key := referenceKey(ref.field.TableCode, item)
+			if seen[key] || !slices.Contains(referenceValues(item[code], true), value) {
+				continue
+			}
+			seen[key] = true
+			rows = append(rows, item)
+		}
+	}
+	return rows, nil
+}
+
+// clearReferences 清空引用记录中被删除的值, 多值字段只移除被删除的值
+func (s *approvalService) clearReferences(c *gin.Context, ref *EntityReference, reason string) error {
+	field := ref.reference.field
+	userName := c.GetString("user_name")
+	for _, row := range ref.Records {
+		id, err := importRowID(row)
+		if err != nil {
+			return err
+		}
+
+		before := row[field.Code]
+		var after any
+		if ref.reference.multiple {
+			var kept []string
+			for _, value := range referenceValues(before, true) {
+				if !slices.Contains(ref.values, value) {
+					kept = append(kept, value)
+				}
+			}
+			if len(kept) > 0 {
+				data, _ := json.Marshal(kept)
+				after = string(data)
+			}
+		}
+
+		entityMap := map[string]any{
+			field.Code:   after,
+			"updated_by": userName,
+			"updated_at": time.Now(),
+		}
+		if err := s.entityRepository.Update(c, field.TableCode, entityMap, map[string]any{"id": id}); err != nil {
+			return fmt.Errorf("清空引用失败: %v", err)
+		}
+
+		event := entityEvent{Operation: "ClearReference", Reason: reason}
+		if err := writeEntityLog(c, s.entityLogService, field.TableCode, []*model.TableField{field}, event, id, row, entityMap); err != nil {
+			s.logger.Error("创建变更日志失败", "error", err, "field", field.Code, "id", id)
+		}
+	}
+	return nil
+}
+
+// cascadeDelete 级联删除引用记录: 引用表配置了删除审批时提交审批, 审批通过后再处理其引用;
+// 否则直接标记删除并继续处理其引用
+func (s *approvalService) cascadeDelete(c *gin.Context, tableCode, reason string, records []map[string]any) error {
+	ids := make([]uint, 0, len(records))
+	for _, record := range records {
+		id, err := importRowID(record)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	operation := "BatchDelete"
+	tableApprovalDefs, err := s.tableApprovalDefinitionRepository.List(tableCode, operation)
+	if err != nil {
+		return err
+	}
+	if len(tableApprovalDefs) > 0 {
+		return s.UpdateByIdsWithApproval(c, tableCode, reason, ids, map[string]any{"operation": operation})
+	}
+
+	operationInfo := make(map[string]string)
+	if err := s.GetOperationInfo(operation, &operationInfo); err != nil {
+		return err
+	}
+	userName := c.GetString("user_name")
+	entityMap := map[string]any{
+		"status":     operationInfo["status"],
+		"action":     operationInfo["action"],
+		"operation":  operation,
+		"updated_by": userName,
+		"updated_at": time.Now(),
+	}
+	markDeleted(entityMap, userName, reason)
+	if err := s.entityRepository.BatchUpdate(c, tableCode, ids, nil, entityMap); err != nil {
+		return fmt.Errorf("级联删除失败: %v", err)
+	}
+
+	// 记录变更历史: 删除前的全部字段
+	tableFields, err := s.tableFieldService.Find("", map[string]any{"table_code": tableCode})
+	if err != nil {
+		s.logger.Error("获取表字段失败", "error", err)
+	}
+	event := entityEvent{Operation: operation, Reason: reason}
+	for i, record := range records {
+		if err := writeEntityLog(c, s.entityLogService, tableCode, tableFields, event, ids[i], record, nil); err != nil {
+			s.logger.Error("创建变更日志失败", "error", err, "id", ids[i])
+		}
+	}
+
+	return s.ApplyDeleteReferences(c, tableCode, reason, records)
+}
@@ -0,0 +1,186 @@
+package service_test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"piemdm/internal/model"
+	"piemdm/internal/service"
+	"piemdm/pkg/helper/sid"
+	"piemdm/pkg/jwt"
+	"piemdm/pkg/log"
+	mock_repository "piemdm/test/mocks/repository"
+	mock_service "piemdm/test/mocks/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type referenceMocks struct {
+	entityRepo   *mock_repository.MockEntityRepository
+	fieldService *mock_service.MockTableFieldService
+	logService   *mock_service.MockEntityLogService
+	tadRepo      *mock_repository.MockTableApprovalDefinitionRepository
+}
+
+func setupReferenceService(t *testing.T, fields []*model.TableField) (service.ApprovalService, *referenceMocks, *gin.Context) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	m := &referenceMocks{
+		entityRepo:   mock_repository.NewMockEntityRepository(ctrl),
+		fieldService: mock_service.NewMockTableFieldService(ctrl),
+		logService:   mock_service.NewMockEntityLogService(ctrl),
+		tadRepo:      mock_repository.NewMockTableApprovalDefinitionRepository(ctrl),
+	}
+	m.fieldService.EXPECT().Find("", map[string]any{
+		"status":     "Normal",
+		"field_type": []string{"belongsto", "hasmany", "manytomany"},
+	}).Return(fields, nil).AnyTimes()
+
+	logger := &log.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	baseService := service.NewService(logger, &sid.Sid{}, &jwt.JWT{})
+	s := service.NewApprovalService(baseService, nil, nil, nil, nil, m.entityRepo, m.fieldService, m.logService, nil, nil, nil, m.tadRepo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_name", "tester")
+	return s, m, c
+}
+
+func relationField(tableCode, code, fieldType, onDelete string) *model.TableField {
+	return &model.TableField{
+		TableCode: tableCode,
+		Code:      code,
+		Name:      code,
+		FieldType: fieldType,
+		Options: &model.FieldOptions{Relation: &model.FieldRelation{
+			Target:   "customer",
+			OnDelete: onDelete,
+		}},
+	}
+}
+
+func TestApprovalService_CheckDeleteReferences(t *testing.T) {
+	fields := []*model.TableField{
+		relationField("sales_order", "customer", "belongsto", ""),
+		relationField("contact", "customer", "belongsto", model.RelationOnDeleteCascade),
+		relationField("supplier", "customer", "belongsto", ""), // 其它表引用 supplier, 不相关
+	}
+	fields[2].Options.Relation.Target = "supplier"
+	s, m, c := setupReferenceService(t, fields)
+
+	customers := []map[string]any{{"id": uint64(1), "code": "C1", "status": "Normal"}}
+	m.entityRepo.EXPECT().Find("customer", "*", map[string]any{"id in": []uint{1}}).Return(customers, nil)
+	m.entityRepo.EXPECT().Find("sales_order", "*", map[string]any{
+		"customer": []string{"C1"}, "status <>": "Deleted",
+	}).Return([]map[string]any{{"id": uint64(10), "customer": "C1"}}, nil)
+	// cascade 引用: 继续检查被级联删除的联系人是否被引用 (没有关系字段指向 contact)
+	m.entityRepo.EXPECT().Find("contact", "*", map[string]any{
+		"customer": []string{"C1"}, "status <>": "Deleted",
+	}).Return([]map[string]any{{"id": uint64(20), "customer": "C1"}}, nil)
+
+	err := s.CheckDeleteReferences(c, "customer", []uint{1})
+	var referenceErr *service.ReferenceError
+	require.ErrorAs(t, err, &referenceErr)
+	require.Len(t, referenceErr.References, 1)
+	assert.Equal(t, "sales_order", referenceErr.References[0].TableCode)
+	assert.Equal(t, model.RelationOnDeleteRestrict, referenceErr.References[0].OnDelete)
+	assert.Equal(t, 1, referenceErr.References[0].Total)
+}
+
+func TestApprovalService_CheckDeleteReferences_FindError(t *testing.T) {
+	s, m, c := setupReferenceService(t, []*model.TableField{relationField("sales_order", "customer", "belongsto", "")})
+
+	m.entityRepo.EXPECT().Find("customer", "*", map[string]any{"id in": []uint{1}}).
+		Return([]map[string]any{{"id": uint64(1), "code": "C1", "status": "Normal"}}, nil)
+	m.entityRepo.EXPECT().Find("sales_order", "*", map[string]any{
+		"customer": []string{"C1"}, "status <>": "Deleted",
+	}).Return(nil, errors.New("connection reset"))
+
+	// 查询引用失败时不能删除
+	err := s.CheckDeleteReferences(c, "customer", []uint{1})
+	assert.EqualError(t, err, "查询 sales_order.customer 的引用记录失败: connection reset")
+}
+
+func TestApprovalService_ApplyDeleteReferences(t *testing.T) {
+	fields := []*model.TableField{
+		relationField("project", "customers", "manytomany", model.RelationOnDeleteSetNull),
+		relationField("contact", "customer", "belongsto", model.RelationOnDeleteCascade),
+	}
+	s, m, c := setupReferenceService(t, fields)
+	deleted := []map[string]any{{"id": uint64(1), "code": "C1", "status": "Normal"}}
+
+	// setNull: 多对多字段从关联表查询引用记录, 只移除被删除的值
+	m.entityRepo.EXPECT().FindLinked("project", "customers", []string{"C1"}).Return([]uint{10}, nil)
+	m.entityRepo.EXPECT().Find("project", "*", map[string]any{
+		"id in": []uint{10}, "status <>": "Deleted",
+	}).Return([]map[string]any{
+		{"id": uint64(10), "customers": []string{"C1", "C2"}},
+	}, nil)
+	var cleared map[string]any
+	m.entityRepo.EXPECT().Update(c, "project", gomock.Any(), map[string]any{"id": uint(10)}).
+		DoAndReturn(func(_ *gin.Context, _ string, entity any, _ map[string]any) error {
+			cleared = entity.(map[string]any)
+			return nil
+		})
+	m.logService.EXPECT().Create(c, "project", gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, _ string, entityLog *model.EntityLog) error {
+			assert.Equal(t, "ClearReference", entityLog.Operation)
+			assert.JSONEq(t, `{"customers":{"name":"customers","before":["C1","C2"],"after":["C2"]}}`, string(entityLog.Changes))
+			return nil
+		})
+
+	// cascade: 未配置删除审批, 直接标记删除并记录日志
+	contacts := []map[string]any{{"id": uint64(20), "customer": "C1", "status": "Normal"}}
+	m.entityRepo.EXPECT().Find("contact", "*", map[string]any{
+		"customer": []string{"C1"}, "status <>": "Deleted",
+	}).Return(contacts, nil)
+	m.tadRepo.EXPECT().List("contact", "BatchDelete").Return(nil, nil)
+	m.entityRepo.EXPECT().BatchUpdate(c, "contact", []uint{20}, gomock.Nil(), gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, _ string, _ []uint, _ map[uint]uint, entityMap map[string]any) error {
+			assert.Equal(t, "Deleted", entityMap["status"])
+			return nil
+		})
+	m.fieldService.EXPECT().Find("", map[string]any{"table_code": "contact"}).Return(fields[1:], nil)
+	m.logService.EXPECT().Create(c, "contact", gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, _ string, entityLog *model.EntityLog) error {
+			assert.Equal(t, uint(20), entityLog.EntityID)
+			assert.Equal(t, "BatchDelete", entityLog.Operation)
+			assert.JSONEq(t, `{
+				"customer": {"name": "customer", "before": "C1", "after": null},
+				"status": {"name": "状态", "before": "Normal", "after": null}
+			}`, string(entityLog.Changes))
+			return nil
+		})
+
+	require.NoError(t, s.ApplyDeleteReferences(c, "customer", "停用客户", deleted))
+	assert.Equal(t, `["C2"]`, cleared["customers"])
+	assert.Equal(t, "tester", cleared["updated_by"])
+}
+
+func TestApprovalService_FindReferences_JSONArray(t *testing.T) {
+	fields := []*model.TableField{relationField("region", "customers", "hasmany", "")}
+	s, m, c := setupReferenceService(t, fields)
+
+	m.entityRepo.EXPECT().Find("customer", "*", map[string]any{"id in": []uint{1}}).
+		Return([]map[string]any{{"id": uint64(1), "code": "C1"}}, nil)
+	// 一对多字段以 JSON 数组存储: 模糊匹配后精确比对
+	m.entityRepo.EXPECT().Find("region", "*", map[string]any{
+		"customers like": "%C1%", "status <>": "Deleted",
+	}).Return([]map[string]any{
+		{"id": uint64(10), "customers": `["C1","C2"]`},
+		{"id": uint64(11), "customers": `["C10"]`}, // 模糊匹配命中但不是引用
+	}, nil)
+
+	references, err := s.FindReferences(c, "customer", []uint{1})
+	require.NoError(t, err)
+	require.Len(t, references, 1)
+	assert.Equal(t, 1, references[0].Total)
+	assert.Equal(t, uint64(10), references[0].Records[0]["id"])
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+
+	"piemdm/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// relationsColumn 导出文件中保存关联视图数据的列, 值为按关联模型编码分组的 JSON, 如 {"supplier_bank": [{...}]}
+// 关联视图数据只导出, 导入时忽略
+const relationsColumn = "relations"
+
+// EntityRelation 记录的一个关联视图及一页关联记录
+type EntityRelation struct {
+	ID        uint             `json:"id"`
+	Name      string           `json:"name"`
+	TableCode string           `json:"table_code"` // 关联模型
+	FieldCode string           `json:"field_code"` // 关联模型中引用该记录的字段
+	Total     int64            `json:"total"`
+	Records   []map[string]any `json:"records"`
+}
+
+// relationView 关联视图及其引用配置
+type relationView struct {
+	relation  *model.TableRelation
+	reference *fieldReference
+	columns   []exportColumn // 导出的关联模型值列 (id 及当前用户可以查看的已发布字段)
+	access    *FieldAccess   // 当前用户对关联模型的字段组权限
+}
+
+// relationReference 解析关联视图的引用配置: 关联字段为关系字段时必须引用该模型, 其它字段按该模型的 code 关联
+func relationReference(relation *model.TableRelation, fields []*model.TableField) (*fieldReference, error) {
+	for _, field := range fields {
+		if field.Code != relation.RelationCode {
+			continue
+		}
+		if ref := referenceOf(field); ref != nil {
+			if ref.target != relation.TableCode {
+				return nil, fmt.Errorf("字段 %s 引用的是表 %s, 不是表 %s", field.Code, ref.target, relation.TableCode)
+			}
+			return ref, nil
+		}
+		if !labelColumnPattern.MatchString(field.Code) {
+			return nil, fmt.Errorf("字段编码 %s 不合法", field.Code)
+		}
+		return &fieldReference{field: field, target: relation.TableCode, valueField: "code"}, nil
+	}
+	return nil, fmt.Errorf("表 %s 没有字段 %s", relation.RelationTable, relation.RelationCode)
+}
+
+// relationFilter 查询引用 value 的关联记录的条件
+// 多对多字段按关联表匹配; 其它多值字段以 JSON 数组存储, 按包含匹配
+func relationFilter(ref *fieldReference, value string) *model.EntityFilter {
+	switch {
+	case ref.field.FieldType == "manytomany":
+		return &model.EntityFilter{Field: ref.field.Code, Op: model.QueryOpHasAny, Value: []any{value}}
+	case ref.multiple:
+		return &model.EntityFilter{Field: ref.field.Code, Op: model.QueryOpContains, Value: value}
+	default:
+		return &model.EntityFilter{Field: ref.field.Code, Op: model.QueryOpEq, Value: value}
+	}
+}
+
+// loadRelationView 查询关联视图并解析引用配置
+func (s *entityService) loadRelationView(c *gin.Context, relation *model.TableRelation) (*relationView, error) {
+	fields, err := s.tableFieldService.Find("*", map[string]any{"table_code": relation.RelationTable, "status": "Normal"})
+	if err != nil {
+		return nil, fmt.Errorf("获取表字段失败: %v", err)
+	}
+	ref, err := relationReference(relation, fields)
+	if err != nil {
+		return nil, err
+	}
+	access, err := s.tableFieldService.GetFieldAccess(c, relation.RelationTable)
+	if err != nil {
+		return nil, err
+	}
+	columns := []exportColumn{{code: "id", dataType: "Number"}}
+	for _, field := range fields {
+		if access != nil && access.Hidden[field.Code] {
+			continue
+		}
+		columns = append(columns, exportColumn{code: field.Code, dataType: fieldDataType(field)})
+	}
+	return &relationView{relation: relation, reference: ref, columns: columns, access: access}, nil
+}
+
+// relationViews 表的关联视图, 按显示顺序; 当前用户没有权限访问的关联模型和配置失效的关联视图不返回
+func (s *entityService) relationViews(c *gin.Context, tableCode string) ([]*relationView, error) {
+	relations, err := s.tableRelationRepository.Find(map[string]any{"table_code": tableCode, "status": "Normal"})
+	if err != nil {
+		return nil, fmt.Errorf("获取关联视图失败: %v", err)
+	}
+	views := make([]*relationView, 0, len(relations))
+	for _, relation := range relations {
+		if err := s.checkPermission(c, relation.RelationTable); err != nil {
+			continue
+		}
+		view, err := s.loadRelationView(c, relation)
+		if err != nil {
+			s.logger.Warn("关联视图配置无效", "table", tableCode, "relation", relation.ID, "err", err)
+			continue
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// ListRelations 查询记录的全部关联视图, 每个关联视图返回第一页关联记录
+func (s *entityService) ListRelations(c *gin.Context, tableCode string, id uint, pageSize int) ([]*EntityRelation, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
+	}
+	record, err := s.entityRepository.FindOne(tableCode, id)
+	if err != nil || record == nil {
+		return nil, fmt.Errorf("记录 %d 不存在", id)
+	}
+	views, err := s.relationViews(c, tableCode)
+	if err != nil {
+		return nil, err
+	}
+
+	relations := make([]*EntityRelation, 0, len(views))
+	for _, view := range views {
+		relation := &EntityRelation{
+			ID:        view.relation.ID,
+			Name:      view.relation.RelationName,
+			TableCode: view.relation.RelationTable,
+			FieldCode: view.relation.RelationCode,
+		}
+		relation.Records, err = s.findRelated(view, record, 1, pageSize, &relation.Total, nil)
+		if err != nil {
+			return nil, err
+		}
+		relations = append(relations, relation)
+	}
+	return relations, nil
+}
+
+// ListRelated 分页查询记录在一个关联视图中的关联记录, query 为关联模型上的过滤、排序和字段选择
+func (s *entityService) ListRelated(c *gin.Context, tableCode string, id, relationID uint, page, pageSize int, total *int64, query *model.EntityQuery) ([]map[string]any, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
+	}
+	relation, err := s.tableRelationRepository.FindOne(relationID)
+	if err != nil || relation.TableCode != tableCode || relation.Status != "Normal" {
+		return nil, fmt.Errorf("%w: 表 %s 没有关联视图 %d", model.ErrInvalidQuery, tableCode, relationID)
+	}
+	if err := s.checkPermission(c, relation.RelationTable); err != nil {
+		return nil, err
+	}
+	view, err := s.loadRelationView(c, relation)
+	if err != nil {
+		return nil, err
+	}
+	record, err := s.entityRepository.FindOne(tableCode, id)
+	if err != nil || record == nil {
+		return nil, fmt.Errorf("记录 %d 不存在", id)
+	}
+	return s.findRelated(view, record, page, pageSize, total, query)
+}
+
+// findRelated 分页查询引用 record 的关联记录, 引用的值为空时没有关联记录
+// query 只能使用当前用户可以查看的字段, 关联条件使用的引用字段不受限制
+func (s *entityService) findRelated(view *relationView, record map[string]any, page, pageSize int, total *int64, query *model.EntityQuery) ([]map[string]any, error) {
+	ref := view.reference
+	values := labelValues(record[ref.valueField])
+	if len(values) == 0 {
+		*total = 0
+		return []map[string]any{}, nil
+	}
+
+	relatedQuery := model.EntityQuery{}
+	if query != nil {
+		relatedQuery = *query
+	}
+	columns, err := s.queryColumns(ref.field.TableCode)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.entityRepository.CompileQuery(ref.field.TableCode, &relatedQuery, hideColumns(columns, view.access), "t"); err != nil {
+		return nil, err
+	}
+	filter := relationFilter(ref, values[0])
+	if relatedQuery.Filter != nil {
+		filter = &model.EntityFilter{And: []*model.EntityFilter{filter, relatedQuery.Filter}}
+	}
+	relatedQuery.Filter = filter
+
+	compiled, err := s.entityRepository.CompileQuery(ref.field.TableCode, &relatedQuery, columns, "t")
+	if err != nil {
+		return nil, err
+	}
+	records, err := s.entityRepository.FindPage(ref.field.TableCode, page, pageSize, total, compiled)
+	if err != nil {
+		return nil, err
+	}
+	view.access.Hide(records...)
+	return records, nil
+}
+
+// loadRelationRecords 查询本批记录的关联记录, 按关联模型和被引用的值分组, 值按导出格式转换
+func (s *entityService) loadRelationRecords(views []*relationView, rows []map[string]any) (map[string]map[string][]map[string]any, error) {
+	records := make(map[string]map[string][]map[string]any, len(views))
+	for _, view := range views {
+		ref := view.reference
+		var values []string
+		for _, row := range rows {
+			for _, value := range labelValues(row[ref.valueField]) {
+				if !slices.Contains(values, value) {
+					values = append(values, value)
+				}
+			}
+		}
+		grouped := make(map[string][]map[string]any)
+		records[view.relation.RelationTable] = grouped
+		if len(values) == 0 {
+			continue
+		}
+
+		found, err := findReferencingRecords(s.entityRepository, ref, values)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range found {
+			exported := make(map[string]any, len(view.columns))
+			for _, column := range view.columns {
+				exported[column.code] = exportFieldValue(column.dataType, item[column.code])
+			}
+			for _, value := range referenceValues(item[ref.field.Code], ref.multiple) {
+				if slices.Contains(values, value) {
+					grouped[value] = append(grouped[value], exported)
+				}
+			}
+		}
+	}
+	return records, nil
+}
+
+// relationRecords 记录的关联记录, 没有关联记录的关联模型为空数组
+func relationRecords(views []*relationView, records map[string]map[string][]map[string]any, row map[string]any) map[string][]map[string]any {
+	result := make(map[string][]map[string]any, len(views))
+	for _, view := range views {
+		table := view.relation.RelationTable
+		var items []map[string]any
+		if values := labelValues(row[view.reference.valueField]); len(values) > 0 {
+			items = records[table][values[0]]
+		}
+		if items == nil {
+			items = []map[string]any{}
+		}
+		result[table] = items
+	}
+	return result
+}
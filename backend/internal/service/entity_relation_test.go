@@ -0,0 +1,142 @@
+package service_test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+	"piemdm/internal/service"
+	"piemdm/pkg/helper/sid"
+	"piemdm/pkg/jwt"
+	"piemdm/pkg/log"
+	mock_repository "piemdm/test/mocks/repository"
+	mock_service "piemdm/test/mocks/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntityService_Relations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	entityRepo := mock_repository.NewMockEntityRepository(ctrl)
+	expectCompileQuery(entityRepo)
+	fieldService := mock_service.NewMockTableFieldService(ctrl)
+	permissionService := mock_service.NewMockTablePermissionService(ctrl)
+	relationRepo := mock_repository.NewMockTableRelationRepository(ctrl)
+	logger := &log.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s := service.NewEntityService(service.NewService(logger, &sid.Sid{}, &jwt.JWT{}),
+		entityRepo, fieldService, nil, nil, nil, nil, nil, nil, permissionService, nil, nil, nil, nil, relationRepo, nil)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_id", uint(7))
+
+	// 联系人按关系字段关联, 银行账户按文本字段保存客户编码, 用户没有 secret 表的权限, 不能查看银行账户的 iban
+	fields := map[string][]*model.TableField{
+		"contact":       {relationField("contact", "customer", "belongsto", ""), {TableCode: "contact", Code: "name", Type: "Text"}},
+		"customer_bank": {{TableCode: "customer_bank", Code: "customer_code", Type: "Text"}, {TableCode: "customer_bank", Code: "bank", Type: "Text"}, {TableCode: "customer_bank", Code: "iban", Type: "Text"}},
+	}
+	fieldService.EXPECT().Find(gomock.Any(), gomock.Any()).DoAndReturn(func(_ string, where map[string]any) ([]*model.TableField, error) {
+		return fields[where["table_code"].(string)], nil
+	}).AnyTimes()
+	fieldService.EXPECT().GetFieldAccess(c, gomock.Any()).DoAndReturn(func(_ *gin.Context, table string) (*service.FieldAccess, error) {
+		if table == "customer_bank" {
+			return &service.FieldAccess{Hidden: map[string]bool{"iban": true}}, nil
+		}
+		return nil, nil
+	}).AnyTimes()
+	permissionService.EXPECT().CheckTablePermission(gomock.Any(), uint(7), gomock.Any()).DoAndReturn(func(_ *gin.Context, _ uint, table string) (bool, error) {
+		return table != "secret", nil
+	}).AnyTimes()
+	relations := []*model.TableRelation{
+		{ID: 1, TableCode: "customer", RelationTable: "contact", RelationName: "联系人", RelationCode: "customer", Status: "Normal"},
+		{ID: 2, TableCode: "customer", RelationTable: "customer_bank", RelationName: "银行账户", RelationCode: "customer_code", Status: "Normal"},
+		{ID: 3, TableCode: "customer", RelationTable: "secret", RelationName: "机密", RelationCode: "customer", Status: "Normal"},
+	}
+	relationRepo.EXPECT().Find(map[string]any{"table_code": "customer", "status": "Normal"}).Return(relations, nil).AnyTimes()
+	for _, relation := range relations {
+		relationRepo.EXPECT().FindOne(relation.ID).Return(relation, nil).AnyTimes()
+	}
+	entityRepo.EXPECT().FindOne("customer", uint(1)).Return(map[string]any{"id": uint64(1), "code": "C1"}, nil).AnyTimes()
+
+	var queries []*repository.CompiledEntityQuery
+	entityRepo.EXPECT().FindPage(gomock.Any(), 1, 10, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(table string, _, _ int, total *int64, query *repository.CompiledEntityQuery) ([]map[string]any, error) {
+			queries = append(queries, query)
+			*total = 1
+			return []map[string]any{{"id": uint64(5), "table": table, "iban": "DE89"}}, nil
+		}).AnyTimes()
+
+	result, err := s.ListRelations(c, "customer", 1, 10)
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, "联系人", result[0].Name)
+	assert.Equal(t, "customer_bank", result[1].TableCode)
+	assert.Equal(t, int64(1), result[1].Total)
+	assert.Equal(t, "DE89", result[0].Records[0]["iban"])
+	assert.NotContains(t, result[1].Records[0], "iban")
+	require.Len(t, queries, 2)
+	assert.Equal(t, []any{"C1"}, queries[1].Values)
+
+	// 关联记录的过滤条件与关联条件同时生效
+	var total int64
+	query := &model.EntityQuery{Filter: &model.EntityFilter{Field: "bank", Op: model.QueryOpEq, Value: "ICBC"}}
+	_, err = s.ListRelated(c, "customer", 1, 2, 1, 10, &total, query)
+	require.NoError(t, err)
+	assert.Equal(t, []any{"C1", "ICBC"}, queries[2].Values)
+
+	// 不能查看的字段不能用于过滤
+	query = &model.EntityQuery{Filter: &model.EntityFilter{Field: "iban", Op: model.QueryOpEq, Value: "DE89"}}
+	_, err = s.ListRelated(c, "customer", 1, 2, 1, 10, &total, query)
+	assert.True(t, errors.Is(err, model.ErrInvalidQuery))
+
+	// 没有权限的关联模型、其它表的关联视图不能查询
+	_, err = s.ListRelated(c, "customer", 1, 3, 1, 10, &total, nil)
+	assert.Error(t, err)
+	relationRepo.EXPECT().FindOne(uint(9)).Return(&model.TableRelation{ID: 9, TableCode: "supplier", Status: "Normal"}, nil)
+	_, err = s.ListRelated(c, "customer", 1, 9, 1, 10, &total, nil)
+	assert.True(t, errors.Is(err, model.ErrInvalidQuery))
+}
+
+func TestTableRelationService_Validate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	relationRepo := mock_repository.NewMockTableRelationRepository(ctrl)
+	fieldRepo := mock_repository.NewMockTableFieldRepository(ctrl)
+	tableRepo := mock_repository.NewMockTableRepository(ctrl)
+	logger := &log.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s := service.NewTableRelationService(service.NewService(logger, &sid.Sid{}, &jwt.JWT{}), relationRepo, fieldRepo, tableRepo)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	tableRepo.EXPECT().Find("", gomock.Any()).Return([]*model.Table{{}}, nil).AnyTimes()
+	supplierField := relationField("contact", "supplier", "belongsto", "")
+	supplierField.Options.Relation.Target = "supplier"
+	fieldRepo.EXPECT().Find("", map[string]any{"table_code": "contact", "status": "Normal"}).
+		Return([]*model.TableField{relationField("contact", "customer", "belongsto", ""), supplierField}, nil).AnyTimes()
+
+	err := s.Create(c, &model.TableRelation{TableCode: "customer", RelationTable: "contact", RelationName: "联系人", RelationCode: "phone"})
+	assert.EqualError(t, err, "表 contact 没有字段 phone")
+	err = s.Create(c, &model.TableRelation{TableCode: "customer", RelationTable: "contact", RelationName: "联系人", RelationCode: "supplier"})
+	assert.EqualError(t, err, "字段 supplier 引用的是表 supplier, 不是表 customer")
+
+	// 同一模型到同一关联模型只能有一个关联视图
+	relationRepo.EXPECT().Find(map[string]any{"table_code": "customer", "relation_table": "contact"}).
+		Return([]*model.TableRelation{{ID: 1, RelationName: "联系人"}}, nil)
+	err = s.Create(c, &model.TableRelation{TableCode: "customer", RelationTable: "contact", RelationName: "联系人2", RelationCode: "customer"})
+	assert.EqualError(t, err, "表 customer 已有关联模型 contact 的关联视图 联系人")
+
+	relation := &model.TableRelation{TableCode: "customer", RelationTable: "contact", RelationName: "联系人", RelationCode: "customer"}
+	relationRepo.EXPECT().Find(gomock.Any()).Return(nil, nil)
+	relationRepo.EXPECT().Create(c, relation).Return(nil)
+	require.NoError(t, s.Create(c, relation))
+	assert.Equal(t, "Normal", relation.Status)
+}
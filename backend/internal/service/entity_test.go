@@ -18,10 +18,15 @@
 package service_test
 
 import (
+	"encoding/json"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"piemdm/internal/model"
+	"piemdm/internal/repository"
 	"piemdm/internal/service"
 	"piemdm/pkg/configloader"
 	"piemdm/pkg/log"
@@ -30,7 +35,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -51,6 +58,12 @@ func init() {
 	testLogger = log.NewLog(testConfig)
 }
 
+// expectCompileQuery 模拟的实体仓库使用真实的查询编译器 (MySQL 方言) 编译结构化查询
+func expectCompileQuery(repo *mock_repository.MockEntityRepository) {
+	compiler := repository.NewEntityRepository(repository.NewRepository(nil, nil, testLogger), nil, nil)
+	repo.EXPECT().CompileQuery(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(compiler.CompileQuery).AnyTimes()
+}
+
 // TestValidateUniqueConstraints_CreateNoWorkflow_NoConflict 测试创建无流程场景 - 无冲突
 func TestValidateUniqueConstraints_CreateNoWorkflow_NoConflict(t *testing.T) {
 	ctrl := gomock.NewController(t)
@@ -58,6 +71,7 @@ func TestValidateUniqueConstraints_CreateNoWorkflow_NoConflict(t *testing.T) {
 
 	mockEntityRepo := mock_repository.NewMockEntityRepository(ctrl)
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	mockTableFieldService.EXPECT().GetFieldAccess(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
 	mockTableFieldRepo := mock_repository.NewMockTableFieldRepository(ctrl)
 	mockTableApprovalDefRepo := mock_repository.NewMockTableApprovalDefinitionRepository(ctrl)
 	mockApprovalService := mock_service.NewMockApprovalService(ctrl)
@@ -78,8 +92,12 @@ func TestValidateUniqueConstraints_CreateNoWorkflow_NoConflict(t *testing.T) {
 		mockEntityLogService,
 		mockAutocodeService,
 		mockTablePermissionService,
-		mockTableRepo, // tableRepository
-		nil,           // viper config
+		mockTableRepo,      // tableRepository
+		nil,                // entityJobService
+		noMatchRules(ctrl), // matchRuleRepository
+		nil,                // entityMergeRepository
+		nil,                // tableRelationRepository
+		nil,                // viper config
 	)
 
 	// Mock HasPermission check - assume has permission for tests
@@ -112,6 +130,11 @@ func TestValidateUniqueConstraints_CreateNoWorkflow_NoConflict(t *testing.T) {
 		GenerateOrRestoreAutocodes(gomock.Any(), "test_entity", gomock.Any(), mockTableFieldService, mockEntityRepo, gomock.Any()).
 		Return(nil)
 
+	// 模拟字段校验的字段定义查询 - 无校验规则
+	mockTableFieldService.EXPECT().
+		Find("", map[string]any{"table_code": "test_entity", "status": "Normal"}).
+		Return([]*model.TableField{}, nil)
+
 	// 模拟唯一索引字段查询
 	uniqueFields := []*model.TableField{
 		{Code: "code", IsUnique: "Yes", IndexName: ""},
@@ -133,6 +156,23 @@ func TestValidateUniqueConstraints_CreateNoWorkflow_NoConflict(t *testing.T) {
 		Create(gomock.Any(), "test_entity", gomock.Any()).
 		Return(nil)
 
+	// 新增后记录变更历史
+	mockTableFieldService.EXPECT().
+		Find("", map[string]any{"table_code": "test_entity"}).
+		Return([]*model.TableField{}, nil)
+	mockEntityLogService.EXPECT().
+		Create(gomock.Any(), "test_entity", gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, _ string, entityLog *model.EntityLog) error {
+			assert.Equal(t, uint(1), entityLog.EntityID)
+			assert.Equal(t, "Create", entityLog.Operation)
+			assert.JSONEq(t, `{
+				"code": {"before": null, "after": "TEST001"},
+				"name": {"before": null, "after": "Test Entity"},
+				"status": {"name": "状态", "before": null, "after": "Normal"}
+			}`, string(entityLog.Changes))
+			return nil
+		})
+
 	entityMap := map[string]any{
 		"code": "TEST001",
 		"name": "Test Entity",
@@ -154,6 +194,7 @@ func TestValidateUniqueConstraints_CreateNoWorkflow_Conflict(t *testing.T) {
 
 	mockEntityRepo := mock_repository.NewMockEntityRepository(ctrl)
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	mockTableFieldService.EXPECT().GetFieldAccess(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
 	mockTableFieldRepo := mock_repository.NewMockTableFieldRepository(ctrl)
 	mockTableApprovalDefRepo := mock_repository.NewMockTableApprovalDefinitionRepository(ctrl)
 	mockApprovalService := mock_service.NewMockApprovalService(ctrl)
@@ -174,8 +215,12 @@ func TestValidateUniqueConstraints_CreateNoWorkflow_Conflict(t *testing.T) {
 		mockEntityLogService,
 		mockAutocodeService,
 		mockTablePermissionService,
-		mockTableRepo, // tableRepository
-		nil,           // viper config
+		mockTableRepo,      // tableRepository
+		nil,                // entityJobService
+		noMatchRules(ctrl), // matchRuleRepository
+		nil,                // entityMergeRepository
+		nil,                // tableRelationRepository
+		nil,                // viper config
 	)
 
 	// Mock HasPermission check
@@ -208,6 +253,11 @@ func TestValidateUniqueConstraints_CreateNoWorkflow_Conflict(t *testing.T) {
 		GenerateOrRestoreAutocodes(gomock.Any(), "test_entity", gomock.Any(), mockTableFieldService, mockEntityRepo, gomock.Any()).
 		Return(nil)
 
+	// 模拟字段校验的字段定义查询 - 无校验规则
+	mockTableFieldService.EXPECT().
+		Find("", map[string]any{"table_code": "test_entity", "status": "Normal"}).
+		Return([]*model.TableField{}, nil)
+
 	// 模拟唯一索引字段查询
 	uniqueFields := []*model.TableField{
 		{Code: "code", IsUnique: "Yes", IndexName: ""},
@@ -244,6 +294,7 @@ func TestValidateUniqueConstraints_CreateWithWorkflow_NoConflict(t *testing.T) {
 
 	mockEntityRepo := mock_repository.NewMockEntityRepository(ctrl)
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	mockTableFieldService.EXPECT().GetFieldAccess(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
 	mockTableFieldRepo := mock_repository.NewMockTableFieldRepository(ctrl)
 	mockTableApprovalDefRepo := mock_repository.NewMockTableApprovalDefinitionRepository(ctrl)
 	mockApprovalService := mock_service.NewMockApprovalService(ctrl)
@@ -264,8 +315,12 @@ func TestValidateUniqueConstraints_CreateWithWorkflow_NoConflict(t *testing.T) {
 		mockEntityLogService,
 		mockAutocodeService,
 		mockTablePermissionService,
-		mockTableRepo, // tableRepository
-		nil,           // viper config
+		mockTableRepo,      // tableRepository
+		nil,                // entityJobService
+		noMatchRules(ctrl), // matchRuleRepository
+		nil,                // entityMergeRepository
+		nil,                // tableRelationRepository
+		nil,                // viper config
 	)
 
 	// Mock HasPermission check
@@ -303,6 +358,7 @@ func TestValidateUniqueConstraints_CreateWithWorkflow_Conflict(t *testing.T) {
 
 	mockEntityRepo := mock_repository.NewMockEntityRepository(ctrl)
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	mockTableFieldService.EXPECT().GetFieldAccess(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
 	mockTableFieldRepo := mock_repository.NewMockTableFieldRepository(ctrl)
 	mockTableApprovalDefRepo := mock_repository.NewMockTableApprovalDefinitionRepository(ctrl)
 	mockApprovalService := mock_service.NewMockApprovalService(ctrl)
@@ -323,8 +379,12 @@ func TestValidateUniqueConstraints_CreateWithWorkflow_Conflict(t *testing.T) {
 		mockEntityLogService,
 		mockAutocodeService,
 		mockTablePermissionService,
-		mockTableRepo, // tableRepository
-		nil,           // viper config
+		mockTableRepo,      // tableRepository
+		nil,                // entityJobService
+		noMatchRules(ctrl), // matchRuleRepository
+		nil,                // entityMergeRepository
+		nil,                // tableRelationRepository
+		nil,                // viper config
 	)
 
 	// Mock HasPermission check
@@ -365,6 +425,7 @@ func TestValidateUniqueConstraints_UpdateNoWorkflow_NoConflict(t *testing.T) {
 
 	mockEntityRepo := mock_repository.NewMockEntityRepository(ctrl)
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	mockTableFieldService.EXPECT().GetFieldAccess(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
 	mockTableFieldRepo := mock_repository.NewMockTableFieldRepository(ctrl)
 	mockTableApprovalDefRepo := mock_repository.NewMockTableApprovalDefinitionRepository(ctrl)
 	mockApprovalService := mock_service.NewMockApprovalService(ctrl)
@@ -385,8 +446,12 @@ func TestValidateUniqueConstraints_UpdateNoWorkflow_NoConflict(t *testing.T) {
 		mockEntityLogService,
 		mockAutocodeService,
 		mockTablePermissionService,
-		mockTableRepo, // tableRepository
-		nil,           // viper config
+		mockTableRepo,      // tableRepository
+		nil,                // entityJobService
+		noMatchRules(ctrl), // matchRuleRepository
+		nil,                // entityMergeRepository
+		nil,                // tableRelationRepository
+		nil,                // viper config
 	)
 
 	// Mock HasPermission check
@@ -395,11 +460,21 @@ func TestValidateUniqueConstraints_UpdateNoWorkflow_NoConflict(t *testing.T) {
 		Return(true, nil).
 		AnyTimes()
 
+	// Mock 状态检查 - 记录未锁定
+	mockEntityRepo.EXPECT().
+		Find("test_entity", "id,status", map[string]any{"id in": []uint{1}}).
+		Return([]map[string]any{{"id": uint(1), "status": "Normal"}}, nil)
+
 	// Mock 审批流程定义查询 - 无流程
 	mockTableApprovalDefRepo.EXPECT().
 		List("test_entity", "Update").
 		Return([]model.TableApprovalDefinition{}, nil)
 
+	// 模拟字段校验的字段定义查询 - 无校验规则
+	mockTableFieldService.EXPECT().
+		Find("", map[string]any{"table_code": "test_entity", "status": "Normal"}).
+		Return([]*model.TableField{}, nil)
+
 	// 模拟唯一索引字段查询
 	uniqueFields := []*model.TableField{
 		{Code: "code", IsUnique: "Yes", IndexName: ""},
@@ -463,6 +538,7 @@ func TestValidateUniqueConstraints_MultiFieldIndex(t *testing.T) {
 
 	mockEntityRepo := mock_repository.NewMockEntityRepository(ctrl)
 	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	mockTableFieldService.EXPECT().GetFieldAccess(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
 	mockTableFieldRepo := mock_repository.NewMockTableFieldRepository(ctrl)
 	mockTableApprovalDefRepo := mock_repository.NewMockTableApprovalDefinitionRepository(ctrl)
 	mockApprovalService := mock_service.NewMockApprovalService(ctrl)
@@ -483,8 +559,12 @@ func TestValidateUniqueConstraints_MultiFieldIndex(t *testing.T) {
 		mockEntityLogService,
 		mockAutocodeService,
 		mockTablePermissionService,
-		mockTableRepo, // tableRepository
-		nil,           // viper config
+		mockTableRepo,      // tableRepository
+		nil,                // entityJobService
+		noMatchRules(ctrl), // matchRuleRepository
+		nil,                // entityMergeRepository
+		nil,                // tableRelationRepository
+		nil,                // viper config
 	)
 
 	// Mock HasPermission check
@@ -517,6 +597,11 @@ func TestValidateUniqueConstraints_MultiFieldIndex(t *testing.T) {
 		GenerateOrRestoreAutocodes(gomock.Any(), "test_entity", gomock.Any(), mockTableFieldService, mockEntityRepo, gomock.Any()).
 		Return(nil)
 
+	// 模拟字段校验的字段定义查询 - 无校验规则
+	mockTableFieldService.EXPECT().
+		Find("", map[string]any{"table_code": "test_entity", "status": "Normal"}).
+		Return([]*model.TableField{}, nil)
+
 	// 模拟多字段联合唯一索引
 	uniqueFields := []*model.TableField{
 		{Code: "company_code", IsUnique: "Yes", IndexName: "idx_company_account"},
@@ -550,3 +635,774 @@ func TestValidateUniqueConstraints_MultiFieldIndex(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "唯一索引字段")
 }
+
+// TestCreate_FieldValidationFailed 测试新增时字段校验失败, 返回逐字段错误且不写入数据
+func TestCreate_FieldValidationFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockEntityRepo := mock_repository.NewMockEntityRepository(ctrl)
+	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	mockTableFieldService.EXPECT().GetFieldAccess(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	mockTableFieldRepo := mock_repository.NewMockTableFieldRepository(ctrl)
+	mockTableApprovalDefRepo := mock_repository.NewMockTableApprovalDefinitionRepository(ctrl)
+	mockApprovalService := mock_service.NewMockApprovalService(ctrl)
+	mockGlobalIdService := mock_service.NewMockGlobalIdService(ctrl)
+	mockEntityLogService := mock_service.NewMockEntityLogService(ctrl)
+	mockAutocodeService := mock_service.NewMockAutocodeService(ctrl)
+	mockTablePermissionService := mock_service.NewMockTablePermissionService(ctrl)
+	mockTableRepo := mock_repository.NewMockTableRepository(ctrl)
+
+	entityService := service.NewEntityService(
+		service.NewService(testLogger, nil, nil),
+		mockEntityRepo,
+		mockTableFieldService,
+		mockTableFieldRepo,
+		mockTableApprovalDefRepo,
+		mockApprovalService,
+		mockGlobalIdService,
+		mockEntityLogService,
+		mockAutocodeService,
+		mockTablePermissionService,
+		mockTableRepo,      // tableRepository
+		nil,                // entityJobService
+		noMatchRules(ctrl), // matchRuleRepository
+		nil,                // entityMergeRepository
+		nil,                // tableRelationRepository
+		nil,                // viper config
+	)
+
+	mockTablePermissionService.EXPECT().
+		CheckTablePermission(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(true, nil).
+		AnyTimes()
+
+	mockTableRepo.EXPECT().
+		Find("", map[string]any{"code": "test_entity"}).
+		Return([]*model.Table{}, nil)
+
+	mockApprovalService.EXPECT().
+		GetOperationInfo("Create", gomock.Any()).
+		DoAndReturn(func(operation string, info *map[string]string) error {
+			(*info)["action"] = "I"
+			(*info)["status"] = "Normal"
+			return nil
+		})
+
+	mockGlobalIdService.EXPECT().
+		GetNewID("entity").
+		Return(uint(1))
+
+	mockAutocodeService.EXPECT().
+		GenerateOrRestoreAutocodes(gomock.Any(), "test_entity", gomock.Any(), mockTableFieldService, mockEntityRepo, gomock.Any()).
+		Return(nil)
+
+	// 名称必填, 邮箱格式错误
+	mockTableFieldService.EXPECT().
+		Find("", map[string]any{"table_code": "test_entity", "status": "Normal"}).
+		Return([]*model.TableField{
+			{Code: "name", Name: "名称", Type: "Text", Required: "Yes"},
+			{Code: "email", Name: "邮箱", Type: "Text", Options: &model.FieldOptions{
+				Validation: &model.FieldValidation{Format: "email"},
+			}},
+		}, nil)
+
+	entityMap := map[string]any{
+		"email": "not-an-email",
+	}
+
+	c := &gin.Context{}
+	c.Set("user_id", uint(1))
+	err := entityService.Create(c, "test_entity", entityMap)
+
+	var validationErr *service.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Len(t, validationErr.Errors, 2)
+	assert.Equal(t, "name", validationErr.Errors[0].Field)
+	assert.Equal(t, "email", validationErr.Errors[1].Field)
+}
+
+// TestImport_UpsertMatchIndex 测试 Upsert 导入时唯一索引的选择
+func TestImport_UpsertMatchIndex(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockEntityRepo := mock_repository.NewMockEntityRepository(ctrl)
+	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	mockTableFieldService.EXPECT().GetFieldAccess(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	mockTablePermissionService := mock_service.NewMockTablePermissionService(ctrl)
+
+	entityService := service.NewEntityService(
+		service.NewService(testLogger, nil, nil),
+		mockEntityRepo,
+		mockTableFieldService,
+		nil, nil, nil, nil, nil, nil,
+		mockTablePermissionService,
+		nil,                // tableRepository
+		nil,                // entityJobService
+		noMatchRules(ctrl), // matchRuleRepository
+		nil,                // entityMergeRepository
+		nil,                // tableRelationRepository
+		nil,                // viper config
+	)
+
+	mockTablePermissionService.EXPECT().
+		CheckTablePermission(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(true, nil).
+		AnyTimes()
+
+	// 两个唯一索引: code 单字段, (org, tax_no) 联合
+	mockTableFieldService.EXPECT().
+		Find("code,is_unique,index_name", map[string]any{"table_code": "test_entity", "is_unique": "Yes", "status": "Normal"}).
+		Return([]*model.TableField{
+			{Code: "code", IsUnique: "Yes"},
+			{Code: "org", IsUnique: "Yes", IndexName: "uniq_org_tax"},
+			{Code: "tax_no", IsUnique: "Yes", IndexName: "uniq_org_tax"},
+		}, nil).
+		Times(2)
+
+	c := &gin.Context{}
+	c.Set("user_id", uint(1))
+
+	_, err := entityService.Import(c, "test_entity", service.ImportOptions{Operation: model.ImportOperationUpsert}, nil)
+	assert.ErrorContains(t, err, "match_index")
+
+	_, err = entityService.Import(c, "test_entity", service.ImportOptions{Operation: model.ImportOperationUpsert, MatchIndex: "uniq_missing"}, nil)
+	assert.ErrorContains(t, err, "uniq_missing")
+}
+
+// TestImport_JobSnapshot 导入在后台执行时, 返回的任务可以被并发读取 (go test -race)
+func TestImport_JobSnapshot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	mockTableFieldService.EXPECT().GetFieldAccess(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	mockTableFieldService.EXPECT().Find(gomock.Any(), gomock.Any()).
+		Return([]*model.TableField{{Code: "qty", Name: "数量", Type: "Number"}}, nil).AnyTimes()
+	mockTableApprovalDefRepo := mock_repository.NewMockTableApprovalDefinitionRepository(ctrl)
+	mockTableApprovalDefRepo.EXPECT().List("test_entity", model.ImportOperationCreate).Return(nil, nil)
+	mockApprovalService := mock_service.NewMockApprovalService(ctrl)
+	mockApprovalService.EXPECT().GetOperationInfo(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockTablePermissionService := mock_service.NewMockTablePermissionService(ctrl)
+	mockTablePermissionService.EXPECT().CheckTablePermission(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+
+	// 任务结束 (写入 FinishedAt) 时通知测试
+	finished := make(chan string, 1)
+	mockJobRepo := mock_repository.NewMockEntityJobRepository(ctrl)
+	mockJobRepo.EXPECT().FindExpired(gomock.Any()).Return(nil, nil)
+	mockJobRepo.EXPECT().Create(gomock.Any()).Return(nil)
+	mockJobRepo.EXPECT().Save(gomock.Any()).DoAndReturn(func(job *model.EntityJob) error {
+		if job.FinishedAt != nil {
+			finished <- job.Status
+		}
+		return nil
+	}).AnyTimes()
+	conf := viper.New()
+	conf.Set("app.runtime-root-path", t.TempDir()+"/")
+	jobService := service.NewEntityJobService(service.NewService(testLogger, nil, nil), mockJobRepo, conf)
+
+	entityService := service.NewEntityService(
+		service.NewService(testLogger, nil, nil),
+		nil,
+		mockTableFieldService,
+		nil,
+		mockTableApprovalDefRepo,
+		mockApprovalService,
+		nil, nil, nil,
+		mockTablePermissionService,
+		nil,                // tableRepository
+		jobService,         // entityJobService
+		noMatchRules(ctrl), // matchRuleRepository
+		nil,                // entityMergeRepository
+		nil,                // tableRelationRepository
+		nil,                // viper config
+	)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_id", uint(1))
+	file, err := service.ParseFileOptions("csv", "", "")
+	require.NoError(t, err)
+	job, err := entityService.Import(c, "test_entity", service.ImportOptions{Operation: model.ImportOperationCreate, File: file},
+		strings.NewReader("qty\nx\ny\n"))
+	require.NoError(t, err)
+
+	// 与查询任务进度的接口一样, 在后台任务执行期间序列化任务
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case status := <-finished:
+			assert.Equal(t, model.EntityJobStatusSucceeded, status)
+			assert.Equal(t, model.EntityJobStatusPending, job.Status)
+			return
+		case <-timeout:
+			t.Fatal("导入任务未结束")
+		default:
+			_, err := json.Marshal(job)
+			require.NoError(t, err)
+		}
+	}
+}
+
+// TestSearch_HiddenFields 当前用户不能查看的字段不返回, 也不能用于过滤
+func TestSearch_HiddenFields(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockEntityRepo := mock_repository.NewMockEntityRepository(ctrl)
+	expectCompileQuery(mockEntityRepo)
+	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	entityService := service.NewEntityService(
+		service.NewService(testLogger, nil, nil),
+		mockEntityRepo,
+		mockTableFieldService,
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+	)
+
+	mockTableFieldService.EXPECT().
+		Find("code,type,field_type", map[string]any{"table_code": "test_entity", "status": "Normal"}).
+		Return([]*model.TableField{{Code: "name", Type: "Text"}, {Code: "cost", Type: "Number"}}, nil).
+		Times(2)
+	mockTableFieldService.EXPECT().GetFieldAccess(gomock.Any(), "test_entity").
+		Return(&service.FieldAccess{Hidden: map[string]bool{"cost": true}}, nil).
+		Times(2)
+	mockEntityRepo.EXPECT().FindPage("test_entity", 1, 10, gomock.Any(), gomock.Any()).
+		Return([]map[string]any{{"id": uint64(1), "name": "A", "cost": 12}}, nil)
+
+	c := &gin.Context{}
+	var total int64
+	entities, err := entityService.Search(c, "test_entity", 1, 10, &total, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"id": uint64(1), "name": "A"}}, entities)
+
+	query := &model.EntityQuery{Filter: &model.EntityFilter{Field: "cost", Op: model.QueryOpGt, Value: 10}}
+	_, err = entityService.Search(c, "test_entity", 1, 10, &total, query)
+	assert.ErrorIs(t, err, model.ErrInvalidQuery)
+}
+
+// TestRollback_WithWorkflow 回滚按普通修改处理: 检查版本, 表配置了修改审批流程时提交审批
+func TestRollback_WithWorkflow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockEntityRepo := mock_repository.NewMockEntityRepository(ctrl)
+	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	mockTableFieldService.EXPECT().GetFieldAccess(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	mockTableApprovalDefRepo := mock_repository.NewMockTableApprovalDefinitionRepository(ctrl)
+	mockApprovalService := mock_service.NewMockApprovalService(ctrl)
+	mockTablePermissionService := mock_service.NewMockTablePermissionService(ctrl)
+
+	entityService := service.NewEntityService(
+		service.NewService(testLogger, nil, nil),
+		mockEntityRepo,
+		mockTableFieldService,
+		nil, // tableFieldRepository
+		mockTableApprovalDefRepo,
+		mockApprovalService,
+		nil, // globalIdService
+		nil, // entityLogService
+		nil, // autocodeService
+		mockTablePermissionService,
+		nil,                // tableRepository
+		nil,                // entityJobService
+		noMatchRules(ctrl), // matchRuleRepository
+		nil,                // entityMergeRepository
+		nil,                // tableRelationRepository
+		nil,                // viper config
+	)
+
+	mockTablePermissionService.EXPECT().
+		CheckTablePermission(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(true, nil).
+		AnyTimes()
+
+	current := map[string]any{"id": uint64(1), "name": "C", "price": "3", "status": "Normal", "version": uint64(4)}
+	mockEntityRepo.EXPECT().FindOne("test_entity", uint(1)).Return(current, nil)
+	mockTableFieldService.EXPECT().
+		Find("", map[string]any{"table_code": "test_entity", "status": "Normal"}).
+		Return([]*model.TableField{
+			{Code: "name", Name: "名称", Type: "Text"},
+			{Code: "price", Name: "价格", Type: "Number"},
+			{Code: "total", Name: "合计", Type: "Number", FieldType: "formula"},
+		}, nil)
+	// 撤销变更记录 10 之后的事件, 公式字段和状态不回滚
+	mockEntityRepo.EXPECT().
+		Find("test_entity_log", "*", map[string]any{"entity_id": uint(1), "id >": uint(10)}).
+		Return([]map[string]any{
+			{"id": uint64(11), "entity_id": uint64(1), "operation": "Update", "changes": `{"name":{"before":"B","after":"C"},"total":{"before":2,"after":3},"status":{"before":"Frozen","after":"Normal"}}`},
+		}, nil)
+
+	mockEntityRepo.EXPECT().Find("test_entity", "id,status", map[string]any{"id in": []uint{1}}).Return([]map[string]any{current}, nil)
+	mockTableFieldService.EXPECT().
+		Find("", map[string]any{"table_code": "test_entity"}).
+		Return([]*model.TableField{}, nil)
+	mockEntityRepo.EXPECT().Find("test_entity", "*", map[string]any{"id in": []uint{1}}).Return([]map[string]any{current}, nil)
+	mockTableApprovalDefRepo.EXPECT().
+		List("test_entity", "Update").
+		Return([]model.TableApprovalDefinition{{EntityCode: "test_entity", Operation: "Update", ApprovalDefCode: "AD1"}}, nil)
+	mockTableFieldService.EXPECT().
+		Find("code,is_unique,index_name", gomock.Any()).
+		Return([]*model.TableField{}, nil)
+	mockApprovalService.EXPECT().
+		UpdateDraftWithApproval(gomock.Any(), "test_entity", "回滚到变更记录 10", map[string]any{
+			"id":         uint(1),
+			"table_code": "test_entity",
+			"version":    uint(4),
+			"name":       "B",
+		}).
+		Return(nil)
+
+	c := &gin.Context{}
+	c.Set("user_id", uint(1))
+	err := entityService.Rollback(c, "test_entity", "", 1, service.RollbackTarget{LogID: 10})
+
+	assert.NoError(t, err)
+}
+
+func TestRestore_UniqueConflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockEntityRepo := mock_repository.NewMockEntityRepository(ctrl)
+	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	mockTableFieldService.EXPECT().GetFieldAccess(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	mockEntityLogService := mock_service.NewMockEntityLogService(ctrl)
+	mockTablePermissionService := mock_service.NewMockTablePermissionService(ctrl)
+
+	entityService := service.NewEntityService(
+		service.NewService(testLogger, nil, nil),
+		mockEntityRepo,
+		mockTableFieldService,
+		nil, // tableFieldRepository
+		nil, // tableApprovalDefinitionRepository
+		nil, // approvalService
+		nil, // globalIdService
+		mockEntityLogService,
+		nil, // autocodeService
+		mockTablePermissionService,
+		nil,                // tableRepository
+		nil,                // entityJobService
+		noMatchRules(ctrl), // matchRuleRepository
+		nil,                // entityMergeRepository
+		nil,                // tableRelationRepository
+		nil,                // viper config
+	)
+
+	mockTablePermissionService.EXPECT().
+		CheckTablePermission(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(true, nil).
+		AnyTimes()
+
+	deletedAt := time.Now().Add(-time.Hour)
+	trash := []map[string]any{
+		{"id": uint64(1), "code": "A", "status": "Deleted", "deleted_at": deletedAt},
+		{"id": uint64(2), "code": "B", "status": "Normal", "deleted_at": deletedAt},
+	}
+	mockEntityRepo.EXPECT().
+		FindWithDeleted("test_entity", "*", map[string]any{"id in": []uint{1, 2}}).
+		Return(trash, nil).
+		Times(2)
+	mockTableFieldService.EXPECT().
+		Find("code,is_unique,index_name", gomock.Any()).
+		Return([]*model.TableField{{Code: "code", IsUnique: "Yes"}}, nil).
+		AnyTimes()
+
+	// 编码 B 已被现有记录使用时不能恢复
+	mockEntityRepo.EXPECT().Find("test_entity", "id", map[string]any{"code": "A", "id !=": uint64(1)}).Return(nil, nil).Times(2)
+	mockEntityRepo.EXPECT().Find("test_entity", "id", map[string]any{"code": "B", "id !=": uint64(2)}).Return([]map[string]any{{"id": uint64(3)}}, nil)
+
+	c := &gin.Context{}
+	c.Set("user_id", uint(1))
+	err := entityService.Restore(c, "test_entity", "", []uint{1, 2})
+	assert.ErrorContains(t, err, "记录 2 不能恢复")
+
+	mockEntityRepo.EXPECT().Find("test_entity", "id", map[string]any{"code": "B", "id !=": uint64(2)}).Return(nil, nil)
+	mockEntityRepo.EXPECT().Restore(c, "test_entity", []uint{1, 2}).Return(nil)
+	mockTableFieldService.EXPECT().
+		Find("", map[string]any{"table_code": "test_entity"}).
+		Return([]*model.TableField{{Code: "code", Name: "编码"}}, nil)
+	// 恢复事件记录恢复后的字段, 标记为 Deleted 的状态恢复为 Normal
+	var logs []*model.EntityLog
+	mockEntityLogService.EXPECT().
+		Create(c, "test_entity", gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, _ string, entityLog *model.EntityLog) error {
+			logs = append(logs, entityLog)
+			return nil
+		}).
+		Times(2)
+
+	require.NoError(t, entityService.Restore(c, "test_entity", "误删", []uint{1, 2}))
+	require.Len(t, logs, 2)
+	assert.Equal(t, "Restore", logs[0].Operation)
+	assert.Equal(t, "误删", logs[0].Reason)
+	assert.JSONEq(t, `{"code":{"name":"编码","before":null,"after":"A"},"status":{"name":"状态","before":null,"after":"Normal"}}`, string(logs[0].Changes))
+}
+
+func TestPurge_Retention(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockEntityRepo := mock_repository.NewMockEntityRepository(ctrl)
+	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	mockTableFieldService.EXPECT().GetFieldAccess(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	mockEntityLogService := mock_service.NewMockEntityLogService(ctrl)
+
+	conf := viper.New()
+	conf.Set("app.trash-retention", "24h")
+	entityService := service.NewEntityService(
+		service.NewService(testLogger, nil, nil),
+		mockEntityRepo,
+		mockTableFieldService,
+		nil, // tableFieldRepository
+		nil, // tableApprovalDefinitionRepository
+		nil, // approvalService
+		nil, // globalIdService
+		mockEntityLogService,
+		nil,                // autocodeService
+		nil,                // tablePermissionService
+		nil,                // tableRepository
+		nil,                // entityJobService
+		noMatchRules(ctrl), // matchRuleRepository
+		nil,                // entityMergeRepository
+		nil,                // tableRelationRepository
+		conf,
+	)
+
+	expired := map[string]any{"id": uint64(1), "code": "A", "deleted_at": time.Now().Add(-48 * time.Hour)}
+	// 只查询删除超过保留时长的记录
+	findExpired := func(_, _ string, where map[string]any) ([]map[string]any, error) {
+		deletedBefore, ok := where["deleted_at <="].(time.Time)
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(-24*time.Hour), deletedBefore, time.Minute)
+		return []map[string]any{expired}, nil
+	}
+
+	// 指定的记录删除未满保留时长时不能彻底删除
+	mockEntityRepo.EXPECT().FindWithDeleted("test_entity", "*", gomock.Any()).DoAndReturn(findExpired)
+	c := &gin.Context{}
+	_, err := entityService.Purge(c, "test_entity", "", []uint{1, 2})
+	assert.ErrorContains(t, err, "记录 2 不在回收站中或删除未满 24h0m0s")
+
+	// 不指定 ids 时清理全部过期记录, 并记录彻底删除事件
+	mockEntityRepo.EXPECT().FindWithDeleted("test_entity", "*", gomock.Any()).DoAndReturn(findExpired)
+	mockEntityRepo.EXPECT().Purge(c, "test_entity", []uint{1}).Return(nil)
+	mockTableFieldService.EXPECT().
+		Find("", map[string]any{"table_code": "test_entity"}).
+		Return([]*model.TableField{{Code: "code", Name: "编码"}}, nil)
+	mockEntityLogService.EXPECT().
+		Create(c, "test_entity", gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, _ string, entityLog *model.EntityLog) error {
+			assert.Equal(t, "Purge", entityLog.Operation)
+			assert.JSONEq(t, `{"code":{"name":"编码","before":"A","after":null}}`, string(entityLog.Changes))
+			return nil
+		})
+
+	purged, err := entityService.Purge(c, "test_entity", "清理", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+}
+
+func TestChangeStatus_NoWorkflow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockEntityRepo := mock_repository.NewMockEntityRepository(ctrl)
+	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	mockTableFieldService.EXPECT().GetFieldAccess(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	mockTableApprovalDefRepo := mock_repository.NewMockTableApprovalDefinitionRepository(ctrl)
+	mockApprovalService := mock_service.NewMockApprovalService(ctrl)
+	mockEntityLogService := mock_service.NewMockEntityLogService(ctrl)
+	mockTablePermissionService := mock_service.NewMockTablePermissionService(ctrl)
+
+	entityService := service.NewEntityService(
+		service.NewService(testLogger, nil, nil),
+		mockEntityRepo,
+		mockTableFieldService,
+		nil, // tableFieldRepository
+		mockTableApprovalDefRepo,
+		mockApprovalService,
+		nil, // globalIdService
+		mockEntityLogService,
+		nil, // autocodeService
+		mockTablePermissionService,
+		nil,                // tableRepository
+		nil,                // entityJobService
+		noMatchRules(ctrl), // matchRuleRepository
+		nil,                // entityMergeRepository
+		nil,                // tableRelationRepository
+		nil,                // viper config
+	)
+
+	mockTablePermissionService.EXPECT().
+		CheckTablePermission(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(true, nil).
+		AnyTimes()
+	mockTableFieldService.EXPECT().
+		Find(gomock.Any(), gomock.Any()).
+		Return([]*model.TableField{{Code: "name", Name: "名称"}}, nil).
+		AnyTimes()
+
+	c := &gin.Context{}
+	c.Set("user_id", uint(1))
+
+	// 不是生命周期操作
+	assert.Error(t, entityService.ChangeStatus(c, "test_entity", "Delete", "", []uint{1}, nil))
+
+	// 未冻结的数据不能解冻
+	mockEntityRepo.EXPECT().
+		Find("test_entity", "id,status", map[string]any{"id in": []uint{1}}).
+		Return([]map[string]any{{"id": uint64(1), "status": "Normal"}}, nil)
+	err := entityService.ChangeStatus(c, "test_entity", "Unfreeze", "", []uint{1}, nil)
+	var statusErr *service.StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, uint(1), statusErr.ID)
+	assert.Equal(t, "记录 1 当前状态为 Normal, 不能解冻", err.Error())
+
+	// 没有审批流程时直接锁定, 并记录变更历史
+	origin := map[string]any{"id": uint64(1), "name": "A", "status": "Normal", "version": uint64(3)}
+	mockEntityRepo.EXPECT().
+		Find("test_entity", "id,status", map[string]any{"id in": []uint{1}}).
+		Return([]map[string]any{origin}, nil)
+	mockTableApprovalDefRepo.EXPECT().List("test_entity", "Lock").Return(nil, nil)
+	mockApprovalService.EXPECT().
+		GetOperationInfo("Lock", gomock.Any()).
+		DoAndReturn(func(_ string, info *map[string]string) error {
+			(*info)["status"] = "Locked"
+			(*info)["action"] = "U"
+			return nil
+		})
+	// 检查版本号和记录变更历史各读取一次
+	mockEntityRepo.EXPECT().Find("test_entity", "*", map[string]any{"id in": []uint{1}}).Return([]map[string]any{origin}, nil).Times(2)
+	mockEntityRepo.EXPECT().
+		BatchUpdate(c, "test_entity", []uint{1}, map[uint]uint{1: 3}, gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, _ string, _ []uint, _ map[uint]uint, entityMap map[string]any) error {
+			assert.Equal(t, "Locked", entityMap["status"])
+			assert.Equal(t, "Lock", entityMap["operation"])
+			return nil
+		})
+	mockEntityLogService.EXPECT().
+		Create(c, "test_entity", gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, _ string, entityLog *model.EntityLog) error {
+			assert.Equal(t, "Lock", entityLog.Operation)
+			assert.Equal(t, "停用", entityLog.Reason)
+			assert.JSONEq(t, `{"status":{"name":"状态","before":"Normal","after":"Locked"}}`, string(entityLog.Changes))
+			return nil
+		})
+
+	require.NoError(t, entityService.ChangeStatus(c, "test_entity", "Lock", "停用", []uint{1}, map[uint]uint{1: 3}))
+}
+
+func TestMove_Subtree(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockEntityRepo := mock_repository.NewMockEntityRepository(ctrl)
+	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	mockTableFieldService.EXPECT().GetFieldAccess(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	mockTableApprovalDefRepo := mock_repository.NewMockTableApprovalDefinitionRepository(ctrl)
+	mockEntityLogService := mock_service.NewMockEntityLogService(ctrl)
+	mockTablePermissionService := mock_service.NewMockTablePermissionService(ctrl)
+	mockTableRepo := mock_repository.NewMockTableRepository(ctrl)
+
+	entityService := service.NewEntityService(
+		service.NewService(testLogger, nil, nil),
+		mockEntityRepo,
+		mockTableFieldService,
+		nil, // tableFieldRepository
+		mockTableApprovalDefRepo,
+		nil, // approvalService
+		nil, // globalIdService
+		mockEntityLogService,
+		nil, // autocodeService
+		mockTablePermissionService,
+		mockTableRepo,
+		nil,                // entityJobService
+		noMatchRules(ctrl), // matchRuleRepository
+		nil,                // entityMergeRepository
+		nil,                // tableRelationRepository
+		nil,                // viper config
+	)
+
+	mockTablePermissionService.EXPECT().
+		CheckTablePermission(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(true, nil).
+		AnyTimes()
+	mockTableRepo.EXPECT().
+		Find("", map[string]any{"code": "category"}).
+		Return([]*model.Table{{Code: "category", DisplayMode: "Tree"}}, nil).
+		AnyTimes()
+	mockTableFieldService.EXPECT().
+		Find(gomock.Any(), gomock.Any()).
+		Return([]*model.TableField{}, nil).
+		AnyTimes()
+	mockEntityRepo.EXPECT().
+		Find("category", "id,status", gomock.Any()).
+		Return([]map[string]any{{"id": uint64(2), "status": "Normal"}}, nil).
+		AnyTimes()
+
+	c := &gin.Context{}
+	c.Set("user_id", uint(1))
+
+	// 树: 1 -> 2 -> 3, 4 为另一个根节点
+	child := map[string]any{"id": uint64(3), "parent_id": uint64(2), "level": uint64(3), "path": "/1/2/3/"}
+	mockEntityRepo.EXPECT().FindChildren("category", []uint{2}, true).Return([]map[string]any{child}, nil).Times(2)
+	mockEntityRepo.EXPECT().FindChildren("category", []uint{3}, true).Return(nil, nil)
+
+	// 不能移动到自身或后代下
+	var cycleErr *service.TreeCycleError
+	require.ErrorAs(t, entityService.Move(c, "category", "", 2, 2, nil), &cycleErr)
+	mockEntityRepo.EXPECT().FindOne("category", uint(3)).Return(child, nil)
+	err := entityService.Move(c, "category", "", 2, 3, nil)
+	require.ErrorAs(t, err, &cycleErr)
+	assert.Equal(t, "记录 3 是记录 2 的后代, 不能作为其父节点", err.Error())
+
+	// 移动到根节点 4 下, 同时改写后代的层级和路径
+	mockEntityRepo.EXPECT().FindOne("category", uint(4)).Return(map[string]any{"id": uint64(4), "level": uint64(1), "path": "/4/"}, nil)
+	mockTableApprovalDefRepo.EXPECT().List("category", "Update").Return(nil, nil)
+	mockEntityRepo.EXPECT().FindOne("category", uint(2)).Return(map[string]any{"id": uint64(2), "parent_id": uint64(1), "level": uint64(2), "path": "/1/2/"}, nil)
+	mockEntityRepo.EXPECT().
+		MoveSubtree(c, "category", gomock.Any(), map[string]any{"id": uint(2)}, gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, _ string, entity, _ map[string]any, descendants []*repository.TreePath) error {
+			assert.Equal(t, uint(4), entity["parent_id"])
+			assert.Equal(t, 2, entity["level"])
+			assert.Equal(t, "/4/2/", entity["path"])
+			assert.Equal(t, []*repository.TreePath{{ID: 3, Level: 3, Path: "/4/2/3/"}}, descendants)
+			return nil
+		})
+	mockEntityLogService.EXPECT().
+		Create(c, "category", gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, _ string, entityLog *model.EntityLog) error {
+			assert.Equal(t, "移动", entityLog.Reason)
+			assert.JSONEq(t, `{"parent_id":{"before":"1","after":"4"},"path":{"before":"/1/2/","after":"/4/2/"}}`, string(entityLog.Changes))
+			return nil
+		})
+	require.NoError(t, entityService.Move(c, "category", "移动", 2, 4, nil))
+}
+
+// TestSaveDocument_NoWorkflow 测试无流程修改单据: 抬头和行项目在同一事务中修改、新增、删除
+func TestSaveDocument_NoWorkflow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockEntityRepo := mock_repository.NewMockEntityRepository(ctrl)
+	mockTableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	mockTableFieldService.EXPECT().GetFieldAccess(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	mockTableApprovalDefRepo := mock_repository.NewMockTableApprovalDefinitionRepository(ctrl)
+	mockApprovalService := mock_service.NewMockApprovalService(ctrl)
+	mockGlobalIdService := mock_service.NewMockGlobalIdService(ctrl)
+	mockEntityLogService := mock_service.NewMockEntityLogService(ctrl)
+	mockAutocodeService := mock_service.NewMockAutocodeService(ctrl)
+	mockTablePermissionService := mock_service.NewMockTablePermissionService(ctrl)
+	mockTableRepo := mock_repository.NewMockTableRepository(ctrl)
+
+	entityService := service.NewEntityService(
+		service.NewService(testLogger, nil, nil),
+		mockEntityRepo,
+		mockTableFieldService,
+		nil, // tableFieldRepository
+		mockTableApprovalDefRepo,
+		mockApprovalService,
+		mockGlobalIdService,
+		mockEntityLogService,
+		mockAutocodeService,
+		mockTablePermissionService,
+		mockTableRepo,
+		nil,                // entityJobService
+		noMatchRules(ctrl), // matchRuleRepository
+		nil,                // entityMergeRepository
+		nil,                // tableRelationRepository
+		nil,                // viper config
+	)
+
+	mockTablePermissionService.EXPECT().
+		CheckTablePermission(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(true, nil).
+		AnyTimes()
+	mockTableRepo.EXPECT().
+		Find("", map[string]any{"table_type": "Item", "parent_table": "order"}).
+		Return([]*model.Table{{Code: "order_item", TableType: "Item", ParentTable: "order", ParentField: "code", SelfField: "order_code"}}, nil).
+		AnyTimes()
+	mockTableRepo.EXPECT().Find("", map[string]any{"code": "order_item"}).Return(nil, nil).AnyTimes()
+	mockTableFieldService.EXPECT().
+		Find(gomock.Any(), gomock.Any()).
+		Return([]*model.TableField{}, nil).
+		AnyTimes()
+	mockTableApprovalDefRepo.EXPECT().List("order", "Update").Return(nil, nil).Times(2)
+
+	c := &gin.Context{}
+	c.Set("user_id", uint(1))
+
+	// 不是抬头表的行项目表
+	_, err := entityService.SaveDocument(c, "order", "", &service.EntityDocument{
+		Header: map[string]any{"id": uint(1)},
+		Items:  map[string][]map[string]any{"invoice_item": {}},
+	})
+	require.ErrorIs(t, err, model.ErrInvalidQuery)
+
+	// 抬头 SO1 改为 SO2: 行 10 修改, 行 11 未提交删除, 新增一行
+	mockEntityRepo.EXPECT().
+		FindOne("order", uint(1)).
+		Return(map[string]any{"id": uint64(1), "code": "SO1", "status": "Normal", "version": uint64(3)}, nil)
+	mockEntityRepo.EXPECT().
+		Find("order_item", "*", map[string]any{"order_code": "SO1"}).
+		Return([]map[string]any{
+			{"id": uint64(10), "order_code": "SO1", "qty": int64(1), "status": "Normal", "version": uint64(0)},
+			{"id": uint64(11), "order_code": "SO1", "qty": int64(5), "status": "Normal", "version": uint64(2)},
+		}, nil)
+	mockApprovalService.EXPECT().GetOperationInfo("Create", gomock.Any()).Return(nil)
+	mockGlobalIdService.EXPECT().GetNewID("entity").Return(uint(12))
+	mockAutocodeService.EXPECT().GenerateOrRestoreAutocodes(c, "order_item", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	mockApprovalService.EXPECT().CheckDeleteReferences(c, "order_item", []uint{11}).Return(nil)
+
+	mockEntityRepo.EXPECT().
+		Transaction(c, gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, fn func(repository.EntityRepository) error) error {
+			return fn(mockEntityRepo)
+		})
+	mockEntityRepo.EXPECT().
+		Update(c, "order", gomock.Any(), map[string]any{"id": uint(1), "version": uint(3)}).
+		DoAndReturn(func(_ *gin.Context, _ string, entity any, _ map[string]any) error {
+			assert.Equal(t, "SO2", entity.(map[string]any)["code"])
+			return nil
+		})
+	mockEntityRepo.EXPECT().
+		Update(c, "order_item", gomock.Any(), map[string]any{"id": uint(10), "version": uint(0)}).
+		DoAndReturn(func(_ *gin.Context, _ string, entity any, _ map[string]any) error {
+			assert.Equal(t, "SO2", entity.(map[string]any)["order_code"])
+			assert.Equal(t, 2, entity.(map[string]any)["qty"])
+			return nil
+		})
+	mockEntityRepo.EXPECT().
+		Create(c, "order_item", gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, _ string, entity any) error {
+			assert.Equal(t, uint(12), entity.(map[string]any)["id"])
+			assert.Equal(t, "SO2", entity.(map[string]any)["order_code"])
+			return nil
+		})
+	mockEntityRepo.EXPECT().Delete(c, "order_item", uint(11), "调整").Return(nil)
+	mockEntityLogService.EXPECT().Create(c, gomock.Any(), gomock.Any()).Return(nil).Times(4)
+	mockApprovalService.EXPECT().
+		ApplyDeleteReferences(c, "order_item", "调整", gomock.Any()).
+		DoAndReturn(func(_ *gin.Context, _, _ string, rows []map[string]any) error {
+			require.Len(t, rows, 1)
+			assert.Equal(t, uint64(11), rows[0]["id"])
+			return nil
+		})
+
+	id, err := entityService.SaveDocument(c, "order", "调整", &service.EntityDocument{
+		Header: map[string]any{"id": uint(1), "code": "SO2", "version": uint(3)},
+		Items: map[string][]map[string]any{"order_item": {
+			{"id": uint(10), "qty": 2},
+			{"qty": 3},
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), id)
+}
+
+// noMatchRules 没有配置匹配规则的表
+func noMatchRules(ctrl *gomock.Controller) *mock_repository.MockMatchRuleRepository {
+	repo := mock_repository.NewMockMatchRuleRepository(ctrl)
+	repo.EXPECT().Find(gomock.Any()).Return(nil, nil).AnyTimes()
+	return repo
+}
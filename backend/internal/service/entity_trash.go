@@ -0,0 +1,179 @@
+package service
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"piemdm/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultTrashRetention 回收站记录的默认保留时长, 超过后才允许彻底删除
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// markDeleted 标记软删除: 删除时间、删除人和删除原因
+func markDeleted(entityMap map[string]any, deletedBy, reason string) {
+	entityMap["deleted_at"] = time.Now()
+	entityMap["deleted_by"] = deletedBy
+	entityMap["delete_reason"] = reason
+}
+
+// trashRetention 回收站保留时长, 配置项 app.trash-retention (如 720h)
+func (s *entityService) trashRetention() time.Duration {
+	if s.conf != nil {
+		if retention := s.conf.GetDuration("app.trash-retention"); retention > 0 {
+			return retention
+		}
+	}
+	return defaultTrashRetention
+}
+
+// ListTrash 查询回收站中的记录, 默认按删除时间倒序
+func (s *entityService) ListTrash(c *gin.Context, tableCode string, page, pageSize int, total *int64, query *model.EntityQuery) ([]map[string]any, error) {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return nil, err
+	}
+	if query == nil {
+		query = &model.EntityQuery{}
+	}
+	if len(query.Sort) == 0 {
+		query.Sort = []model.EntitySort{{Field: "deleted_at", Desc: true}}
+	}
+	columns, access, err := s.visibleColumns(c, tableCode)
+	if err != nil {
+		return nil, err
+	}
+	columns["deleted_at"] = "DateTime"
+	columns["deleted_by"] = "Text"
+	columns["delete_reason"] = "Text"
+	compiled, err := s.entityRepository.CompileQuery(tableCode, query, columns, "t")
+	if err != nil {
+		return nil, err
+	}
+	entities, err := s.entityRepository.FindDeletedPage(tableCode, page, pageSize, total, compiled)
+	if err != nil {
+		return nil, err
+	}
+	access.Hide(entities...)
+	return entities, nil
+}
+
+// trashRows 读取回收站中的记录, 有记录不存在或未删除时返回错误
+func (s *entityService) trashRows(tableCode string, ids []uint) ([]map[string]any, error) {
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("请选择要恢复的记录")
+	}
+	rows, err := s.entityRepository.FindWithDeleted(tableCode, "*", map[string]any{"id in": ids})
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[uint]bool, len(rows))
+	for _, row := range rows {
+		id, err := importRowID(row)
+		if err != nil {
+			continue
+		}
+		if row["deleted_at"] == nil {
+			return nil, fmt.Errorf("记录 %d 未删除", id)
+		}
+		found[id] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return nil, fmt.Errorf("记录 %d 不在回收站中", id)
+		}
+	}
+	return rows, nil
+}
+
+// Restore 从回收站恢复记录, 恢复后的记录不能与现有记录的唯一字段重复
+func (s *entityService) Restore(c *gin.Context, tableCode, reason string, ids []uint) error {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return err
+	}
+	rows, err := s.trashRows(tableCode, ids)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := s.validateUniqueConstraints(c, "Update", tableCode, row, false); err != nil {
+			return fmt.Errorf("记录 %v 不能恢复: %w", row["id"], err)
+		}
+	}
+	if err := s.entityRepository.Restore(c, tableCode, ids); err != nil {
+		return err
+	}
+
+	// 记录变更历史: 恢复后的全部字段
+	tableFields, err := s.tableFieldService.Find("", map[string]any{"table_code": tableCode})
+	if err != nil {
+		s.logger.Error("获取表字段失败", "error", err)
+	}
+	event := entityEvent{Operation: "Restore", Reason: reason}
+	for _, row := range rows {
+		id, err := importRowID(row)
+		if err != nil {
+			continue
+		}
+		restored := maps.Clone(row)
+		if restored["status"] == "Deleted" {
+			restored["status"] = "Normal"
+		}
+		if err := writeEntityLog(c, s.entityLogService, tableCode, tableFields, event, id, nil, restored); err != nil {
+			s.logger.Error("创建变更日志失败", "error", err, "id", id)
+		}
+	}
+	return nil
+}
+
+// Purge 彻底删除回收站中超过保留时长的记录, 不指定 ids 时清理全部过期记录, 返回删除的记录数
+// 仅供管理员接口调用, 不做用户表权限检查
+func (s *entityService) Purge(c *gin.Context, tableCode, reason string, ids []uint) (int, error) {
+	retention := s.trashRetention()
+	where := map[string]any{"deleted_at <=": time.Now().Add(-retention)}
+	if len(ids) > 0 {
+		where["id in"] = ids
+	}
+	rows, err := s.entityRepository.FindWithDeleted(tableCode, "*", where)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		if id, err := importRowID(row); err == nil {
+			purged = append(purged, id)
+		}
+	}
+	for _, id := range ids {
+		if !slices.Contains(purged, id) {
+			return 0, fmt.Errorf("记录 %d 不在回收站中或删除未满 %s", id, retention)
+		}
+	}
+	if len(purged) == 0 {
+		return 0, nil
+	}
+	if err := s.entityRepository.Purge(c, tableCode, purged); err != nil {
+		return 0, err
+	}
+
+	// 记录变更历史: 彻底删除前的全部字段
+	tableFields, err := s.tableFieldService.Find("", map[string]any{"table_code": tableCode})
+	if err != nil {
+		s.logger.Error("获取表字段失败", "error", err)
+	}
+	event := entityEvent{Operation: "Purge", Reason: reason}
+	for _, row := range rows {
+		id, err := importRowID(row)
+		if err != nil {
+			continue
+		}
+		if err := writeEntityLog(c, s.entityLogService, tableCode, tableFields, event, id, row, nil); err != nil {
+			s.logger.Error("创建变更日志失败", "error", err, "id", id)
+		}
+	}
+	return len(purged), nil
+}
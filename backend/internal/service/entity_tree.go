@@ -0,0 +1,302 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxTreeNodes 移动子树或层级查询一次处理的最大节点数
+const maxTreeNodes = 10000
+
+// TreeCycleError 移动节点后形成环: 新的父节点是节点本身或其后代
+type TreeCycleError struct {
+	ID       uint `json:"id"`
+	ParentID uint `json:"parent_id"`
+}
+
+func (e *TreeCycleError) Error() string {
+	if e.ID == e.ParentID {
+		return fmt.Sprintf("记录 %d 不能作为自身的父节点", e.ID)
+	}
+	return fmt.Sprintf("记录 %d 是记录 %d 的后代, 不能作为其父节点", e.ParentID, e.ID)
+}
+
+// isTreeTable 表是否为树形结构 (DisplayMode 为 Tree)
+func (s *entityService) isTreeTable(tableCode string) bool {
+	tables, err := s.tableRepository.Find("", map[string]any{"code": tableCode})
+	return err == nil && len(tables) > 0 && tables[0].DisplayMode == "Tree"
+}
+
+// hasTreeFields 表字段中是否有树形结构字段 (parent_id、path)
+func hasTreeFields(fields []*model.TableField) bool {
+	var parent, path bool
+	for _, field := range fields {
+		parent = parent || field.Code == "parent_id"
+		path = path || field.Code == "path"
+	}
+	return parent && path
+}
+
+// treeParentID 记录的父节点 id, 根节点 (parent_id 为空或 0) 返回 0
+func treeParentID(row map[string]any) uint {
+	id, _ := uintValue(row["parent_id"])
+	return id
+}
+
+// childTreePath 节点作为 parent 子节点时的层级和路径, parent 为 nil 时为根节点
+// 父节点没有路径时 (历史数据) 按 /<父节点 id>/ 处理
+func childTreePath(parent map[string]any, id uint) (int, string) {
+	if parent == nil {
+		return 1, fmt.Sprintf("/%d/", id)
+	}
+	parentID, _ := importRowID(parent)
+	level, _ := uintValue(parent["level"])
+	path, _ := parent["path"].(string)
+	if b, ok := parent["path"].([]byte); ok {
+		path = string(b)
+	}
+	if path == "" || path == "/" {
+		path = fmt.Sprintf("/%d/", parentID)
+	} else if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return int(level) + 1, fmt.Sprintf("%s%d/", path, id)
+}
+
+// planTreeMove 节点 (entityMap 中的 id) 移动到 parent_id 下: 检查是否形成环, 计算节点及其后代新的层级和路径
+// 节点的 level、path 写入 entityMap, 返回需要改写的后代 (含回收站中的记录)
+func planTreeMove(repo repository.EntityRepository, tableCode string, entityMap map[string]any) ([]*repository.TreePath, error) {
+	id, ok := uintValue(entityMap["id"])
+	if !ok || id == 0 {
+		return nil, fmt.Errorf("invalid entity id type")
+	}
+	parentID := treeParentID(entityMap)
+	if parentID == id {
+		return nil, &TreeCycleError{ID: id, ParentID: parentID}
+	}
+
+	var parent map[string]any
+	if parentID != 0 {
+		row, err := repo.FindOne(tableCode, parentID)
+		if err != nil || row == nil || row["deleted_at"] != nil {
+			return nil, fmt.Errorf("父节点 %d 不存在", parentID)
+		}
+		parent = row
+	}
+	level, path := childTreePath(parent, id)
+	entityMap["level"], entityMap["path"] = level, path
+
+	// 逐层查询后代, 按新的父节点路径计算; 父节点在后代中时形成环
+	nodes := map[uint]*repository.TreePath{id: {ID: id, Level: level, Path: path}}
+	var descendants []*repository.TreePath
+	for frontier := []uint{id}; len(frontier) > 0; {
+		children, err := repo.FindChildren(tableCode, frontier, true)
+		if err != nil {
+			return nil, fmt.Errorf("查询子节点失败: %v", err)
+		}
+		frontier = nil
+		for _, child := range children {
+			childID, err := importRowID(child)
+			if err != nil || nodes[childID] != nil {
+				continue
+			}
+			if childID == parentID {
+				return nil, &TreeCycleError{ID: id, ParentID: parentID}
+			}
+			upper := nodes[treeParentID(child)]
+			node := &repository.TreePath{ID: childID, Level: upper.Level + 1, Path: fmt.Sprintf("%s%d/", upper.Path, childID)}
+			nodes[childID] = node
+			descendants = append(descendants, node)
+			frontier = append(frontier, childID)
+		}
+		if len(descendants) > maxTreeNodes {
+			return nil, fmt.Errorf("子树超过 %d 个节点, 不能移动", maxTreeNodes)
+		}
+	}
+	return descendants, nil
+}
+
+// treeMoved 修改是否改变了父节点
+func treeMoved(origin, entityMap map[string]any) bool {
+	value, ok := entityMap["parent_id"]
+	if !ok {
+		return false
+	}
+	parentID, _ := uintValue(value)
+	return parentID != treeParentID(origin)
+}
+
+// checkTreeTable 层级操作只支持树形结构的表
+func (s *entityService) checkTreeTable(c *gin.Context, tableCode string) error {
+	if err := s.checkPermission(c, tableCode); err != nil {
+		return err
+	}
+	if !s.isTreeTable(tableCode) {
+		return fmt.Errorf("%w: 表 %s 不是树形结构", model.ErrInvalidQuery, tableCode)
+	}
+	return nil
+}
+
+// treeNode 读取未删除的节点
+func (s *entityService) treeNode(tableCode string, id uint) (map[string]any, error) {
+	row, err := s.entityRepository.FindOne(tableCode, id)
+	if err != nil || row == nil || row["deleted_at"] != nil {
+		return nil, fmt.Errorf("记录 %d 不存在", id)
+	}
+	return row, nil
+}
+
+// subtree 逐层查询 ids 的后代 (不含 ids 本身), depth 为查询的层数, 小于等于 0 时不限层数
+func (s *entityService) subtree(tableCode string, ids []uint, depth int) ([]map[string]any, error) {
+	visited := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		visited[id] = true
+	}
+	var rows []map[string]any
+	for level := 1; len(ids) > 0 && (depth <= 0 || level <= depth); level++ {
+		children, err := s.entityRepository.FindChildren(tableCode, ids, false)
+		if err != nil {
+			return nil, err
+		}
+		ids = nil
+		for _, child := range children {
+			id, err := importRowID(child)
+			if err != nil || visited[id] {
+				continue
+			}
+			visited[id] = true
+			rows = append(rows, child)
+			ids = append(ids, id)
+		}
+		if len(rows) > maxTreeNodes {
+			return nil, fmt.Errorf("%w: 超过 %d 个节点, 请限制层级深度", model.ErrInvalidQuery, maxTreeNodes)
+		}
+	}
+	return rows, nil
+}
+
+// Move 移动节点及其子树到新的父节点下, parentID 为 0 时移动为根节点
+// 按修改父节点处理: 表配置了修改审批流程时提交审批, 否则直接修改并改写后代的层级和路径
+func (s *entityService) Move(c *gin.Context, tableCode, reason string, id, parentID uint, versions map[uint]uint) error {
+	if err := s.checkTreeTable(c, tableCode); err != nil {
+		return err
+	}
+	entityMap := map[string]any{"id": id, "parent_id": nil}
+	if parentID != 0 {
+		entityMap["parent_id"] = parentID
+	}
+	if version, ok := versions[id]; ok {
+		entityMap["version"] = version
+	}
+	return s.UpdateDraft(c, tableCode, reason, entityMap)
+}
+
+// Ancestors 查询节点的祖先, 从根节点到父节点排列
+func (s *entityService) Ancestors(c *gin.Context, tableCode string, id uint) ([]map[string]any, error) {
+	if err := s.checkTreeTable(c, tableCode); err != nil {
+		return nil, err
+	}
+	node, err := s.treeNode(tableCode, id)
+	if err != nil {
+		return nil, err
+	}
+	ancestors := []map[string]any{}
+	visited := map[uint]bool{id: true}
+	for parentID := treeParentID(node); parentID != 0 && !visited[parentID]; parentID = treeParentID(node) {
+		visited[parentID] = true
+		if node, err = s.treeNode(tableCode, parentID); err != nil {
+			return nil, err
+		}
+		ancestors = append(ancestors, node)
+	}
+	slices.Reverse(ancestors)
+	return ancestors, nil
+}
+
+// Descendants 查询节点的后代, 按层级排列; depth 为查询的层数, 小于等于 0 时不限层数
+func (s *entityService) Descendants(c *gin.Context, tableCode string, id uint, depth int) ([]map[string]any, error) {
+	if err := s.checkTreeTable(c, tableCode); err != nil {
+		return nil, err
+	}
+	if _, err := s.treeNode(tableCode, id); err != nil {
+		return nil, err
+	}
+	rows, err := s.subtree(tableCode, []uint{id}, depth)
+	if rows == nil && err == nil {
+		rows = []map[string]any{}
+	}
+	return rows, err
+}
+
+// Siblings 查询与节点同一父节点的其他节点, 根节点的兄弟为其他根节点
+func (s *entityService) Siblings(c *gin.Context, tableCode string, id uint) ([]map[string]any, error) {
+	if err := s.checkTreeTable(c, tableCode); err != nil {
+		return nil, err
+	}
+	node, err := s.treeNode(tableCode, id)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.entityRepository.FindChildren(tableCode, []uint{treeParentID(node)}, false)
+	if err != nil {
+		return nil, err
+	}
+	siblings := []map[string]any{}
+	for _, row := range rows {
+		if rowID, err := importRowID(row); err == nil && rowID != id {
+			siblings = append(siblings, row)
+		}
+	}
+	return siblings, nil
+}
+
+// Tree 查询树形数据, 子节点放在 children 中; rootID 为 0 时返回全部根节点, 否则返回该节点及其子树
+// depth 为返回的层数 (含起始层), 小于等于 0 时不限层数
+func (s *entityService) Tree(c *gin.Context, tableCode string, rootID uint, depth int) ([]map[string]any, error) {
+	if err := s.checkTreeTable(c, tableCode); err != nil {
+		return nil, err
+	}
+	var roots, rows []map[string]any
+	var err error
+	if rootID == 0 {
+		rows, err = s.subtree(tableCode, []uint{0}, depth)
+	} else {
+		var root map[string]any
+		if root, err = s.treeNode(tableCode, rootID); err != nil {
+			return nil, err
+		}
+		roots = append(roots, root)
+		if depth != 1 {
+			rows, err = s.subtree(tableCode, []uint{rootID}, depth-1)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uint]map[string]any, len(roots)+len(rows))
+	for _, row := range append(roots, rows...) {
+		if id, err := importRowID(row); err == nil {
+			nodes[id] = row
+		}
+	}
+	for _, row := range rows {
+		parent, ok := nodes[treeParentID(row)]
+		if !ok {
+			roots = append(roots, row)
+			continue
+		}
+		children, _ := parent["children"].([]map[string]any)
+		parent["children"] = append(children, row)
+	}
+	if roots == nil {
+		roots = []map[string]any{}
+	}
+	return roots, nil
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+)
+
+// ConflictError 乐观锁冲突: 记录在读取后已被其他人修改, 附带当前值和字段差异
+type ConflictError struct {
+	Conflicts []*EntityConflict `json:"conflicts"`
+}
+
+func (e *ConflictError) Error() string {
+	ids := make([]string, 0, len(e.Conflicts))
+	for _, conflict := range e.Conflicts {
+		ids = append(ids, strconv.FormatUint(uint64(conflict.ID), 10))
+	}
+	return "数据已被其他人修改, 请刷新后重试: id " + strings.Join(ids, ", ")
+}
+
+// EntityConflict 一条记录的冲突明细
+type EntityConflict struct {
+	ID             uint           `json:"id"`
+	Version        uint           `json:"version"`         // 修改依据的版本
+	CurrentVersion uint           `json:"current_version"` // 当前版本
+	Current        map[string]any `json:"current"`         // 当前值
+	Diff           []FieldDiff    `json:"diff"`            // 提交值与当前值不同的字段
+}
+
+// FieldDiff 字段差异, 值按变更日志的格式转换为字符串
+type FieldDiff struct {
+	Field     string `json:"field"`
+	Name      string `json:"name"`
+	Submitted string `json:"submitted"`
+	Current   string `json:"current"`
+}
+
+// uintValue 将记录或请求中的值 (如版本号、id) 转换为 uint, 没有值或不是非负整数时返回 false
+func uintValue(value any) (uint, bool) {
+	switch v := value.(type) {
+	case uint:
+		return v, true
+	case uint64:
+		return uint(v), true
+	case uint32:
+		return uint(v), true
+	case int:
+		return uint(v), v >= 0
+	case int64:
+		return uint(v), v >= 0
+	case int32:
+		return uint(v), v >= 0
+	case float64:
+		return uint(v), v >= 0
+	case json.Number:
+		n, err := strconv.ParseUint(v.String(), 10, 64)
+		return uint(n), err == nil
+	case string:
+		n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+		return uint(n), err == nil
+	case []byte:
+		n, err := strconv.ParseUint(string(v), 10, 64)
+		return uint(n), err == nil
+	}
+	return 0, false
+}
+
+// versionConflict 比较修改依据的版本与当前记录的版本, 一致时返回 nil
+// changes 为提交的修改内容, 与当前值不同的字段记录在字段差异中
+func versionConflict(fields []*model.TableField, id, version uint, current, changes map[string]any) *EntityConflict {
+	currentVersion, _ := uintValue(current["version"])
+	if currentVersion == version {
+		return nil
+	}
+
+	conflict := &EntityConflict{
+		ID:             id,
+		Version:        version,
+		CurrentVersion: currentVersion,
+		Current:        current,
+		Diff:           []FieldDiff{},
+	}
+	names := map[string]string{"status": "状态"}
+	codes := make([]string, 0, len(fields)+1)
+	for _, field := range fields {
+		names[field.Code] = field.Name
+		codes = append(codes, field.Code)
+	}
+	codes = append(codes, "status")
+	for _, code := range codes {
+		value, ok := changes[code]
+		if !ok {
+			continue
+		}
+		submitted, now := logFieldValue(value), logFieldValue(current[code])
+		if submitted != now {
+			conflict.Diff = append(conflict.Diff, FieldDiff{Field: code, Name: names[code], Submitted: submitted, Current: now})
+		}
+	}
+	return conflict
+}
+
+// checkVersions 比较修改依据的版本与数据表当前版本, 有记录不一致时返回 ConflictError
+// versions 为 id 对应的依据版本, changes 为提交的修改内容
+func checkVersions(entityRepository repository.EntityRepository, fields []*model.TableField, tableCode string, versions map[uint]uint, changes map[string]any) error {
+	if len(versions) == 0 {
+		return nil
+	}
+	ids := slices.Sorted(maps.Keys(versions))
+	// 读取失败时返回错误, 不能当作记录不存在或版本冲突
+	rows, err := entityRepository.Find(tableCode, "*", map[string]any{"id in": ids})
+	if err != nil {
+		return fmt.Errorf("读取当前版本失败: %v", err)
+	}
+	current := make(map[uint]map[string]any, len(rows))
+	for _, row := range rows {
+		if id, err := importRowID(row); err == nil {
+			current[id] = row
+		}
+	}
+
+	var conflicts []*EntityConflict
+	for _, id := range ids {
+		row, ok := current[id]
+		if !ok {
+			return fmt.Errorf("记录 %d 不存在", id)
+		}
+		if conflict := versionConflict(fields, id, versions[id], row, changes); conflict != nil {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	if len(conflicts) > 0 {
+		return &ConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// versionError 按版本修改失败时, 重新读取当前数据生成 ConflictError; 其它错误原样返回
+func versionError(err error, entityRepository repository.EntityRepository, fields []*model.TableField, tableCode string, versions map[uint]uint, changes map[string]any) error {
+	if !errors.Is(err, repository.ErrVersionConflict) {
+		return err
+	}
+	if conflictErr := checkVersions(entityRepository, fields, tableCode, versions, changes); conflictErr != nil {
+		return conflictErr
+	}
+	return err
+}
+
+// isUpdateOperation 判断是否为修改字段的操作: 不改变记录状态, 发布修改草稿时按版本检查
+func isUpdateOperation(operation string) bool {
+	switch operation {
+	case "U", "MU", "Update", "BatchUpdate":
+		return true
+	}
+	return false
+}
+
+// checkDraftVersions 审批通过前检查修改草稿依据的版本, 数据在提交审批后已被其他人修改时返回 ConflictError
+func (s *approvalService) checkDraftVersions(approval *model.Approval) error {
+	if approval.EntityCode == "" {
+		return nil
+	}
+	// 层级版本没有草稿表, 合并按主记录版本检查
+	if _, ok := model.HierarchyCodeOf(approval.EntityCode); ok {
+		return nil
+	}
+	if _, ok := model.MergeTableOf(approval.EntityCode); ok {
+		return s.checkMergeVersions(approval)
+	}
+	drafts, err := s.entityRepository.Find(approval.EntityCode+"_draft", "*", map[string]any{"approval_code": approval.Code})
+	if err != nil {
+		return err
+	}
+
+	var tableFields []*model.TableField
+	var conflicts []*EntityConflict
+	for _, draft := range drafts {
+		operation, _ := draft["operation"].(string)
+		if !isUpdateOperation(operation) {
+			continue
+		}
+		if tableFields == nil {
+			if tableFields, err = s.tableFieldService.Find("", map[string]any{"table_code": approval.EntityCode}); err != nil {
+				return err
+			}
+		}
+		id, _ := uintValue(draft["entity_id"])
+		version, _ := uintValue(draft["version"])
+		origin, err := s.entityRepository.FindOne(approval.EntityCode, id)
+		if err != nil {
+			return err
+		}
+		if conflict := versionConflict(tableFields, id, version, origin, draft); conflict != nil {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	if len(conflicts) > 0 {
+		return &ConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+	mock_repository "piemdm/test/mocks/repository"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	entityRepo := mock_repository.NewMockEntityRepository(ctrl)
+	fields := []*model.TableField{
+		{Code: "name", Name: "名称"},
+		{Code: "price", Name: "价格"},
+	}
+	rows := []map[string]any{
+		{"id": uint64(1), "name": "A", "price": "10", "status": "Normal", "version": uint64(3)},
+		{"id": uint64(2), "name": "B", "price": "20", "status": "Normal", "version": uint64(7)},
+	}
+	entityRepo.EXPECT().Find("product", "*", map[string]any{"id in": []uint{1, 2}}).Return(rows, nil).Times(2)
+
+	changes := map[string]any{"name": "B", "price": "20", "reason": "修改"}
+	assert.NoError(t, checkVersions(entityRepo, fields, "product", map[uint]uint{1: 3, 2: 7}, map[string]any{"name": "X"}))
+
+	err := checkVersions(entityRepo, fields, "product", map[uint]uint{1: 2, 2: 7}, changes)
+	var conflictErr *ConflictError
+	require.True(t, errors.As(err, &conflictErr))
+	require.Len(t, conflictErr.Conflicts, 1)
+	conflict := conflictErr.Conflicts[0]
+	assert.Equal(t, uint(1), conflict.ID)
+	assert.Equal(t, uint(2), conflict.Version)
+	assert.Equal(t, uint(3), conflict.CurrentVersion)
+	assert.Equal(t, "A", conflict.Current["name"])
+	// 只列出提交值与当前值不同的字段
+	assert.Equal(t, []FieldDiff{
+		{Field: "name", Name: "名称", Submitted: "B", Current: "A"},
+		{Field: "price", Name: "价格", Submitted: "20", Current: "10"},
+	}, conflict.Diff)
+	assert.Equal(t, "数据已被其他人修改, 请刷新后重试: id 1", err.Error())
+
+	// 读取失败时返回错误, 不是冲突
+	entityRepo.EXPECT().Find("product", "*", map[string]any{"id in": []uint{1}}).Return(nil, errors.New("connection reset"))
+	err = checkVersions(entityRepo, fields, "product", map[uint]uint{1: 2}, changes)
+	assert.EqualError(t, err, "读取当前版本失败: connection reset")
+	assert.False(t, errors.As(err, &conflictErr))
+}
+
+func TestVersionError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	entityRepo := mock_repository.NewMockEntityRepository(ctrl)
+	entityRepo.EXPECT().Find("product", "*", gomock.Any()).
+		Return([]map[string]any{{"id": int64(1), "status": "Frozen", "version": int64(4)}}, nil)
+
+	other := errors.New("db error")
+	assert.Equal(t, other, versionError(other, entityRepo, nil, "product", map[uint]uint{1: 3}, nil))
+
+	err := versionError(repository.ErrVersionConflict, entityRepo, nil, "product", map[uint]uint{1: 3}, map[string]any{"status": "Normal"})
+	var conflictErr *ConflictError
+	require.True(t, errors.As(err, &conflictErr))
+	assert.Equal(t, []FieldDiff{{Field: "status", Name: "状态", Submitted: "Normal", Current: "Frozen"}}, conflictErr.Conflicts[0].Diff)
+
+	// 重新读取失败时返回读取错误, 不作为 409
+	entityRepo.EXPECT().Find("product", "*", gomock.Any()).Return(nil, errors.New("connection reset"))
+	err = versionError(repository.ErrVersionConflict, entityRepo, nil, "product", map[uint]uint{1: 3}, nil)
+	assert.EqualError(t, err, "读取当前版本失败: connection reset")
+	assert.False(t, errors.Is(err, repository.ErrVersionConflict))
+}
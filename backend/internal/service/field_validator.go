@@ -1,18 +1,24 @@
 package service
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 
+	"piemdm/internal/constants"
 	"piemdm/internal/model"
+	"piemdm/internal/repository"
 )
 
 // ValidateFieldValue 验证字段值
 func ValidateFieldValue(field *model.TableField, value any) error {
 	strValue := fmt.Sprintf("%v", value)
-	// 必填验证
-	if field.Required == "Yes" && (value == nil || strValue == "") {
+	// 必填验证 (兼容 Required 列与 Options.validation.required)
+	required := field.Required == "Yes" ||
+		(field.Options != nil && field.Options.Validation != nil && field.Options.Validation.Required)
+	if required && (value == nil || strValue == "") {
 		return fmt.Errorf("字段 '%s' 是必填项", field.Name)
 	}
 
@@ -155,3 +161,141 @@ func validateByValidator(validator string, rawValue any, value, fieldName string
 
 	return nil
 }
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`   // 字段编码
+	Name    string `json:"name"`    // 字段名称
+	Message string `json:"message"` // 错误信息
+}
+
+// ValidationError 汇总一次写入中所有字段的校验错误
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		messages = append(messages, fe.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ValidateEntityValues 按字段定义校验整条记录, 收集全部字段错误而不是遇到第一个就返回
+// partial 为 true 时(修改场景)只校验 entityMap 中出现的字段
+func ValidateEntityValues(fields []*model.TableField, entityMap map[string]any, partial bool) []FieldError {
+	var fieldErrors []FieldError
+	for _, field := range fields {
+		// 系统字段、自动编码和公式字段由系统赋值, 不做用户输入校验
+		if constants.IsSystemFieldCode(field.Code) || field.FieldType == "autocode" || isFormulaField(field) {
+			continue
+		}
+
+		value, exists := entityMap[field.Code]
+		if partial && !exists {
+			continue
+		}
+
+		value, err := normalizeFieldValue(field, value)
+		if err == nil {
+			err = ValidateFieldValue(field, value)
+		}
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   field.Code,
+				Name:    field.Name,
+				Message: err.Error(),
+			})
+		}
+	}
+	return fieldErrors
+}
+
+// normalizeFieldValue 将数值字段的字符串值(Excel、json.Number)转换为数字, 以便进行数值范围校验
+// 多对多字段的值统一为 JSON 数组字符串, 空数组视为未填写
+// JSON 字段的值统一为紧凑 JSON 文本, 并按配置的 JSON Schema 校验
+func normalizeFieldValue(field *model.TableField, value any) (any, error) {
+	if isJSONField(field) {
+		return normalizeJSONValue(field, value)
+	}
+	if field.FieldType == "manytomany" {
+		values := repository.ParseLinkValues(value)
+		if len(values) == 0 {
+			return nil, nil
+		}
+		data, _ := json.Marshal(values)
+		return string(data), nil
+	}
+	if !isNumberField(field) {
+		return value, nil
+	}
+
+	var strValue string
+	switch v := value.(type) {
+	case string:
+		strValue = strings.TrimSpace(v)
+	case json.Number:
+		strValue = v.String()
+	default:
+		return value, nil
+	}
+	if strValue == "" {
+		return nil, nil
+	}
+
+	if i, err := strconv.ParseInt(strValue, 10, 64); err == nil {
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(strValue, 64)
+	if err != nil {
+		return nil, fmt.Errorf("字段 '%s' 必须是数字", field.Name)
+	}
+	return f, nil
+}
+
+// isNumberField 判断字段是否为数值类型
+func isNumberField(field *model.TableField) bool {
+	dbType := strings.ToLower(field.Type)
+	return strings.Contains(dbType, "number") ||
+		strings.Contains(dbType, "int") ||
+		strings.Contains(dbType, "decimal") ||
+		strings.Contains(dbType, "float") ||
+		strings.Contains(dbType, "double")
+}
+
+// findValidationFields 获取参与校验的字段定义
+func findValidationFields(tableFieldService TableFieldService, tableCode string) ([]*model.TableField, error) {
+	fields, err := tableFieldService.Find("", map[string]any{
+		"table_code": tableCode,
+		"status":     "Normal",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取表字段失败: %v", err)
+	}
+	return fields, nil
+}
+
+// validateEntity 校验单条记录, 直接写入与审批草稿共用
+// 先计算公式字段写入 entityMap, 字段值校验通过后将 JSON 字段转换为 JSON 文本, 再校验关系字段引用的记录是否存在
+func validateEntity(tableFieldService TableFieldService, entityRepository repository.EntityRepository, tableCode string, entityMap map[string]any, partial bool) error {
+	fields, err := findValidationFields(tableFieldService, tableCode)
+	if err != nil {
+		return err
+	}
+	if err := applyFormulaFields(entityRepository, tableCode, fields, entityMap, partial); err != nil {
+		return err
+	}
+	if fieldErrors := ValidateEntityValues(fields, entityMap, partial); len(fieldErrors) > 0 {
+		return &ValidationError{Errors: fieldErrors}
+	}
+	encodeJSONFields(fields, entityMap)
+	fieldErrors, err := newReferenceChecker(entityRepository, fields).Check(entityMap)
+	if err != nil {
+		return err
+	}
+	if len(fieldErrors) > 0 {
+		return &ValidationError{Errors: fieldErrors}
+	}
+	return nil
+}
@@ -1,8 +1,15 @@
 package service
 
 import (
+	"errors"
 	"piemdm/internal/model"
 	"testing"
+
+	mock_repository "piemdm/test/mocks/repository"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestValidateFieldValue_Required(t *testing.T) {
@@ -161,3 +168,107 @@ func TestValidateFieldValue_Validator(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateEntityValues(t *testing.T) {
+	maxAge := 150
+	fields := []*model.TableField{
+		{Code: "name", Name: "名称", Type: "Text", Required: "Yes"},
+		{Code: "email", Name: "邮箱", Type: "Text", Options: &model.FieldOptions{
+			Validation: &model.FieldValidation{Format: "email"},
+		}},
+		{Code: "age", Name: "年龄", Type: "Number", Options: &model.FieldOptions{
+			Validation: &model.FieldValidation{Validator: "integer", Max: &maxAge},
+		}},
+		{Code: "code", Name: "编码", FieldType: "autocode", Required: "Yes"},
+		{Code: "status", Name: "状态", Required: "Yes"},
+	}
+
+	tests := []struct {
+		name       string
+		entityMap  map[string]any
+		partial    bool
+		wantFields []string
+	}{
+		{"valid entity", map[string]any{"name": "a", "email": "a@b.com", "age": "18"}, false, nil},
+		{"collect all errors", map[string]any{"email": "bad", "age": "200"}, false, []string{"name", "email", "age"}},
+		{"number string is not a number", map[string]any{"name": "a", "age": "abc"}, false, []string{"age"}},
+		{"partial skips missing fields", map[string]any{"email": "bad"}, true, []string{"email"}},
+		{"partial checks present required field", map[string]any{"name": ""}, true, []string{"name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fieldErrors := ValidateEntityValues(fields, tt.entityMap, tt.partial)
+			if len(fieldErrors) != len(tt.wantFields) {
+				t.Fatalf("ValidateEntityValues() got %d errors %v, want fields %v", len(fieldErrors), fieldErrors, tt.wantFields)
+			}
+			for i, fe := range fieldErrors {
+				if fe.Field != tt.wantFields[i] {
+					t.Errorf("ValidateEntityValues() error[%d].Field = %s, want %s", i, fe.Field, tt.wantFields[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReferenceValues(t *testing.T) {
+	assert.Equal(t, []string{"C1"}, referenceValues(" C1 ", false))
+	assert.Equal(t, []string{"C1,C2"}, referenceValues("C1,C2", false))
+	assert.Equal(t, []string{"C1", "C2"}, referenceValues("C1, C2,C1", true))
+	assert.Equal(t, []string{"C1", "2"}, referenceValues(`["C1", 2, null]`, true))
+	assert.Equal(t, []string{"C1", "C2"}, referenceValues([]any{"C1", nil, "C2"}, true))
+	assert.Empty(t, referenceValues(nil, true))
+	assert.Empty(t, referenceValues("", false))
+}
+
+func TestReferenceChecker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := mock_repository.NewMockEntityRepository(ctrl)
+
+	fields := []*model.TableField{
+		{Code: "customer", Name: "客户", FieldType: "belongsto", Options: &model.FieldOptions{
+			Relation: &model.FieldRelation{Target: "customer"},
+		}},
+		{Code: "colors", Name: "颜色", FieldType: "manytomany", Options: &model.FieldOptions{
+			Relation: &model.FieldRelation{Target: "dict_item", Filter: map[string]any{"dict_code": "color"}},
+		}},
+		// 选择字段不校验引用
+		{Code: "parent_id", Name: "父节点", FieldType: "select", Options: &model.FieldOptions{
+			Relation: &model.FieldRelation{Target: "customer", ValueField: "id"},
+		}},
+	}
+	rc := newReferenceChecker(repo, fields)
+
+	repo.EXPECT().Find("customer", "code", map[string]any{
+		"code": []string{"C1"}, "status <>": "Deleted",
+	}).Return([]map[string]any{{"code": "C1"}}, nil)
+	repo.EXPECT().Find("dict_item", "code", map[string]any{
+		"dict_code": "color", "code": []string{"red", "pink"}, "status <>": "Deleted",
+	}).Return([]map[string]any{{"code": []byte("red")}}, nil)
+
+	fieldErrors, err := rc.Check(map[string]any{"customer": "C1", "colors": `["red","pink"]`, "parent_id": 99})
+	require.NoError(t, err)
+	require.Len(t, fieldErrors, 1)
+	assert.Equal(t, "colors", fieldErrors[0].Field)
+	assert.Equal(t, "字段 '颜色' 引用的记录不存在: pink", fieldErrors[0].Message)
+
+	// 已查询过的值使用缓存, 不再查询
+	fieldErrors, err = rc.Check(map[string]any{"customer": "C1", "colors": "pink"})
+	require.NoError(t, err)
+	require.Len(t, fieldErrors, 1)
+
+	// 多对一只能引用一条记录
+	fieldErrors, err = rc.Check(map[string]any{"customer": `["C1","C2"]`})
+	require.NoError(t, err)
+	require.Len(t, fieldErrors, 1)
+	assert.Equal(t, "字段 '客户' 只能引用一条记录", fieldErrors[0].Message)
+
+	// 查询失败时返回错误, 不作为引用不存在的校验错误
+	repo.EXPECT().Find("customer", "code", map[string]any{
+		"code": []string{"C3"}, "status <>": "Deleted",
+	}).Return(nil, errors.New("connection reset"))
+	fieldErrors, err = rc.Check(map[string]any{"customer": "C3"})
+	assert.EqualError(t, err, "查询字段 '客户' 引用的记录失败: connection reset")
+	assert.Empty(t, fieldErrors)
+}
@@ -0,0 +1,541 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"piemdm/internal/constants"
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+	"piemdm/pkg/formula"
+
+	"github.com/gin-gonic/gin"
+)
+
+// formulaFieldType 公式字段, 值由表达式根据同一记录的其它字段计算, 不接受用户输入
+const formulaFieldType = "formula"
+
+// recomputeChunkSize 批量重算每批读取的行数
+const recomputeChunkSize = 500
+
+// recomputeReason 批量重算写入变更日志的原因
+const recomputeReason = "公式重算"
+
+// compiledFormula 已解析的公式字段
+type compiledFormula struct {
+	field *model.TableField
+	expr  *formula.Expr
+}
+
+// formulaSet 一张表的公式字段, 按依赖顺序排列: 被其它公式引用的公式先计算
+type formulaSet struct {
+	formulas []*compiledFormula
+	types    map[string]string // 字段编码 -> 数据类型, 用于转换引用字段的值
+}
+
+// isFormulaField 判断是否为公式字段
+func isFormulaField(field *model.TableField) bool {
+	return field.FieldType == formulaFieldType
+}
+
+// formulaResultType 公式结果类型, 未配置时为 Text
+func formulaResultType(field *model.TableField) string {
+	if field.Options != nil && field.Options.Formula != nil && field.Options.Formula.ResultType != "" {
+		return field.Options.Formula.ResultType
+	}
+	return model.FormulaResultText
+}
+
+// formulaDecimalPrecision 配置了小数位的数值公式默认的 decimal 总位数
+const formulaDecimalPrecision = 18
+
+// applyFormulaColumn 按结果类型设置公式字段的列定义
+// 数值结果配置了小数位而未配置总位数时按 decimal(18, 小数位) 存储
+func applyFormulaColumn(field *model.TableField) {
+	field.Type = formulaResultType(field)
+	if field.Type != model.FormulaResultText {
+		field.Length = 0
+	}
+	if field.Type == model.FormulaResultNumber && field.Options != nil && field.Options.Validation != nil &&
+		field.Options.Validation.Scale != nil && field.Options.Validation.Precision == nil {
+		precision := formulaDecimalPrecision
+		field.Options.Validation.Precision = &precision
+	}
+}
+
+// parseFormula 解析公式字段的表达式
+func parseFormula(field *model.TableField) (*formula.Expr, error) {
+	if field.Options == nil || field.Options.Formula == nil {
+		return nil, fmt.Errorf("公式字段 '%s' 未配置表达式", field.Name)
+	}
+	expr, err := formula.Parse(field.Options.Formula.Expression)
+	if err != nil {
+		return nil, fmt.Errorf("公式字段 '%s' %v", field.Name, err)
+	}
+	return expr, nil
+}
+
+// newFormulaSet 解析 fields 中未删除的公式字段并按依赖排序, 没有公式字段时返回 nil
+func newFormulaSet(fields []*model.TableField) (*formulaSet, error) {
+	fs := &formulaSet{types: make(map[string]string, len(fields))}
+	byCode := make(map[string]*compiledFormula)
+	var codes []string
+	for _, field := range fields {
+		if field.Status != "" && field.Status != "Normal" {
+			continue
+		}
+		fs.types[field.Code] = fieldDataType(field)
+		if !isFormulaField(field) {
+			continue
+		}
+		expr, err := parseFormula(field)
+		if err != nil {
+			return nil, err
+		}
+		byCode[field.Code] = &compiledFormula{field: field, expr: expr}
+		codes = append(codes, field.Code)
+	}
+	if len(codes) == 0 {
+		return nil, nil
+	}
+
+	// 深度优先排序, 同时检查公式之间的循环引用
+	const visiting, visited = 1, 2
+	state := make(map[string]int, len(codes))
+	var path []string
+	var visit func(code string) error
+	visit = func(code string) error {
+		switch state[code] {
+		case visited:
+			return nil
+		case visiting:
+			start := slices.Index(path, code)
+			return fmt.Errorf("公式字段循环引用: %s", strings.Join(append(path[start:], code), " -> "))
+		}
+		state[code] = visiting
+		path = append(path, code)
+		for _, ref := range byCode[code].expr.Refs() {
+			if _, ok := byCode[ref]; ok {
+				if err := visit(ref); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[code] = visited
+		fs.formulas = append(fs.formulas, byCode[code])
+		return nil
+	}
+	for _, code := range codes {
+		if err := visit(code); err != nil {
+			return nil, err
+		}
+	}
+	return fs, nil
+}
+
+// validateFormulaField 保存字段定义前校验公式: 表达式语法、结果类型、引用的字段存在, 以及与其它公式字段无循环引用
+// fields 为同一表中的其它字段
+func validateFormulaField(field *model.TableField, fields []*model.TableField) error {
+	if !model.IsValidFormulaResultType(formulaResultType(field)) {
+		return fmt.Errorf("不支持的公式结果类型: %s", formulaResultType(field))
+	}
+	expr, err := parseFormula(field)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if f.Code != field.Code && (f.Status == "" || f.Status == "Normal") {
+			known[f.Code] = true
+		}
+	}
+	for _, ref := range expr.Refs() {
+		if ref == field.Code {
+			return fmt.Errorf("公式字段 '%s' 不能引用自身", field.Name)
+		}
+		if !known[ref] && !constants.IsSystemFieldCode(ref) {
+			return fmt.Errorf("公式字段 '%s' 引用的字段不存在: %s", field.Name, ref)
+		}
+	}
+
+	all := make([]*model.TableField, 0, len(fields)+1)
+	for _, f := range fields {
+		if f.Code != field.Code {
+			all = append(all, f)
+		}
+	}
+	_, err = newFormulaSet(append(all, field))
+	return err
+}
+
+// refs 全部公式引用的字段编码
+func (fs *formulaSet) refs() []string {
+	var refs []string
+	for _, f := range fs.formulas {
+		for _, ref := range f.expr.Refs() {
+			if !slices.Contains(refs, ref) {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// dependsOn 是否有公式引用了 entityMap 中的字段
+func (fs *formulaSet) dependsOn(entityMap map[string]any) bool {
+	for _, ref := range fs.refs() {
+		if _, ok := entityMap[ref]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// apply 计算全部公式字段并写入 entityMap, 覆盖用户提交的值
+// origin 为修改前的记录 (新增时为 nil), entityMap 中未提交的字段取原值
+func (fs *formulaSet) apply(entityMap, origin map[string]any) []FieldError {
+	var fieldErrors []FieldError
+	for _, f := range fs.formulas {
+		vars := make(map[string]any)
+		for _, ref := range f.expr.Refs() {
+			value, ok := entityMap[ref]
+			if !ok {
+				value = origin[ref]
+			}
+			vars[ref] = formulaValue(fs.types[ref], value)
+		}
+
+		result, err := f.expr.Eval(vars)
+		if err == nil {
+			result, err = formulaStoreValue(f.field, result)
+		}
+		if err != nil {
+			entityMap[f.field.Code] = nil
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   f.field.Code,
+				Name:    f.field.Name,
+				Message: fmt.Sprintf("公式字段 '%s' 计算失败: %v", f.field.Name, err),
+			})
+			continue
+		}
+		entityMap[f.field.Code] = result
+	}
+	return fieldErrors
+}
+
+// formulaValue 按字段数据类型转换参与计算的值: 数值字段为数字, 日期字段为时间, JSON 字段为 JSON 文本
+func formulaValue(dataType string, value any) any {
+	if dataType == "" || dataType == jsonDataType {
+		dataType = "Text"
+	}
+	value = exportFieldValue(dataType, value)
+	if s, ok := value.(string); ok && (dataType == "Date" || dataType == "DateTime") {
+		if t, err := formula.ToTime(s); err == nil {
+			return t
+		}
+	}
+	return value
+}
+
+// formulaStoreValue 将计算结果转换为字段数据类型的存储值
+// 数值按配置的小数位四舍五入, 未配置小数位时取整; 日期与导入格式一致; 文本超出长度时截断
+func formulaStoreValue(field *model.TableField, result any) (any, error) {
+	if result == nil {
+		return nil, nil
+	}
+	switch fieldDataType(field) {
+	case "Number":
+		n, err := formula.ToNumber(result)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, fmt.Errorf("结果不是有效数字")
+		}
+		if field.Options != nil && field.Options.Validation != nil && field.Options.Validation.Scale != nil {
+			return formula.Round(n, *field.Options.Validation.Scale), nil
+		}
+		return int64(math.Round(n)), nil
+	case "Date", "DateTime":
+		t, err := formula.ToTime(result)
+		if err != nil {
+			return nil, err
+		}
+		if fieldDataType(field) == "Date" {
+			return t.Format(fileDateLayout), nil
+		}
+		return t.Format(fileDateTimeLayout), nil
+	}
+
+	text := formula.ToText(result)
+	if runes := []rune(text); field.Length > 0 && len(runes) > field.Length {
+		text = string(runes[:field.Length])
+	}
+	return text, nil
+}
+
+// applyFormulaFields 计算公式字段写入 entityMap, 直接写入与审批草稿共用
+// 修改时按 id 读取原记录补全未提交的字段; 批量修改没有单条 id, 由写入后的 recomputeRows 计算
+func applyFormulaFields(entityRepository repository.EntityRepository, tableCode string, fields []*model.TableField, entityMap map[string]any, partial bool) error {
+	fs, err := newFormulaSet(fields)
+	if err != nil || fs == nil {
+		return err
+	}
+
+	var origin map[string]any
+	if partial {
+		id, err := importRowID(entityMap)
+		if err != nil {
+			return nil
+		}
+		if origin, err = entityRepository.FindOne(tableCode, id); err != nil {
+			return fmt.Errorf("获取原始数据失败: %v", err)
+		}
+	}
+	if fieldErrors := fs.apply(entityMap, origin); len(fieldErrors) > 0 {
+		return &ValidationError{Errors: fieldErrors}
+	}
+	return nil
+}
+
+// applyPublishFormulas 审批通过发布草稿前计算公式字段, origin 为当前记录 (新增时为 nil)
+func applyPublishFormulas(fields []*model.TableField, draft, origin map[string]any) error {
+	fs, err := newFormulaSet(fields)
+	if err != nil || fs == nil {
+		return err
+	}
+	if fieldErrors := fs.apply(draft, origin); len(fieldErrors) > 0 {
+		return &ValidationError{Errors: fieldErrors}
+	}
+	return nil
+}
+
+// changes 比较重新计算的公式值与记录中的原值, 返回有变化的字段
+func (fs *formulaSet) changes(row, computed map[string]any) map[string]any {
+	changes := make(map[string]any)
+	for _, f := range fs.formulas {
+		dataType := fieldDataType(f.field)
+		before := logFieldValue(exportFieldValue(dataType, row[f.field.Code]))
+		after := logFieldValue(exportFieldValue(dataType, computed[f.field.Code]))
+		if before != after {
+			changes[f.field.Code] = computed[f.field.Code]
+		}
+	}
+	return changes
+}
+
+// recomputeByIds 批量修改后重算公式字段, 只在修改的字段被公式引用时执行
+func (s *entityService) recomputeByIds(c *gin.Context, tableCode string, ids []uint, fields []*model.TableField, entityMap map[string]any) error {
+	fs, err := newFormulaSet(fields)
+	if err != nil || fs == nil || !fs.dependsOn(entityMap) {
+		return err
+	}
+	rows, err := s.entityRepository.Find(tableCode, "*", map[string]any{"id in": ids})
+	if err != nil {
+		return err
+	}
+	_, err = s.recomputeRows(c, tableCode, c.GetString("user_name"), fs, rows)
+	return err
+}
+
+// FormulaChanged 判断字段修改是否改变了公式定义 (表达式、结果类型或小数位), 需要批量重算已有数据
+func FormulaChanged(before, after *model.TableField) bool {
+	if !isFormulaField(after) {
+		return false
+	}
+	if before == nil || !isFormulaField(before) {
+		return true
+	}
+	definition := func(field *model.TableField) string {
+		var expression string
+		if field.Options != nil && field.Options.Formula != nil {
+			expression = strings.TrimSpace(field.Options.Formula.Expression)
+		}
+		scale := -1
+		if field.Options != nil && field.Options.Validation != nil && field.Options.Validation.Scale != nil {
+			scale = *field.Options.Validation.Scale
+		}
+		return fmt.Sprintf("%s|%s|%d", expression, formulaResultType(field), scale)
+	}
+	return definition(before) != definition(after)
+}
+
+// RecomputeFormulas 后台批量重算表中全部记录的公式字段, 公式定义变更或发布后调用
+// 按 id 游标分批读取, 只更新值有变化的记录并记录变更日志; 表中没有公式字段时返回 nil
+func (s *entityService) RecomputeFormulas(c *gin.Context, tableCode string) (*model.EntityJob, error) {
+	fields, err := findValidationFields(s.tableFieldService, tableCode)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := newFormulaSet(fields)
+	if err != nil {
+		return nil, err
+	}
+	if fs == nil {
+		return nil, nil
+	}
+
+	columns, err := s.queryColumns(tableCode)
+	if err != nil {
+		return nil, err
+	}
+	selected := []string{"id"}
+	for _, code := range fs.refs() {
+		if _, ok := columns[code]; ok && !slices.Contains(selected, code) {
+			selected = append(selected, code)
+		}
+	}
+	for _, f := range fs.formulas {
+		if !slices.Contains(selected, f.field.Code) {
+			selected = append(selected, f.field.Code)
+		}
+	}
+	query, err := s.entityRepository.CompileQuery(tableCode, &model.EntityQuery{Fields: selected}, columns, "t")
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.entityRepository.Count(tableCode, query)
+	if err != nil {
+		return nil, err
+	}
+	job := &model.EntityJob{
+		Type:      model.EntityJobTypeRecompute,
+		TableCode: tableCode,
+		Reason:    recomputeReason,
+		Total:     int(total),
+	}
+	if err := s.entityJobService.Create(c, job); err != nil {
+		return nil, err
+	}
+
+	// 后台重算写入的变更历史来源为 System
+	jobContext := c.Copy()
+	jobContext.Set("source", model.EntityLogSourceSystem)
+	queued := snapshotJob(job)
+	go s.runRecomputeJob(jobContext, job, fs, query)
+	return queued, nil
+}
+
+// runRecomputeJob 后台执行批量重算
+func (s *entityService) runRecomputeJob(c *gin.Context, job *model.EntityJob, fs *formulaSet, query *repository.CompiledEntityQuery) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("公式重算任务异常", "job", job.Code, "panic", r)
+			_ = s.entityJobService.Fail(job, fmt.Errorf("%v", r))
+		}
+	}()
+
+	if err := s.entityJobService.Start(job); err != nil {
+		s.logger.Error("更新公式重算任务状态失败", "job", job.Code, "err", err)
+	}
+	changed, err := s.recomputeChunks(c, job, fs, query)
+	if err != nil {
+		s.logger.Error("公式重算任务失败", "job", job.Code, "err", err)
+		if err := s.entityJobService.Fail(job, err); err != nil {
+			s.logger.Error("更新公式重算任务状态失败", "job", job.Code, "err", err)
+		}
+		return
+	}
+
+	job.Succeeded = job.Processed - job.Failed
+	summary := fmt.Sprintf("重算完成: %d 行, 其中 %d 行有变化, %d 行失败", job.Processed, changed, job.Failed)
+	if job.Message != "" {
+		summary += "; " + job.Message
+	}
+	job.Message = truncateMessage(summary, 512)
+	if err := s.entityJobService.Finish(job, ""); err != nil {
+		s.logger.Error("更新公式重算任务状态失败", "job", job.Code, "err", err)
+	}
+}
+
+// recomputeChunks 按游标分批重算, 每批保存一次进度; 计算失败的行计入 Failed, 记录第一条错误
+func (s *entityService) recomputeChunks(c *gin.Context, job *model.EntityJob, fs *formulaSet, query *repository.CompiledEntityQuery) (int, error) {
+	changed := 0
+	var last map[string]any
+	for {
+		rows, err := s.entityRepository.FindChunk(job.TableCode, query, last, recomputeChunkSize)
+		if err != nil {
+			return changed, err
+		}
+		if len(rows) == 0 {
+			return changed, nil
+		}
+
+		n, err := s.recomputeRows(c, job.TableCode, job.CreatedBy, fs, rows)
+		changed += n
+		if err != nil {
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				return changed, err
+			}
+			job.Failed += len(validationErr.Errors)
+			if job.Message == "" {
+				job.Message = validationErr.Error()
+			}
+		}
+
+		job.Processed += len(rows)
+		if err := s.entityJobService.Save(job); err != nil {
+			s.logger.Warn("保存公式重算进度失败", "job", job.Code, "err", err)
+		}
+		if len(rows) < recomputeChunkSize {
+			return changed, nil
+		}
+		last = rows[len(rows)-1]
+	}
+}
+
+// recomputeRows 重算 rows 的公式字段, 更新有变化的记录并记录变更日志, 返回更新的记录数
+// 计算失败的记录不更新, 以 ValidationError 返回, 每条记录一个错误
+func (s *entityService) recomputeRows(c *gin.Context, tableCode, userName string, fs *formulaSet, rows []map[string]any) (int, error) {
+	fields := make([]*model.TableField, 0, len(fs.formulas))
+	for _, f := range fs.formulas {
+		fields = append(fields, f.field)
+	}
+
+	changed := 0
+	var failed []FieldError
+	for _, row := range rows {
+		id, err := importRowID(row)
+		if err != nil {
+			return changed, err
+		}
+		computed := make(map[string]any, len(fs.formulas))
+		if fieldErrors := fs.apply(computed, row); len(fieldErrors) > 0 {
+			fe := fieldErrors[0]
+			fe.Message = fmt.Sprintf("记录 %d: %s", id, fe.Message)
+			failed = append(failed, fe)
+			continue
+		}
+		changes := fs.changes(row, computed)
+		if len(changes) == 0 {
+			continue
+		}
+
+		entityMap := make(map[string]any, len(changes)+1)
+		for k, v := range changes {
+			entityMap[k] = v
+		}
+		entityMap["updated_at"] = time.Now()
+		if err := s.entityRepository.Update(c, tableCode, entityMap, map[string]any{"id": id}); err != nil {
+			return changed, fmt.Errorf("更新记录 %d 失败: %v", id, err)
+		}
+		changed++
+
+		event := entityEvent{Operation: "Recompute", Reason: recomputeReason, UpdateBy: userName}
+		if err := writeEntityLog(c, s.entityLogService, tableCode, fields, event, id, row, changes); err != nil {
+			s.logger.Error("创建变更日志失败", "error", err, "id", id)
+		}
+	}
+	if len(failed) > 0 {
+		return changed, &ValidationError{Errors: failed}
+	}
+	return changed, nil
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"testing"
+
+	"piemdm/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func formulaField(code, expression, resultType string) *model.TableField {
+	return &model.TableField{
+		Code:      code,
+		Name:      code,
+		FieldType: formulaFieldType,
+		Type:      resultType,
+		Status:    "Normal",
+		Options: &model.FieldOptions{Formula: &model.FieldFormula{
+			Expression: expression,
+			ResultType: resultType,
+		}},
+	}
+}
+
+func TestNewFormulaSet(t *testing.T) {
+	scale := 2
+	amount := formulaField("amount", "price * quantity", model.FormulaResultNumber)
+	amount.Options.Validation = &model.FieldValidation{Scale: &scale}
+	fields := []*model.TableField{
+		{Code: "price", Type: "Number", Status: "Normal"},
+		{Code: "quantity", Type: "Number", Status: "Normal"},
+		// total 引用 amount, 需要排在 amount 之后计算
+		formulaField("total", "amount * 1.1", model.FormulaResultNumber),
+		amount,
+		formulaField("deleted", "price", model.FormulaResultNumber),
+	}
+	fields[4].Status = "Deleted"
+
+	fs, err := newFormulaSet(fields)
+	require.NoError(t, err)
+	require.Len(t, fs.formulas, 2)
+	assert.Equal(t, "amount", fs.formulas[0].field.Code)
+	assert.Equal(t, "total", fs.formulas[1].field.Code)
+
+	// 未提交的字段取原值
+	entityMap := map[string]any{"price": "2.5"}
+	origin := map[string]any{"price": int64(1), "quantity": []byte("3")}
+	assert.Empty(t, fs.apply(entityMap, origin))
+	assert.Equal(t, 7.5, entityMap["amount"])
+	assert.Equal(t, int64(8), entityMap["total"])
+
+	fs, err = newFormulaSet([]*model.TableField{{Code: "name", Type: "Text"}})
+	require.NoError(t, err)
+	assert.Nil(t, fs)
+
+	_, err = newFormulaSet([]*model.TableField{
+		formulaField("a", "b + 1", model.FormulaResultNumber),
+		formulaField("b", "c + 1", model.FormulaResultNumber),
+		formulaField("c", "a + 1", model.FormulaResultNumber),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "a -> b -> c -> a")
+}
+
+func TestFormulaSetApply_Errors(t *testing.T) {
+	fs, err := newFormulaSet([]*model.TableField{
+		{Code: "a", Type: "Number"},
+		{Code: "b", Type: "Number"},
+		formulaField("ratio", "a / b", model.FormulaResultNumber),
+	})
+	require.NoError(t, err)
+
+	entityMap := map[string]any{"a": 1, "b": 0, "ratio": 99}
+	fieldErrors := fs.apply(entityMap, nil)
+	require.Len(t, fieldErrors, 1)
+	assert.Equal(t, "ratio", fieldErrors[0].Field)
+	assert.Nil(t, entityMap["ratio"])
+}
+
+func TestFormulaStoreValue(t *testing.T) {
+	due := formulaField("due", "order_date + 30", model.FormulaResultDate)
+	value, err := formulaStoreValue(due, formulaValue("Date", "2024-01-15"))
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-15", value)
+
+	label := formulaField("label", "", model.FormulaResultText)
+	label.Length = 4
+	value, err = formulaStoreValue(label, "数据管理平台")
+	require.NoError(t, err)
+	assert.Equal(t, "数据管理", value)
+
+	count := formulaField("count", "", model.FormulaResultNumber)
+	value, err = formulaStoreValue(count, 2.6)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), value)
+
+	_, err = formulaStoreValue(count, "abc")
+	assert.Error(t, err)
+
+	value, err = formulaStoreValue(count, nil)
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
+
+func TestValidateFormulaField(t *testing.T) {
+	fields := []*model.TableField{
+		{Code: "price", Type: "Number", Status: "Normal"},
+		{Code: "old", Type: "Number", Status: "Deleted"},
+		formulaField("amount", "price * 2", model.FormulaResultNumber),
+	}
+
+	assert.NoError(t, validateFormulaField(formulaField("total", "amount + price", model.FormulaResultNumber), fields))
+	assert.NoError(t, validateFormulaField(formulaField("age", `DATEDIFF(created_at, TODAY(), "day")`, model.FormulaResultNumber), fields))
+
+	for name, field := range map[string]*model.TableField{
+		"语法错误":    formulaField("total", "price *", model.FormulaResultNumber),
+		"引用自身":    formulaField("total", "total + 1", model.FormulaResultNumber),
+		"字段不存在":   formulaField("total", "missing + 1", model.FormulaResultNumber),
+		"引用已删除字段": formulaField("total", "old + 1", model.FormulaResultNumber),
+		"结果类型错误":  formulaField("total", "price", "Boolean"),
+		// 修改 amount 使其引用 total, 与 total 形成循环
+		"循环引用": formulaField("amount", "total", model.FormulaResultNumber),
+	} {
+		t.Run(name, func(t *testing.T) {
+			all := fields
+			if name == "循环引用" {
+				all = append(append([]*model.TableField{}, fields...), formulaField("total", "amount + 1", model.FormulaResultNumber))
+			}
+			assert.Error(t, validateFormulaField(field, all))
+		})
+	}
+}
+
+func TestFormulaChanged(t *testing.T) {
+	before := formulaField("amount", "price * quantity", model.FormulaResultNumber)
+	same := formulaField("amount", " price * quantity ", model.FormulaResultNumber)
+	assert.False(t, FormulaChanged(before, same))
+	assert.True(t, FormulaChanged(before, formulaField("amount", "price", model.FormulaResultNumber)))
+	assert.True(t, FormulaChanged(before, formulaField("amount", "price * quantity", model.FormulaResultText)))
+	assert.True(t, FormulaChanged(&model.TableField{Code: "amount", Type: "Number"}, same))
+	assert.False(t, FormulaChanged(before, &model.TableField{Code: "amount", Type: "Number"}))
+}
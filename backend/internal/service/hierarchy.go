@@ -0,0 +1,672 @@
+package service
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// HierarchyQuery 查询层级时使用的版本和范围
+// 指定 Version 时使用该版本 (含编辑中的版本), 否则使用 Date 时有效的已发布版本
+type HierarchyQuery struct {
+	Version string
+	Date    time.Time // 为零时取当前时间
+	Root    uint      // 起始节点的记录 id, 0 为全部根节点
+	Depth   int       // 返回的层数 (含起始层), 小于等于 0 时不限
+}
+
+// HierarchyNodeView 层级中的节点: 层级、路径和引用的记录
+// 路径为从根节点到该节点的记录 id, 如 /1/5/12/
+type HierarchyNodeView struct {
+	EntityID uint                 `json:"entity_id"`
+	ParentID uint                 `json:"parent_id"`
+	Level    int                  `json:"level"`
+	Path     string               `json:"path"`
+	Sort     int                  `json:"sort"`
+	Record   map[string]any       `json:"record"`
+	Children []*HierarchyNodeView `json:"children,omitempty"`
+}
+
+// HierarchyView 层级版本及其节点
+type HierarchyView struct {
+	Version *model.HierarchyVersion `json:"version"`
+	Nodes   []*HierarchyNodeView    `json:"nodes"`
+}
+
+// HierarchyVersionError 层级版本当前状态不允许操作, 如修改已发布的版本
+type HierarchyVersionError struct {
+	Version   string `json:"version"`
+	Status    string `json:"status"`
+	Operation string `json:"operation"`
+}
+
+func (e *HierarchyVersionError) Error() string {
+	return fmt.Sprintf("层级版本 %s 当前状态为 %s, 不能%s", e.Version, e.Status, e.Operation)
+}
+
+type HierarchyService interface {
+	// 层级定义
+	Get(id uint) (*model.Hierarchy, error)
+	List(page, pageSize int, total *int64, where map[string]any) ([]*model.Hierarchy, error)
+	Create(c *gin.Context, hierarchy *model.Hierarchy) error
+	Update(c *gin.Context, hierarchy *model.Hierarchy) error
+	Delete(c *gin.Context, id uint) error
+
+	// 版本
+	FindByTable(c *gin.Context, tableCode string) ([]*model.Hierarchy, error)
+	Versions(c *gin.Context, code string) ([]*model.HierarchyVersion, error)
+	CreateVersion(c *gin.Context, code string, version *model.HierarchyVersion, copyFrom string) error
+	UpdateVersion(c *gin.Context, code, version string, changes *model.HierarchyVersion) error
+	DeleteVersion(c *gin.Context, code, version string) error
+	Publish(c *gin.Context, code, version, reason string) error
+
+	// 节点
+	SaveNodes(c *gin.Context, code, version string, nodes []*model.HierarchyNode) error
+	MoveNode(c *gin.Context, code, version string, entityID, parentID uint) error
+	Tree(c *gin.Context, code string, query HierarchyQuery) (*HierarchyView, error)
+	Flatten(c *gin.Context, code string, query HierarchyQuery) (*HierarchyView, error)
+}
+
+type hierarchyService struct {
+	*Service
+	hierarchyRepository               repository.HierarchyRepository
+	tableRepository                   repository.TableRepository
+	entityRepository                  repository.EntityRepository
+	tableApprovalDefinitionRepository repository.TableApprovalDefinitionRepository
+	approvalService                   ApprovalService
+	tablePermissionService            TablePermissionService
+}
+
+func NewHierarchyService(
+	service *Service,
+	hierarchyRepository repository.HierarchyRepository,
+	tableRepository repository.TableRepository,
+	entityRepository repository.EntityRepository,
+	tableApprovalDefinitionRepository repository.TableApprovalDefinitionRepository,
+	approvalService ApprovalService,
+	tablePermissionService TablePermissionService,
+) HierarchyService {
+	return &hierarchyService{
+		Service:                           service,
+		hierarchyRepository:               hierarchyRepository,
+		tableRepository:                   tableRepository,
+		entityRepository:                  entityRepository,
+		tableApprovalDefinitionRepository: tableApprovalDefinitionRepository,
+		approvalService:                   approvalService,
+		tablePermissionService:            tablePermissionService,
+	}
+}
+
+func (s *hierarchyService) Get(id uint) (*model.Hierarchy, error) {
+	return s.hierarchyRepository.FindOne(id)
+}
+
+func (s *hierarchyService) List(page, pageSize int, total *int64, where map[string]any) ([]*model.Hierarchy, error) {
+	return s.hierarchyRepository.FindPage(page, pageSize, total, where)
+}
+
+// Create 新增层级, 节点引用的表必须存在
+func (s *hierarchyService) Create(c *gin.Context, hierarchy *model.Hierarchy) error {
+	tables, err := s.tableRepository.Find("", map[string]any{"code": hierarchy.TableCode})
+	if err != nil || len(tables) == 0 {
+		return fmt.Errorf("表 %s 不存在", hierarchy.TableCode)
+	}
+	return s.hierarchyRepository.Create(c, hierarchy)
+}
+
+// Update 修改层级的名称、描述和状态, 编码和引用的表不能修改
+func (s *hierarchyService) Update(c *gin.Context, hierarchy *model.Hierarchy) error {
+	origin, err := s.hierarchyRepository.FindOne(hierarchy.ID)
+	if err != nil {
+		return fmt.Errorf("层级 %d 不存在", hierarchy.ID)
+	}
+	if hierarchy.Code != "" && hierarchy.Code != origin.Code || hierarchy.TableCode != "" && hierarchy.TableCode != origin.TableCode {
+		return fmt.Errorf("层级的编码和引用的表不能修改")
+	}
+	hierarchy.Code, hierarchy.TableCode = "", ""
+	return s.hierarchyRepository.Update(c, hierarchy)
+}
+
+// Delete 删除层级及其全部版本, 有版本在审批中时不能删除
+func (s *hierarchyService) Delete(c *gin.Context, id uint) error {
+	hierarchy, err := s.hierarchyRepository.FindOne(id)
+	if err != nil {
+		return fmt.Errorf("层级 %d 不存在", id)
+	}
+	pending, err := s.hierarchyRepository.FindVersions(map[string]any{"hierarchy_code": hierarchy.Code, "status": model.HierarchyVersionPending})
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return &HierarchyVersionError{Version: pending[0].Version, Status: pending[0].Status, Operation: "删除"}
+	}
+	return s.hierarchyRepository.Delete(c, id)
+}
+
+// hierarchy 读取层级并检查当前用户对引用的表的权限
+func (s *hierarchyService) hierarchy(c *gin.Context, code string) (*model.Hierarchy, error) {
+	hierarchy, err := s.hierarchyRepository.FindByCode(code)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 层级 %s 不存在", model.ErrInvalidQuery, code)
+	}
+	if err := checkTablePermission(c, s.tablePermissionService, hierarchy.TableCode); err != nil {
+		return nil, err
+	}
+	return hierarchy, nil
+}
+
+// version 读取层级的版本, operation 不为空时版本必须是编辑中
+func (s *hierarchyService) version(code, version, operation string) (*model.HierarchyVersion, error) {
+	item, err := s.hierarchyRepository.FindVersion(code, version)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 层级 %s 没有版本 %s", model.ErrInvalidQuery, code, version)
+	}
+	if operation != "" && item.Status != model.HierarchyVersionDraft {
+		return nil, &HierarchyVersionError{Version: item.Version, Status: item.Status, Operation: operation}
+	}
+	return item, nil
+}
+
+// FindByTable 查询表的全部层级
+func (s *hierarchyService) FindByTable(c *gin.Context, tableCode string) ([]*model.Hierarchy, error) {
+	if err := checkTablePermission(c, s.tablePermissionService, tableCode); err != nil {
+		return nil, err
+	}
+	return s.hierarchyRepository.Find(map[string]any{"table_code": tableCode})
+}
+
+// Versions 查询层级的全部版本, 按生效时间排序
+func (s *hierarchyService) Versions(c *gin.Context, code string) ([]*model.HierarchyVersion, error) {
+	if _, err := s.hierarchy(c, code); err != nil {
+		return nil, err
+	}
+	return s.hierarchyRepository.FindVersions(map[string]any{"hierarchy_code": code})
+}
+
+// checkValidity 检查有效期: 失效时间必须晚于生效时间
+func checkValidity(version *model.HierarchyVersion) error {
+	if version.ValidFrom != nil && version.ValidTo != nil && !version.ValidTo.After(*version.ValidFrom) {
+		return fmt.Errorf("层级版本 %s 的失效时间必须晚于生效时间", version.Version)
+	}
+	return nil
+}
+
+// CreateVersion 新增编辑中的版本, copyFrom 不为空时复制该版本的节点
+func (s *hierarchyService) CreateVersion(c *gin.Context, code string, version *model.HierarchyVersion, copyFrom string) error {
+	if _, err := s.hierarchy(c, code); err != nil {
+		return err
+	}
+	if err := checkValidity(version); err != nil {
+		return err
+	}
+	if _, err := s.hierarchyRepository.FindVersion(code, version.Version); err == nil {
+		return fmt.Errorf("层级 %s 的版本 %s 已存在", code, version.Version)
+	}
+	var nodes []*model.HierarchyNode
+	if copyFrom != "" {
+		source, err := s.version(code, copyFrom, "")
+		if err != nil {
+			return err
+		}
+		if nodes, err = s.hierarchyRepository.FindNodes(source.ID); err != nil {
+			return err
+		}
+	}
+	version.ID = 0
+	version.HierarchyCode = code
+	version.Status = model.HierarchyVersionDraft
+	version.ApprovalCode = ""
+	version.CreatedBy = c.GetString("user_name")
+	version.UpdatedBy = c.GetString("user_name")
+	return s.hierarchyRepository.CreateVersion(c, version, nodes)
+}
+
+// UpdateVersion 修改版本的有效期和描述
+// 已发布的版本只能修改有效期 (如为新版本设置失效时间), 修改后不能与其他已发布版本重叠; 审批中的版本不能修改
+func (s *hierarchyService) UpdateVersion(c *gin.Context, code, version string, changes *model.HierarchyVersion) error {
+	if _, err := s.hierarchy(c, code); err != nil {
+		return err
+	}
+	item, err := s.version(code, version, "")
+	if err != nil {
+		return err
+	}
+	if item.Status == model.HierarchyVersionPending {
+		return &HierarchyVersionError{Version: item.Version, Status: item.Status, Operation: "修改"}
+	}
+	item.ValidFrom, item.ValidTo, item.Description = changes.ValidFrom, changes.ValidTo, changes.Description
+	if err := checkValidity(item); err != nil {
+		return err
+	}
+	if item.Status == model.HierarchyVersionActive {
+		if err := checkVersionOverlap(s.hierarchyRepository, item); err != nil {
+			return err
+		}
+	}
+	item.UpdatedBy = c.GetString("user_name")
+	return s.hierarchyRepository.UpdateVersion(c, item)
+}
+
+// DeleteVersion 删除编辑中的版本及其节点
+func (s *hierarchyService) DeleteVersion(c *gin.Context, code, version string) error {
+	if _, err := s.hierarchy(c, code); err != nil {
+		return err
+	}
+	item, err := s.version(code, version, "删除")
+	if err != nil {
+		return err
+	}
+	return s.hierarchyRepository.DeleteVersion(c, item.ID)
+}
+
+// checkHierarchyNodes 检查版本的节点: 记录不重复, 父节点在版本中, 不形成环
+func checkHierarchyNodes(nodes []*model.HierarchyNode) error {
+	parents := make(map[uint]uint, len(nodes))
+	for _, node := range nodes {
+		if node.EntityID == 0 {
+			return fmt.Errorf("层级节点的记录 id 不能为空")
+		}
+		if _, ok := parents[node.EntityID]; ok {
+			return fmt.Errorf("记录 %d 在层级中重复", node.EntityID)
+		}
+		parents[node.EntityID] = node.ParentID
+	}
+	for _, node := range nodes {
+		if node.ParentID == node.EntityID {
+			return &TreeCycleError{ID: node.EntityID, ParentID: node.ParentID}
+		}
+		if _, ok := parents[node.ParentID]; node.ParentID != 0 && !ok {
+			return fmt.Errorf("记录 %d 的父节点 %d 不在层级中", node.EntityID, node.ParentID)
+		}
+	}
+
+	// 沿父节点向上查找, 回到已经过的节点时形成环; reached 中的节点已确认能到达根节点
+	reached := make(map[uint]bool, len(nodes))
+	for _, node := range nodes {
+		var chain []uint
+		for id := node.EntityID; id != 0 && !reached[id]; id = parents[id] {
+			if slices.Contains(chain, id) {
+				return &TreeCycleError{ID: id, ParentID: parents[id]}
+			}
+			chain = append(chain, id)
+		}
+		for _, id := range chain {
+			reached[id] = true
+		}
+	}
+	return nil
+}
+
+// checkNodeRecords 节点引用的记录必须存在且未删除
+func (s *hierarchyService) checkNodeRecords(tableCode string, nodes []*model.HierarchyNode) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(nodes))
+	for i, node := range nodes {
+		ids[i] = node.EntityID
+	}
+	rows, err := s.entityRepository.Find(tableCode, "id", map[string]any{"id": ids})
+	if err != nil {
+		return fmt.Errorf("查询层级节点的记录失败: %v", err)
+	}
+	found := make(map[uint]bool, len(rows))
+	for _, row := range rows {
+		if id, err := importRowID(row); err == nil {
+			found[id] = true
+		}
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return fmt.Errorf("记录 %d 不存在", id)
+		}
+	}
+	return nil
+}
+
+// SaveNodes 用 nodes 替换编辑中版本的全部节点
+func (s *hierarchyService) SaveNodes(c *gin.Context, code, version string, nodes []*model.HierarchyNode) error {
+	hierarchy, err := s.hierarchy(c, code)
+	if err != nil {
+		return err
+	}
+	item, err := s.version(code, version, "修改")
+	if err != nil {
+		return err
+	}
+	if err := checkHierarchyNodes(nodes); err != nil {
+		return err
+	}
+	if err := s.checkNodeRecords(hierarchy.TableCode, nodes); err != nil {
+		return err
+	}
+	return s.hierarchyRepository.ReplaceNodes(c, item.ID, nodes)
+}
+
+// MoveNode 移动编辑中版本的节点及其子树到新的父节点下, parentID 为 0 时移动为根节点; 节点不在版本中时新增
+func (s *hierarchyService) MoveNode(c *gin.Context, code, version string, entityID, parentID uint) error {
+	hierarchy, err := s.hierarchy(c, code)
+	if err != nil {
+		return err
+	}
+	item, err := s.version(code, version, "修改")
+	if err != nil {
+		return err
+	}
+	nodes, err := s.hierarchyRepository.FindNodes(item.ID)
+	if err != nil {
+		return err
+	}
+	index := slices.IndexFunc(nodes, func(node *model.HierarchyNode) bool { return node.EntityID == entityID })
+	if index < 0 {
+		if err := s.checkNodeRecords(hierarchy.TableCode, []*model.HierarchyNode{{EntityID: entityID}}); err != nil {
+			return err
+		}
+		nodes = append(nodes, &model.HierarchyNode{EntityID: entityID})
+		index = len(nodes) - 1
+	}
+	// 排在新的父节点下的最后
+	sort := 0
+	for _, node := range nodes {
+		if node.ParentID == parentID && node.EntityID != entityID {
+			sort = max(sort, node.Sort+1)
+		}
+	}
+	nodes[index].ParentID, nodes[index].Sort = parentID, sort
+	if err := checkHierarchyNodes(nodes); err != nil {
+		return err
+	}
+	return s.hierarchyRepository.ReplaceNodes(c, item.ID, nodes)
+}
+
+// checkVersionOverlap 版本的有效期不能与同一层级其他已发布版本重叠
+func checkVersionOverlap(hierarchyRepository repository.HierarchyRepository, version *model.HierarchyVersion) error {
+	active, err := hierarchyRepository.FindVersions(map[string]any{"hierarchy_code": version.HierarchyCode, "status": model.HierarchyVersionActive})
+	if err != nil {
+		return err
+	}
+	for _, other := range active {
+		if other.ID != version.ID && version.Overlaps(other) {
+			return fmt.Errorf("层级版本 %s 的有效期与已发布的版本 %s 重叠", version.Version, other.Version)
+		}
+	}
+	return nil
+}
+
+// activateHierarchyVersion 发布版本: 检查有效期不重叠后状态改为已发布
+func activateHierarchyVersion(c *gin.Context, hierarchyRepository repository.HierarchyRepository, version *model.HierarchyVersion) error {
+	if err := checkVersionOverlap(hierarchyRepository, version); err != nil {
+		return err
+	}
+	version.Status = model.HierarchyVersionActive
+	version.UpdatedBy = c.GetString("user_name")
+	return hierarchyRepository.UpdateVersion(c, version)
+}
+
+// Publish 发布编辑中的版本
+// 层级配置了发布审批流程 (实体编码 hierarchy:<层级编码>, 操作 Publish) 时提交审批, 审批通过后发布; 否则直接发布
+func (s *hierarchyService) Publish(c *gin.Context, code, version, reason string) error {
+	if _, err := s.hierarchy(c, code); err != nil {
+		return err
+	}
+	item, err := s.version(code, version, "发布")
+	if err != nil {
+		return err
+	}
+	nodes, err := s.hierarchyRepository.FindNodes(item.ID)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("层级版本 %s 没有节点", version)
+	}
+	if err := checkVersionOverlap(s.hierarchyRepository, item); err != nil {
+		return err
+	}
+
+	entityCode := model.HierarchyApprovalEntity(code)
+	tableApprovalDefs, err := s.tableApprovalDefinitionRepository.List(entityCode, model.OperationPublish)
+	if err != nil {
+		s.logger.Error("查询审批流程定义失败", "err", err)
+	}
+	if len(tableApprovalDefs) == 0 {
+		return activateHierarchyVersion(c, s.hierarchyRepository, item)
+	}
+
+	// 先标记审批中, 创建审批失败时恢复为编辑中
+	item.Status = model.HierarchyVersionPending
+	item.ApprovalCode = strings.ToUpper(uuid.New().String())
+	item.UpdatedBy = c.GetString("user_name")
+	if err := s.hierarchyRepository.UpdateVersion(c, item); err != nil {
+		return err
+	}
+	approvalInfo := map[string]string{
+		"operation":     model.OperationPublish,
+		"approvalCode":  item.ApprovalCode,
+		"operationName": "发布",
+		"action":        "U",
+		"reason":        reason,
+		"entityCode":    entityCode,
+	}
+	formData := map[string]any{
+		"hierarchy_code": code,
+		"version":        item.Version,
+		"valid_from":     item.ValidFrom,
+		"valid_to":       item.ValidTo,
+		"node_count":     len(nodes),
+	}
+	if err := s.approvalService.CreateApprovalFlow(c, entityCode, approvalInfo, formData); err != nil {
+		item.Status, item.ApprovalCode = model.HierarchyVersionDraft, ""
+		if revertErr := s.hierarchyRepository.UpdateVersion(c, item); revertErr != nil {
+			s.logger.Error("恢复层级版本状态失败", "err", revertErr, "version", item.Version)
+		}
+		return err
+	}
+	return nil
+}
+
+// view 读取查询使用的版本及其节点
+func (s *hierarchyService) view(c *gin.Context, code string, query HierarchyQuery) (*model.Hierarchy, *model.HierarchyVersion, []*model.HierarchyNode, error) {
+	hierarchy, err := s.hierarchy(c, code)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var version *model.HierarchyVersion
+	if query.Version != "" {
+		if version, err = s.version(code, query.Version, ""); err != nil {
+			return nil, nil, nil, err
+		}
+	} else {
+		date := query.Date
+		if date.IsZero() {
+			date = time.Now()
+		}
+		active, err := s.hierarchyRepository.FindVersions(map[string]any{"hierarchy_code": code, "status": model.HierarchyVersionActive})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		index := slices.IndexFunc(active, func(item *model.HierarchyVersion) bool { return item.ValidAt(date) })
+		if index < 0 {
+			return nil, nil, nil, fmt.Errorf("%w: 层级 %s 在 %s 没有有效的版本", model.ErrInvalidQuery, code, date.Format(time.DateOnly))
+		}
+		version = active[index]
+	}
+	nodes, err := s.hierarchyRepository.FindNodes(version.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return hierarchy, version, nodes, nil
+}
+
+// buildHierarchy 按父节点组装节点, 子节点按 sort、记录 id 排序
+// root 为 0 时从全部根节点开始, depth 为返回的层数 (含起始层), 小于等于 0 时不限
+func buildHierarchy(nodes []*model.HierarchyNode, root uint, depth int) ([]*HierarchyNodeView, error) {
+	children := make(map[uint][]*model.HierarchyNode)
+	parents := make(map[uint]uint, len(nodes))
+	for _, node := range nodes {
+		children[node.ParentID] = append(children[node.ParentID], node)
+		parents[node.EntityID] = node.ParentID
+	}
+	start, level, path := children[0], 1, "/"
+	if root != 0 {
+		index := slices.IndexFunc(nodes, func(node *model.HierarchyNode) bool { return node.EntityID == root })
+		if index < 0 {
+			return nil, fmt.Errorf("%w: 记录 %d 不在层级中", model.ErrInvalidQuery, root)
+		}
+		start = nodes[index : index+1]
+		// 起始节点的层级和路径按其在层级中的位置计算
+		var ancestors []uint
+		for id := parents[root]; id != 0; id = parents[id] {
+			ancestors = append(ancestors, id)
+		}
+		slices.Reverse(ancestors)
+		for _, id := range ancestors {
+			path += fmt.Sprintf("%d/", id)
+		}
+		level += len(ancestors)
+	}
+
+	var build func(nodes []*model.HierarchyNode, level int, path string, remaining int) []*HierarchyNodeView
+	build = func(nodes []*model.HierarchyNode, level int, path string, remaining int) []*HierarchyNodeView {
+		nodes = slices.Clone(nodes)
+		slices.SortFunc(nodes, func(a, b *model.HierarchyNode) int {
+			return cmp.Or(cmp.Compare(a.Sort, b.Sort), cmp.Compare(a.EntityID, b.EntityID))
+		})
+		views := make([]*HierarchyNodeView, 0, len(nodes))
+		for _, node := range nodes {
+			view := &HierarchyNodeView{
+				EntityID: node.EntityID,
+				ParentID: node.ParentID,
+				Level:    level,
+				Path:     fmt.Sprintf("%s%d/", path, node.EntityID),
+				Sort:     node.Sort,
+			}
+			if remaining != 1 {
+				view.Children = build(children[node.EntityID], level+1, view.Path, remaining-1)
+			}
+			views = append(views, view)
+		}
+		return views
+	}
+	return build(start, level, path, depth), nil
+}
+
+// flattenHierarchy 按深度优先顺序展开节点, 展开后的节点不带 children
+func flattenHierarchy(views []*HierarchyNodeView) []*HierarchyNodeView {
+	var rows []*HierarchyNodeView
+	for _, view := range views {
+		children := view.Children
+		view.Children = nil
+		rows = append(rows, view)
+		rows = append(rows, flattenHierarchy(children)...)
+	}
+	return rows
+}
+
+// attachRecords 为节点填充引用的记录, 记录已删除时为空
+func (s *hierarchyService) attachRecords(tableCode string, views []*HierarchyNodeView) error {
+	var ids []uint
+	var collect func(views []*HierarchyNodeView)
+	collect = func(views []*HierarchyNodeView) {
+		for _, view := range views {
+			ids = append(ids, view.EntityID)
+			collect(view.Children)
+		}
+	}
+	collect(views)
+	if len(ids) == 0 {
+		return nil
+	}
+	rows, err := s.entityRepository.Find(tableCode, "*", map[string]any{"id": ids})
+	if err != nil {
+		return err
+	}
+	records := make(map[uint]map[string]any, len(rows))
+	for _, row := range rows {
+		if id, err := importRowID(row); err == nil {
+			records[id] = row
+		}
+	}
+	var attach func(views []*HierarchyNodeView)
+	attach = func(views []*HierarchyNodeView) {
+		for _, view := range views {
+			view.Record = records[view.EntityID]
+			attach(view.Children)
+		}
+	}
+	attach(views)
+	return nil
+}
+
+// Tree 查询层级, 子节点放在 children 中
+func (s *hierarchyService) Tree(c *gin.Context, code string, query HierarchyQuery) (*HierarchyView, error) {
+	hierarchy, version, nodes, err := s.view(c, code, query)
+	if err != nil {
+		return nil, err
+	}
+	views, err := buildHierarchy(nodes, query.Root, query.Depth)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachRecords(hierarchy.TableCode, views); err != nil {
+		return nil, err
+	}
+	return &HierarchyView{Version: version, Nodes: views}, nil
+}
+
+// Flatten 查询层级并按深度优先顺序展开, 每个节点带层级和路径
+func (s *hierarchyService) Flatten(c *gin.Context, code string, query HierarchyQuery) (*HierarchyView, error) {
+	view, err := s.Tree(c, code, query)
+	if err != nil {
+		return nil, err
+	}
+	view.Nodes = flattenHierarchy(view.Nodes)
+	if view.Nodes == nil {
+		view.Nodes = []*HierarchyNodeView{}
+	}
+	return view, nil
+}
+
+// publishHierarchy 审批通过后发布层级版本
+func (s *approvalService) publishHierarchy(c *gin.Context, approval *model.Approval) error {
+	versions, err := s.hierarchyRepository.FindVersions(map[string]any{"approval_code": approval.Code})
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		if version.Status != model.HierarchyVersionPending {
+			continue
+		}
+		if err := activateHierarchyVersion(c, s.hierarchyRepository, version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revertHierarchy 审批拒绝或撤回后, 层级版本恢复为编辑中
+func (s *approvalService) revertHierarchy(c *gin.Context, approval *model.Approval) error {
+	versions, err := s.hierarchyRepository.FindVersions(map[string]any{"approval_code": approval.Code})
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		if version.Status != model.HierarchyVersionPending {
+			continue
+		}
+		version.Status, version.ApprovalCode = model.HierarchyVersionDraft, ""
+		version.UpdatedBy = c.GetString("user_name")
+		if err := s.hierarchyRepository.UpdateVersion(c, version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package service_test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"piemdm/internal/model"
+	"piemdm/internal/service"
+	"piemdm/pkg/helper/sid"
+	"piemdm/pkg/jwt"
+	"piemdm/pkg/log"
+	mock_repository "piemdm/test/mocks/repository"
+	mock_service "piemdm/test/mocks/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type hierarchyMocks struct {
+	hierarchyRepo   *mock_repository.MockHierarchyRepository
+	entityRepo      *mock_repository.MockEntityRepository
+	tadRepo         *mock_repository.MockTableApprovalDefinitionRepository
+	approvalService *mock_service.MockApprovalService
+}
+
+// setupHierarchyService 层级 reporting 引用表 cost_center, 当前用户有表权限
+func setupHierarchyService(t *testing.T) (service.HierarchyService, *hierarchyMocks, *gin.Context) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	m := &hierarchyMocks{
+		hierarchyRepo:   mock_repository.NewMockHierarchyRepository(ctrl),
+		entityRepo:      mock_repository.NewMockEntityRepository(ctrl),
+		tadRepo:         mock_repository.NewMockTableApprovalDefinitionRepository(ctrl),
+		approvalService: mock_service.NewMockApprovalService(ctrl),
+	}
+	permissionService := mock_service.NewMockTablePermissionService(ctrl)
+	permissionService.EXPECT().CheckTablePermission(gomock.Any(), uint(1), "cost_center").Return(true, nil).AnyTimes()
+	m.hierarchyRepo.EXPECT().FindByCode("reporting").Return(&model.Hierarchy{ID: 1, Code: "reporting", TableCode: "cost_center"}, nil).AnyTimes()
+
+	logger := &log.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	baseService := service.NewService(logger, &sid.Sid{}, &jwt.JWT{})
+	s := service.NewHierarchyService(baseService, m.hierarchyRepo, nil, m.entityRepo, m.tadRepo, m.approvalService, permissionService)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_id", uint(1))
+	c.Set("user_name", "tester")
+	return s, m, c
+}
+
+func TestHierarchyService_SaveNodes(t *testing.T) {
+	t.Run("形成环时不保存", func(t *testing.T) {
+		s, m, c := setupHierarchyService(t)
+		m.hierarchyRepo.EXPECT().FindVersion("reporting", "2024").Return(&model.HierarchyVersion{ID: 7, Version: "2024", Status: model.HierarchyVersionDraft}, nil)
+
+		err := s.SaveNodes(c, "reporting", "2024", []*model.HierarchyNode{
+			{EntityID: 1, ParentID: 3},
+			{EntityID: 2, ParentID: 1},
+			{EntityID: 3, ParentID: 2},
+		})
+		var cycleErr *service.TreeCycleError
+		require.True(t, errors.As(err, &cycleErr), "err = %v", err)
+	})
+
+	t.Run("父节点不在版本中", func(t *testing.T) {
+		s, m, c := setupHierarchyService(t)
+		m.hierarchyRepo.EXPECT().FindVersion("reporting", "2024").Return(&model.HierarchyVersion{ID: 7, Version: "2024", Status: model.HierarchyVersionDraft}, nil)
+
+		err := s.SaveNodes(c, "reporting", "2024", []*model.HierarchyNode{{EntityID: 1, ParentID: 9}})
+		assert.Error(t, err)
+	})
+
+	t.Run("已发布的版本不能修改", func(t *testing.T) {
+		s, m, c := setupHierarchyService(t)
+		m.hierarchyRepo.EXPECT().FindVersion("reporting", "2024").Return(&model.HierarchyVersion{ID: 7, Version: "2024", Status: model.HierarchyVersionActive}, nil)
+
+		err := s.SaveNodes(c, "reporting", "2024", []*model.HierarchyNode{{EntityID: 1}})
+		var versionErr *service.HierarchyVersionError
+		require.True(t, errors.As(err, &versionErr), "err = %v", err)
+		assert.Equal(t, model.HierarchyVersionActive, versionErr.Status)
+	})
+
+	t.Run("保存节点", func(t *testing.T) {
+		s, m, c := setupHierarchyService(t)
+		nodes := []*model.HierarchyNode{{EntityID: 1}, {EntityID: 2, ParentID: 1}}
+		m.hierarchyRepo.EXPECT().FindVersion("reporting", "2024").Return(&model.HierarchyVersion{ID: 7, Version: "2024", Status: model.HierarchyVersionDraft}, nil)
+		m.entityRepo.EXPECT().Find("cost_center", "id", map[string]any{"id": []uint{1, 2}}).
+			Return([]map[string]any{{"id": uint64(1)}, {"id": uint64(2)}}, nil)
+		m.hierarchyRepo.EXPECT().ReplaceNodes(c, uint(7), nodes).Return(nil)
+
+		require.NoError(t, s.SaveNodes(c, "reporting", "2024", nodes))
+	})
+}
+
+func TestHierarchyService_MoveNode_Cycle(t *testing.T) {
+	s, m, c := setupHierarchyService(t)
+	m.hierarchyRepo.EXPECT().FindVersion("reporting", "2024").Return(&model.HierarchyVersion{ID: 7, Version: "2024", Status: model.HierarchyVersionDraft}, nil)
+	m.hierarchyRepo.EXPECT().FindNodes(uint(7)).Return([]*model.HierarchyNode{
+		{EntityID: 1},
+		{EntityID: 2, ParentID: 1},
+		{EntityID: 3, ParentID: 2},
+	}, nil)
+
+	// 把 1 移动到其后代 3 下
+	err := s.MoveNode(c, "reporting", "2024", 1, 3)
+	var cycleErr *service.TreeCycleError
+	require.True(t, errors.As(err, &cycleErr), "err = %v", err)
+}
+
+func TestHierarchyService_Publish(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	draft := func() *model.HierarchyVersion {
+		return &model.HierarchyVersion{ID: 7, HierarchyCode: "reporting", Version: "2024", ValidFrom: &from, Status: model.HierarchyVersionDraft}
+	}
+
+	t.Run("没有审批流程时直接发布", func(t *testing.T) {
+		s, m, c := setupHierarchyService(t)
+		m.hierarchyRepo.EXPECT().FindVersion("reporting", "2024").Return(draft(), nil)
+		m.hierarchyRepo.EXPECT().FindNodes(uint(7)).Return([]*model.HierarchyNode{{EntityID: 1}}, nil)
+		m.hierarchyRepo.EXPECT().FindVersions(map[string]any{"hierarchy_code": "reporting", "status": model.HierarchyVersionActive}).Return(nil, nil).Times(2)
+		m.tadRepo.EXPECT().List("hierarchy:reporting", model.OperationPublish).Return(nil, nil)
+		m.hierarchyRepo.EXPECT().UpdateVersion(c, gomock.Any()).DoAndReturn(func(_ *gin.Context, version *model.HierarchyVersion) error {
+			assert.Equal(t, model.HierarchyVersionActive, version.Status)
+			return nil
+		})
+
+		require.NoError(t, s.Publish(c, "reporting", "2024", ""))
+	})
+
+	t.Run("有效期与已发布的版本重叠", func(t *testing.T) {
+		s, m, c := setupHierarchyService(t)
+		m.hierarchyRepo.EXPECT().FindVersion("reporting", "2024").Return(draft(), nil)
+		m.hierarchyRepo.EXPECT().FindNodes(uint(7)).Return([]*model.HierarchyNode{{EntityID: 1}}, nil)
+		m.hierarchyRepo.EXPECT().FindVersions(gomock.Any()).Return([]*model.HierarchyVersion{
+			{ID: 6, Version: "2023", Status: model.HierarchyVersionActive},
+		}, nil)
+
+		assert.Error(t, s.Publish(c, "reporting", "2024", ""))
+	})
+
+	t.Run("配置了审批流程时提交审批", func(t *testing.T) {
+		s, m, c := setupHierarchyService(t)
+		m.hierarchyRepo.EXPECT().FindVersion("reporting", "2024").Return(draft(), nil)
+		m.hierarchyRepo.EXPECT().FindNodes(uint(7)).Return([]*model.HierarchyNode{{EntityID: 1}}, nil)
+		m.hierarchyRepo.EXPECT().FindVersions(gomock.Any()).Return(nil, nil)
+		m.tadRepo.EXPECT().List("hierarchy:reporting", model.OperationPublish).
+			Return([]model.TableApprovalDefinition{{EntityCode: "hierarchy:reporting", Operation: model.OperationPublish}}, nil)
+		var approvalCode string
+		m.hierarchyRepo.EXPECT().UpdateVersion(c, gomock.Any()).DoAndReturn(func(_ *gin.Context, version *model.HierarchyVersion) error {
+			assert.Equal(t, model.HierarchyVersionPending, version.Status)
+			assert.NotEmpty(t, version.ApprovalCode)
+			approvalCode = version.ApprovalCode
+			return nil
+		})
+		m.approvalService.EXPECT().CreateApprovalFlow(c, "hierarchy:reporting", gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ *gin.Context, _ string, info map[string]string, _ map[string]any) error {
+				assert.Equal(t, model.OperationPublish, info["operation"])
+				assert.Equal(t, approvalCode, info["approvalCode"])
+				return nil
+			})
+
+		require.NoError(t, s.Publish(c, "reporting", "2024", "年度调整"))
+	})
+}
+
+func TestHierarchyService_Flatten(t *testing.T) {
+	s, m, c := setupHierarchyService(t)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)
+	m.hierarchyRepo.EXPECT().FindVersions(map[string]any{"hierarchy_code": "reporting", "status": model.HierarchyVersionActive}).
+		Return([]*model.HierarchyVersion{
+			{ID: 6, Version: "2023", ValidTo: &from, Status: model.HierarchyVersionActive},
+			{ID: 7, Version: "2024", ValidFrom: &from, ValidTo: &to, Status: model.HierarchyVersionActive},
+		}, nil)
+	m.hierarchyRepo.EXPECT().FindNodes(uint(7)).Return([]*model.HierarchyNode{
+		{EntityID: 1},
+		{EntityID: 3, ParentID: 1, Sort: 1},
+		{EntityID: 2, ParentID: 1, Sort: 0},
+		{EntityID: 4, ParentID: 2},
+	}, nil)
+	m.entityRepo.EXPECT().Find("cost_center", "*", gomock.Any()).Return([]map[string]any{
+		{"id": uint64(1), "code": "CC1"},
+		{"id": uint64(2), "code": "CC2"},
+	}, nil)
+
+	view, err := s.Flatten(c, "reporting", service.HierarchyQuery{Date: time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)})
+	require.NoError(t, err)
+	assert.Equal(t, "2024", view.Version.Version)
+
+	var ids []uint
+	var paths []string
+	for _, node := range view.Nodes {
+		ids = append(ids, node.EntityID)
+		paths = append(paths, node.Path)
+		assert.Nil(t, node.Children)
+	}
+	assert.Equal(t, []uint{1, 2, 4, 3}, ids)
+	assert.Equal(t, []string{"/1/", "/1/2/", "/1/2/4/", "/1/3/"}, paths)
+	assert.Equal(t, 3, view.Nodes[2].Level)
+	assert.Equal(t, "CC2", view.Nodes[1].Record["code"])
+	assert.Nil(t, view.Nodes[3].Record)
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"piemdm/internal/model"
+	"piemdm/pkg/jsonschema"
+)
+
+// jsonDataType JSON 字段的数据类型, 使用数据库原生 JSON 列
+const jsonDataType = "JSON"
+
+// isJSONField 判断是否为 JSON 字段
+func isJSONField(field *model.TableField) bool {
+	return fieldDataType(field) == jsonDataType
+}
+
+// jsonFieldSchema 字段配置的 JSON Schema, 未配置时返回 nil
+func jsonFieldSchema(field *model.TableField) (*jsonschema.Schema, error) {
+	if field.Options == nil || field.Options.JSON == nil || len(field.Options.JSON.Schema) == 0 {
+		return nil, nil
+	}
+	schema, err := jsonschema.Compile(field.Options.JSON.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("字段 '%s' 的 %v", field.Name, err)
+	}
+	return schema, nil
+}
+
+// validateJSONField 保存字段定义前校验 JSON 字段: JSON 列不能建索引, JSON Schema 必须合法
+func validateJSONField(field *model.TableField) error {
+	if !isJSONField(field) {
+		return nil
+	}
+	if field.IsIndex == "Yes" || field.IsUnique == "Yes" {
+		return fmt.Errorf("JSON 字段 '%s' 不支持索引", field.Name)
+	}
+	_, err := jsonFieldSchema(field)
+	return err
+}
+
+// decodeJSONValue 解析 JSON 字段的值: 字符串按 JSON 文本解析, 其它值 (请求体中的对象、数组等) 原样返回
+// 空值返回 nil
+func decodeJSONValue(value any) (any, bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, false, nil
+	case []byte:
+		value = string(v)
+	case json.RawMessage:
+		value = string(v)
+	}
+	text, ok := value.(string)
+	if !ok {
+		return value, true, nil
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, false, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, false, err
+	}
+	if decoder.More() {
+		return nil, false, fmt.Errorf("JSON 文本后有多余内容")
+	}
+	return decoded, true, nil
+}
+
+// normalizeJSONValue 校验 JSON 字段的值并转换为紧凑 JSON 文本, 配置了 JSON Schema 时按 schema 校验
+func normalizeJSONValue(field *model.TableField, value any) (any, error) {
+	decoded, ok, err := decodeJSONValue(value)
+	if err != nil {
+		return nil, fmt.Errorf("字段 '%s' 不是合法的 JSON: %v", field.Name, err)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	schema, err := jsonFieldSchema(field)
+	if err != nil {
+		return nil, err
+	}
+	if schema != nil {
+		if errs := schema.Validate(decoded); len(errs) > 0 {
+			messages := make([]string, 0, len(errs))
+			for _, e := range errs {
+				messages = append(messages, e.Error())
+			}
+			return nil, fmt.Errorf("字段 '%s' 不符合 JSON Schema: %s", field.Name, strings.Join(messages, "; "))
+		}
+	}
+
+	data, err := json.Marshal(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("字段 '%s' 不是合法的 JSON: %v", field.Name, err)
+	}
+	return string(data), nil
+}
+
+// encodeJSONFields 将 entityMap 中 JSON 字段的值转换为紧凑 JSON 文本后写入, 空值写入 NULL
+// 在字段校验通过后调用, 请求体中的对象、数组才能写入数据表
+func encodeJSONFields(fields []*model.TableField, entityMap map[string]any) {
+	for _, field := range fields {
+		value, ok := entityMap[field.Code]
+		if !ok || !isJSONField(field) {
+			continue
+		}
+		if normalized, err := normalizeJSONValue(field, value); err == nil {
+			entityMap[field.Code] = normalized
+		}
+	}
+}
+
+// jsonPathChanges 比较 JSON 字段修改前后的值, 按路径返回变化, 如 address.city、tags[1]
+// 只有修改前后都是对象或数组时才按路径比较, 否则返回 nil; 路径不存在时值为 null
+func jsonPathChanges(before, after any) map[string]*FieldChange {
+	changes := make(map[string]*FieldChange)
+	var diff func(path string, before, after any)
+	diff = func(path string, before, after any) {
+		if jsonEqual(before, after) {
+			return
+		}
+
+		beforeObj, ok1 := before.(map[string]any)
+		afterObj, ok2 := after.(map[string]any)
+		if ok1 && ok2 {
+			for key := range beforeObj {
+				diff(joinJSONPath(path, key), beforeObj[key], afterObj[key])
+			}
+			for key := range afterObj {
+				if _, ok := beforeObj[key]; !ok {
+					diff(joinJSONPath(path, key), nil, afterObj[key])
+				}
+			}
+			return
+		}
+
+		beforeArr, ok1 := before.([]any)
+		afterArr, ok2 := after.([]any)
+		if ok1 && ok2 {
+			for i := 0; i < max(len(beforeArr), len(afterArr)); i++ {
+				var b, a any
+				if i < len(beforeArr) {
+					b = beforeArr[i]
+				}
+				if i < len(afterArr) {
+					a = afterArr[i]
+				}
+				diff(fmt.Sprintf("%s[%d]", path, i), b, a)
+			}
+			return
+		}
+
+		if path != "" {
+			changes[path] = &FieldChange{Before: before, After: after}
+		}
+	}
+	diff("", before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// joinJSONPath 拼接 JSON 路径
+func joinJSONPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// jsonEqual 比较两个 JSON 值, 忽略格式差异, 数字按数值比较
+func jsonEqual(a, b any) bool {
+	return reflect.DeepEqual(jsonNumbers(a), jsonNumbers(b))
+}
+
+// jsonNumbers 将 json.Number 统一转换为 float64
+func jsonNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			out[key] = jsonNumbers(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = jsonNumbers(item)
+		}
+		return out
+	}
+	return value
+}
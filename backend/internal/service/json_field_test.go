@@ -0,0 +1,87 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"piemdm/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jsonField(code string, schema string) *model.TableField {
+	field := &model.TableField{Code: code, Name: code, FieldType: "json", Type: jsonDataType, Status: "Normal"}
+	if schema != "" {
+		var raw map[string]any
+		if err := json.Unmarshal([]byte(schema), &raw); err != nil {
+			panic(err)
+		}
+		field.Options = &model.FieldOptions{JSON: &model.FieldJSON{Schema: raw}}
+	}
+	return field
+}
+
+func TestValidateEntityValues_JSON(t *testing.T) {
+	attrs := jsonField("attrs", `{"type":"object","required":["color"],"properties":{"color":{"enum":["red","green"]},"size":{"type":"integer"}}}`)
+	fields := []*model.TableField{attrs, jsonField("extra", "")}
+
+	entityMap := map[string]any{
+		"attrs": map[string]any{"color": "red", "size": float64(3)},
+		"extra": ` { "b": [1, 2.50], "a": null } `,
+	}
+	assert.Empty(t, ValidateEntityValues(fields, entityMap, false))
+
+	// 请求体中的对象转换为紧凑 JSON 文本后写入, 空字符串写入 NULL
+	encodeJSONFields(fields, entityMap)
+	assert.Equal(t, `{"color":"red","size":3}`, entityMap["attrs"])
+	assert.Equal(t, `{"a":null,"b":[1,2.50]}`, entityMap["extra"])
+	entityMap["extra"] = "  "
+	encodeJSONFields(fields, entityMap)
+	assert.Nil(t, entityMap["extra"])
+
+	fieldErrors := ValidateEntityValues(fields, map[string]any{
+		"attrs": `{"color":"blue","size":1.5}`,
+		"extra": `{"a":`,
+	}, false)
+	require.Len(t, fieldErrors, 2)
+	assert.Equal(t, "attrs", fieldErrors[0].Field)
+	assert.Equal(t, `字段 'attrs' 不符合 JSON Schema: $.color 必须是以下值之一: ["red","green"]; $.size 类型应为 integer, 实际为 number`, fieldErrors[0].Message)
+	assert.Equal(t, "extra", fieldErrors[1].Field)
+	assert.Contains(t, fieldErrors[1].Message, "不是合法的 JSON")
+}
+
+func TestValidateJSONField(t *testing.T) {
+	assert.NoError(t, validateJSONField(jsonField("attrs", `{"type":"object"}`)))
+	assert.Error(t, validateJSONField(jsonField("attrs", `{"type":"map"}`)))
+
+	indexed := jsonField("attrs", "")
+	indexed.IsIndex = "Yes"
+	assert.Error(t, validateJSONField(indexed))
+}
+
+func TestJSONPathChanges(t *testing.T) {
+	before, _, _ := decodeJSONValue(`{"address": {"city": "Paris", "zip": "75001"}, "tags": ["a", "b"], "price": 1.50}`)
+	after, _, _ := decodeJSONValue(`{"address":{"city":"Lyon","zip":"75001"},"tags":["a"],"price":1.5,"color":{"r":1}}`)
+
+	changes := jsonPathChanges(before, after)
+	got := make(map[string]string, len(changes))
+	for path, change := range changes {
+		data, err := json.Marshal(change)
+		require.NoError(t, err)
+		got[path] = string(data)
+	}
+	assert.Equal(t, map[string]string{
+		"address.city": `{"before":"Paris","after":"Lyon"}`,
+		"color":        `{"before":null,"after":{"r":1}}`,
+		"tags[1]":      `{"before":"b","after":null}`,
+	}, got)
+
+	// 只有格式差异时不记录
+	before, _, _ = decodeJSONValue(`{"a":1,"b":2}`)
+	after, _, _ = decodeJSONValue(`{"b": 2, "a": 1.0}`)
+	assert.Nil(t, jsonPathChanges(before, after))
+
+	// 从空值变为对象时不按路径记录, 字段的变化中已有整个值
+	assert.Nil(t, jsonPathChanges(nil, map[string]any{"a": 1}))
+}
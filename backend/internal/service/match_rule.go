@@ -0,0 +1,144 @@
+package service
+
+import (
+	"fmt"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MatchRuleService interface {
+	Get(id uint) (*model.MatchRule, error)
+	List(page, pageSize int, total *int64, where map[string]any) ([]*model.MatchRule, error)
+	Create(c *gin.Context, rule *model.MatchRule) error
+	Update(c *gin.Context, rule *model.MatchRule) error
+	Delete(c *gin.Context, id uint) error
+}
+
+type matchRuleService struct {
+	*Service
+	matchRuleRepository repository.MatchRuleRepository
+	tableRepository     repository.TableRepository
+	tableFieldService   TableFieldService
+}
+
+func NewMatchRuleService(
+	service *Service,
+	matchRuleRepository repository.MatchRuleRepository,
+	tableRepository repository.TableRepository,
+	tableFieldService TableFieldService,
+) MatchRuleService {
+	return &matchRuleService{
+		Service:             service,
+		matchRuleRepository: matchRuleRepository,
+		tableRepository:     tableRepository,
+		tableFieldService:   tableFieldService,
+	}
+}
+
+func (s *matchRuleService) Get(id uint) (*model.MatchRule, error) {
+	return s.matchRuleRepository.FindOne(id)
+}
+
+func (s *matchRuleService) List(page, pageSize int, total *int64, where map[string]any) ([]*model.MatchRule, error) {
+	return s.matchRuleRepository.FindPage(page, pageSize, total, where)
+}
+
+// Create 新增匹配规则, 表和字段必须存在
+func (s *matchRuleService) Create(c *gin.Context, rule *model.MatchRule) error {
+	tables, err := s.tableRepository.Find("", map[string]any{"code": rule.TableCode})
+	if err != nil || len(tables) == 0 {
+		return fmt.Errorf("表 %s 不存在", rule.TableCode)
+	}
+	if rule.Threshold == 0 {
+		rule.Threshold = 0.8
+	}
+	if rule.Action == "" {
+		rule.Action = model.MatchActionWarn
+	}
+	if err := s.validate(rule); err != nil {
+		return err
+	}
+	return s.matchRuleRepository.Create(c, rule)
+}
+
+// Update 修改匹配规则, 编码和表不能修改
+func (s *matchRuleService) Update(c *gin.Context, rule *model.MatchRule) error {
+	origin, err := s.matchRuleRepository.FindOne(rule.ID)
+	if err != nil {
+		return fmt.Errorf("匹配规则 %d 不存在", rule.ID)
+	}
+	if rule.Code != "" && rule.Code != origin.Code || rule.TableCode != "" && rule.TableCode != origin.TableCode {
+		return fmt.Errorf("匹配规则的编码和表不能修改")
+	}
+	rule.Code, rule.TableCode = "", ""
+
+	// 按修改后的规则校验, 未提交的字段取原值
+	merged := *origin
+	if rule.Fields != nil {
+		merged.Fields = rule.Fields
+	}
+	if rule.Threshold != 0 {
+		merged.Threshold = rule.Threshold
+	}
+	if rule.Action != "" {
+		merged.Action = rule.Action
+	}
+	if rule.BlockFields != "" {
+		merged.BlockFields = rule.BlockFields
+	}
+	if err := s.validate(&merged); err != nil {
+		return err
+	}
+	return s.matchRuleRepository.Update(c, rule)
+}
+
+func (s *matchRuleService) Delete(c *gin.Context, id uint) error {
+	return s.matchRuleRepository.Delete(c, id)
+}
+
+// validate 校验匹配字段、分组字段、匹配方法、阈值和处理方式
+func (s *matchRuleService) validate(rule *model.MatchRule) error {
+	if len(rule.Fields) == 0 {
+		return fmt.Errorf("匹配规则至少需要一个匹配字段")
+	}
+	if rule.Threshold <= 0 || rule.Threshold > 1 {
+		return fmt.Errorf("相似度阈值必须大于 0 且不超过 1")
+	}
+	if rule.Action != model.MatchActionWarn && rule.Action != model.MatchActionBlock {
+		return fmt.Errorf("不支持的处理方式: %s", rule.Action)
+	}
+
+	fields, err := s.tableFieldService.Find("code,field_type", map[string]any{
+		"table_code": rule.TableCode,
+		"status":     "Normal",
+	})
+	if err != nil {
+		return fmt.Errorf("获取表字段失败: %v", err)
+	}
+	// 多对多字段的值不在主表中, 不能用于查重
+	codes := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		codes[field.Code] = field.FieldType != "manytomany"
+	}
+
+	for _, field := range rule.Fields {
+		if !codes[field.Field] {
+			return fmt.Errorf("表 %s 没有可用于查重的字段 %s", rule.TableCode, field.Field)
+		}
+		if !model.IsValidMatchMethod(field.Method) {
+			return fmt.Errorf("字段 %s 的匹配方法 %s 不支持", field.Field, field.Method)
+		}
+		if field.Weight < 0 {
+			return fmt.Errorf("字段 %s 的权重不能小于 0", field.Field)
+		}
+	}
+	for _, code := range matchBlockFields(rule) {
+		if !codes[code] {
+			return fmt.Errorf("表 %s 没有可用于查重的字段 %s", rule.TableCode, code)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,517 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ModelPackageService interface {
+	Export(c *gin.Context, tableCodes []string) (*model.ModelPackage, error)
+	Diff(pkg *model.ModelPackage) (*model.ModelPackageDiff, error)
+	Import(c *gin.Context, pkg *model.ModelPackage, options *ModelImportOptions) (*ModelImportResult, error)
+}
+
+// ModelImportOptions 导入模型定义包的选项
+type ModelImportOptions struct {
+	Checksum      string `json:"checksum"`        // 预览返回的差异摘要, 不为空时校验差异未变化
+	AllowDataLoss bool   `json:"allow_data_loss"` // 确认发布会丢失数据的表结构变更
+}
+
+// ModelImportResult 导入结果: 定义在一个事务中保存, 之后逐表发布表结构
+type ModelImportResult struct {
+	Diff     *model.ModelPackageDiff `json:"diff"`
+	Versions []*model.SchemaVersion  `json:"versions"`         // 发布的表结构版本
+	Failed   map[string]string       `json:"failed,omitempty"` // 发布失败的表及原因, 定义已导入, 可修正后重新发布
+}
+
+// ModelPackageError 模型定义包不能导入: 差异已变化、存在错误或会丢失数据但未确认
+type ModelPackageError struct {
+	Reason string                  `json:"reason"`
+	Diff   *model.ModelPackageDiff `json:"diff"`
+}
+
+func (e *ModelPackageError) Error() string {
+	return e.Reason
+}
+
+type modelPackageService struct {
+	*Service
+	modelPackageRepository repository.ModelPackageRepository
+	tableFieldService      TableFieldService
+}
+
+func NewModelPackageService(
+	service *Service,
+	modelPackageRepository repository.ModelPackageRepository,
+	tableFieldService TableFieldService,
+) ModelPackageService {
+	return &modelPackageService{
+		Service:                service,
+		modelPackageRepository: modelPackageRepository,
+		tableFieldService:      tableFieldService,
+	}
+}
+
+// Export 导出表的模型定义, 不指定表时导出全部表
+func (s *modelPackageService) Export(c *gin.Context, tableCodes []string) (*model.ModelPackage, error) {
+	if len(tableCodes) == 0 {
+		tableCodes = nil
+	}
+	pkg, err := s.modelPackageRepository.Export(tableCodes)
+	if err != nil {
+		return nil, fmt.Errorf("导出模型定义失败: %w", err)
+	}
+	exported := make(map[string]bool, len(pkg.Tables))
+	for _, table := range pkg.Tables {
+		exported[table.Code] = true
+	}
+	for _, code := range tableCodes {
+		if !exported[code] {
+			return nil, fmt.Errorf("表 %s 不存在", code)
+		}
+	}
+	now := time.Now()
+	pkg.ExportedAt = &now
+	pkg.ExportedBy = c.GetString("user_name")
+	return pkg, nil
+}
+
+// validatePackage 校验包格式和编码
+func validatePackage(pkg *model.ModelPackage) error {
+	if pkg == nil || pkg.Format != model.ModelPackageFormat {
+		return fmt.Errorf("不是模型定义包, format 应为 %s", model.ModelPackageFormat)
+	}
+	if pkg.Version < 1 || pkg.Version > model.ModelPackageVersion {
+		return fmt.Errorf("不支持的模型定义包版本 %d", pkg.Version)
+	}
+	tables := make(map[string]bool, len(pkg.Tables))
+	for _, table := range pkg.Tables {
+		if table.Code == "" || tables[table.Code] {
+			return fmt.Errorf("表编码 %q 为空或重复", table.Code)
+		}
+		tables[table.Code] = true
+		fields := make(map[string]bool, len(table.Fields))
+		for _, field := range table.Fields {
+			if field.Code == "" || fields[field.Code] {
+				return fmt.Errorf("表 %s 的字段编码 %q 为空或重复", table.Code, field.Code)
+			}
+			fields[field.Code] = true
+		}
+		entities := tableEntities(table.Code)
+		for _, approval := range table.Approvals {
+			if !entities[approval.EntityCode] {
+				return fmt.Errorf("表 %s 的审批绑定实体 %s 不属于该表", table.Code, approval.EntityCode)
+			}
+		}
+	}
+	for _, def := range pkg.ApprovalDefinitions {
+		if def.Code == "" {
+			return fmt.Errorf("审批定义编码为空")
+		}
+	}
+	return nil
+}
+
+// tableEntities 审批绑定中属于表的实体编码: 表本身和合并记录
+func tableEntities(tableCode string) map[string]bool {
+	return map[string]bool{tableCode: true, model.MergeApprovalEntity(tableCode): true}
+}
+
+// Diff 比较模型定义包与当前环境, 返回变更、需要发布的表及其表结构变更计划, 不修改数据库
+func (s *modelPackageService) Diff(pkg *model.ModelPackage) (*model.ModelPackageDiff, error) {
+	if err := validatePackage(pkg); err != nil {
+		return nil, err
+	}
+	diff := &model.ModelPackageDiff{Changes: []*model.ModelPackageChange{}, Tables: []string{}, Errors: []string{}}
+
+	var tableCodes, defCodes []string
+	for _, table := range pkg.Tables {
+		tableCodes = append(tableCodes, table.Code)
+	}
+	for _, def := range pkg.ApprovalDefinitions {
+		defCodes = append(defCodes, def.Code)
+	}
+	target := &model.ModelPackage{}
+	if len(tableCodes) > 0 {
+		var err error
+		if target, err = s.modelPackageRepository.Export(tableCodes); err != nil {
+			return nil, fmt.Errorf("读取当前模型定义失败: %w", err)
+		}
+	}
+	targetDefs, err := s.modelPackageRepository.FindApprovalDefinitions(defCodes)
+	if err != nil {
+		return nil, fmt.Errorf("读取当前审批定义失败: %w", err)
+	}
+
+	// 审批定义和节点
+	targetDefByCode := make(map[string]*model.PackageApprovalDefinition, len(targetDefs))
+	for _, def := range targetDefs {
+		targetDefByCode[def.Code] = def
+	}
+	for _, def := range pkg.ApprovalDefinitions {
+		before := targetDefByCode[def.Code]
+		diff.Changes = append(diff.Changes, diffObject(model.PackageApprovalDefinitionKind, def.Code, approvalDefinitionOnly(before), approvalDefinitionOnly(def))...)
+		var beforeNodes []*model.PackageApprovalNode
+		if before != nil {
+			beforeNodes = before.Nodes
+		}
+		diff.Changes = append(diff.Changes, diffObjects(model.PackageApprovalNodeKind,
+			nodeObjects(def.Code, def.Nodes), nodeObjects(def.Code, beforeNodes))...)
+	}
+
+	// 表和子对象
+	targetByCode := make(map[string]*model.PackageTable, len(target.Tables))
+	for _, table := range target.Tables {
+		targetByCode[table.Code] = table
+	}
+	for _, table := range pkg.Tables {
+		before := targetByCode[table.Code]
+		if before == nil {
+			before = &model.PackageTable{}
+		}
+		tableChanges := diffObject(model.PackageTableKind, table.Code, tableOnly(targetByCode[table.Code]), tableOnly(table))
+		fieldChanges := diffObjects(model.PackageFieldKind, fieldObjects(table.Code, table.Fields), fieldObjects(table.Code, before.Fields))
+		diff.Changes = append(diff.Changes, tableChanges...)
+		diff.Changes = append(diff.Changes, fieldChanges...)
+		diff.Changes = append(diff.Changes, diffObjects(model.PackageFieldGroupKind,
+			groupObjects(table.Code, table.FieldGroups), groupObjects(table.Code, before.FieldGroups))...)
+		diff.Changes = append(diff.Changes, diffObjects(model.PackageTableApprovalKind,
+			approvalObjects(table.Approvals), approvalObjects(before.Approvals))...)
+		diff.Changes = append(diff.Changes, diffObjects(model.PackageTablePermissionKind,
+			permissionObjects(table.Code, table.Permissions), permissionObjects(table.Code, before.Permissions))...)
+
+		// 新表、字段或展示模式变化时需要发布表结构
+		if len(fieldChanges) > 0 || (len(tableChanges) > 0 && (targetByCode[table.Code] == nil || changeHas(tableChanges[0], "display_mode"))) {
+			diff.Tables = append(diff.Tables, table.Code)
+		}
+	}
+
+	if err := s.checkReferences(pkg, diff); err != nil {
+		return nil, err
+	}
+
+	// 按包中的字段定义预览表结构变更计划
+	tableByCode := make(map[string]*model.PackageTable, len(pkg.Tables))
+	for _, table := range pkg.Tables {
+		tableByCode[table.Code] = table
+	}
+	for _, code := range diff.Tables {
+		table := tableByCode[code]
+		plan, err := s.tableFieldService.PlanFields(code, table.DisplayMode, packageTableFields(table))
+		if err != nil {
+			return nil, err
+		}
+		diff.Schemas = append(diff.Schemas, plan)
+		diff.DataLoss = diff.DataLoss || plan.DataLoss
+		if plan.Conflicts {
+			diff.Errors = append(diff.Errors, fmt.Sprintf("表 %s 存在冲突的数据, 不能发布表结构", code))
+		}
+	}
+
+	diff.Checksum = packageChecksum(diff)
+	return diff, nil
+}
+
+// checkReferences 检查包引用的角色、审批定义和表在包中或目标环境中存在
+func (s *modelPackageService) checkReferences(pkg *model.ModelPackage, diff *model.ModelPackageDiff) error {
+	inPackage := make(map[string]bool)
+	for _, table := range pkg.Tables {
+		inPackage["table:"+table.Code] = true
+	}
+	for _, def := range pkg.ApprovalDefinitions {
+		inPackage["approval:"+def.Code] = true
+	}
+
+	var roles, defs, tables []string
+	for _, table := range pkg.Tables {
+		for _, permission := range table.Permissions {
+			roles = append(roles, permission.RoleCode)
+		}
+		for _, group := range table.FieldGroups {
+			roles = append(roles, model.GroupRoles(group.ViewRoles)...)
+			roles = append(roles, model.GroupRoles(group.EditRoles)...)
+		}
+		for _, approval := range table.Approvals {
+			if !inPackage["approval:"+approval.ApprovalDefCode] {
+				defs = append(defs, approval.ApprovalDefCode)
+			}
+		}
+		for _, ref := range []string{table.ParentTable, table.TreeTable} {
+			if ref != "" && !inPackage["table:"+ref] {
+				tables = append(tables, ref)
+			}
+		}
+	}
+
+	checks := []struct {
+		model any
+		codes []string
+		label string
+	}{
+		{&model.Role{}, roles, "角色"},
+		{&model.ApprovalDefinition{}, defs, "审批定义"},
+		{&model.Table{}, tables, "表"},
+	}
+	for _, check := range checks {
+		existing, err := s.modelPackageRepository.ExistingCodes(check.model, check.codes)
+		if err != nil {
+			return err
+		}
+		reported := make(map[string]bool)
+		for _, code := range check.codes {
+			if !existing[code] && !reported[code] {
+				reported[code] = true
+				diff.Errors = append(diff.Errors, fmt.Sprintf("%s %s 不存在", check.label, code))
+			}
+		}
+	}
+	return nil
+}
+
+// Import 按预览的差异导入模型定义包, 然后发布字段有变化的表
+// 差异存在错误时不能导入, 表结构变更会丢失数据时需要确认
+func (s *modelPackageService) Import(c *gin.Context, pkg *model.ModelPackage, options *ModelImportOptions) (*ModelImportResult, error) {
+	if options == nil {
+		options = &ModelImportOptions{}
+	}
+	diff, err := s.Diff(pkg)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case options.Checksum != "" && options.Checksum != diff.Checksum:
+		return nil, &ModelPackageError{Reason: "模型定义差异已变化, 请重新预览", Diff: diff}
+	case len(diff.Errors) > 0:
+		return nil, &ModelPackageError{Reason: "模型定义包存在错误, 不能导入: " + strings.Join(diff.Errors, "; "), Diff: diff}
+	case diff.DataLoss && !options.AllowDataLoss:
+		return nil, &ModelPackageError{Reason: "发布表结构会丢失数据, 请确认后导入", Diff: diff}
+	}
+
+	result := &ModelImportResult{Diff: diff, Versions: []*model.SchemaVersion{}}
+	if len(diff.Changes) == 0 {
+		return result, nil
+	}
+	if err := s.modelPackageRepository.Import(c, pkg); err != nil {
+		return nil, fmt.Errorf("导入模型定义失败: %w", err)
+	}
+
+	for _, code := range diff.Tables {
+		version, err := s.tableFieldService.Public(c, code, &PublicOptions{AllowDataLoss: options.AllowDataLoss})
+		if err != nil {
+			s.logger.Error("导入后发布表结构失败", "table", code, "err", err)
+			if result.Failed == nil {
+				result.Failed = make(map[string]string)
+			}
+			result.Failed[code] = err.Error()
+			continue
+		}
+		if version != nil {
+			result.Versions = append(result.Versions, version)
+		}
+	}
+	return result, nil
+}
+
+// packageTableFields 包中发布使用的字段定义
+func packageTableFields(table *model.PackageTable) []*model.TableField {
+	var fields []*model.TableField
+	for _, field := range table.Fields {
+		if field.Status != "Normal" {
+			continue
+		}
+		fields = append(fields, &model.TableField{
+			TableCode:     table.Code,
+			Code:          field.Code,
+			Name:          field.Name,
+			FieldType:     field.FieldType,
+			Type:          field.Type,
+			Length:        field.Length,
+			Required:      field.Required,
+			IsIndex:       field.IsIndex,
+			IsUnique:      field.IsUnique,
+			IndexName:     field.IndexName,
+			IndexPriority: field.IndexPriority,
+			Description:   field.Description,
+			IsFilter:      field.IsFilter,
+			IsShow:        field.IsShow,
+			GroupName:     field.GroupName,
+			Sort:          field.Sort,
+			Options:       field.Options,
+			Status:        field.Status,
+		})
+	}
+	return fields
+}
+
+// packageChecksum 差异内容的摘要, 不含前后的值和行数
+func packageChecksum(diff *model.ModelPackageDiff) string {
+	type change struct {
+		Kind, Key, Action string
+		Fields            []string
+	}
+	var changes []change
+	for _, c := range diff.Changes {
+		changes = append(changes, change{c.Kind, c.Key, c.Action, c.Fields})
+	}
+	var schemas []string
+	for _, plan := range diff.Schemas {
+		schemas = append(schemas, plan.TableCode+":"+plan.Checksum)
+	}
+	data, _ := json.Marshal([]any{changes, schemas})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// packageObject 按编码比较的对象
+type packageObject struct {
+	key   string
+	value any
+}
+
+// diffObjects 按编码比较两组对象, 返回新增、修改和删除
+func diffObjects(kind string, after, before []packageObject) []*model.ModelPackageChange {
+	var changes []*model.ModelPackageChange
+	beforeByKey := make(map[string]any, len(before))
+	for _, object := range before {
+		beforeByKey[object.key] = object.value
+	}
+	seen := make(map[string]bool, len(after))
+	for _, object := range after {
+		seen[object.key] = true
+		changes = append(changes, diffObject(kind, object.key, beforeByKey[object.key], object.value)...)
+	}
+	for _, object := range before {
+		if !seen[object.key] {
+			changes = append(changes, &model.ModelPackageChange{Kind: kind, Key: object.key, Action: model.PackageDelete, Before: object.value})
+		}
+	}
+	return changes
+}
+
+// diffObject 比较一个对象, before 为 nil 时新增
+func diffObject(kind, key string, before, after any) []*model.ModelPackageChange {
+	if isNil(before) {
+		return []*model.ModelPackageChange{{Kind: kind, Key: key, Action: model.PackageCreate, After: after}}
+	}
+	fields := changedFields(before, after)
+	if len(fields) == 0 {
+		return nil
+	}
+	return []*model.ModelPackageChange{{Kind: kind, Key: key, Action: model.PackageUpdate, Fields: fields, Before: before, After: after}}
+}
+
+func isNil(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case *model.PackageTable:
+		return v == nil
+	case *model.PackageApprovalDefinition:
+		return v == nil
+	}
+	return false
+}
+
+// changedFields 按 JSON 属性比较两个对象, 返回值不同的属性
+func changedFields(before, after any) []string {
+	var b, a map[string]json.RawMessage
+	beforeData, _ := json.Marshal(before)
+	afterData, _ := json.Marshal(after)
+	_ = json.Unmarshal(beforeData, &b)
+	_ = json.Unmarshal(afterData, &a)
+
+	var fields []string
+	for key, value := range a {
+		if !bytes.Equal(value, b[key]) {
+			fields = append(fields, key)
+		}
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// changeHas 判断修改是否包含属性
+func changeHas(change *model.ModelPackageChange, field string) bool {
+	for _, f := range change.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// tableOnly 表属性, 不含子对象, 子对象单独比较
+func tableOnly(table *model.PackageTable) *model.PackageTable {
+	if table == nil {
+		return nil
+	}
+	copied := *table
+	copied.Fields, copied.FieldGroups, copied.Approvals, copied.Permissions = nil, nil, nil, nil
+	return &copied
+}
+
+// approvalDefinitionOnly 审批定义属性, 不含节点
+func approvalDefinitionOnly(def *model.PackageApprovalDefinition) *model.PackageApprovalDefinition {
+	if def == nil {
+		return nil
+	}
+	copied := *def
+	copied.Nodes = nil
+	return &copied
+}
+
+func fieldObjects(tableCode string, fields []*model.PackageField) []packageObject {
+	objects := make([]packageObject, 0, len(fields))
+	for _, field := range fields {
+		objects = append(objects, packageObject{tableCode + "." + field.Code, field})
+	}
+	return objects
+}
+
+func groupObjects(tableCode string, groups []*model.PackageFieldGroup) []packageObject {
+	objects := make([]packageObject, 0, len(groups))
+	for _, group := range groups {
+		objects = append(objects, packageObject{tableCode + "." + group.Code, group})
+	}
+	return objects
+}
+
+func approvalObjects(approvals []*model.PackageTableApproval) []packageObject {
+	objects := make([]packageObject, 0, len(approvals))
+	for _, approval := range approvals {
+		objects = append(objects, packageObject{approval.EntityCode + "/" + approval.Operation, approval})
+	}
+	return objects
+}
+
+func permissionObjects(tableCode string, permissions []*model.PackageTablePermission) []packageObject {
+	objects := make([]packageObject, 0, len(permissions))
+	for _, permission := range permissions {
+		objects = append(objects, packageObject{tableCode + "/" + permission.RoleCode + "/" + permission.Operation, permission})
+	}
+	return objects
+}
+
+func nodeObjects(defCode string, nodes []*model.PackageApprovalNode) []packageObject {
+	objects := make([]packageObject, 0, len(nodes))
+	for _, node := range nodes {
+		objects = append(objects, packageObject{defCode + "." + node.NodeCode, node})
+	}
+	return objects
+}
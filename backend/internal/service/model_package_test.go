@@ -0,0 +1,102 @@
+package service_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"piemdm/internal/model"
+	"piemdm/internal/service"
+	"piemdm/pkg/helper/sid"
+	"piemdm/pkg/jwt"
+	"piemdm/pkg/log"
+	mock_repository "piemdm/test/mocks/repository"
+	mock_service "piemdm/test/mocks/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelPackageService_DiffAndImport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mock_repository.NewMockModelPackageRepository(ctrl)
+	tableFieldService := mock_service.NewMockTableFieldService(ctrl)
+	logger := &log.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s := service.NewModelPackageService(service.NewService(logger, &sid.Sid{}, &jwt.JWT{}), repo, tableFieldService)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_name", "tester")
+
+	pkg := &model.ModelPackage{
+		Format:  model.ModelPackageFormat,
+		Version: model.ModelPackageVersion,
+		Tables: []*model.PackageTable{{
+			Code: "product", Name: "产品", DisplayMode: "List", TableType: "Entity", Status: "Normal",
+			Fields: []*model.PackageField{
+				{Code: "code", Name: "编码", Type: "Text", Length: 64, Status: "Normal"},
+				{Code: "qty", Name: "数量", Type: "Integer", Status: "Normal"},
+			},
+			Permissions: []*model.PackageTablePermission{{RoleCode: "auditor", Operation: "All", Status: "Normal"}},
+		}},
+	}
+	// 目标环境: 编码名称不同, 没有 qty, 多了 legacy
+	repo.EXPECT().Export([]string{"product"}).Return(&model.ModelPackage{Tables: []*model.PackageTable{{
+		Code: "product", Name: "产品", DisplayMode: "List", TableType: "Entity", Status: "Normal",
+		Fields: []*model.PackageField{
+			{Code: "code", Name: "产品编码", Type: "Text", Length: 64, Status: "Normal"},
+			{Code: "legacy", Name: "旧字段", Type: "Text", Length: 32, Status: "Normal"},
+		},
+	}}}, nil).AnyTimes()
+	repo.EXPECT().FindApprovalDefinitions(nil).Return(nil, nil).AnyTimes()
+	repo.EXPECT().ExistingCodes(gomock.Any(), gomock.Any()).DoAndReturn(func(m any, codes []string) (map[string]bool, error) {
+		_, isRole := m.(*model.Role)
+		if isRole && len(codes) == 1 && codes[0] == "auditor" {
+			return map[string]bool{}, nil
+		}
+		return map[string]bool{"editor": true}, nil
+	}).AnyTimes()
+	plan := &model.SchemaPlan{TableCode: "product", Checksum: "plan", DataLoss: true}
+	tableFieldService.EXPECT().PlanFields("product", "List", gomock.Len(2)).Return(plan, nil).AnyTimes()
+
+	diff, err := s.Diff(pkg)
+	require.NoError(t, err)
+	changes := make(map[string]*model.ModelPackageChange)
+	for _, change := range diff.Changes {
+		changes[change.Kind+":"+change.Key] = change
+	}
+	assert.Len(t, diff.Changes, 4)
+	assert.Equal(t, []string{"name"}, changes["Field:product.code"].Fields)
+	assert.Equal(t, model.PackageCreate, changes["Field:product.qty"].Action)
+	assert.Equal(t, model.PackageDelete, changes["Field:product.legacy"].Action)
+	assert.Equal(t, model.PackageCreate, changes["TablePermission:product/auditor/All"].Action)
+	assert.Equal(t, []string{"product"}, diff.Tables)
+	assert.Equal(t, []string{"角色 auditor 不存在"}, diff.Errors)
+	assert.True(t, diff.DataLoss)
+
+	// 引用的角色不存在时不能导入
+	_, err = s.Import(c, pkg, &service.ModelImportOptions{AllowDataLoss: true})
+	var packageErr *service.ModelPackageError
+	require.ErrorAs(t, err, &packageErr)
+
+	// 会丢失数据时需要确认
+	pkg.Tables[0].Permissions[0].RoleCode = "editor"
+	diff, err = s.Diff(pkg)
+	require.NoError(t, err)
+	assert.Empty(t, diff.Errors)
+	_, err = s.Import(c, pkg, &service.ModelImportOptions{Checksum: diff.Checksum})
+	require.ErrorAs(t, err, &packageErr)
+
+	// 确认后导入定义并发布表结构
+	repo.EXPECT().Import(c, pkg).Return(nil)
+	tableFieldService.EXPECT().Public(c, "product", &service.PublicOptions{AllowDataLoss: true}).
+		Return(&model.SchemaVersion{TableCode: "product", Version: 2}, nil)
+	result, err := s.Import(c, pkg, &service.ModelImportOptions{Checksum: diff.Checksum, AllowDataLoss: true})
+	require.NoError(t, err)
+	require.Len(t, result.Versions, 1)
+	assert.Empty(t, result.Failed)
+}
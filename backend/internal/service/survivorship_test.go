@@ -0,0 +1,82 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"piemdm/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func survivorshipField(code, strategy string) *model.TableField {
+	field := &model.TableField{Code: code, Type: "Text", FieldType: "text"}
+	if strategy != "" {
+		field.Options = &model.FieldOptions{Survivorship: &model.FieldSurvivorship{Strategy: strategy}}
+	}
+	return field
+}
+
+func TestPlanMerge(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.Local) }
+	source := survivorshipField("phone", model.SurvivorshipSourcePriority)
+	source.Options.Survivorship.SourceField = "source"
+	source.Options.Survivorship.Sources = []string{"CRM", "ERP"}
+	fields := []*model.TableField{
+		survivorshipField("name", ""),
+		survivorshipField("email", ""),
+		survivorshipField("city", model.SurvivorshipMostRecent),
+		source,
+		survivorshipField("address", model.SurvivorshipMostComplete),
+		survivorshipField("remark", model.SurvivorshipManual),
+		{Code: "tax_no", Type: "Text", IsUnique: "Yes"},
+		{Code: "code", Type: "Text", FieldType: "autocode"},
+	}
+	// 主记录在前
+	records := []map[string]any{
+		{"id": uint64(1), "name": "Acme", "email": "", "city": "Shanghai", "phone": "111", "source": "ERP",
+			"address": "No.1 Road", "remark": "A", "tax_no": "T1", "code": "C1", "updated_at": day(1)},
+		{"id": uint64(2), "name": "ACME Ltd", "email": "old@acme.com", "city": "Beijing", "phone": "222", "source": "CRM",
+			"address": "No.1 Road, Pudong", "remark": "B", "tax_no": "T2", "code": "C2", "updated_at": day(3)},
+		{"id": uint64(3), "name": "Acme Co", "email": "new@acme.com", "city": "", "phone": "333", "source": "Web",
+			"address": "", "remark": "C", "tax_no": "T3", "code": "C3", "updated_at": day(4)},
+	}
+
+	plan, err := planMerge(fields, records, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"email":   "new@acme.com",      // 默认: 主记录为空时取最近修改的非空值
+		"city":    "Beijing",           // 最近修改的非空值
+		"phone":   "222",               // 来源 CRM 最可信
+		"address": "No.1 Road, Pudong", // 最完整
+	}, plan.values)
+	assert.Equal(t, map[string]uint{"email": 3, "city": 2, "phone": 2, "address": 2}, plan.sources)
+	assert.Equal(t, "Acme", plan.survivor["name"])
+	assert.Equal(t, "T1", plan.survivor["tax_no"], "唯一字段保留主记录的值")
+
+	// 人工选择优先于保留规则, 未选择的 manual 字段保留主记录的值
+	plan, err = planMerge(fields, records, map[string]uint{"name": 2, "city": 1})
+	require.NoError(t, err)
+	assert.Equal(t, "ACME Ltd", plan.values["name"])
+	assert.NotContains(t, plan.values, "city")
+	assert.NotContains(t, plan.values, "remark")
+
+	_, err = planMerge(fields, records, map[string]uint{"tax_no": 2, "name": 9})
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Len(t, validationErr.Errors, 2)
+	assert.Equal(t, "name", validationErr.Errors[0].Field)
+	assert.Equal(t, "tax_no", validationErr.Errors[1].Field)
+}
+
+func TestMergeReferenceValue(t *testing.T) {
+	single := &fieldReference{field: &model.TableField{Code: "customer", FieldType: "belongsto"}}
+	assert.Equal(t, "C1", mergeReferenceValue(single, "C2", []string{"C2"}, "C1"))
+
+	links := &fieldReference{field: &model.TableField{Code: "customers", FieldType: "manytomany"}, multiple: true}
+	assert.Equal(t, []string{"C1", "C9"}, mergeReferenceValue(links, []string{"C2", "C1", "C9", "C3"}, []string{"C2", "C3"}, "C1"))
+
+	multiple := &fieldReference{field: &model.TableField{Code: "customers", FieldType: "belongsto"}, multiple: true}
+	assert.Equal(t, `["C1","C9"]`, mergeReferenceValue(multiple, `["C2","C9"]`, []string{"C2"}, "C1"))
+}
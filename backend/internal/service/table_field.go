@@ -3,6 +3,7 @@ package service
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"piemdm/internal/constants"
@@ -22,22 +23,39 @@ type TableFieldService interface {
 	BatchUpdate(c *gin.Context, ids []uint, tableField *model.TableField) error
 	Delete(c *gin.Context, id uint) (*model.TableField, error)
 	BatchDelete(c *gin.Context, ids []uint) error
-	Public(c *gin.Context, tableCode string) error
-	GetTableFields(tableCode string) ([]*model.FieldMetadata, error)
+	PlanPublic(tableCode string) (*model.SchemaPlan, error)
+	PlanFields(tableCode, displayMode string, fields []*model.TableField) (*model.SchemaPlan, error)
+	Public(c *gin.Context, tableCode string, options *PublicOptions) (*model.SchemaVersion, error)
+	ListSchemaVersions(page, pageSize int, total *int64, where map[string]any) ([]*model.SchemaVersion, error)
+	GetTableFields(c *gin.Context, tableCode string) ([]*model.FieldMetadata, error)
+	GetFieldAccess(c *gin.Context, tableCode string) (*FieldAccess, error)
 	GetTableOptions(tableCode string, filter map[string]any) ([]map[string]any, error)
 }
 
 type tableFieldService struct {
 	*Service
-	tableFieldRepository repository.TableFieldRepository
-	tableRepository      repository.TableRepository
+	tableFieldRepository      repository.TableFieldRepository
+	tableRepository           repository.TableRepository
+	schemaVersionRepository   repository.SchemaVersionRepository
+	tableFieldGroupRepository repository.TableFieldGroupRepository
+	userRoleRepository        repository.UserRoleRepository
 }
 
-func NewTableFieldService(service *Service, tableFieldRepository repository.TableFieldRepository, tableRepository repository.TableRepository) TableFieldService {
+func NewTableFieldService(
+	service *Service,
+	tableFieldRepository repository.TableFieldRepository,
+	tableRepository repository.TableRepository,
+	schemaVersionRepository repository.SchemaVersionRepository,
+	tableFieldGroupRepository repository.TableFieldGroupRepository,
+	userRoleRepository repository.UserRoleRepository,
+) TableFieldService {
 	return &tableFieldService{
-		Service:              service,
-		tableFieldRepository: tableFieldRepository,
-		tableRepository:      tableRepository,
+		Service:                   service,
+		tableFieldRepository:      tableFieldRepository,
+		tableRepository:           tableRepository,
+		schemaVersionRepository:   schemaVersionRepository,
+		tableFieldGroupRepository: tableFieldGroupRepository,
+		userRoleRepository:        userRoleRepository,
 	}
 }
 
@@ -137,6 +155,27 @@ func (s *tableFieldService) applyPreset(field *model.TableField) error {
 		field.Options.Validation.Scale = &scale
 	}
 
+	// 公式字段: 数据类型由结果类型决定
+	if isFormulaField(field) {
+		applyFormulaColumn(field)
+	}
+
+	// 关系字段: 校验被引用记录删除时的处理方式
+	if preset.RequireRelation && field.Options.Relation != nil &&
+		!model.IsValidRelationOnDelete(field.Options.Relation.OnDelete) {
+		return fmt.Errorf("不支持的删除处理方式: %s", field.Options.Relation.OnDelete)
+	}
+
+	// 合并保留规则: 按来源优先时必须指定来源字段
+	if rule := field.Options.Survivorship; rule != nil {
+		if !model.IsValidSurvivorship(rule.Strategy) {
+			return fmt.Errorf("不支持的保留规则: %s", rule.Strategy)
+		}
+		if rule.Strategy == model.SurvivorshipSourcePriority && (rule.SourceField == "" || len(rule.Sources) == 0) {
+			return fmt.Errorf("保留规则 %s 需要指定来源字段和可信来源", rule.Strategy)
+		}
+	}
+
 	return nil
 }
 
@@ -150,14 +189,79 @@ func (s *tableFieldService) Create(c *gin.Context, tableField *model.TableField)
 	if err := s.applyPreset(tableField); err != nil {
 		return err
 	}
+	if err := s.validateFormula(tableField); err != nil {
+		return err
+	}
+	if err := validateJSONField(tableField); err != nil {
+		return err
+	}
+	if err := s.resolveGroup(tableField); err != nil {
+		return err
+	}
 
 	return s.tableFieldRepository.Create(c, tableField)
 }
 
 func (s *tableFieldService) Update(c *gin.Context, tableField *model.TableField) error {
+	if isFormulaField(tableField) {
+		applyFormulaColumn(tableField)
+		if err := s.validateFormula(tableField); err != nil {
+			return err
+		}
+	}
+	if err := validateJSONField(tableField); err != nil {
+		return err
+	}
+	if err := s.resolveGroup(tableField); err != nil {
+		return err
+	}
 	return s.tableFieldRepository.Update(c, tableField)
 }
 
+// resolveGroup 字段组按编码或名称匹配表的字段组, 统一保存为字段组编码;
+// 修改字段时未改变的旧组名保留
+func (s *tableFieldService) resolveGroup(field *model.TableField) error {
+	if field.GroupName == "" {
+		return nil
+	}
+	groups, err := s.tableFieldGroupRepository.Find(map[string]any{"table_code": field.TableCode, "status": "Normal"})
+	if err != nil {
+		return fmt.Errorf("获取字段组失败: %v", err)
+	}
+	for _, group := range groups {
+		if group.Code == field.GroupName {
+			return nil
+		}
+	}
+	for _, group := range groups {
+		if group.Name == field.GroupName {
+			field.GroupName = group.Code
+			return nil
+		}
+	}
+	if field.ID > 0 {
+		if origin, err := s.tableFieldRepository.FindOne(field.ID); err == nil && origin.GroupName == field.GroupName {
+			return nil
+		}
+	}
+	return fmt.Errorf("表 %s 没有字段组 %s", field.TableCode, field.GroupName)
+}
+
+// validateFormula 校验公式字段的表达式, 引用的字段必须是同一表中的字段或系统字段
+func (s *tableFieldService) validateFormula(field *model.TableField) error {
+	if !isFormulaField(field) {
+		return nil
+	}
+	fields, err := s.tableFieldRepository.Find("", map[string]any{
+		"table_code": field.TableCode,
+		"status":     "Normal",
+	})
+	if err != nil {
+		return fmt.Errorf("获取表字段失败: %v", err)
+	}
+	return validateFormulaField(field, fields)
+}
+
 func (s *tableFieldService) BatchUpdate(c *gin.Context, ids []uint, tableField *model.TableField) error {
 	return s.tableFieldRepository.BatchUpdate(c, ids, tableField)
 }
@@ -170,80 +274,8 @@ func (s *tableFieldService) BatchDelete(c *gin.Context, ids []uint) error {
 	return s.tableFieldRepository.BatchDelete(c, ids)
 }
 
-func (s *tableFieldService) Public(c *gin.Context, tableCode string) error {
-	// 检查表的 DisplayMode,如果是 Tree 则自动创建树形字段
-	tables, err := s.tableRepository.Find("", map[string]any{"code": tableCode, "status": "Normal"})
-	if err == nil && len(tables) > 0 && tables[0].DisplayMode == "Tree" {
-		// 检查是否已经存在树形字段
-		existingFields, _ := s.tableFieldRepository.Find("", map[string]any{
-			"table_code": tableCode,
-			"code":       "parent_id",
-			"status":     "Normal",
-		})
-
-		// 如果不存在 parent_id 字段,则创建树形字段
-		if len(existingFields) == 0 {
-			if err := s.createTreeFields(c, tableCode); err != nil {
-				return fmt.Errorf("failed to create tree fields: %w", err)
-			}
-		}
-	}
-
-	where := make(map[string]any)
-	// 生成 entity 结构
-	tableName := fmt.Sprintf("t_%s", tableCode)
-	entity := s.tableFieldRepository.BuildEntity(tableCode)
-
-	// 生成 entity 的 draft 结构
-	tableCodeDraft := fmt.Sprintf("%s_draft", tableCode)
-	where["table_code"] = tableCodeDraft
-	draftTableName := fmt.Sprintf("t_%s_draft", tableCode)
-	draftEntity := s.tableFieldRepository.BuildEntity(tableCodeDraft)
-
-	// // 获取 entity 的 修改log 表名
-	logTableName := fmt.Sprintf("t_%s_log", tableCode)
-
-	// 生成 entity 表
-	// ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
-	if err := s.tableFieldRepository.Public(tableName, entity); err != nil {
-		panic(err)
-	}
-
-	// .source.Source.DB().Table(tableName).Set("gorm:table_options", "ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci").AutoMigrate(entity); err != nil {
-	// 	panic(err)
-	// }
-
-	// 生成 entity 的 draft 表
-	// if err := s.Dao.Base.Source.DB().Table(tableDraft).Set("gorm:table_options", "ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci").AutoMigrate(draft); err != nil {
-	// 	panic(err)
-	// }
-	if err := s.tableFieldRepository.Public(draftTableName, draftEntity); err != nil {
-		panic(err)
-	}
-
-	// // 生成 entity 的 修改日志 表
-	// if err := s.Dao.Base.Source.DB().Table(tableLog).Set("gorm:table_options", "ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci").AutoMigrate(model.EntityLog{}); err != nil {
-	// 	panic(err)
-	// }
-	if err := s.tableFieldRepository.Public(logTableName, model.EntityLog{}); err != nil {
-		panic(err)
-	}
-
-	// return s.tableFieldRepository.Public(tableCode)
-	return nil
-}
-
-// createTreeFields 为树形表自动创建必需的字段
-func (s *tableFieldService) createTreeFields(c *gin.Context, tableCode string) error {
-	// 获取表的现有字段,确定 labelField
-	existingFields, err := s.tableFieldRepository.Find("", map[string]any{
-		"table_code": tableCode,
-		"status":     "Normal",
-	})
-	if err != nil {
-		return err
-	}
-
+// buildTreeFields 树形表必需的字段, 发布时创建
+func buildTreeFields(tableCode string, existingFields []*model.TableField) []*model.TableField {
 	// 动态确定 labelField: 优先 name → code → id
 	labelField := "id"
 	for _, f := range existingFields {
@@ -311,16 +343,7 @@ func (s *tableFieldService) createTreeFields(c *gin.Context, tableCode string) e
 		Status:    "Normal",
 	}
 
-	// 批量创建字段
-	fields := []*model.TableField{parentIdField, levelField, pathField}
-	for _, field := range fields {
-		err = s.tableFieldRepository.Create(c, field)
-		if err != nil {
-			return fmt.Errorf("failed to create field %s: %w", field.Code, err)
-		}
-	}
-
-	return nil
+	return []*model.TableField{parentIdField, levelField, pathField}
 }
 
 // BatchCreate 批量创建字段
@@ -338,6 +361,12 @@ func (s *tableFieldService) BatchCreate(c *gin.Context, fields []*model.TableFie
 		if err := s.applyPreset(field); err != nil {
 			return err
 		}
+		if err := validateJSONField(field); err != nil {
+			return err
+		}
+		if err := s.resolveGroup(field); err != nil {
+			return err
+		}
 
 		if err := s.tableFieldRepository.Create(c, field); err != nil {
 			return err
@@ -348,7 +377,9 @@ func (s *tableFieldService) BatchCreate(c *gin.Context, fields []*model.TableFie
 }
 
 // GetTableFields 获取表的所有字段（包括系统字段）
-func (s *tableFieldService) GetTableFields(tableCode string) ([]*model.FieldMetadata, error) {
+// 业务字段按字段组顺序、组内按字段顺序排列: 未分组的字段在前, 然后是维护的字段组, 最后是只填写了组名的旧字段;
+// 当前用户不能查看的字段组中的字段不返回, 不能修改的字段标记为只读
+func (s *tableFieldService) GetTableFields(c *gin.Context, tableCode string) ([]*model.FieldMetadata, error) {
 	var fields []*model.FieldMetadata
 
 	// 1. 从table_field读取用户定义的业务字段
@@ -362,9 +393,23 @@ func (s *tableFieldService) GetTableFields(tableCode string) ([]*model.FieldMeta
 	if err != nil {
 		return nil, err
 	}
+	layout, err := s.fieldLayout(c, baseTableCode)
+	if err != nil {
+		return nil, err
+	}
 
+	// 字段组排序: 未分组 0, 字段组 1, 旧组名 2, 系统字段 3
+	type orderKey struct {
+		rank      int
+		groupSort uint
+	}
+	orders := make(map[*model.FieldMetadata]orderKey, len(userFields))
 	for _, field := range userFields {
-		fields = append(fields, &model.FieldMetadata{
+		group, visible := layout.group(field.GroupName)
+		if !visible {
+			continue
+		}
+		metadata := &model.FieldMetadata{
 			Code:      field.Code,
 			Name:      field.Name,
 			FieldType: field.FieldType, // 添加 FieldType
@@ -374,23 +419,195 @@ func (s *tableFieldService) GetTableFields(tableCode string) ([]*model.FieldMeta
 			IsSystem:  false,
 			IsShow:    field.IsShow == "Yes",
 			IsFilter:  field.IsFilter == "Yes",
+			IsUnique:  field.IsUnique == "Yes",
+			IndexName: field.IndexName,
 			Sort:      int(field.Sort),
 			Options:   field.Options, // 添加 Options 配置
-		})
+			Group:     group,
+			Readonly:  group != nil && !group.Editable,
+		}
+		switch {
+		case group == nil:
+			orders[metadata] = orderKey{0, 0}
+		case group.Code != "":
+			orders[metadata] = orderKey{1, group.Sort}
+		default:
+			orders[metadata] = orderKey{2, 0}
+		}
+		fields = append(fields, metadata)
 	}
 
 	// 2. 添加系统字段元数据
 	systemFields := s.getSystemFieldsMetadata(tableCode)
+	for _, field := range systemFields {
+		orders[field] = orderKey{3, 0}
+	}
 	fields = append(fields, systemFields...)
 
-	// 3. 按sort排序
-	sort.Slice(fields, func(i, j int) bool {
+	// 3. 按字段组和sort排序
+	sort.SliceStable(fields, func(i, j int) bool {
+		a, b := orders[fields[i]], orders[fields[j]]
+		if a != b {
+			if a.rank != b.rank {
+				return a.rank < b.rank
+			}
+			return a.groupSort < b.groupSort
+		}
 		return fields[i].Sort < fields[j].Sort
 	})
 
 	return fields, nil
 }
 
+// FieldAccess 当前用户按字段组权限不能查看、不能修改的字段
+type FieldAccess struct {
+	Hidden   map[string]bool
+	Readonly map[string]bool
+}
+
+// Editable 字段能否由当前用户填写或修改
+func (a *FieldAccess) Editable(code string) bool {
+	return a == nil || !a.Hidden[code] && !a.Readonly[code]
+}
+
+// Hide 删除记录中当前用户不能查看的字段
+func (a *FieldAccess) Hide(rows ...map[string]any) {
+	if a == nil {
+		return
+	}
+	for _, row := range rows {
+		for code := range a.Hidden {
+			delete(row, code)
+		}
+	}
+}
+
+// GetFieldAccess 获取当前用户对表字段的访问限制, 没有限制时返回 nil
+func (s *tableFieldService) GetFieldAccess(c *gin.Context, tableCode string) (*FieldAccess, error) {
+	baseTableCode := strings.TrimSuffix(tableCode, "_draft")
+	layout, err := s.fieldLayout(c, baseTableCode)
+	if err != nil || layout.unrestricted() {
+		return nil, err
+	}
+	userFields, err := s.tableFieldRepository.Find("code,group_name", map[string]any{
+		"status":     "Normal",
+		"table_code": baseTableCode,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	access := &FieldAccess{Hidden: make(map[string]bool), Readonly: make(map[string]bool)}
+	for _, field := range userFields {
+		group, visible := layout.group(field.GroupName)
+		if !visible {
+			access.Hidden[field.Code] = true
+		} else if group != nil && !group.Editable {
+			access.Readonly[field.Code] = true
+		}
+	}
+	if len(access.Hidden) == 0 && len(access.Readonly) == 0 {
+		return nil, nil
+	}
+	return access, nil
+}
+
+// fieldLayout 表的字段组和当前用户的角色
+type fieldLayout struct {
+	groups map[string]*model.TableFieldGroup // 按编码和名称索引
+	roles  []string
+	all    bool // 超级管理员和后台任务不受字段组权限限制
+}
+
+func (s *tableFieldService) fieldLayout(c *gin.Context, tableCode string) (*fieldLayout, error) {
+	groups, err := s.tableFieldGroupRepository.Find(map[string]any{"table_code": tableCode, "status": "Normal"})
+	if err != nil {
+		return nil, err
+	}
+	layout := &fieldLayout{groups: make(map[string]*model.TableFieldGroup, len(groups)*2)}
+	for _, group := range groups {
+		// 编码优先, 名称只用于兼容按组名关联的旧字段
+		if _, ok := layout.groups[group.Name]; !ok {
+			layout.groups[group.Name] = group
+		}
+	}
+	for _, group := range groups {
+		layout.groups[group.Code] = group
+	}
+	layout.roles, layout.all, err = currentUserRoles(c, s.userRoleRepository)
+	return layout, err
+}
+
+// unrestricted 当前用户能查看、修改所有字段组
+func (l *fieldLayout) unrestricted() bool {
+	if l.all {
+		return true
+	}
+	for _, group := range l.groups {
+		if !group.CanView(l.roles) || !group.CanEdit(l.roles) {
+			return false
+		}
+	}
+	return true
+}
+
+// group 字段所属字段组的元数据, 未分组时为 nil; visible 为 false 表示当前用户不能查看
+func (l *fieldLayout) group(groupName string) (group *model.FieldGroupMetadata, visible bool) {
+	if groupName == "" {
+		return nil, true
+	}
+	managed, ok := l.groups[groupName]
+	if !ok {
+		return &model.FieldGroupMetadata{Name: groupName, Editable: true}, true
+	}
+	if !l.all && !managed.CanView(l.roles) {
+		return nil, false
+	}
+	return &model.FieldGroupMetadata{
+		Code:        managed.Code,
+		Name:        managed.Name,
+		Sort:        managed.Sort,
+		Collapsible: managed.Collapsible == "Yes",
+		Collapsed:   managed.Collapsed == "Yes",
+		Editable:    l.all || managed.CanEdit(l.roles),
+	}, true
+}
+
+// currentUserRoles 当前登录用户的角色编码; all 为 true 表示超级管理员, 或没有登录用户的后台任务
+func currentUserRoles(c *gin.Context, userRoleRepository repository.UserRoleRepository) ([]string, bool, error) {
+	if c == nil {
+		return nil, true, nil
+	}
+	value, exists := c.Get("user_id")
+	if !exists {
+		return nil, true, nil
+	}
+	var userId uint
+	switch v := value.(type) {
+	case string:
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid user id")
+		}
+		userId = uint(id)
+	case uint:
+		userId = v
+	}
+
+	roles, err := userRoleRepository.FindRolesByUserID(userId)
+	if err != nil {
+		return nil, false, err
+	}
+	codes := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if role.Code == "superuser" || (role.Code == "admin" && role.DataScope == "All") {
+			return nil, true, nil
+		}
+		codes = append(codes, role.Code)
+	}
+	return codes, false, nil
+}
+
 // getSystemFieldsMetadata 获取系统字段元数据
 func (s *tableFieldService) getSystemFieldsMetadata(tableCode string) []*model.FieldMetadata {
 	systemFields := []*model.FieldMetadata{
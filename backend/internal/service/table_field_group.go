@@ -0,0 +1,156 @@
+package service
+
+import (
+	"fmt"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TableFieldGroupService interface {
+	Get(id uint) (*model.TableFieldGroup, error)
+	Find(where map[string]any) ([]*model.TableFieldGroup, error)
+	List(page, pageSize int, total *int64, where map[string]any) ([]*model.TableFieldGroup, error)
+	Create(c *gin.Context, group *model.TableFieldGroup) error
+	Update(c *gin.Context, group *model.TableFieldGroup) error
+	Delete(c *gin.Context, id uint) error
+}
+
+type tableFieldGroupService struct {
+	*Service
+	tableFieldGroupRepository repository.TableFieldGroupRepository
+	tableFieldRepository      repository.TableFieldRepository
+	tableRepository           repository.TableRepository
+	roleRepository            repository.RoleRepository
+}
+
+func NewTableFieldGroupService(
+	service *Service,
+	tableFieldGroupRepository repository.TableFieldGroupRepository,
+	tableFieldRepository repository.TableFieldRepository,
+	tableRepository repository.TableRepository,
+	roleRepository repository.RoleRepository,
+) TableFieldGroupService {
+	return &tableFieldGroupService{
+		Service:                   service,
+		tableFieldGroupRepository: tableFieldGroupRepository,
+		tableFieldRepository:      tableFieldRepository,
+		tableRepository:           tableRepository,
+		roleRepository:            roleRepository,
+	}
+}
+
+func (s *tableFieldGroupService) Get(id uint) (*model.TableFieldGroup, error) {
+	return s.tableFieldGroupRepository.FindOne(id)
+}
+
+func (s *tableFieldGroupService) Find(where map[string]any) ([]*model.TableFieldGroup, error) {
+	return s.tableFieldGroupRepository.Find(where)
+}
+
+func (s *tableFieldGroupService) List(page, pageSize int, total *int64, where map[string]any) ([]*model.TableFieldGroup, error) {
+	return s.tableFieldGroupRepository.FindPage(page, pageSize, total, where)
+}
+
+// Create 新增字段组, 表和角色必须存在
+func (s *tableFieldGroupService) Create(c *gin.Context, group *model.TableFieldGroup) error {
+	tables, err := s.tableRepository.Find("", map[string]any{"code": group.TableCode})
+	if err != nil || len(tables) == 0 {
+		return fmt.Errorf("表 %s 不存在", group.TableCode)
+	}
+	if group.Collapsible == "" {
+		group.Collapsible = "No"
+	}
+	if group.Collapsed == "" {
+		group.Collapsed = "No"
+	}
+	if group.Status == "" {
+		group.Status = "Normal"
+	}
+	if err := s.validate(group); err != nil {
+		return err
+	}
+	return s.tableFieldGroupRepository.Create(c, group)
+}
+
+// Update 修改字段组, 编码和表不能修改
+func (s *tableFieldGroupService) Update(c *gin.Context, group *model.TableFieldGroup) error {
+	origin, err := s.tableFieldGroupRepository.FindOne(group.ID)
+	if err != nil {
+		return fmt.Errorf("字段组 %d 不存在", group.ID)
+	}
+	if group.Code != "" && group.Code != origin.Code || group.TableCode != "" && group.TableCode != origin.TableCode {
+		return fmt.Errorf("字段组的编码和表不能修改")
+	}
+
+	// 未提交的属性取原值, 角色按提交的值保存, 为空表示不限制
+	merged := *origin
+	merged.Name = group.Name
+	merged.View = group.View
+	merged.Sort = group.Sort
+	merged.ViewRoles = group.ViewRoles
+	merged.EditRoles = group.EditRoles
+	merged.Description = group.Description
+	if group.Collapsible != "" {
+		merged.Collapsible = group.Collapsible
+	}
+	if group.Collapsed != "" {
+		merged.Collapsed = group.Collapsed
+	}
+	if group.Status != "" {
+		merged.Status = group.Status
+	}
+	merged.UpdatedBy = c.GetString("user_name")
+	if err := s.validate(&merged); err != nil {
+		return err
+	}
+	return s.tableFieldGroupRepository.Update(c, &merged)
+}
+
+// Delete 删除字段组, 仍有字段属于该组时不能删除
+func (s *tableFieldGroupService) Delete(c *gin.Context, id uint) error {
+	group, err := s.tableFieldGroupRepository.FindOne(id)
+	if err != nil {
+		return fmt.Errorf("字段组 %d 不存在", id)
+	}
+	fields, err := s.tableFieldRepository.Find("code", map[string]any{
+		"table_code": group.TableCode,
+		"group_name": []string{group.Code, group.Name},
+		"status":     "Normal",
+	})
+	if err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		return fmt.Errorf("字段组 %s 中还有 %d 个字段, 请先移出字段", group.Name, len(fields))
+	}
+	return s.tableFieldGroupRepository.Delete(c, id)
+}
+
+// validate 校验折叠设置和可查看、可修改的角色
+func (s *tableFieldGroupService) validate(group *model.TableFieldGroup) error {
+	if group.Collapsed == "Yes" && group.Collapsible != "Yes" {
+		return fmt.Errorf("默认折叠的字段组必须可折叠")
+	}
+
+	codes := append(model.GroupRoles(group.ViewRoles), model.GroupRoles(group.EditRoles)...)
+	if len(codes) == 0 {
+		return nil
+	}
+	roles, err := s.roleRepository.Find("code", map[string]any{"code": codes})
+	if err != nil {
+		return fmt.Errorf("获取角色失败: %v", err)
+	}
+	exists := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		exists[role.Code] = true
+	}
+	for _, code := range codes {
+		if !exists[code] {
+			return fmt.Errorf("角色 %s 不存在", code)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package service_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"piemdm/internal/model"
+	"piemdm/internal/service"
+	"piemdm/pkg/helper/sid"
+	"piemdm/pkg/jwt"
+	"piemdm/pkg/log"
+	mock_repository "piemdm/test/mocks/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableFieldService_GetTableFields_Groups(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fieldRepo := mock_repository.NewMockTableFieldRepository(ctrl)
+	groupRepo := mock_repository.NewMockTableFieldGroupRepository(ctrl)
+	userRoleRepo := mock_repository.NewMockUserRoleRepository(ctrl)
+	logger := &log.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s := service.NewTableFieldService(service.NewService(logger, &sid.Sid{}, &jwt.JWT{}), fieldRepo,
+		mock_repository.NewMockTableRepository(ctrl), mock_repository.NewMockSchemaVersionRepository(ctrl), groupRepo, userRoleRepo)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_id", "7")
+
+	fieldRepo.EXPECT().Find(gomock.Any(), gomock.Any()).Return([]*model.TableField{
+		{Code: "cost", Sort: 1, GroupName: "finance"},
+		{Code: "price", Sort: 2, GroupName: "sales"},
+		{Code: "name", Sort: 3},
+		{Code: "note", Sort: 4, GroupName: "备注"},
+		{Code: "discount", Sort: 5, GroupName: "销售信息"}, // 按组名关联的旧字段
+	}, nil).AnyTimes()
+	groupRepo.EXPECT().Find(map[string]any{"table_code": "product", "status": "Normal"}).Return([]*model.TableFieldGroup{
+		{Code: "sales", Name: "销售信息", Sort: 1, Collapsible: "Yes", EditRoles: "manager"},
+		{Code: "finance", Name: "财务信息", Sort: 2, ViewRoles: "finance"},
+	}, nil).AnyTimes()
+	userRoleRepo.EXPECT().FindRolesByUserID(uint(7)).Return([]model.Role{{Code: "sales"}}, nil).AnyTimes()
+
+	fields, err := s.GetTableFields(c, "product")
+	require.NoError(t, err)
+	var codes []string
+	for _, field := range fields {
+		if !field.IsSystem {
+			codes = append(codes, field.Code)
+		}
+	}
+	// 未分组在前, 字段组按顺序, 旧组名在后; 不能查看的财务信息不返回
+	assert.Equal(t, []string{"name", "price", "discount", "note"}, codes)
+	assert.Nil(t, fields[0].Group)
+	require.NotNil(t, fields[1].Group)
+	assert.Equal(t, "sales", fields[1].Group.Code)
+	assert.True(t, fields[1].Group.Collapsible)
+	assert.True(t, fields[1].Readonly)
+	assert.Equal(t, "sales", fields[2].Group.Code)
+	assert.Equal(t, &model.FieldGroupMetadata{Name: "备注", Editable: true}, fields[3].Group)
+	assert.False(t, fields[3].Readonly)
+
+	access, err := s.GetFieldAccess(c, "product")
+	require.NoError(t, err)
+	assert.True(t, access.Hidden["cost"])
+	assert.True(t, access.Readonly["price"])
+	assert.True(t, access.Editable("name"))
+	assert.False(t, access.Editable("discount"))
+
+	// 超级管理员不受字段组权限限制
+	admin, _ := gin.CreateTestContext(httptest.NewRecorder())
+	admin.Set("user_id", uint(1))
+	userRoleRepo.EXPECT().FindRolesByUserID(uint(1)).Return([]model.Role{{Code: "superuser"}}, nil).AnyTimes()
+	fields, err = s.GetTableFields(admin, "product")
+	require.NoError(t, err)
+	assert.Len(t, fields, 5+10)
+	access, err = s.GetFieldAccess(admin, "product")
+	require.NoError(t, err)
+	assert.Nil(t, access)
+}
+
+func TestTableFieldGroupService_Validate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	groupRepo := mock_repository.NewMockTableFieldGroupRepository(ctrl)
+	fieldRepo := mock_repository.NewMockTableFieldRepository(ctrl)
+	tableRepo := mock_repository.NewMockTableRepository(ctrl)
+	roleRepo := mock_repository.NewMockRoleRepository(ctrl)
+	logger := &log.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s := service.NewTableFieldGroupService(service.NewService(logger, &sid.Sid{}, &jwt.JWT{}), groupRepo, fieldRepo, tableRepo, roleRepo)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	tableRepo.EXPECT().Find("", map[string]any{"code": "product"}).Return([]*model.Table{{Code: "product"}}, nil).AnyTimes()
+	roleRepo.EXPECT().Find("code", gomock.Any()).Return([]*model.Role{{Code: "sales"}}, nil).AnyTimes()
+
+	err := s.Create(c, &model.TableFieldGroup{Code: "sales", Name: "销售信息", TableCode: "product", Collapsed: "Yes"})
+	assert.EqualError(t, err, "默认折叠的字段组必须可折叠")
+	err = s.Create(c, &model.TableFieldGroup{Code: "sales", Name: "销售信息", TableCode: "product", EditRoles: "sales, buyer"})
+	assert.EqualError(t, err, "角色 buyer 不存在")
+
+	group := &model.TableFieldGroup{Code: "sales", Name: "销售信息", TableCode: "product", ViewRoles: "sales"}
+	groupRepo.EXPECT().Create(c, group).Return(nil)
+	require.NoError(t, s.Create(c, group))
+	assert.Equal(t, "No", group.Collapsible)
+
+	// 组中还有字段时不能删除
+	group.ID = 3
+	groupRepo.EXPECT().FindOne(uint(3)).Return(group, nil).AnyTimes()
+	fieldRepo.EXPECT().Find("code", map[string]any{"table_code": "product", "group_name": []string{"sales", "销售信息"}, "status": "Normal"}).
+		Return([]*model.TableField{{Code: "price"}}, nil)
+	assert.Error(t, s.Delete(c, 3))
+}
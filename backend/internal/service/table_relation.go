@@ -0,0 +1,129 @@
+package service
+
+import (
+	"fmt"
+
+	"piemdm/internal/model"
+	"piemdm/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TableRelationService interface {
+	Get(id uint) (*model.TableRelation, error)
+	Find(where map[string]any) ([]*model.TableRelation, error)
+	List(page, pageSize int, total *int64, where map[string]any) ([]*model.TableRelation, error)
+	Create(c *gin.Context, relation *model.TableRelation) error
+	Update(c *gin.Context, relation *model.TableRelation) error
+	Delete(c *gin.Context, id uint) error
+}
+
+type tableRelationService struct {
+	*Service
+	tableRelationRepository repository.TableRelationRepository
+	tableFieldRepository    repository.TableFieldRepository
+	tableRepository         repository.TableRepository
+}
+
+func NewTableRelationService(
+	service *Service,
+	tableRelationRepository repository.TableRelationRepository,
+	tableFieldRepository repository.TableFieldRepository,
+	tableRepository repository.TableRepository,
+) TableRelationService {
+	return &tableRelationService{
+		Service:                 service,
+		tableRelationRepository: tableRelationRepository,
+		tableFieldRepository:    tableFieldRepository,
+		tableRepository:         tableRepository,
+	}
+}
+
+func (s *tableRelationService) Get(id uint) (*model.TableRelation, error) {
+	return s.tableRelationRepository.FindOne(id)
+}
+
+func (s *tableRelationService) Find(where map[string]any) ([]*model.TableRelation, error) {
+	return s.tableRelationRepository.Find(where)
+}
+
+func (s *tableRelationService) List(page, pageSize int, total *int64, where map[string]any) ([]*model.TableRelation, error) {
+	return s.tableRelationRepository.FindPage(page, pageSize, total, where)
+}
+
+// Create 新增关联视图, 模型和关联字段必须存在
+func (s *tableRelationService) Create(c *gin.Context, relation *model.TableRelation) error {
+	if relation.Status == "" {
+		relation.Status = "Normal"
+	}
+	relation.CreatedBy = c.GetString("user_name")
+	relation.UpdatedBy = relation.CreatedBy
+	if err := s.validate(relation); err != nil {
+		return err
+	}
+	return s.tableRelationRepository.Create(c, relation)
+}
+
+// Update 修改关联视图, 所属模型不能修改
+func (s *tableRelationService) Update(c *gin.Context, relation *model.TableRelation) error {
+	origin, err := s.tableRelationRepository.FindOne(relation.ID)
+	if err != nil {
+		return fmt.Errorf("关联视图 %d 不存在", relation.ID)
+	}
+	if relation.TableCode != "" && relation.TableCode != origin.TableCode {
+		return fmt.Errorf("关联视图所属的模型不能修改")
+	}
+
+	merged := *origin
+	merged.RelationTable = relation.RelationTable
+	merged.RelationName = relation.RelationName
+	merged.RelationCode = relation.RelationCode
+	merged.Sort = relation.Sort
+	if relation.Status != "" {
+		merged.Status = relation.Status
+	}
+	merged.UpdatedBy = c.GetString("user_name")
+	if err := s.validate(&merged); err != nil {
+		return err
+	}
+	return s.tableRelationRepository.Update(c, &merged)
+}
+
+func (s *tableRelationService) Delete(c *gin.Context, id uint) error {
+	if _, err := s.tableRelationRepository.FindOne(id); err != nil {
+		return fmt.Errorf("关联视图 %d 不存在", id)
+	}
+	return s.tableRelationRepository.Delete(c, id)
+}
+
+// validate 校验模型、关联字段, 同一模型到同一关联模型只能有一个关联视图 (导出时按关联模型编码分组)
+func (s *tableRelationService) validate(relation *model.TableRelation) error {
+	for _, code := range []string{relation.TableCode, relation.RelationTable} {
+		tables, err := s.tableRepository.Find("", map[string]any{"code": code})
+		if err != nil || len(tables) == 0 {
+			return fmt.Errorf("表 %s 不存在", code)
+		}
+	}
+
+	fields, err := s.tableFieldRepository.Find("", map[string]any{"table_code": relation.RelationTable, "status": "Normal"})
+	if err != nil {
+		return fmt.Errorf("获取表字段失败: %v", err)
+	}
+	if _, err := relationReference(relation, fields); err != nil {
+		return err
+	}
+
+	existing, err := s.tableRelationRepository.Find(map[string]any{
+		"table_code":     relation.TableCode,
+		"relation_table": relation.RelationTable,
+	})
+	if err != nil {
+		return err
+	}
+	for _, item := range existing {
+		if item.ID != relation.ID {
+			return fmt.Errorf("表 %s 已有关联模型 %s 的关联视图 %s", relation.TableCode, relation.RelationTable, item.RelationName)
+		}
+	}
+	return nil
+}
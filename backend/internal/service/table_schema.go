@@ -0,0 +1,186 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"piemdm/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PublicOptions 发布表结构的选项
+type PublicOptions struct {
+	Checksum      string `json:"checksum"`        // 预览返回的计划摘要, 不为空时校验计划未变化
+	AllowDataLoss bool   `json:"allow_data_loss"` // 确认执行会丢失数据的变更
+}
+
+// SchemaPlanError 变更计划不能发布: 计划已变化、会丢失数据但未确认或存在冲突
+type SchemaPlanError struct {
+	Reason string            `json:"reason"`
+	Plan   *model.SchemaPlan `json:"plan"`
+}
+
+func (e *SchemaPlanError) Error() string {
+	return e.Reason
+}
+
+// schemaTables 发布时同步结构的表: 数据表和草稿表, 日志表结构固定
+func schemaTables(tableCode string) []string {
+	return []string{"t_" + tableCode, "t_" + tableCode + "_draft"}
+}
+
+// publicFields 发布使用的字段定义, 树形表缺少的树形字段一并返回, 发布时保存
+func (s *tableFieldService) publicFields(tableCode string) ([]*model.TableField, []*model.TableField, error) {
+	fields, err := s.tableFieldRepository.Find("", map[string]any{"table_code": tableCode, "status": "Normal"})
+	if err != nil {
+		return nil, nil, err
+	}
+	tables, err := s.tableRepository.Find("", map[string]any{"code": tableCode, "status": "Normal"})
+	if err != nil || len(tables) == 0 {
+		return fields, nil, nil
+	}
+	all, treeFields := withTreeFields(tableCode, tables[0].DisplayMode, fields)
+	return all, treeFields, nil
+}
+
+// withTreeFields 树形表缺少树形字段时补充, 返回全部字段和补充的字段
+func withTreeFields(tableCode, displayMode string, fields []*model.TableField) ([]*model.TableField, []*model.TableField) {
+	if displayMode != "Tree" {
+		return fields, nil
+	}
+	for _, field := range fields {
+		if field.Code == "parent_id" {
+			return fields, nil
+		}
+	}
+	treeFields := buildTreeFields(tableCode, fields)
+	return append(fields, treeFields...), treeFields
+}
+
+// PlanPublic 预览发布表结构的变更计划, 不修改数据库
+func (s *tableFieldService) PlanPublic(tableCode string) (*model.SchemaPlan, error) {
+	fields, _, err := s.publicFields(tableCode)
+	if err != nil {
+		return nil, fmt.Errorf("获取表字段失败: %v", err)
+	}
+	return s.planSchema(tableCode, fields)
+}
+
+// PlanFields 按给定的字段定义预览变更计划, 用于字段保存前预览, 如导入模型定义
+func (s *tableFieldService) PlanFields(tableCode, displayMode string, fields []*model.TableField) (*model.SchemaPlan, error) {
+	fields, _ = withTreeFields(tableCode, displayMode, fields)
+	return s.planSchema(tableCode, fields)
+}
+
+func (s *tableFieldService) planSchema(tableCode string, fields []*model.TableField) (*model.SchemaPlan, error) {
+	plan := &model.SchemaPlan{TableCode: tableCode, Version: 1}
+	latest, err := s.schemaVersionRepository.Latest(tableCode)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil {
+		plan.Version = latest.Version + 1
+	}
+	for _, tableName := range schemaTables(tableCode) {
+		tablePlan, err := s.tableFieldRepository.PlanSchema(tableName, fields)
+		if err != nil {
+			return nil, fmt.Errorf("生成 %s 的变更计划失败: %v", tableName, err)
+		}
+		for _, change := range tablePlan.Changes {
+			plan.DataLoss = plan.DataLoss || change.Lost > 0
+			plan.Conflicts = plan.Conflicts || change.Conflicts > 0
+		}
+		plan.Tables = append(plan.Tables, tablePlan)
+	}
+	plan.Checksum = schemaChecksum(plan)
+	return plan, nil
+}
+
+// schemaChecksum 计划中变更内容的摘要, 不含行数, 数据变化不影响摘要
+func schemaChecksum(plan *model.SchemaPlan) string {
+	type change struct {
+		Table, Kind, Column, From, Index, Before, After string
+	}
+	var changes []change
+	for _, table := range plan.Tables {
+		for _, c := range table.Changes {
+			changes = append(changes, change{table.Table, c.Kind, c.Column, c.From, c.Index, c.Before, c.After})
+		}
+	}
+	data, _ := json.Marshal(changes)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Public 按变更计划发布表结构并记录表结构版本
+// 计划会丢失数据时需要确认, 存在冲突 (如唯一索引的重复值) 时不能发布
+func (s *tableFieldService) Public(c *gin.Context, tableCode string, options *PublicOptions) (*model.SchemaVersion, error) {
+	if options == nil {
+		options = &PublicOptions{}
+	}
+	fields, treeFields, err := s.publicFields(tableCode)
+	if err != nil {
+		return nil, fmt.Errorf("获取表字段失败: %v", err)
+	}
+	plan, err := s.planSchema(tableCode, fields)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case options.Checksum != "" && options.Checksum != plan.Checksum:
+		return nil, &SchemaPlanError{Reason: "表结构变更计划已变化, 请重新预览", Plan: plan}
+	case plan.Conflicts:
+		return nil, &SchemaPlanError{Reason: "存在冲突的数据, 不能发布", Plan: plan}
+	case plan.DataLoss && !options.AllowDataLoss:
+		return nil, &SchemaPlanError{Reason: "发布会丢失数据, 请确认后发布", Plan: plan}
+	}
+
+	// 树形表自动创建树形字段
+	for _, field := range treeFields {
+		if err := s.tableFieldRepository.Create(c, field); err != nil {
+			return nil, fmt.Errorf("failed to create field %s: %w", field.Code, err)
+		}
+	}
+
+	var version *model.SchemaVersion
+	if plan.HasChanges() {
+		data, _ := json.Marshal(plan)
+		version = &model.SchemaVersion{
+			TableCode: tableCode,
+			Version:   plan.Version,
+			Checksum:  plan.Checksum,
+			Plan:      data,
+			Status:    model.SchemaVersionApplied,
+			CreatedBy: c.GetString("user_name"),
+		}
+		if err := s.tableFieldRepository.ApplySchema(plan.Tables, fields, version); err != nil {
+			version.ID = 0
+			version.Status = model.SchemaVersionFailed
+			version.Error = err.Error()
+			if recordErr := s.schemaVersionRepository.Create(version); recordErr != nil {
+				s.logger.Error("记录表结构版本失败", "table", tableCode, "err", recordErr)
+			}
+			return nil, fmt.Errorf("发布表结构失败: %w", err)
+		}
+	} else if version, err = s.schemaVersionRepository.Latest(tableCode); err != nil {
+		return nil, err
+	}
+
+	// 日志表结构固定, 按模型同步
+	if err := s.tableFieldRepository.Public("t_"+tableCode+"_log", model.EntityLog{}); err != nil {
+		return nil, fmt.Errorf("failed to publish log table: %w", err)
+	}
+	// 生成多对多字段的关联表
+	if _, err := s.tableFieldRepository.PublicLinks(tableCode); err != nil {
+		return nil, fmt.Errorf("failed to publish link tables: %w", err)
+	}
+	return version, nil
+}
+
+// ListSchemaVersions 分页查询表结构版本
+func (s *tableFieldService) ListSchemaVersions(page, pageSize int, total *int64, where map[string]any) ([]*model.SchemaVersion, error) {
+	return s.schemaVersionRepository.FindPage(page, pageSize, total, where)
+}
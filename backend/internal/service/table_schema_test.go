@@ -0,0 +1,77 @@
+package service_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"piemdm/internal/model"
+	"piemdm/internal/service"
+	"piemdm/pkg/helper/sid"
+	"piemdm/pkg/jwt"
+	"piemdm/pkg/log"
+	mock_repository "piemdm/test/mocks/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableFieldService_Public(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fieldRepo := mock_repository.NewMockTableFieldRepository(ctrl)
+	tableRepo := mock_repository.NewMockTableRepository(ctrl)
+	versionRepo := mock_repository.NewMockSchemaVersionRepository(ctrl)
+	logger := &log.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s := service.NewTableFieldService(service.NewService(logger, &sid.Sid{}, &jwt.JWT{}), fieldRepo, tableRepo, versionRepo,
+		mock_repository.NewMockTableFieldGroupRepository(ctrl), mock_repository.NewMockUserRoleRepository(ctrl))
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_name", "tester")
+
+	fields := []*model.TableField{{TableCode: "product", Code: "qty", Type: "Number"}}
+	fieldRepo.EXPECT().Find("", map[string]any{"table_code": "product", "status": "Normal"}).Return(fields, nil).AnyTimes()
+	tableRepo.EXPECT().Find("", map[string]any{"code": "product", "status": "Normal"}).
+		Return([]*model.Table{{Code: "product", DisplayMode: "List"}}, nil).AnyTimes()
+	versionRepo.EXPECT().Latest("product").Return(&model.SchemaVersion{TableCode: "product", Version: 3}, nil).AnyTimes()
+	fieldRepo.EXPECT().PlanSchema("t_product", fields).Return(&model.SchemaTablePlan{
+		Table: "t_product", Exists: true, Rows: 2,
+		Changes: []*model.SchemaChange{{Kind: model.SchemaConvertColumn, Column: "qty", Before: "varchar(32)", After: "bigint", Affected: 2, Lost: 1}},
+	}, nil).AnyTimes()
+	fieldRepo.EXPECT().PlanSchema("t_product_draft", fields).Return(&model.SchemaTablePlan{
+		Table: "t_product_draft", Exists: true,
+	}, nil).AnyTimes()
+
+	plan, err := s.PlanPublic("product")
+	require.NoError(t, err)
+	assert.Equal(t, 4, plan.Version)
+	assert.True(t, plan.DataLoss)
+	assert.NotEmpty(t, plan.Checksum)
+
+	// 计划已变化或未确认丢失数据时不发布
+	_, err = s.Public(c, "product", &service.PublicOptions{Checksum: "stale", AllowDataLoss: true})
+	var planErr *service.SchemaPlanError
+	require.ErrorAs(t, err, &planErr)
+	assert.Equal(t, plan.Checksum, planErr.Plan.Checksum)
+	_, err = s.Public(c, "product", &service.PublicOptions{Checksum: plan.Checksum})
+	require.ErrorAs(t, err, &planErr)
+
+	// 确认后执行计划并记录版本
+	fieldRepo.EXPECT().ApplySchema(gomock.Len(2), fields, gomock.Any()).
+		DoAndReturn(func(_ []*model.SchemaTablePlan, _ []*model.TableField, version *model.SchemaVersion) error {
+			assert.Equal(t, 4, version.Version)
+			assert.Equal(t, model.SchemaVersionApplied, version.Status)
+			assert.Equal(t, "tester", version.CreatedBy)
+			return nil
+		})
+	fieldRepo.EXPECT().Public("t_product_log", model.EntityLog{}).Return(nil)
+	fieldRepo.EXPECT().PublicLinks("product").Return(nil, nil)
+
+	version, err := s.Public(c, "product", &service.PublicOptions{Checksum: plan.Checksum, AllowDataLoss: true})
+	require.NoError(t, err)
+	assert.Equal(t, plan.Checksum, version.Checksum)
+}
@@ -0,0 +1,456 @@
+package formula
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrDivisionByZero 除数为 0
+var ErrDivisionByZero = errors.New("除数不能为 0")
+
+// 日期值的文本格式, 与导入导出一致
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02 15:04:05"
+)
+
+// dateLayouts 字符串转换为日期时支持的格式
+var dateLayouts = []string{
+	dateTimeLayout,
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+	dateLayout,
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+}
+
+// now 当前时间, 测试时替换
+var now = time.Now
+
+type node interface {
+	eval(vars map[string]any) (any, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+func (n *literalNode) eval(map[string]any) (any, error) {
+	return n.value, nil
+}
+
+type fieldNode struct {
+	name string
+}
+
+func (n *fieldNode) eval(vars map[string]any) (any, error) {
+	return normalize(vars[n.name]), nil
+}
+
+type negNode struct {
+	x node
+}
+
+func (n *negNode) eval(vars map[string]any) (any, error) {
+	x, err := n.x.eval(vars)
+	if err != nil || x == nil {
+		return nil, err
+	}
+	v, err := ToNumber(x)
+	if err != nil {
+		return nil, err
+	}
+	return -v, nil
+}
+
+type notNode struct {
+	x node
+}
+
+func (n *notNode) eval(vars map[string]any) (any, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(x), nil
+}
+
+// logicalNode AND / OR, 短路求值
+type logicalNode struct {
+	and  bool
+	x, y node
+}
+
+func (n *logicalNode) eval(vars map[string]any) (any, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(x) != n.and {
+		return !n.and, nil
+	}
+	y, err := n.y.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(y), nil
+}
+
+type concatNode struct {
+	x, y node
+}
+
+func (n *concatNode) eval(vars map[string]any) (any, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	y, err := n.y.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return ToText(x) + ToText(y), nil
+}
+
+// arithNode 算术运算, 任一操作数为空时结果为空
+type arithNode struct {
+	op   string
+	x, y node
+}
+
+func (n *arithNode) eval(vars map[string]any) (any, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	y, err := n.y.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return arith(n.op, x, y)
+}
+
+func arith(op string, x, y any) (any, error) {
+	if x == nil || y == nil {
+		return nil, nil
+	}
+
+	// 日期运算: 日期 ± 天数, 日期 - 日期
+	xt, xIsTime := x.(time.Time)
+	yt, yIsTime := y.(time.Time)
+	switch {
+	case xIsTime && yIsTime:
+		if op != "-" {
+			return nil, fmt.Errorf("日期之间不支持 %s 运算", op)
+		}
+		return xt.Sub(yt).Hours() / 24, nil
+	case xIsTime || yIsTime:
+		if op != "+" && !(op == "-" && xIsTime) {
+			return nil, fmt.Errorf("日期不支持 %s 运算", op)
+		}
+		t, days := xt, y
+		if yIsTime {
+			t, days = yt, x
+		}
+		d, err := ToNumber(days)
+		if err != nil {
+			return nil, err
+		}
+		if op == "-" {
+			d = -d
+		}
+		return addDays(t, d), nil
+	}
+
+	a, err := ToNumber(x)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ToNumber(y)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/":
+		if b == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return a / b, nil
+	case "%":
+		if b == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return math.Mod(a, b), nil
+	}
+	return nil, fmt.Errorf("不支持的运算符 %s", op)
+}
+
+// addDays 加减天数, 小数部分按小时折算
+func addDays(t time.Time, days float64) time.Time {
+	whole := math.Trunc(days)
+	return t.AddDate(0, 0, int(whole)).Add(time.Duration((days - whole) * 24 * float64(time.Hour)))
+}
+
+type compareNode struct {
+	op   string
+	x, y node
+}
+
+func (n *compareNode) eval(vars map[string]any) (any, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	y, err := n.y.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return compare(n.op, x, y)
+}
+
+// compare 比较运算: 空值只等于空值或空字符串, 与其它值比较大小时结果为 false
+func compare(op string, x, y any) (bool, error) {
+	equality := op == "=" || op == "==" || op == "!=" || op == "<>"
+	if isBlank(x) || isBlank(y) {
+		if !equality {
+			return false, nil
+		}
+		equal := isBlank(x) && isBlank(y)
+		return equal == (op == "=" || op == "=="), nil
+	}
+
+	c, err := compareValues(x, y)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case "=", "==":
+		return c == 0, nil
+	case "!=", "<>":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return false, fmt.Errorf("不支持的比较运算符 %s", op)
+}
+
+// compareValues 比较两个非空值: 日期按时间, 数字按数值, 其它按文本
+func compareValues(x, y any) (int, error) {
+	_, xIsTime := x.(time.Time)
+	_, yIsTime := y.(time.Time)
+	if xIsTime || yIsTime {
+		a, err := ToTime(x)
+		if err != nil {
+			return 0, err
+		}
+		b, err := ToTime(y)
+		if err != nil {
+			return 0, err
+		}
+		return a.Compare(b), nil
+	}
+
+	if a, err := ToNumber(x); err == nil {
+		if b, err := ToNumber(y); err == nil {
+			switch {
+			case a < b:
+				return -1, nil
+			case a > b:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	return strings.Compare(ToText(x), ToText(y)), nil
+}
+
+type callNode struct {
+	name string
+	fn   *function
+	args []node
+}
+
+func (n *callNode) eval(vars map[string]any) (any, error) {
+	// IF 只计算命中的分支, 避免未命中分支中的除零等错误
+	if n.name == "IF" {
+		cond, err := n.args[0].eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(cond) {
+			return n.args[1].eval(vars)
+		}
+		if len(n.args) > 2 {
+			return n.args[2].eval(vars)
+		}
+		return nil, nil
+	}
+
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := n.fn.call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return v, nil
+}
+
+// normalize 将字段值统一为 nil、float64、string、bool 或 time.Time
+func normalize(value any) any {
+	switch v := value.(type) {
+	case nil, float64, string, bool, time.Time:
+		return v
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case []byte:
+		return string(v)
+	case int:
+		return float64(v)
+	case int8:
+		return float64(v)
+	case int16:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case uint8:
+		return float64(v)
+	case uint16:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// isBlank 空值或空字符串
+func isBlank(value any) bool {
+	if value == nil {
+		return true
+	}
+	s, ok := value.(string)
+	return ok && strings.TrimSpace(s) == ""
+}
+
+// truthy 条件判断: 空值、false、0、空字符串为假
+func truthy(value any) bool {
+	switch v := normalize(value).(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		s := strings.TrimSpace(v)
+		return s != "" && !strings.EqualFold(s, "false") && s != "0"
+	case time.Time:
+		return !v.IsZero()
+	}
+	return true
+}
+
+// ToNumber 转换为数字, 字符串按数字解析, TRUE 为 1, FALSE 为 0
+func ToNumber(value any) (float64, error) {
+	switch v := normalize(value).(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" {
+			return 0, nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q 不是数字", v)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("%v 不是数字", value)
+}
+
+// ToText 转换为文本: 数字不带多余的 0, 日期为 2006-01-02 或 2006-01-02 15:04:05, 空值为空字符串
+func ToText(value any) string {
+	switch v := normalize(value).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case time.Time:
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+			return v.Format(dateLayout)
+		}
+		return v.Format(dateTimeLayout)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// ToTime 转换为日期, 字符串支持 2006-01-02、2006-01-02 15:04:05 及 RFC3339 等格式
+func ToTime(value any) (time.Time, error) {
+	switch v := normalize(value).(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		s := strings.TrimSpace(v)
+		for _, layout := range dateLayouts {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%q 不是日期", v)
+	}
+	return time.Time{}, fmt.Errorf("%v 不是日期", value)
+}
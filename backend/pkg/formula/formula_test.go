@@ -0,0 +1,149 @@
+package formula
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func eval(t *testing.T, source string, vars map[string]any) any {
+	t.Helper()
+	expr, err := Parse(source)
+	require.NoError(t, err, source)
+	result, err := expr.Eval(vars)
+	require.NoError(t, err, source)
+	return result
+}
+
+func TestEval(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 3, 15, 10, 30, 0, 0, time.Local) }
+	defer func() { now = time.Now }()
+
+	orderDate := time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local)
+	vars := map[string]any{
+		"price":      int64(12),
+		"quantity":   "3",
+		"discount":   0.1,
+		"first_name": "Ada",
+		"last_name":  []byte("Lovelace"),
+		"order_date": orderDate,
+		"ship_date":  "2024-02-10",
+		"empty":      nil,
+	}
+
+	tests := []struct {
+		source string
+		want   any
+	}{
+		// 算术与优先级
+		{"price * quantity * (1 - discount)", 32.4},
+		{"1 + 2 * 3 - 4 / 2", 5.0},
+		{"-price + 2", -10.0},
+		{"7 % 3", 1.0},
+		{"price + empty", nil},
+		// 字符串连接
+		{`first_name & " " & last_name`, "Ada Lovelace"},
+		{`CONCAT(first_name, "-", price)`, "Ada-12"},
+		{`"x" & empty`, "x"},
+		{`UPPER(LEFT(last_name, 4))`, "LOVE"},
+		{`MID("abcdef", 2, 3)`, "bcd"},
+		{`LEN("数据")`, 2.0},
+		// 条件与比较
+		{`IF(price > 10, "high", "low")`, "high"},
+		{`IF(price > 10 AND quantity < 2, "a", "b")`, "b"},
+		{`IF(NOT ISBLANK(empty), 1, 0)`, 0.0},
+		{`IF(empty = "", "blank")`, "blank"},
+		{`IF(FALSE, 1 / 0, 2)`, 2.0},
+		{`price = 12 && first_name <> "Bob"`, true},
+		{`COALESCE(empty, first_name)`, "Ada"},
+		// 数字函数
+		{"ROUND(2 / 3, 2)", 0.67},
+		{"ROUND(-2.5)", -3.0},
+		{"MAX(price, quantity, empty)", 12.0},
+		{"SUM(price, quantity)", 15.0},
+		// 日期运算
+		{"order_date + 10", time.Date(2024, 2, 10, 0, 0, 0, 0, time.Local)},
+		{`DATEADD(order_date, 1, "month")`, time.Date(2024, 2, 29, 0, 0, 0, 0, time.Local)},
+		{`DATEDIFF(order_date, ship_date)`, 10.0},
+		{`DATEDIFF(order_date, TODAY(), "months")`, 1.0},
+		{`DATEDIFF(DATE(2020, 2, 29), DATE(2024, 2, 28), "year")`, 3.0},
+		{`YEAR(order_date) & "-" & MONTH(order_date)`, "2024-1"},
+		{"TODAY()", time.Date(2024, 3, 15, 0, 0, 0, 0, time.Local)},
+		{"order_date < ship_date", true},
+		{`TEXT(order_date)`, "2024-01-31"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got := eval(t, tt.source, vars)
+			if f, ok := tt.want.(float64); ok {
+				assert.InDelta(t, f, got, 1e-9)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEval_DateMinusTextIsError(t *testing.T) {
+	// 字符串不会自动当作日期参与算术运算, 需要先转换为日期字段
+	expr, err := Parse("ship_date - 1")
+	require.NoError(t, err)
+	_, err = expr.Eval(map[string]any{"ship_date": "2024-02-10"})
+	assert.Error(t, err)
+
+	got := eval(t, "order_date - ship_date", map[string]any{
+		"order_date": time.Date(2024, 2, 10, 12, 0, 0, 0, time.Local),
+		"ship_date":  time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local),
+	})
+	assert.InDelta(t, 9.5, got, 1e-9)
+}
+
+func TestEval_Errors(t *testing.T) {
+	tests := []struct {
+		source string
+		vars   map[string]any
+	}{
+		{"a / b", map[string]any{"a": 1, "b": 0}},
+		{"a * 2", map[string]any{"a": "abc"}},
+		{`DATEADD(a, 1, "century")`, map[string]any{"a": time.Now()}},
+		{"YEAR(a)", map[string]any{"a": "not a date"}},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.source)
+		require.NoError(t, err, tt.source)
+		_, err = expr.Eval(tt.vars)
+		assert.Error(t, err, tt.source)
+	}
+}
+
+func TestParse(t *testing.T) {
+	expr, err := Parse(`IF(amount > limit, amount - limit, 0) & unit & amount`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"amount", "limit", "unit"}, expr.Refs())
+
+	for _, source := range []string{
+		"",
+		"1 +",
+		"(a + b",
+		"a b",
+		`"unterminated`,
+		"UNKNOWN(a)",
+		"IF(a)",
+		"ROUND(a, 1, 2)",
+		"a # b",
+		"AND",
+	} {
+		_, err := Parse(source)
+		assert.Error(t, err, source)
+	}
+}
+
+func TestToText(t *testing.T) {
+	assert.Equal(t, "1.5", ToText(1.5))
+	assert.Equal(t, "3", ToText(int64(3)))
+	assert.Equal(t, "TRUE", ToText(true))
+	assert.Equal(t, "", ToText(nil))
+	assert.Equal(t, "2024-01-02 03:04:05", ToText(time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)))
+}
@@ -0,0 +1,351 @@
+package formula
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// function 内置函数, maxArgs 为 -1 表示参数个数不限
+type function struct {
+	minArgs int
+	maxArgs int
+	call    func(args []any) (any, error)
+}
+
+// functions 内置函数, 函数名为大写
+//
+//	逻辑: IF(条件, 值1[, 值2]) AND(...) OR(...) NOT(x) ISBLANK(x) COALESCE(...)
+//	文本: CONCAT(...) UPPER LOWER TRIM LEN LEFT(s, n) RIGHT(s, n) MID(s, 开始, 长度) SUBSTITUTE(s, 旧, 新) CONTAINS(s, 子串) TEXT(x)
+//	数字: ROUND(x[, 小数位]) FLOOR CEIL ABS MOD(a, b) POWER(a, b) MIN(...) MAX(...) SUM(...) VALUE(s)
+//	日期: TODAY() NOW() DATE(年, 月, 日) YEAR MONTH DAY DATEADD(日期, 数量[, 单位]) DATEDIFF(开始, 结束[, 单位])
+//	日期单位: year month week day hour minute, 默认 day
+var functions = map[string]*function{
+	// IF 在 callNode 中按条件只计算一个分支
+	"IF": {minArgs: 2, maxArgs: 3},
+	"AND": {minArgs: 1, maxArgs: -1, call: func(args []any) (any, error) {
+		for _, arg := range args {
+			if !truthy(arg) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}},
+	"OR": {minArgs: 1, maxArgs: -1, call: func(args []any) (any, error) {
+		for _, arg := range args {
+			if truthy(arg) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}},
+	"NOT": {minArgs: 1, maxArgs: 1, call: func(args []any) (any, error) {
+		return !truthy(args[0]), nil
+	}},
+	"ISBLANK": {minArgs: 1, maxArgs: 1, call: func(args []any) (any, error) {
+		return isBlank(args[0]), nil
+	}},
+	"COALESCE": {minArgs: 1, maxArgs: -1, call: func(args []any) (any, error) {
+		for _, arg := range args {
+			if !isBlank(arg) {
+				return arg, nil
+			}
+		}
+		return nil, nil
+	}},
+
+	"CONCAT": {minArgs: 1, maxArgs: -1, call: func(args []any) (any, error) {
+		var sb strings.Builder
+		for _, arg := range args {
+			sb.WriteString(ToText(arg))
+		}
+		return sb.String(), nil
+	}},
+	"UPPER": textFunc(strings.ToUpper),
+	"LOWER": textFunc(strings.ToLower),
+	"TRIM":  textFunc(strings.TrimSpace),
+	"TEXT": {minArgs: 1, maxArgs: 1, call: func(args []any) (any, error) {
+		return ToText(args[0]), nil
+	}},
+	"LEN": {minArgs: 1, maxArgs: 1, call: func(args []any) (any, error) {
+		return float64(len([]rune(ToText(args[0])))), nil
+	}},
+	"LEFT": {minArgs: 2, maxArgs: 2, call: func(args []any) (any, error) {
+		runes := []rune(ToText(args[0]))
+		n, err := intArg(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return string(runes[:clamp(n, 0, len(runes))]), nil
+	}},
+	"RIGHT": {minArgs: 2, maxArgs: 2, call: func(args []any) (any, error) {
+		runes := []rune(ToText(args[0]))
+		n, err := intArg(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return string(runes[len(runes)-clamp(n, 0, len(runes)):]), nil
+	}},
+	"MID": {minArgs: 3, maxArgs: 3, call: func(args []any) (any, error) {
+		runes := []rune(ToText(args[0]))
+		start, err := intArg(args[1])
+		if err != nil {
+			return nil, err
+		}
+		n, err := intArg(args[2])
+		if err != nil {
+			return nil, err
+		}
+		from := clamp(start-1, 0, len(runes))
+		return string(runes[from:clamp(from+n, from, len(runes))]), nil
+	}},
+	"SUBSTITUTE": {minArgs: 3, maxArgs: 3, call: func(args []any) (any, error) {
+		old := ToText(args[1])
+		if old == "" {
+			return ToText(args[0]), nil
+		}
+		return strings.ReplaceAll(ToText(args[0]), old, ToText(args[2])), nil
+	}},
+	"CONTAINS": {minArgs: 2, maxArgs: 2, call: func(args []any) (any, error) {
+		return strings.Contains(ToText(args[0]), ToText(args[1])), nil
+	}},
+
+	"ROUND": {minArgs: 1, maxArgs: 2, call: func(args []any) (any, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		x, err := ToNumber(args[0])
+		if err != nil {
+			return nil, err
+		}
+		digits := 0
+		if len(args) > 1 {
+			if digits, err = intArg(args[1]); err != nil {
+				return nil, err
+			}
+		}
+		return Round(x, digits), nil
+	}},
+	"FLOOR": numberFunc(math.Floor),
+	"CEIL":  numberFunc(math.Ceil),
+	"ABS":   numberFunc(math.Abs),
+	"VALUE": numberFunc(func(x float64) float64 { return x }),
+	"MOD": {minArgs: 2, maxArgs: 2, call: func(args []any) (any, error) {
+		return arith("%", args[0], args[1])
+	}},
+	"POWER": {minArgs: 2, maxArgs: 2, call: func(args []any) (any, error) {
+		if args[0] == nil || args[1] == nil {
+			return nil, nil
+		}
+		x, err := ToNumber(args[0])
+		if err != nil {
+			return nil, err
+		}
+		y, err := ToNumber(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return math.Pow(x, y), nil
+	}},
+	"MIN": aggregateFunc(func(acc, x float64) float64 { return math.Min(acc, x) }),
+	"MAX": aggregateFunc(func(acc, x float64) float64 { return math.Max(acc, x) }),
+	"SUM": aggregateFunc(func(acc, x float64) float64 { return acc + x }),
+
+	"TODAY": {minArgs: 0, maxArgs: 0, call: func([]any) (any, error) {
+		t := now()
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()), nil
+	}},
+	"NOW": {minArgs: 0, maxArgs: 0, call: func([]any) (any, error) {
+		return now().Truncate(time.Second), nil
+	}},
+	"DATE": {minArgs: 3, maxArgs: 3, call: func(args []any) (any, error) {
+		parts := make([]int, 3)
+		for i, arg := range args {
+			if arg == nil {
+				return nil, nil
+			}
+			n, err := intArg(arg)
+			if err != nil {
+				return nil, err
+			}
+			parts[i] = n
+		}
+		return time.Date(parts[0], time.Month(parts[1]), parts[2], 0, 0, 0, 0, time.Local), nil
+	}},
+	"YEAR":  datePartFunc(func(t time.Time) int { return t.Year() }),
+	"MONTH": datePartFunc(func(t time.Time) int { return int(t.Month()) }),
+	"DAY":   datePartFunc(func(t time.Time) int { return t.Day() }),
+	"DATEADD": {minArgs: 2, maxArgs: 3, call: func(args []any) (any, error) {
+		if args[0] == nil || args[1] == nil {
+			return nil, nil
+		}
+		t, err := ToTime(args[0])
+		if err != nil {
+			return nil, err
+		}
+		n, err := ToNumber(args[1])
+		if err != nil {
+			return nil, err
+		}
+		unit, err := dateUnit(args, 2)
+		if err != nil {
+			return nil, err
+		}
+		switch unit {
+		case "year":
+			return addMonths(t, int(n)*12), nil
+		case "month":
+			return addMonths(t, int(n)), nil
+		case "week":
+			return addDays(t, n*7), nil
+		case "hour":
+			return t.Add(time.Duration(n * float64(time.Hour))), nil
+		case "minute":
+			return t.Add(time.Duration(n * float64(time.Minute))), nil
+		}
+		return addDays(t, n), nil
+	}},
+	"DATEDIFF": {minArgs: 2, maxArgs: 3, call: func(args []any) (any, error) {
+		if args[0] == nil || args[1] == nil {
+			return nil, nil
+		}
+		start, err := ToTime(args[0])
+		if err != nil {
+			return nil, err
+		}
+		end, err := ToTime(args[1])
+		if err != nil {
+			return nil, err
+		}
+		unit, err := dateUnit(args, 2)
+		if err != nil {
+			return nil, err
+		}
+		return dateDiff(start, end, unit), nil
+	}},
+}
+
+func textFunc(fn func(string) string) *function {
+	return &function{minArgs: 1, maxArgs: 1, call: func(args []any) (any, error) {
+		return fn(ToText(args[0])), nil
+	}}
+}
+
+// numberFunc 单参数数值函数, 空值返回空
+func numberFunc(fn func(float64) float64) *function {
+	return &function{minArgs: 1, maxArgs: 1, call: func(args []any) (any, error) {
+		if isBlank(args[0]) {
+			return nil, nil
+		}
+		x, err := ToNumber(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return fn(x), nil
+	}}
+}
+
+// aggregateFunc 多参数聚合函数, 忽略空值, 全部为空时返回空
+func aggregateFunc(fn func(acc, x float64) float64) *function {
+	return &function{minArgs: 1, maxArgs: -1, call: func(args []any) (any, error) {
+		var result any
+		for _, arg := range args {
+			if isBlank(arg) {
+				continue
+			}
+			x, err := ToNumber(arg)
+			if err != nil {
+				return nil, err
+			}
+			if result == nil {
+				result = x
+				continue
+			}
+			result = fn(result.(float64), x)
+		}
+		return result, nil
+	}}
+}
+
+// datePartFunc 取日期的年、月、日, 空值返回空
+func datePartFunc(fn func(time.Time) int) *function {
+	return &function{minArgs: 1, maxArgs: 1, call: func(args []any) (any, error) {
+		if isBlank(args[0]) {
+			return nil, nil
+		}
+		t, err := ToTime(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return float64(fn(t)), nil
+	}}
+}
+
+// dateUnit 读取日期单位参数, 默认 day
+func dateUnit(args []any, i int) (string, error) {
+	if len(args) <= i || isBlank(args[i]) {
+		return "day", nil
+	}
+	unit := strings.ToLower(strings.TrimSpace(ToText(args[i])))
+	unit = strings.TrimSuffix(unit, "s")
+	switch unit {
+	case "year", "month", "week", "day", "hour", "minute":
+		return unit, nil
+	}
+	return "", fmt.Errorf("不支持的日期单位 %q", ToText(args[i]))
+}
+
+// dateDiff 两个日期相差的完整单位数, end 早于 start 时为负数
+func dateDiff(start, end time.Time, unit string) float64 {
+	switch unit {
+	case "year", "month":
+		sign := 1
+		if end.Before(start) {
+			start, end, sign = end, start, -1
+		}
+		months := (end.Year()-start.Year())*12 + int(end.Month()) - int(start.Month())
+		// 不足一个月的部分不计入
+		if addMonths(start, months).After(end) {
+			months--
+		}
+		if unit == "year" {
+			return float64(sign * (months / 12))
+		}
+		return float64(sign * months)
+	case "week":
+		return math.Trunc(end.Sub(start).Hours() / 24 / 7)
+	case "hour":
+		return math.Trunc(end.Sub(start).Hours())
+	case "minute":
+		return math.Trunc(end.Sub(start).Minutes())
+	}
+	return math.Trunc(end.Sub(start).Hours() / 24)
+}
+
+// addMonths 加减月数, 目标月份没有对应日期时取月末, 如 1 月 31 日加 1 个月为 2 月末
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	target := first.AddDate(0, months, 0)
+	lastDay := target.AddDate(0, 1, -1).Day()
+	return target.AddDate(0, 0, min(t.Day(), lastDay)-1)
+}
+
+// intArg 整数参数
+func intArg(value any) (int, error) {
+	f, err := ToNumber(value)
+	if err != nil {
+		return 0, err
+	}
+	return int(math.Trunc(f)), nil
+}
+
+func clamp(n, low, high int) int {
+	return max(low, min(n, high))
+}
+
+// Round 四舍五入到指定小数位, 远离 0 方向进位
+func Round(x float64, digits int) float64 {
+	pow := math.Pow(10, float64(digits))
+	return math.Round(x*pow) / pow
+}
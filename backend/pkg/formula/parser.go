@@ -0,0 +1,444 @@
+// Package formula 公式字段的表达式解析与计算
+//
+// 语法接近电子表格公式:
+//   - 字面量: 数字 12.5, 字符串 "abc" 或 'abc', TRUE FALSE NULL
+//   - 字段引用: 同一记录中的字段编码, 如 price * quantity
+//   - 运算符: + - * / % 算术, & 字符串连接, = == != <> < <= > >= 比较, AND OR NOT (&& || !) 逻辑
+//   - 日期运算: 日期 ± 数字 为加减天数, 日期 - 日期 为相差天数
+//   - 函数: IF CONCAT ROUND DATEADD DATEDIFF 等, 函数名不区分大小写, 完整列表见 functions
+package formula
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+	num  float64
+}
+
+// 两个字符的运算符, 需要先于单字符运算符匹配
+var operators2 = []string{"==", "!=", "<>", "<=", ">=", "&&", "||"}
+
+const operators1 = "+-*/%&=<>!"
+
+// lex 将公式拆分为 token
+func lex(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			i++
+		case isDigit(r) || (r == '.' && i+1 < len(runes) && isDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (isDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				i++
+				if i < len(runes) && (runes[i] == '+' || runes[i] == '-') {
+					i++
+				}
+				for i < len(runes) && isDigit(runes[i]) {
+					i++
+				}
+			}
+			text := string(runes[start:i])
+			num, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, syntaxError(start, "数字格式不正确: %s", text)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, pos: start, num: num})
+		case r == '"' || r == '\'':
+			start := i
+			var sb strings.Builder
+			closed := false
+			for i++; i < len(runes); i++ {
+				c := runes[i]
+				if c == '\\' && i+1 < len(runes) {
+					i++
+					switch runes[i] {
+					case 'n':
+						sb.WriteRune('\n')
+					case 't':
+						sb.WriteRune('\t')
+					default:
+						sb.WriteRune(runes[i])
+					}
+					continue
+				}
+				if c == r {
+					closed = true
+					i++
+					break
+				}
+				sb.WriteRune(c)
+			}
+			if !closed {
+				return nil, syntaxError(start, "字符串缺少结束引号")
+			}
+			tokens = append(tokens, token{kind: tokenString, text: sb.String(), pos: start})
+		case isIdentStart(r):
+			start := i
+			for i < len(runes) && (isIdentStart(runes[i]) || isDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: i})
+			i++
+		default:
+			matched := false
+			if i+1 < len(runes) {
+				pair := string(runes[i : i+2])
+				for _, op := range operators2 {
+					if pair == op {
+						tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+						i += 2
+						matched = true
+						break
+					}
+				}
+			}
+			if !matched && strings.ContainsRune(operators1, r) {
+				tokens = append(tokens, token{kind: tokenOperator, text: string(r), pos: i})
+				i++
+				matched = true
+			}
+			if !matched {
+				return nil, syntaxError(i, "无法识别的字符 %q", r)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func syntaxError(pos int, format string, args ...any) error {
+	return fmt.Errorf("公式语法错误 (位置 %d): %s", pos+1, fmt.Sprintf(format, args...))
+}
+
+// Expr 解析后的公式
+type Expr struct {
+	source string
+	root   node
+	refs   []string
+}
+
+// Parse 解析公式, 语法错误、未知函数及参数个数错误在解析时返回
+func Parse(source string) (*Expr, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, fmt.Errorf("公式不能为空")
+	}
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, refs: make(map[string]bool)}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, syntaxError(tok.pos, "多余的内容 %q", tok.text)
+	}
+	return &Expr{source: source, root: root, refs: p.order}, nil
+}
+
+// String 公式原文
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Refs 公式引用的字段编码, 按首次出现的顺序
+func (e *Expr) Refs() []string {
+	return append([]string(nil), e.refs...)
+}
+
+// Eval 使用字段值计算公式, vars 中缺少的字段按空值处理
+// 结果为 nil、float64、string、bool 或 time.Time
+func (e *Expr) Eval(vars map[string]any) (any, error) {
+	return e.root.eval(vars)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	refs   map[string]bool
+	order  []string
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// matchOperator 当前 token 是给定运算符之一时消费并返回
+func (p *parser) matchOperator(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.next()
+			return op, true
+		}
+	}
+	return "", false
+}
+
+// matchKeyword 当前 token 是给定关键字 (不区分大小写) 且不是函数调用时消费
+func (p *parser) matchKeyword(keyword string) bool {
+	tok := p.peek()
+	if tok.kind != tokenIdent || !strings.EqualFold(tok.text, keyword) {
+		return false
+	}
+	if p.tokens[p.pos+1].kind == tokenLParen {
+		return false
+	}
+	p.next()
+	return true
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.matchOperator("||"); !ok && !p.matchKeyword("OR") {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: false, x: left, y: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.matchOperator("&&"); !ok && !p.matchKeyword("AND") {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: true, x: left, y: right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.matchOperator("!"); ok || p.matchKeyword("NOT") {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{x: x}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.matchOperator("=", "==", "!=", "<>", "<", "<=", ">", ">=")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		left = &compareNode{op: op, x: left, y: right}
+	}
+}
+
+func (p *parser) parseConcat() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.matchOperator("&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = &concatNode{x: left, y: right}
+	}
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.matchOperator("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithNode{op: op, x: left, y: right}
+	}
+}
+
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.matchOperator("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithNode{op: op, x: left, y: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if op, ok := p.matchOperator("-", "+"); ok {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if op == "+" {
+			return x, nil
+		}
+		return &negNode{x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		return &literalNode{value: tok.num}, nil
+	case tokenString:
+		return &literalNode{value: tok.text}, nil
+	case tokenLParen:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, syntaxError(closing.pos, "缺少右括号")
+		}
+		return x, nil
+	case tokenIdent:
+		if p.peek().kind == tokenLParen {
+			return p.parseCall(tok)
+		}
+		switch strings.ToUpper(tok.text) {
+		case "TRUE":
+			return &literalNode{value: true}, nil
+		case "FALSE":
+			return &literalNode{value: false}, nil
+		case "NULL":
+			return &literalNode{value: nil}, nil
+		case "AND", "OR", "NOT":
+			return nil, syntaxError(tok.pos, "%s 缺少操作数", tok.text)
+		}
+		if !p.refs[tok.text] {
+			p.refs[tok.text] = true
+			p.order = append(p.order, tok.text)
+		}
+		return &fieldNode{name: tok.text}, nil
+	case tokenEOF:
+		return nil, syntaxError(tok.pos, "公式不完整")
+	default:
+		return nil, syntaxError(tok.pos, "意外的 %q", tok.text)
+	}
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	upper := strings.ToUpper(name.text)
+	fn, ok := functions[upper]
+	if !ok {
+		return nil, syntaxError(name.pos, "未知函数 %s", name.text)
+	}
+	p.next() // (
+
+	var args []node
+	if p.peek().kind != tokenRParen {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if closing := p.next(); closing.kind != tokenRParen {
+		return nil, syntaxError(closing.pos, "函数 %s 缺少右括号", upper)
+	}
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, syntaxError(name.pos, "函数 %s 参数个数不正确", upper)
+	}
+	return &callNode{name: upper, fn: fn, args: args}, nil
+}
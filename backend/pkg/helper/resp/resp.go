@@ -34,25 +34,33 @@ func HandleError(c *gin.Context, httpCode int, message string, data interface{})
 	}
 	// resp := response{Code: code, Message: message, Data: data}
 	// c.JSON(httpCode, resp)
+	body := gin.H{
+		"message": message,
+	}
 	// 如果 data 中有 errors 则输出errors
+	// 409 冲突时输出 references (引用明细)、conflicts (当前值与字段差异)、plan (表结构变更计划) 或 diff (模型定义差异)
+	var detail map[string]any
+	switch d := data.(type) {
+	case gin.H:
+		detail = d
+	case map[string]any:
+		detail = d
+	}
+	for _, key := range []string{"errors", "references", "conflicts", "plan", "diff"} {
+		if value, ok := detail[key]; ok {
+			body[key] = value
+		}
+	}
 	// 如果 data 中有 rate 则输出 rate
 	// 如果 data 中有 documentation_url 则输出 documentation_url
-	c.JSON(httpCode, gin.H{
-		"message": message,
-		// "errors": []map[string]string{
-		// 	{
-		// 		"resource": "CommitComment",
-		// 		"field":    "body",
-		// 		"code":     "blank",
-		// 	},
-		// },
-		// "rate": map[string]int{
-		// 	"limit":     5000,
-		// 	"remaining": 0,
-		// 	"reset":     1634234567, // UTC 时间戳，表示速率限制重置时间
-		// },
-		// "documentation_url": "https://docs.github.com/rest/repos/commits#create-a-commit-comment"
-	})
+	c.JSON(httpCode, body)
+	// 输出格式参考:
+	// {
+	// 	"message": "Validation Failed",
+	// 	"errors": [{"resource": "CommitComment", "field": "body", "code": "blank"}],
+	// 	"rate": {"limit": 5000, "remaining": 0, "reset": 1634234567},
+	// 	"documentation_url": "https://docs.github.com/rest/repos/commits#create-a-commit-comment"
+	// }
 }
 
 func SetHeader(c *gin.Context) {
@@ -86,7 +94,7 @@ func SetHeader(c *gin.Context) {
 	// - Last-Modified
 	// - Pragma
 	// 如果想让浏览器能访问到其他的 响应头的话 需要在服务器上设置 Access-Control-Expose-Headers
-	c.Header("Access-Control-Expose-Headers", "Link, Accept, Content-Type, Authorization, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Used, X-RateLimit-Resource, X-RateLimit-Reset, X-Request-Id")
+	c.Header("Access-Control-Expose-Headers", "Link, Accept, Content-Type, Authorization, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Used, X-RateLimit-Resource, X-RateLimit-Reset, X-Request-Id, ETag")
 }
 
 // GeneratePaginationLinks 生成分页链接
@@ -0,0 +1,553 @@
+// Package jsonschema JSON 字段的 JSON Schema 校验
+//
+// 支持 JSON Schema 中常用的校验关键字:
+//   - 通用: type enum const allOf anyOf oneOf not
+//   - 对象: properties required additionalProperties minProperties maxProperties
+//   - 数组: items minItems maxItems uniqueItems
+//   - 字符串: minLength maxLength pattern format (date date-time time email uri)
+//   - 数值: minimum maximum exclusiveMinimum exclusiveMaximum multipleOf
+//
+// 不支持 $ref, 其它关键字 (title description default 等) 忽略
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 支持的类型
+var schemaTypes = []string{"null", "boolean", "object", "array", "number", "integer", "string"}
+
+// Schema 编译后的 JSON Schema
+type Schema struct {
+	// 布尔 schema: true 接受任意值, false 拒绝任意值
+	boolean *bool
+
+	types    []string
+	enum     []any
+	constant any
+	hasConst bool
+	allOf    []*Schema
+	anyOf    []*Schema
+	oneOf    []*Schema
+	not      *Schema
+
+	properties    map[string]*Schema
+	required      []string
+	additional    *Schema
+	minProperties *int
+	maxProperties *int
+
+	items       *Schema
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+	format    string
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+}
+
+// ValidationError 一个位置上的校验错误, Path 以 $ 表示根, 如 $.address.city, $.items[0]
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Path + " " + e.Message
+}
+
+// Parse 解析 JSON 文本形式的 schema
+func Parse(data []byte) (*Schema, error) {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("JSON Schema 不是合法的 JSON: %v", err)
+	}
+	return Compile(raw)
+}
+
+// Compile 编译已解码的 schema (对象或布尔值)
+func Compile(raw any) (*Schema, error) {
+	return compile(raw, "$")
+}
+
+func compile(raw any, at string) (*Schema, error) {
+	switch v := raw.(type) {
+	case bool:
+		return &Schema{boolean: &v}, nil
+	case map[string]any:
+		return compileObject(v, at)
+	}
+	return nil, fmt.Errorf("JSON Schema %s 必须是对象或布尔值", at)
+}
+
+func compileObject(raw map[string]any, at string) (*Schema, error) {
+	s := &Schema{}
+	var err error
+
+	if v, ok := raw["$ref"]; ok && v != nil {
+		return nil, fmt.Errorf("JSON Schema %s 不支持 $ref", at)
+	}
+
+	switch v := raw["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{v}
+	case []any:
+		for _, item := range v {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("JSON Schema %s.type 必须是字符串或字符串数组", at)
+			}
+			s.types = append(s.types, name)
+		}
+	default:
+		return nil, fmt.Errorf("JSON Schema %s.type 必须是字符串或字符串数组", at)
+	}
+	for _, name := range s.types {
+		if !slices.Contains(schemaTypes, name) {
+			return nil, fmt.Errorf("JSON Schema %s.type 不支持的类型: %s", at, name)
+		}
+	}
+
+	if v, ok := raw["enum"]; ok {
+		items, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("JSON Schema %s.enum 必须是数组", at)
+		}
+		s.enum = items
+	}
+	if v, ok := raw["const"]; ok {
+		s.constant, s.hasConst = v, true
+	}
+
+	for _, kw := range []struct {
+		name   string
+		target *[]*Schema
+	}{{"allOf", &s.allOf}, {"anyOf", &s.anyOf}, {"oneOf", &s.oneOf}} {
+		v, ok := raw[kw.name]
+		if !ok {
+			continue
+		}
+		items, ok := v.([]any)
+		if !ok || len(items) == 0 {
+			return nil, fmt.Errorf("JSON Schema %s.%s 必须是非空数组", at, kw.name)
+		}
+		for i, item := range items {
+			child, err := compile(item, fmt.Sprintf("%s.%s[%d]", at, kw.name, i))
+			if err != nil {
+				return nil, err
+			}
+			*kw.target = append(*kw.target, child)
+		}
+	}
+	if v, ok := raw["not"]; ok {
+		if s.not, err = compile(v, at+".not"); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := raw["properties"]; ok {
+		props, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("JSON Schema %s.properties 必须是对象", at)
+		}
+		s.properties = make(map[string]*Schema, len(props))
+		for name, prop := range props {
+			if s.properties[name], err = compile(prop, at+".properties."+name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if v, ok := raw["required"]; ok {
+		items, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("JSON Schema %s.required 必须是字符串数组", at)
+		}
+		for _, item := range items {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("JSON Schema %s.required 必须是字符串数组", at)
+			}
+			s.required = append(s.required, name)
+		}
+	}
+	if v, ok := raw["additionalProperties"]; ok {
+		if s.additional, err = compile(v, at+".additionalProperties"); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := raw["items"]; ok {
+		if s.items, err = compile(v, at+".items"); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := raw["uniqueItems"]; ok {
+		if s.uniqueItems, ok = v.(bool); !ok {
+			return nil, fmt.Errorf("JSON Schema %s.uniqueItems 必须是布尔值", at)
+		}
+	}
+
+	for _, kw := range []struct {
+		name   string
+		target **int
+	}{
+		{"minProperties", &s.minProperties}, {"maxProperties", &s.maxProperties},
+		{"minItems", &s.minItems}, {"maxItems", &s.maxItems},
+		{"minLength", &s.minLength}, {"maxLength", &s.maxLength},
+	} {
+		v, ok := raw[kw.name]
+		if !ok {
+			continue
+		}
+		n, ok := toNumber(v)
+		if !ok || n < 0 || n != math.Trunc(n) {
+			return nil, fmt.Errorf("JSON Schema %s.%s 必须是非负整数", at, kw.name)
+		}
+		i := int(n)
+		*kw.target = &i
+	}
+
+	for _, kw := range []struct {
+		name   string
+		target **float64
+	}{
+		{"minimum", &s.minimum}, {"maximum", &s.maximum},
+		{"exclusiveMinimum", &s.exclusiveMinimum}, {"exclusiveMaximum", &s.exclusiveMaximum},
+		{"multipleOf", &s.multipleOf},
+	} {
+		v, ok := raw[kw.name]
+		if !ok {
+			continue
+		}
+		n, ok := toNumber(v)
+		if !ok {
+			return nil, fmt.Errorf("JSON Schema %s.%s 必须是数字", at, kw.name)
+		}
+		*kw.target = &n
+	}
+	if s.multipleOf != nil && *s.multipleOf <= 0 {
+		return nil, fmt.Errorf("JSON Schema %s.multipleOf 必须大于 0", at)
+	}
+
+	if v, ok := raw["pattern"]; ok {
+		pattern, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("JSON Schema %s.pattern 必须是字符串", at)
+		}
+		if s.pattern, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("JSON Schema %s.pattern 正则表达式错误: %v", at, err)
+		}
+	}
+	if v, ok := raw["format"]; ok {
+		if s.format, ok = v.(string); !ok {
+			return nil, fmt.Errorf("JSON Schema %s.format 必须是字符串", at)
+		}
+	}
+
+	return s, nil
+}
+
+// Validate 校验已解码的 JSON 值 (数字可以是 json.Number 或 float64), 返回全部错误
+func (s *Schema) Validate(value any) []*ValidationError {
+	var errs []*ValidationError
+	s.validate(value, "$", &errs)
+	return errs
+}
+
+func (s *Schema) validate(value any, path string, errs *[]*ValidationError) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.boolean != nil {
+		if !*s.boolean {
+			fail("不允许出现")
+		}
+		return
+	}
+
+	if len(s.types) > 0 && !slices.ContainsFunc(s.types, func(name string) bool { return isType(value, name) }) {
+		fail("类型应为 %s, 实际为 %s", strings.Join(s.types, " 或 "), typeName(value))
+		return
+	}
+	if s.enum != nil && !slices.ContainsFunc(s.enum, func(item any) bool { return equal(item, value) }) {
+		fail("必须是以下值之一: %s", compact(s.enum))
+	}
+	if s.hasConst && !equal(s.constant, value) {
+		fail("必须等于 %s", compact(s.constant))
+	}
+
+	for _, child := range s.allOf {
+		child.validate(value, path, errs)
+	}
+	if len(s.anyOf) > 0 && !slices.ContainsFunc(s.anyOf, func(child *Schema) bool { return child.valid(value) }) {
+		fail("不满足 anyOf 中的任何一个条件")
+	}
+	if len(s.oneOf) > 0 {
+		matched := 0
+		for _, child := range s.oneOf {
+			if child.valid(value) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fail("必须且只能满足 oneOf 中的一个条件, 实际满足 %d 个", matched)
+		}
+	}
+	if s.not != nil && s.not.valid(value) {
+		fail("不能满足 not 中的条件")
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		s.validateObject(v, path, errs)
+	case []any:
+		s.validateArray(v, path, errs)
+	case string:
+		s.validateString(v, fail)
+	default:
+		if n, ok := toNumber(value); ok {
+			s.validateNumber(n, fail)
+		}
+	}
+}
+
+// valid 是否通过校验, 用于 anyOf / oneOf / not
+func (s *Schema) valid(value any) bool {
+	var errs []*ValidationError
+	s.validate(value, "$", &errs)
+	return len(errs) == 0
+}
+
+func (s *Schema) validateObject(obj map[string]any, path string, errs *[]*ValidationError) {
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			*errs = append(*errs, &ValidationError{Path: childPath(path, name), Message: "是必填项"})
+		}
+	}
+	if s.minProperties != nil && len(obj) < *s.minProperties {
+		*errs = append(*errs, &ValidationError{Path: path, Message: fmt.Sprintf("属性不能少于 %d 个", *s.minProperties)})
+	}
+	if s.maxProperties != nil && len(obj) > *s.maxProperties {
+		*errs = append(*errs, &ValidationError{Path: path, Message: fmt.Sprintf("属性不能超过 %d 个", *s.maxProperties)})
+	}
+
+	// 按属性名顺序校验, 保证错误顺序稳定
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if prop, ok := s.properties[name]; ok {
+			prop.validate(obj[name], childPath(path, name), errs)
+		} else if s.additional != nil {
+			if s.additional.boolean != nil && !*s.additional.boolean {
+				*errs = append(*errs, &ValidationError{Path: childPath(path, name), Message: "是未定义的属性"})
+				continue
+			}
+			s.additional.validate(obj[name], childPath(path, name), errs)
+		}
+	}
+}
+
+func (s *Schema) validateArray(arr []any, path string, errs *[]*ValidationError) {
+	if s.minItems != nil && len(arr) < *s.minItems {
+		*errs = append(*errs, &ValidationError{Path: path, Message: fmt.Sprintf("元素不能少于 %d 个", *s.minItems)})
+	}
+	if s.maxItems != nil && len(arr) > *s.maxItems {
+		*errs = append(*errs, &ValidationError{Path: path, Message: fmt.Sprintf("元素不能超过 %d 个", *s.maxItems)})
+	}
+	if s.uniqueItems {
+		for i := 1; i < len(arr); i++ {
+			if slices.ContainsFunc(arr[:i], func(item any) bool { return equal(item, arr[i]) }) {
+				*errs = append(*errs, &ValidationError{Path: fmt.Sprintf("%s[%d]", path, i), Message: "与前面的元素重复"})
+			}
+		}
+	}
+	if s.items != nil {
+		for i, item := range arr {
+			s.items.validate(item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+func (s *Schema) validateString(str string, fail func(string, ...any)) {
+	length := utf8.RuneCountInString(str)
+	if s.minLength != nil && length < *s.minLength {
+		fail("长度不能少于 %d", *s.minLength)
+	}
+	if s.maxLength != nil && length > *s.maxLength {
+		fail("长度不能超过 %d", *s.maxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		fail("不匹配格式 %s", s.pattern.String())
+	}
+	if s.format != "" && !validFormat(s.format, str) {
+		fail("不是合法的 %s", s.format)
+	}
+}
+
+func (s *Schema) validateNumber(n float64, fail func(string, ...any)) {
+	format := func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+	if s.minimum != nil && n < *s.minimum {
+		fail("不能小于 %s", format(*s.minimum))
+	}
+	if s.maximum != nil && n > *s.maximum {
+		fail("不能大于 %s", format(*s.maximum))
+	}
+	if s.exclusiveMinimum != nil && n <= *s.exclusiveMinimum {
+		fail("必须大于 %s", format(*s.exclusiveMinimum))
+	}
+	if s.exclusiveMaximum != nil && n >= *s.exclusiveMaximum {
+		fail("必须小于 %s", format(*s.exclusiveMaximum))
+	}
+	if s.multipleOf != nil {
+		q := n / *s.multipleOf
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("必须是 %s 的倍数", format(*s.multipleOf))
+		}
+	}
+}
+
+// validFormat 校验字符串格式, 不认识的格式视为通过
+func validFormat(format, str string) bool {
+	switch format {
+	case "date":
+		_, err := time.Parse("2006-01-02", str)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, str)
+		return err == nil
+	case "time":
+		_, err := time.Parse("15:04:05", str)
+		return err == nil
+	case "email":
+		addr, err := mail.ParseAddress(str)
+		return err == nil && addr.Address == str
+	case "uri":
+		u, err := url.Parse(str)
+		return err == nil && u.Scheme != ""
+	}
+	return true
+}
+
+// childPath 对象属性的路径, 非标识符的属性名加引号
+func childPath(path, name string) string {
+	for _, r := range name {
+		if !(r == '_' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return path + "." + strconv.Quote(name)
+		}
+	}
+	return path + "." + name
+}
+
+// isType 判断值是否为 schema 类型
+func isType(value any, name string) bool {
+	switch name {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := toNumber(value)
+		return ok
+	case "integer":
+		n, ok := toNumber(value)
+		return ok && n == math.Trunc(n)
+	}
+	return false
+}
+
+// typeName 值的类型名称, 用于错误信息
+func typeName(value any) string {
+	for _, name := range []string{"null", "boolean", "object", "array", "string", "integer", "number"} {
+		if isType(value, name) {
+			return name
+		}
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// toNumber 将 JSON 数字转换为 float64
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// equal 比较两个 JSON 值, 数字按数值比较
+func equal(a, b any) bool {
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+func normalize(value any) any {
+	if n, ok := toNumber(value); ok {
+		return n
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = normalize(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = normalize(item)
+		}
+		return out
+	}
+	return value
+}
+
+// compact 值的紧凑 JSON 文本, 用于错误信息
+func compact(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
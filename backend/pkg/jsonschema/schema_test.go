@@ -0,0 +1,131 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, source string) any {
+	t.Helper()
+	decoder := json.NewDecoder(strings.NewReader(source))
+	decoder.UseNumber()
+	var value any
+	require.NoError(t, decoder.Decode(&value), source)
+	return value
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := Parse([]byte(`{
+		"type": "object",
+		"required": ["sku", "price"],
+		"additionalProperties": false,
+		"properties": {
+			"sku":   {"type": "string", "pattern": "^[A-Z]{3}-\\d+$"},
+			"price": {"type": "number", "minimum": 0, "exclusiveMaximum": 10000, "multipleOf": 0.01},
+			"qty":   {"type": "integer", "maximum": 99},
+			"color": {"enum": ["red", "green"]},
+			"email": {"type": "string", "format": "email"},
+			"ship":  {"type": "string", "format": "date"},
+			"tags":  {"type": "array", "items": {"type": "string", "maxLength": 3}, "maxItems": 3, "uniqueItems": true},
+			"size":  {"oneOf": [{"type": "integer"}, {"type": "string", "enum": ["S", "M", "L"]}]},
+			"note":  {"type": ["string", "null"]}
+		}
+	}`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{
+			name:  "valid",
+			value: `{"sku":"ABC-1","price":12.5,"qty":3,"color":"red","email":"a@b.co","ship":"2024-02-29","tags":["a","b"],"size":"M","note":null}`,
+		},
+		{
+			name:  "missing required and unknown property",
+			value: `{"sku":"ABC-1","extra":1}`,
+			want:  []string{"$.price 是必填项", "$.extra 是未定义的属性"},
+		},
+		{
+			name:  "type mismatch",
+			value: `{"sku":1,"price":"1"}`,
+			want:  []string{"$.price 类型应为 number, 实际为 string", "$.sku 类型应为 string, 实际为 integer"},
+		},
+		{
+			name:  "string and number constraints",
+			value: `{"sku":"abc","price":10000,"qty":1.5,"ship":"2024-02-30"}`,
+			want: []string{
+				"$.price 必须小于 10000",
+				"$.qty 类型应为 integer, 实际为 number",
+				"$.ship 不是合法的 date",
+				`$.sku 不匹配格式 ^[A-Z]{3}-\d+$`,
+			},
+		},
+		{
+			name:  "multipleOf",
+			value: `{"sku":"ABC-1","price":1.005}`,
+			want:  []string{"$.price 必须是 0.01 的倍数"},
+		},
+		{
+			name:  "array items",
+			value: `{"sku":"ABC-1","price":1,"tags":["a","long","a","b"]}`,
+			want:  []string{"$.tags 元素不能超过 3 个", "$.tags[2] 与前面的元素重复", "$.tags[1] 长度不能超过 3"},
+		},
+		{
+			name:  "enum and oneOf",
+			value: `{"sku":"ABC-1","price":1,"color":"blue","size":"XL"}`,
+			want:  []string{`$.color 必须是以下值之一: ["red","green"]`, "$.size 必须且只能满足 oneOf 中的一个条件, 实际满足 0 个"},
+		},
+		{
+			name:  "root type",
+			value: `[1]`,
+			want:  []string{"$ 类型应为 object, 实际为 array"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range schema.Validate(decode(t, tt.value)) {
+				got = append(got, err.Error())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidate_Combinators(t *testing.T) {
+	schema, err := Parse([]byte(`{"allOf":[{"minLength":2}],"anyOf":[{"const":"ok"},{"pattern":"^x"}],"not":{"const":"xx"}}`))
+	require.NoError(t, err)
+
+	assert.Empty(t, schema.Validate("ok"))
+	assert.Empty(t, schema.Validate("xyz"))
+	assert.Len(t, schema.Validate("no"), 1)
+	assert.Len(t, schema.Validate("xx"), 1)
+	assert.Len(t, schema.Validate("x"), 1)
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []string{
+		`{"type":`,
+		`"string"`,
+		`{"type":"text"}`,
+		`{"$ref":"#/definitions/a"}`,
+		`{"properties":[]}`,
+		`{"minLength":-1}`,
+		`{"maximum":"10"}`,
+		`{"multipleOf":0}`,
+		`{"pattern":"("}`,
+		`{"anyOf":[]}`,
+		`{"items":1}`,
+	}
+	for _, source := range tests {
+		_, err := Parse([]byte(source))
+		assert.Error(t, err, source)
+	}
+}
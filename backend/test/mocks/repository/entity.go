@@ -5,6 +5,8 @@
 package mock_repository
 
 import (
+	model "piemdm/internal/model"
+	repository "piemdm/internal/repository"
 	reflect "reflect"
 
 	gin "github.com/gin-gonic/gin"
@@ -35,31 +37,61 @@ func (m *MockEntityRepository) EXPECT() *MockEntityRepositoryMockRecorder {
 }
 
 // BatchDelete mocks base method.
-func (m *MockEntityRepository) BatchDelete(c *gin.Context, tableCode string, ids []uint) error {
+func (m *MockEntityRepository) BatchDelete(c *gin.Context, tableCode string, ids []uint, reason string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "BatchDelete", c, tableCode, ids)
+	ret := m.ctrl.Call(m, "BatchDelete", c, tableCode, ids, reason)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // BatchDelete indicates an expected call of BatchDelete.
-func (mr *MockEntityRepositoryMockRecorder) BatchDelete(c, tableCode, ids interface{}) *gomock.Call {
+func (mr *MockEntityRepositoryMockRecorder) BatchDelete(c, tableCode, ids, reason interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchDelete", reflect.TypeOf((*MockEntityRepository)(nil).BatchDelete), c, tableCode, ids)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchDelete", reflect.TypeOf((*MockEntityRepository)(nil).BatchDelete), c, tableCode, ids, reason)
 }
 
 // BatchUpdate mocks base method.
-func (m *MockEntityRepository) BatchUpdate(c *gin.Context, tableCode string, ids []uint, entityMap map[string]any) error {
+func (m *MockEntityRepository) BatchUpdate(c *gin.Context, tableCode string, ids []uint, versions map[uint]uint, entityMap map[string]any) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "BatchUpdate", c, tableCode, ids, entityMap)
+	ret := m.ctrl.Call(m, "BatchUpdate", c, tableCode, ids, versions, entityMap)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // BatchUpdate indicates an expected call of BatchUpdate.
-func (mr *MockEntityRepositoryMockRecorder) BatchUpdate(c, tableCode, ids, entityMap interface{}) *gomock.Call {
+func (mr *MockEntityRepositoryMockRecorder) BatchUpdate(c, tableCode, ids, versions, entityMap interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchUpdate", reflect.TypeOf((*MockEntityRepository)(nil).BatchUpdate), c, tableCode, ids, entityMap)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchUpdate", reflect.TypeOf((*MockEntityRepository)(nil).BatchUpdate), c, tableCode, ids, versions, entityMap)
+}
+
+// CompileQuery mocks base method.
+func (m *MockEntityRepository) CompileQuery(tableCode string, query *model.EntityQuery, columns map[string]string, alias string) (*repository.CompiledEntityQuery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompileQuery", tableCode, query, columns, alias)
+	ret0, _ := ret[0].(*repository.CompiledEntityQuery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompileQuery indicates an expected call of CompileQuery.
+func (mr *MockEntityRepositoryMockRecorder) CompileQuery(tableCode, query, columns, alias interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompileQuery", reflect.TypeOf((*MockEntityRepository)(nil).CompileQuery), tableCode, query, columns, alias)
+}
+
+// Count mocks base method.
+func (m *MockEntityRepository) Count(tableCode string, query *repository.CompiledEntityQuery) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", tableCode, query)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockEntityRepositoryMockRecorder) Count(tableCode, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockEntityRepository)(nil).Count), tableCode, query)
 }
 
 // Create mocks base method.
@@ -77,17 +109,17 @@ func (mr *MockEntityRepositoryMockRecorder) Create(c, tableCode, entityMap inter
 }
 
 // Delete mocks base method.
-func (m *MockEntityRepository) Delete(c *gin.Context, tableCode string, id uint) error {
+func (m *MockEntityRepository) Delete(c *gin.Context, tableCode string, id uint, reason string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", c, tableCode, id)
+	ret := m.ctrl.Call(m, "Delete", c, tableCode, id, reason)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockEntityRepositoryMockRecorder) Delete(c, tableCode, id interface{}) *gomock.Call {
+func (mr *MockEntityRepositoryMockRecorder) Delete(c, tableCode, id, reason interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockEntityRepository)(nil).Delete), c, tableCode, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockEntityRepository)(nil).Delete), c, tableCode, id, reason)
 }
 
 // Find mocks base method.
@@ -105,6 +137,66 @@ func (mr *MockEntityRepositoryMockRecorder) Find(tableCode, selectString, where
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockEntityRepository)(nil).Find), tableCode, selectString, where)
 }
 
+// FindChildren mocks base method.
+func (m *MockEntityRepository) FindChildren(tableCode string, parentIDs []uint, withDeleted bool) ([]map[string]any, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindChildren", tableCode, parentIDs, withDeleted)
+	ret0, _ := ret[0].([]map[string]any)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindChildren indicates an expected call of FindChildren.
+func (mr *MockEntityRepositoryMockRecorder) FindChildren(tableCode, parentIDs, withDeleted interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindChildren", reflect.TypeOf((*MockEntityRepository)(nil).FindChildren), tableCode, parentIDs, withDeleted)
+}
+
+// FindChunk mocks base method.
+func (m *MockEntityRepository) FindChunk(tableCode string, query *repository.CompiledEntityQuery, last map[string]any, limit int) ([]map[string]any, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindChunk", tableCode, query, last, limit)
+	ret0, _ := ret[0].([]map[string]any)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindChunk indicates an expected call of FindChunk.
+func (mr *MockEntityRepositoryMockRecorder) FindChunk(tableCode, query, last, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindChunk", reflect.TypeOf((*MockEntityRepository)(nil).FindChunk), tableCode, query, last, limit)
+}
+
+// FindDeletedPage mocks base method.
+func (m *MockEntityRepository) FindDeletedPage(tableCode string, page, pageSize int, total *int64, query *repository.CompiledEntityQuery) ([]map[string]any, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDeletedPage", tableCode, page, pageSize, total, query)
+	ret0, _ := ret[0].([]map[string]any)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDeletedPage indicates an expected call of FindDeletedPage.
+func (mr *MockEntityRepositoryMockRecorder) FindDeletedPage(tableCode, page, pageSize, total, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDeletedPage", reflect.TypeOf((*MockEntityRepository)(nil).FindDeletedPage), tableCode, page, pageSize, total, query)
+}
+
+// FindLinked mocks base method.
+func (m *MockEntityRepository) FindLinked(tableCode, fieldCode string, values []string) ([]uint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindLinked", tableCode, fieldCode, values)
+	ret0, _ := ret[0].([]uint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindLinked indicates an expected call of FindLinked.
+func (mr *MockEntityRepositoryMockRecorder) FindLinked(tableCode, fieldCode, values interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindLinked", reflect.TypeOf((*MockEntityRepository)(nil).FindLinked), tableCode, fieldCode, values)
+}
+
 // FindLogPage mocks base method.
 func (m *MockEntityRepository) FindLogPage(tableCode string, page, pageSize int, total *int64, where map[string]any) ([]map[string]any, error) {
 	m.ctrl.T.Helper()
@@ -136,18 +228,33 @@ func (mr *MockEntityRepositoryMockRecorder) FindOne(tableCode, id interface{}) *
 }
 
 // FindPage mocks base method.
-func (m *MockEntityRepository) FindPage(tableCode string, page, pageSize int, total *int64, where map[string]any) ([]map[string]any, error) {
+func (m *MockEntityRepository) FindPage(tableCode string, page, pageSize int, total *int64, query *repository.CompiledEntityQuery) ([]map[string]any, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindPage", tableCode, page, pageSize, total, where)
+	ret := m.ctrl.Call(m, "FindPage", tableCode, page, pageSize, total, query)
 	ret0, _ := ret[0].([]map[string]any)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // FindPage indicates an expected call of FindPage.
-func (mr *MockEntityRepositoryMockRecorder) FindPage(tableCode, page, pageSize, total, where interface{}) *gomock.Call {
+func (mr *MockEntityRepositoryMockRecorder) FindPage(tableCode, page, pageSize, total, query interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPage", reflect.TypeOf((*MockEntityRepository)(nil).FindPage), tableCode, page, pageSize, total, where)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPage", reflect.TypeOf((*MockEntityRepository)(nil).FindPage), tableCode, page, pageSize, total, query)
+}
+
+// FindWithDeleted mocks base method.
+func (m *MockEntityRepository) FindWithDeleted(tableCode, selectString string, where map[string]any) ([]map[string]any, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindWithDeleted", tableCode, selectString, where)
+	ret0, _ := ret[0].([]map[string]any)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindWithDeleted indicates an expected call of FindWithDeleted.
+func (mr *MockEntityRepositoryMockRecorder) FindWithDeleted(tableCode, selectString, where interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindWithDeleted", reflect.TypeOf((*MockEntityRepository)(nil).FindWithDeleted), tableCode, selectString, where)
 }
 
 // GetStatisticsByStatus mocks base method.
@@ -165,6 +272,62 @@ func (mr *MockEntityRepositoryMockRecorder) GetStatisticsByStatus(tableCode inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatisticsByStatus", reflect.TypeOf((*MockEntityRepository)(nil).GetStatisticsByStatus), tableCode)
 }
 
+// MoveSubtree mocks base method.
+func (m *MockEntityRepository) MoveSubtree(c *gin.Context, tableCode string, entity, where map[string]any, descendants []*repository.TreePath) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MoveSubtree", c, tableCode, entity, where, descendants)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MoveSubtree indicates an expected call of MoveSubtree.
+func (mr *MockEntityRepositoryMockRecorder) MoveSubtree(c, tableCode, entity, where, descendants interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MoveSubtree", reflect.TypeOf((*MockEntityRepository)(nil).MoveSubtree), c, tableCode, entity, where, descendants)
+}
+
+// Purge mocks base method.
+func (m *MockEntityRepository) Purge(c *gin.Context, tableCode string, ids []uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", c, tableCode, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockEntityRepositoryMockRecorder) Purge(c, tableCode, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockEntityRepository)(nil).Purge), c, tableCode, ids)
+}
+
+// Restore mocks base method.
+func (m *MockEntityRepository) Restore(c *gin.Context, tableCode string, ids []uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", c, tableCode, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockEntityRepositoryMockRecorder) Restore(c, tableCode, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockEntityRepository)(nil).Restore), c, tableCode, ids)
+}
+
+// Transaction mocks base method.
+func (m *MockEntityRepository) Transaction(c *gin.Context, fn func(repository.EntityRepository) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transaction", c, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Transaction indicates an expected call of Transaction.
+func (mr *MockEntityRepositoryMockRecorder) Transaction(c, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transaction", reflect.TypeOf((*MockEntityRepository)(nil).Transaction), c, fn)
+}
+
 // Update mocks base method.
 func (m *MockEntityRepository) Update(c *gin.Context, tableCode string, entity any, where map[string]any) error {
 	m.ctrl.T.Helper()
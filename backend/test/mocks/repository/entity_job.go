@@ -0,0 +1,124 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/entity_job.go
+
+// Package mock_repository is a generated GoMock package.
+package mock_repository
+
+import (
+	model "piemdm/internal/model"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockEntityJobRepository is a mock of EntityJobRepository interface.
+type MockEntityJobRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockEntityJobRepositoryMockRecorder
+}
+
+// MockEntityJobRepositoryMockRecorder is the mock recorder for MockEntityJobRepository.
+type MockEntityJobRepositoryMockRecorder struct {
+	mock *MockEntityJobRepository
+}
+
+// NewMockEntityJobRepository creates a new mock instance.
+func NewMockEntityJobRepository(ctrl *gomock.Controller) *MockEntityJobRepository {
+	mock := &MockEntityJobRepository{ctrl: ctrl}
+	mock.recorder = &MockEntityJobRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEntityJobRepository) EXPECT() *MockEntityJobRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockEntityJobRepository) Create(job *model.EntityJob) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockEntityJobRepositoryMockRecorder) Create(job interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockEntityJobRepository)(nil).Create), job)
+}
+
+// FindExpired mocks base method.
+func (m *MockEntityJobRepository) FindExpired(before time.Time) ([]*model.EntityJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindExpired", before)
+	ret0, _ := ret[0].([]*model.EntityJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindExpired indicates an expected call of FindExpired.
+func (mr *MockEntityJobRepositoryMockRecorder) FindExpired(before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindExpired", reflect.TypeOf((*MockEntityJobRepository)(nil).FindExpired), before)
+}
+
+// FindOne mocks base method.
+func (m *MockEntityJobRepository) FindOne(id uint) (*model.EntityJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOne", id)
+	ret0, _ := ret[0].(*model.EntityJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOne indicates an expected call of FindOne.
+func (mr *MockEntityJobRepositoryMockRecorder) FindOne(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOne", reflect.TypeOf((*MockEntityJobRepository)(nil).FindOne), id)
+}
+
+// FindPage mocks base method.
+func (m *MockEntityJobRepository) FindPage(page, pageSize int, total *int64, where map[string]any) ([]*model.EntityJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPage", page, pageSize, total, where)
+	ret0, _ := ret[0].([]*model.EntityJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPage indicates an expected call of FindPage.
+func (mr *MockEntityJobRepositoryMockRecorder) FindPage(page, pageSize, total, where interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPage", reflect.TypeOf((*MockEntityJobRepository)(nil).FindPage), page, pageSize, total, where)
+}
+
+// FirstByCode mocks base method.
+func (m *MockEntityJobRepository) FirstByCode(code string) (*model.EntityJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FirstByCode", code)
+	ret0, _ := ret[0].(*model.EntityJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FirstByCode indicates an expected call of FirstByCode.
+func (mr *MockEntityJobRepositoryMockRecorder) FirstByCode(code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FirstByCode", reflect.TypeOf((*MockEntityJobRepository)(nil).FirstByCode), code)
+}
+
+// Save mocks base method.
+func (m *MockEntityJobRepository) Save(job *model.EntityJob) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockEntityJobRepositoryMockRecorder) Save(job interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockEntityJobRepository)(nil).Save), job)
+}
@@ -35,6 +35,20 @@ func (m *MockTableFieldRepository) EXPECT() *MockTableFieldRepositoryMockRecorde
 	return m.recorder
 }
 
+// ApplySchema mocks base method.
+func (m *MockTableFieldRepository) ApplySchema(plans []*model.SchemaTablePlan, fields []*model.TableField, version *model.SchemaVersion) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplySchema", plans, fields, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApplySchema indicates an expected call of ApplySchema.
+func (mr *MockTableFieldRepositoryMockRecorder) ApplySchema(plans, fields, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplySchema", reflect.TypeOf((*MockTableFieldRepository)(nil).ApplySchema), plans, fields, version)
+}
+
 // BatchDelete mocks base method.
 func (m *MockTableFieldRepository) BatchDelete(c *gin.Context, ids []uint) error {
 	m.ctrl.T.Helper()
@@ -181,6 +195,21 @@ func (mr *MockTableFieldRepositoryMockRecorder) GetTableOptions(tableCode, filte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTableOptions", reflect.TypeOf((*MockTableFieldRepository)(nil).GetTableOptions), tableCode, filter)
 }
 
+// PlanSchema mocks base method.
+func (m *MockTableFieldRepository) PlanSchema(tableName string, fields []*model.TableField) (*model.SchemaTablePlan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PlanSchema", tableName, fields)
+	ret0, _ := ret[0].(*model.SchemaTablePlan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PlanSchema indicates an expected call of PlanSchema.
+func (mr *MockTableFieldRepositoryMockRecorder) PlanSchema(tableName, fields interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PlanSchema", reflect.TypeOf((*MockTableFieldRepository)(nil).PlanSchema), tableName, fields)
+}
+
 // Public mocks base method.
 func (m *MockTableFieldRepository) Public(tableName string, entity any) error {
 	m.ctrl.T.Helper()
@@ -195,6 +224,21 @@ func (mr *MockTableFieldRepositoryMockRecorder) Public(tableName, entity interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Public", reflect.TypeOf((*MockTableFieldRepository)(nil).Public), tableName, entity)
 }
 
+// PublicLinks mocks base method.
+func (m *MockTableFieldRepository) PublicLinks(tableCode string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublicLinks", tableCode)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PublicLinks indicates an expected call of PublicLinks.
+func (mr *MockTableFieldRepositoryMockRecorder) PublicLinks(tableCode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublicLinks", reflect.TypeOf((*MockTableFieldRepository)(nil).PublicLinks), tableCode)
+}
+
 // Update mocks base method.
 func (m *MockTableFieldRepository) Update(c *gin.Context, tableField *model.TableField) error {
 	m.ctrl.T.Helper()
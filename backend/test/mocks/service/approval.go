@@ -6,8 +6,8 @@ package mock_service
 
 import (
 	context "context"
-	io "io"
 	model "piemdm/internal/model"
+	service "piemdm/internal/service"
 	reflect "reflect"
 
 	gin "github.com/gin-gonic/gin"
@@ -37,6 +37,34 @@ func (m *MockApprovalService) EXPECT() *MockApprovalServiceMockRecorder {
 	return m.recorder
 }
 
+// ApplyDeleteReferences mocks base method.
+func (m *MockApprovalService) ApplyDeleteReferences(c *gin.Context, tableCode, reason string, records []map[string]any) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyDeleteReferences", c, tableCode, reason, records)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApplyDeleteReferences indicates an expected call of ApplyDeleteReferences.
+func (mr *MockApprovalServiceMockRecorder) ApplyDeleteReferences(c, tableCode, reason, records interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyDeleteReferences", reflect.TypeOf((*MockApprovalService)(nil).ApplyDeleteReferences), c, tableCode, reason, records)
+}
+
+// ApplyMerge mocks base method.
+func (m *MockApprovalService) ApplyMerge(c *gin.Context, merge *model.EntityMerge) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyMerge", c, merge)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApplyMerge indicates an expected call of ApplyMerge.
+func (mr *MockApprovalServiceMockRecorder) ApplyMerge(c, merge interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyMerge", reflect.TypeOf((*MockApprovalService)(nil).ApplyMerge), c, merge)
+}
+
 // ApproveTask mocks base method.
 func (m *MockApprovalService) ApproveTask(c *gin.Context, taskId uint, comment string) error {
 	m.ctrl.T.Helper()
@@ -109,6 +137,20 @@ func (mr *MockApprovalServiceMockRecorder) CanProcessFlow(c, approvalCode, assig
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CanProcessFlow", reflect.TypeOf((*MockApprovalService)(nil).CanProcessFlow), c, approvalCode, assigneeID)
 }
 
+// CheckDeleteReferences mocks base method.
+func (m *MockApprovalService) CheckDeleteReferences(c *gin.Context, tableCode string, ids []uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckDeleteReferences", c, tableCode, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckDeleteReferences indicates an expected call of CheckDeleteReferences.
+func (mr *MockApprovalServiceMockRecorder) CheckDeleteReferences(c, tableCode, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckDeleteReferences", reflect.TypeOf((*MockApprovalService)(nil).CheckDeleteReferences), c, tableCode, ids)
+}
+
 // CheckExistingActiveDraft mocks base method.
 func (m *MockApprovalService) CheckExistingActiveDraft(c *gin.Context, tableCodeDraft string, entityID any) error {
 	m.ctrl.T.Helper()
@@ -296,6 +338,21 @@ func (mr *MockApprovalServiceMockRecorder) FindProcessedByAssignee(assigneeName,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindProcessedByAssignee", reflect.TypeOf((*MockApprovalService)(nil).FindProcessedByAssignee), assigneeName, page, pageSize, total, timeRange)
 }
 
+// FindReferences mocks base method.
+func (m *MockApprovalService) FindReferences(c *gin.Context, tableCode string, ids []uint) ([]*service.EntityReference, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindReferences", c, tableCode, ids)
+	ret0, _ := ret[0].([]*service.EntityReference)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindReferences indicates an expected call of FindReferences.
+func (mr *MockApprovalServiceMockRecorder) FindReferences(c, tableCode, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindReferences", reflect.TypeOf((*MockApprovalService)(nil).FindReferences), c, tableCode, ids)
+}
+
 // GenerateSerialNumber mocks base method.
 func (m *MockApprovalService) GenerateSerialNumber() string {
 	m.ctrl.T.Helper()
@@ -490,17 +547,18 @@ func (mr *MockApprovalServiceMockRecorder) GetPendingByApplicant(applicantID int
 }
 
 // ImportWithApproval mocks base method.
-func (m *MockApprovalService) ImportWithApproval(c *gin.Context, tableCode, reason, operation string, r io.Reader) error {
+func (m *MockApprovalService) ImportWithApproval(c *gin.Context, tableCode, reason, operation string, rows []*service.ImportRow) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ImportWithApproval", c, tableCode, reason, operation, r)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "ImportWithApproval", c, tableCode, reason, operation, rows)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // ImportWithApproval indicates an expected call of ImportWithApproval.
-func (mr *MockApprovalServiceMockRecorder) ImportWithApproval(c, tableCode, reason, operation, r interface{}) *gomock.Call {
+func (mr *MockApprovalServiceMockRecorder) ImportWithApproval(c, tableCode, reason, operation, rows interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportWithApproval", reflect.TypeOf((*MockApprovalService)(nil).ImportWithApproval), c, tableCode, reason, operation, r)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportWithApproval", reflect.TypeOf((*MockApprovalService)(nil).ImportWithApproval), c, tableCode, reason, operation, rows)
 }
 
 // List mocks base method.
@@ -602,6 +660,20 @@ func (mr *MockApprovalServiceMockRecorder) RejectTask(c, taskId, comment interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectTask", reflect.TypeOf((*MockApprovalService)(nil).RejectTask), c, taskId, comment)
 }
 
+// RevertMerge mocks base method.
+func (m *MockApprovalService) RevertMerge(c *gin.Context, merge *model.EntityMerge, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevertMerge", c, merge, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevertMerge indicates an expected call of RevertMerge.
+func (mr *MockApprovalServiceMockRecorder) RevertMerge(c, merge, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevertMerge", reflect.TypeOf((*MockApprovalService)(nil).RevertMerge), c, merge, reason)
+}
+
 // StartApproval mocks base method.
 func (m *MockApprovalService) StartApproval(c *gin.Context, approvalDefCode, applicantID, title, formData string) (*model.Approval, error) {
 	m.ctrl.T.Helper()
@@ -632,6 +704,21 @@ func (mr *MockApprovalServiceMockRecorder) StartApprovalFlow(c, approvalDefCode,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartApprovalFlow", reflect.TypeOf((*MockApprovalService)(nil).StartApprovalFlow), c, approvalDefCode, applicantID, title, formData)
 }
 
+// SubmitDocumentWithApproval mocks base method.
+func (m *MockApprovalService) SubmitDocumentWithApproval(c *gin.Context, tableCode, reason, operation string, drafts []*service.DocumentDraft) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubmitDocumentWithApproval", c, tableCode, reason, operation, drafts)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubmitDocumentWithApproval indicates an expected call of SubmitDocumentWithApproval.
+func (mr *MockApprovalServiceMockRecorder) SubmitDocumentWithApproval(c, tableCode, reason, operation, drafts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitDocumentWithApproval", reflect.TypeOf((*MockApprovalService)(nil).SubmitDocumentWithApproval), c, tableCode, reason, operation, drafts)
+}
+
 // SyncFeishuSubscriptions mocks base method.
 func (m *MockApprovalService) SyncFeishuSubscriptions(ctx context.Context) error {
 	m.ctrl.T.Helper()
@@ -5,8 +5,8 @@
 package mock_service
 
 import (
-	io "io"
 	model "piemdm/internal/model"
+	service "piemdm/internal/service"
 	reflect "reflect"
 
 	gin "github.com/gin-gonic/gin"
@@ -180,17 +180,18 @@ func (mr *MockApprovalWorkflowServiceMockRecorder) GetOperationInfo(operation, o
 }
 
 // ImportWithApproval mocks base method.
-func (m *MockApprovalWorkflowService) ImportWithApproval(c *gin.Context, tableCode, reason, operation string, r io.Reader) error {
+func (m *MockApprovalWorkflowService) ImportWithApproval(c *gin.Context, tableCode, reason, operation string, rows []*service.ImportRow) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ImportWithApproval", c, tableCode, reason, operation, r)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "ImportWithApproval", c, tableCode, reason, operation, rows)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // ImportWithApproval indicates an expected call of ImportWithApproval.
-func (mr *MockApprovalWorkflowServiceMockRecorder) ImportWithApproval(c, tableCode, reason, operation, r interface{}) *gomock.Call {
+func (mr *MockApprovalWorkflowServiceMockRecorder) ImportWithApproval(c, tableCode, reason, operation, rows interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportWithApproval", reflect.TypeOf((*MockApprovalWorkflowService)(nil).ImportWithApproval), c, tableCode, reason, operation, r)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportWithApproval", reflect.TypeOf((*MockApprovalWorkflowService)(nil).ImportWithApproval), c, tableCode, reason, operation, rows)
 }
 
 // ParseApproverConfig mocks base method.
@@ -207,6 +208,21 @@ func (mr *MockApprovalWorkflowServiceMockRecorder) ParseApproverConfig(node, ass
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseApproverConfig", reflect.TypeOf((*MockApprovalWorkflowService)(nil).ParseApproverConfig), node, assigneeID, assigneeName)
 }
 
+// SubmitDocumentWithApproval mocks base method.
+func (m *MockApprovalWorkflowService) SubmitDocumentWithApproval(c *gin.Context, tableCode, reason, operation string, drafts []*service.DocumentDraft) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubmitDocumentWithApproval", c, tableCode, reason, operation, drafts)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubmitDocumentWithApproval indicates an expected call of SubmitDocumentWithApproval.
+func (mr *MockApprovalWorkflowServiceMockRecorder) SubmitDocumentWithApproval(c, tableCode, reason, operation, drafts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitDocumentWithApproval", reflect.TypeOf((*MockApprovalWorkflowService)(nil).SubmitDocumentWithApproval), c, tableCode, reason, operation, drafts)
+}
+
 // UpdateByIdsWithApproval mocks base method.
 func (m *MockApprovalWorkflowService) UpdateByIdsWithApproval(c *gin.Context, tableCode, reason string, ids []uint, entityMap map[string]any) error {
 	m.ctrl.T.Helper()
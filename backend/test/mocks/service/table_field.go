@@ -6,6 +6,7 @@ package mock_service
 
 import (
 	model "piemdm/internal/model"
+	service "piemdm/internal/service"
 	reflect "reflect"
 
 	gin "github.com/gin-gonic/gin"
@@ -136,19 +137,34 @@ func (mr *MockTableFieldServiceMockRecorder) Get(id interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockTableFieldService)(nil).Get), id)
 }
 
+// GetFieldAccess mocks base method.
+func (m *MockTableFieldService) GetFieldAccess(c *gin.Context, tableCode string) (*service.FieldAccess, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFieldAccess", c, tableCode)
+	ret0, _ := ret[0].(*service.FieldAccess)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFieldAccess indicates an expected call of GetFieldAccess.
+func (mr *MockTableFieldServiceMockRecorder) GetFieldAccess(c, tableCode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFieldAccess", reflect.TypeOf((*MockTableFieldService)(nil).GetFieldAccess), c, tableCode)
+}
+
 // GetTableFields mocks base method.
-func (m *MockTableFieldService) GetTableFields(tableCode string) ([]*model.FieldMetadata, error) {
+func (m *MockTableFieldService) GetTableFields(c *gin.Context, tableCode string) ([]*model.FieldMetadata, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTableFields", tableCode)
+	ret := m.ctrl.Call(m, "GetTableFields", c, tableCode)
 	ret0, _ := ret[0].([]*model.FieldMetadata)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetTableFields indicates an expected call of GetTableFields.
-func (mr *MockTableFieldServiceMockRecorder) GetTableFields(tableCode interface{}) *gomock.Call {
+func (mr *MockTableFieldServiceMockRecorder) GetTableFields(c, tableCode interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTableFields", reflect.TypeOf((*MockTableFieldService)(nil).GetTableFields), tableCode)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTableFields", reflect.TypeOf((*MockTableFieldService)(nil).GetTableFields), c, tableCode)
 }
 
 // GetTableOptions mocks base method.
@@ -181,18 +197,64 @@ func (mr *MockTableFieldServiceMockRecorder) List(page, pageSize, total, where i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockTableFieldService)(nil).List), page, pageSize, total, where)
 }
 
+// ListSchemaVersions mocks base method.
+func (m *MockTableFieldService) ListSchemaVersions(page, pageSize int, total *int64, where map[string]any) ([]*model.SchemaVersion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSchemaVersions", page, pageSize, total, where)
+	ret0, _ := ret[0].([]*model.SchemaVersion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSchemaVersions indicates an expected call of ListSchemaVersions.
+func (mr *MockTableFieldServiceMockRecorder) ListSchemaVersions(page, pageSize, total, where interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSchemaVersions", reflect.TypeOf((*MockTableFieldService)(nil).ListSchemaVersions), page, pageSize, total, where)
+}
+
+// PlanFields mocks base method.
+func (m *MockTableFieldService) PlanFields(tableCode, displayMode string, fields []*model.TableField) (*model.SchemaPlan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PlanFields", tableCode, displayMode, fields)
+	ret0, _ := ret[0].(*model.SchemaPlan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PlanFields indicates an expected call of PlanFields.
+func (mr *MockTableFieldServiceMockRecorder) PlanFields(tableCode, displayMode, fields interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PlanFields", reflect.TypeOf((*MockTableFieldService)(nil).PlanFields), tableCode, displayMode, fields)
+}
+
+// PlanPublic mocks base method.
+func (m *MockTableFieldService) PlanPublic(tableCode string) (*model.SchemaPlan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PlanPublic", tableCode)
+	ret0, _ := ret[0].(*model.SchemaPlan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PlanPublic indicates an expected call of PlanPublic.
+func (mr *MockTableFieldServiceMockRecorder) PlanPublic(tableCode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PlanPublic", reflect.TypeOf((*MockTableFieldService)(nil).PlanPublic), tableCode)
+}
+
 // Public mocks base method.
-func (m *MockTableFieldService) Public(c *gin.Context, tableCode string) error {
+func (m *MockTableFieldService) Public(c *gin.Context, tableCode string, options *service.PublicOptions) (*model.SchemaVersion, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Public", c, tableCode)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "Public", c, tableCode, options)
+	ret0, _ := ret[0].(*model.SchemaVersion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Public indicates an expected call of Public.
-func (mr *MockTableFieldServiceMockRecorder) Public(c, tableCode interface{}) *gomock.Call {
+func (mr *MockTableFieldServiceMockRecorder) Public(c, tableCode, options interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Public", reflect.TypeOf((*MockTableFieldService)(nil).Public), c, tableCode)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Public", reflect.TypeOf((*MockTableFieldService)(nil).Public), c, tableCode, options)
 }
 
 // Update mocks base method.
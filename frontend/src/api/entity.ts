@@ -87,6 +87,10 @@ export interface EntityExportParams {
  */
 export interface EntityTemplateParams {
   table_code: string;
+  operation?: string;
+  format?: string; // xlsx csv json ndjson
+  delimiter?: string; // CSV delimiter, "tab" for tab
+  encoding?: string; // CSV encoding: utf-8 utf-8-bom gbk gb18030
 }
 
 /**
@@ -190,6 +194,461 @@ export const getEntityList = <T = Record<string, any>>(
   return service.get(`/entities/${params.table_code}`, { params });
 };
 
+/**
+ * 查询引用指定记录的数据 (where used)
+ *
+ * @param tableCode - 表编码
+ * @param id - 记录ID
+ * @returns Promise<AxiosResponse<ApiResponse>> 按引用表和关系字段分组的引用记录
+ */
+export const getEntityWhereUsed = (
+  tableCode: string,
+  id: string | number
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.get(`/entities/${tableCode}/${id}/where-used`);
+};
+
+/**
+ * 查询记录的关联视图, 每个视图返回第一页关联记录
+ *
+ * @param tableCode - 表编码
+ * @param id - 记录ID
+ * @param params - pageSize 每个视图返回的记录数
+ * @returns Promise<AxiosResponse<ApiResponse>> [{ id, name, table_code, field_code, total, records }]
+ */
+export const getEntityRelations = (
+  tableCode: string,
+  id: string | number,
+  params?: { pageSize?: number }
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.get(`/entities/${tableCode}/${id}/relations`, { params });
+};
+
+/**
+ * 分页查询记录在一个关联视图中的关联记录, 过滤、排序参数与列表相同
+ *
+ * @param tableCode - 表编码
+ * @param id - 记录ID
+ * @param relationId - 关联视图ID
+ * @param params - 分页和查询参数
+ * @returns Promise<AxiosResponse<ApiResponse>> 关联记录, 总页数在 Link 头中
+ */
+export const getEntityRelated = (
+  tableCode: string,
+  id: string | number,
+  relationId: number,
+  params?: EntityQueryParams | Record<string, any>
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.get(`/entities/${tableCode}/${id}/relations/${relationId}`, { params });
+};
+
+/**
+ * 回滚记录到指定时间点 (as_of) 或指定变更记录 (log_id) 之后的版本
+ * 表配置了修改审批流程时提交审批
+ *
+ * @param tableCode - 表编码
+ * @param id - 记录ID
+ * @param data - 回滚目标和原因
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const rollbackEntity = (
+  tableCode: string,
+  id: string | number,
+  data: { as_of?: string; log_id?: number; reason?: string }
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.post(`/entities/${tableCode}/${id}/rollback`, data);
+};
+
+/**
+ * 生命周期操作: 冻结、解冻、锁定、解锁、作废、扩展
+ */
+export type EntityLifecycleOperation = 'freeze' | 'unfreeze' | 'lock' | 'unlock' | 'void' | 'extend';
+
+/**
+ * 对单条记录执行生命周期操作, 表配置了该操作的审批流程时提交审批
+ *
+ * @param tableCode - 表编码
+ * @param id - 记录ID
+ * @param operation - 生命周期操作
+ * @param data - 原因和版本号
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const changeEntityStatus = (
+  tableCode: string,
+  id: string | number,
+  operation: EntityLifecycleOperation,
+  data: { reason?: string; version?: number } = {}
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.post(`/entities/${tableCode}/${id}/${operation}`, data);
+};
+
+/**
+ * 对多条记录执行生命周期操作
+ *
+ * @param tableCode - 表编码
+ * @param operation - 生命周期操作
+ * @param data - 记录ID、版本号和原因
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const batchChangeEntityStatus = (
+  tableCode: string,
+  operation: EntityLifecycleOperation,
+  data: { ids: Array<string | number>; versions?: Record<string, number>; reason?: string }
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.post(`/entities/${tableCode}/batch_${operation}`, data);
+};
+
+/**
+ * 移动树形表的节点及其子树
+ *
+ * @param tableCode - 表编码
+ * @param id - 记录ID
+ * @param data - 新的父节点ID (0 或 null 为根节点)、原因和版本号
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const moveEntity = (
+  tableCode: string,
+  id: string | number,
+  data: { parent_id: string | number | null; reason?: string; version?: number }
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.post(`/entities/${tableCode}/${id}/move`, data);
+};
+
+/**
+ * 获取树形表节点的祖先 (ancestors)、后代 (descendants) 或兄弟节点 (siblings)
+ *
+ * @param tableCode - 表编码
+ * @param id - 记录ID
+ * @param relation - 层级关系
+ * @param depth - 后代的层数, 不传时返回全部后代
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const getEntityHierarchy = (
+  tableCode: string,
+  id: string | number,
+  relation: 'ancestors' | 'descendants' | 'siblings',
+  depth?: number
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.get(`/entities/${tableCode}/${id}/${relation}`, { params: { depth } });
+};
+
+/**
+ * 获取树形数据, 子节点在 children 中
+ *
+ * @param tableCode - 表编码
+ * @param params - 起始节点和层数, 不传 root 时返回全部根节点
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const getEntityTree = (
+  tableCode: string,
+  params: { root?: string | number; depth?: number } = {}
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.get(`/entities/${tableCode}/tree`, { params });
+};
+
+/**
+ * 获取单据: 抬头记录及其行项目 (按行项目表编码分组)
+ *
+ * @param tableCode - 抬头表编码
+ * @param id - 抬头记录ID
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const getEntityDocument = (
+  tableCode: string,
+  id: string | number
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.get(`/entities/${tableCode}/${id}/document`);
+};
+
+/**
+ * 新增单据, 抬头和行项目在同一事务中保存
+ *
+ * @param tableCode - 抬头表编码
+ * @param data - 抬头、行项目和原因
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const createEntityDocument = (
+  tableCode: string,
+  data: { header: Record<string, any>; items?: Record<string, Record<string, any>[]>; reason: string }
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.post(`/entities/${tableCode}/documents`, data);
+};
+
+/**
+ * 修改单据, 提交的行项目表整体替换
+ *
+ * @param tableCode - 抬头表编码
+ * @param id - 抬头记录ID
+ * @param data - 抬头、行项目和原因
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const updateEntityDocument = (
+  tableCode: string,
+  id: string | number,
+  data: { header: Record<string, any>; items?: Record<string, Record<string, any>[]>; reason: string }
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.put(`/entities/${tableCode}/${id}/document`, data);
+};
+
+/**
+ * 获取回收站中已删除的记录
+ *
+ * @param tableCode - 表编码
+ * @param params - 查询参数, 同列表查询
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const getEntityTrash = (
+  tableCode: string,
+  params?: Record<string, any>
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.get(`/entities/${tableCode}/trash`, { params });
+};
+
+/**
+ * 从回收站恢复记录
+ *
+ * @param tableCode - 表编码
+ * @param data - 记录ID和原因
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const restoreEntity = (
+  tableCode: string,
+  data: { ids: Array<string | number>; reason?: string }
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.post(`/entities/${tableCode}/restore`, data);
+};
+
+/**
+ * 彻底删除回收站中超过保留时长的记录 (管理员), 不指定 ids 时清理全部过期记录
+ *
+ * @param tableCode - 表编码
+ * @param data - 记录ID和原因
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const purgeEntity = (
+  tableCode: string,
+  data: { ids?: Array<string | number>; reason?: string }
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.post(`/admin/entities/${tableCode}/purge`, data);
+};
+
+/**
+ * 疑似重复的记录
+ */
+export interface DuplicateCandidate {
+  id: number;
+  rule: string; // 命中的匹配规则编码
+  action: 'Warn' | 'Block';
+  score: number; // 加权相似度 0~1
+  fields: Record<string, number>; // 各字段相似度
+  record: Record<string, any> | null;
+}
+
+/**
+ * 疑似重复分组, Status: Open 待处理 Confirmed 已确认重复 Dismissed 已排除
+ */
+export interface DuplicateCluster {
+  ID: number;
+  TableCode: string;
+  RuleCode: string;
+  JobCode: string;
+  Score: number;
+  Size: number;
+  Status: 'Open' | 'Confirmed' | 'Dismissed';
+  ReviewedBy?: string;
+  ReviewedAt?: string | null;
+  Members: { EntityID: number; Score: number }[];
+  Records?: Record<string, any>[]; // 仅查询单个分组时返回
+}
+
+/**
+ * 按匹配规则查找与数据疑似重复的记录, 不保存数据; 带 id 时结果中不含记录本身
+ *
+ * @param tableCode - 表编码
+ * @param data - 记录数据
+ * @returns Promise<AxiosResponse<ApiResponse<DuplicateCandidate[]>>>
+ */
+export const checkEntityDuplicates = (
+  tableCode: string,
+  data: Record<string, any>
+): Promise<AxiosResponse<ApiResponse<DuplicateCandidate[]>>> => {
+  return service.post(`/entities/${tableCode}/duplicates/check`, data);
+};
+
+/**
+ * 创建后台查重任务, 按匹配规则扫描全表
+ *
+ * @param tableCode - 表编码
+ * @param rule - 匹配规则编码
+ * @returns Promise<AxiosResponse<ApiResponse>> 包含 job
+ */
+export const scanEntityDuplicates = (
+  tableCode: string,
+  rule: string
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.post(`/entities/${tableCode}/duplicates/scan`, { rule });
+};
+
+/**
+ * 分页查询疑似重复分组
+ *
+ * @param tableCode - 表编码
+ * @param params - 查询参数, 可按 status、rule 过滤
+ * @returns Promise<AxiosResponse<ApiResponse<DuplicateCluster[]>>>
+ */
+export const getDuplicateClusters = (
+  tableCode: string,
+  params?: { page?: number; pageSize?: number; status?: string; rule?: string }
+): Promise<AxiosResponse<ApiResponse<DuplicateCluster[]>>> => {
+  return service.get(`/entities/${tableCode}/duplicates`, { params });
+};
+
+/**
+ * 查询疑似重复分组及其成员记录
+ *
+ * @param tableCode - 表编码
+ * @param id - 分组 ID
+ * @returns Promise<AxiosResponse<ApiResponse<DuplicateCluster>>>
+ */
+export const getDuplicateCluster = (
+  tableCode: string,
+  id: number
+): Promise<AxiosResponse<ApiResponse<DuplicateCluster>>> => {
+  return service.get(`/entities/${tableCode}/duplicates/${id}`);
+};
+
+/**
+ * 确认或排除疑似重复分组, 排除的分组在重新扫描时不再生成
+ *
+ * @param tableCode - 表编码
+ * @param id - 分组 ID
+ * @param status - Confirmed 确认重复 Dismissed 排除
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const reviewDuplicateCluster = (
+  tableCode: string,
+  id: number,
+  status: 'Confirmed' | 'Dismissed'
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.put(`/entities/${tableCode}/duplicates/${id}`, { status });
+};
+
+/**
+ * 合并请求, picks 指定字段取哪条记录的值, 优先于保留规则
+ */
+export interface MergeRequest {
+  survivor_id: number; // 主记录
+  merged_ids: number[]; // 被合并记录, 合并后作废
+  picks?: Record<string, number>;
+}
+
+/**
+ * 合并预览
+ */
+export interface MergePreview {
+  survivor: Record<string, any>; // 合并后的主记录
+  values: Record<string, any>; // 主记录有变化的字段值
+  sources: Record<string, number>; // 有变化的字段取值的记录 id
+  records: Record<string, any>[]; // 参与合并的记录, 主记录在前
+  references: Record<string, any>[] | null; // 引用被合并记录的数据
+}
+
+/**
+ * 合并记录, Status: Pending 审批中 Merged 已合并 Rejected 已拒绝 Reverted 已取消合并
+ */
+export interface EntityMerge {
+  ID: number;
+  Code: string;
+  TableCode: string;
+  SurvivorID: number;
+  MergedIDs: number[];
+  Values: Record<string, any>;
+  Sources: Record<string, number>;
+  References:
+    | { table_code: string; field_code: string; entity_id: number; before: any; after: any }[]
+    | null;
+  Status: 'Pending' | 'Merged' | 'Rejected' | 'Reverted';
+  ApprovalCode: string;
+  Reason: string;
+  MergedAt?: string | null;
+  RevertedBy?: string;
+  RevertedAt?: string | null;
+  CreatedBy: string;
+  CreatedAt: string;
+}
+
+/**
+ * 按保留规则预览合并结果, 不保存数据
+ *
+ * @param tableCode - 表编码
+ * @param data - 合并请求
+ * @returns Promise<AxiosResponse<ApiResponse<MergePreview>>>
+ */
+export const previewEntityMerge = (
+  tableCode: string,
+  data: MergeRequest
+): Promise<AxiosResponse<ApiResponse<MergePreview>>> => {
+  return service.post(`/entities/${tableCode}/merges/preview`, data);
+};
+
+/**
+ * 合并记录; 配置了合并审批时返回审批中的合并
+ *
+ * @param tableCode - 表编码
+ * @param data - 合并请求和原因
+ * @returns Promise<AxiosResponse<ApiResponse<EntityMerge>>>
+ */
+export const mergeEntities = (
+  tableCode: string,
+  data: MergeRequest & { reason?: string }
+): Promise<AxiosResponse<ApiResponse<EntityMerge>>> => {
+  return service.post(`/entities/${tableCode}/merges`, data);
+};
+
+/**
+ * 分页查询合并记录
+ *
+ * @param tableCode - 表编码
+ * @param params - 查询参数, 可按 status、survivor_id 过滤
+ * @returns Promise<AxiosResponse<ApiResponse<EntityMerge[]>>>
+ */
+export const getEntityMerges = (
+  tableCode: string,
+  params?: { page?: number; pageSize?: number; status?: string; survivor_id?: number }
+): Promise<AxiosResponse<ApiResponse<EntityMerge[]>>> => {
+  return service.get(`/entities/${tableCode}/merges`, { params });
+};
+
+/**
+ * 查询合并记录
+ *
+ * @param tableCode - 表编码
+ * @param id - 合并 ID
+ * @returns Promise<AxiosResponse<ApiResponse<EntityMerge>>>
+ */
+export const getEntityMerge = (
+  tableCode: string,
+  id: number
+): Promise<AxiosResponse<ApiResponse<EntityMerge>>> => {
+  return service.get(`/entities/${tableCode}/merges/${id}`);
+};
+
+/**
+ * 取消合并, 合并后又被修改过的值保留当前值
+ *
+ * @param tableCode - 表编码
+ * @param id - 合并 ID
+ * @param reason - 原因
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const unmergeEntities = (
+  tableCode: string,
+  id: number,
+  reason: string
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.post(`/entities/${tableCode}/merges/${id}/unmerge`, { reason });
+};
+
 /**
  * 获取实体日志列表
  *
@@ -240,6 +699,20 @@ export const importFile = (
   return service.post(`/entities/${tableCode}/import`, data);
 };
 
+/**
+ * 查询导入/导出后台任务进度
+ *
+ * @param tableCode - 表编码
+ * @param code - 任务编号
+ * @returns Promise<AxiosResponse<ApiResponse>> 包含 job 和 result_url
+ */
+export const getEntityJob = (
+  tableCode: string,
+  code: string
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.get(`/entities/${tableCode}/jobs/${code}`);
+};
+
 /**
  * 获取导入模板
  *
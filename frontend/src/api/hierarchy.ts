@@ -0,0 +1,274 @@
+/**
+ * Hierarchy API
+ *
+ * 层级 (表的命名层级、版本和节点) 相关 API 封装
+ */
+
+import requestService from '@/utils/request';
+import type { AxiosInstance, AxiosResponse } from 'axios';
+import type { ApiResponse } from '@/api/types';
+
+// 类型断言: request.js 导出的 service 是一个 Axios 实例
+const service = requestService as AxiosInstance;
+
+/**
+ * 层级数据模型
+ */
+export interface Hierarchy {
+  ID?: number;
+  Code: string;
+  Name: string;
+  TableCode: string;
+  Description?: string;
+  Status?: string;
+  CreatedAt?: string;
+  UpdatedAt?: string;
+}
+
+/**
+ * 层级版本, Status: Draft 编辑中 Pending 审批中 Active 已发布
+ */
+export interface HierarchyVersion {
+  ID?: number;
+  HierarchyCode?: string;
+  Version: string;
+  ValidFrom?: string | null;
+  ValidTo?: string | null;
+  Status?: string;
+  ApprovalCode?: string;
+  Description?: string;
+}
+
+/**
+ * 层级节点, parent_id 为 0 时是根节点
+ */
+export interface HierarchyNode {
+  entity_id: number;
+  parent_id: number;
+  sort?: number;
+}
+
+/**
+ * 层级查询返回的节点
+ */
+export interface HierarchyNodeView extends HierarchyNode {
+  level: number;
+  path: string;
+  record: Record<string, any> | null;
+  children?: HierarchyNodeView[];
+}
+
+/**
+ * 新增或修改版本的请求参数, 时间格式为 2006-01-02 或 2006-01-02 15:04:05
+ */
+export interface HierarchyVersionRequest {
+  version?: string;
+  valid_from?: string;
+  valid_to?: string;
+  description?: string;
+  copy_from?: string;
+}
+
+/**
+ * 层级查询参数: 指定 version 时使用该版本, 否则使用 date (默认当天) 有效的已发布版本
+ */
+export interface HierarchyQueryParams {
+  version?: string;
+  date?: string;
+  root?: number;
+  depth?: number;
+}
+
+/**
+ * 分页获取层级列表 (管理端)
+ *
+ * @param params - 查询参数
+ * @returns Promise<AxiosResponse<ApiResponse<Hierarchy[]>>>
+ */
+export const getHierarchyList = (
+  params?: { page?: number; pageSize?: number; tableCode?: string }
+): Promise<AxiosResponse<ApiResponse<Hierarchy[]>>> => {
+  return service.get('/admin/hierarchies', { params });
+};
+
+/**
+ * 根据 ID 查询层级 (管理端)
+ *
+ * @param id - 层级 ID
+ * @returns Promise<AxiosResponse<ApiResponse<Hierarchy>>>
+ */
+export const findHierarchy = (id: number): Promise<AxiosResponse<ApiResponse<Hierarchy>>> => {
+  return service.get(`/admin/hierarchies/${id}`);
+};
+
+/**
+ * 创建层级 (管理端)
+ *
+ * @param data - 层级信息
+ * @returns Promise<AxiosResponse<ApiResponse<Hierarchy>>>
+ */
+export const createHierarchy = (data: Hierarchy): Promise<AxiosResponse<ApiResponse<Hierarchy>>> => {
+  return service.post('/admin/hierarchies', data);
+};
+
+/**
+ * 更新层级 (管理端), 编码和引用的表不能修改
+ *
+ * @param data - 层级信息
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const updateHierarchy = (data: Hierarchy & { ID: number }): Promise<AxiosResponse<ApiResponse>> => {
+  return service.put(`/admin/hierarchies/${data.ID}`, data);
+};
+
+/**
+ * 删除层级及其全部版本 (管理端)
+ *
+ * @param id - 层级 ID
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const deleteHierarchy = (id: number): Promise<AxiosResponse<ApiResponse>> => {
+  return service.delete(`/admin/hierarchies/${id}`);
+};
+
+/**
+ * 查询表的全部层级
+ *
+ * @param tableCode - 表代码
+ * @returns Promise<AxiosResponse<ApiResponse<Hierarchy[]>>>
+ */
+export const getTableHierarchies = (tableCode: string): Promise<AxiosResponse<ApiResponse<Hierarchy[]>>> => {
+  return service.get('/hierarchies', { params: { table_code: tableCode } });
+};
+
+/**
+ * 查询层级的全部版本
+ *
+ * @param code - 层级编码
+ * @returns Promise<AxiosResponse<ApiResponse<HierarchyVersion[]>>>
+ */
+export const getHierarchyVersions = (code: string): Promise<AxiosResponse<ApiResponse<HierarchyVersion[]>>> => {
+  return service.get(`/hierarchies/${code}/versions`);
+};
+
+/**
+ * 新增编辑中的版本, copy_from 复制其他版本的节点
+ *
+ * @param code - 层级编码
+ * @param data - 版本信息
+ * @returns Promise<AxiosResponse<ApiResponse<HierarchyVersion>>>
+ */
+export const createHierarchyVersion = (
+  code: string,
+  data: HierarchyVersionRequest
+): Promise<AxiosResponse<ApiResponse<HierarchyVersion>>> => {
+  return service.post(`/hierarchies/${code}/versions`, data);
+};
+
+/**
+ * 修改版本的有效期和描述
+ *
+ * @param code - 层级编码
+ * @param version - 版本号
+ * @param data - 有效期和描述
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const updateHierarchyVersion = (
+  code: string,
+  version: string,
+  data: HierarchyVersionRequest
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.put(`/hierarchies/${code}/versions/${version}`, data);
+};
+
+/**
+ * 删除编辑中的版本
+ *
+ * @param code - 层级编码
+ * @param version - 版本号
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const deleteHierarchyVersion = (code: string, version: string): Promise<AxiosResponse<ApiResponse>> => {
+  return service.delete(`/hierarchies/${code}/versions/${version}`);
+};
+
+/**
+ * 替换编辑中版本的全部节点
+ *
+ * @param code - 层级编码
+ * @param version - 版本号
+ * @param nodes - 节点
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const saveHierarchyNodes = (
+  code: string,
+  version: string,
+  nodes: HierarchyNode[]
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.put(`/hierarchies/${code}/versions/${version}/nodes`, nodes);
+};
+
+/**
+ * 移动节点及其子树, parentId 为 0 时移动为根节点
+ *
+ * @param code - 层级编码
+ * @param version - 版本号
+ * @param entityId - 记录 ID
+ * @param parentId - 新的父节点记录 ID
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const moveHierarchyNode = (
+  code: string,
+  version: string,
+  entityId: number,
+  parentId: number
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.post(`/hierarchies/${code}/versions/${version}/move`, {
+    entity_id: entityId,
+    parent_id: parentId,
+  });
+};
+
+/**
+ * 发布编辑中的版本, 配置了发布审批流程时提交审批
+ *
+ * @param code - 层级编码
+ * @param version - 版本号
+ * @param reason - 原因
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const publishHierarchyVersion = (
+  code: string,
+  version: string,
+  reason: string
+): Promise<AxiosResponse<ApiResponse>> => {
+  return service.post(`/hierarchies/${code}/versions/${version}/publish`, { reason });
+};
+
+/**
+ * 查询层级的树形数据, 子节点放在 children 中
+ *
+ * @param code - 层级编码
+ * @param params - 查询参数
+ * @returns Promise<AxiosResponse<ApiResponse<{ version: HierarchyVersion; nodes: HierarchyNodeView[] }>>>
+ */
+export const getHierarchyTree = (
+  code: string,
+  params?: HierarchyQueryParams
+): Promise<AxiosResponse<ApiResponse<{ version: HierarchyVersion; nodes: HierarchyNodeView[] }>>> => {
+  return service.get(`/hierarchies/${code}/tree`, { params });
+};
+
+/**
+ * 查询层级的扁平数据, 按深度优先排列
+ *
+ * @param code - 层级编码
+ * @param params - 查询参数
+ * @returns Promise<AxiosResponse<ApiResponse<{ version: HierarchyVersion; nodes: HierarchyNodeView[] }>>>
+ */
+export const getHierarchyFlat = (
+  code: string,
+  params?: HierarchyQueryParams
+): Promise<AxiosResponse<ApiResponse<{ version: HierarchyVersion; nodes: HierarchyNodeView[] }>>> => {
+  return service.get(`/hierarchies/${code}/flat`, { params });
+};
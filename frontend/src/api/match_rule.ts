@@ -0,0 +1,98 @@
+/**
+ * Match Rule API
+ *
+ * 查重匹配规则相关 API 封装 (管理端)
+ */
+
+import requestService from '@/utils/request';
+import type { AxiosInstance, AxiosResponse } from 'axios';
+import type { ApiResponse } from '@/api/types';
+
+// 类型断言: request.js 导出的 service 是一个 Axios 实例
+const service = requestService as AxiosInstance;
+
+/**
+ * 匹配方法: exact 完全相同 normalized 规范化后相同 phonetic 发音相同
+ * edit_distance 编辑距离相似度 token 词集合相似度
+ */
+export type MatchMethod = 'exact' | 'normalized' | 'phonetic' | 'edit_distance' | 'token';
+
+/**
+ * 匹配字段, weight 为 0 时按 1 计算
+ */
+export interface MatchField {
+  field: string;
+  method: MatchMethod;
+  weight: number;
+}
+
+/**
+ * 匹配规则数据模型
+ */
+export interface MatchRule {
+  ID?: number;
+  Code: string;
+  Name: string;
+  TableCode: string;
+  Fields: MatchField[];
+  BlockFields?: string; // 分组字段, 逗号分隔
+  Threshold?: number; // 相似度阈值 (0~1], 默认 0.8
+  Action?: 'Warn' | 'Block';
+  Sort?: number;
+  Description?: string;
+  Status?: string;
+  CreatedAt?: string;
+  UpdatedAt?: string;
+}
+
+/**
+ * 分页获取匹配规则列表
+ *
+ * @param params - 查询参数
+ * @returns Promise<AxiosResponse<ApiResponse<MatchRule[]>>>
+ */
+export const getMatchRuleList = (
+  params?: { page?: number; pageSize?: number; tableCode?: string }
+): Promise<AxiosResponse<ApiResponse<MatchRule[]>>> => {
+  return service.get('/admin/match_rules', { params });
+};
+
+/**
+ * 根据 ID 查询匹配规则
+ *
+ * @param id - 规则 ID
+ * @returns Promise<AxiosResponse<ApiResponse<MatchRule>>>
+ */
+export const findMatchRule = (id: number): Promise<AxiosResponse<ApiResponse<MatchRule>>> => {
+  return service.get(`/admin/match_rules/${id}`);
+};
+
+/**
+ * 创建匹配规则
+ *
+ * @param data - 规则信息
+ * @returns Promise<AxiosResponse<ApiResponse<MatchRule>>>
+ */
+export const createMatchRule = (data: MatchRule): Promise<AxiosResponse<ApiResponse<MatchRule>>> => {
+  return service.post('/admin/match_rules', data);
+};
+
+/**
+ * 更新匹配规则, 编码和表不能修改
+ *
+ * @param data - 规则信息
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const updateMatchRule = (data: MatchRule & { ID: number }): Promise<AxiosResponse<ApiResponse>> => {
+  return service.put(`/admin/match_rules/${data.ID}`, data);
+};
+
+/**
+ * 删除匹配规则
+ *
+ * @param id - 规则 ID
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const deleteMatchRule = (id: number): Promise<AxiosResponse<ApiResponse>> => {
+  return service.delete(`/admin/match_rules/${id}`);
+};
@@ -0,0 +1,88 @@
+/**
+ * Model Package API
+ *
+ * 模型定义导出导入相关 API 封装 (管理端), 用于在环境间迁移数据模型
+ */
+
+import requestService from '@/utils/request';
+import type { AxiosInstance, AxiosResponse } from 'axios';
+import type { ApiResponse } from '@/api/types';
+import type { SchemaPlan, SchemaVersion } from '@/api/table_field';
+
+// 类型断言: request.js 导出的 service 是一个 Axios 实例
+const service = requestService as AxiosInstance;
+
+/**
+ * 模型定义包, format 固定为 piemdm-model, version 为包格式版本
+ * 表中包含字段、字段组、审批绑定和数据权限, 按编码关联
+ */
+export interface ModelPackage {
+  format: string;
+  version: number;
+  exported_at?: string;
+  exported_by?: string;
+  tables: Record<string, any>[];
+  approval_definitions: Record<string, any>[];
+}
+
+/**
+ * 导入时一个对象的变更
+ */
+export interface ModelPackageChange {
+  kind: string; // Table, Field, FieldGroup, TableApproval, TablePermission, ApprovalDefinition, ApprovalNode
+  key: string;
+  action: 'Create' | 'Update' | 'Delete';
+  fields?: string[]; // 修改的属性
+  before?: Record<string, any>;
+  after?: Record<string, any>;
+}
+
+/**
+ * 模型定义包与当前环境的差异
+ */
+export interface ModelPackageDiff {
+  checksum: string;
+  changes: ModelPackageChange[];
+  tables: string[]; // 需要发布表结构的表
+  schemas: SchemaPlan[];
+  errors: string[]; // 阻止导入的问题
+  data_loss: boolean;
+}
+
+/**
+ * 导入结果, failed 为发布表结构失败的表及原因
+ */
+export interface ModelImportResult {
+  diff: ModelPackageDiff;
+  versions: SchemaVersion[];
+  failed?: Record<string, string>;
+}
+
+/**
+ * 导出模型定义包
+ *
+ * @param params - table_codes: 表编码, 逗号分隔, 为空时导出全部表
+ */
+export const exportModelPackage = (
+  params?: { table_codes?: string }
+): Promise<AxiosResponse<ApiResponse<ModelPackage>>> => {
+  return service.get('/admin/model_packages/export', { params });
+};
+
+/**
+ * 预览导入模型定义包的差异, 不修改数据库
+ */
+export const diffModelPackage = (
+  data: ModelPackage
+): Promise<AxiosResponse<ApiResponse<ModelPackageDiff>>> => {
+  return service.post('/admin/model_packages/diff', data);
+};
+
+/**
+ * 导入模型定义包, checksum 为预览返回的差异摘要, 差异变化时返回 409
+ */
+export const importModelPackage = (
+  data: { package: ModelPackage; checksum?: string; allow_data_loss?: boolean }
+): Promise<AxiosResponse<ApiResponse<ModelImportResult>>> => {
+  return service.post('/admin/model_packages/import', data);
+};
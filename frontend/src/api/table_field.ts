@@ -126,14 +126,75 @@ export const getTableField = (
 };
 
 /**
- * 发布实体表
+ * 表结构变更, kind: CreateTable | DropIndex | RenameColumn | ConvertColumn | AlterColumn | AddColumn | DropColumn | AddIndex
+ */
+export interface SchemaChange {
+  kind: string;
+  column?: string;
+  from?: string; // 重命名前的列名
+  index?: string;
+  before?: string;
+  after?: string;
+  affected: number; // 需要改写的行数
+  lost: number; // 丢失或截断数据的行数
+  conflicts: number; // 阻止变更的行数, 如唯一索引的重复值
+  warning?: string;
+}
+
+/**
+ * 发布表结构的变更计划
+ */
+export interface SchemaPlan {
+  table_code: string;
+  version: number; // 发布后的表结构版本
+  checksum: string; // 发布时传回, 校验计划未变化
+  data_loss: boolean;
+  conflicts: boolean;
+  tables: { table: string; exists: boolean; rows: number; changes: SchemaChange[] | null }[];
+}
+
+/**
+ * 表结构版本, Status: Applied 已发布 Failed 发布失败
+ */
+export interface SchemaVersion {
+  ID: number;
+  TableCode: string;
+  Version: number;
+  Checksum: string;
+  Plan: SchemaPlan;
+  Status: 'Applied' | 'Failed';
+  Error: string;
+  CreatedBy: string;
+  CreatedAt: string;
+}
+
+/**
+ * 预览发布表结构的变更计划, 不修改数据库
+ */
+export const getPublicPlan = (
+  params: { table_code: string }
+): Promise<AxiosResponse<ApiResponse<SchemaPlan>>> => {
+  return service.get('/table_fields/public/plan', { params });
+};
+
+/**
+ * 发布实体表; checksum 为预览返回的计划摘要, 会丢失数据时需要 allow_data_loss 确认
  */
 export const publicTable = (
-  data: { tableCode: string }
+  data: { table_code: string; checksum?: string; allow_data_loss?: boolean }
 ): Promise<AxiosResponse<ApiResponse>> => {
   return service.post('/table_fields/public', data);
 };
 
+/**
+ * 获取表结构版本列表
+ */
+export const getSchemaVersions = (
+  params: { table_code: string; status?: string; page?: number; pageSize?: number }
+): Promise<AxiosResponse<ApiResponse<SchemaVersion[]>>> => {
+  return service.get('/table_fields/schema_versions', { params });
+};
+
 /**
  * 获取表列表
  */
@@ -0,0 +1,96 @@
+/**
+ * Table Field Group API
+ *
+ * 字段组相关 API 封装 (管理端)
+ */
+
+import requestService from '@/utils/request';
+import type { AxiosInstance, AxiosResponse } from 'axios';
+import type { ApiResponse } from '@/api/types';
+
+// 类型断言: request.js 导出的 service 是一个 Axios 实例
+const service = requestService as AxiosInstance;
+
+/**
+ * 字段组数据模型, 字段的 group_name 保存字段组编码
+ */
+export interface TableFieldGroup {
+  ID?: number;
+  Code: string;
+  Name: string;
+  TableCode: string;
+  View?: string;
+  Sort?: number;
+  Collapsible?: 'Yes' | 'No'; // 是否可折叠
+  Collapsed?: 'Yes' | 'No'; // 是否默认折叠
+  ViewRoles?: string; // 可查看的角色编码, 逗号分隔, 为空时不限制
+  EditRoles?: string; // 可修改的角色编码, 逗号分隔, 为空时不限制
+  Description?: string;
+  Status?: string;
+  CreatedAt?: string;
+  UpdatedAt?: string;
+}
+
+/**
+ * 字段元数据中的字段组信息 (getTableFields 返回), 已按当前用户的角色计算 editable
+ */
+export interface FieldGroupMetadata {
+  code: string;
+  name: string;
+  sort: number;
+  collapsible: boolean;
+  collapsed: boolean;
+  editable: boolean;
+}
+
+/**
+ * 分页获取字段组列表
+ *
+ * @param params - 查询参数
+ * @returns Promise<AxiosResponse<ApiResponse<TableFieldGroup[]>>>
+ */
+export const getTableFieldGroupList = (
+  params?: { page?: number; pageSize?: number; tableCode?: string }
+): Promise<AxiosResponse<ApiResponse<TableFieldGroup[]>>> => {
+  return service.get('/admin/table_field_groups', { params });
+};
+
+/**
+ * 根据 ID 查询字段组
+ *
+ * @param id - 字段组 ID
+ * @returns Promise<AxiosResponse<ApiResponse<TableFieldGroup>>>
+ */
+export const findTableFieldGroup = (id: number): Promise<AxiosResponse<ApiResponse<TableFieldGroup>>> => {
+  return service.get(`/admin/table_field_groups/${id}`);
+};
+
+/**
+ * 创建字段组
+ *
+ * @param data - 字段组信息
+ * @returns Promise<AxiosResponse<ApiResponse<TableFieldGroup>>>
+ */
+export const createTableFieldGroup = (data: TableFieldGroup): Promise<AxiosResponse<ApiResponse<TableFieldGroup>>> => {
+  return service.post('/admin/table_field_groups', data);
+};
+
+/**
+ * 更新字段组, 编码和表不能修改
+ *
+ * @param data - 字段组信息
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const updateTableFieldGroup = (data: TableFieldGroup & { ID: number }): Promise<AxiosResponse<ApiResponse>> => {
+  return service.put(`/admin/table_field_groups/${data.ID}`, data);
+};
+
+/**
+ * 删除字段组, 组中还有字段时不能删除
+ *
+ * @param id - 字段组 ID
+ * @returns Promise<AxiosResponse<ApiResponse>>
+ */
+export const deleteTableFieldGroup = (id: number): Promise<AxiosResponse<ApiResponse>> => {
+  return service.delete(`/admin/table_field_groups/${id}`);
+};
@@ -21,12 +21,23 @@ export default {
   "All": "All",
   "For Create": "For Create",
   "For Update": "For Update",
+  "For Upsert": "For Create or Update",
+  "Match By Unique Index": "Match by unique index",
+  "No unique index defined for this table": "No unique index is defined for this table",
   "Download": "Download",
   "File": "File",
+  "File Format": "File Format",
+  "Delimiter": "Delimiter",
+  "Encoding": "Encoding",
   "Reason": "Reason",
   "Please": "Please",
   "and": "and",
   "check the last template.": "check the last template.",
+  "Validate only, do not write data": "Validate only, do not write data",
+  "Import progress": "Import progress",
+  "Download result": "Download result",
+  "Import job failed": "Import job failed",
+  "Export job failed": "Export job failed",
   "Close": "Close",
   "Confirm": "Confirm",
   "Search": "Search",
@@ -85,6 +96,12 @@ export default {
   "Table Create": "Table Create",
   "Table Update": "Table Update",
   "Basic Info": "Basic Info",
+  "Read only": "Read only",
+  "Where Used": "Where Used",
+  "No records reference this data": "No records reference this data",
+  "Restrict delete": "Restrict delete",
+  "Clear reference": "Clear reference",
+  "Cascade delete": "Cascade delete",
   "Extension": "Extension",
   "ExtTable": "ExtTable",
   "ExtField": "ExtField",
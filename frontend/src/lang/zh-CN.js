@@ -22,12 +22,23 @@ export default {
   "All": "全部",
   "For Create": "新增",
   "For Update": "更新",
+  "For Upsert": "新增或更新",
+  "Match By Unique Index": "按唯一索引匹配",
+  "No unique index defined for this table": "该表没有定义唯一索引",
   "Download": "下载",
   "File": "文件",
+  "File Format": "文件格式",
+  "Delimiter": "分隔符",
+  "Encoding": "编码",
   "Reason": "原因",
   "Please": "请",
   "and": "和",
   "check the last template.": "检查最后一个模板。",
+  "Validate only, do not write data": "仅校验，不写入数据",
+  "Import progress": "导入进度",
+  "Download result": "下载结果文件",
+  "Import job failed": "导入任务失败",
+  "Export job failed": "导出任务失败",
   "Close": "关闭",
   "Confirm": "确认",
   "Search": "搜索",
@@ -87,6 +98,12 @@ export default {
   "Table Create": "创建表",
   "Table Update": "更新表",
   "Basic Info": "基础信息",
+  "Read only": "只读",
+  "Where Used": "引用记录",
+  "No records reference this data": "没有数据引用此记录",
+  "Restrict delete": "禁止删除",
+  "Clear reference": "清空引用",
+  "Cascade delete": "级联删除",
   "Extension": "扩展",
   "ExtTable": "扩展表",
   "ExtField": "扩展字段",
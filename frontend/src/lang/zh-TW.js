@@ -22,12 +22,23 @@ export default {
   "All": "全部",
   "For Create": "新增",
   "For Update": "更新",
+  "For Upsert": "新增或更新",
+  "Match By Unique Index": "按唯一索引匹配",
+  "No unique index defined for this table": "該表沒有定義唯一索引",
   "Download": "下載",
   "File": "文件",
+  "File Format": "檔案格式",
+  "Delimiter": "分隔符",
+  "Encoding": "編碼",
   "Reason": "原因",
   "Please": "請",
   "and": "和",
   "check the last template.": "檢查最後一個模板。",
+  "Validate only, do not write data": "僅校驗，不寫入資料",
+  "Import progress": "匯入進度",
+  "Download result": "下載結果檔案",
+  "Import job failed": "匯入任務失敗",
+  "Export job failed": "匯出任務失敗",
   "Close": "關閉",
   "Confirm": "確認",
   "Search": "搜索",
@@ -86,6 +97,12 @@ export default {
   "Table Create": "創建表",
   "Table Update": "更新表",
   "Basic Info": "基礎信息",
+  "Read only": "唯讀",
+  "Where Used": "引用記錄",
+  "No records reference this data": "沒有數據引用此記錄",
+  "Restrict delete": "禁止刪除",
+  "Clear reference": "清空引用",
+  "Cascade delete": "級聯刪除",
   "Extension": "擴展",
   "ExtTable": "擴展表",
   "ExtField": "擴展字段",
@@ -0,0 +1,82 @@
+/**
+ * 字段组工具
+ * 表单、详情和审批表单按相同的字段组布局显示字段
+ */
+
+/**
+ * 字段所属字段组的键: 带有字段组元数据 (Group/group) 时为字段组编码, 兼容只有组名 (GroupName) 的旧字段
+ * @param {Object} field - 字段配置
+ * @returns {string} 未分组时为空字符串
+ */
+export function fieldGroupKey(field) {
+  const group = field.Group || field.group;
+  return group?.code || group?.name || field.GroupName || '';
+}
+
+/**
+ * 按字段组分组并扁平化字段列表, 每组前插入标题行 { isHeader, key, name, collapsible, collapsed, editable }
+ * 未分组的字段在前, 维护的字段组按 sort 排列, 只有组名的旧字段组保持出现顺序; 组内保持字段顺序
+ * @param {Array} fields - 已按字段顺序排列的字段
+ * @param {string} defaultName - 未分组字段的标题
+ * @returns {Array}
+ */
+export function groupFields(fields, defaultName = '基本信息') {
+  const groups = {};
+  const groupOrder = [];
+
+  fields.forEach(field => {
+    const key = fieldGroupKey(field);
+    if (!groups[key]) {
+      const group = field.Group || field.group;
+      groups[key] = { group, name: group?.name || field.GroupName || defaultName, fields: [] };
+      groupOrder.push(key);
+    }
+    groups[key].fields.push(field);
+  });
+
+  const rank = key => {
+    const group = groups[key].group;
+    if (!key) return [0, 0];
+    return group?.code ? [1, group.sort || 0] : [2, 0];
+  };
+  const ordered = groupOrder
+    .map((key, index) => ({ key, index, rank: rank(key) }))
+    .sort((a, b) => a.rank[0] - b.rank[0] || a.rank[1] - b.rank[1] || a.index - b.index);
+
+  const result = [];
+  ordered.forEach(({ key }) => {
+    const { group, name, fields: groupFields } = groups[key];
+    result.push({
+      isHeader: true,
+      key,
+      name,
+      collapsible: group?.collapsible === true,
+      collapsed: group?.collapsible === true && group?.collapsed === true,
+      editable: group?.editable !== false,
+    });
+    groupFields.forEach(field => result.push(field));
+  });
+  return result;
+}
+
+/**
+ * 表格列的字段组表头: 相邻同组的列合并为一个单元格
+ * @param {Array} fields - 按列顺序排列的字段
+ * @returns {Array} [{ key, name, span }], 没有字段组时为空数组
+ */
+export function groupBands(fields) {
+  const bands = [];
+  let grouped = false;
+  fields.forEach(field => {
+    const key = fieldGroupKey(field);
+    const group = field.Group || field.group;
+    grouped = grouped || key !== '';
+    const last = bands[bands.length - 1];
+    if (last && last.key === key) {
+      last.span++;
+    } else {
+      bands.push({ key, name: group?.name || field.GroupName || '', span: 1 });
+    }
+  });
+  return grouped ? bands : [];
+}
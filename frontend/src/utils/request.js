@@ -174,6 +174,21 @@ service.interceptors.response.use(
                 `,
           });
           break;
+        case 409:
+          // 数据已被其他人修改: 列出提交值与当前值不同的字段
+          const conflictRows = (error.response?.data?.conflicts || [])
+            .flatMap(conflict => (conflict.diff || []).map(diff => `
+              <tr><td>${conflict.id}</td><td>${diff.name || diff.field}</td><td>${diff.submitted}</td><td>${diff.current}</td></tr>`))
+            .join('');
+          AppModal.alert({
+            title: '数据冲突',
+            bodyHtml: true,
+            bodyContent: `
+              <p>错误信息: <span style="color:red">${error.response?.data?.message}</span></p>
+              ${conflictRows ? `<table class="table table-sm"><thead><tr><th>ID</th><th>字段</th><th>提交值</th><th>当前值</th></tr></thead><tbody>${conflictRows}</tbody></table>` : ''}
+              `,
+          });
+          break;
         case 403:
           const errorMsg = error.response?.data?.message || '您没有权限执行此操作';
           if (errorMsg === 'admin access required') {